@@ -17,6 +17,8 @@ package main
 import (
 	"github.com/uber/peloton/pkg/apiproxy"
 	"github.com/uber/peloton/pkg/auth"
+	"github.com/uber/peloton/pkg/common/audit"
+	"github.com/uber/peloton/pkg/common/leader"
 	"github.com/uber/peloton/pkg/common/metrics"
 	"github.com/uber/peloton/pkg/middleware/inbound"
 )
@@ -27,4 +29,6 @@ type Config struct {
 	APIProxy  apiproxy.Config         `yaml:"api_proxy"`
 	Auth      auth.Config             `yaml:"auth"`
 	RateLimit inbound.RateLimitConfig `yaml:"rate_limit"`
+	Election  leader.ElectionConfig   `yaml:"election"`
+	Audit     audit.Config            `yaml:"audit"`
 }
@@ -17,11 +17,14 @@ package main
 import (
 	"os"
 
+	"github.com/uber/peloton/pkg/apiproxy"
 	"github.com/uber/peloton/pkg/auth"
 	auth_impl "github.com/uber/peloton/pkg/auth/impl"
 	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/audit"
 	"github.com/uber/peloton/pkg/common/buildversion"
 	"github.com/uber/peloton/pkg/common/config"
+	"github.com/uber/peloton/pkg/common/leader"
 	"github.com/uber/peloton/pkg/common/logging"
 	"github.com/uber/peloton/pkg/common/metrics"
 	"github.com/uber/peloton/pkg/common/rpc"
@@ -30,7 +33,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"go.uber.org/yarpc"
-	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/transport/http"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -53,6 +56,27 @@ var (
 		Required().
 		ExistingFiles()
 
+	electionZkServers = app.Flag(
+		"election-zk-server",
+		"Election Zookeeper servers. Specify multiple times for multiple servers "+
+			"(election.zk_servers override) (set $ELECTION_ZK_SERVERS to override)").
+		Envar("ELECTION_ZK_SERVERS").
+		Strings()
+
+	electionBackend = app.Flag(
+		"election-backend",
+		"Election backend, either zookeeper or etcd "+
+			"(election.backend override) (set $ELECTION_BACKEND to override)").
+		Envar("ELECTION_BACKEND").
+		String()
+
+	electionEtcdEndpoints = app.Flag(
+		"election-etcd-endpoint",
+		"Election etcd endpoints. Specify multiple times for multiple endpoints "+
+			"(election.etcd_endpoints override) (set $ELECTION_ETCD_ENDPOINTS to override)").
+		Envar("ELECTION_ETCD_ENDPOINTS").
+		Strings()
+
 	httpPort = app.Flag(
 		"http-port", "API Proxy HTTP port (apiproxy.http_port override) "+
 			"(set $PORT to override)").
@@ -106,6 +130,18 @@ func main() {
 		log.WithField("error", err).Fatal("Cannot parse yaml config")
 	}
 
+	if len(*electionZkServers) > 0 {
+		cfg.Election.ZKServers = *electionZkServers
+	}
+
+	if *electionBackend != "" {
+		cfg.Election.Backend = *electionBackend
+	}
+
+	if len(*electionEtcdEndpoints) > 0 {
+		cfg.Election.EtcdEndpoints = *electionEtcdEndpoints
+	}
+
 	if *httpPort != 0 {
 		cfg.APIProxy.HTTPPort = *httpPort
 	}
@@ -142,7 +178,24 @@ func main() {
 		mux,
 	)
 
-	outbounds := yarpc.Outbounds{}
+	// Discover the leaders of the Peloton components, and create the
+	// outbounds following them
+	discovery, err := leader.NewServiceDiscovery(cfg.Election)
+	if err != nil {
+		log.WithError(err).
+			Fatal("Could not create service discovery")
+	}
+
+	outbounds, err := apiproxy.NewOutbounds(
+		rpc.NewTransport(),
+		http.NewTransport(),
+		discovery,
+		&cfg.APIProxy,
+	)
+	if err != nil {
+		log.WithError(err).
+			Fatal("Could not create outbounds")
+	}
 
 	securityManager, err := auth_impl.CreateNewSecurityManager(&cfg.Auth)
 	if err != nil {
@@ -156,6 +209,12 @@ func main() {
 			Fatal("Could not create rate limit middleware")
 	}
 
+	auditTrail, err := audit.NewTrail(cfg.Audit)
+	if err != nil {
+		log.WithError(err).Fatal("Cannot init audit trail")
+	}
+	auditInboundMiddleware := inbound.NewAuditInboundMiddleware(auditTrail)
+
 	authInboundMiddleware := inbound.NewAuthInboundMiddleware(securityManager)
 
 	securityClient, err := auth_impl.CreateNewSecurityClient(&cfg.Auth)
@@ -173,10 +232,12 @@ func main() {
 		Metrics: yarpc.MetricsConfig{
 			Tally: rootScope,
 		},
+		// the audit middleware runs after auth, so that it records the
+		// authenticated user of the calls
 		InboundMiddleware: yarpc.InboundMiddleware{
-			Unary:  yarpc.UnaryInboundMiddleware(rateLimitMiddleware, authInboundMiddleware),
-			Stream: yarpc.StreamInboundMiddleware(rateLimitMiddleware, authInboundMiddleware),
-			Oneway: yarpc.OnewayInboundMiddleware(rateLimitMiddleware, authInboundMiddleware),
+			Unary:  yarpc.UnaryInboundMiddleware(rateLimitMiddleware, authInboundMiddleware, auditInboundMiddleware),
+			Stream: yarpc.StreamInboundMiddleware(rateLimitMiddleware, authInboundMiddleware, auditInboundMiddleware),
+			Oneway: yarpc.OnewayInboundMiddleware(rateLimitMiddleware, authInboundMiddleware, auditInboundMiddleware),
 		},
 		OutboundMiddleware: yarpc.OutboundMiddleware{
 			Unary:  authOutboundMiddleware,
@@ -185,7 +246,9 @@ func main() {
 		},
 	})
 
-	dispatcher.Register([]transport.Procedure{})
+	// Forward the v0 and v1alpha procedures to the Peloton components
+	server := apiproxy.NewServer(dispatcher, rootScope)
+	dispatcher.Register(server.Procedures())
 
 	// Start the dispatcher.
 	if err := dispatcher.Start(); err != nil {
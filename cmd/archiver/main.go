@@ -257,11 +257,10 @@ func main() {
 		mux,
 	)
 
-	discovery, err := leader.NewZkServiceDiscovery(
-		cfg.Election.ZKServers, cfg.Election.Root)
+	discovery, err := leader.NewServiceDiscovery(cfg.Election)
 	if err != nil {
 		log.WithError(err).
-			Fatal("Could not create zk service discovery")
+			Fatal("Could not create service discovery")
 	}
 
 	archiverEngine, err := engine.New(
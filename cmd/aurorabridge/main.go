@@ -75,6 +75,20 @@ var (
 		Envar("ELECTION_ZK_SERVERS").
 		Strings()
 
+	electionBackend = app.Flag(
+		"election-backend",
+		"Election backend, either zookeeper or etcd "+
+			"(election.backend override) (set $ELECTION_BACKEND to override)").
+		Envar("ELECTION_BACKEND").
+		String()
+
+	electionEtcdEndpoints = app.Flag(
+		"election-etcd-endpoint",
+		"Election etcd endpoints. Specify multiple times for multiple endpoints "+
+			"(election.etcd_endpoints override) (set $ELECTION_ETCD_ENDPOINTS to override)").
+		Envar("ELECTION_ETCD_ENDPOINTS").
+		Strings()
+
 	datacenter = app.Flag(
 		"datacenter", "Datacenter name").
 		Default("").
@@ -147,6 +161,14 @@ func main() {
 		cfg.Election.ZKServers = *electionZkServers
 	}
 
+	if *electionBackend != "" {
+		cfg.Election.Backend = *electionBackend
+	}
+
+	if len(*electionEtcdEndpoints) > 0 {
+		cfg.Election.EtcdEndpoints = *electionEtcdEndpoints
+	}
+
 	if *httpPort != 0 {
 		cfg.HTTPPort = *httpPort
 	}
@@ -196,11 +218,10 @@ func main() {
 		cfg.GRPCPort, // dummy grpc port for aurora bridge
 		mux)
 
-	discovery, err := leader.NewZkServiceDiscovery(
-		cfg.Election.ZKServers, cfg.Election.Root)
+	discovery, err := leader.NewServiceDiscovery(cfg.Election)
 	if err != nil {
 		log.WithError(err).
-			Fatal("Could not create zk service discovery")
+			Fatal("Could not create service discovery")
 	}
 
 	clientRecvOption := grpc.ClientMaxRecvMsgSize(cfg.EventPublisher.GRPCMsgSize)
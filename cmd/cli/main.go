@@ -20,8 +20,10 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	pt "github.com/uber/peloton/.gen/peloton/api/v0/task"
+	pbstateless "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless"
 
 	pc "github.com/uber/peloton/pkg/cli"
 	"github.com/uber/peloton/pkg/cli/config"
@@ -94,6 +96,14 @@ var (
 		Envar("ZK_SERVERS").
 		Strings()
 
+	etcdEndpoints = app.Flag(
+		"etcd-endpoints",
+		"etcd endpoints used for peloton service discovery instead of zookeeper. "+
+			"Specify multiple times for multiple endpoints"+
+			"(set $ETCD_ENDPOINTS to override with '\n' as delimiter)").
+		Envar("ETCD_ENDPOINTS").
+		Strings()
+
 	zkRoot = app.Flag(
 		"zkroot",
 		"zookeeper root path for peloton service discovery(set $ZK_ROOT to override)").
@@ -194,6 +204,9 @@ var (
 
 	jobGetActiveJobs = job.Command("active-list", "get a list of active jobs")
 
+	jobRetryFailed     = job.Command("retry-failed", "restart the failed and lost instances of a terminal batch job")
+	jobRetryFailedName = jobRetryFailed.Arg("job", "job identifier").Required().String()
+
 	// Top level job command for stateless jobs
 	stateless = job.Command("stateless", "manage stateless jobs")
 
@@ -233,6 +246,13 @@ var (
 	workflowResumeOpaqueData = workflowResume.Flag("opaque-data",
 		"opaque data provided by the user").Default("").String()
 
+	workflowPromote              = workflow.Command("promote", "promote the canary phase of a workflow")
+	workflowPromoteName          = workflowPromote.Arg("job", "job identifier").Required().String()
+	workflowPromoteEntityVersion = workflowPromote.Arg("entityVersion",
+		"entity version for concurrency control").Required().String()
+	workflowPromoteOpaqueData = workflowPromote.Flag("opaque-data",
+		"opaque data provided by the user").Default("").String()
+
 	workflowAbort              = workflow.Command("abort", "abort a workflow")
 	workflowAbortName          = workflowAbort.Arg("job", "job identifier").Required().String()
 	workflowAbortEntityVersion = workflowAbort.Arg("entityVersion",
@@ -294,6 +314,18 @@ var (
 		"start the update with best effort in-place update").Default("false").Bool()
 	statelessStartPods = statelessReplace.Flag("start-pods",
 		"start pods affected by the update if they are not running").Default("false").Bool()
+	statelessReplaceCanaryInstances = statelessReplace.Flag("canary-instances",
+		"number of instances to update in the canary phase of the update").Default("0").Uint32()
+	statelessReplaceCanaryPercentage = statelessReplace.Flag("canary-percentage",
+		"percentage of instances to update in the canary phase of the update, "+
+			"takes precedence over canary-instances").Default("0").Float64()
+	statelessReplaceCanaryBakePeriod = statelessReplace.Flag("canary-bake-period",
+		"time to wait after the canary instances are updated before checking their health").Default("0s").Duration()
+	statelessReplaceCanaryAutoPromote = statelessReplace.Flag("canary-auto-promote",
+		"continue the update automatically if the canary succeeds, "+
+			"instead of waiting for workflow promote").Default("false").Bool()
+	statelessReplaceCanaryMaxFailures = statelessReplace.Flag("canary-max-failures",
+		"maximum number of canary instance failures tolerable before rolling back the update").Default("0").Uint32()
 
 	statelessListJobs = stateless.Command("list", "list all jobs")
 
@@ -342,6 +374,16 @@ var (
 	statelessRestartInPlace   = statelessRestartJob.Flag("in-place",
 		"start the restart with best effort in-place restart").Default("false").Bool()
 
+	statelessRotateSecret          = stateless.Command("rotate-secret", "rotate a secret and restart the jobs mounting it")
+	statelessRotateSecretRef       = statelessRotateSecret.Arg("secret", "secret ID, or <store>:<path> for secrets from an external secret store").Required().String()
+	statelessRotateSecretData      = statelessRotateSecret.Flag("secret-data", "new secret data string of a secret stored by Peloton").Default("").String()
+	statelessRotateSecretVersion   = statelessRotateSecret.Flag("secret-version", "version of a secret from an external secret store to pin the jobs to").Default("").String()
+	statelessRotateSecretBatchSize = statelessRotateSecret.Flag("batch-size", "batch size for the restart").Default("0").Uint32()
+
+	statelessUsage          = stateless.Command("usage", "get the current resource usage of a job, or of all the jobs in a resource pool and its descendant pools")
+	statelessUsageJobID     = statelessUsage.Arg("job", "job identifier").String()
+	statelessUsageRespoolID = statelessUsage.Flag("respool-id", "resource pool identifier").Default("").String()
+
 	statelessStop              = stateless.Command("stop", "stop all pods in a job")
 	statelessStopJobID         = statelessStop.Arg("job", "job identifier").Required().String()
 	statelessStopEntityVersion = statelessStop.Arg("entityVersion",
@@ -365,6 +407,49 @@ var (
 		"and the job cannot be re-created (with same uuid) till the delete is complete. "+
 		"USE WITH CAUTION!").Default("false").Short('f').Bool()
 
+	// Top level job command for batch jobs
+	batchJob = job.Command("batch", "manage batch jobs")
+
+	batchCreate            = batchJob.Command("create", "create batch job")
+	batchCreateResPoolPath = batchCreate.Arg("respool", "complete path of the "+
+		"resource pool starting from the root").Required().String()
+	batchCreateSpec = batchCreate.Arg("spec", "YAML job specification").Required().ExistingFile()
+	batchCreateID   = batchCreate.Flag("jobID", "optional job identifier, must be UUID format").Short('i').String()
+
+	batchGet            = batchJob.Command("get", "get batch job")
+	batchGetJobID       = batchGet.Arg("job", "job identifier").Required().String()
+	batchGetSummaryOnly = batchGet.Flag("summaryonly", "only return the job summary").Default("false").Bool()
+
+	batchQuery            = batchJob.Command("query", "query batch jobs by mesos label / respool")
+	batchQueryLabels      = batchQuery.Flag("labels", "labels").Default("").Short('l').String()
+	batchQueryRespoolPath = batchQuery.Flag("respool", "respool path").Default("").Short('r').String()
+	batchQueryKeywords    = batchQuery.Flag("keywords", "keywords").Default("").Short('k').String()
+	batchQueryStates      = batchQuery.Flag("states", "job states").Default("").Short('s').String()
+	batchQueryOwner       = batchQuery.Flag("owner", "job owner").Default("").String()
+	batchQueryName        = batchQuery.Flag("name", "job name").Default("").String()
+	batchQueryTimeRange   = batchQuery.Flag("timerange", "query jobs created within last d days").Short('d').Default("0").Uint32()
+	batchQueryLimit       = batchQuery.Flag("limit", "maximum number of jobs to return").Default("100").Short('n').Uint32()
+	batchQueryMaxLimit    = batchQuery.Flag("total", "total number of jobs to query").Default("100").Short('q').Uint32()
+	batchQueryOffset      = batchQuery.Flag("offset", "offset").Default("0").Short('o').Uint32()
+	batchQuerySortBy      = batchQuery.Flag("sort", "sort by property").Default("creation_time").Short('p').String()
+	batchQuerySortOrder   = batchQuery.Flag("sortorder", "sort order (ASC or DESC)").Default("DESC").Short('a').String()
+
+	batchKill              = batchJob.Command("kill", "kill all pods in a batch job")
+	batchKillJobID         = batchKill.Arg("job", "job identifier").Required().String()
+	batchKillEntityVersion = batchKill.Arg("entityVersion",
+		"entity version for concurrency control").Required().String()
+
+	batchDelete              = batchJob.Command("delete", "delete a terminal batch job")
+	batchDeleteJobID         = batchDelete.Arg("job", "job identifier").Required().String()
+	batchDeleteEntityVersion = batchDelete.Arg("entityVersion",
+		"entity version for concurrency control").Required().String()
+
+	batchRestartFailed = batchJob.Command("restart-failed",
+		"restart the failed and lost pods of a terminal batch job")
+	batchRestartFailedJobID         = batchRestartFailed.Arg("job", "job identifier").Required().String()
+	batchRestartFailedEntityVersion = batchRestartFailed.Arg("entityVersion",
+		"entity version for concurrency control").Required().String()
+
 	// Top level pod command
 	pod = app.Command("pod", "CLI reflects pod(s) actions, such as get pod details, create/restart/update a pod...")
 
@@ -391,6 +476,16 @@ var (
 	podLogsGetFileName = podLogsGet.Flag("filename", "log filename to browse").Default("stdout").Short('f').String()
 	podLogsGetPodName  = podLogsGet.Arg("name", "pod name").Required().String()
 	podLogsGetPodID    = podLogsGet.Flag("id", "pod identifier").Short('p').String()
+	podLogsGetOffset   = podLogsGet.Flag("offset", "byte offset to start reading stdout/stderr from, negative to read from the end").Default("0").Int64()
+	podLogsGetLength   = podLogsGet.Flag("length", "maximum number of bytes of stdout/stderr to read, 0 for no limit").Default("0").Int64()
+	podLogsGetFollow   = podLogsGet.Flag("follow", "keep streaming stdout/stderr as new output is written").Bool()
+
+	podExec            = pod.Command("exec", "run a command inside a running pod, e.g. peloton pod exec <pod> -- ls -l")
+	podExecPodName     = podExec.Arg("name", "pod name").Required().String()
+	podExecCommand     = podExec.Arg("command", "command to run, along with its arguments").Required().Strings()
+	podExecPodID       = podExec.Flag("id", "pod identifier").Short('p').String()
+	podExecTTY         = podExec.Flag("tty", "allocate a TTY for the command").Short('t').Bool()
+	podExecInteractive = podExec.Flag("interactive", "forward the standard input to the command").Short('i').Bool()
 
 	podRestart     = pod.Command("restart", "restart a pod")
 	podRestartName = podRestart.Arg("name", "pod name").Required().String()
@@ -402,6 +497,7 @@ var (
 	podGetPodName    = podGet.Arg("name", "pod name").Required().String()
 	podGetStatusOnly = podGet.Flag("statusonly", "get pod status only(not spec)").Default("false").Bool()
 	podGetLimit      = podGet.Flag("limit", "get a subset of the previous pod runs (0 implies to get all the runs)").Default("0").Uint32()
+	podGetUsage      = podGet.Flag("usage", "get the recent resource usage of the pod if it is running").Default("false").Bool()
 
 	podDeleteEvents        = pod.Command("delete-events", "delete pod events")
 	podDeleteEventsPodName = podDeleteEvents.Arg("name", "pod name").Required().String()
@@ -632,6 +728,12 @@ var (
 	// command to disable the kill tasks request to mesos master
 	disableKillTasks = hostmgr.Command("disable-kill-tasks", "disable the kill task request to mesos master")
 
+	// command to reconcile tasks with mesos master on demand
+	hostmgrReconcile            = hostmgr.Command("reconcile", "reconcile tasks with mesos master and report the tasks on which peloton and mesos disagree")
+	hostmgrReconcileJobID       = hostmgrReconcile.Flag("job", "reconcile the tasks of this job only").Short('j').Default("").String()
+	hostmgrReconcileHostname    = hostmgrReconcile.Flag("host", "reconcile the tasks on this host only").Default("").String()
+	hostmgrReconcileKillOrphans = hostmgrReconcile.Flag("kill-orphans", "kill tasks running in mesos which are unknown to peloton").Default("false").Bool()
+
 	// Top level admin command
 	admin = app.Command("admin", "administrative APIs")
 	// command for locking down components
@@ -644,6 +746,37 @@ var (
 	unlockComponents = unlock.Arg("components", "components to remove lockdown. "+
 		"Now support GoalStateEngine, Read, Write").
 		Enums("GoalStateEngine", "Read", "Write", "Kill")
+	// command for exporting the cluster state
+	adminExport = admin.Command("export",
+		"export resource pools, host pools and active jobs to a bundle")
+	adminExportFile = adminExport.Arg("file", "bundle file to write").
+			Required().String()
+	adminExportFormat = adminExport.Flag("format", "bundle format").
+				Default("yaml").Enum("yaml", "yml", "json")
+	adminExportSecretsKeyFile = adminExport.Flag("secrets-key-file",
+		"file holding the base64 encoded 32 byte key to encrypt secret "+
+			"data with, required if any job has secrets").
+		Default("").String()
+	// command for importing a cluster state bundle
+	adminImport = admin.Command("import",
+		"recreate resource pools, host pools and jobs from a bundle")
+	adminImportFile = adminImport.Arg("file", "bundle file to read").
+			Required().ExistingFile()
+	adminImportDryRun = adminImport.Flag("dry-run",
+		"print the import plan without changing the cluster").
+		Default("false").Bool()
+	adminImportOnConflict = adminImport.Flag("on-conflict",
+		"action to take when an entity already exists").
+		Default(pc.ConflictPolicySkip).
+		Enum(pc.ConflictPolicySkip, pc.ConflictPolicyFail,
+			pc.ConflictPolicyOverwrite)
+	adminImportKeepJobIDs = adminImport.Flag("keep-job-ids",
+		"create jobs with their IDs from the bundle instead of new ones").
+		Default("false").Bool()
+	adminImportSecretsKeyFile = adminImport.Flag("secrets-key-file",
+		"file holding the base64 encoded key the secret data was "+
+			"exported with").
+		Default("").String()
 
 	// Top level hostcache commands
 	hostcache     = hostmgr.Command("hostcache", "manage hostcache")
@@ -791,6 +924,28 @@ func taskRangeListFlag(s kingpin.Settings) (target *[]*pt.InstanceRange) {
 	return
 }
 
+// canarySpec returns the canary spec of a stateless update,
+// or nil if no canary instances are requested
+func canarySpec(
+	instanceCount uint32,
+	instancePercentage float64,
+	bakePeriod time.Duration,
+	autoPromote bool,
+	maxFailures uint32,
+) *pbstateless.CanarySpec {
+	if instanceCount == 0 && instancePercentage == 0 {
+		return nil
+	}
+
+	return &pbstateless.CanarySpec{
+		InstanceCount:                instanceCount,
+		InstancePercentage:           instancePercentage,
+		BakePeriodSeconds:            uint32(bakePeriod.Seconds()),
+		AutoPromote:                  autoPromote,
+		MaxTolerableInstanceFailures: maxFailures,
+	}
+}
+
 func main() {
 	app.Version(version)
 	app.HelpFlag.Short('h')
@@ -819,7 +974,9 @@ func main() {
 		zkServers = &zkInfoSlice
 	}
 	var discovery leader.Discovery
-	if len(*zkServers) > 0 {
+	if len(*etcdEndpoints) > 0 {
+		discovery, err = leader.NewEtcdServiceDiscovery(*etcdEndpoints, *zkRoot)
+	} else if len(*zkServers) > 0 {
 		discovery, err = leader.NewZkServiceDiscovery(*zkServers, *zkRoot)
 	} else {
 		discovery, err = leader.NewStaticServiceDiscovery(*jobMgrURL, *resMgrURL, *hostMgrURL)
@@ -878,6 +1035,8 @@ func main() {
 		err = client.JobGetCacheAction(*jobGetCacheName)
 	case jobGetActiveJobs.FullCommand():
 		err = client.JobGetActiveJobsAction()
+	case jobRetryFailed.FullCommand():
+		err = client.JobRetryFailedAction(*jobRetryFailedName)
 	case taskGet.FullCommand():
 		err = client.TaskGetAction(*taskGetJobName, *taskGetInstanceID)
 	case taskGetCache.FullCommand():
@@ -963,6 +1122,8 @@ func main() {
 		err = client.HostsGetAction(*getHostsCPU, *getHostsGPU, *getHostsCmpLess, *getHostsHostnames)
 	case disableKillTasks.FullCommand():
 		err = client.DisableKillTasksAction()
+	case hostmgrReconcile.FullCommand():
+		err = client.HostMgrReconcileAction(*hostmgrReconcileJobID, *hostmgrReconcileHostname, *hostmgrReconcileKillOrphans)
 	case podGetEvents.FullCommand():
 		err = client.PodGetEventsAction(*podGetEventsJobName, *podGetEventsInstanceID, *podGetEventsRunID, *podGetEventsLimit)
 	case podGetCache.FullCommand():
@@ -997,6 +1158,12 @@ func main() {
 			*workflowResumeEntityVersion,
 			*workflowResumeOpaqueData,
 		)
+	case workflowPromote.FullCommand():
+		err = client.StatelessWorkflowPromoteAction(
+			*workflowPromoteName,
+			*workflowPromoteEntityVersion,
+			*workflowPromoteOpaqueData,
+		)
 	case workflowAbort.FullCommand():
 		err = client.StatelessWorkflowAbortAction(
 			*workflowAbortName,
@@ -1020,6 +1187,13 @@ func main() {
 			*statelessReplaceOpaqueData,
 			*statelessReplaceInPlace,
 			*statelessStartPods,
+			canarySpec(
+				*statelessReplaceCanaryInstances,
+				*statelessReplaceCanaryPercentage,
+				*statelessReplaceCanaryBakePeriod,
+				*statelessReplaceCanaryAutoPromote,
+				*statelessReplaceCanaryMaxFailures,
+			),
 		)
 	case statelessReplaceJobDiff.FullCommand():
 		err = client.StatelessReplaceJobDiffAction(
@@ -1052,6 +1226,18 @@ func main() {
 			*statelessRestartOpaqueData,
 			*statelessRestartInPlace,
 		)
+	case statelessRotateSecret.FullCommand():
+		err = client.StatelessRotateSecretAction(
+			*statelessRotateSecretRef,
+			[]byte(*statelessRotateSecretData),
+			*statelessRotateSecretVersion,
+			*statelessRotateSecretBatchSize,
+		)
+	case statelessUsage.FullCommand():
+		err = client.StatelessGetJobUsageAction(
+			*statelessUsageJobID,
+			*statelessUsageRespoolID,
+		)
 	case statelessListUpdates.FullCommand():
 		err = client.StatelessListUpdatesAction(
 			*statelessListUpdatesName,
@@ -1062,13 +1248,38 @@ func main() {
 			*workflowEventsJob,
 			*workflowEventsInstance)
 	case podLogsGet.FullCommand():
-		err = client.PodLogsGetAction(*podLogsGetFileName, *podLogsGetPodName, *podLogsGetPodID)
+		switch *podLogsGetFileName {
+		case "stdout", "stderr":
+			err = client.PodLogsStreamAction(
+				*podLogsGetFileName,
+				*podLogsGetPodName,
+				*podLogsGetPodID,
+				*podLogsGetOffset,
+				*podLogsGetLength,
+				*podLogsGetFollow,
+			)
+		default:
+			err = client.PodLogsGetAction(*podLogsGetFileName, *podLogsGetPodName, *podLogsGetPodID)
+		}
+	case podExec.FullCommand():
+		var exitCode int32
+		exitCode, err = client.PodExecAction(
+			*podExecPodName,
+			*podExecPodID,
+			*podExecCommand,
+			*podExecTTY,
+			*podExecInteractive,
+		)
+		if err == nil && exitCode != 0 {
+			client.Cleanup()
+			os.Exit(int(exitCode))
+		}
 	case podRestart.FullCommand():
 		err = client.PodRestartAction(*podRestartName)
 	case podStop.FullCommand():
 		err = client.PodStopAction(*podStopPodName)
 	case podGet.FullCommand():
-		err = client.PodGetAction(*podGetPodName, *podGetStatusOnly, *podGetLimit)
+		err = client.PodGetAction(*podGetPodName, *podGetStatusOnly, *podGetLimit, *podGetUsage)
 	case podDeleteEvents.FullCommand():
 		err = client.PodDeleteEvents(*podDeleteEventsPodName, *podDeleteEventsPodID)
 	case statelessGet.FullCommand():
@@ -1092,6 +1303,25 @@ func main() {
 			*statelessDeleteEntityVersion,
 			*statelessDeleteForce,
 		)
+	case batchCreate.FullCommand():
+		err = client.BatchCreateAction(
+			*batchCreateID,
+			*batchCreateResPoolPath,
+			*batchCreateSpec,
+		)
+	case batchGet.FullCommand():
+		err = client.BatchGetAction(*batchGetJobID, *batchGetSummaryOnly)
+	case batchQuery.FullCommand():
+		err = client.BatchQueryAction(*batchQueryLabels, *batchQueryRespoolPath, *batchQueryKeywords, *batchQueryStates, *batchQueryOwner, *batchQueryName, *batchQueryTimeRange, *batchQueryLimit, *batchQueryMaxLimit, *batchQueryOffset, *batchQuerySortBy, *batchQuerySortOrder)
+	case batchKill.FullCommand():
+		err = client.BatchKillAction(*batchKillJobID, *batchKillEntityVersion)
+	case batchDelete.FullCommand():
+		err = client.BatchDeleteAction(*batchDeleteJobID, *batchDeleteEntityVersion)
+	case batchRestartFailed.FullCommand():
+		err = client.BatchRestartFailedAction(
+			*batchRestartFailedJobID,
+			*batchRestartFailedEntityVersion,
+		)
 	case watchPod.FullCommand():
 		err = client.WatchPod(*watchPodJobID, *watchPodPodNames, *watchLabels)
 	case watchCancel.FullCommand():
@@ -1100,6 +1330,20 @@ func main() {
 		err = client.LockComponents(*lockComponents)
 	case unlock.FullCommand():
 		err = client.UnlockComponents(*unlockComponents)
+	case adminExport.FullCommand():
+		err = client.AdminExportAction(
+			*adminExportFile,
+			*adminExportFormat,
+			*adminExportSecretsKeyFile)
+	case adminImport.FullCommand():
+		err = client.AdminImportAction(
+			*adminImportFile,
+			pc.ClusterImportOptions{
+				DryRun:         *adminImportDryRun,
+				OnConflict:     *adminImportOnConflict,
+				KeepJobIDs:     *adminImportKeepJobIDs,
+				SecretsKeyFile: *adminImportSecretsKeyFile,
+			})
 	case hostpoolList.FullCommand():
 		err = client.HostPoolList()
 	case hostpoolListHosts.FullCommand():
@@ -37,8 +37,10 @@ import (
 	"github.com/uber/peloton/pkg/hostmgr"
 	bin_packing "github.com/uber/peloton/pkg/hostmgr/binpacking"
 	"github.com/uber/peloton/pkg/hostmgr/host"
+	"github.com/uber/peloton/pkg/hostmgr/hosthealth"
 	"github.com/uber/peloton/pkg/hostmgr/hostpool/manager"
 	"github.com/uber/peloton/pkg/hostmgr/hostsvc"
+	"github.com/uber/peloton/pkg/hostmgr/logs"
 	"github.com/uber/peloton/pkg/hostmgr/mesos"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/peer"
@@ -51,6 +53,8 @@ import (
 	"github.com/uber/peloton/pkg/hostmgr/p2k/plugins/k8s"
 	"github.com/uber/peloton/pkg/hostmgr/p2k/podeventmanager"
 	"github.com/uber/peloton/pkg/hostmgr/p2k/scalar"
+	"github.com/uber/peloton/pkg/hostmgr/podexec"
+	"github.com/uber/peloton/pkg/hostmgr/podusage"
 	"github.com/uber/peloton/pkg/hostmgr/queue"
 	"github.com/uber/peloton/pkg/hostmgr/reconcile"
 	"github.com/uber/peloton/pkg/hostmgr/watchevent"
@@ -61,6 +65,7 @@ import (
 	"github.com/uber/peloton/pkg/storage/stores"
 
 	log "github.com/sirupsen/logrus"
+	uatomic "github.com/uber-go/atomic"
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
@@ -105,6 +110,20 @@ var (
 		Envar("ELECTION_ZK_SERVERS").
 		Strings()
 
+	electionBackend = app.Flag(
+		"election-backend",
+		"Election backend, either zookeeper or etcd "+
+			"(election.backend override) (set $ELECTION_BACKEND to override)").
+		Envar("ELECTION_BACKEND").
+		String()
+
+	electionEtcdEndpoints = app.Flag(
+		"election-etcd-endpoint",
+		"Election etcd endpoints. Specify multiple times for multiple endpoints "+
+			"(election.etcd_endpoints override) (set $ELECTION_ETCD_ENDPOINTS to override)").
+		Envar("ELECTION_ETCD_ENDPOINTS").
+		Strings()
+
 	httpPort = app.Flag(
 		"http-port", "Host manager HTTP port (hostmgr.http_port override) "+
 			"(set $HTTP_PORT to override)").
@@ -279,6 +298,14 @@ func main() {
 		cfg.Election.ZKServers = *electionZkServers
 	}
 
+	if *electionBackend != "" {
+		cfg.Election.Backend = *electionBackend
+	}
+
+	if len(*electionEtcdEndpoints) > 0 {
+		cfg.Election.EtcdEndpoints = *electionEtcdEndpoints
+	}
+
 	if !*useCassandra {
 		cfg.Storage.UseCassandra = false
 	}
@@ -552,6 +579,7 @@ func main() {
 	// Declare background works
 	reconciler := reconcile.NewTaskReconciler(
 		schedulerClient,
+		masterOperatorClient,
 		rootScope,
 		driver,
 		activeJobsOps,
@@ -657,6 +685,31 @@ func main() {
 		offer.GetEventHandler().SetHostPoolManager(hostPoolManager)
 	}
 
+	// Construct host health tracker if quarantine of unhealthy hosts
+	// is enabled.
+	var hostHealthTracker hosthealth.Tracker
+	if cfg.HostManager.HostHealth.Enabled {
+		hostHealthTracker = hosthealth.NewTracker(
+			cfg.HostManager.HostHealth,
+			rootScope)
+		err = backgroundManager.RegisterWorks(
+			background.Work{
+				Name: "hostHealthReleaser",
+				Func: func(_ *uatomic.Bool) {
+					hostHealthTracker.ReleaseExpiredQuarantines(time.Now())
+				},
+				Period: cfg.HostManager.HostHealth.ReleaseCheckPeriod,
+			},
+		)
+		if err != nil {
+			log.WithError(err).
+				Fatal("Cannot register host health releaser background worker.")
+		}
+
+		// Set host health tracker in offer pool event handler.
+		offer.GetEventHandler().SetHostHealthTracker(hostHealthTracker)
+	}
+
 	maintenanceQueue := queue.NewMaintenanceQueue()
 
 	plugin := plugins.NewNoopPlugin()
@@ -670,6 +723,7 @@ func main() {
 		var err error
 		plugin, err = plugins.NewK8sPlugin(
 			cfg.K8s.Kubeconfig,
+			cfg.K8s.RevocableResources,
 			podEventCh,
 			hostEventCh,
 		)
@@ -679,7 +733,29 @@ func main() {
 	}
 
 	// Create host cache instance.
-	hostCache = hostcache.New(hostEventCh, podEventCh, plugin)
+	hostCache = hostcache.New(
+		hostEventCh,
+		podEventCh,
+		plugin,
+		hostHealthTracker,
+		cfg.HostManager.PodHoldTimeout,
+	)
+
+	// Release hosts held for pods whose hold has expired, so that the pods
+	// can be placed on any host.
+	err = backgroundManager.RegisterWorks(
+		background.Work{
+			Name: "hostCacheHeldHostPruner",
+			Func: func(_ *uatomic.Bool) {
+				hostCache.ResetExpiredHeldHostSummaries(time.Now())
+			},
+			Period: cfg.HostManager.HeldHostPruningPeriodSec,
+		},
+	)
+	if err != nil {
+		log.WithError(err).
+			Fatal("Cannot register host cache held host pruner background worker.")
+	}
 
 	pem := podeventmanager.New(
 		dispatcher,
@@ -697,6 +773,48 @@ func main() {
 		pem,
 	)
 
+	// Stream pod logs from the K8s pod log API if k8s is enabled,
+	// otherwise from the Mesos agent files API.
+	logBackend := logs.NewMesosBackend(cfg.HostManager.PodLogs, driver)
+	if cfg.K8s.Enabled {
+		logBackend, err = logs.NewK8sBackend(
+			cfg.HostManager.PodLogs,
+			cfg.K8s.Kubeconfig,
+		)
+		if err != nil {
+			log.WithError(err).Fatal("Cannot init pod log backend.")
+		}
+	}
+
+	// Run commands inside pods using the K8s pod exec API if k8s is
+	// enabled, otherwise using nested containers of the Mesos agents.
+	execBackend := podexec.NewMesosBackend(cfg.HostManager.PodExec, driver)
+	if cfg.K8s.Enabled {
+		execBackend, err = podexec.NewK8sBackend(cfg.K8s.Kubeconfig)
+		if err != nil {
+			log.WithError(err).Fatal("Cannot init pod exec backend.")
+		}
+	}
+
+	// Collect the resource usage of pods from the K8s metrics API if k8s is
+	// enabled, otherwise from the executor statistics of the Mesos agents.
+	usageBackend := podusage.NewMesosBackend(cfg.HostManager.PodUsage, driver)
+	if cfg.K8s.Enabled {
+		usageBackend, err = podusage.NewK8sBackend(cfg.K8s.Kubeconfig)
+		if err != nil {
+			log.WithError(err).Fatal("Cannot init pod usage backend.")
+		}
+	}
+	usageCollector := podusage.NewCollector(
+		cfg.HostManager.PodUsage,
+		usageBackend,
+		rootScope,
+	)
+	if err := usageCollector.Register(backgroundManager); err != nil {
+		log.WithError(err).
+			Fatal("Cannot register pod usage collector background worker.")
+	}
+
 	// Create new hostmgr internal service handler.
 	serviceHandler := hostmgr.NewServiceHandler(
 		dispatcher,
@@ -712,6 +830,10 @@ func main() {
 		maintenanceHostInfoMap,
 		watchProcessor,
 		hostPoolManager,
+		reconciler,
+		logBackend,
+		execBackend,
+		usageCollector,
 	)
 
 	hostsvc.InitServiceHandler(
@@ -721,6 +843,7 @@ func main() {
 		maintenanceQueue,
 		maintenanceHostInfoMap,
 		hostPoolManager,
+		hostHealthTracker,
 	)
 
 	recoveryHandler := hostmgr.NewRecoveryHandler(
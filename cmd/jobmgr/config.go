@@ -16,11 +16,13 @@ package main
 
 import (
 	"github.com/uber/peloton/pkg/auth"
+	"github.com/uber/peloton/pkg/common/audit"
 	"github.com/uber/peloton/pkg/common/health"
 	"github.com/uber/peloton/pkg/common/leader"
 	"github.com/uber/peloton/pkg/common/logging"
 	"github.com/uber/peloton/pkg/common/metrics"
 	"github.com/uber/peloton/pkg/jobmgr"
+	"github.com/uber/peloton/pkg/jobmgr/secret"
 	"github.com/uber/peloton/pkg/middleware/inbound"
 	storage "github.com/uber/peloton/pkg/storage/config"
 )
@@ -38,4 +40,10 @@ type Config struct {
 	// APILock defines which APIs are read/write APIs,
 	// so when lockdown is requested, the correct APIs are locked.
 	APILock inbound.APILockConfig `yaml:"api_lock"`
+	// Audit defines where the privileged operations, such as running
+	// commands inside pods, are recorded.
+	Audit audit.Config `yaml:"audit"`
+	// Secrets configures the external secret stores secret volumes
+	// can reference in addition to the secrets stored by Peloton.
+	Secrets secret.Config `yaml:"secrets"`
 }
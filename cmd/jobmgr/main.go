@@ -15,17 +15,20 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
 	"github.com/uber/peloton/pkg/auth"
 	auth_impl "github.com/uber/peloton/pkg/auth/impl"
 	"github.com/uber/peloton/pkg/common"
 	"github.com/uber/peloton/pkg/common/api"
+	"github.com/uber/peloton/pkg/common/audit"
 	"github.com/uber/peloton/pkg/common/background"
 	"github.com/uber/peloton/pkg/common/buildversion"
 	"github.com/uber/peloton/pkg/common/config"
@@ -37,13 +40,18 @@ import (
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/peer"
 	"github.com/uber/peloton/pkg/jobmgr"
 	"github.com/uber/peloton/pkg/jobmgr/adminsvc"
+	"github.com/uber/peloton/pkg/jobmgr/autoscaler"
 	"github.com/uber/peloton/pkg/jobmgr/cached"
+	"github.com/uber/peloton/pkg/jobmgr/dashboard"
 	"github.com/uber/peloton/pkg/jobmgr/goalstate"
 	"github.com/uber/peloton/pkg/jobmgr/jobsvc"
+	"github.com/uber/peloton/pkg/jobmgr/jobsvc/batch"
 	"github.com/uber/peloton/pkg/jobmgr/jobsvc/private"
 	"github.com/uber/peloton/pkg/jobmgr/jobsvc/stateless"
 	"github.com/uber/peloton/pkg/jobmgr/logmanager"
 	"github.com/uber/peloton/pkg/jobmgr/podsvc"
+	"github.com/uber/peloton/pkg/jobmgr/retention"
+	"github.com/uber/peloton/pkg/jobmgr/secret"
 	"github.com/uber/peloton/pkg/jobmgr/task/activermtask"
 	"github.com/uber/peloton/pkg/jobmgr/task/deadline"
 	"github.com/uber/peloton/pkg/jobmgr/task/event"
@@ -65,7 +73,6 @@ import (
 	"github.com/uber-go/atomic"
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/yarpc"
-	"go.uber.org/yarpc/api/transport"
 	"golang.org/x/time/rate"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
@@ -111,6 +118,20 @@ var (
 		Envar("ELECTION_ZK_SERVERS").
 		Strings()
 
+	electionBackend = app.Flag(
+		"election-backend",
+		"Election backend, either zookeeper or etcd "+
+			"(election.backend override) (set $ELECTION_BACKEND to override)").
+		Envar("ELECTION_BACKEND").
+		String()
+
+	electionEtcdEndpoints = app.Flag(
+		"election-etcd-endpoint",
+		"Election etcd endpoints. Specify multiple times for multiple endpoints "+
+			"(election.etcd_endpoints override) (set $ELECTION_ETCD_ENDPOINTS to override)").
+		Envar("ELECTION_ETCD_ENDPOINTS").
+		Strings()
+
 	httpPort = app.Flag(
 		"http-port", "Job manager HTTP port (jobmgr.http_port override) "+
 			"(set $PORT to override)").
@@ -185,6 +206,13 @@ var (
 		Envar("ENABLE_SECRETS").
 		Bool()
 
+	rebuildJobQueryIndex = app.Flag(
+		"rebuild-job-query-index",
+		"add all the jobs to the job query index at startup").
+		Default("false").
+		Envar("REBUILD_JOB_QUERY_INDEX").
+		Bool()
+
 	// TODO: remove this flag and all related code after
 	// storage layer can figure out recovery
 	jobType = app.Flag(
@@ -296,6 +324,14 @@ func main() {
 		cfg.Election.ZKServers = *electionZkServers
 	}
 
+	if *electionBackend != "" {
+		cfg.Election.Backend = *electionBackend
+	}
+
+	if len(*electionEtcdEndpoints) > 0 {
+		cfg.Election.EtcdEndpoints = *electionEtcdEndpoints
+	}
+
 	if *placementDequeLimit != 0 {
 		cfg.JobManager.Placement.PlacementDequeueLimit = *placementDequeLimit
 	}
@@ -403,6 +439,14 @@ func main() {
 		log.WithError(ormErr).Fatal("Failed to create ORM store for Cassandra")
 	}
 
+	// Backfill the job query index for jobs created before it existed
+	if *rebuildJobQueryIndex {
+		if err := ormobjects.NewJobIndexOps(ormStore).RebuildQueryIndex(
+			context.Background()); err != nil {
+			log.WithError(err).Fatal("Failed to rebuild job query index")
+		}
+	}
+
 	// Create both HTTP and GRPC inbounds
 	inbounds := rpc.NewInbounds(
 		cfg.JobManager.HTTPPort,
@@ -446,14 +490,7 @@ func main() {
 
 	hostmgrOutbound := t.NewOutbound(hostmgrPeerChooser)
 
-	outbounds := yarpc.Outbounds{
-		common.PelotonResourceManager: transport.Outbounds{
-			Unary: resmgrOutbound,
-		},
-		common.PelotonHostManager: transport.Outbounds{
-			Unary: hostmgrOutbound,
-		},
-	}
+	outbounds := jobmgr.NewOutbounds(resmgrOutbound, hostmgrOutbound)
 
 	securityManager, err := auth_impl.CreateNewSecurityManager(&cfg.Auth)
 	if err != nil {
@@ -519,6 +556,14 @@ func main() {
 		cfg.JobManager.Watch,
 	)
 
+	dashboardAggregator := dashboard.NewAggregator(
+		respool.NewResourceManagerYARPCClient(
+			dispatcher.ClientConfig(common.PelotonResourceManager),
+		),
+		dashboard.NewMetrics(rootScope),
+		&cfg.JobManager.Dashboard,
+	)
+
 	jobFactory := cached.InitJobFactory(
 		store, // store implements JobStore
 		store, // store implements TaskStore
@@ -526,7 +571,10 @@ func main() {
 		store, // store implements VolumeStore
 		ormStore,
 		rootScope,
-		[]cached.JobTaskListener{watchsvc.NewWatchListener(watchProcessor)},
+		[]cached.JobTaskListener{
+			watchsvc.NewWatchListener(watchProcessor),
+			dashboardAggregator,
+		},
 	)
 
 	// Register WorkflowProgressCheck
@@ -540,12 +588,21 @@ func main() {
 			Fatal("fail to register workflowCheck in backgroundManager")
 	}
 
+	secretProvider, err := secret.NewProvider(
+		cfg.Secrets,
+		ormobjects.NewSecretInfoOps(ormStore),
+	)
+	if err != nil {
+		log.WithError(err).Fatal("Cannot init secret provider")
+	}
+
 	// TODO: We need to cleanup the client names
 	launcher.InitTaskLauncher(
 		dispatcher,
 		common.PelotonHostManager,
 		jobFactory,
 		ormStore,
+		secretProvider,
 		rootScope,
 		cfg.JobManager.HostManagerAPIVersion,
 	)
@@ -564,6 +621,46 @@ func main() {
 		cfg.JobManager.HostManagerAPIVersion,
 	)
 
+	// Register autoscaler
+	jobAutoscaler := &autoscaler.Autoscaler{
+		JobFactory:         jobFactory,
+		GoalStateDriver:    goalStateDriver,
+		JobConfigOps:       ormobjects.NewJobConfigOps(ormStore),
+		JobUpdateEventsOps: ormobjects.NewJobUpdateEventsOps(ormStore),
+		Source: autoscaler.NewHostMgrSource(
+			dispatcher,
+			common.PelotonHostManager,
+		),
+		Metrics: autoscaler.NewMetrics(rootScope),
+		Config:  &cfg.JobManager.Autoscaler,
+	}
+	if err := jobAutoscaler.Register(backgroundManager); err != nil {
+		log.WithError(err).
+			Fatal("fail to register autoscaler in backgroundManager")
+	}
+
+	// Register retention compactor
+	retentionCompactor := &retention.Compactor{
+		JobFactory:      jobFactory,
+		TaskStore:       store,
+		UpdateStore:     store,
+		JobConfigOps:    ormobjects.NewJobConfigOps(ormStore),
+		TaskConfigV2Ops: ormobjects.NewTaskConfigV2Ops(ormStore),
+		Metrics:         retention.NewMetrics(rootScope),
+		Config:          &cfg.JobManager.Retention,
+	}
+	if err := retentionCompactor.Register(backgroundManager); err != nil {
+		log.WithError(err).
+			Fatal("fail to register retention compactor in backgroundManager")
+	}
+	mux.HandleFunc(retention.ReportPath, retentionCompactor.ReportHandler)
+
+	// Register dashboard aggregator
+	if err := dashboardAggregator.Register(backgroundManager, jobFactory); err != nil {
+		log.WithError(err).
+			Fatal("fail to register dashboard aggregator in backgroundManager")
+	}
+
 	// Init placement processor
 	placementProcessor := placement.InitProcessor(
 		dispatcher,
@@ -645,6 +742,7 @@ func main() {
 		candidate,
 		common.PelotonResourceManager, // TODO: to be removed
 		cfg.JobManager.JobSvcCfg,
+		secretProvider,
 	)
 
 	private.InitPrivateJobServiceHandler(
@@ -658,6 +756,12 @@ func main() {
 		candidate,
 	)
 
+	dashboard.InitServiceHandler(
+		dispatcher,
+		dashboardAggregator,
+		candidate,
+	)
+
 	stateless.InitV1AlphaJobServiceHandler(
 		dispatcher,
 		store,
@@ -669,6 +773,18 @@ func main() {
 		candidate,
 		cfg.JobManager.JobSvcCfg,
 		activeJobCache,
+		secretProvider,
+	)
+
+	batch.InitV1AlphaBatchJobServiceHandler(
+		dispatcher,
+		store,
+		store,
+		ormStore,
+		jobFactory,
+		goalStateDriver,
+		candidate,
+		cfg.JobManager.JobSvcCfg,
 	)
 
 	tasksvc.InitServiceHandler(
@@ -687,6 +803,11 @@ func main() {
 		activeJobCache,
 	)
 
+	auditTrail, err := audit.NewTrail(cfg.Audit)
+	if err != nil {
+		log.WithError(err).Fatal("Cannot init audit trail")
+	}
+
 	podsvc.InitV1AlphaPodServiceHandler(
 		dispatcher,
 		store,
@@ -699,6 +820,7 @@ func main() {
 		logmanager.NewLogManager(&http.Client{Timeout: _httpClientTimeout}),
 		*mesosAgentWorkDir,
 		hostsvc.NewInternalHostServiceYARPCClient(dispatcher.ClientConfig(common.PelotonHostManager)),
+		auditTrail,
 	)
 
 	volumesvc.InitServiceHandler(
@@ -719,6 +841,8 @@ func main() {
 		dispatcher,
 		goalStateDriver,
 		apiLockInboundMiddleware,
+		ormStore,
+		secretProvider,
 	)
 
 	// Start dispatch loop
@@ -0,0 +1,26 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	storage_config "github.com/uber/peloton/pkg/storage/config"
+)
+
+// Config contains the configuration used by the schema migration tool.
+// It is a subset of the configuration of the Peloton components, so
+// their config files can be used as is.
+type Config struct {
+	Storage storage_config.Config `yaml:"storage"`
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/config"
+	"github.com/uber/peloton/pkg/storage/cassandra/migrator"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	version string
+	app     = kingpin.New("peloton-migrate", "Peloton schema migration tool")
+
+	cfgFiles = app.Flag(
+		"config",
+		"YAML config files (can be provided multiple times to merge configs)").
+		Short('c').
+		Required().
+		ExistingFiles()
+
+	cassandraHosts = app.Flag(
+		"cassandra-hosts", "Cassandra hosts").
+		Envar("CASSANDRA_HOSTS").
+		Strings()
+
+	cassandraStore = app.Flag(
+		"cassandra-store", "Cassandra store name").
+		Default("").
+		Envar("CASSANDRA_STORE").
+		String()
+
+	cassandraPort = app.Flag(
+		"cassandra-port", "Cassandra port to connect").
+		Default("0").
+		Envar("CASSANDRA_PORT").
+		Int()
+
+	datacenter = app.Flag(
+		"datacenter", "Datacenter name").
+		Default("").
+		Envar("DATACENTER").
+		String()
+
+	pelotonSecretFile = app.Flag(
+		"peloton-secret-file",
+		"Secret file containing all Peloton secrets").
+		Default("").
+		Envar("PELOTON_SECRET_FILE").
+		String()
+
+	migrations = app.Flag(
+		"migrations", "Directory of the migration files").
+		Default("").
+		Envar("MIGRATIONS").
+		String()
+
+	lockWait = app.Flag(
+		"lock-wait",
+		"How long to wait for the migration lock held by another migrator").
+		Default("0s").
+		Duration()
+
+	status = app.Command("status", "show the status of the migrations")
+
+	up   = app.Command("up", "apply pending migrations")
+	upTo = up.Flag("to", "apply migrations up to this version (default all)").
+		Default("0").
+		Uint64()
+	upDryRun = up.Flag("dry-run", "print the migrations to apply").
+			Default("false").
+			Bool()
+
+	down   = app.Command("down", "roll back applied migrations")
+	downTo = down.Flag("to", "roll back migrations newer than this version").
+		Required().
+		Uint64()
+	downDryRun = down.Flag("dry-run", "print the migrations to roll back").
+			Default("false").
+			Bool()
+)
+
+func main() {
+	app.Version(version)
+	app.HelpFlag.Short('h')
+	cmd := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	var cfg Config
+	if err := config.Parse(&cfg, *cfgFiles...); err != nil {
+		log.WithError(err).Fatal("Cannot parse yaml config")
+	}
+
+	cassandraCfg := &cfg.Storage.Cassandra
+	if len(*cassandraHosts) > 0 {
+		cassandraCfg.CassandraConn.ContactPoints = *cassandraHosts
+	}
+	if *cassandraStore != "" {
+		cassandraCfg.StoreName = *cassandraStore
+	}
+	if *cassandraPort != 0 {
+		cassandraCfg.CassandraConn.Port = *cassandraPort
+	}
+	if *datacenter != "" {
+		cassandraCfg.CassandraConn.DataCenter = *datacenter
+	}
+	if *migrations != "" {
+		cassandraCfg.Migrations = *migrations
+	}
+	if *pelotonSecretFile != "" {
+		var secretsCfg config.PelotonSecretsConfig
+		if err := config.Parse(&secretsCfg, *pelotonSecretFile); err != nil {
+			log.WithError(err).
+				WithField("peloton_secret_file", *pelotonSecretFile).
+				Fatal("Cannot parse secret config")
+		}
+		cassandraCfg.CassandraConn.Username = secretsCfg.CassandraUsername
+		cassandraCfg.CassandraConn.Password = secretsCfg.CassandraPassword
+	}
+
+	m, err := cassandraCfg.NewMigrator(*lockWait)
+	if err != nil {
+		log.WithError(err).Fatal("Cannot create schema migrator")
+	}
+	defer m.Close()
+
+	switch cmd {
+	case status.FullCommand():
+		err = printStatus(m)
+	case up.FullCommand():
+		var applied []*migrator.Migration
+		if applied, err = m.Up(*upTo, *upDryRun); err == nil {
+			printMigrations(applied, *upDryRun, "apply", "Applied")
+		}
+	case down.FullCommand():
+		var rolledBack []*migrator.Migration
+		if rolledBack, err = m.Down(*downTo, *downDryRun); err == nil {
+			printMigrations(rolledBack, *downDryRun, "roll back", "Rolled back")
+		}
+	}
+	if err != nil {
+		m.Close()
+		log.WithError(err).Fatal("Schema migration failed")
+	}
+}
+
+func printStatus(m *migrator.Migrator) error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Version\tName\tState\tUpdated At\tUpdated By\n")
+	for _, s := range statuses {
+		updatedAt := ""
+		if !s.UpdatedAt.IsZero() {
+			updatedAt = s.UpdatedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			s.Version, s.Name, s.State, updatedAt, s.UpdatedBy)
+	}
+	return w.Flush()
+}
+
+func printMigrations(
+	migrations []*migrator.Migration,
+	dryRun bool,
+	op string,
+	done string,
+) {
+	if len(migrations) == 0 {
+		fmt.Printf("No migrations to %s\n", op)
+		return
+	}
+	for _, mig := range migrations {
+		if dryRun {
+			fmt.Printf("Would %s %d (%s)\n", op, mig.Version, mig.Name)
+		} else {
+			fmt.Printf("%s %d (%s)\n", done, mig.Version, mig.Name)
+		}
+	}
+}
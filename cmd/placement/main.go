@@ -94,6 +94,20 @@ var (
 		Envar("ELECTION_ZK_SERVERS").
 		Strings()
 
+	electionBackend = app.Flag(
+		"election-backend",
+		"Election backend, either zookeeper or etcd "+
+			"(election.backend override) (set $ELECTION_BACKEND to override)").
+		Envar("ELECTION_BACKEND").
+		String()
+
+	electionEtcdEndpoints = app.Flag(
+		"election-etcd-endpoint",
+		"Election etcd endpoints. Specify multiple times for multiple endpoints "+
+			"(election.etcd_endpoints override) (set $ELECTION_ETCD_ENDPOINTS to override)").
+		Envar("ELECTION_ETCD_ENDPOINTS").
+		Strings()
+
 	useCassandra = app.Flag(
 		"use-cassandra", "Use cassandra storage implementation").
 		Default("true").
@@ -222,6 +236,14 @@ func main() {
 		cfg.Election.ZKServers = *electionZkServers
 	}
 
+	if *electionBackend != "" {
+		cfg.Election.Backend = *electionBackend
+	}
+
+	if len(*electionEtcdEndpoints) > 0 {
+		cfg.Election.EtcdEndpoints = *electionEtcdEndpoints
+	}
+
 	if !*useCassandra {
 		cfg.Storage.UseCassandra = false
 	}
@@ -82,6 +82,20 @@ var (
 		Envar("ELECTION_ZK_SERVERS").
 		Strings()
 
+	electionBackend = app.Flag(
+		"election-backend",
+		"Election backend, either zookeeper or etcd "+
+			"(election.backend override) (set $ELECTION_BACKEND to override)").
+		Envar("ELECTION_BACKEND").
+		String()
+
+	electionEtcdEndpoints = app.Flag(
+		"election-etcd-endpoint",
+		"Election etcd endpoints. Specify multiple times for multiple endpoints "+
+			"(election.etcd_endpoints override) (set $ELECTION_ETCD_ENDPOINTS to override)").
+		Envar("ELECTION_ETCD_ENDPOINTS").
+		Strings()
+
 	httpPort = app.Flag(
 		"http-port", "Resource manager HTTP port (resmgr.http_port override) "+
 			"(set $HTTP_PORT to override)").
@@ -186,6 +200,15 @@ func getConfig(cfgFiles ...string) Config {
 	if len(*electionZkServers) > 0 {
 		cfg.Election.ZKServers = *electionZkServers
 	}
+
+	if *electionBackend != "" {
+		cfg.Election.Backend = *electionBackend
+	}
+
+	if len(*electionEtcdEndpoints) > 0 {
+		cfg.Election.EtcdEndpoints = *electionEtcdEndpoints
+	}
+
 	if *httpPort != 0 {
 		cfg.ResManager.HTTPPort = *httpPort
 	}
@@ -14,6 +14,18 @@
 
 package apiproxy
 
+import "time"
+
+const (
+	_defaultDiscoveryInterval = time.Second
+
+	// GRPCTransport forwards the calls to the Peloton components over gRPC
+	GRPCTransport = "grpc"
+	// HTTPTransport forwards the unary calls to the Peloton components
+	// over HTTP. Streams are always forwarded over gRPC.
+	HTTPTransport = "http"
+)
+
 // Config contains APIProxy specific configuration
 type Config struct {
 	// HTTP port which API Proxy is listening on
@@ -21,4 +33,22 @@ type Config struct {
 
 	// gRPC port which API Proxy is listening on
 	GRPCPort int `yaml:"grpc_port"`
+
+	// DiscoveryInterval is the interval at which the leaders of the
+	// Peloton components are looked up, so that calls are forwarded
+	// to the new leader after a leader change.
+	DiscoveryInterval time.Duration `yaml:"discovery_interval"`
+
+	// Transport the unary calls are forwarded over to the Peloton
+	// components, either "grpc" or "http". Defaults to "grpc".
+	Transport string `yaml:"transport"`
+}
+
+func (c *Config) normalize() {
+	if len(c.Transport) == 0 {
+		c.Transport = GRPCTransport
+	}
+	if c.DiscoveryInterval == time.Duration(0) {
+		c.DiscoveryInterval = _defaultDiscoveryInterval
+	}
 }
@@ -0,0 +1,43 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiproxy
+
+import "github.com/uber-go/tally"
+
+// Metrics is the struct containing all the metrics of the calls
+// forwarded by the API proxy to a Peloton component.
+type Metrics struct {
+	Call     tally.Counter
+	CallFail tally.Counter
+
+	Stream     tally.Counter
+	StreamFail tally.Counter
+}
+
+// NewMetrics returns a new Metrics struct for the given Peloton
+// component, with all metrics initialized and rooted at the given
+// tally.Scope
+func NewMetrics(scope tally.Scope, service string) *Metrics {
+	forwardScope := scope.SubScope("api_proxy").Tagged(
+		map[string]string{"component": service},
+	)
+	return &Metrics{
+		Call:     forwardScope.Counter("call"),
+		CallFail: forwardScope.Counter("call_fail"),
+
+		Stream:     forwardScope.Counter("stream"),
+		StreamFail: forwardScope.Counter("stream_fail"),
+	}
+}
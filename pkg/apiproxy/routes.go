@@ -0,0 +1,98 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiproxy
+
+import (
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	updatesvc "github.com/uber/peloton/.gen/peloton/api/v0/update/svc"
+	volumesvc "github.com/uber/peloton/.gen/peloton/api/v0/volume/svc"
+	adminsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/admin/svc"
+	batchsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch/svc"
+	statelesssvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc"
+	podsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/pod/svc"
+	watchsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/watch/svc"
+	"github.com/uber/peloton/.gen/peloton/private/dashboardsvc"
+
+	"github.com/uber/peloton/pkg/common"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// component is a Peloton component whose procedures are forwarded
+// by the API proxy.
+type component struct {
+	// service name of the component, which is also the name of
+	// the outbound to the component
+	service string
+	// leader election role of the component
+	role string
+	// procedures served by the component
+	procedures []transport.Procedure
+}
+
+// components returns the Peloton components, with the v0, v1alpha and
+// dashboard procedures each of them serves. The procedures are built from the
+// generated code with no implementation, only their names, encodings
+// and handler types are used.
+func components() []*component {
+	return []*component{
+		{
+			service: common.PelotonJobManager,
+			role:    common.JobManagerRole,
+			procedures: concat(
+				// v0
+				job.BuildJobManagerYARPCProcedures(nil),
+				task.BuildTaskManagerYARPCProcedures(nil),
+				updatesvc.BuildUpdateServiceYARPCProcedures(nil),
+				volumesvc.BuildVolumeServiceYARPCProcedures(nil),
+				// v1alpha
+				statelesssvc.BuildJobServiceYARPCProcedures(nil),
+				batchsvc.BuildJobServiceYARPCProcedures(nil),
+				podsvc.BuildPodServiceYARPCProcedures(nil),
+				watchsvc.BuildWatchServiceYARPCProcedures(nil),
+				adminsvc.BuildAdminServiceYARPCProcedures(nil),
+				// private
+				dashboardsvc.BuildDashboardServiceYARPCProcedures(nil),
+			),
+		},
+		{
+			service: common.PelotonResourceManager,
+			role:    common.ResourceManagerRole,
+			procedures: concat(
+				// v0
+				respool.BuildResourceManagerYARPCProcedures(nil),
+			),
+		},
+		{
+			service: common.PelotonHostManager,
+			role:    common.HostManagerRole,
+			procedures: concat(
+				// v0
+				hostsvc.BuildHostServiceYARPCProcedures(nil),
+			),
+		},
+	}
+}
+
+func concat(procedures ...[]transport.Procedure) []transport.Procedure {
+	var result []transport.Procedure
+	for _, p := range procedures {
+		result = append(result, p...)
+	}
+	return result
+}
@@ -15,15 +15,220 @@
 package apiproxy
 
 import (
-	"sync"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/leader"
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/peer"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/transport/grpc"
+	"go.uber.org/yarpc/transport/http"
+	"go.uber.org/yarpc/yarpcerrors"
 )
 
-// Server contains all structs necessary to run API Proxy server.
+var noOutboundErrorStr = "no outbound to %s"
+
+// Server contains all structs necessary to run API Proxy server. It
+// forwards the calls to the v0 and v1alpha procedures of the Peloton
+// components to the current leader of each component.
 type Server struct {
-	mu sync.Mutex
+	// returns the outbound config, with the outbound middleware
+	// applied, to the component with the given service name
+	outboundConfig func(service string) (*transport.OutboundConfig, bool)
+	scope          tally.Scope
+}
+
+// NewServer creates an new API Proxy instance, which forwards calls
+// over the outbounds of the given dispatcher. The outbounds are
+// expected to have been created with NewOutbounds.
+func NewServer(d *yarpc.Dispatcher, scope tally.Scope) *Server {
+	return &Server{
+		outboundConfig: d.OutboundConfig,
+		scope:          scope,
+	}
+}
+
+// NewOutbounds creates the outbounds to the Peloton components. Each
+// outbound follows the leader of its component as discovered with the
+// given discovery, so that leader changes are transparent to the
+// clients of the API proxy. Unary calls are forwarded over the
+// transport selected in the config, streams always over gRPC.
+func NewOutbounds(
+	gt *grpc.Transport,
+	ht *http.Transport,
+	discovery leader.Discovery,
+	config *Config,
+) (yarpc.Outbounds, error) {
+	config.normalize()
+
+	outbounds := yarpc.Outbounds{}
+	for _, c := range components() {
+		var unary transport.UnaryOutbound
+		switch config.Transport {
+		case GRPCTransport:
+			unary = gt.NewOutbound(
+				peer.NewPeerChooser(gt, config.DiscoveryInterval, discovery.GetAppURL, c.role),
+			)
+		case HTTPTransport:
+			unary = ht.NewOutbound(
+				peer.NewPeerChooser(ht, config.DiscoveryInterval, discovery.GetAppHTTPURL, c.role),
+			)
+		default:
+			return nil, fmt.Errorf("unknown transport %s", config.Transport)
+		}
+
+		outbounds[c.service] = transport.Outbounds{
+			Unary: unary,
+			Stream: gt.NewOutbound(
+				peer.NewPeerChooser(gt, config.DiscoveryInterval, discovery.GetAppURL, c.role),
+			),
+		}
+	}
+	return outbounds, nil
 }
 
-// NewServer creates an new API Proxy instance.
-func NewServer() *Server {
-	return &Server{}
+// Procedures returns the procedures forwarded by the API proxy. Each
+// procedure is registered both under the service name of the component
+// serving it, so that existing clients only need to be pointed at the
+// API proxy, and under the service name of the API proxy.
+func (s *Server) Procedures() []transport.Procedure {
+	var procedures []transport.Procedure
+	for _, c := range components() {
+		f := &forwarder{
+			service: c.service,
+			metrics: NewMetrics(s.scope, c.service),
+		}
+		if oc, ok := s.outboundConfig(c.service); ok {
+			f.outbounds = oc.Outbounds
+		}
+
+		for _, p := range c.procedures {
+			var spec transport.HandlerSpec
+			switch p.HandlerSpec.Type() {
+			case transport.Unary:
+				spec = transport.NewUnaryHandlerSpec(f)
+			case transport.Streaming:
+				spec = transport.NewStreamHandlerSpec(f)
+			default:
+				// no Peloton procedure is oneway
+				continue
+			}
+
+			for _, service := range []string{c.service, common.PelotonAPIProxy} {
+				procedures = append(procedures, transport.Procedure{
+					Name:        p.Name,
+					Service:     service,
+					HandlerSpec: spec,
+					Encoding:    p.Encoding,
+					Signature:   p.Signature,
+				})
+			}
+		}
+	}
+	return procedures
+}
+
+// forwarder forwards the calls to the procedures of a Peloton component
+// to the current leader of the component.
+type forwarder struct {
+	service   string
+	outbounds transport.Outbounds
+	metrics   *Metrics
+}
+
+// Handle forwards a unary call.
+func (f *forwarder) Handle(
+	ctx context.Context,
+	req *transport.Request,
+	resw transport.ResponseWriter,
+) error {
+	f.metrics.Call.Inc(1)
+	if f.outbounds.Unary == nil {
+		f.metrics.CallFail.Inc(1)
+		return yarpcerrors.UnavailableErrorf(noOutboundErrorStr, f.service)
+	}
+
+	forwardReq := *req
+	forwardReq.Service = f.service
+	resp, err := f.outbounds.Unary.Call(ctx, &forwardReq)
+	if err != nil {
+		f.metrics.CallFail.Inc(1)
+		return err
+	}
+	if resp.Body != nil {
+		defer resp.Body.Close()
+	}
+
+	resw.AddHeaders(resp.Headers)
+	if resp.ApplicationError {
+		resw.SetApplicationError()
+	}
+	if resp.Body == nil {
+		return nil
+	}
+	if _, err := io.Copy(resw, resp.Body); err != nil {
+		f.metrics.CallFail.Inc(1)
+		return err
+	}
+	return nil
+}
+
+// HandleStream forwards a stream, until either the client or the
+// component ends it.
+func (f *forwarder) HandleStream(serverStream *transport.ServerStream) error {
+	f.metrics.Stream.Inc(1)
+	if f.outbounds.Stream == nil {
+		f.metrics.StreamFail.Inc(1)
+		return yarpcerrors.UnavailableErrorf(noOutboundErrorStr, f.service)
+	}
+
+	ctx, cancel := context.WithCancel(serverStream.Context())
+	defer cancel()
+
+	meta := *serverStream.Request().Meta
+	meta.Service = f.service
+	clientStream, err := f.outbounds.Stream.CallStream(
+		ctx,
+		&transport.StreamRequest{Meta: &meta},
+	)
+	if err != nil {
+		f.metrics.StreamFail.Inc(1)
+		return err
+	}
+
+	// forward the messages of the client to the component, and close
+	// the stream to the component once the client is done sending
+	go func() {
+		defer clientStream.Close(ctx)
+		for {
+			msg, err := serverStream.ReceiveMessage(ctx)
+			if err != nil {
+				return
+			}
+			if err := clientStream.SendMessage(ctx, msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	// forward the messages of the component to the client
+	for {
+		msg, err := clientStream.ReceiveMessage(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			f.metrics.StreamFail.Inc(1)
+			return err
+		}
+		if err := serverStream.SendMessage(ctx, msg); err != nil {
+			f.metrics.StreamFail.Inc(1)
+			return err
+		}
+	}
 }
@@ -0,0 +1,282 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/uber/peloton/pkg/common"
+	leadermocks "github.com/uber/peloton/pkg/common/leader/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/transport/grpc"
+	"go.uber.org/yarpc/transport/http"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_getJobProcedure     = "peloton.api.v1alpha.job.stateless.svc.JobService::GetJob"
+	_watchProcedure      = "peloton.api.v1alpha.watch.svc.WatchService::Watch"
+	_getPoolProcedure    = "peloton.api.v0.respool.ResourceManager::GetResourcePool"
+	_queryHostsProcedure = "peloton.api.v0.host.svc.HostService::QueryHosts"
+	_rollupsProcedure    = "peloton.private.dashboard.DashboardService::GetRespoolRollups"
+)
+
+type ServerTestSuite struct {
+	suite.Suite
+	ctrl *gomock.Controller
+
+	unaryOutbound  *transporttest.MockUnaryOutbound
+	streamOutbound *transporttest.MockStreamOutbound
+	forwarder      *forwarder
+}
+
+func (suite *ServerTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.unaryOutbound = transporttest.NewMockUnaryOutbound(suite.ctrl)
+	suite.streamOutbound = transporttest.NewMockStreamOutbound(suite.ctrl)
+	suite.forwarder = &forwarder{
+		service: common.PelotonJobManager,
+		outbounds: transport.Outbounds{
+			Unary:  suite.unaryOutbound,
+			Stream: suite.streamOutbound,
+		},
+		metrics: NewMetrics(tally.NoopScope, common.PelotonJobManager),
+	}
+}
+
+func (suite *ServerTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestServer(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
+
+// TestProcedures tests that the procedures of the Peloton components
+// are registered under the component and the API proxy service names
+func (suite *ServerTestSuite) TestProcedures() {
+	s := &Server{
+		outboundConfig: func(service string) (*transport.OutboundConfig, bool) {
+			return &transport.OutboundConfig{
+				CallerName: common.PelotonAPIProxy,
+				Outbounds: transport.Outbounds{
+					ServiceName: service,
+					Unary:       suite.unaryOutbound,
+					Stream:      suite.streamOutbound,
+				},
+			}, true
+		},
+		scope: tally.NoopScope,
+	}
+
+	types := make(map[string]map[string]transport.Type)
+	for _, p := range s.Procedures() {
+		if _, ok := types[p.Name]; !ok {
+			types[p.Name] = make(map[string]transport.Type)
+		}
+		types[p.Name][p.Service] = p.HandlerSpec.Type()
+	}
+
+	tt := []struct {
+		procedure   string
+		service     string
+		handlerType transport.Type
+	}{
+		{_getJobProcedure, common.PelotonJobManager, transport.Unary},
+		{_watchProcedure, common.PelotonJobManager, transport.Streaming},
+		{_getPoolProcedure, common.PelotonResourceManager, transport.Unary},
+		{_queryHostsProcedure, common.PelotonHostManager, transport.Unary},
+		{_rollupsProcedure, common.PelotonJobManager, transport.Unary},
+	}
+	for _, test := range tt {
+		suite.Equal(map[string]transport.Type{
+			test.service:           test.handlerType,
+			common.PelotonAPIProxy: test.handlerType,
+		}, types[test.procedure], test.procedure)
+	}
+}
+
+// TestNewOutbounds tests that unary calls are forwarded over the
+// transport selected in the config, and streams over gRPC
+func (suite *ServerTestSuite) TestNewOutbounds() {
+	discovery := leadermocks.NewMockDiscovery(suite.ctrl)
+	gt := grpc.NewTransport()
+	ht := http.NewTransport()
+
+	outbounds, err := NewOutbounds(gt, ht, discovery, &Config{})
+	suite.NoError(err)
+	for _, c := range components() {
+		suite.IsType(&grpc.Outbound{}, outbounds[c.service].Unary)
+		suite.IsType(&grpc.Outbound{}, outbounds[c.service].Stream)
+	}
+
+	outbounds, err = NewOutbounds(gt, ht, discovery, &Config{
+		Transport: HTTPTransport,
+	})
+	suite.NoError(err)
+	for _, c := range components() {
+		suite.IsType(&http.Outbound{}, outbounds[c.service].Unary)
+		suite.IsType(&grpc.Outbound{}, outbounds[c.service].Stream)
+	}
+
+	_, err = NewOutbounds(gt, ht, discovery, &Config{Transport: "tchannel"})
+	suite.Error(err)
+}
+
+// TestHandle tests forwarding a unary call to the component
+func (suite *ServerTestSuite) TestHandle() {
+	req := &transport.Request{
+		Caller:    "peloton-client",
+		Service:   common.PelotonAPIProxy,
+		Encoding:  "proto",
+		Procedure: _getJobProcedure,
+		Body:      bytes.NewReader([]byte("request")),
+	}
+	suite.unaryOutbound.EXPECT().
+		Call(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			forwardReq *transport.Request,
+		) (*transport.Response, error) {
+			suite.Equal(common.PelotonJobManager, forwardReq.Service)
+			suite.Equal(req.Caller, forwardReq.Caller)
+			suite.Equal(req.Procedure, forwardReq.Procedure)
+			suite.Equal(req.Encoding, forwardReq.Encoding)
+			return &transport.Response{
+				Headers:          transport.NewHeaders().With("key", "value"),
+				Body:             ioutil.NopCloser(bytes.NewReader([]byte("response"))),
+				ApplicationError: true,
+			}, nil
+		})
+
+	resw := &transporttest.FakeResponseWriter{}
+	suite.NoError(suite.forwarder.Handle(context.Background(), req, resw))
+	suite.Equal("response", resw.Body.String())
+	suite.True(resw.IsApplicationError)
+	value, ok := resw.Headers.Get("key")
+	suite.True(ok)
+	suite.Equal("value", value)
+	// the request is not modified
+	suite.Equal(common.PelotonAPIProxy, req.Service)
+}
+
+// TestHandleError tests forwarding a unary call which fails
+func (suite *ServerTestSuite) TestHandleError() {
+	unavailableErr := yarpcerrors.UnavailableErrorf("test error")
+	suite.unaryOutbound.EXPECT().
+		Call(gomock.Any(), gomock.Any()).
+		Return(nil, unavailableErr)
+
+	suite.Equal(unavailableErr, suite.forwarder.Handle(
+		context.Background(),
+		&transport.Request{Procedure: _getJobProcedure},
+		&transporttest.FakeResponseWriter{},
+	))
+}
+
+// TestHandleNoOutbound tests forwarding a call to a component
+// with no outbound
+func (suite *ServerTestSuite) TestHandleNoOutbound() {
+	suite.forwarder.outbounds = transport.Outbounds{}
+	err := suite.forwarder.Handle(
+		context.Background(),
+		&transport.Request{Procedure: _getJobProcedure},
+		&transporttest.FakeResponseWriter{},
+	)
+	suite.True(yarpcerrors.IsUnavailable(err))
+}
+
+// TestHandleStream tests forwarding a stream in both directions
+func (suite *ServerTestSuite) TestHandleStream() {
+	serverMock := transporttest.NewMockStream(suite.ctrl)
+	serverStream, err := transport.NewServerStream(serverMock)
+	suite.NoError(err)
+	clientMock := transporttest.NewMockStreamCloser(suite.ctrl)
+	clientStream, err := transport.NewClientStream(clientMock)
+	suite.NoError(err)
+
+	reqMsg := &transport.StreamMessage{
+		Body: ioutil.NopCloser(bytes.NewReader([]byte("request"))),
+	}
+	respMsg := &transport.StreamMessage{
+		Body: ioutil.NopCloser(bytes.NewReader([]byte("response"))),
+	}
+
+	serverMock.EXPECT().Context().Return(context.Background()).AnyTimes()
+	serverMock.EXPECT().Request().Return(&transport.StreamRequest{
+		Meta: &transport.RequestMeta{
+			Service:   common.PelotonAPIProxy,
+			Procedure: _watchProcedure,
+		},
+	}).AnyTimes()
+	suite.streamOutbound.EXPECT().
+		CallStream(gomock.Any(), &transport.StreamRequest{
+			Meta: &transport.RequestMeta{
+				Service:   common.PelotonJobManager,
+				Procedure: _watchProcedure,
+			},
+		}).
+		Return(clientStream, nil)
+
+	// client to component
+	closed := make(chan struct{})
+	serverMock.EXPECT().ReceiveMessage(gomock.Any()).Return(reqMsg, nil)
+	clientMock.EXPECT().SendMessage(gomock.Any(), reqMsg).Return(nil)
+	serverMock.EXPECT().ReceiveMessage(gomock.Any()).Return(nil, io.EOF)
+	clientMock.EXPECT().
+		Close(gomock.Any()).
+		Do(func(_ context.Context) { close(closed) }).
+		Return(nil)
+
+	// component to client, once the client is done sending
+	clientMock.EXPECT().
+		ReceiveMessage(gomock.Any()).
+		DoAndReturn(func(_ context.Context) (*transport.StreamMessage, error) {
+			<-closed
+			return respMsg, nil
+		})
+	serverMock.EXPECT().SendMessage(gomock.Any(), respMsg).Return(nil)
+	clientMock.EXPECT().ReceiveMessage(gomock.Any()).Return(nil, io.EOF)
+
+	suite.NoError(suite.forwarder.HandleStream(serverStream))
+}
+
+// TestHandleStreamError tests forwarding a stream which fails
+// to be created
+func (suite *ServerTestSuite) TestHandleStreamError() {
+	serverMock := transporttest.NewMockStream(suite.ctrl)
+	serverStream, err := transport.NewServerStream(serverMock)
+	suite.NoError(err)
+
+	serverMock.EXPECT().Context().Return(context.Background()).AnyTimes()
+	serverMock.EXPECT().Request().Return(&transport.StreamRequest{
+		Meta: &transport.RequestMeta{Procedure: _watchProcedure},
+	}).AnyTimes()
+	suite.streamOutbound.EXPECT().
+		CallStream(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("test error"))
+
+	suite.Error(suite.forwarder.HandleStream(serverStream))
+}
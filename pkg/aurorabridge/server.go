@@ -62,9 +62,15 @@ func NewServer(
 	additionalEndpoints := make(map[string]leader.Endpoint)
 	additionalEndpoints["http"] = endpoint
 
-	zkClient, err := zookeeper.New(cfg.ZKServers, nil)
-	if err != nil {
-		return nil, err
+	// The dummy node is only needed by Aurora clients watching
+	// leadership changes in ZooKeeper.
+	var zkClient store.Store
+	if !cfg.IsEtcd() {
+		var err error
+		zkClient, err = zookeeper.New(cfg.ZKServers, nil)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &Server{
@@ -94,15 +100,17 @@ func (s *Server) GainedLeadershipCallback() (err error) {
 	// so that the client can pick up the leadership change when it's
 	// doing a watch. If it fails to re-create the node, throw the
 	// error to give up the leadership.
-	key := path.Join(s.zkRoot, _dummyNode)
-	err = s.zkClient.Delete(key)
-	if err != nil && err != store.ErrKeyNotFound {
-		return errors.Wrap(err, "failed to delete dummy node")
-	}
+	if s.zkClient != nil {
+		key := path.Join(s.zkRoot, _dummyNode)
+		err = s.zkClient.Delete(key)
+		if err != nil && err != store.ErrKeyNotFound {
+			return errors.Wrap(err, "failed to delete dummy node")
+		}
 
-	err = s.zkClient.Put(key, []byte{}, nil)
-	if err != nil {
-		return errors.Wrap(err, "failed to create dummy node")
+		err = s.zkClient.Put(key, []byte{}, nil)
+		if err != nil {
+			return errors.Wrap(err, "failed to create dummy node")
+		}
 	}
 
 	// start event publisher
@@ -123,8 +131,10 @@ func (s *Server) LostLeadershipCallback() error {
 	// Remove the dummy node under /peloton/aurora/scheduler
 	// to trigger a watch event, ignore the deletion error here
 	// since the node is no longer the leader.
-	key := path.Join(s.zkRoot, _dummyNode)
-	s.zkClient.Delete(key)
+	if s.zkClient != nil {
+		key := path.Join(s.zkRoot, _dummyNode)
+		s.zkClient.Delete(key)
+	}
 
 	// stop event publisher
 	s.eventPublisher.Stop()
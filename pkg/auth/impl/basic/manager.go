@@ -119,6 +119,11 @@ func (u *user) IsPermitted(procedure string) bool {
 	return false
 }
 
+// Name returns the username of the user
+func (u *user) Name() string {
+	return u.username
+}
+
 func matchRules(service, method string, rules map[string][]string) bool {
 	// _matchAllRule is set, all services and methods are matched
 	if _, ok := rules[_matchAllRule]; ok {
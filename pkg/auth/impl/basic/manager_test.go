@@ -329,12 +329,14 @@ func (suite *SecurityManagerTestSuite) TestNormalUserPermission() {
 		{procedureName: "peloton.api.v1alpha.respool.svc.ResourcePoolService::GetResourcePool", isPermitted: true},
 		{procedureName: "peloton.api.v1alpha.job.stateless.svc.JobService::CreateJob", isPermitted: false},
 		{procedureName: "peloton.api.v1alpha.job.volume.svc.VolumeService::GetVolume", isPermitted: false},
+		{procedureName: "peloton.api.v1alpha.admin.svc.AdminService::GetJobSecrets", isPermitted: false},
 	}
 
 	u, err := suite.m.Authenticate(
 		&testToken{username: "user1", password: "password1"},
 	)
 	suite.NoError(err)
+	suite.Equal("user1", u.Name())
 
 	for _, test := range tests {
 		if test.isPermitted {
@@ -346,6 +348,19 @@ func (suite *SecurityManagerTestSuite) TestNormalUserPermission() {
 	}
 }
 
+// TestAdminUserPermission tests that the secrets of the jobs are only
+// returned to admins
+func (suite *SecurityManagerTestSuite) TestAdminUserPermission() {
+	u, err := suite.m.Authenticate(
+		&testToken{username: "admin", password: "password3"},
+	)
+	suite.NoError(err)
+	suite.True(u.IsPermitted(
+		"peloton.api.v1alpha.admin.svc.AdminService::GetJobSecrets"))
+	suite.False(u.IsPermitted(
+		"peloton.api.v1alpha.admin.svc.AdminService::Lockdown"))
+}
+
 func (suite *SecurityManagerTestSuite) TestValidateRule() {
 	tests := []struct {
 		rule      string
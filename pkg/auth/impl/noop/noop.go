@@ -38,6 +38,11 @@ func (u *noopUser) IsPermitted(procedure string) bool {
 	return true
 }
 
+// Name returns an empty string, the user is not identified
+func (u *noopUser) Name() string {
+	return ""
+}
+
 // NewNoopSecurityManager returns SecurityManager
 func NewNoopSecurityManager() *SecurityManager {
 	return &SecurityManager{}
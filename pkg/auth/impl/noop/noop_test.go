@@ -29,6 +29,7 @@ func TestNoopSecurityManager(t *testing.T) {
 	assert.True(t, u.IsPermitted("peloton.api.v1alpha.job.stateless.svc.JobService::CreateJob"))
 	// even if the procedure name is not valid, still should pass permit check
 	assert.True(t, u.IsPermitted(""))
+	assert.Empty(t, u.Name())
 }
 
 func TestNoopSecurityClient(t *testing.T) {
@@ -55,6 +55,9 @@ type User interface {
 	// IsPermitted returns whether user can
 	// access the specified procedure
 	IsPermitted(procedure string) bool
+	// Name returns the name of the user, or an empty
+	// string if the user is not identified
+	Name() string
 }
 
 // SecurityClient is the internal client used by each of
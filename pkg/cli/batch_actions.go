@@ -0,0 +1,310 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch"
+	batchsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch/svc"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	v1alphaquery "github.com/uber/peloton/.gen/peloton/api/v1alpha/query"
+	v1alpharespool "github.com/uber/peloton/.gen/peloton/api/v1alpha/respool"
+
+	"github.com/golang/protobuf/ptypes"
+	"go.uber.org/yarpc/yarpcerrors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	batchJobSummaryFormatHeader = "ID\tName\tOwner\tState\tCreation Time\t" +
+		"Completion Time\tTotal\tRunning\tSucceeded\tFailed\tKilled\t\n"
+	batchJobSummaryFormatBody = "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t\n"
+)
+
+// BatchCreateAction is the action for creating a batch job
+func (c *Client) BatchCreateAction(
+	jobID string,
+	respoolPath string,
+	cfg string,
+) error {
+	respoolID, err := c.LookupResourcePoolID(respoolPath)
+	if err != nil {
+		return err
+	}
+	if respoolID == nil {
+		return fmt.Errorf("unable to find resource pool ID for "+
+			":%s", respoolPath)
+	}
+
+	var jobSpec batch.JobSpec
+	buffer, err := ioutil.ReadFile(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to open file %s: %v", cfg, err)
+	}
+	if err := yaml.Unmarshal(buffer, &jobSpec); err != nil {
+		return fmt.Errorf("unable to parse file %s: %v", cfg, err)
+	}
+
+	jobSpec.RespoolId = &v1alphapeloton.ResourcePoolID{Value: respoolID.GetValue()}
+
+	request := &batchsvc.CreateJobRequest{
+		JobId: &v1alphapeloton.JobID{Value: jobID},
+		Spec:  &jobSpec,
+	}
+	response, err := c.batchClient.CreateJob(c.ctx, request)
+
+	printBatchJobCreateResponse(request, response, err, c.Debug)
+
+	return err
+}
+
+// BatchGetAction is the action for getting status
+// and spec (or only summary) of a batch job
+func (c *Client) BatchGetAction(jobID string, summaryOnly bool) error {
+	resp, err := c.batchClient.GetJob(
+		c.ctx,
+		&batchsvc.GetJobRequest{
+			JobId:       &v1alphapeloton.JobID{Value: jobID},
+			SummaryOnly: summaryOnly,
+		})
+	if err != nil {
+		return err
+	}
+
+	out, err := marshallResponse(defaultResponseFormat, resp)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%v\n", string(out))
+
+	return nil
+}
+
+// BatchQueryAction queries batch jobs given the spec
+func (c *Client) BatchQueryAction(
+	labels string,
+	respoolPath string,
+	keywords string,
+	states string,
+	owner string,
+	name string,
+	days uint32,
+	limit uint32,
+	maxLimit uint32,
+	offset uint32,
+	sortBy string,
+	sortOrder string) error {
+	pelotonLabels, err := parseLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	orderBy, err := parseOrderBy(sortBy, sortOrder)
+	if err != nil {
+		return err
+	}
+
+	spec := &batch.QuerySpec{
+		Pagination: &v1alphaquery.PaginationSpec{
+			Offset:   offset,
+			Limit:    limit,
+			OrderBy:  orderBy,
+			MaxLimit: maxLimit,
+		},
+		Labels:    pelotonLabels,
+		Keywords:  parseKeyWords(keywords),
+		JobStates: parseBatchJobStates(states),
+		Owner:     owner,
+		Name:      name,
+	}
+
+	if len(respoolPath) > 0 {
+		spec.Respool = &v1alpharespool.ResourcePoolPath{
+			Value: respoolPath,
+		}
+	}
+
+	if days > 0 {
+		now := time.Now().UTC()
+		max, err := ptypes.TimestampProto(now)
+		if err != nil {
+			return err
+		}
+		min, err := ptypes.TimestampProto(now.AddDate(0, 0, -int(days)))
+		if err != nil {
+			return err
+		}
+		spec.CreationTimeRange = &v1alphapeloton.TimeRange{Min: min, Max: max}
+	}
+
+	resp, err := c.batchClient.QueryJobs(c.ctx, &batchsvc.QueryJobsRequest{
+		Spec: spec,
+	})
+	if err != nil {
+		return err
+	}
+	printBatchQueryResponse(resp)
+
+	tabWriter.Flush()
+	return nil
+}
+
+// BatchKillAction is the action for killing a batch job
+func (c *Client) BatchKillAction(jobID string, entityVersion string) error {
+	resp, err := c.batchClient.KillJob(
+		c.ctx,
+		&batchsvc.KillJobRequest{
+			JobId:   &v1alphapeloton.JobID{Value: jobID},
+			Version: &v1alphapeloton.EntityVersion{Value: entityVersion},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Job killed. New EntityVersion: %s\n", resp.GetVersion().GetValue())
+
+	return nil
+}
+
+// BatchDeleteAction is the action for deleting a terminal batch job
+func (c *Client) BatchDeleteAction(jobID string, entityVersion string) error {
+	_, err := c.batchClient.DeleteJob(
+		c.ctx,
+		&batchsvc.DeleteJobRequest{
+			JobId:   &v1alphapeloton.JobID{Value: jobID},
+			Version: &v1alphapeloton.EntityVersion{Value: entityVersion},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Job deleted\n")
+	return nil
+}
+
+// BatchRestartFailedAction is the action for restarting the failed
+// instances of a terminal batch job
+func (c *Client) BatchRestartFailedAction(
+	jobID string,
+	entityVersion string,
+) error {
+	resp, err := c.batchClient.RestartFailedInstances(
+		c.ctx,
+		&batchsvc.RestartFailedInstancesRequest{
+			JobId:   &v1alphapeloton.JobID{Value: jobID},
+			Version: &v1alphapeloton.EntityVersion{Value: entityVersion},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restarted %d failed instances. New EntityVersion: %s\n",
+		len(resp.GetInstanceIds()), resp.GetVersion().GetValue())
+
+	return nil
+}
+
+func printBatchJobCreateResponse(
+	req *batchsvc.CreateJobRequest,
+	resp *batchsvc.CreateJobResponse,
+	err error,
+	jsonFormat bool,
+) {
+	defer tabWriter.Flush()
+	if jsonFormat {
+		printResponseJSON(resp)
+	} else {
+		if err != nil {
+			if yarpcerrors.IsAlreadyExists(err) {
+				fmt.Fprintf(tabWriter, "Job %s already exists: %s\n",
+					req.GetJobId(), err.Error())
+			} else if yarpcerrors.IsInvalidArgument(err) {
+				fmt.Fprintf(tabWriter, "Invalid job spec: %s\n",
+					err.Error())
+			}
+		} else if resp.GetJobId() != nil {
+			fmt.Fprintf(
+				tabWriter,
+				"Job %s created. Entity Version: %s\n",
+				resp.GetJobId().GetValue(),
+				resp.GetVersion().GetValue(),
+			)
+		} else {
+			fmt.Fprint(tabWriter, "Missing job ID in job create response\n")
+		}
+	}
+}
+
+func printBatchQueryResponse(resp *batchsvc.QueryJobsResponse) {
+	results := resp.GetRecords()
+	if len(results) == 0 {
+		fmt.Fprintf(tabWriter, "No results found\n")
+		return
+	}
+
+	fmt.Fprint(tabWriter, batchJobSummaryFormatHeader)
+	for _, r := range results {
+		printBatchQueryResult(r)
+	}
+}
+
+func printBatchQueryResult(j *batch.JobSummary) {
+	fmt.Fprintf(
+		tabWriter,
+		batchJobSummaryFormatBody,
+		j.GetJobId().GetValue(),
+		j.GetName(),
+		j.GetOwningTeam(),
+		j.GetStatus().GetState().String(),
+		formatRFC3339(j.GetStatus().GetCreationTime()),
+		formatRFC3339(j.GetStatus().GetCompletionTime()),
+		j.GetInstanceCount(),
+		j.GetStatus().GetPodStats()["POD_STATE_RUNNING"],
+		j.GetStatus().GetPodStats()["POD_STATE_SUCCEEDED"],
+		j.GetStatus().GetPodStats()["POD_STATE_FAILED"],
+		j.GetStatus().GetPodStats()["POD_STATE_KILLED"],
+	)
+}
+
+// formatRFC3339 truncates a RFC3339Nano timestamp to RFC3339,
+// and returns an empty string if the timestamp can't be parsed
+func formatRFC3339(timestamp string) string {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func parseBatchJobStates(states string) []batch.JobState {
+	if len(states) == 0 {
+		return nil
+	}
+
+	var pelotonStates []batch.JobState
+	for _, k := range strings.Split(states, labelSeparator) {
+		if k != "" {
+			pelotonStates = append(pelotonStates, batch.JobState(batch.JobState_value[k]))
+		}
+	}
+	return pelotonStates
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch"
+	batchsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch/svc"
+	batchmocks "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch/svc/mocks"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type batchActionsTestSuite struct {
+	suite.Suite
+	ctx    context.Context
+	client Client
+
+	ctrl        *gomock.Controller
+	batchClient *batchmocks.MockJobServiceYARPCClient
+}
+
+func (suite *batchActionsTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.batchClient = batchmocks.NewMockJobServiceYARPCClient(suite.ctrl)
+	suite.ctx = context.Background()
+	suite.client = Client{
+		Debug:       false,
+		batchClient: suite.batchClient,
+		dispatcher:  nil,
+		ctx:         suite.ctx,
+	}
+}
+
+func (suite *batchActionsTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestBatchActions(t *testing.T) {
+	suite.Run(t, new(batchActionsTestSuite))
+}
+
+// TestBatchGetActionSuccess tests getting a batch job successfully
+func (suite *batchActionsTestSuite) TestBatchGetActionSuccess() {
+	suite.batchClient.EXPECT().
+		GetJob(suite.ctx, &batchsvc.GetJobRequest{
+			JobId:       &v1alphapeloton.JobID{Value: testJobID},
+			SummaryOnly: true,
+		}).
+		Return(&batchsvc.GetJobResponse{
+			Summary: &batch.JobSummary{
+				JobId: &v1alphapeloton.JobID{Value: testJobID},
+			},
+		}, nil)
+
+	suite.NoError(suite.client.BatchGetAction(testJobID, true))
+}
+
+// TestBatchGetActionFailure tests failing to get a batch job
+func (suite *batchActionsTestSuite) TestBatchGetActionFailure() {
+	suite.batchClient.EXPECT().
+		GetJob(suite.ctx, gomock.Any()).
+		Return(nil, errors.New("test error"))
+
+	suite.Error(suite.client.BatchGetAction(testJobID, false))
+}
+
+// TestBatchQueryActionSuccess tests querying batch jobs successfully
+func (suite *batchActionsTestSuite) TestBatchQueryActionSuccess() {
+	suite.batchClient.EXPECT().
+		QueryJobs(suite.ctx, gomock.Any()).
+		Do(func(_ context.Context, req *batchsvc.QueryJobsRequest) {
+			suite.Equal(
+				[]batch.JobState{batch.JobState_JOB_STATE_FAILED},
+				req.GetSpec().GetJobStates(),
+			)
+			suite.Equal("/testPath", req.GetSpec().GetRespool().GetValue())
+		}).
+		Return(&batchsvc.QueryJobsResponse{
+			Records: []*batch.JobSummary{
+				{
+					JobId: &v1alphapeloton.JobID{Value: testJobID},
+					Name:  "test",
+					Status: &batch.JobStatus{
+						State: batch.JobState_JOB_STATE_FAILED,
+					},
+				},
+			},
+		}, nil)
+
+	suite.NoError(suite.client.BatchQueryAction(
+		"", "/testPath", "", "JOB_STATE_FAILED", "", "",
+		1, 10, 100, 0, "creation_time", "DESC",
+	))
+}
+
+// TestBatchQueryActionFailure tests failing to query batch jobs
+func (suite *batchActionsTestSuite) TestBatchQueryActionFailure() {
+	suite.batchClient.EXPECT().
+		QueryJobs(suite.ctx, gomock.Any()).
+		Return(nil, errors.New("test error"))
+
+	suite.Error(suite.client.BatchQueryAction(
+		"", "", "", "", "", "",
+		0, 10, 100, 0, "creation_time", "DESC",
+	))
+}
+
+// TestBatchKillActionSuccess tests killing a batch job successfully
+func (suite *batchActionsTestSuite) TestBatchKillActionSuccess() {
+	suite.batchClient.EXPECT().
+		KillJob(suite.ctx, &batchsvc.KillJobRequest{
+			JobId:   &v1alphapeloton.JobID{Value: testJobID},
+			Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+		}).
+		Return(&batchsvc.KillJobResponse{
+			Version: &v1alphapeloton.EntityVersion{Value: "2-1-1"},
+		}, nil)
+
+	suite.NoError(suite.client.BatchKillAction(testJobID, testEntityVersion))
+}
+
+// TestBatchKillActionFailure tests failing to kill a batch job
+func (suite *batchActionsTestSuite) TestBatchKillActionFailure() {
+	suite.batchClient.EXPECT().
+		KillJob(suite.ctx, gomock.Any()).
+		Return(nil, errors.New("test error"))
+
+	suite.Error(suite.client.BatchKillAction(testJobID, testEntityVersion))
+}
+
+// TestBatchDeleteActionSuccess tests deleting a batch job successfully
+func (suite *batchActionsTestSuite) TestBatchDeleteActionSuccess() {
+	suite.batchClient.EXPECT().
+		DeleteJob(suite.ctx, &batchsvc.DeleteJobRequest{
+			JobId:   &v1alphapeloton.JobID{Value: testJobID},
+			Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+		}).
+		Return(&batchsvc.DeleteJobResponse{}, nil)
+
+	suite.NoError(suite.client.BatchDeleteAction(testJobID, testEntityVersion))
+}
+
+// TestBatchDeleteActionFailure tests failing to delete a batch job
+func (suite *batchActionsTestSuite) TestBatchDeleteActionFailure() {
+	suite.batchClient.EXPECT().
+		DeleteJob(suite.ctx, gomock.Any()).
+		Return(nil, errors.New("test error"))
+
+	suite.Error(suite.client.BatchDeleteAction(testJobID, testEntityVersion))
+}
+
+// TestBatchRestartFailedActionSuccess tests restarting the failed
+// instances of a batch job successfully
+func (suite *batchActionsTestSuite) TestBatchRestartFailedActionSuccess() {
+	suite.batchClient.EXPECT().
+		RestartFailedInstances(suite.ctx, &batchsvc.RestartFailedInstancesRequest{
+			JobId:   &v1alphapeloton.JobID{Value: testJobID},
+			Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+		}).
+		Return(&batchsvc.RestartFailedInstancesResponse{
+			Version:     &v1alphapeloton.EntityVersion{Value: "2-1-1"},
+			InstanceIds: []uint32{1, 3},
+		}, nil)
+
+	suite.NoError(
+		suite.client.BatchRestartFailedAction(testJobID, testEntityVersion))
+}
+
+// TestBatchRestartFailedActionFailure tests failing to restart the
+// failed instances of a batch job
+func (suite *batchActionsTestSuite) TestBatchRestartFailedActionFailure() {
+	suite.batchClient.EXPECT().
+		RestartFailedInstances(suite.ctx, gomock.Any()).
+		Return(nil, errors.New("test error"))
+
+	suite.Error(
+		suite.client.BatchRestartFailedAction(testJobID, testEntityVersion))
+}
+
+// TestParseBatchJobStates tests parsing job states from the command line
+func (suite *batchActionsTestSuite) TestParseBatchJobStates() {
+	suite.Nil(parseBatchJobStates(""))
+	suite.Equal(
+		[]batch.JobState{
+			batch.JobState_JOB_STATE_RUNNING,
+			batch.JobState_JOB_STATE_SUCCEEDED,
+		},
+		parseBatchJobStates("JOB_STATE_RUNNING,JOB_STATE_SUCCEEDED"),
+	)
+}
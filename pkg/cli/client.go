@@ -30,6 +30,7 @@ import (
 	updatesvc "github.com/uber/peloton/.gen/peloton/api/v0/update/svc"
 	volume_svc "github.com/uber/peloton/.gen/peloton/api/v0/volume/svc"
 	adminsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/admin/svc"
+	batchsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch/svc"
 	statelesssvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc"
 	podsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/pod/svc"
 	watchsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/watch/svc"
@@ -49,6 +50,7 @@ type Client struct {
 	taskClient      task.TaskManagerYARPCClient
 	podClient       podsvc.PodServiceYARPCClient
 	statelessClient statelesssvc.JobServiceYARPCClient
+	batchClient     batchsvc.JobServiceYARPCClient
 	watchClient     watchsvc.WatchServiceYARPCClient
 	resClient       respool.ResourceManagerYARPCClient
 	resMgrClient    resmgrsvc.ResourceManagerServiceYARPCClient
@@ -153,6 +155,9 @@ func New(
 		statelessClient: statelesssvc.NewJobServiceYARPCClient(
 			dispatcher.ClientConfig(common.PelotonJobManager),
 		),
+		batchClient: batchsvc.NewJobServiceYARPCClient(
+			dispatcher.ClientConfig(common.PelotonJobManager),
+		),
 		watchClient: watchsvc.NewWatchServiceYARPCClient(
 			dispatcher.ClientConfig(common.PelotonJobManager),
 		),
@@ -0,0 +1,728 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	adminsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/admin/svc"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+
+	jobmgrsecret "github.com/uber/peloton/pkg/jobmgr/secret"
+	jobmgrtask "github.com/uber/peloton/pkg/jobmgr/task"
+
+	"github.com/pkg/errors"
+	"go.uber.org/yarpc/yarpcerrors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// ClusterBundleVersion is the version of the cluster state bundle
+	// format written by export. Import refuses bundles with a newer version.
+	ClusterBundleVersion = 1
+
+	// ConflictPolicySkip leaves existing entities on the target cluster
+	// untouched.
+	ConflictPolicySkip = "skip"
+	// ConflictPolicyFail aborts the import on the first existing entity.
+	ConflictPolicyFail = "fail"
+	// ConflictPolicyOverwrite replaces existing entities with the ones
+	// in the bundle.
+	ConflictPolicyOverwrite = "overwrite"
+
+	_importPlanFormat = "%s\t%s\t%s\t%s\n"
+
+	// size of the AES-256 key encrypting secret data in bundles
+	_secretsKeySize = 32
+)
+
+// ClusterBundle is a portable snapshot of the state of a Peloton cluster.
+type ClusterBundle struct {
+	// Version of the bundle format
+	Version int `yaml:"version"`
+	// Time at which the bundle was exported, in RFC3339
+	ExportedAt string `yaml:"exported_at"`
+	// Resource pools, ordered such that parents precede their children
+	ResourcePools []*ClusterBundleResPool `yaml:"resource_pools"`
+	// Host pools along with their hosts
+	HostPools []*ClusterBundleHostPool `yaml:"host_pools"`
+	// Active jobs
+	Jobs []*ClusterBundleJob `yaml:"jobs"`
+}
+
+// ClusterBundleResPool is a resource pool in a cluster bundle. Resource
+// pools are identified by path since IDs are not portable across clusters.
+type ClusterBundleResPool struct {
+	Path   string                      `yaml:"path"`
+	Config *respool.ResourcePoolConfig `yaml:"config"`
+}
+
+// ClusterBundleHostPool is a host pool in a cluster bundle.
+type ClusterBundleHostPool struct {
+	Name  string   `yaml:"name"`
+	Hosts []string `yaml:"hosts,omitempty"`
+}
+
+// ClusterBundleSecret is a job secret in a cluster bundle.
+type ClusterBundleSecret struct {
+	// Mount path of the secret
+	Path string `yaml:"path"`
+	// Reference to the secret in an external secret store such as
+	// `vault:<path>`, set instead of the data for secrets Peloton does
+	// not hold
+	Reference string `yaml:"reference,omitempty"`
+	// Secret data encrypted with AES-256-GCM using the operator supplied
+	// key, as the base64 encoding of the nonce followed by the ciphertext
+	EncryptedData string `yaml:"encrypted_data,omitempty"`
+}
+
+// ClusterBundleJob is a job in a cluster bundle.
+type ClusterBundleJob struct {
+	// ID of the job on the source cluster
+	ID string `yaml:"id"`
+	// Path of the resource pool of the job
+	ResPoolPath string `yaml:"respool_path"`
+	// Version of the job configuration on the source cluster
+	ConfigVersion uint64 `yaml:"config_version"`
+	// Configuration of the job
+	Config *job.JobConfig `yaml:"config"`
+	// Secrets of the job
+	Secrets []*ClusterBundleSecret `yaml:"secrets,omitempty"`
+}
+
+// ClusterImportOptions controls how a cluster bundle is imported.
+type ClusterImportOptions struct {
+	// Print the import plan without changing the target cluster
+	DryRun bool
+	// One of ConflictPolicySkip, ConflictPolicyFail, ConflictPolicyOverwrite
+	OnConflict string
+	// Create jobs with their source IDs instead of new ones
+	KeepJobIDs bool
+	// File holding the base64 encoded key the secret data was exported
+	// with, required if any job has secrets
+	SecretsKeyFile string
+}
+
+// AdminExportAction exports the resource pools, host pools and active
+// jobs of the cluster to a bundle file. Secret data is encrypted with the
+// key in secretsKeyFile, which is required if any job has secrets.
+func (c *Client) AdminExportAction(
+	outputFile string,
+	format string,
+	secretsKeyFile string,
+) error {
+	var aead cipher.AEAD
+	if secretsKeyFile != "" {
+		var err error
+		if aead, err = loadSecretsKey(secretsKeyFile); err != nil {
+			return err
+		}
+	}
+
+	bundle, err := c.exportClusterBundle(aead)
+	if err != nil {
+		return err
+	}
+
+	out, err := marshall(format, bundle)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(outputFile, out, 0600); err != nil {
+		return fmt.Errorf("unable to write file %s: %v", outputFile, err)
+	}
+
+	fmt.Fprintf(
+		tabWriter,
+		"Exported %d resource pools, %d host pools and %d jobs to %s\n",
+		len(bundle.ResourcePools),
+		len(bundle.HostPools),
+		len(bundle.Jobs),
+		outputFile)
+	tabWriter.Flush()
+	return nil
+}
+
+func (c *Client) exportClusterBundle(
+	aead cipher.AEAD,
+) (*ClusterBundle, error) {
+	bundle := &ClusterBundle{
+		Version:    ClusterBundleVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	respools, err := c.resClient.Query(c.ctx, &respool.QueryRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if respools.GetError() != nil {
+		return nil, errors.New("error querying resource pools")
+	}
+
+	respoolPaths := make(map[string]string)
+	for _, p := range respools.GetResourcePools() {
+		path := p.GetPath().GetValue()
+		respoolPaths[p.GetId().GetValue()] = path
+		// the root resource pool exists on every cluster
+		if path == ResourcePoolPathDelim {
+			continue
+		}
+		config := *p.GetConfig()
+		config.Parent = nil
+		config.ChangeLog = nil
+		bundle.ResourcePools = append(
+			bundle.ResourcePools,
+			&ClusterBundleResPool{Path: path, Config: &config})
+	}
+	sortResPoolsByPath(bundle.ResourcePools)
+
+	hostPools, err := c.hostClient.ListHostPools(
+		c.ctx,
+		&host_svc.ListHostPoolsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range hostPools.GetPools() {
+		hosts := append([]string(nil), p.GetHosts()...)
+		sort.Strings(hosts)
+		bundle.HostPools = append(
+			bundle.HostPools,
+			&ClusterBundleHostPool{Name: p.GetName(), Hosts: hosts})
+	}
+	sort.Slice(bundle.HostPools, func(i, j int) bool {
+		return bundle.HostPools[i].Name < bundle.HostPools[j].Name
+	})
+
+	activeJobs, err := c.jobClient.GetActiveJobs(
+		c.ctx,
+		&job.GetActiveJobsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range activeJobs.GetIds() {
+		resp, err := c.jobGet(id.GetValue())
+		if err != nil {
+			return nil, err
+		}
+		if resp.GetError() != nil {
+			// the job may have been deleted since it was listed
+			if resp.GetError().GetNotFound() != nil {
+				continue
+			}
+			return nil, fmt.Errorf("error getting job %s", id.GetValue())
+		}
+
+		config := *resp.GetJobInfo().GetConfig()
+		bundleJob := &ClusterBundleJob{
+			ID:            id.GetValue(),
+			ResPoolPath:   respoolPaths[config.GetRespoolID().GetValue()],
+			ConfigVersion: config.GetChangeLog().GetVersion(),
+			Config:        &config,
+		}
+		config.RespoolID = nil
+		config.ChangeLog = nil
+		if len(resp.GetSecrets()) > 0 {
+			secrets, err := c.exportJobSecrets(id.GetValue(), aead)
+			if yarpcerrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			bundleJob.Secrets = secrets
+		}
+		bundle.Jobs = append(bundle.Jobs, bundleJob)
+	}
+	sort.Slice(bundle.Jobs, func(i, j int) bool {
+		return bundle.Jobs[i].ID < bundle.Jobs[j].ID
+	})
+
+	return bundle, nil
+}
+
+// exportJobSecrets returns the secrets of a job with their data encrypted.
+// Secrets from an external secret store are exported as references only.
+func (c *Client) exportJobSecrets(
+	jobID string,
+	aead cipher.AEAD,
+) ([]*ClusterBundleSecret, error) {
+	resp, err := c.adminClient.GetJobSecrets(
+		c.ctx,
+		&adminsvc.GetJobSecretsRequest{
+			JobId: &v1alphapeloton.JobID{Value: jobID},
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []*ClusterBundleSecret
+	for _, s := range resp.GetSecrets() {
+		if ref := jobmgrsecret.ParseReference(
+			s.GetSecretId().GetValue()); ref.IsExternal() {
+			secrets = append(secrets, &ClusterBundleSecret{
+				Path:      s.GetPath(),
+				Reference: ref.String(),
+			})
+			continue
+		}
+		if aead == nil {
+			return nil, fmt.Errorf("job %s has secrets, a secrets "+
+				"key file is required to export them", jobID)
+		}
+		data, err := base64.StdEncoding.DecodeString(
+			string(s.GetValue().GetData()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode secret %s of job %s: %v",
+				s.GetPath(), jobID, err)
+		}
+		encrypted, err := encryptSecret(aead, jobID, s.GetPath(), data)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, &ClusterBundleSecret{
+			Path:          s.GetPath(),
+			EncryptedData: encrypted,
+		})
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Path < secrets[j].Path
+	})
+	return secrets, nil
+}
+
+// AdminImportAction recreates the resource pools, host pools and jobs in
+// a bundle file on the cluster. Resource pool IDs are remapped by path and
+// jobs get new IDs unless KeepJobIDs is set.
+func (c *Client) AdminImportAction(
+	bundleFile string,
+	opts ClusterImportOptions,
+) error {
+	bundle, err := readClusterBundle(bundleFile)
+	if err != nil {
+		return err
+	}
+
+	switch opts.OnConflict {
+	case ConflictPolicySkip, ConflictPolicyFail, ConflictPolicyOverwrite:
+	default:
+		return fmt.Errorf("invalid conflict policy %s", opts.OnConflict)
+	}
+
+	var aead cipher.AEAD
+	if opts.SecretsKeyFile != "" {
+		if aead, err = loadSecretsKey(opts.SecretsKeyFile); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(tabWriter, _importPlanFormat, "Type", "Name", "Action", "Result")
+	defer tabWriter.Flush()
+
+	for _, p := range bundle.ResourcePools {
+		if err := c.importResPool(p, opts); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range bundle.HostPools {
+		if err := c.importHostPool(p, opts); err != nil {
+			return err
+		}
+	}
+
+	for _, j := range bundle.Jobs {
+		if err := c.importJob(j, aead, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readClusterBundle(bundleFile string) (*ClusterBundle, error) {
+	var bundle ClusterBundle
+	buffer, err := ioutil.ReadFile(bundleFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %v", bundleFile, err)
+	}
+	if err := yaml.Unmarshal(buffer, &bundle); err != nil {
+		return nil, fmt.Errorf("unable to parse file %s: %v", bundleFile, err)
+	}
+	if bundle.Version == 0 || bundle.Version > ClusterBundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d",
+			bundle.Version)
+	}
+	// parents have to be created before their children
+	sortResPoolsByPath(bundle.ResourcePools)
+	return &bundle, nil
+}
+
+func (c *Client) importResPool(
+	p *ClusterBundleResPool,
+	opts ClusterImportOptions,
+) error {
+	existingID, err := c.lookupResPoolIfExists(p.Path)
+	if err != nil {
+		return err
+	}
+
+	action := "create"
+	if existingID != nil {
+		switch opts.OnConflict {
+		case ConflictPolicyFail:
+			return fmt.Errorf("resource pool %s already exists", p.Path)
+		case ConflictPolicySkip:
+			printImportResult("respool", p.Path, "skip", "exists")
+			return nil
+		}
+		action = "overwrite"
+	}
+
+	if opts.DryRun {
+		printImportResult("respool", p.Path, action, "dry-run")
+		return nil
+	}
+
+	parentPath := parseParentPath(p.Path)
+	parentID, err := c.LookupResourcePoolID(parentPath)
+	if err != nil {
+		return err
+	}
+	if parentID == nil {
+		return errors.Errorf("unable to find resource pool ID "+
+			"for parent:%s", parentPath)
+	}
+	config := *p.Config
+	config.Parent = parentID
+
+	if existingID == nil {
+		resp, err := c.resClient.CreateResourcePool(
+			c.ctx,
+			&respool.CreateRequest{Config: &config})
+		if err != nil {
+			return err
+		}
+		if resp.GetError() != nil {
+			return fmt.Errorf("unable to create resource pool %s: %v",
+				p.Path, resp.GetError())
+		}
+	} else {
+		resp, err := c.resClient.UpdateResourcePool(
+			c.ctx,
+			&respool.UpdateRequest{Id: existingID, Config: &config})
+		if err != nil {
+			return err
+		}
+		if resp.GetError() != nil {
+			return fmt.Errorf("unable to update resource pool %s: %v",
+				p.Path, resp.GetError())
+		}
+	}
+	printImportResult("respool", p.Path, action, "done")
+	return nil
+}
+
+// lookupResPoolIfExists returns the ID of the resource pool at the given
+// path, or nil if there is no such resource pool.
+func (c *Client) lookupResPoolIfExists(
+	path string,
+) (*peloton.ResourcePoolID, error) {
+	resp, err := c.resClient.LookupResourcePoolID(
+		c.ctx,
+		&respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: path},
+		})
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetError().GetNotFound() != nil {
+		return nil, nil
+	}
+	if resp.GetError() != nil {
+		return nil, fmt.Errorf("unable to lookup resource pool %s: %v",
+			path, resp.GetError())
+	}
+	return resp.GetId(), nil
+}
+
+// importHostPool creates the host pool if needed and moves the hosts of
+// the bundle pool into it. Hosts missing from the cluster are reported and
+// skipped, and hosts of an existing pool which are not in the bundle are
+// left in it.
+func (c *Client) importHostPool(
+	p *ClusterBundleHostPool,
+	opts ClusterImportOptions,
+) error {
+	resp, err := c.hostClient.ListHostPools(
+		c.ctx,
+		&host_svc.ListHostPoolsRequest{})
+	if err != nil {
+		return err
+	}
+
+	exists := false
+	// current pool of each host of the cluster
+	hostPools := make(map[string]string)
+	for _, pool := range resp.GetPools() {
+		if pool.GetName() == p.Name {
+			exists = true
+		}
+		for _, host := range pool.GetHosts() {
+			hostPools[host] = pool.GetName()
+		}
+	}
+
+	action := "create"
+	if exists {
+		switch opts.OnConflict {
+		case ConflictPolicyFail:
+			return fmt.Errorf("host pool %s already exists", p.Name)
+		case ConflictPolicySkip:
+			printImportResult("hostpool", p.Name, "skip", "exists")
+			return nil
+		}
+		action = "overwrite"
+	}
+
+	if opts.DryRun {
+		printImportResult("hostpool", p.Name, action, "dry-run")
+	} else {
+		if !exists {
+			if _, err := c.hostClient.CreateHostPool(
+				c.ctx,
+				&host_svc.CreateHostPoolRequest{Name: p.Name},
+			); err != nil {
+				return err
+			}
+		}
+		printImportResult("hostpool", p.Name, action, "done")
+	}
+
+	for _, host := range p.Hosts {
+		current, ok := hostPools[host]
+		switch {
+		case !ok:
+			printImportResult("host", host, "move", "not found")
+		case current == p.Name:
+		case opts.DryRun:
+			printImportResult("host", host, "move", "dry-run")
+		default:
+			if _, err := c.hostClient.ChangeHostPool(
+				c.ctx,
+				&host_svc.ChangeHostPoolRequest{
+					Hostname:        host,
+					SourcePool:      current,
+					DestinationPool: p.Name,
+				},
+			); err != nil {
+				return err
+			}
+			printImportResult("host", host, "move", p.Name)
+		}
+	}
+	return nil
+}
+
+func (c *Client) importJob(
+	j *ClusterBundleJob,
+	aead cipher.AEAD,
+	opts ClusterImportOptions,
+) error {
+	var jobSecrets []*peloton.Secret
+	for _, s := range j.Secrets {
+		if s.Reference != "" {
+			if !jobmgrsecret.ParseReference(s.Reference).IsExternal() {
+				return fmt.Errorf("secret %s of job %s has an invalid "+
+					"reference %s", s.Path, j.ID, s.Reference)
+			}
+			jobSecrets = append(
+				jobSecrets,
+				jobmgrtask.CreateSecretProto(s.Reference, s.Path, nil))
+			continue
+		}
+		if aead == nil {
+			return fmt.Errorf("job %s has secrets, a secrets key file is "+
+				"required to import them", j.ID)
+		}
+		data, err := decryptSecret(aead, j.ID, s.Path, s.EncryptedData)
+		if err != nil {
+			return fmt.Errorf("unable to decrypt secret %s of job %s: %v",
+				s.Path, j.ID, err)
+		}
+		jobSecrets = append(
+			jobSecrets,
+			jobmgrtask.CreateSecretProto("", s.Path, data))
+	}
+
+	var jobID string
+	exists := false
+	if opts.KeepJobIDs {
+		jobID = j.ID
+		resp, err := c.jobGet(jobID)
+		if err != nil {
+			return err
+		}
+		exists = resp.GetJobInfo() != nil
+	}
+
+	action := "create"
+	if exists {
+		switch opts.OnConflict {
+		case ConflictPolicyFail:
+			return fmt.Errorf("job %s already exists", j.ID)
+		case ConflictPolicySkip:
+			printImportResult("job", j.ID, "skip", "exists")
+			return nil
+		}
+		action = "overwrite"
+	}
+
+	respoolID, err := c.lookupResPoolIfExists(j.ResPoolPath)
+	if err != nil {
+		return err
+	}
+	if respoolID == nil && !opts.DryRun {
+		return fmt.Errorf("unable to find resource pool %s for job %s",
+			j.ResPoolPath, j.ID)
+	}
+
+	if opts.DryRun {
+		printImportResult("job", j.ID, action, "dry-run")
+		return nil
+	}
+
+	config := *j.Config
+	config.RespoolID = respoolID
+
+	if exists {
+		resp, err := c.jobClient.Update(c.ctx, &job.UpdateRequest{
+			Id:      &peloton.JobID{Value: jobID},
+			Config:  &config,
+			Secrets: jobSecrets,
+		})
+		if err != nil {
+			return err
+		}
+		if resp.GetError() != nil {
+			return fmt.Errorf("unable to update job %s: %v",
+				jobID, resp.GetError())
+		}
+		printImportResult("job", j.ID, action, jobID)
+		return nil
+	}
+
+	resp, err := c.jobClient.Create(c.ctx, &job.CreateRequest{
+		Id:      &peloton.JobID{Value: jobID},
+		Config:  &config,
+		Secrets: jobSecrets,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.GetError() != nil {
+		return fmt.Errorf("unable to create job %s: %v",
+			j.ID, resp.GetError())
+	}
+	// print the new job ID so that callers can remap references
+	printImportResult("job", j.ID, action, resp.GetJobId().GetValue())
+	return nil
+}
+
+// loadSecretsKey reads the key encrypting secret data in bundles. The file
+// holds the base64 encoding of a 32 byte AES-256 key.
+func loadSecretsKey(keyFile string) (cipher.AEAD, error) {
+	buffer, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %v", keyFile, err)
+	}
+	key, err := base64.StdEncoding.DecodeString(
+		strings.TrimSpace(string(buffer)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode key in %s: %v", keyFile, err)
+	}
+	if len(key) != _secretsKeySize {
+		return nil, fmt.Errorf("key in %s must be %d bytes, got %d",
+			keyFile, _secretsKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptSecret encrypts the data of a secret. The job ID and mount path
+// are authenticated so that encrypted data cannot be moved to another
+// secret of the bundle.
+func encryptSecret(
+	aead cipher.AEAD,
+	jobID, path string,
+	data []byte,
+) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, data, []byte(jobID+":"+path))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret decrypts the data of a secret encrypted by encryptSecret.
+func decryptSecret(
+	aead cipher.AEAD,
+	jobID, path string,
+	encrypted string,
+) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted data too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(jobID+":"+path))
+}
+
+func printImportResult(entity, name, action, result string) {
+	fmt.Fprintf(tabWriter, _importPlanFormat, entity, name, action, result)
+}
+
+// sortResPoolsByPath orders resource pools by depth and then by path.
+func sortResPoolsByPath(pools []*ClusterBundleResPool) {
+	sort.Slice(pools, func(i, j int) bool {
+		di, dj := resPoolDepth(pools[i].Path), resPoolDepth(pools[j].Path)
+		if di != dj {
+			return di < dj
+		}
+		return pools[i].Path < pools[j].Path
+	})
+}
+
+func resPoolDepth(resourcePoolPath string) int {
+	resourcePoolPath = strings.TrimSuffix(resourcePoolPath, ResourcePoolPathDelim)
+	return strings.Count(resourcePoolPath, ResourcePoolPathDelim)
+}
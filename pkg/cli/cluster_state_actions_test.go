@@ -0,0 +1,589 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pberrors "github.com/uber/peloton/.gen/peloton/api/v0/errors"
+	pb_host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	adminsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/admin/svc"
+	adminmocks "github.com/uber/peloton/.gen/peloton/api/v1alpha/admin/svc/mocks"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+type clusterStateActionsTestSuite struct {
+	suite.Suite
+	mockCtrl    *gomock.Controller
+	mockJob     *jobmocks.MockJobManagerYARPCClient
+	mockRespool *respoolmocks.MockResourceManagerYARPCClient
+	mockHost    *hostmocks.MockHostServiceYARPCClient
+	mockAdmin   *adminmocks.MockAdminServiceYARPCClient
+	ctx         context.Context
+	client      Client
+	dir         string
+}
+
+func TestClusterStateActions(t *testing.T) {
+	suite.Run(t, new(clusterStateActionsTestSuite))
+}
+
+func (suite *clusterStateActionsTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.mockRespool = respoolmocks.NewMockResourceManagerYARPCClient(
+		suite.mockCtrl)
+	suite.mockHost = hostmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.mockAdmin = adminmocks.NewMockAdminServiceYARPCClient(suite.mockCtrl)
+	suite.ctx = context.Background()
+	suite.client = Client{
+		Debug:       false,
+		resClient:   suite.mockRespool,
+		jobClient:   suite.mockJob,
+		hostClient:  suite.mockHost,
+		adminClient: suite.mockAdmin,
+		dispatcher:  nil,
+		ctx:         suite.ctx,
+	}
+
+	var err error
+	suite.dir, err = ioutil.TempDir("", "cluster-bundle")
+	suite.NoError(err)
+}
+
+func (suite *clusterStateActionsTestSuite) TearDownTest() {
+	suite.mockCtrl.Finish()
+	os.RemoveAll(suite.dir)
+}
+
+// writeBundle writes the bundle to a file and returns its path
+func (suite *clusterStateActionsTestSuite) writeBundle(
+	bundle *ClusterBundle,
+) string {
+	out, err := yaml.Marshal(bundle)
+	suite.NoError(err)
+	path := filepath.Join(suite.dir, "bundle.yaml")
+	suite.NoError(ioutil.WriteFile(path, out, 0600))
+	return path
+}
+
+// writeKey writes a new secrets key to a file and returns its path
+func (suite *clusterStateActionsTestSuite) writeKey(name string) string {
+	key := make([]byte, _secretsKeySize)
+	_, err := rand.Read(key)
+	suite.NoError(err)
+	path := filepath.Join(suite.dir, name)
+	suite.NoError(ioutil.WriteFile(
+		path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+	return path
+}
+
+func (suite *clusterStateActionsTestSuite) testBundle() *ClusterBundle {
+	return &ClusterBundle{
+		Version: ClusterBundleVersion,
+		ResourcePools: []*ClusterBundleResPool{
+			{
+				Path:   "/infra/batch",
+				Config: &respool.ResourcePoolConfig{Name: "batch"},
+			},
+			{
+				Path:   "/infra",
+				Config: &respool.ResourcePoolConfig{Name: "infra"},
+			},
+		},
+		HostPools: []*ClusterBundleHostPool{
+			{Name: "shared", Hosts: []string{"host1", "host2"}},
+		},
+		Jobs: []*ClusterBundleJob{
+			{
+				ID:            "job1",
+				ResPoolPath:   "/infra/batch",
+				ConfigVersion: 3,
+				Config: &job.JobConfig{
+					Name:          "job1",
+					InstanceCount: 2,
+				},
+			},
+		},
+	}
+}
+
+func (suite *clusterStateActionsTestSuite) expectLookup(
+	path string,
+	id string,
+) *gomock.Call {
+	resp := &respool.LookupResponse{}
+	if id == "" {
+		resp.Error = &respool.LookupResponse_Error{
+			NotFound: &respool.ResourcePoolPathNotFound{},
+		}
+	} else {
+		resp.Id = &peloton.ResourcePoolID{Value: id}
+	}
+	return suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), &respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: path},
+		}).
+		Return(resp, nil)
+}
+
+// TestAdminExport tests exporting the cluster state to a bundle
+func (suite *clusterStateActionsTestSuite) TestAdminExport() {
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{
+			ResourcePools: []*respool.ResourcePoolInfo{
+				{
+					Id:     &peloton.ResourcePoolID{Value: "root"},
+					Path:   &respool.ResourcePoolPath{Value: "/"},
+					Config: &respool.ResourcePoolConfig{Name: "root"},
+				},
+				{
+					Id:   &peloton.ResourcePoolID{Value: "rp2"},
+					Path: &respool.ResourcePoolPath{Value: "/infra/batch"},
+					Config: &respool.ResourcePoolConfig{
+						Name:   "batch",
+						Parent: &peloton.ResourcePoolID{Value: "rp1"},
+					},
+				},
+				{
+					Id:   &peloton.ResourcePoolID{Value: "rp1"},
+					Path: &respool.ResourcePoolPath{Value: "/infra"},
+					Config: &respool.ResourcePoolConfig{
+						Name:   "infra",
+						Parent: &peloton.ResourcePoolID{Value: "root"},
+					},
+				},
+			},
+		}, nil)
+	suite.mockHost.EXPECT().
+		ListHostPools(gomock.Any(), &host_svc.ListHostPoolsRequest{}).
+		Return(&host_svc.ListHostPoolsResponse{
+			Pools: []*pb_host.HostPoolInfo{
+				{Name: "shared", Hosts: []string{"host2", "host1"}},
+				{Name: "default"},
+			},
+		}, nil)
+	suite.mockJob.EXPECT().
+		GetActiveJobs(gomock.Any(), &job.GetActiveJobsRequest{}).
+		Return(&job.GetActiveJobsResponse{
+			Ids: []*peloton.JobID{{Value: "job1"}, {Value: "job2"}},
+		}, nil)
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: &peloton.JobID{Value: "job1"}}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Config: &job.JobConfig{
+					Name:      "job1",
+					RespoolID: &peloton.ResourcePoolID{Value: "rp2"},
+					ChangeLog: &peloton.ChangeLog{Version: 3},
+				},
+			},
+			Secrets: []*peloton.Secret{{Path: "/tmp/secret"}},
+		}, nil)
+	suite.mockAdmin.EXPECT().
+		GetJobSecrets(gomock.Any(), &adminsvc.GetJobSecretsRequest{
+			JobId: &v1alphapeloton.JobID{Value: "job1"},
+		}).
+		Return(&adminsvc.GetJobSecretsResponse{
+			Secrets: []*v1alphapeloton.Secret{
+				{
+					Path: "/tmp/secret",
+					Value: &v1alphapeloton.Secret_Value{
+						Data: []byte(base64.StdEncoding.EncodeToString(
+							[]byte("my-secret"))),
+					},
+				},
+				{
+					SecretId: &v1alphapeloton.SecretID{
+						Value: "vault:db/password@3",
+					},
+					Path:  "/tmp/vault",
+					Value: &v1alphapeloton.Secret_Value{},
+				},
+			},
+		}, nil)
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: &peloton.JobID{Value: "job2"}}).
+		Return(&job.GetResponse{
+			Error: &job.GetResponse_Error{
+				NotFound: &pberrors.JobNotFound{},
+			},
+		}, nil)
+
+	keyFile := suite.writeKey("secrets.key")
+	path := filepath.Join(suite.dir, "export.yaml")
+	suite.NoError(suite.client.AdminExportAction(path, "yaml", keyFile))
+
+	bundle, err := readClusterBundle(path)
+	suite.NoError(err)
+	suite.Equal(ClusterBundleVersion, bundle.Version)
+	suite.Len(bundle.ResourcePools, 2)
+	suite.Equal("/infra", bundle.ResourcePools[0].Path)
+	suite.Nil(bundle.ResourcePools[0].Config.GetParent())
+	suite.Equal("/infra/batch", bundle.ResourcePools[1].Path)
+	suite.Equal([]*ClusterBundleHostPool{
+		{Name: "default"},
+		{Name: "shared", Hosts: []string{"host1", "host2"}},
+	}, bundle.HostPools)
+	suite.Len(bundle.Jobs, 1)
+	suite.Equal("job1", bundle.Jobs[0].ID)
+	suite.Equal("/infra/batch", bundle.Jobs[0].ResPoolPath)
+	suite.Equal(uint64(3), bundle.Jobs[0].ConfigVersion)
+	suite.Nil(bundle.Jobs[0].Config.GetRespoolID())
+	suite.Nil(bundle.Jobs[0].Config.GetChangeLog())
+	suite.Len(bundle.Jobs[0].Secrets, 2)
+	suite.Equal("/tmp/secret", bundle.Jobs[0].Secrets[0].Path)
+	suite.Empty(bundle.Jobs[0].Secrets[0].Reference)
+	suite.NotContains(bundle.Jobs[0].Secrets[0].EncryptedData, "my-secret")
+	// secrets from an external secret store are exported as references
+	suite.Equal(&ClusterBundleSecret{
+		Path:      "/tmp/vault",
+		Reference: "vault:db/password@3",
+	}, bundle.Jobs[0].Secrets[1])
+
+	aead, err := loadSecretsKey(keyFile)
+	suite.NoError(err)
+	data, err := decryptSecret(
+		aead, "job1", "/tmp/secret", bundle.Jobs[0].Secrets[0].EncryptedData)
+	suite.NoError(err)
+	suite.Equal("my-secret", string(data))
+}
+
+// TestAdminExportSecretsWithoutKey tests that jobs with secrets cannot be
+// exported without a secrets key
+func (suite *clusterStateActionsTestSuite) TestAdminExportSecretsWithoutKey() {
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{}, nil)
+	suite.mockHost.EXPECT().
+		ListHostPools(gomock.Any(), &host_svc.ListHostPoolsRequest{}).
+		Return(&host_svc.ListHostPoolsResponse{}, nil)
+	suite.mockJob.EXPECT().
+		GetActiveJobs(gomock.Any(), &job.GetActiveJobsRequest{}).
+		Return(&job.GetActiveJobsResponse{
+			Ids: []*peloton.JobID{{Value: "job1"}},
+		}, nil)
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: &peloton.JobID{Value: "job1"}}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{Config: &job.JobConfig{Name: "job1"}},
+			Secrets: []*peloton.Secret{{Path: "/tmp/secret"}},
+		}, nil)
+	suite.mockAdmin.EXPECT().
+		GetJobSecrets(gomock.Any(), &adminsvc.GetJobSecretsRequest{
+			JobId: &v1alphapeloton.JobID{Value: "job1"},
+		}).
+		Return(&adminsvc.GetJobSecretsResponse{
+			Secrets: []*v1alphapeloton.Secret{
+				{
+					SecretId: &v1alphapeloton.SecretID{Value: "secret-id"},
+					Path:     "/tmp/secret",
+					Value: &v1alphapeloton.Secret_Value{
+						Data: []byte(base64.StdEncoding.EncodeToString(
+							[]byte("my-secret"))),
+					},
+				},
+			},
+		}, nil)
+
+	suite.Error(suite.client.AdminExportAction(
+		filepath.Join(suite.dir, "export.yaml"), "yaml", ""))
+}
+
+// TestAdminImport tests importing a bundle into an empty cluster
+func (suite *clusterStateActionsTestSuite) TestAdminImport() {
+	gomock.InOrder(
+		suite.expectLookup("/infra", ""),
+		suite.expectLookup("/", "root"),
+		suite.mockRespool.EXPECT().
+			CreateResourcePool(gomock.Any(), &respool.CreateRequest{
+				Config: &respool.ResourcePoolConfig{
+					Name:   "infra",
+					Parent: &peloton.ResourcePoolID{Value: "root"},
+				},
+			}).
+			Return(&respool.CreateResponse{
+				Result: &peloton.ResourcePoolID{Value: "new-rp1"},
+			}, nil),
+		suite.expectLookup("/infra/batch", ""),
+		suite.expectLookup("/infra/", "new-rp1"),
+		suite.mockRespool.EXPECT().
+			CreateResourcePool(gomock.Any(), &respool.CreateRequest{
+				Config: &respool.ResourcePoolConfig{
+					Name:   "batch",
+					Parent: &peloton.ResourcePoolID{Value: "new-rp1"},
+				},
+			}).
+			Return(&respool.CreateResponse{
+				Result: &peloton.ResourcePoolID{Value: "new-rp2"},
+			}, nil),
+	)
+	suite.mockHost.EXPECT().
+		ListHostPools(gomock.Any(), &host_svc.ListHostPoolsRequest{}).
+		Return(&host_svc.ListHostPoolsResponse{
+			Pools: []*pb_host.HostPoolInfo{
+				{Name: "default", Hosts: []string{"host1"}},
+			},
+		}, nil)
+	suite.mockHost.EXPECT().
+		CreateHostPool(
+			gomock.Any(),
+			&host_svc.CreateHostPoolRequest{Name: "shared"}).
+		Return(&host_svc.CreateHostPoolResponse{}, nil)
+	// host2 is not part of the target cluster, so only host1 is moved
+	suite.mockHost.EXPECT().
+		ChangeHostPool(gomock.Any(), &host_svc.ChangeHostPoolRequest{
+			Hostname:        "host1",
+			SourcePool:      "default",
+			DestinationPool: "shared",
+		}).
+		Return(&host_svc.ChangeHostPoolResponse{}, nil)
+	suite.expectLookup("/infra/batch", "new-rp2")
+	suite.mockJob.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.CreateRequest) {
+			suite.Empty(req.GetId().GetValue())
+			suite.Equal("new-rp2", req.GetConfig().GetRespoolID().GetValue())
+		}).
+		Return(&job.CreateResponse{
+			JobId: &peloton.JobID{Value: "new-job1"},
+		}, nil)
+
+	suite.NoError(suite.client.AdminImportAction(
+		suite.writeBundle(suite.testBundle()),
+		ClusterImportOptions{OnConflict: ConflictPolicySkip}))
+}
+
+// TestAdminImportDryRun tests that dry-run does not change the cluster
+func (suite *clusterStateActionsTestSuite) TestAdminImportDryRun() {
+	suite.expectLookup("/infra", "")
+	suite.expectLookup("/infra/batch", "").Times(2)
+	suite.mockHost.EXPECT().
+		ListHostPools(gomock.Any(), &host_svc.ListHostPoolsRequest{}).
+		Return(&host_svc.ListHostPoolsResponse{
+			Pools: []*pb_host.HostPoolInfo{
+				{Name: "default", Hosts: []string{"host1"}},
+			},
+		}, nil)
+
+	suite.NoError(suite.client.AdminImportAction(
+		suite.writeBundle(suite.testBundle()),
+		ClusterImportOptions{DryRun: true, OnConflict: ConflictPolicySkip}))
+}
+
+// TestAdminImportConflicts tests the conflict policies
+func (suite *clusterStateActionsTestSuite) TestAdminImportConflicts() {
+	bundle := suite.testBundle()
+	bundle.ResourcePools = bundle.ResourcePools[1:]
+	bundle.HostPools = nil
+	path := suite.writeBundle(bundle)
+
+	// fail on an existing resource pool
+	suite.expectLookup("/infra", "rp1")
+	suite.Error(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{OnConflict: ConflictPolicyFail}))
+
+	// overwrite an existing resource pool and job
+	suite.expectLookup("/infra", "rp1")
+	suite.expectLookup("/", "root")
+	suite.mockRespool.EXPECT().
+		UpdateResourcePool(gomock.Any(), &respool.UpdateRequest{
+			Id: &peloton.ResourcePoolID{Value: "rp1"},
+			Config: &respool.ResourcePoolConfig{
+				Name:   "infra",
+				Parent: &peloton.ResourcePoolID{Value: "root"},
+			},
+		}).
+		Return(&respool.UpdateResponse{}, nil)
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: &peloton.JobID{Value: "job1"}}).
+		Return(&job.GetResponse{JobInfo: &job.JobInfo{}}, nil)
+	suite.expectLookup("/infra/batch", "rp2")
+	suite.mockJob.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.UpdateRequest) {
+			suite.Equal("job1", req.GetId().GetValue())
+			suite.Equal("rp2", req.GetConfig().GetRespoolID().GetValue())
+		}).
+		Return(&job.UpdateResponse{}, nil)
+	suite.NoError(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{
+			OnConflict: ConflictPolicyOverwrite,
+			KeepJobIDs: true,
+		}))
+
+	suite.Error(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{OnConflict: "merge"}))
+}
+
+// TestAdminImportHostPoolConflicts tests the conflict policies of host
+// pools
+func (suite *clusterStateActionsTestSuite) TestAdminImportHostPoolConflicts() {
+	bundle := suite.testBundle()
+	bundle.ResourcePools = nil
+	bundle.Jobs = nil
+	path := suite.writeBundle(bundle)
+
+	suite.mockHost.EXPECT().
+		ListHostPools(gomock.Any(), &host_svc.ListHostPoolsRequest{}).
+		Return(&host_svc.ListHostPoolsResponse{
+			Pools: []*pb_host.HostPoolInfo{
+				{Name: "shared", Hosts: []string{"host2"}},
+				{Name: "default", Hosts: []string{"host1"}},
+			},
+		}, nil).
+		Times(3)
+
+	// fail on an existing host pool
+	suite.Error(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{OnConflict: ConflictPolicyFail}))
+
+	// skip leaves the membership of the existing host pool untouched
+	suite.NoError(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{OnConflict: ConflictPolicySkip}))
+
+	// overwrite moves the hosts of the bundle into the existing host pool
+	suite.mockHost.EXPECT().
+		ChangeHostPool(gomock.Any(), &host_svc.ChangeHostPoolRequest{
+			Hostname:        "host1",
+			SourcePool:      "default",
+			DestinationPool: "shared",
+		}).
+		Return(&host_svc.ChangeHostPoolResponse{}, nil)
+	suite.NoError(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{OnConflict: ConflictPolicyOverwrite}))
+}
+
+// TestAdminImportSecrets tests that secret data is decrypted with the
+// secrets key
+func (suite *clusterStateActionsTestSuite) TestAdminImportSecrets() {
+	keyFile := suite.writeKey("secrets.key")
+	aead, err := loadSecretsKey(keyFile)
+	suite.NoError(err)
+	encrypted, err := encryptSecret(
+		aead, "job1", "/tmp/secret", []byte("my-secret"))
+	suite.NoError(err)
+
+	bundle := suite.testBundle()
+	bundle.ResourcePools = nil
+	bundle.HostPools = nil
+	bundle.Jobs[0].Secrets = []*ClusterBundleSecret{
+		{Path: "/tmp/secret", EncryptedData: encrypted},
+	}
+	path := suite.writeBundle(bundle)
+
+	// the secrets key is missing
+	suite.Error(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{OnConflict: ConflictPolicySkip}))
+
+	// the secrets key is not the one the bundle was exported with
+	suite.Error(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{
+			OnConflict:     ConflictPolicySkip,
+			SecretsKeyFile: suite.writeKey("other.key"),
+		}))
+
+	suite.expectLookup("/infra/batch", "rp2")
+	suite.mockJob.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.CreateRequest) {
+			suite.Len(req.GetSecrets(), 1)
+			suite.Equal("/tmp/secret", req.GetSecrets()[0].GetPath())
+			suite.Equal(
+				base64.StdEncoding.EncodeToString([]byte("my-secret")),
+				string(req.GetSecrets()[0].GetValue().GetData()))
+		}).
+		Return(&job.CreateResponse{
+			JobId: &peloton.JobID{Value: "new-job1"},
+		}, nil)
+	suite.NoError(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{
+			OnConflict:     ConflictPolicySkip,
+			SecretsKeyFile: keyFile,
+		}))
+}
+
+// TestAdminImportSecretReferences tests that secrets from an external
+// secret store are recreated as references, without a secrets key
+func (suite *clusterStateActionsTestSuite) TestAdminImportSecretReferences() {
+	bundle := suite.testBundle()
+	bundle.ResourcePools = nil
+	bundle.HostPools = nil
+	bundle.Jobs[0].Secrets = []*ClusterBundleSecret{
+		{Path: "/tmp/vault", Reference: "vault:db/password@3"},
+	}
+	path := suite.writeBundle(bundle)
+
+	suite.expectLookup("/infra/batch", "rp2")
+	suite.mockJob.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.CreateRequest) {
+			suite.Len(req.GetSecrets(), 1)
+			suite.Equal(
+				"vault:db/password@3", req.GetSecrets()[0].GetId().GetValue())
+			suite.Equal("/tmp/vault", req.GetSecrets()[0].GetPath())
+			suite.Empty(req.GetSecrets()[0].GetValue().GetData())
+		}).
+		Return(&job.CreateResponse{
+			JobId: &peloton.JobID{Value: "new-job1"},
+		}, nil)
+	suite.NoError(suite.client.AdminImportAction(
+		path,
+		ClusterImportOptions{OnConflict: ConflictPolicySkip}))
+
+	// a reference to a secret stored by Peloton is rejected
+	bundle.Jobs[0].Secrets[0].Reference = "secret-id"
+	suite.Error(suite.client.AdminImportAction(
+		suite.writeBundle(bundle),
+		ClusterImportOptions{OnConflict: ConflictPolicySkip}))
+}
+
+// TestAdminImportUnsupportedVersion tests that bundles from a newer
+// version are rejected
+func (suite *clusterStateActionsTestSuite) TestAdminImportUnsupportedVersion() {
+	bundle := suite.testBundle()
+	bundle.Version = ClusterBundleVersion + 1
+
+	suite.Error(suite.client.AdminImportAction(
+		suite.writeBundle(bundle),
+		ClusterImportOptions{OnConflict: ConflictPolicySkip}))
+}
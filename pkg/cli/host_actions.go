@@ -15,12 +15,14 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
 	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	pb_task "github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	host_svc_v1 "github.com/uber/peloton/.gen/peloton/private/hostmgr/v1alpha/svc"
@@ -29,13 +31,15 @@ import (
 )
 
 const (
-	hostQueryFormatHeader = "Hostname\tIP\tState\n"
-	hostQueryFormatBody   = "%s\t%s\t%s\n"
+	hostQueryFormatHeader = "Hostname\tIP\tState\tFailed\tLost\tLaunch Failures\tQuarantined Until\n"
+	hostQueryFormatBody   = "%s\t%s\t%s\t%d\t%d\t%d\t%s\n"
 	hostSeparator         = ","
 	getHostsFormatHeader  = "Hostname\tCPU\tGPU\tMEM\tDisk\tState\t Task Hold\t Task Running\n"
 	getHostsFormatBody    = "%s\t%.2f\t%.2f\t%.2f MB\t%.2f MB\t%s\t%s\t%s\n"
 	hostCacheFormatHeader = "Hostname\tCPU\tGPU\tMEM\tDisk\tStatus\n"
 	hostCacheFormatBody   = "%s\t%.2f/%.2f\t%.2f/%.2f\t%.2f/%.2f MB\t%.2f/%.2f MB\t%s\n"
+	reconcileFormatHeader = "Task ID\tHostname\tPeloton State\tMesos State\n"
+	reconcileFormatBody   = "%s\t%s\t%s\t%s\n"
 )
 
 // HostCacheDump dumps the contents of the host cache.
@@ -158,6 +162,10 @@ func printHostQueryResponse(r *host_svc.QueryHostsResponse, debug bool) {
 				h.GetHostname(),
 				h.GetIp(),
 				h.GetState(),
+				h.GetHealth().GetFailedTasks(),
+				h.GetHealth().GetLostTasks(),
+				h.GetHealth().GetLaunchFailures(),
+				h.GetHealth().GetQuarantineReleaseTime(),
 			)
 		}
 	}
@@ -268,3 +276,95 @@ func (c *Client) DisableKillTasksAction() error {
 	tabWriter.Flush()
 	return nil
 }
+
+// HostMgrReconcileAction explicitly reconciles the tasks of a job, of a host
+// or all tasks with Mesos master, and prints the tasks on which Peloton and
+// Mesos disagree. Orphan tasks unknown to Peloton are killed if requested.
+func (c *Client) HostMgrReconcileAction(
+	jobID string,
+	hostname string,
+	killOrphans bool) error {
+	if jobID != "" && hostname != "" {
+		return fmt.Errorf("only one of job and host can be specified")
+	}
+
+	req := &hostsvc.ReconcileTasksRequest{
+		Hostname:    hostname,
+		KillOrphans: killOrphans,
+	}
+	if jobID != "" {
+		req.JobId = &peloton.JobID{Value: jobID}
+	}
+
+	resp, err := c.hostMgrClient.ReconcileTasks(c.ctx, req)
+	if err != nil {
+		return err
+	}
+
+	printReconcileTasksResponse(resp, c.Debug)
+	if resp.GetError() != nil {
+		return errors.New(resp.GetError().GetMessage())
+	}
+	return nil
+}
+
+func printReconcileTasksResponse(
+	resp *hostsvc.ReconcileTasksResponse,
+	debug bool) {
+	defer tabWriter.Flush()
+
+	if debug {
+		printResponseJSON(resp)
+		return
+	}
+
+	report := resp.GetReport()
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintf(
+		tabWriter,
+		"Reconciled %d Peloton tasks and %d Mesos tasks\n",
+		report.GetPelotonTasks(),
+		report.GetMesosTasks(),
+	)
+
+	printReconciledTasks(
+		"Tasks missing in Mesos",
+		report.GetMissingInMesos())
+	printReconciledTasks(
+		"Orphan tasks unknown to Peloton",
+		report.GetOrphans())
+
+	if len(report.GetKilledOrphans()) > 0 {
+		fmt.Fprintf(tabWriter, "Killed %d orphan tasks:\n", len(report.GetKilledOrphans()))
+		for _, taskID := range report.GetKilledOrphans() {
+			fmt.Fprintf(tabWriter, "%s\n", taskID.GetValue())
+		}
+	}
+}
+
+func printReconciledTasks(title string, tasks []*hostsvc.ReconciledTask) {
+	if len(tasks) == 0 {
+		fmt.Fprintf(tabWriter, "%s: none\n", title)
+		return
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].GetTaskId().GetValue() < tasks[j].GetTaskId().GetValue()
+	})
+
+	fmt.Fprintf(tabWriter, "%s: %d\n", title, len(tasks))
+	fmt.Fprint(tabWriter, reconcileFormatHeader)
+	for _, t := range tasks {
+		fmt.Fprintf(
+			tabWriter,
+			reconcileFormatBody,
+			t.GetTaskId().GetValue(),
+			t.GetHostname(),
+			t.GetPelotonState(),
+			t.GetMesosState(),
+		)
+	}
+}
@@ -140,6 +140,25 @@ func (suite *hostmgrActionsTestSuite) TestClientHostQueryAction() {
 			},
 			err: nil,
 		},
+		{
+			resp: &hostsvc.QueryHostsResponse{
+				HostInfos: []*host.HostInfo{
+					{
+						Hostname: "host0",
+						State:    host.HostState_HOST_STATE_UP,
+						Health: &host.HostHealth{
+							TerminalTasks:         5,
+							FailedTasks:           4,
+							LaunchFailures:        4,
+							Quarantined:           true,
+							QuarantineReleaseTime: "2019-07-01T00:05:00Z",
+							QuarantineCount:       1,
+						},
+					},
+				},
+			},
+			err: nil,
+		},
 		{
 			resp: &hostsvc.QueryHostsResponse{
 				HostInfos: []*host.HostInfo{},
@@ -270,6 +289,52 @@ func (suite *hostmgrActionsInternalTestSuite) TestDisableKillTasks() {
 	suite.NoError(c.DisableKillTasksAction())
 }
 
+func (suite *hostmgrActionsInternalTestSuite) TestHostMgrReconcileAction() {
+	c := Client{
+		Debug:         false,
+		hostMgrClient: suite.mockHostMgr,
+		dispatcher:    nil,
+		ctx:           suite.ctx,
+	}
+
+	taskID := "task"
+	resp := &hostmgrsvc.ReconcileTasksResponse{
+		Report: &hostmgrsvc.ReconcileTasksReport{
+			PelotonTasks: 1,
+			MesosTasks:   1,
+			Orphans: []*hostmgrsvc.ReconciledTask{
+				{
+					TaskId:     &mesos.TaskID{Value: &taskID},
+					Hostname:   "host1",
+					MesosState: "TASK_RUNNING",
+				},
+			},
+			KilledOrphans: []*mesos.TaskID{{Value: &taskID}},
+		},
+	}
+
+	suite.mockHostMgr.EXPECT().
+		ReconcileTasks(gomock.Any(), &hostmgrsvc.ReconcileTasksRequest{
+			Hostname:    "host1",
+			KillOrphans: true,
+		}).
+		Return(resp, nil)
+	suite.NoError(c.HostMgrReconcileAction("", "host1", true))
+
+	// error in response
+	suite.mockHostMgr.EXPECT().
+		ReconcileTasks(gomock.Any(), gomock.Any()).
+		Return(&hostmgrsvc.ReconcileTasksResponse{
+			Error: &hostmgrsvc.ReconcileTasksResponse_Error{
+				Message: "fake error",
+			},
+		}, nil)
+	suite.Error(c.HostMgrReconcileAction("job", "", false))
+
+	// both job and host
+	suite.Error(c.HostMgrReconcileAction("job", "host1", false))
+}
+
 func (suite *hostmgrActionsInternalTestSuite) TestGetHostsByQueryLessThan() {
 	c := Client{
 		Debug:         false,
@@ -606,6 +606,23 @@ func (c *Client) JobStopV1BetaAction(
 	return nil
 }
 
+// JobRetryFailedAction is the action for restarting the failed and lost
+// instances of a terminal batch job
+func (c *Client) JobRetryFailedAction(jobID string) error {
+	response, err := c.jobClient.RestartFailedInstances(
+		c.ctx,
+		&job.RestartFailedInstancesRequest{
+			Id: &peloton.JobID{Value: jobID},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	printResponseJSON(response)
+	return nil
+}
+
 func (c *Client) retryUntilConcurrencyControlSucceeds(
 	id *peloton.JobID,
 	resourceVersion uint64,
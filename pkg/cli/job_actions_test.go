@@ -2049,3 +2049,27 @@ func (suite *jobActionsTestSuite) TestClientJobStopActionJobIDAndLabels() {
 	}).Return(getResponse, nil)
 	suite.NoError(suite.client.JobStopAction(testJobID, false, "", "key=value", true))
 }
+
+// TestClientJobRetryFailedActionSuccess tests restarting the failed
+// instances of a batch job successfully
+func (suite *jobActionsTestSuite) TestClientJobRetryFailedActionSuccess() {
+	suite.mockJob.EXPECT().
+		RestartFailedInstances(gomock.Any(), &job.RestartFailedInstancesRequest{
+			Id: &peloton.JobID{Value: testJobID},
+		}).
+		Return(&job.RestartFailedInstancesResponse{
+			InstanceIds: []uint32{1, 5},
+		}, nil)
+
+	suite.NoError(suite.client.JobRetryFailedAction(testJobID))
+}
+
+// TestClientJobRetryFailedActionFailure tests failing to restart the
+// failed instances of a batch job
+func (suite *jobActionsTestSuite) TestClientJobRetryFailedActionFailure() {
+	suite.mockJob.EXPECT().
+		RestartFailedInstances(gomock.Any(), gomock.Any()).
+		Return(nil, yarpcerrors.FailedPreconditionErrorf("job has no failed instances"))
+
+	suite.Error(suite.client.JobRetryFailedAction(testJobID))
+}
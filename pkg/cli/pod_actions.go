@@ -16,8 +16,10 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
 	"strings"
 
 	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
@@ -25,6 +27,9 @@ import (
 )
 
 const (
+	// maximum number of bytes of the standard input sent at once
+	podExecStdinChunkSize = 4 * 1024
+
 	podGetEventsV1AlphaFormatHeader = "Pod Id\tDesired Pod Id\tActual State\tDesired State\tJob Version\tDesired Job Version\tHealthy\tHost\tMessage\tReason\tUpdate Time\t\n"
 	podGetEventsV1AlphaFormatBody   = "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n"
 )
@@ -141,6 +146,157 @@ func (c *Client) PodLogsGetAction(filename string, podName string, podID string)
 	return nil
 }
 
+// PodLogsStreamAction is the action to stream the stdout or stderr of
+// given pod, optionally following the log as new output is written
+func (c *Client) PodLogsStreamAction(
+	filename string,
+	podName string,
+	podID string,
+	offset int64,
+	length int64,
+	follow bool,
+) error {
+	var logStream podsvc.LogStream
+	switch filename {
+	case "stdout":
+		logStream = podsvc.LogStream_LOG_STREAM_STDOUT
+	case "stderr":
+		logStream = podsvc.LogStream_LOG_STREAM_STDERR
+	default:
+		return fmt.Errorf("filename:%s cannot be streamed", filename)
+	}
+
+	stream, err := c.podClient.GetPodLogs(
+		c.ctx,
+		&podsvc.GetPodLogsRequest{
+			PodName: &v1alphapeloton.PodName{
+				Value: podName,
+			},
+			PodId: &v1alphapeloton.PodID{
+				Value: podID,
+			},
+			Stream: logStream,
+			Offset: offset,
+			Length: length,
+			Follow: follow,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer stream.CloseSend()
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s", resp.GetData())
+	}
+}
+
+// PodExecAction is the action to run a command inside a running pod.
+// It returns the exit code of the command.
+func (c *Client) PodExecAction(
+	podName string,
+	podID string,
+	command []string,
+	tty bool,
+	interactive bool,
+) (int32, error) {
+	var stdin io.Reader
+	if interactive {
+		stdin = os.Stdin
+	}
+	return c.podExec(podName, podID, command, tty, stdin, os.Stdout, os.Stderr)
+}
+
+// podExec runs a command inside a running pod, forwarding the given
+// standard input to the command and its output to the given writers.
+func (c *Client) podExec(
+	podName string,
+	podID string,
+	command []string,
+	tty bool,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+) (int32, error) {
+	stream, err := c.podClient.ExecPod(c.ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := stream.Send(&podsvc.ExecPodRequest{
+		PodName: &v1alphapeloton.PodName{
+			Value: podName,
+		},
+		PodId: &v1alphapeloton.PodID{
+			Value: podID,
+		},
+		Command: command,
+		Tty:     tty,
+	}); err != nil {
+		return 0, err
+	}
+
+	if stdin == nil {
+		if err := closeExecStdin(stream); err != nil {
+			return 0, err
+		}
+	} else {
+		go func() {
+			buf := make([]byte, podExecStdinChunkSize)
+			for {
+				n, err := stdin.Read(buf)
+				if n > 0 {
+					data := make([]byte, n)
+					copy(data, buf[:n])
+					if err := stream.Send(&podsvc.ExecPodRequest{
+						Stdin: data,
+					}); err != nil {
+						return
+					}
+				}
+				if err != nil {
+					closeExecStdin(stream)
+					return
+				}
+			}
+		}()
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+
+		if len(resp.GetData()) > 0 {
+			w := stdout
+			if resp.GetStream() == podsvc.LogStream_LOG_STREAM_STDERR {
+				w = stderr
+			}
+			w.Write(resp.GetData())
+		}
+
+		if resp.GetExited() {
+			return resp.GetExitCode(), nil
+		}
+	}
+}
+
+// closeExecStdin closes the standard input of a command run inside a pod.
+func closeExecStdin(stream podsvc.PodServiceServiceExecPodYARPCClient) error {
+	if err := stream.Send(&podsvc.ExecPodRequest{}); err != nil {
+		return err
+	}
+	return stream.CloseSend()
+}
+
 func printPodGetEventsV1AlphaResponse(r *podsvc.GetPodEventsResponse, debug bool) {
 	defer tabWriter.Flush()
 
@@ -237,13 +393,15 @@ func (c *Client) PodGetAction(
 	podName string,
 	statusOnly bool,
 	limit uint32,
+	includeUsage bool,
 ) error {
 	resp, err := c.podClient.GetPod(
 		c.ctx,
 		&podsvc.GetPodRequest{
-			PodName:    &v1alphapeloton.PodName{Value: podName},
-			StatusOnly: statusOnly,
-			Limit:      limit,
+			PodName:      &v1alphapeloton.PodName{Value: podName},
+			StatusOnly:   statusOnly,
+			Limit:        limit,
+			IncludeUsage: includeUsage,
 		},
 	)
 	if err != nil {
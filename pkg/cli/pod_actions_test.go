@@ -15,7 +15,10 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
@@ -296,7 +299,21 @@ func (suite *podActionsTestSuite) TestClientPodGetSuccess() {
 		GetPod(gomock.Any(), gomock.Any()).
 		Return(&podsvc.GetPodResponse{}, nil)
 
-	suite.NoError(suite.client.PodGetAction(testPodName, false, uint32(2)))
+	suite.NoError(suite.client.PodGetAction(testPodName, false, uint32(2), false))
+}
+
+// TestClientPodGetWithUsage tests getting pod info along with its
+// resource usage
+func (suite *podActionsTestSuite) TestClientPodGetWithUsage() {
+	suite.podClient.EXPECT().
+		GetPod(gomock.Any(), &podsvc.GetPodRequest{
+			PodName:      &peloton.PodName{Value: testPodName},
+			Limit:        1,
+			IncludeUsage: true,
+		}).
+		Return(&podsvc.GetPodResponse{}, nil)
+
+	suite.NoError(suite.client.PodGetAction(testPodName, false, uint32(1), true))
 }
 
 // TestClientPodGetFailure tests the failure case of getting pod info
@@ -305,7 +322,7 @@ func (suite *podActionsTestSuite) TestClientPodGetFailure() {
 		GetPod(gomock.Any(), gomock.Any()).
 		Return(nil, yarpcerrors.InternalErrorf("test error"))
 
-	suite.Error(suite.client.PodGetAction(testPodName, false, uint32(2)))
+	suite.Error(suite.client.PodGetAction(testPodName, false, uint32(2), false))
 }
 
 // TestClientPodDeleteEventsSuccess tests the success case of deleting pod events
@@ -329,3 +346,201 @@ func (suite *podActionsTestSuite) TestClientPodDeleteEventsFailure() {
 func TestPodActions(t *testing.T) {
 	suite.Run(t, new(podActionsTestSuite))
 }
+
+// TestPodLogsStreamActionSuccess tests streaming pod stdout
+func (suite *podActionsTestSuite) TestPodLogsStreamActionSuccess() {
+	stream := mocks.NewMockPodServiceServiceGetPodLogsYARPCClient(suite.ctrl)
+	req := &podsvc.GetPodLogsRequest{
+		PodName: &peloton.PodName{Value: testPodName},
+		PodId:   &peloton.PodID{Value: testPodID},
+		Stream:  podsvc.LogStream_LOG_STREAM_STDERR,
+		Offset:  -100,
+		Follow:  true,
+	}
+
+	gomock.InOrder(
+		suite.podClient.EXPECT().
+			GetPodLogs(suite.ctx, req).
+			Return(stream, nil),
+		stream.EXPECT().Recv().
+			Return(&podsvc.GetPodLogsResponse{Data: []byte("test")}, nil),
+		stream.EXPECT().Recv().Return(nil, io.EOF),
+		stream.EXPECT().CloseSend().Return(nil),
+	)
+
+	suite.NoError(suite.client.PodLogsStreamAction(
+		"stderr",
+		testPodName,
+		testPodID,
+		-100,
+		0,
+		true,
+	))
+}
+
+// TestPodLogsStreamActionInvalidFilename tests failure of streaming a
+// file other than stdout or stderr
+func (suite *podActionsTestSuite) TestPodLogsStreamActionInvalidFilename() {
+	suite.Error(suite.client.PodLogsStreamAction(
+		"thermos.log",
+		testPodName,
+		testPodID,
+		0,
+		0,
+		false,
+	))
+}
+
+// TestPodLogsStreamActionFailure tests failure of streaming pod logs
+// due to GetPodLogs API error
+func (suite *podActionsTestSuite) TestPodLogsStreamActionFailure() {
+	stream := mocks.NewMockPodServiceServiceGetPodLogsYARPCClient(suite.ctrl)
+
+	suite.podClient.EXPECT().
+		GetPodLogs(suite.ctx, gomock.Any()).
+		Return(nil, yarpcerrors.InternalErrorf("test error"))
+	suite.Error(suite.client.PodLogsStreamAction(
+		"stdout",
+		testPodName,
+		testPodID,
+		0,
+		0,
+		false,
+	))
+
+	gomock.InOrder(
+		suite.podClient.EXPECT().
+			GetPodLogs(suite.ctx, gomock.Any()).
+			Return(stream, nil),
+		stream.EXPECT().Recv().
+			Return(nil, yarpcerrors.NotFoundErrorf("test error")),
+		stream.EXPECT().CloseSend().Return(nil),
+	)
+	suite.Error(suite.client.PodLogsStreamAction(
+		"stdout",
+		testPodName,
+		testPodID,
+		0,
+		0,
+		false,
+	))
+}
+
+// TestPodExecSuccess tests running a command inside a pod
+func (suite *podActionsTestSuite) TestPodExecSuccess() {
+	stream := mocks.NewMockPodServiceServiceExecPodYARPCClient(suite.ctrl)
+	var stdout, stderr bytes.Buffer
+
+	gomock.InOrder(
+		suite.podClient.EXPECT().
+			ExecPod(suite.ctx).
+			Return(stream, nil),
+		stream.EXPECT().
+			Send(&podsvc.ExecPodRequest{
+				PodName: &peloton.PodName{Value: testPodName},
+				PodId:   &peloton.PodID{Value: testPodID},
+				Command: []string{"ls", "-l"},
+			}).
+			Return(nil),
+		stream.EXPECT().Send(&podsvc.ExecPodRequest{}).Return(nil),
+		stream.EXPECT().CloseSend().Return(nil),
+		stream.EXPECT().Recv().
+			Return(&podsvc.ExecPodResponse{
+				Stream: podsvc.LogStream_LOG_STREAM_STDOUT,
+				Data:   []byte("hello"),
+			}, nil),
+		stream.EXPECT().Recv().
+			Return(&podsvc.ExecPodResponse{
+				Stream: podsvc.LogStream_LOG_STREAM_STDERR,
+				Data:   []byte("world"),
+			}, nil),
+		stream.EXPECT().Recv().
+			Return(&podsvc.ExecPodResponse{Exited: true, ExitCode: 3}, nil),
+	)
+
+	exitCode, err := suite.client.podExec(
+		testPodName,
+		testPodID,
+		[]string{"ls", "-l"},
+		false,
+		nil,
+		&stdout,
+		&stderr,
+	)
+	suite.NoError(err)
+	suite.Equal(int32(3), exitCode)
+	suite.Equal("hello", stdout.String())
+	suite.Equal("world", stderr.String())
+}
+
+// TestPodExecStdin tests forwarding the standard input to a command
+// run inside a pod
+func (suite *podActionsTestSuite) TestPodExecStdin() {
+	stream := mocks.NewMockPodServiceServiceExecPodYARPCClient(suite.ctrl)
+	var stdout bytes.Buffer
+
+	// the command exits once its standard input is closed
+	stdinClosed := make(chan struct{})
+	gomock.InOrder(
+		suite.podClient.EXPECT().
+			ExecPod(suite.ctx).
+			Return(stream, nil),
+		stream.EXPECT().
+			Send(&podsvc.ExecPodRequest{
+				PodName: &peloton.PodName{Value: testPodName},
+				PodId:   &peloton.PodID{Value: ""},
+				Command: []string{"cat"},
+				Tty:     true,
+			}).
+			Return(nil),
+		stream.EXPECT().
+			Send(&podsvc.ExecPodRequest{Stdin: []byte("input")}).
+			Return(nil),
+		stream.EXPECT().Send(&podsvc.ExecPodRequest{}).Return(nil),
+		stream.EXPECT().CloseSend().
+			Do(func() { close(stdinClosed) }).
+			Return(nil),
+	)
+	stream.EXPECT().Recv().
+		DoAndReturn(func() (*podsvc.ExecPodResponse, error) {
+			<-stdinClosed
+			return &podsvc.ExecPodResponse{Exited: true}, nil
+		})
+
+	exitCode, err := suite.client.podExec(
+		testPodName,
+		"",
+		[]string{"cat"},
+		true,
+		strings.NewReader("input"),
+		&stdout,
+		&stdout,
+	)
+	suite.NoError(err)
+	suite.Equal(int32(0), exitCode)
+}
+
+// TestPodExecFailure tests failures to run a command inside a pod
+func (suite *podActionsTestSuite) TestPodExecFailure() {
+	suite.podClient.EXPECT().
+		ExecPod(suite.ctx).
+		Return(nil, yarpcerrors.InternalErrorf("test error"))
+	_, err := suite.client.PodExecAction(
+		testPodName, testPodID, []string{"ls"}, false, false)
+	suite.Error(err)
+
+	stream := mocks.NewMockPodServiceServiceExecPodYARPCClient(suite.ctrl)
+	gomock.InOrder(
+		suite.podClient.EXPECT().
+			ExecPod(suite.ctx).
+			Return(stream, nil),
+		stream.EXPECT().Send(gomock.Any()).Return(nil),
+		stream.EXPECT().Send(gomock.Any()).Return(nil),
+		stream.EXPECT().CloseSend().Return(nil),
+		stream.EXPECT().Recv().
+			Return(nil, yarpcerrors.AbortedErrorf("pod is not running")),
+	)
+	_, err = suite.client.PodExecAction(
+		testPodName, testPodID, []string{"ls"}, false, false)
+	suite.True(yarpcerrors.IsAborted(err))
+}
@@ -15,6 +15,7 @@
 package cli
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -44,8 +45,8 @@ const (
 		"Workflow Status\tCompleted\tFailed\tCurrent\n"
 	statelessSummaryFormatBody = "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\t%d\t%d\t%d\t\n"
 
-	statelessUpdateEventsFormatHeader = "Type\tTimestamp\tState\t\n"
-	statelessUpdateEventsFormatBody   = "%s\t%s\t%s\t\n"
+	statelessUpdateEventsFormatHeader = "Type\tTimestamp\tState\tMessage\t\n"
+	statelessUpdateEventsFormatBody   = "%s\t%s\t%s\t%s\t\n"
 
 	workflowEventsV1AlphaFormatHeader = "Workflow State\tWorkflow Type\tTimestamp\n"
 	workflowEventsV1AlphaFormatBody   = "%s\t%s\t%s\n"
@@ -57,6 +58,9 @@ const (
 	podListFormatHeader = "Name\tPod ID\tState\tHealthy\tStart Time\t" +
 		"Host\tMessage\tReason\t\n"
 	podListFormatBody = "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n"
+
+	jobUsageFormatHeader = "Job ID\tResource Pool ID\tPods\tCPU\tMem (MB)\tDisk (MB)\t\n"
+	jobUsageFormatBody   = "%s\t%s\t%d\t%.2f\t%.1f\t%.1f\t\n"
 )
 
 // StatelessGetCacheAction get cache of stateless job
@@ -155,6 +159,34 @@ func (c *Client) StatelessWorkflowResumeAction(
 	return nil
 }
 
+// StatelessWorkflowPromoteAction promotes the canary phase of a workflow
+func (c *Client) StatelessWorkflowPromoteAction(
+	jobID string,
+	entityVersion string,
+	opaqueData string,
+) error {
+	var opaque *v1alphapeloton.OpaqueData
+	if len(opaqueData) > 0 {
+		opaque = &v1alphapeloton.OpaqueData{Data: opaqueData}
+	}
+
+	resp, err := c.statelessClient.PromoteJobWorkflow(
+		c.ctx,
+		&statelesssvc.PromoteJobWorkflowRequest{
+			JobId:      &v1alphapeloton.JobID{Value: jobID},
+			Version:    &v1alphapeloton.EntityVersion{Value: entityVersion},
+			OpaqueData: opaque,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Workflow promoted. New EntityVersion: %s\n", resp.GetVersion().GetValue())
+
+	return nil
+}
+
 // StatelessWorkflowAbortAction aborts a workflow
 func (c *Client) StatelessWorkflowAbortAction(
 	jobID string,
@@ -285,6 +317,7 @@ func (c *Client) StatelessReplaceJobAction(
 	opaqueData string,
 	inPlace bool,
 	startPods bool,
+	canarySpec *stateless.CanarySpec,
 ) error {
 	var jobSpec stateless.JobSpec
 
@@ -350,6 +383,7 @@ func (c *Client) StatelessReplaceJobAction(
 			StartPaused:                  startPaused,
 			InPlace:                      inPlace,
 			StartPods:                    startPods,
+			Canary:                       canarySpec,
 		},
 		OpaqueData: opaque,
 	}
@@ -911,6 +945,108 @@ func (c *Client) StatelessRestartJobAction(
 	return nil
 }
 
+// StatelessRotateSecretAction rotates a secret and restarts the jobs
+// mounting it
+func (c *Client) StatelessRotateSecretAction(
+	secret string,
+	data []byte,
+	version string,
+	batchSize uint32,
+) error {
+	req := &statelesssvc.RotateSecretRequest{
+		Secret:    secret,
+		Version:   version,
+		BatchSize: batchSize,
+	}
+	if len(data) > 0 {
+		req.Value = &v1alphapeloton.Secret_Value{
+			Data: []byte(base64.StdEncoding.EncodeToString(data)),
+		}
+	}
+
+	resp, err := c.statelessClient.RotateSecret(c.ctx, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Secret rotated, %d jobs restarted\n", len(resp.GetJobIds()))
+	for _, jobID := range resp.GetJobIds() {
+		fmt.Println(jobID.GetValue())
+	}
+
+	if len(resp.GetDeferredJobIds()) > 0 {
+		fmt.Printf("%d jobs not restarted because of an active workflow, "+
+			"rotate the secret again once it is done\n",
+			len(resp.GetDeferredJobIds()))
+		for _, jobID := range resp.GetDeferredJobIds() {
+			fmt.Println(jobID.GetValue())
+		}
+	}
+
+	if len(resp.GetFailures()) == 0 {
+		return nil
+	}
+	fmt.Printf("%d jobs failed to restart\n", len(resp.GetFailures()))
+	for _, failure := range resp.GetFailures() {
+		fmt.Printf("%s: %s\n",
+			failure.GetJobId().GetValue(), failure.GetMessage())
+	}
+	return fmt.Errorf("%d jobs failed to restart", len(resp.GetFailures()))
+}
+
+// StatelessGetJobUsageAction prints the current resource usage of the
+// running pods of a job, or of all the jobs of a resource pool
+func (c *Client) StatelessGetJobUsageAction(
+	jobID string,
+	respoolID string,
+) error {
+	req := &statelesssvc.GetJobUsageRequest{}
+	if len(jobID) != 0 {
+		req.JobId = &v1alphapeloton.JobID{Value: jobID}
+	}
+	if len(respoolID) != 0 {
+		req.RespoolId = &v1alphapeloton.ResourcePoolID{Value: respoolID}
+	}
+
+	resp, err := c.statelessClient.GetJobUsage(c.ctx, req)
+	if err != nil {
+		return err
+	}
+
+	printJobUsageResponse(resp)
+	return nil
+}
+
+func printJobUsageResponse(r *statelesssvc.GetJobUsageResponse) {
+	defer tabWriter.Flush()
+
+	fmt.Fprint(tabWriter, jobUsageFormatHeader)
+	var podCount uint32
+	for _, job := range r.GetJobs() {
+		podCount += job.GetPodCount()
+		fmt.Fprintf(
+			tabWriter,
+			jobUsageFormatBody,
+			job.GetJobId().GetValue(),
+			job.GetRespoolId().GetValue(),
+			job.GetPodCount(),
+			job.GetUsage().GetCpu(),
+			job.GetUsage().GetMemMb(),
+			job.GetUsage().GetDiskMb(),
+		)
+	}
+	fmt.Fprintf(
+		tabWriter,
+		jobUsageFormatBody,
+		"Total",
+		"",
+		podCount,
+		r.GetTotal().GetCpu(),
+		r.GetTotal().GetMemMb(),
+		r.GetTotal().GetDiskMb(),
+	)
+}
+
 // StatelessListUpdatesAction lists updates of a job
 func (c *Client) StatelessListUpdatesAction(
 	jobID string,
@@ -972,6 +1108,7 @@ func printUpdateInfo(workflowInfo *stateless.WorkflowInfo) error {
 				event.GetType().String(),
 				event.GetTimestamp(),
 				event.GetState().String(),
+				event.GetMessage(),
 			)
 		}
 		tabWriter.Flush()
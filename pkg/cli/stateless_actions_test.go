@@ -16,6 +16,7 @@ package cli
 
 import (
 	"context"
+	"encoding/base64"
 	"io"
 	"io/ioutil"
 	"testing"
@@ -183,6 +184,34 @@ func (suite *statelessActionsTestSuite) TestStatelessWorkflowResumeActionFailure
 	suite.Error(suite.client.StatelessWorkflowResumeAction(testJobID, entityVersion.GetValue(), ""))
 }
 
+func (suite *statelessActionsTestSuite) TestStatelessWorkflowPromoteAction() {
+	opaque := "test"
+	entityVersion := &v1alphapeloton.EntityVersion{Value: testEntityVersion}
+	suite.statelessClient.EXPECT().
+		PromoteJobWorkflow(suite.ctx, &svc.PromoteJobWorkflowRequest{
+			JobId:      &v1alphapeloton.JobID{Value: testJobID},
+			Version:    entityVersion,
+			OpaqueData: &v1alphapeloton.OpaqueData{Data: opaque},
+		}).
+		Return(&svc.PromoteJobWorkflowResponse{
+			Version: entityVersion,
+		}, nil)
+
+	suite.NoError(suite.client.StatelessWorkflowPromoteAction(testJobID, entityVersion.GetValue(), opaque))
+}
+
+func (suite *statelessActionsTestSuite) TestStatelessWorkflowPromoteActionFailure() {
+	entityVersion := &v1alphapeloton.EntityVersion{Value: testEntityVersion}
+	suite.statelessClient.EXPECT().
+		PromoteJobWorkflow(suite.ctx, &svc.PromoteJobWorkflowRequest{
+			JobId:   &v1alphapeloton.JobID{Value: testJobID},
+			Version: entityVersion,
+		}).
+		Return(nil, yarpcerrors.FailedPreconditionErrorf("test error"))
+
+	suite.Error(suite.client.StatelessWorkflowPromoteAction(testJobID, entityVersion.GetValue(), ""))
+}
+
 func (suite *statelessActionsTestSuite) TestStatelessWorkflowAbortAction() {
 	opaque := "test"
 	entityVersion := &v1alphapeloton.EntityVersion{Value: testEntityVersion}
@@ -373,6 +402,48 @@ func (suite *statelessActionsTestSuite) TestStatelessReplaceJobActionSuccess() {
 		opaque,
 		inPlace,
 		startPods,
+		nil,
+	))
+}
+
+// TestStatelessReplaceJobActionWithCanary tests that the canary spec
+// is passed through to the replace request
+func (suite *statelessActionsTestSuite) TestStatelessReplaceJobActionWithCanary() {
+	canarySpec := &stateless.CanarySpec{
+		InstanceCount:     1,
+		BakePeriodSeconds: 60,
+	}
+
+	suite.resClient.EXPECT().
+		LookupResourcePoolID(gomock.Any(), gomock.Any()).
+		Return(&respool.LookupResponse{
+			Id: &peloton.ResourcePoolID{Value: uuid.New()},
+		}, nil)
+
+	suite.statelessClient.EXPECT().
+		ReplaceJob(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *svc.ReplaceJobRequest) {
+			suite.Equal(canarySpec, req.GetUpdateSpec().GetCanary())
+		}).
+		Return(&svc.ReplaceJobResponse{
+			Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+		}, nil)
+
+	suite.NoError(suite.client.StatelessReplaceJobAction(
+		testJobID,
+		testStatelessSpecConfig,
+		1,
+		testRespoolPath,
+		testEntityVersion,
+		false,
+		0,
+		0,
+		false,
+		false,
+		"",
+		false,
+		false,
+		canarySpec,
 	))
 }
 
@@ -410,6 +481,7 @@ func (suite *statelessActionsTestSuite) TestStatelessReplaceJobActionLookupResou
 		"",
 		inPlace,
 		startPods,
+		nil,
 	))
 }
 
@@ -455,6 +527,7 @@ func (suite *statelessActionsTestSuite) TestStatelessReplaceJobActionGetJobFail(
 		opaque,
 		inPlace,
 		startPods,
+		nil,
 	))
 }
 
@@ -924,6 +997,105 @@ func (suite *statelessActionsTestSuite) TestStatelessRestartJobActionSuccess() {
 	))
 }
 
+// TestStatelessRotateSecretActionSuccess tests the success path of
+// rotating a secret
+func (suite *statelessActionsTestSuite) TestStatelessRotateSecretActionSuccess() {
+	suite.statelessClient.
+		EXPECT().
+		RotateSecret(gomock.Any(), &svc.RotateSecretRequest{
+			Secret: "secret-id",
+			Value: &v1alphapeloton.Secret_Value{
+				Data: []byte(base64.StdEncoding.EncodeToString([]byte("data"))),
+			},
+			BatchSize: 1,
+		}).
+		Return(&svc.RotateSecretResponse{
+			JobIds: []*v1alphapeloton.JobID{{Value: testJobID}},
+			DeferredJobIds: []*v1alphapeloton.JobID{
+				{Value: "other-job-id"},
+			},
+		}, nil)
+	suite.NoError(suite.client.StatelessRotateSecretAction(
+		"secret-id", []byte("data"), "", 1))
+
+	suite.statelessClient.
+		EXPECT().
+		RotateSecret(gomock.Any(), &svc.RotateSecretRequest{
+			Secret:  "vault:db/password",
+			Version: "4",
+		}).
+		Return(&svc.RotateSecretResponse{}, nil)
+	suite.NoError(suite.client.StatelessRotateSecretAction(
+		"vault:db/password", nil, "4", 0))
+}
+
+// TestStatelessRotateSecretActionFailure tests the failure path of
+// rotating a secret
+func (suite *statelessActionsTestSuite) TestStatelessRotateSecretActionFailure() {
+	suite.statelessClient.
+		EXPECT().
+		RotateSecret(gomock.Any(), gomock.Any()).
+		Return(nil, yarpcerrors.InternalErrorf("test error"))
+	suite.Error(suite.client.StatelessRotateSecretAction(
+		"secret-id", []byte("data"), "", 0))
+
+	// the secret is rotated but some jobs failed to restart
+	suite.statelessClient.
+		EXPECT().
+		RotateSecret(gomock.Any(), gomock.Any()).
+		Return(&svc.RotateSecretResponse{
+			JobIds: []*v1alphapeloton.JobID{{Value: testJobID}},
+			Failures: []*svc.RotateSecretFailure{
+				{
+					JobId:   &v1alphapeloton.JobID{Value: "other-job-id"},
+					Message: "concurrency error",
+				},
+			},
+		}, nil)
+	suite.Error(suite.client.StatelessRotateSecretAction(
+		"secret-id", []byte("data"), "", 0))
+}
+
+// TestStatelessGetJobUsageActionSuccess tests the success path of
+// getting the resource usage of a job
+func (suite *statelessActionsTestSuite) TestStatelessGetJobUsageActionSuccess() {
+	suite.statelessClient.
+		EXPECT().
+		GetJobUsage(gomock.Any(), &svc.GetJobUsageRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+		}).
+		Return(&svc.GetJobUsageResponse{
+			Jobs: []*svc.JobUsage{
+				{
+					JobId:     &v1alphapeloton.JobID{Value: testJobID},
+					RespoolId: &v1alphapeloton.ResourcePoolID{Value: "respool"},
+					PodCount:  2,
+					Usage:     &v1alphapod.ResourceUsage{Cpu: 1.5, MemMb: 128},
+				},
+			},
+			Total: &v1alphapod.ResourceUsage{Cpu: 1.5, MemMb: 128},
+		}, nil)
+	suite.NoError(suite.client.StatelessGetJobUsageAction(testJobID, ""))
+
+	suite.statelessClient.
+		EXPECT().
+		GetJobUsage(gomock.Any(), &svc.GetJobUsageRequest{
+			RespoolId: &v1alphapeloton.ResourcePoolID{Value: "respool"},
+		}).
+		Return(&svc.GetJobUsageResponse{}, nil)
+	suite.NoError(suite.client.StatelessGetJobUsageAction("", "respool"))
+}
+
+// TestStatelessGetJobUsageActionFailure tests the failure path of
+// getting the resource usage of a job
+func (suite *statelessActionsTestSuite) TestStatelessGetJobUsageActionFailure() {
+	suite.statelessClient.
+		EXPECT().
+		GetJobUsage(gomock.Any(), gomock.Any()).
+		Return(nil, yarpcerrors.InternalErrorf("test error"))
+	suite.Error(suite.client.StatelessGetJobUsageAction(testJobID, ""))
+}
+
 // TestStatelessRestartJobActionFailure tests the failure path of restart job
 func (suite *statelessActionsTestSuite) TestStatelessRestartJobActionFailure() {
 	entityVersion := &v1alphapeloton.EntityVersion{Value: testEntityVersion}
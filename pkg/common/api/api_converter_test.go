@@ -32,6 +32,7 @@ import (
 	v1alphaquery "github.com/uber/peloton/.gen/peloton/api/v1alpha/query"
 	v1alpharespool "github.com/uber/peloton/.gen/peloton/api/v1alpha/respool"
 	v1alphavolume "github.com/uber/peloton/.gen/peloton/api/v1alpha/volume"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	"github.com/uber/peloton/.gen/peloton/private/models"
 
 	"github.com/uber/peloton/pkg/common/util"
@@ -1845,3 +1846,37 @@ func (suite *apiConverterTestSuite) TestConvertTaskStatsToPodStats() {
 func TestAPIConverter(t *testing.T) {
 	suite.Run(t, new(apiConverterTestSuite))
 }
+
+// TestConvertHostPodUsageToPodUsage tests converting the pod usage
+// collected by host manager to v1alpha pod usage
+func (suite *apiConverterTestSuite) TestConvertHostPodUsageToPodUsage() {
+	suite.Nil(ConvertHostPodUsageToPodUsage(nil))
+	suite.Nil(ConvertPodUsageSampleToResourceUsage(nil))
+
+	usage := ConvertHostPodUsageToPodUsage(&hostsvc.PodUsage{
+		PodId: testMesosTaskID,
+		Samples: []*hostsvc.PodUsageSample{
+			{
+				Timestamp: 1546300800,
+				Cpus:      0.5,
+				MemBytes:  256 * 1024 * 1024,
+				DiskBytes: 1024 * 1024 * 1024,
+			},
+			{
+				Timestamp: 1546300830,
+				Cpus:      1.5,
+				MemBytes:  512 * 1024 * 1024,
+			},
+		},
+	})
+
+	suite.Len(usage.GetSamples(), 2)
+	suite.Equal("2019-01-01T00:00:00Z", usage.GetSamples()[0].GetTimestamp())
+	suite.Equal(0.5, usage.GetSamples()[0].GetUsage().GetCpu())
+	suite.Equal(float64(256), usage.GetSamples()[0].GetUsage().GetMemMb())
+	suite.Equal(float64(1024), usage.GetSamples()[0].GetUsage().GetDiskMb())
+	suite.Equal("2019-01-01T00:00:30Z", usage.GetSamples()[1].GetTimestamp())
+	suite.Equal(1.5, usage.GetSamples()[1].GetUsage().GetCpu())
+	suite.Equal(float64(512), usage.GetSamples()[1].GetUsage().GetMemMb())
+	suite.Zero(usage.GetSamples()[1].GetUsage().GetDiskMb())
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pelotonv0respool "github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/pod"
+
+	versionutil "github.com/uber/peloton/pkg/common/util/entityversion"
+)
+
+// ConvertJobConfigToBatchJobSpec converts v0 job.JobConfig to
+// v1alpha batch.JobSpec
+func ConvertJobConfigToBatchJobSpec(config *job.JobConfig) *batch.JobSpec {
+	instanceSpec := make(map[uint32]*pod.PodSpec)
+	for instID, taskConfig := range config.GetInstanceConfig() {
+		instanceSpec[instID] = ConvertTaskConfigToPodSpec(taskConfig, "", instID)
+	}
+
+	return &batch.JobSpec{
+		Revision: &v1alphapeloton.Revision{
+			Version:   config.GetChangeLog().GetVersion(),
+			CreatedAt: config.GetChangeLog().GetCreatedAt(),
+			UpdatedAt: config.GetChangeLog().GetUpdatedAt(),
+			UpdatedBy: config.GetChangeLog().GetUpdatedBy(),
+		},
+		Name:          config.GetName(),
+		Owner:         config.GetOwner(),
+		OwningTeam:    config.GetOwningTeam(),
+		LdapGroups:    config.GetLdapGroups(),
+		Description:   config.GetDescription(),
+		Labels:        ConvertLabels(config.GetLabels()),
+		InstanceCount: config.GetInstanceCount(),
+		Sla:           ConvertSLAConfigToBatchSLASpec(config.GetSLA()),
+		DefaultSpec:   ConvertTaskConfigToPodSpec(config.GetDefaultConfig(), "", 0),
+		InstanceSpec:  instanceSpec,
+		RespoolId: &v1alphapeloton.ResourcePoolID{
+			Value: config.GetRespoolID().GetValue()},
+		CompletionPolicy: ConvertCompletionPolicyToBatchCompletionPolicy(
+			config.GetCompletionPolicy()),
+		InstanceGroups: ConvertInstanceGroupsToBatchInstanceGroups(
+			config.GetInstanceGroups()),
+	}
+}
+
+// ConvertBatchJobSpecToJobConfig converts v1alpha batch.JobSpec to
+// v0 job.JobConfig
+func ConvertBatchJobSpecToJobConfig(spec *batch.JobSpec) (*job.JobConfig, error) {
+	result := &job.JobConfig{
+		Type:          job.JobType_BATCH,
+		Name:          spec.GetName(),
+		Owner:         spec.GetOwner(),
+		OwningTeam:    spec.GetOwningTeam(),
+		LdapGroups:    spec.GetLdapGroups(),
+		Description:   spec.GetDescription(),
+		InstanceCount: spec.GetInstanceCount(),
+	}
+
+	if spec.GetRevision() != nil {
+		result.ChangeLog = &peloton.ChangeLog{
+			Version:   spec.GetRevision().GetVersion(),
+			CreatedAt: spec.GetRevision().GetCreatedAt(),
+			UpdatedAt: spec.GetRevision().GetUpdatedAt(),
+			UpdatedBy: spec.GetRevision().GetUpdatedBy(),
+		}
+	}
+
+	for _, label := range spec.GetLabels() {
+		result.Labels = append(result.Labels, &peloton.Label{
+			Key: label.GetKey(), Value: label.GetValue(),
+		})
+	}
+
+	if spec.GetSla() != nil {
+		result.SLA = ConvertBatchSLASpecToSLAConfig(spec.GetSla())
+	}
+
+	if spec.GetDefaultSpec() != nil {
+		defaultConfig, err := ConvertPodSpecToTaskConfig(spec.GetDefaultSpec())
+		if err != nil {
+			return nil, err
+		}
+		result.DefaultConfig = defaultConfig
+	}
+
+	if len(spec.GetInstanceSpec()) != 0 {
+		result.InstanceConfig = make(map[uint32]*task.TaskConfig)
+		for instanceID, instanceSpec := range spec.GetInstanceSpec() {
+			instanceConfig, err := ConvertPodSpecToTaskConfig(instanceSpec)
+			if err != nil {
+				return nil, err
+			}
+			result.InstanceConfig[instanceID] = instanceConfig
+		}
+	}
+
+	if spec.GetRespoolId() != nil {
+		result.RespoolID = &peloton.ResourcePoolID{
+			Value: spec.GetRespoolId().GetValue(),
+		}
+	}
+
+	result.CompletionPolicy = ConvertBatchCompletionPolicyToCompletionPolicy(
+		spec.GetCompletionPolicy())
+	result.InstanceGroups = ConvertBatchInstanceGroupsToInstanceGroups(
+		spec.GetInstanceGroups())
+
+	return result, nil
+}
+
+// ConvertSLAConfigToBatchSLASpec converts job's sla config to
+// batch sla spec
+func ConvertSLAConfigToBatchSLASpec(slaConfig *job.SlaConfig) *batch.SlaSpec {
+	return &batch.SlaSpec{
+		Priority:                slaConfig.GetPriority(),
+		Preemptible:             slaConfig.GetPreemptible(),
+		Revocable:               slaConfig.GetRevocable(),
+		MaximumRunningInstances: slaConfig.GetMaximumRunningInstances(),
+		MinimumRunningInstances: slaConfig.GetMinimumRunningInstances(),
+		MaxRunningTime:          slaConfig.GetMaxRunningTime(),
+	}
+}
+
+// ConvertBatchSLASpecToSLAConfig converts batch sla spec to
+// job's sla config
+func ConvertBatchSLASpecToSLAConfig(slaSpec *batch.SlaSpec) *job.SlaConfig {
+	return &job.SlaConfig{
+		Priority:                slaSpec.GetPriority(),
+		Preemptible:             slaSpec.GetPreemptible(),
+		Revocable:               slaSpec.GetRevocable(),
+		MaximumRunningInstances: slaSpec.GetMaximumRunningInstances(),
+		MinimumRunningInstances: slaSpec.GetMinimumRunningInstances(),
+		MaxRunningTime:          slaSpec.GetMaxRunningTime(),
+	}
+}
+
+// ConvertCompletionPolicyToBatchCompletionPolicy converts job's
+// completion policy to batch completion policy
+func ConvertCompletionPolicyToBatchCompletionPolicy(
+	policy *job.CompletionPolicy,
+) *batch.CompletionPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	return &batch.CompletionPolicy{
+		SuccessThreshold: policy.GetSuccessThreshold(),
+		MaxFailures:      policy.GetMaxFailures(),
+	}
+}
+
+// ConvertBatchCompletionPolicyToCompletionPolicy converts batch
+// completion policy to job's completion policy
+func ConvertBatchCompletionPolicyToCompletionPolicy(
+	policy *batch.CompletionPolicy,
+) *job.CompletionPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	return &job.CompletionPolicy{
+		SuccessThreshold: policy.GetSuccessThreshold(),
+		MaxFailures:      policy.GetMaxFailures(),
+	}
+}
+
+// ConvertInstanceGroupsToBatchInstanceGroups converts job's
+// instance groups to batch instance groups
+func ConvertInstanceGroupsToBatchInstanceGroups(
+	groups []*job.InstanceGroup,
+) []*batch.InstanceGroup {
+	var result []*batch.InstanceGroup
+	for _, group := range groups {
+		var ranges []*pod.InstanceIDRange
+		for _, r := range group.GetRanges() {
+			ranges = append(ranges, &pod.InstanceIDRange{
+				From: r.GetFrom(),
+				To:   r.GetTo(),
+			})
+		}
+		result = append(result, &batch.InstanceGroup{
+			Name:          group.GetName(),
+			Ranges:        ranges,
+			DependsOn:     group.GetDependsOn(),
+			FailurePolicy: batch.GangFailurePolicy(group.GetFailurePolicy()),
+		})
+	}
+	return result
+}
+
+// ConvertBatchInstanceGroupsToInstanceGroups converts batch
+// instance groups to job's instance groups
+func ConvertBatchInstanceGroupsToInstanceGroups(
+	groups []*batch.InstanceGroup,
+) []*job.InstanceGroup {
+	var result []*job.InstanceGroup
+	for _, group := range groups {
+		var ranges []*task.InstanceRange
+		for _, r := range group.GetRanges() {
+			ranges = append(ranges, &task.InstanceRange{
+				From: r.GetFrom(),
+				To:   r.GetTo(),
+			})
+		}
+		result = append(result, &job.InstanceGroup{
+			Name:          group.GetName(),
+			Ranges:        ranges,
+			DependsOn:     group.GetDependsOn(),
+			FailurePolicy: job.GangFailurePolicy(group.GetFailurePolicy()),
+		})
+	}
+	return result
+}
+
+// ConvertRuntimeInfoToBatchJobStatus converts v0 job.RuntimeInfo to
+// v1alpha batch.JobStatus
+func ConvertRuntimeInfoToBatchJobStatus(
+	runtime *job.RuntimeInfo,
+) *batch.JobStatus {
+	return &batch.JobStatus{
+		Revision: &v1alphapeloton.Revision{
+			Version:   runtime.GetRevision().GetVersion(),
+			CreatedAt: runtime.GetRevision().GetCreatedAt(),
+			UpdatedAt: runtime.GetRevision().GetUpdatedAt(),
+			UpdatedBy: runtime.GetRevision().GetUpdatedBy(),
+		},
+		State:          batch.JobState(runtime.GetState()),
+		CreationTime:   runtime.GetCreationTime(),
+		StartTime:      runtime.GetStartTime(),
+		CompletionTime: runtime.GetCompletionTime(),
+		PodStats:       ConvertTaskStatsToPodStats(runtime.GetTaskStats()),
+		DesiredState:   batch.JobState(runtime.GetGoalState()),
+		Version: versionutil.GetJobEntityVersion(
+			runtime.GetConfigurationVersion(),
+			runtime.GetDesiredStateVersion(),
+			runtime.GetWorkflowVersion(),
+		),
+	}
+}
+
+// ConvertJobSummaryToBatchJobSummary converts v0 job.JobSummary to
+// v1alpha batch.JobSummary
+func ConvertJobSummaryToBatchJobSummary(
+	summary *job.JobSummary,
+) *batch.JobSummary {
+	return &batch.JobSummary{
+		JobId:         &v1alphapeloton.JobID{Value: summary.GetId().GetValue()},
+		Name:          summary.GetName(),
+		Owner:         summary.GetOwner(),
+		OwningTeam:    summary.GetOwningTeam(),
+		Labels:        ConvertLabels(summary.GetLabels()),
+		InstanceCount: summary.GetInstanceCount(),
+		RespoolId: &v1alphapeloton.ResourcePoolID{
+			Value: summary.GetRespoolID().GetValue()},
+		Status: ConvertRuntimeInfoToBatchJobStatus(summary.GetRuntime()),
+		Sla:    ConvertSLAConfigToBatchSLASpec(summary.GetSLA()),
+	}
+}
+
+// ConvertBatchQuerySpecToJobQuerySpec converts v1alpha batch.QuerySpec
+// to v0 job.QuerySpec
+func ConvertBatchQuerySpecToJobQuerySpec(spec *batch.QuerySpec) *job.QuerySpec {
+	result := &job.QuerySpec{
+		Pagination: convertV1AlphaPaginationSpecToV0PaginationSpec(
+			spec.GetPagination()),
+		Keywords: spec.GetKeywords(),
+		Owner:    spec.GetOwner(),
+		Name:     spec.GetName(),
+	}
+
+	for _, label := range spec.GetLabels() {
+		result.Labels = append(result.Labels, &peloton.Label{
+			Key:   label.GetKey(),
+			Value: label.GetValue(),
+		})
+	}
+
+	for _, jobState := range spec.GetJobStates() {
+		result.JobStates = append(result.JobStates, job.JobState(jobState))
+	}
+
+	if spec.GetRespool() != nil {
+		result.Respool = &pelotonv0respool.ResourcePoolPath{
+			Value: spec.GetRespool().GetValue(),
+		}
+	}
+
+	if spec.GetCreationTimeRange() != nil {
+		result.CreationTimeRange = &peloton.TimeRange{
+			Min: spec.GetCreationTimeRange().GetMin(),
+			Max: spec.GetCreationTimeRange().GetMax(),
+		}
+	}
+
+	if spec.GetCompletionTimeRange() != nil {
+		result.CompletionTimeRange = &peloton.TimeRange{
+			Min: spec.GetCompletionTimeRange().GetMin(),
+			Max: spec.GetCompletionTimeRange().GetMax(),
+		}
+	}
+
+	return result
+}
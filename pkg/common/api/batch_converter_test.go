@@ -0,0 +1,183 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	v1alpharespool "github.com/uber/peloton/.gen/peloton/api/v1alpha/respool"
+
+	versionutil "github.com/uber/peloton/pkg/common/util/entityversion"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type batchConverterTestSuite struct {
+	suite.Suite
+}
+
+func TestBatchConverter(t *testing.T) {
+	suite.Run(t, new(batchConverterTestSuite))
+}
+
+// TestConvertJobConfigToBatchJobSpecAndViceVersa tests the conversion
+// between v0 job config and v1alpha batch job spec
+func (suite *batchConverterTestSuite) TestConvertJobConfigToBatchJobSpecAndViceVersa() {
+	config := &job.JobConfig{
+		Type:          job.JobType_BATCH,
+		Name:          "test-batch-job",
+		Owner:         "owner",
+		OwningTeam:    "team",
+		LdapGroups:    []string{"group"},
+		Description:   "description",
+		Labels:        []*peloton.Label{{Key: "k", Value: "v"}},
+		InstanceCount: 10,
+		SLA: &job.SlaConfig{
+			Priority:                1,
+			Preemptible:             true,
+			MaximumRunningInstances: 5,
+			MinimumRunningInstances: 2,
+			MaxRunningTime:          100,
+		},
+		DefaultConfig: &task.TaskConfig{Name: "default"},
+		InstanceConfig: map[uint32]*task.TaskConfig{
+			3: {Name: "instance-3"},
+		},
+		RespoolID: &peloton.ResourcePoolID{Value: uuid.New()},
+		CompletionPolicy: &job.CompletionPolicy{
+			SuccessThreshold: 8,
+			MaxFailures:      2,
+		},
+	}
+
+	spec := ConvertJobConfigToBatchJobSpec(config)
+	suite.Equal(config.GetName(), spec.GetName())
+	suite.Equal(config.GetInstanceCount(), spec.GetInstanceCount())
+	suite.Equal(config.GetSLA().GetMaximumRunningInstances(),
+		spec.GetSla().GetMaximumRunningInstances())
+	suite.Equal(config.GetSLA().GetMaxRunningTime(),
+		spec.GetSla().GetMaxRunningTime())
+	suite.Equal(config.GetRespoolID().GetValue(), spec.GetRespoolId().GetValue())
+	suite.Equal(uint32(8), spec.GetCompletionPolicy().GetSuccessThreshold())
+	suite.Equal(uint32(2), spec.GetCompletionPolicy().GetMaxFailures())
+	suite.NotNil(spec.GetInstanceSpec()[3])
+
+	result, err := ConvertBatchJobSpecToJobConfig(spec)
+	suite.NoError(err)
+	suite.Equal(job.JobType_BATCH, result.GetType())
+	suite.Equal(config.GetName(), result.GetName())
+	suite.Equal(config.GetOwner(), result.GetOwner())
+	suite.Equal(config.GetLabels(), result.GetLabels())
+	suite.Equal(config.GetSLA(), result.GetSLA())
+	suite.Equal(config.GetRespoolID(), result.GetRespoolID())
+	suite.Equal(config.GetCompletionPolicy(), result.GetCompletionPolicy())
+	suite.Len(result.GetInstanceConfig(), 1)
+}
+
+// TestConvertNilCompletionPolicy tests the conversion of an unset
+// completion policy
+func (suite *batchConverterTestSuite) TestConvertNilCompletionPolicy() {
+	suite.Nil(ConvertCompletionPolicyToBatchCompletionPolicy(nil))
+	suite.Nil(ConvertBatchCompletionPolicyToCompletionPolicy(nil))
+}
+
+// TestConvertInstanceGroups tests the conversion of instance groups
+// between v0 and v1alpha batch
+func (suite *batchConverterTestSuite) TestConvertInstanceGroups() {
+	groups := []*job.InstanceGroup{
+		{
+			Name:   "ps",
+			Ranges: []*task.InstanceRange{{From: 0, To: 2}},
+		},
+		{
+			Name:          "worker",
+			Ranges:        []*task.InstanceRange{{From: 2, To: 10}},
+			DependsOn:     []string{"ps"},
+			FailurePolicy: job.GangFailurePolicy_GANG_FAILURE_POLICY_RESTART,
+		},
+	}
+
+	batchGroups := ConvertInstanceGroupsToBatchInstanceGroups(groups)
+	suite.Len(batchGroups, 2)
+	suite.Equal("worker", batchGroups[1].GetName())
+	suite.Equal(uint32(10), batchGroups[1].GetRanges()[0].GetTo())
+	suite.Equal([]string{"ps"}, batchGroups[1].GetDependsOn())
+	suite.Equal(
+		batch.GangFailurePolicy_GANG_FAILURE_POLICY_RESTART,
+		batchGroups[1].GetFailurePolicy(),
+	)
+
+	suite.Equal(groups, ConvertBatchInstanceGroupsToInstanceGroups(batchGroups))
+	suite.Nil(ConvertInstanceGroupsToBatchInstanceGroups(nil))
+}
+
+// TestConvertJobSummaryToBatchJobSummary tests the conversion of
+// v0 job summary to v1alpha batch job summary
+func (suite *batchConverterTestSuite) TestConvertJobSummaryToBatchJobSummary() {
+	summary := &job.JobSummary{
+		Id:            &peloton.JobID{Value: uuid.New()},
+		Name:          "test-batch-job",
+		InstanceCount: 10,
+		Runtime: &job.RuntimeInfo{
+			State:                job.JobState_FAILED,
+			GoalState:            job.JobState_SUCCEEDED,
+			CompletionTime:       "2019-01-01T00:00:00Z",
+			TaskStats:            map[string]uint32{task.TaskState_FAILED.String(): 10},
+			ConfigurationVersion: 1,
+			DesiredStateVersion:  2,
+			WorkflowVersion:      3,
+		},
+	}
+
+	result := ConvertJobSummaryToBatchJobSummary(summary)
+	suite.Equal(summary.GetId().GetValue(), result.GetJobId().GetValue())
+	suite.Equal(summary.GetName(), result.GetName())
+	suite.Equal(batch.JobState_JOB_STATE_FAILED, result.GetStatus().GetState())
+	suite.Equal(batch.JobState_JOB_STATE_SUCCEEDED,
+		result.GetStatus().GetDesiredState())
+	suite.Equal(summary.GetRuntime().GetCompletionTime(),
+		result.GetStatus().GetCompletionTime())
+	suite.Equal(uint32(10),
+		result.GetStatus().GetPodStats()["POD_STATE_FAILED"])
+	suite.Equal(versionutil.GetJobEntityVersion(1, 2, 3),
+		result.GetStatus().GetVersion())
+}
+
+// TestConvertBatchQuerySpecToJobQuerySpec tests the conversion of
+// v1alpha batch query spec to v0 job query spec
+func (suite *batchConverterTestSuite) TestConvertBatchQuerySpecToJobQuerySpec() {
+	spec := &batch.QuerySpec{
+		Labels:    []*v1alphapeloton.Label{{Key: "k", Value: "v"}},
+		Keywords:  []string{"keyword"},
+		JobStates: []batch.JobState{batch.JobState_JOB_STATE_FAILED},
+		Respool:   &v1alpharespool.ResourcePoolPath{Value: "/respool"},
+		Owner:     "owner",
+		Name:      "name",
+	}
+
+	result := ConvertBatchQuerySpecToJobQuerySpec(spec)
+	suite.Equal([]*peloton.Label{{Key: "k", Value: "v"}}, result.GetLabels())
+	suite.Equal(spec.GetKeywords(), result.GetKeywords())
+	suite.Equal([]job.JobState{job.JobState_FAILED}, result.GetJobStates())
+	suite.Equal("/respool", result.GetRespool().GetValue())
+	suite.Equal(spec.GetOwner(), result.GetOwner())
+	suite.Equal(spec.GetName(), result.GetName())
+}
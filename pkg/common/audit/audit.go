@@ -0,0 +1,116 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// AuthenticatedUserHeader is the header carrying the name of the user
+	// authenticated by the auth inbound middleware. The middleware sets
+	// it, or removes it if the user is not identified, so that callers
+	// cannot set it themselves.
+	AuthenticatedUserHeader = "peloton-authenticated-user"
+
+	// _anonymousUser is recorded when the caller is not authenticated.
+	_anonymousUser = "anonymous"
+
+	_auditFileMode = 0600
+)
+
+// Config for the audit trail
+type Config struct {
+	// Path of the file the audit trail is appended to.
+	// The audit trail is written to the process log if not set.
+	Path string `yaml:"path"`
+}
+
+// Event is an entry of the audit trail
+type Event struct {
+	// User who performed the operation
+	User string
+	// Procedure called by the user
+	Procedure string
+	// Resource the operation was performed on, e.g. a pod name
+	Resource string
+	// Details of the operation
+	Details map[string]interface{}
+	// Error the operation failed with, nil if it succeeded
+	Error error
+}
+
+// Trail records the privileged operations performed by the users
+type Trail interface {
+	// Record appends an event to the audit trail
+	Record(event *Event)
+}
+
+type trail struct {
+	logger *log.Logger
+}
+
+// NewTrail returns a Trail writing to the file set in the config,
+// or to the process log if no file is set.
+func NewTrail(cfg Config) (Trail, error) {
+	if len(cfg.Path) == 0 {
+		return &trail{logger: log.StandardLogger()}, nil
+	}
+
+	f, err := os.OpenFile(
+		cfg.Path,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		_auditFileMode,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := log.New()
+	logger.Out = f
+	logger.Formatter = &log.JSONFormatter{}
+	return &trail{logger: logger}, nil
+}
+
+// Record implements Trail.Record
+func (t *trail) Record(event *Event) {
+	entry := t.logger.WithFields(log.Fields{
+		"audit":     true,
+		"user":      event.User,
+		"procedure": event.Procedure,
+		"resource":  event.Resource,
+	})
+	for k, v := range event.Details {
+		entry = entry.WithField(k, v)
+	}
+
+	if event.Error != nil {
+		entry.WithError(event.Error).Warn("audit")
+		return
+	}
+	entry.Info("audit")
+}
+
+// UserFromHeaders returns the name of the authenticated user who made
+// a call, given the headers of the call once they went through the auth
+// inbound middleware.
+func UserFromHeaders(headers map[string]string) string {
+	if user := headers[AuthenticatedUserHeader]; len(user) > 0 {
+		return user
+	}
+	return _anonymousUser
+}
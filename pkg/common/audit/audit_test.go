@@ -0,0 +1,91 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrailRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	tr, err := NewTrail(Config{Path: path})
+	assert.NoError(t, err)
+
+	tr.Record(&Event{
+		User:      "user1",
+		Procedure: "ExecPod",
+		Resource:  "pod-1",
+		Details:   map[string]interface{}{"command": "ls"},
+	})
+	tr.Record(&Event{
+		User:      "user2",
+		Procedure: "ExecPod",
+		Resource:  "pod-2",
+		Error:     errors.New("test error"),
+	})
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+
+	first := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "user1", first["user"])
+	assert.Equal(t, "ExecPod", first["procedure"])
+	assert.Equal(t, "pod-1", first["resource"])
+	assert.Equal(t, "ls", first["command"])
+	assert.Equal(t, "info", first["level"])
+
+	second := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "user2", second["user"])
+	assert.Equal(t, "test error", second["error"])
+	assert.Equal(t, "warning", second["level"])
+}
+
+func TestNewTrailInvalidPath(t *testing.T) {
+	_, err := NewTrail(Config{Path: "/non/existent/dir/audit.log"})
+	assert.Error(t, err)
+}
+
+func TestNewTrailProcessLog(t *testing.T) {
+	tr, err := NewTrail(Config{})
+	assert.NoError(t, err)
+	tr.Record(&Event{User: "user1", Procedure: "ExecPod"})
+}
+
+func TestUserFromHeaders(t *testing.T) {
+	assert.Equal(t, "user1", UserFromHeaders(map[string]string{
+		AuthenticatedUserHeader: "user1",
+	}))
+	// the user name sent by the caller is not authenticated
+	assert.Equal(t, _anonymousUser, UserFromHeaders(map[string]string{
+		"username": "user1",
+	}))
+	assert.Equal(t, _anonymousUser, UserFromHeaders(nil))
+}
@@ -15,12 +15,14 @@
 package leader
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
 
 	"github.com/uber/peloton/pkg/common"
 
+	"github.com/coreos/etcd/clientv3"
 	"github.com/docker/libkv/store"
 	"github.com/docker/libkv/store/zookeeper"
 	log "github.com/sirupsen/logrus"
@@ -32,6 +34,10 @@ type Discovery interface {
 	// Returns the app URL for a given Peloton role such as
 	// peloton-jobmgr, peloton-resmgr or peloton-hostmgr etc.
 	GetAppURL(role string) (*url.URL, error)
+
+	// Returns the URL of the HTTP inbound of the app for a given
+	// Peloton role.
+	GetAppHTTPURL(role string) (*url.URL, error)
 }
 
 // NewStaticServiceDiscovery creates a staticDiscovery object
@@ -72,6 +78,21 @@ func (s *staticDiscovery) GetAppURL(role string) (*url.URL, error) {
 	}
 }
 
+// GetAppHTTPURL returns the app URL for a given Peloton role. The static
+// URLs are used for every transport.
+func (s *staticDiscovery) GetAppHTTPURL(role string) (*url.URL, error) {
+	return s.GetAppURL(role)
+}
+
+// NewServiceDiscovery creates a Discovery object backed by the election
+// backend of the given config
+func NewServiceDiscovery(cfg ElectionConfig) (Discovery, error) {
+	if cfg.IsEtcd() {
+		return NewEtcdServiceDiscovery(cfg.EtcdEndpoints, cfg.Root)
+	}
+	return NewZkServiceDiscovery(cfg.ZKServers, cfg.Root)
+}
+
 // NewZkServiceDiscovery creates a zkDiscovery object
 func NewZkServiceDiscovery(
 	zkServers []string,
@@ -100,18 +121,107 @@ type zkDiscovery struct {
 
 // GetAppURL reads app URL from Zookeeper for a given Peloton role
 func (s *zkDiscovery) GetAppURL(role string) (*url.URL, error) {
+	id, err := s.getLeaderID(role)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{
+		Host: fmt.Sprintf("%s:%d", id.IP, id.GRPCPort),
+	}, nil
+}
+
+// GetAppHTTPURL reads the app HTTP URL from Zookeeper for a given
+// Peloton role
+func (s *zkDiscovery) GetAppHTTPURL(role string) (*url.URL, error) {
+	id, err := s.getLeaderID(role)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{
+		Host: fmt.Sprintf("%s:%d", id.IP, id.HTTPPort),
+	}, nil
+}
+
+// getLeaderID reads the ID of the leader of a given Peloton role
+// from Zookeeper
+func (s *zkDiscovery) getLeaderID(role string) (*ID, error) {
 	zkPath := leaderZkPath(s.zkRoot, role)
 	leader, err := s.zkClient.Get(zkPath)
 	if err != nil {
 		return nil, err
 	}
 
-	id := ID{}
-	if err := json.Unmarshal([]byte(leader.Value), &id); err != nil {
+	id := &ID{}
+	if err := json.Unmarshal([]byte(leader.Value), id); err != nil {
 		log.WithField("leader", leader.Value).Error("Failed to parse leader json")
 		return nil, err
 	}
+	return id, nil
+}
+
+// NewEtcdServiceDiscovery creates an etcdDiscovery object
+func NewEtcdServiceDiscovery(
+	etcdEndpoints []string,
+	etcdRoot string) (Discovery, error) {
+
+	client, err := newEtcdClient(ElectionConfig{
+		Backend:       EtcdBackend,
+		EtcdEndpoints: etcdEndpoints,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	discovery := &etcdDiscovery{
+		client:   client,
+		etcdRoot: etcdRoot,
+	}
+	return discovery, nil
+}
+
+// etcdDiscovery is the etcd based implementation of Discovery
+type etcdDiscovery struct {
+	client   *clientv3.Client
+	etcdRoot string
+}
+
+// GetAppURL reads app URL from etcd for a given Peloton role
+func (s *etcdDiscovery) GetAppURL(role string) (*url.URL, error) {
+	id, err := s.getLeaderID(role)
+	if err != nil {
+		return nil, err
+	}
 	return &url.URL{
 		Host: fmt.Sprintf("%s:%d", id.IP, id.GRPCPort),
 	}, nil
 }
+
+// GetAppHTTPURL reads the app HTTP URL from etcd for a given Peloton role
+func (s *etcdDiscovery) GetAppHTTPURL(role string) (*url.URL, error) {
+	id, err := s.getLeaderID(role)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{
+		Host: fmt.Sprintf("%s:%d", id.IP, id.HTTPPort),
+	}, nil
+}
+
+// getLeaderID reads the ID of the leader of a given Peloton role
+// from etcd
+func (s *etcdDiscovery) getLeaderID(role string) (*ID, error) {
+	leader, err := getEtcdLeader(
+		context.Background(),
+		s.client,
+		leaderEtcdPrefix(s.etcdRoot, role))
+	if err != nil {
+		return nil, err
+	}
+
+	id := &ID{}
+	if err := json.Unmarshal([]byte(leader), id); err != nil {
+		log.WithField("leader", leader).Error("Failed to parse leader json")
+		return nil, err
+	}
+	return id, nil
+}
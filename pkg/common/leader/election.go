@@ -49,16 +49,42 @@ const (
 	_metricsUpdateTick = 10 * time.Second
 )
 
+const (
+	// ZookeeperBackend runs leader election and discovery on ZooKeeper.
+	ZookeeperBackend = "zookeeper"
+
+	// EtcdBackend runs leader election and discovery on etcd v3.
+	EtcdBackend = "etcd"
+)
+
 // ElectionConfig is config related to leader election of this service.
 type ElectionConfig struct {
+	// Backend used for leader election and discovery, either "zookeeper"
+	// or "etcd". Defaults to "zookeeper" if not set.
+	Backend string `yaml:"backend"`
+
 	// A comma separated list of ZK servers to use for leader election.
 	ZKServers []string `yaml:"zk_servers"`
 
+	// A list of etcd endpoints to use for leader election when the
+	// backend is etcd.
+	EtcdEndpoints []string `yaml:"etcd_endpoints"`
+
+	// TTL of the etcd lease backing the leadership of a candidate. A leader
+	// which fails to keep its lease alive loses leadership after the TTL.
+	EtcdSessionTTL time.Duration `yaml:"etcd_session_ttl"`
+
 	// The root path in ZK to use for role leader election.
 	// This will be something like /peloton/YOURCLUSTERHERE.
+	// The same root is used as key prefix in etcd.
 	Root string `yaml:"root"`
 }
 
+// IsEtcd returns true if the config selects the etcd backend.
+func (c ElectionConfig) IsEtcd() bool {
+	return c.Backend == EtcdBackend
+}
+
 // election holds the state of the zkelection.
 type election struct {
 	sync.Mutex
@@ -84,6 +110,10 @@ func NewCandidate(
 			"for that isnt the empty string")
 	}
 
+	if cfg.IsEtcd() {
+		return newEtcdCandidate(cfg, parent, role, nomination)
+	}
+
 	client, err := zookeeper.New(
 		cfg.ZKServers,
 		&store.Config{ConnectionTimeout: znodeEphemeralTimeout},
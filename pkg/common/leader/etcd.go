@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+)
+
+const (
+	// _defaultEtcdSessionTTL is the TTL of the etcd lease backing a
+	// leadership session if none is configured.
+	_defaultEtcdSessionTTL = 10 * time.Second
+
+	// _etcdDialTimeout is the timeout to establish a connection to etcd.
+	_etcdDialTimeout = 5 * time.Second
+
+	// _etcdRequestTimeout is the timeout of single etcd requests.
+	_etcdRequestTimeout = 5 * time.Second
+
+	// etcdConnErrRetry is how long to wait before restarting campaigning
+	// for or observing leadership on etcd errors.
+	etcdConnErrRetry = 5 * time.Second
+)
+
+var (
+	// errEtcdSessionExpired is returned when the lease backing a leadership
+	// session could not be kept alive.
+	errEtcdSessionExpired = errors.New("etcd session expired")
+
+	// errNoEtcdLeader is returned when no candidate holds the leadership.
+	errNoEtcdLeader = errors.New("no leader elected in etcd")
+)
+
+// newEtcdClient creates an etcd v3 client for the configured endpoints.
+func newEtcdClient(cfg ElectionConfig) (*clientv3.Client, error) {
+	if len(cfg.EtcdEndpoints) == 0 {
+		return nil, errors.New("no etcd endpoints configured")
+	}
+	return clientv3.New(clientv3.Config{
+		Endpoints:   cfg.EtcdEndpoints,
+		DialTimeout: _etcdDialTimeout,
+	})
+}
+
+// etcdSessionTTLSeconds returns the configured session TTL in seconds,
+// which is the granularity of etcd leases.
+func etcdSessionTTLSeconds(cfg ElectionConfig) int {
+	ttl := cfg.EtcdSessionTTL
+	if ttl <= 0 {
+		ttl = _defaultEtcdSessionTTL
+	}
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	return int(ttl / time.Second)
+}
+
+// leaderEtcdPrefix returns the etcd key prefix under which candidates
+// for the role campaign.
+func leaderEtcdPrefix(rootPath string, role string) string {
+	return path.Join("/", rootPath, role, "leader")
+}
+
+// getEtcdLeader returns the value of the current leader under the
+// election prefix, which is the key with the lowest create revision.
+func getEtcdLeader(
+	ctx context.Context,
+	client *clientv3.Client,
+	prefix string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, _etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := client.Get(
+		ctx,
+		prefix+"/",
+		clientv3.WithFirstCreate()...)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", errNoEtcdLeader
+	}
+	return string(resp.Kvs[0].Value), nil
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3/concurrency"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+// etcdElection implements Candidate on top of etcd v3 elections. The
+// leadership of the candidate is backed by a session lease, and is lost
+// as soon as the lease cannot be kept alive.
+type etcdElection struct {
+	sync.Mutex
+	metrics    electionMetrics
+	running    bool
+	leader     bool
+	role       string
+	prefix     string
+	ttl        int
+	client     *clientv3.Client
+	nomination Nomination
+	stopChan   chan struct{}
+	resignChan chan struct{}
+}
+
+// newEtcdCandidate creates new etcd election object to control
+// participation in leader election.
+func newEtcdCandidate(
+	cfg ElectionConfig,
+	parent tally.Scope,
+	role string,
+	nomination Nomination) (Candidate, error) {
+	client, err := newEtcdClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := leaderEtcdPrefix(cfg.Root, role)
+	log.WithFields(log.Fields{
+		"id":          nomination.GetID(),
+		"role":        role,
+		"leader_path": prefix,
+	}).Debug("Creating new etcd Candidate")
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.WithError(err).Fatal("failed to get hostname")
+	}
+	return &etcdElection{
+		metrics:    newElectionMetrics(parent.SubScope("election"), hostname),
+		role:       role,
+		prefix:     prefix,
+		ttl:        etcdSessionTTLSeconds(cfg),
+		client:     client,
+		nomination: nomination,
+		stopChan:   make(chan struct{}),
+		resignChan: make(chan struct{}, 1),
+	}, nil
+}
+
+// Start begins running election for leadership and calls callbacks when
+// caller gain/lose leadership.
+func (el *etcdElection) Start() error {
+	el.Lock()
+	defer el.Unlock()
+
+	if el.running {
+		return errors.New("Already running election")
+	}
+	el.running = true
+	el.metrics.Start.Inc(1)
+	el.metrics.Running.Update(1)
+
+	log.WithFields(log.Fields{"role": el.role}).Info("Joining etcd election")
+
+	go el.campaign()
+	go el.updateLeaderElectionMetrics(_metricsUpdateTick)
+	return nil
+}
+
+// Stop stops campaigning for leadership, resigns if leader and calls
+// shutdown.
+func (el *etcdElection) Stop() error {
+	el.Lock()
+	if el.running {
+		el.running = false
+		close(el.stopChan)
+		el.metrics.Stop.Inc(1)
+		el.metrics.Running.Update(0)
+		el.metrics.Resigned.Inc(1)
+	}
+	el.Unlock()
+	return el.nomination.ShutDownCallback()
+}
+
+// IsLeader returns whether this candidate is the current leader.
+func (el *etcdElection) IsLeader() bool {
+	el.Lock()
+	defer el.Unlock()
+	return el.running && el.leader
+}
+
+// Resign gives up leadership. The candidate campaigns again right after.
+func (el *etcdElection) Resign() {
+	el.metrics.Resigned.Inc(1)
+	select {
+	case el.resignChan <- struct{}{}:
+	default:
+	}
+}
+
+// updateLeaderElectionMetrics emits leader election metrics at constant
+// interval.
+func (el *etcdElection) updateLeaderElectionMetrics(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-el.stopChan:
+			return
+		case <-ticker.C:
+			if el.IsLeader() {
+				el.metrics.IsLeader.Update(1)
+			} else {
+				el.metrics.IsLeader.Update(0)
+			}
+		}
+	}
+}
+
+// campaign repeatedly runs for the election, and retries when errors
+// are encountered.
+func (el *etcdElection) campaign() {
+	for {
+		select {
+		case <-el.stopChan:
+			log.Info("Stopped running etcd election")
+			return
+		default:
+		}
+
+		if err := el.runForElection(); err != nil {
+			log.WithFields(log.Fields{"role": el.role}).
+				WithError(err).
+				Error("Failure running etcd election; retrying")
+			el.metrics.Error.Inc(1)
+			select {
+			case <-el.stopChan:
+				return
+			case <-time.After(etcdConnErrRetry):
+			}
+		}
+	}
+}
+
+// runForElection campaigns for leadership within a new session, and
+// blocks until leadership is lost, resigned or the election is stopped.
+func (el *etcdElection) runForElection() error {
+	session, err := concurrency.NewSession(
+		el.client,
+		concurrency.WithTTL(el.ttl))
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	election := concurrency.NewElection(session, el.prefix)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-el.stopChan:
+		case <-session.Done():
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	// Campaign blocks until this candidate is elected.
+	if err := election.Campaign(ctx, el.nomination.GetID()); err != nil {
+		select {
+		case <-el.stopChan:
+			return nil
+		case <-session.Done():
+			return errEtcdSessionExpired
+		default:
+			return err
+		}
+	}
+
+	el.setLeader(true)
+	log.WithFields(log.Fields{
+		"id":   el.nomination.GetID(),
+		"role": el.role,
+	}).Info("Leadership gained")
+	el.metrics.GainedLeadership.Inc(1)
+	el.metrics.IsLeader.Update(1)
+
+	if err := el.nomination.GainedLeadershipCallback(); err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"id":   el.nomination.GetID(),
+			"role": el.role,
+		}).Error("GainedLeadershipCallback failed")
+		el.resign(election)
+		el.declareLostLeadership()
+		return nil
+	}
+
+	select {
+	case <-el.stopChan:
+		el.resign(election)
+		el.setLeader(false)
+		return nil
+	case <-el.resignChan:
+		el.resign(election)
+		el.declareLostLeadership()
+		return nil
+	case <-session.Done():
+		el.declareLostLeadership()
+		return errEtcdSessionExpired
+	}
+}
+
+// resign gives up the leadership held in the election.
+func (el *etcdElection) resign(election *concurrency.Election) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		_etcdRequestTimeout)
+	defer cancel()
+
+	if err := election.Resign(ctx); err != nil {
+		log.WithError(err).
+			WithField("role", el.role).
+			Warn("Failed to resign from etcd election")
+	}
+}
+
+// declareLostLeadership declares lost leadership.
+func (el *etcdElection) declareLostLeadership() {
+	el.setLeader(false)
+	log.WithFields(log.Fields{
+		"id":   el.nomination.GetID(),
+		"role": el.role,
+	}).Info("Leadership lost")
+	el.metrics.LostLeadership.Inc(1)
+	el.metrics.IsLeader.Update(0)
+
+	if err := el.nomination.LostLeadershipCallback(); err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"id":   el.nomination.GetID(),
+			"role": el.role,
+		}).Error("LostLeadershipCallback failed")
+	}
+}
+
+func (el *etcdElection) setLeader(leader bool) {
+	el.Lock()
+	defer el.Unlock()
+	el.leader = leader
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3/concurrency"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+// etcdObserver implements Observer on top of etcd v3 elections.
+type etcdObserver struct {
+	sync.Mutex
+	metrics  observerMetrics
+	client   *clientv3.Client
+	prefix   string
+	ttl      int
+	role     string
+	callback func(string) error
+	leader   string
+	running  bool
+	stopChan chan struct{}
+}
+
+// newEtcdObserver creates a new Observer that watches leadership changes
+// of the given role in etcd.
+func newEtcdObserver(
+	cfg ElectionConfig,
+	scope tally.Scope,
+	role string,
+	newLeaderCallback func(string) error) (Observer, error) {
+	client, err := newEtcdClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &etcdObserver{
+		metrics:  newObserverMetrics(scope, role),
+		client:   client,
+		prefix:   leaderEtcdPrefix(cfg.Root, role),
+		ttl:      etcdSessionTTLSeconds(cfg),
+		role:     role,
+		callback: newLeaderCallback,
+		stopChan: make(chan struct{}),
+	}, nil
+}
+
+// Start begins observing the election results. When new leaders are
+// detected, the callback will be invoked.
+func (o *etcdObserver) Start() error {
+	o.Lock()
+	defer o.Unlock()
+	if o.running {
+		return errors.New("Already observing election, cannot Start again")
+	}
+	o.running = true
+	o.metrics.Start.Inc(1)
+	o.metrics.Running.Update(1)
+
+	log.WithFields(log.Fields{"role": o.role}).Info("Watching for leadership changes in etcd")
+
+	go o.observe()
+	return nil
+}
+
+// Stop cancels the observation of an election.
+func (o *etcdObserver) Stop() {
+	o.Lock()
+	defer o.Unlock()
+	if o.running {
+		o.running = false
+		close(o.stopChan)
+		o.metrics.Stop.Inc(1)
+		o.metrics.Running.Update(0)
+	}
+}
+
+// CurrentLeader returns the currently observed leader, or an error if
+// not running.
+func (o *etcdObserver) CurrentLeader() (string, error) {
+	o.Lock()
+	defer o.Unlock()
+	if o.running {
+		return o.leader, nil
+	}
+	return "", errors.New("observer is not running")
+}
+
+// waitForEvent follows the election and invokes the callback for every
+// new leader, until the observation stops or an error occurs.
+func (o *etcdObserver) waitForEvent() error {
+	session, err := concurrency.NewSession(
+		o.client,
+		concurrency.WithTTL(o.ttl))
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leaderCh := concurrency.NewElection(session, o.prefix).Observe(ctx)
+	for {
+		select {
+		case <-o.stopChan:
+			return nil
+		case <-session.Done():
+			return errEtcdSessionExpired
+		case resp, ok := <-leaderCh:
+			if !ok {
+				return errors.New("etcd leader observation closed")
+			}
+			if len(resp.Kvs) == 0 {
+				continue
+			}
+			leader := string(resp.Kvs[0].Value)
+			o.Lock() // make sure we lock around modifying the current leader, and invoking callback
+			if leader == o.leader {
+				o.Unlock()
+				continue
+			}
+			log.WithFields(log.Fields{"role": o.role, "leader": leader}).Info("New leader detected")
+			o.metrics.LeaderChanged.Inc(1)
+			o.leader = leader
+			err := o.callback(leader)
+			o.Unlock()
+			if err != nil {
+				log.WithFields(log.Fields{"role": o.role, "error": err}).Error("NewLeaderCallback failed")
+			}
+		}
+	}
+}
+
+// observe will repeatedly call waitForEvent(), and retry when errors
+// are encountered.
+func (o *etcdObserver) observe() {
+	for {
+		select {
+		case <-o.stopChan:
+			return
+		default:
+			err := o.waitForEvent()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"role":  o.role,
+					"error": err,
+				}).Errorf("Failure observing etcd election; retrying")
+				o.metrics.Error.Inc(1)
+				select {
+				case <-o.stopChan:
+					return
+				case <-time.After(etcdConnErrRetry):
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/embed"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const _etcdTestTimeout = 20 * time.Second
+
+type EtcdElectionTestSuite struct {
+	suite.Suite
+
+	dir    string
+	server *embed.Etcd
+	config ElectionConfig
+}
+
+func TestEtcdElection(t *testing.T) {
+	suite.Run(t, new(EtcdElectionTestSuite))
+}
+
+func (suite *EtcdElectionTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "peloton-etcd")
+	suite.NoError(err)
+	suite.dir = dir
+
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	clientURL, _ := url.Parse("http://127.0.0.1:0")
+	peerURL, _ := url.Parse("http://127.0.0.1:0")
+	cfg.LCUrls = []url.URL{*clientURL}
+	cfg.LPUrls = []url.URL{*peerURL}
+
+	server, err := embed.StartEtcd(cfg)
+	suite.NoError(err)
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(_etcdTestTimeout):
+		suite.FailNow("etcd server took too long to start")
+	}
+	suite.server = server
+
+	suite.config = ElectionConfig{
+		Backend:        EtcdBackend,
+		EtcdEndpoints:  []string{server.Clients[0].Addr().String()},
+		EtcdSessionTTL: time.Second,
+		Root:           "/peloton/etcdtest",
+	}
+}
+
+func (suite *EtcdElectionTestSuite) TearDownTest() {
+	suite.server.Close()
+	os.RemoveAll(suite.dir)
+}
+
+// etcdTestComponent is a testComponent whose ID can be parsed by
+// service discovery.
+type etcdTestComponent struct {
+	*testComponent
+	id string
+}
+
+func (x *etcdTestComponent) GetID() string { return x.id }
+
+func (suite *EtcdElectionTestSuite) newNomination(port int) *etcdTestComponent {
+	id, err := json.Marshal(&ID{IP: "127.0.0.1", HTTPPort: port + 1, GRPCPort: port})
+	suite.NoError(err)
+	return &etcdTestComponent{
+		testComponent: &testComponent{events: make(chan string, 100)},
+		id:            string(id),
+	}
+}
+
+func (suite *EtcdElectionTestSuite) waitForEvent(
+	nomination *etcdTestComponent,
+	expected string) {
+	select {
+	case event := <-nomination.events:
+		suite.Equal(expected, event)
+	case <-time.After(_etcdTestTimeout):
+		suite.FailNow(fmt.Sprintf("timed out waiting for %s", expected))
+	}
+}
+
+// TestEtcdCandidateFailover tests that leadership moves to the other
+// candidate once the leader stops, and that discovery follows it.
+func (suite *EtcdElectionTestSuite) TestEtcdCandidateFailover() {
+	role := "testrole"
+	n1 := suite.newNomination(1000)
+	n2 := suite.newNomination(2000)
+
+	c1, err := NewCandidate(suite.config, tally.NoopScope, role, n1)
+	suite.NoError(err)
+	suite.NoError(c1.Start())
+	suite.waitForEvent(n1, "leadership_gained")
+	suite.True(c1.IsLeader())
+
+	c2, err := NewCandidate(suite.config, tally.NoopScope, role, n2)
+	suite.NoError(err)
+	suite.NoError(c2.Start())
+	suite.Error(c2.Start())
+	suite.False(c2.IsLeader())
+
+	discovery, err := NewServiceDiscovery(suite.config)
+	suite.NoError(err)
+	u, err := discovery.GetAppURL(role)
+	suite.NoError(err)
+	suite.Equal("127.0.0.1:1000", u.Host)
+	u, err = discovery.GetAppHTTPURL(role)
+	suite.NoError(err)
+	suite.Equal("127.0.0.1:1001", u.Host)
+
+	suite.NoError(c1.Stop())
+	suite.waitForEvent(n1, "shutdown")
+	suite.False(c1.IsLeader())
+
+	suite.waitForEvent(n2, "leadership_gained")
+	suite.True(c2.IsLeader())
+	u, err = discovery.GetAppURL(role)
+	suite.NoError(err)
+	suite.Equal("127.0.0.1:2000", u.Host)
+
+	suite.NoError(c2.Stop())
+	suite.waitForEvent(n2, "shutdown")
+}
+
+// TestEtcdCandidateResign tests that a resigned leader campaigns again.
+func (suite *EtcdElectionTestSuite) TestEtcdCandidateResign() {
+	n := suite.newNomination(1000)
+	c, err := NewCandidate(suite.config, tally.NoopScope, "testrole", n)
+	suite.NoError(err)
+	suite.NoError(c.Start())
+	suite.waitForEvent(n, "leadership_gained")
+
+	c.Resign()
+	suite.waitForEvent(n, "leadership_lost")
+	suite.waitForEvent(n, "leadership_gained")
+	suite.True(c.IsLeader())
+
+	suite.NoError(c.Stop())
+	suite.waitForEvent(n, "shutdown")
+}
+
+// TestEtcdObserver tests that the observer reports new leaders.
+func (suite *EtcdElectionTestSuite) TestEtcdObserver() {
+	role := "testrole"
+	leaders := make(chan string, 10)
+	o, err := NewObserver(
+		suite.config,
+		tally.NoopScope,
+		role,
+		func(leader string) error {
+			leaders <- leader
+			return nil
+		})
+	suite.NoError(err)
+
+	_, err = o.CurrentLeader()
+	suite.Error(err)
+	suite.NoError(o.Start())
+
+	n := suite.newNomination(1000)
+	c, err := NewCandidate(suite.config, tally.NoopScope, role, n)
+	suite.NoError(err)
+	suite.NoError(c.Start())
+	suite.waitForEvent(n, "leadership_gained")
+
+	select {
+	case leader := <-leaders:
+		suite.Equal(n.GetID(), leader)
+	case <-time.After(_etcdTestTimeout):
+		suite.FailNow("timed out waiting for leader")
+	}
+	leader, err := o.CurrentLeader()
+	suite.NoError(err)
+	suite.Equal(n.GetID(), leader)
+
+	o.Stop()
+	suite.NoError(c.Stop())
+}
+
+// TestEtcdDiscoveryNoLeader tests discovery when no leader is elected.
+func (suite *EtcdElectionTestSuite) TestEtcdDiscoveryNoLeader() {
+	discovery, err := NewEtcdServiceDiscovery(
+		suite.config.EtcdEndpoints,
+		suite.config.Root)
+	suite.NoError(err)
+	_, err = discovery.GetAppURL("testrole")
+	suite.Equal(errNoEtcdLeader, err)
+}
+
+// TestNewEtcdCandidateNoEndpoints tests that creating a candidate fails
+// without etcd endpoints.
+func (suite *EtcdElectionTestSuite) TestNewEtcdCandidateNoEndpoints() {
+	cfg := suite.config
+	cfg.EtcdEndpoints = nil
+	_, err := NewCandidate(cfg, tally.NoopScope, "testrole", suite.newNomination(1000))
+	suite.Error(err)
+}
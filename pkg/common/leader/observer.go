@@ -49,6 +49,9 @@ type observer struct {
 // a given `role`, and will call newLeaderCallback whenever leadership changes
 func NewObserver(cfg ElectionConfig, scope tally.Scope, role string, newLeaderCallback func(string) error) (Observer, error) {
 	log.WithFields(log.Fields{"role": role}).Debug("Creating new observer of election")
+	if cfg.IsEtcd() {
+		return newEtcdObserver(cfg, scope, role, newLeaderCallback)
+	}
 	client, err := zookeeper.New(cfg.ZKServers, &store.Config{ConnectionTimeout: zkConnErrRetry})
 	if err != nil {
 		return nil, err
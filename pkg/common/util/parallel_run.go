@@ -114,3 +114,40 @@ func RunInParallel(identifier string, idList []uint32, task singleTask) error {
 	}
 	return nil
 }
+
+// RunInParallelWithLimit runs the task for every instance in idList with
+// at most maxParallel tasks running at a time, and returns the errors of
+// the failed tasks by instance. Unlike RunInParallel, a failure does not
+// stop the other tasks, so that callers can use the partial results.
+func RunInParallelWithLimit(
+	idList []uint32,
+	maxParallel int,
+	task singleTask,
+) map[uint32]error {
+	if maxParallel <= 0 {
+		maxParallel = _defaultMaxParallelBatches
+	}
+
+	var mu sync.Mutex
+	errs := make(map[uint32]error)
+	sem := make(chan struct{}, maxParallel)
+	wg := new(sync.WaitGroup)
+
+	for _, id := range idList {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(id uint32) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := task(id); err != nil {
+				mu.Lock()
+				errs[id] = err
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+	return errs
+}
@@ -70,3 +70,45 @@ func (suite *TaskTestSuite) TestRunInParallelFail() {
 	err := RunInParallel(uuid.NewRandom().String(), instances, worker)
 	suite.True(yarpcerrors.IsAborted(err))
 }
+
+// TestRunInParallelWithLimit tests that all instances are run with
+// bounded parallelism and failures are returned by instance.
+func (suite *TaskTestSuite) TestRunInParallelWithLimit() {
+	var instances []uint32
+	for i := uint32(0); i < 100; i++ {
+		instances = append(instances, i)
+	}
+
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	done := make(map[uint32]bool)
+	worker := func(id uint32) error {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+
+		defer func() {
+			lock.Lock()
+			running--
+			done[id] = true
+			lock.Unlock()
+		}()
+
+		if id%10 == 0 {
+			return yarpcerrors.NotFoundErrorf("test error")
+		}
+		return nil
+	}
+
+	errs := RunInParallelWithLimit(instances, 5, worker)
+	suite.Len(errs, 10)
+	for id, err := range errs {
+		suite.Equal(uint32(0), id%10)
+		suite.True(yarpcerrors.IsNotFound(err))
+	}
+	suite.Len(done, 100)
+	suite.True(maxRunning <= 5)
+}
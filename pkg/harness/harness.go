@@ -0,0 +1,675 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package harness runs job manager, resource manager, placement engine
+// and a fake host manager in a single process, talking to each other
+// over gRPC on loopback and sharing an in-memory store, so that the
+// lifecycle of a job can be tested end to end without Mesos or
+// Cassandra.
+//
+// Job manager and resource manager keep process wide singletons (the
+// task tracker, the task scheduler, the task launcher and the watch
+// processor), so only one harness may be created per process.
+package harness
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+	resmgr_task "github.com/uber/peloton/.gen/peloton/private/resmgr"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/api"
+	"github.com/uber/peloton/pkg/common/async"
+	"github.com/uber/peloton/pkg/common/background"
+	"github.com/uber/peloton/pkg/common/config"
+	"github.com/uber/peloton/pkg/common/leader"
+	"github.com/uber/peloton/pkg/jobmgr"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	"github.com/uber/peloton/pkg/jobmgr/goalstate"
+	"github.com/uber/peloton/pkg/jobmgr/jobsvc"
+	"github.com/uber/peloton/pkg/jobmgr/logmanager"
+	"github.com/uber/peloton/pkg/jobmgr/secret"
+	"github.com/uber/peloton/pkg/jobmgr/task/activermtask"
+	"github.com/uber/peloton/pkg/jobmgr/task/deadline"
+	"github.com/uber/peloton/pkg/jobmgr/task/event"
+	"github.com/uber/peloton/pkg/jobmgr/task/launcher"
+	jobmgr_placement "github.com/uber/peloton/pkg/jobmgr/task/placement"
+	"github.com/uber/peloton/pkg/jobmgr/task/preemptor"
+	"github.com/uber/peloton/pkg/jobmgr/tasksvc"
+	"github.com/uber/peloton/pkg/jobmgr/watchsvc"
+	"github.com/uber/peloton/pkg/placement"
+	placementconfig "github.com/uber/peloton/pkg/placement/config"
+	"github.com/uber/peloton/pkg/placement/hosts"
+	tally_metrics "github.com/uber/peloton/pkg/placement/metrics"
+	offers_v0 "github.com/uber/peloton/pkg/placement/offers/v0"
+	"github.com/uber/peloton/pkg/placement/plugins/batch"
+	"github.com/uber/peloton/pkg/placement/tasks"
+	"github.com/uber/peloton/pkg/resmgr"
+	"github.com/uber/peloton/pkg/resmgr/entitlement"
+	maintenance "github.com/uber/peloton/pkg/resmgr/host"
+	"github.com/uber/peloton/pkg/resmgr/preemption"
+	res "github.com/uber/peloton/pkg/resmgr/respool"
+	"github.com/uber/peloton/pkg/resmgr/respool/respoolsvc"
+	rmtask "github.com/uber/peloton/pkg/resmgr/task"
+	"github.com/uber/peloton/pkg/storage/memory"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/pkg/errors"
+	"github.com/uber-go/tally"
+	"go.uber.org/atomic"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/transport/grpc"
+)
+
+const (
+	// _entitlementCalculationPeriod replaces the entitlement calculation
+	// period of the resource manager config, so that the entitlement
+	// follows the cluster capacity within a test
+	_entitlementCalculationPeriod = time.Second
+
+	// _taskDequeuePeriod is the period at which the placement engine
+	// dequeues tasks to place
+	_taskDequeuePeriod = 100 * time.Millisecond
+
+	_httpClientTimeout = 10 * time.Second
+)
+
+// Config is the configuration of the components run by the harness
+type Config struct {
+	JobManager jobmgr.Config                   `yaml:"job_manager"`
+	ResManager resmgr.Config                   `yaml:"resmgr"`
+	Placement  placementconfig.PlacementConfig `yaml:"placement"`
+
+	// Hosts offered by the fake host manager
+	Hosts []*Host `yaml:"hosts"`
+}
+
+// LoadConfig loads the base configuration of job manager, resource
+// manager and placement engine from the config directory of the
+// repository at root, and adjusts it to the harness: every component
+// talks to the v0 host manager API, and the placement engine places
+// batch tasks.
+func LoadConfig(root string) (Config, error) {
+	var cfg Config
+	if err := config.Parse(
+		&cfg,
+		filepath.Join(root, "config", "jobmgr", "base.yaml"),
+		filepath.Join(root, "config", "resmgr", "base.yaml"),
+		filepath.Join(root, "config", "placement", "base.yaml"),
+	); err != nil {
+		return cfg, errors.Wrap(err, "failed to load config")
+	}
+
+	cfg.JobManager.HostManagerAPIVersion = api.V0
+	cfg.ResManager.HostManagerAPIVersion = api.V0
+	cfg.ResManager.EntitlementCaculationPeriod = _entitlementCalculationPeriod
+
+	cfg.Placement.HostManagerAPIVersion = api.V0
+	cfg.Placement.TaskType = resmgr_task.TaskType_BATCH
+	cfg.Placement.Strategy = placementconfig.Batch
+	cfg.Placement.FetchOfferTasks = false
+	cfg.Placement.TaskDequeuePeriod = _taskDequeuePeriod
+	return cfg, nil
+}
+
+// Harness runs job manager, resource manager, placement engine and a
+// fake host manager in process.
+type Harness struct {
+	// Store is the in-memory store shared by job manager and
+	// resource manager
+	Store    *memory.Store
+	OrmStore *ormobjects.Store
+
+	// HostManager is the fake host manager
+	HostManager *HostManager
+
+	// Clients of the job manager and resource manager APIs
+	JobClient     job.JobManagerYARPCClient
+	TaskClient    task.TaskManagerYARPCClient
+	ResPoolClient respool.ResourceManagerYARPCClient
+
+	hostmgrDispatcher   *yarpc.Dispatcher
+	resmgrDispatcher    *yarpc.Dispatcher
+	jobmgrDispatcher    *yarpc.Dispatcher
+	placementDispatcher *yarpc.Dispatcher
+	clientDispatcher    *yarpc.Dispatcher
+
+	resmgrCandidate *localCandidate
+	jobmgrCandidate *localCandidate
+	placementEngine placement.Engine
+	placementPool   *async.Pool
+}
+
+// New creates a harness running the components with cfg. The harness
+// does not serve any request until it is started.
+func New(cfg Config) (*Harness, error) {
+	scope := tally.NoopScope
+
+	ormStore, err := ormobjects.NewMemoryStore(scope)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create ORM store")
+	}
+	store := memory.NewStore(ormStore)
+
+	listeners := make(map[string]net.Listener)
+	for _, name := range []string{
+		common.PelotonHostManager,
+		common.PelotonResourceManager,
+		common.PelotonJobManager,
+	} {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, errors.Wrapf(err, "failed to listen for %s", name)
+		}
+		listeners[name] = listener
+	}
+
+	t := grpc.NewTransport()
+	newOutbound := func(name string) *grpc.Outbound {
+		return t.NewSingleOutbound(listeners[name].Addr().String())
+	}
+
+	h := &Harness{
+		Store:    store,
+		OrmStore: ormStore,
+	}
+
+	// Host manager
+	h.hostmgrDispatcher = yarpc.NewDispatcher(yarpc.Config{
+		Name: common.PelotonHostManager,
+		Inbounds: yarpc.Inbounds{
+			t.NewInbound(listeners[common.PelotonHostManager]),
+		},
+		Outbounds: yarpc.Outbounds{
+			common.PelotonResourceManager: transport.Outbounds{
+				Unary: newOutbound(common.PelotonResourceManager),
+			},
+		},
+	})
+	h.HostManager = NewHostManager(h.hostmgrDispatcher, scope, cfg.Hosts)
+
+	// Resource manager
+	h.resmgrDispatcher = yarpc.NewDispatcher(yarpc.Config{
+		Name: common.PelotonResourceManager,
+		Inbounds: yarpc.Inbounds{
+			t.NewInbound(listeners[common.PelotonResourceManager]),
+		},
+		Outbounds: yarpc.Outbounds{
+			common.PelotonHostManager: transport.Outbounds{
+				Unary: newOutbound(common.PelotonHostManager),
+			},
+		},
+	})
+	resmgrServer := newResourceManager(
+		h.resmgrDispatcher, scope, store, ormStore, cfg.ResManager)
+	h.resmgrCandidate = &localCandidate{nomination: resmgrServer}
+
+	// Job manager
+	h.jobmgrDispatcher = yarpc.NewDispatcher(yarpc.Config{
+		Name: common.PelotonJobManager,
+		Inbounds: yarpc.Inbounds{
+			t.NewInbound(listeners[common.PelotonJobManager]),
+		},
+		Outbounds: jobmgr.NewOutbounds(
+			newOutbound(common.PelotonResourceManager),
+			newOutbound(common.PelotonHostManager),
+		),
+	})
+	h.jobmgrCandidate = &localCandidate{}
+	jobmgrServer, err := newJobManager(
+		h.jobmgrDispatcher,
+		scope,
+		store,
+		ormStore,
+		h.jobmgrCandidate,
+		cfg.JobManager,
+	)
+	if err != nil {
+		return nil, err
+	}
+	h.jobmgrCandidate.nomination = jobmgrServer
+
+	// Placement engine
+	h.placementDispatcher = yarpc.NewDispatcher(yarpc.Config{
+		Name: common.PelotonPlacement,
+		Outbounds: yarpc.Outbounds{
+			common.PelotonResourceManager: transport.Outbounds{
+				Unary: newOutbound(common.PelotonResourceManager),
+			},
+			common.PelotonHostManager: transport.Outbounds{
+				Unary: newOutbound(common.PelotonHostManager),
+			},
+		},
+	})
+	h.placementEngine, h.placementPool = newPlacementEngine(
+		h.placementDispatcher, scope, &cfg.Placement)
+
+	// Clients
+	h.clientDispatcher = yarpc.NewDispatcher(yarpc.Config{
+		Name: common.PelotonCLI,
+		Outbounds: yarpc.Outbounds{
+			common.PelotonJobManager: transport.Outbounds{
+				Unary: newOutbound(common.PelotonJobManager),
+			},
+			common.PelotonResourceManager: transport.Outbounds{
+				Unary: newOutbound(common.PelotonResourceManager),
+			},
+		},
+	})
+	h.JobClient = job.NewJobManagerYARPCClient(
+		h.clientDispatcher.ClientConfig(common.PelotonJobManager))
+	h.TaskClient = task.NewTaskManagerYARPCClient(
+		h.clientDispatcher.ClientConfig(common.PelotonJobManager))
+	h.ResPoolClient = respool.NewResourceManagerYARPCClient(
+		h.clientDispatcher.ClientConfig(common.PelotonResourceManager))
+
+	return h, nil
+}
+
+// newResourceManager wires the resource manager components on the
+// dispatcher, and returns the server starting them on leadership.
+func newResourceManager(
+	d *yarpc.Dispatcher,
+	scope tally.Scope,
+	store *memory.Store,
+	ormStore *ormobjects.Store,
+	cfg resmgr.Config,
+) *resmgr.Server {
+	hostmgrClient := hostsvc.NewInternalHostServiceYARPCClient(
+		d.ClientConfig(common.PelotonHostManager))
+
+	tree := res.NewTree(
+		scope,
+		ormobjects.NewResPoolOps(ormStore),
+		store, // store implements JobStore
+		store, // store implements TaskStore
+		*cfg.PreemptionConfig)
+
+	respoolsvc.InitServiceHandler(
+		d,
+		scope,
+		tree,
+		ormobjects.NewResPoolOps(ormStore),
+	)
+
+	rmtask.InitTaskTracker(scope, cfg.RmTaskConfig)
+	rmtask.InitScheduler(
+		scope,
+		tree,
+		cfg.TaskSchedulingPeriod,
+		rmtask.GetTracker(),
+	)
+
+	calculator := entitlement.NewCalculator(
+		cfg.EntitlementCaculationPeriod,
+		scope,
+		d,
+		tree,
+		cfg.HostManagerAPIVersion,
+	)
+	reconciler := rmtask.NewReconciler(
+		rmtask.GetTracker(),
+		store, // store implements TaskStore
+		scope,
+		cfg.TaskReconciliationPeriod,
+	)
+	preemptor := preemption.NewPreemptor(
+		scope,
+		cfg.PreemptionConfig,
+		rmtask.GetTracker(),
+		tree,
+	)
+	drainer := maintenance.NewDrainer(
+		scope,
+		hostmgrClient,
+		cfg.HostDrainerPeriod,
+		rmtask.GetTracker(),
+		preemptor)
+
+	serviceHandler := resmgr.NewServiceHandler(
+		d,
+		scope,
+		rmtask.GetTracker(),
+		tree,
+		preemptor,
+		hostmgrClient,
+		cfg,
+	)
+	recoveryHandler := resmgr.NewRecovery(
+		scope,
+		store, // store implements TaskStore
+		ormobjects.NewActiveJobsOps(ormStore),
+		ormobjects.NewJobConfigOps(ormStore),
+		ormobjects.NewJobRuntimeOps(ormStore),
+		serviceHandler,
+		tree,
+		cfg,
+		hostmgrClient,
+	)
+
+	return resmgr.NewServer(
+		scope,
+		cfg.HTTPPort,
+		cfg.GRPCPort,
+		tree,
+		recoveryHandler,
+		calculator,
+		reconciler,
+		preemptor,
+		drainer,
+	)
+}
+
+// newJobManager wires the job manager components on the dispatcher, and
+// returns the server starting them on leadership.
+func newJobManager(
+	d *yarpc.Dispatcher,
+	scope tally.Scope,
+	store *memory.Store,
+	ormStore *ormobjects.Store,
+	candidate leader.Candidate,
+	cfg jobmgr.Config,
+) (*jobmgr.Server, error) {
+	backgroundManager := background.NewManager()
+
+	activeJobCache := activermtask.NewActiveRMTasks(d, scope)
+	backgroundManager.RegisterWorks(
+		background.Work{
+			Name: "ActiveCacheJob",
+			Func: func(_ *atomic.Bool) {
+				activeJobCache.UpdateActiveTasks()
+			},
+			Period: time.Duration(cfg.ActiveTaskUpdatePeriod),
+		},
+	)
+
+	watchProcessor := watchsvc.InitV1AlphaWatchServiceHandler(
+		d,
+		scope,
+		cfg.Watch,
+	)
+
+	jobFactory := cached.InitJobFactory(
+		store, // store implements JobStore
+		store, // store implements TaskStore
+		store, // store implements UpdateStore
+		store, // store implements VolumeStore
+		ormStore,
+		scope,
+		[]cached.JobTaskListener{watchsvc.NewWatchListener(watchProcessor)},
+	)
+
+	secretProvider, err := secret.NewProvider(
+		secret.Config{},
+		ormobjects.NewSecretInfoOps(ormStore),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create secret provider")
+	}
+
+	launcher.InitTaskLauncher(
+		d,
+		common.PelotonHostManager,
+		jobFactory,
+		ormStore,
+		secretProvider,
+		scope,
+		cfg.HostManagerAPIVersion,
+	)
+
+	goalStateDriver := goalstate.NewDriver(
+		d,
+		store, // store implements JobStore
+		store, // store implements TaskStore
+		store, // store implements VolumeStore
+		store, // store implements UpdateStore
+		ormStore,
+		jobFactory,
+		job.JobType_BATCH,
+		scope,
+		cfg.GoalState,
+		cfg.HostManagerAPIVersion,
+	)
+
+	placementProcessor := jobmgr_placement.InitProcessor(
+		d,
+		common.PelotonResourceManager,
+		jobFactory,
+		goalStateDriver,
+		launcher.GetLauncher(),
+		&cfg.Placement,
+		scope,
+	)
+
+	taskPreemptor := preemptor.New(
+		d,
+		common.PelotonResourceManager,
+		ormStore,
+		jobFactory,
+		goalStateDriver,
+		&cfg.Preemptor,
+		scope,
+	)
+
+	deadlineTracker := deadline.New(
+		d,
+		store, // store implements JobStore
+		store, // store implements TaskStore
+		jobFactory,
+		goalStateDriver,
+		scope,
+		&cfg.Deadline,
+	)
+
+	statusUpdate := event.NewTaskStatusUpdate(
+		d,
+		store, // store implements JobStore
+		store, // store implements TaskStore
+		store, // store implements VolumeStore
+		jobFactory,
+		goalStateDriver,
+		[]event.Listener{},
+		scope,
+		cfg.HostManagerAPIVersion,
+	)
+
+	jobsvc.InitServiceHandler(
+		d,
+		scope,
+		store, // store implements JobStore
+		store, // store implements TaskStore
+		ormStore,
+		jobFactory,
+		goalStateDriver,
+		candidate,
+		common.PelotonResourceManager,
+		cfg.JobSvcCfg,
+		secretProvider,
+	)
+
+	tasksvc.InitServiceHandler(
+		d,
+		scope,
+		ormStore,
+		store, // store implements TaskStore
+		store, // store implements UpdateStore
+		store, // store implements FrameworkInfoStore
+		jobFactory,
+		goalStateDriver,
+		candidate,
+		"",
+		common.PelotonHostManager,
+		logmanager.NewLogManager(&http.Client{Timeout: _httpClientTimeout}),
+		activeJobCache,
+	)
+
+	return jobmgr.NewServer(
+		cfg.HTTPPort,
+		cfg.GRPCPort,
+		jobFactory,
+		goalStateDriver,
+		taskPreemptor,
+		deadlineTracker,
+		placementProcessor,
+		statusUpdate,
+		backgroundManager,
+		watchProcessor,
+	), nil
+}
+
+// newPlacementEngine creates a batch placement engine calling resource
+// manager and host manager over the outbounds of the dispatcher.
+func newPlacementEngine(
+	d *yarpc.Dispatcher,
+	scope tally.Scope,
+	cfg *placementconfig.PlacementConfig,
+) (placement.Engine, *async.Pool) {
+	tallyMetrics := tally_metrics.NewMetrics(scope.SubScope("placement"))
+	resourceManager := resmgrsvc.NewResourceManagerServiceYARPCClient(
+		d.ClientConfig(common.PelotonResourceManager))
+	hostManager := hostsvc.NewInternalHostServiceYARPCClient(
+		d.ClientConfig(common.PelotonHostManager))
+
+	pool := async.NewPool(async.PoolOptions{
+		MaxWorkers: cfg.Concurrency,
+	}, nil)
+
+	engine := placement.New(
+		scope,
+		cfg,
+		offers_v0.NewService(hostManager, resourceManager, tallyMetrics),
+		tasks.NewService(resourceManager, cfg, tallyMetrics),
+		hosts.NewService(hostManager, resourceManager, tallyMetrics),
+		batch.New(cfg),
+		pool,
+	)
+	return engine, pool
+}
+
+// Start starts the components: the dispatchers first, then resource
+// manager and job manager as leaders, then the host manager event
+// forwarding and the placement engine.
+func (h *Harness) Start() error {
+	for _, d := range []*yarpc.Dispatcher{
+		h.hostmgrDispatcher,
+		h.resmgrDispatcher,
+		h.jobmgrDispatcher,
+		h.placementDispatcher,
+		h.clientDispatcher,
+	} {
+		if err := d.Start(); err != nil {
+			return errors.Wrapf(err, "failed to start %s dispatcher", d.Name())
+		}
+	}
+
+	if err := h.resmgrCandidate.Start(); err != nil {
+		return errors.Wrap(err, "failed to start resource manager")
+	}
+	if err := h.jobmgrCandidate.Start(); err != nil {
+		return errors.Wrap(err, "failed to start job manager")
+	}
+
+	h.HostManager.Start()
+	h.placementPool.Start()
+	h.placementEngine.Start()
+	return nil
+}
+
+// Stop stops the components in the reverse order they are started.
+func (h *Harness) Stop() error {
+	h.placementEngine.Stop()
+	h.placementPool.Stop()
+	h.HostManager.Stop()
+
+	var errs []error
+	for _, c := range []*localCandidate{h.jobmgrCandidate, h.resmgrCandidate} {
+		if err := c.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, d := range []*yarpc.Dispatcher{
+		h.clientDispatcher,
+		h.placementDispatcher,
+		h.jobmgrDispatcher,
+		h.resmgrDispatcher,
+		h.hostmgrDispatcher,
+	} {
+		if err := d.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("failed to stop harness: %v", errs)
+	}
+	return nil
+}
+
+// localCandidate is a leader candidate which is elected as soon as it is
+// started, for components running without leader election.
+type localCandidate struct {
+	sync.Mutex
+
+	nomination leader.Nomination
+	leader     bool
+}
+
+// IsLeader returns whether the candidate is started
+func (c *localCandidate) IsLeader() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.leader
+}
+
+// Start elects the candidate
+func (c *localCandidate) Start() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.leader {
+		return nil
+	}
+	if err := c.nomination.GainedLeadershipCallback(); err != nil {
+		return err
+	}
+	c.leader = true
+	return nil
+}
+
+// Stop shuts the candidate down
+func (c *localCandidate) Stop() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.leader {
+		return nil
+	}
+	c.leader = false
+	return c.nomination.ShutDownCallback()
+}
+
+// Resign is a no-op, there is no other candidate to take over
+func (c *localCandidate) Resign() {}
@@ -0,0 +1,242 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	_testTimeout  = 60 * time.Second
+	_pollInterval = 100 * time.Millisecond
+)
+
+type HarnessTestSuite struct {
+	suite.Suite
+
+	harness *Harness
+}
+
+func (suite *HarnessTestSuite) SetupSuite() {
+	cfg, err := LoadConfig("../..")
+	suite.Require().NoError(err)
+	cfg.Hosts = []*Host{
+		{Hostname: "host-0", CPU: 4, Memory: 4096, Disk: 4096},
+		{Hostname: "host-1", CPU: 4, Memory: 4096, Disk: 4096},
+	}
+
+	suite.harness, err = New(cfg)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.harness.Start())
+}
+
+func (suite *HarnessTestSuite) TearDownSuite() {
+	suite.NoError(suite.harness.Stop())
+}
+
+func TestHarness(t *testing.T) {
+	suite.Run(t, new(HarnessTestSuite))
+}
+
+// waitFor polls condition until it holds, and fails the test with msg
+// if it does not hold within the test timeout
+func (suite *HarnessTestSuite) waitFor(condition func() bool, msg string) {
+	deadline := time.Now().Add(_testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			suite.FailNow(msg)
+		}
+		time.Sleep(_pollInterval)
+	}
+}
+
+// createResPool creates a leaf resource pool under the root pool
+func (suite *HarnessTestSuite) createResPool(
+	ctx context.Context,
+	name string,
+) *peloton.ResourcePoolID {
+	var resources []*respool.ResourceConfig
+	for kind, amount := range map[string]float64{
+		common.CPU:    4,
+		common.MEMORY: 4096,
+		common.DISK:   4096,
+		common.GPU:    0,
+	} {
+		resources = append(resources, &respool.ResourceConfig{
+			Kind:        kind,
+			Reservation: amount,
+			Limit:       amount,
+			Share:       1,
+		})
+	}
+
+	resp, err := suite.harness.ResPoolClient.CreateResourcePool(
+		ctx,
+		&respool.CreateRequest{
+			Config: &respool.ResourcePoolConfig{
+				Name:      name,
+				Parent:    &peloton.ResourcePoolID{Value: common.RootResPoolID},
+				Resources: resources,
+				Policy:    respool.SchedulingPolicy_PriorityFIFO,
+			},
+		})
+	suite.Require().NoError(err)
+	suite.Require().Nil(resp.GetError())
+	return resp.GetResult()
+}
+
+// waitForTasks polls the tasks of a job until all of them are in state,
+// and returns them.
+func (suite *HarnessTestSuite) waitForTasks(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceCount uint32,
+	state task.TaskState,
+) map[uint32]*task.TaskInfo {
+	var tasks map[uint32]*task.TaskInfo
+	suite.waitFor(func() bool {
+		resp, err := suite.harness.TaskClient.List(
+			ctx,
+			&task.ListRequest{JobId: jobID})
+		if err != nil || resp.GetResult() == nil {
+			return false
+		}
+		tasks = resp.GetResult().GetValue()
+		if uint32(len(tasks)) != instanceCount {
+			return false
+		}
+		for _, t := range tasks {
+			if t.GetRuntime().GetState() != state {
+				return false
+			}
+		}
+		return true
+	}, "tasks are not "+state.String())
+	return tasks
+}
+
+// createBatchJob creates a batch job with instanceCount tasks in the
+// resource pool
+func (suite *HarnessTestSuite) createBatchJob(
+	ctx context.Context,
+	name string,
+	respoolID *peloton.ResourcePoolID,
+	instanceCount uint32,
+) *peloton.JobID {
+	command := "echo hello"
+	createResp, err := suite.harness.JobClient.Create(ctx, &job.CreateRequest{
+		Config: &job.JobConfig{
+			Name:          name,
+			Type:          job.JobType_BATCH,
+			InstanceCount: instanceCount,
+			RespoolID:     respoolID,
+			DefaultConfig: &task.TaskConfig{
+				Resource: &task.ResourceConfig{
+					CpuLimit:    1,
+					MemLimitMb:  128,
+					DiskLimitMb: 128,
+				},
+				Command: &mesos.CommandInfo{Value: &command},
+			},
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Nil(createResp.GetError())
+	return createResp.GetJobId()
+}
+
+// TestTaskRunning tests that a task driven to RUNNING is written to the
+// task_runtime table of the ORM store, where the job cache patches it,
+// and that its pod events are recorded.
+func (suite *HarnessTestSuite) TestTaskRunning() {
+	ctx, cancel := context.WithTimeout(context.Background(), _testTimeout)
+	defer cancel()
+
+	respoolID := suite.createResPool(ctx, "running")
+	jobID := suite.createBatchJob(ctx, "harness-running-job", respoolID, 1)
+
+	tasks := suite.waitForTasks(ctx, jobID, 1, task.TaskState_RUNNING)
+	mesosTaskID := tasks[0].GetRuntime().GetMesosTaskId().GetValue()
+
+	runtime, err := ormobjects.NewTaskRuntimeOps(suite.harness.OrmStore).
+		Get(ctx, jobID, 0)
+	suite.Require().NoError(err)
+	suite.Equal(task.TaskState_RUNNING, runtime.GetState())
+	suite.Equal(mesosTaskID, runtime.GetMesosTaskId().GetValue())
+
+	events, err := suite.harness.Store.GetPodEvents(
+		ctx, jobID.GetValue(), 0)
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(events)
+	suite.Equal(task.TaskState_RUNNING.String(), events[0].GetActualState())
+
+	suite.NoError(suite.harness.HostManager.SendStatusUpdate(
+		mesosTaskID, mesos.TaskState_TASK_FINISHED))
+	suite.waitForTasks(ctx, jobID, 1, task.TaskState_SUCCEEDED)
+}
+
+// TestBatchJobLifecycle tests a batch job being created, its tasks being
+// placed on the hosts, launched, reported running and then finished.
+func (suite *HarnessTestSuite) TestBatchJobLifecycle() {
+	ctx, cancel := context.WithTimeout(context.Background(), _testTimeout)
+	defer cancel()
+
+	respoolID := suite.createResPool(ctx, "batch")
+
+	instanceCount := uint32(2)
+	jobID := suite.createBatchJob(
+		ctx, "harness-batch-job", respoolID, instanceCount)
+
+	// the tasks are placed on the hosts of the fake host manager,
+	// launched and reported running
+	tasks := suite.waitForTasks(
+		ctx, jobID, instanceCount, task.TaskState_RUNNING)
+	for _, t := range tasks {
+		suite.NotEmpty(t.GetRuntime().GetHost())
+		state, ok := suite.harness.HostManager.GetTaskState(
+			t.GetRuntime().GetMesosTaskId().GetValue())
+		suite.True(ok)
+		suite.Equal(mesos.TaskState_TASK_RUNNING, state)
+	}
+
+	// the tasks finishing on the hosts complete the job
+	for _, t := range tasks {
+		suite.NoError(suite.harness.HostManager.SendStatusUpdate(
+			t.GetRuntime().GetMesosTaskId().GetValue(),
+			mesos.TaskState_TASK_FINISHED))
+	}
+	suite.waitForTasks(ctx, jobID, instanceCount, task.TaskState_SUCCEEDED)
+
+	suite.waitFor(func() bool {
+		resp, err := suite.harness.JobClient.Get(
+			ctx,
+			&job.GetRequest{Id: jobID})
+		return err == nil &&
+			resp.GetJobInfo().GetRuntime().GetState() == job.JobState_SUCCEEDED
+	}, "job is not succeeded")
+}
@@ -0,0 +1,655 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package harness
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pb_eventstream "github.com/uber/peloton/.gen/peloton/private/eventstream"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/eventstream"
+	"github.com/uber/peloton/pkg/common/util"
+
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_eventStreamBufferSize = 1000
+
+	_notifyResourceManagerTimeout = 10 * time.Second
+)
+
+// Host is a host offered by the fake host manager. Memory and disk
+// are in MB.
+type Host struct {
+	Hostname string  `yaml:"hostname"`
+	CPU      float64 `yaml:"cpu"`
+	Memory   float64 `yaml:"memory"`
+	Disk     float64 `yaml:"disk"`
+	GPU      float64 `yaml:"gpu"`
+}
+
+// resources is the amount of each resource kind used on a host
+type resources struct {
+	cpu, mem, disk, gpu float64
+}
+
+func (r *resources) add(other resources) {
+	r.cpu += other.cpu
+	r.mem += other.mem
+	r.disk += other.disk
+	r.gpu += other.gpu
+}
+
+func (r *resources) subtract(other resources) {
+	r.cpu -= other.cpu
+	r.mem -= other.mem
+	r.disk -= other.disk
+	r.gpu -= other.gpu
+}
+
+// hostState is the state of a host offered by the fake host manager
+type hostState struct {
+	host    *Host
+	agentID string
+	// offerID is the ID of the outstanding offer of the host, empty if
+	// the host is not offered
+	offerID string
+	// used is the resources used by the tasks launched on the host
+	used resources
+}
+
+// launchedTask is a task launched on the fake host manager
+type launchedTask struct {
+	hostname  string
+	agentID   string
+	resources resources
+	state     mesos.TaskState
+}
+
+// HostManager is a fake host manager serving the v0 host manager API to
+// the other components. It offers the unused resources of a fixed set of
+// hosts, reports the tasks it launches as running, and lets tests drive
+// the tasks further with SendStatusUpdate. The status updates are
+// published on an event stream to job manager, and forwarded to
+// resource manager as the real host manager does.
+type HostManager struct {
+	sync.Mutex
+
+	hosts map[string]*hostState
+	tasks map[string]*launchedTask
+
+	eventStreamHandler *eventstream.Handler
+	eventForwarder     *eventForwarder
+	scope              tally.Scope
+
+	// forwardClient consumes the event stream on behalf of resource
+	// manager once the host manager is started
+	forwardClient *eventstream.Client
+}
+
+// ensure that HostManager implements every host manager procedure
+var _ hostsvc.InternalHostServiceYARPCServer = (*HostManager)(nil)
+
+// NewHostManager creates a fake host manager offering hosts, and
+// registers its procedures and event stream on the dispatcher. Resource
+// manager is called over the outbound of the dispatcher.
+func NewHostManager(
+	d *yarpc.Dispatcher,
+	scope tally.Scope,
+	hosts []*Host,
+) *HostManager {
+	h := &HostManager{
+		hosts: make(map[string]*hostState),
+		tasks: make(map[string]*launchedTask),
+		eventStreamHandler: eventstream.NewEventStreamHandler(
+			_eventStreamBufferSize,
+			[]string{
+				common.PelotonJobManager,
+				common.PelotonResourceManager,
+			},
+			nil,
+			scope,
+		),
+		eventForwarder: &eventForwarder{
+			client: resmgrsvc.NewResourceManagerServiceYARPCClient(
+				d.ClientConfig(common.PelotonResourceManager)),
+		},
+		scope: scope,
+	}
+	for _, host := range hosts {
+		h.hosts[host.Hostname] = &hostState{
+			host:    host,
+			agentID: uuid.New(),
+		}
+	}
+
+	d.Register(hostsvc.BuildInternalHostServiceYARPCProcedures(h))
+	d.Register(pb_eventstream.BuildEventStreamServiceYARPCProcedures(
+		h.eventStreamHandler))
+	return h
+}
+
+// Start starts forwarding the status updates to resource manager
+func (h *HostManager) Start() {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.forwardClient != nil {
+		return
+	}
+	h.forwardClient = eventstream.NewLocalEventStreamClient(
+		common.PelotonResourceManager,
+		h.eventStreamHandler,
+		h.eventForwarder,
+		h.scope,
+	)
+}
+
+// Stop stops forwarding the status updates to resource manager
+func (h *HostManager) Stop() {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.forwardClient == nil {
+		return
+	}
+	h.forwardClient.Stop()
+	h.forwardClient = nil
+}
+
+// SendStatusUpdate reports a new state of a launched task, identified by
+// its mesos task ID. Terminal states free the resources of the task.
+func (h *HostManager) SendStatusUpdate(
+	mesosTaskID string,
+	state mesos.TaskState,
+) error {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.sendStatusUpdate(mesosTaskID, state)
+}
+
+// sendStatusUpdate reports a new state of a launched task.
+// Must be called with the lock held.
+func (h *HostManager) sendStatusUpdate(
+	mesosTaskID string,
+	state mesos.TaskState,
+) error {
+	t, ok := h.tasks[mesosTaskID]
+	if !ok {
+		return yarpcerrors.NotFoundErrorf("task %s not found", mesosTaskID)
+	}
+	if util.IsPelotonStateTerminal(util.MesosStateToPelotonState(t.state)) {
+		return yarpcerrors.FailedPreconditionErrorf(
+			"task %s is already terminated", mesosTaskID)
+	}
+
+	t.state = state
+	if util.IsPelotonStateTerminal(util.MesosStateToPelotonState(state)) {
+		h.hosts[t.hostname].used.subtract(t.resources)
+	}
+
+	timestamp := float64(time.Now().UnixNano()) / float64(time.Second)
+	return h.eventStreamHandler.AddEvent(&pb_eventstream.Event{
+		Type: pb_eventstream.Event_MESOS_TASK_STATUS,
+		MesosTaskStatus: &mesos.TaskStatus{
+			TaskId:    &mesos.TaskID{Value: &mesosTaskID},
+			State:     &state,
+			AgentId:   &mesos.AgentID{Value: &t.agentID},
+			Timestamp: &timestamp,
+			Uuid:      uuid.NewRandom(),
+		},
+	})
+}
+
+// GetTaskState returns the state of a launched task, identified by its
+// mesos task ID.
+func (h *HostManager) GetTaskState(mesosTaskID string) (mesos.TaskState, bool) {
+	h.Lock()
+	defer h.Unlock()
+
+	t, ok := h.tasks[mesosTaskID]
+	if !ok {
+		return mesos.TaskState_TASK_STAGING, false
+	}
+	return t.state, true
+}
+
+// ClusterCapacity returns the total and allocated resources of the hosts.
+func (h *HostManager) ClusterCapacity(
+	ctx context.Context,
+	req *hostsvc.ClusterCapacityRequest,
+) (*hostsvc.ClusterCapacityResponse, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	var total, used resources
+	for _, hs := range h.hosts {
+		total.add(resources{
+			cpu:  hs.host.CPU,
+			mem:  hs.host.Memory,
+			disk: hs.host.Disk,
+			gpu:  hs.host.GPU,
+		})
+		used.add(hs.used)
+	}
+
+	return &hostsvc.ClusterCapacityResponse{
+		Resources:         toHostsvcResources(used),
+		PhysicalResources: toHostsvcResources(total),
+	}, nil
+}
+
+// toHostsvcResources converts resources to the host manager resources
+func toHostsvcResources(r resources) []*hostsvc.Resource {
+	return []*hostsvc.Resource{
+		{Kind: common.CPU, Capacity: r.cpu},
+		{Kind: common.MEMORY, Capacity: r.mem},
+		{Kind: common.DISK, Capacity: r.disk},
+		{Kind: common.GPU, Capacity: r.gpu},
+	}
+}
+
+// AcquireHostOffers offers the unused resources of the hosts which are
+// not offered already, up to the max hosts of the filter.
+func (h *HostManager) AcquireHostOffers(
+	ctx context.Context,
+	req *hostsvc.AcquireHostOffersRequest,
+) (*hostsvc.AcquireHostOffersResponse, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	maxHosts := int(req.GetFilter().GetQuantity().GetMaxHosts())
+
+	var hostOffers []*hostsvc.HostOffer
+	for hostname, hs := range h.hosts {
+		if maxHosts > 0 && len(hostOffers) >= maxHosts {
+			break
+		}
+		if hs.offerID != "" {
+			continue
+		}
+
+		hs.offerID = uuid.New()
+		agentID := hs.agentID
+		hostOffers = append(hostOffers, &hostsvc.HostOffer{
+			Id:       &peloton.HostOfferID{Value: hs.offerID},
+			Hostname: hostname,
+			AgentId:  &mesos.AgentID{Value: &agentID},
+			Resources: []*mesos.Resource{
+				newMesosResource(common.MesosCPU, hs.host.CPU-hs.used.cpu),
+				newMesosResource(common.MesosMem, hs.host.Memory-hs.used.mem),
+				newMesosResource(common.MesosDisk, hs.host.Disk-hs.used.disk),
+				newMesosResource(common.MesosGPU, hs.host.GPU-hs.used.gpu),
+			},
+		})
+	}
+
+	return &hostsvc.AcquireHostOffersResponse{
+		HostOffers: hostOffers,
+		FilterResultCounts: map[string]uint32{
+			hostsvc.HostFilterResult_MATCH.String(): uint32(len(hostOffers)),
+		},
+	}, nil
+}
+
+// newMesosResource creates a scalar mesos resource
+func newMesosResource(name string, value float64) *mesos.Resource {
+	return util.NewMesosResourceBuilder().
+		WithName(name).
+		WithValue(value).
+		Build()
+}
+
+// ReleaseHostOffers returns the offers of hosts without launching tasks.
+func (h *HostManager) ReleaseHostOffers(
+	ctx context.Context,
+	req *hostsvc.ReleaseHostOffersRequest,
+) (*hostsvc.ReleaseHostOffersResponse, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	for _, offer := range req.GetHostOffers() {
+		hs, ok := h.hosts[offer.GetHostname()]
+		if ok && hs.offerID == offer.GetId().GetValue() {
+			hs.offerID = ""
+		}
+	}
+	return &hostsvc.ReleaseHostOffersResponse{}, nil
+}
+
+// LaunchTasks launches tasks on the offer of a host, and reports them
+// starting and running.
+func (h *HostManager) LaunchTasks(
+	ctx context.Context,
+	req *hostsvc.LaunchTasksRequest,
+) (*hostsvc.LaunchTasksResponse, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	hs, ok := h.hosts[req.GetHostname()]
+	if !ok || hs.offerID == "" || hs.offerID != req.GetId().GetValue() {
+		return &hostsvc.LaunchTasksResponse{
+			Error: &hostsvc.LaunchTasksResponse_Error{
+				InvalidOffers: &hostsvc.InvalidOffers{
+					Message: "no outstanding offer for host " +
+						req.GetHostname(),
+				},
+			},
+		}, nil
+	}
+	hs.offerID = ""
+
+	for _, t := range req.GetTasks() {
+		resource := t.GetConfig().GetResource()
+		launched := &launchedTask{
+			hostname: req.GetHostname(),
+			agentID:  hs.agentID,
+			resources: resources{
+				cpu:  resource.GetCpuLimit(),
+				mem:  resource.GetMemLimitMb(),
+				disk: resource.GetDiskLimitMb(),
+				gpu:  resource.GetGpuLimit(),
+			},
+			state: mesos.TaskState_TASK_STAGING,
+		}
+		hs.used.add(launched.resources)
+		h.tasks[t.GetTaskId().GetValue()] = launched
+
+		for _, state := range []mesos.TaskState{
+			mesos.TaskState_TASK_STARTING,
+			mesos.TaskState_TASK_RUNNING,
+		} {
+			if err := h.sendStatusUpdate(
+				t.GetTaskId().GetValue(), state); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &hostsvc.LaunchTasksResponse{}, nil
+}
+
+// KillTasks kills launched tasks, and reports them killed.
+func (h *HostManager) KillTasks(
+	ctx context.Context,
+	req *hostsvc.KillTasksRequest,
+) (*hostsvc.KillTasksResponse, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	for _, taskID := range req.GetTaskIds() {
+		err := h.sendStatusUpdate(
+			taskID.GetValue(), mesos.TaskState_TASK_KILLED)
+		if err != nil && !yarpcerrors.IsFailedPrecondition(err) {
+			log.WithError(err).
+				WithField("task_id", taskID.GetValue()).
+				Info("failed to kill task")
+		}
+	}
+	return &hostsvc.KillTasksResponse{}, nil
+}
+
+// ShutdownExecutors is a no-op, the fake host manager has no executors.
+func (h *HostManager) ShutdownExecutors(
+	ctx context.Context,
+	req *hostsvc.ShutdownExecutorsRequest,
+) (*hostsvc.ShutdownExecutorsResponse, error) {
+	return &hostsvc.ShutdownExecutorsResponse{}, nil
+}
+
+// GetDrainingHosts returns no hosts, the hosts are never drained.
+func (h *HostManager) GetDrainingHosts(
+	ctx context.Context,
+	req *hostsvc.GetDrainingHostsRequest,
+) (*hostsvc.GetDrainingHostsResponse, error) {
+	return &hostsvc.GetDrainingHostsResponse{}, nil
+}
+
+// MarkHostDrained acknowledges the host as drained.
+func (h *HostManager) MarkHostDrained(
+	ctx context.Context,
+	req *hostsvc.MarkHostDrainedRequest,
+) (*hostsvc.MarkHostDrainedResponse, error) {
+	return &hostsvc.MarkHostDrainedResponse{
+		Hostname: req.GetHostname(),
+	}, nil
+}
+
+// ReleaseHostsHeldForTasks is a no-op, hosts are never held for tasks.
+func (h *HostManager) ReleaseHostsHeldForTasks(
+	ctx context.Context,
+	req *hostsvc.ReleaseHostsHeldForTasksRequest,
+) (*hostsvc.ReleaseHostsHeldForTasksResponse, error) {
+	return &hostsvc.ReleaseHostsHeldForTasksResponse{}, nil
+}
+
+// GetHosts is not supported by the fake host manager.
+func (h *HostManager) GetHosts(
+	ctx context.Context,
+	req *hostsvc.GetHostsRequest,
+) (*hostsvc.GetHostsResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("GetHosts is not supported")
+}
+
+// ReserveHosts is not supported by the fake host manager.
+func (h *HostManager) ReserveHosts(
+	ctx context.Context,
+	req *hostsvc.ReserveHostsRequest,
+) (*hostsvc.ReserveHostsResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("ReserveHosts is not supported")
+}
+
+// GetCompletedReservations is not supported by the fake host manager.
+func (h *HostManager) GetCompletedReservations(
+	ctx context.Context,
+	req *hostsvc.GetCompletedReservationRequest,
+) (*hostsvc.GetCompletedReservationResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("GetCompletedReservations is not supported")
+}
+
+// KillAndReserveTasks is not supported by the fake host manager.
+func (h *HostManager) KillAndReserveTasks(
+	ctx context.Context,
+	req *hostsvc.KillAndReserveTasksRequest,
+) (*hostsvc.KillAndReserveTasksResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("KillAndReserveTasks is not supported")
+}
+
+// ReserveResources is not supported by the fake host manager.
+func (h *HostManager) ReserveResources(
+	ctx context.Context,
+	req *hostsvc.ReserveResourcesRequest,
+) (*hostsvc.ReserveResourcesResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("ReserveResources is not supported")
+}
+
+// UnreserveResources is not supported by the fake host manager.
+func (h *HostManager) UnreserveResources(
+	ctx context.Context,
+	req *hostsvc.UnreserveResourcesRequest,
+) (*hostsvc.UnreserveResourcesResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("UnreserveResources is not supported")
+}
+
+// CreateVolumes is not supported by the fake host manager.
+func (h *HostManager) CreateVolumes(
+	ctx context.Context,
+	req *hostsvc.CreateVolumesRequest,
+) (*hostsvc.CreateVolumesResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("CreateVolumes is not supported")
+}
+
+// DestroyVolumes is not supported by the fake host manager.
+func (h *HostManager) DestroyVolumes(
+	ctx context.Context,
+	req *hostsvc.DestroyVolumesRequest,
+) (*hostsvc.DestroyVolumesResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("DestroyVolumes is not supported")
+}
+
+// OfferOperations is not supported by the fake host manager.
+func (h *HostManager) OfferOperations(
+	ctx context.Context,
+	req *hostsvc.OfferOperationsRequest,
+) (*hostsvc.OfferOperationsResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("OfferOperations is not supported")
+}
+
+// GetMesosMasterHostPort is not supported by the fake host manager.
+func (h *HostManager) GetMesosMasterHostPort(
+	ctx context.Context,
+	req *hostsvc.MesosMasterHostPortRequest,
+) (*hostsvc.MesosMasterHostPortResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("GetMesosMasterHostPort is not supported")
+}
+
+// GetOutstandingOffers is not supported by the fake host manager.
+func (h *HostManager) GetOutstandingOffers(
+	ctx context.Context,
+	req *hostsvc.GetOutstandingOffersRequest,
+) (*hostsvc.GetOutstandingOffersResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("GetOutstandingOffers is not supported")
+}
+
+// DisableKillTasks is not supported by the fake host manager.
+func (h *HostManager) DisableKillTasks(
+	ctx context.Context,
+	req *hostsvc.DisableKillTasksRequest,
+) (*hostsvc.DisableKillTasksResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("DisableKillTasks is not supported")
+}
+
+// GetHostsByQuery is not supported by the fake host manager.
+func (h *HostManager) GetHostsByQuery(
+	ctx context.Context,
+	req *hostsvc.GetHostsByQueryRequest,
+) (*hostsvc.GetHostsByQueryResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("GetHostsByQuery is not supported")
+}
+
+// CancelWatchEvent is not supported by the fake host manager.
+func (h *HostManager) CancelWatchEvent(
+	ctx context.Context,
+	req *hostsvc.CancelWatchRequest,
+) (*hostsvc.CancelWatchResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("CancelWatchEvent is not supported")
+}
+
+// GetMesosAgentInfo is not supported by the fake host manager.
+func (h *HostManager) GetMesosAgentInfo(
+	ctx context.Context,
+	req *hostsvc.GetMesosAgentInfoRequest,
+) (*hostsvc.GetMesosAgentInfoResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("GetMesosAgentInfo is not supported")
+}
+
+// ReconcileTasks is not supported by the fake host manager.
+func (h *HostManager) ReconcileTasks(
+	ctx context.Context,
+	req *hostsvc.ReconcileTasksRequest,
+) (*hostsvc.ReconcileTasksResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("ReconcileTasks is not supported")
+}
+
+// GetPodUsage is not supported by the fake host manager.
+func (h *HostManager) GetPodUsage(
+	ctx context.Context,
+	req *hostsvc.GetPodUsageRequest,
+) (*hostsvc.GetPodUsageResponse, error) {
+	return nil, yarpcerrors.UnimplementedErrorf("GetPodUsage is not supported")
+}
+
+// WatchEventStreamEvent is not supported by the fake host manager.
+func (h *HostManager) WatchEventStreamEvent(
+	req *hostsvc.WatchEventRequest,
+	stream hostsvc.InternalHostServiceServiceWatchEventStreamEventYARPCServer,
+) error {
+	return yarpcerrors.UnimplementedErrorf("WatchEventStreamEvent is not supported")
+}
+
+// WatchHostSummaryEvent is not supported by the fake host manager.
+func (h *HostManager) WatchHostSummaryEvent(
+	req *hostsvc.WatchEventRequest,
+	stream hostsvc.InternalHostServiceServiceWatchHostSummaryEventYARPCServer,
+) error {
+	return yarpcerrors.UnimplementedErrorf("WatchHostSummaryEvent is not supported")
+}
+
+// GetPodLogs is not supported by the fake host manager.
+func (h *HostManager) GetPodLogs(
+	req *hostsvc.GetPodLogsRequest,
+	stream hostsvc.InternalHostServiceServiceGetPodLogsYARPCServer,
+) error {
+	return yarpcerrors.UnimplementedErrorf("GetPodLogs is not supported")
+}
+
+// ExecPod is not supported by the fake host manager.
+func (h *HostManager) ExecPod(
+	stream hostsvc.InternalHostServiceServiceExecPodYARPCServer,
+) error {
+	return yarpcerrors.UnimplementedErrorf("ExecPod is not supported")
+}
+
+// eventForwarder forwards the status updates of the event stream to
+// resource manager, as the host manager does.
+type eventForwarder struct {
+	client resmgrsvc.ResourceManagerServiceYARPCClient
+	// progress is the purge offset returned by resource manager
+	progress uint64
+}
+
+// OnV0Event is not used, the events are forwarded in batches
+func (f *eventForwarder) OnV0Event(event *pb_eventstream.Event) {}
+
+// OnV0Events forwards a batch of events to resource manager
+func (f *eventForwarder) OnV0Events(events []*pb_eventstream.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		_notifyResourceManagerTimeout)
+	defer cancel()
+
+	response, err := f.client.NotifyTaskUpdates(
+		ctx,
+		&resmgrsvc.NotifyTaskUpdatesRequest{Events: events})
+	if err != nil {
+		log.WithError(err).Info("failed to notify resource manager")
+		return
+	}
+	if response.GetPurgeOffset() > 0 {
+		atomic.StoreUint64(&f.progress, response.GetPurgeOffset())
+	}
+}
+
+// GetEventProgress returns the purge offset returned by resource manager
+func (f *eventForwarder) GetEventProgress() uint64 {
+	return atomic.LoadUint64(&f.progress)
+}
@@ -17,6 +17,10 @@ package config
 import (
 	"time"
 
+	"github.com/uber/peloton/pkg/hostmgr/hosthealth"
+	"github.com/uber/peloton/pkg/hostmgr/logs"
+	"github.com/uber/peloton/pkg/hostmgr/podexec"
+	"github.com/uber/peloton/pkg/hostmgr/podusage"
 	"github.com/uber/peloton/pkg/hostmgr/reconcile"
 	"github.com/uber/peloton/pkg/hostmgr/watchevent"
 )
@@ -57,6 +61,11 @@ type Config struct {
 	// Period in sec for running host pruning for host in HELD state
 	HeldHostPruningPeriodSec time.Duration `yaml:"held_host_pruning_period_sec"`
 
+	// Period for which a host is held for a pod being updated in-place on
+	// the v1alpha launch path. Once the hold expires, the pod can be placed
+	// on any host.
+	PodHoldTimeout time.Duration `yaml:"pod_hold_timeout"`
+
 	// Period for which to wait for host in PLACING state before reset.
 	HostPlacingOfferStatusTimeout time.Duration `yaml:"host_placing_offer_status_sec"`
 
@@ -88,4 +97,16 @@ type Config struct {
 
 	// EnableHostPool is the config switch to enable host pool logic in Host Manager
 	EnableHostPool bool `yaml:"enable_host_pool"`
+
+	// Host health tracking and quarantine specific configuration
+	HostHealth hosthealth.Config `yaml:"host_health"`
+
+	// Pod log streaming specific configuration
+	PodLogs logs.Config `yaml:"pod_logs"`
+
+	// Pod exec specific configuration
+	PodExec podexec.Config `yaml:"pod_exec"`
+
+	// Pod resource usage collection specific configuration
+	PodUsage podusage.Config `yaml:"pod_usage"`
 }
@@ -38,12 +38,16 @@ import (
 	"github.com/uber/peloton/pkg/hostmgr/factory/task"
 	"github.com/uber/peloton/pkg/hostmgr/host"
 	"github.com/uber/peloton/pkg/hostmgr/hostpool/manager"
+	"github.com/uber/peloton/pkg/hostmgr/logs"
 	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/metrics"
 	"github.com/uber/peloton/pkg/hostmgr/offer"
 	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
+	"github.com/uber/peloton/pkg/hostmgr/podexec"
+	"github.com/uber/peloton/pkg/hostmgr/podusage"
 	mqueue "github.com/uber/peloton/pkg/hostmgr/queue"
+	"github.com/uber/peloton/pkg/hostmgr/reconcile"
 	"github.com/uber/peloton/pkg/hostmgr/reserver"
 	"github.com/uber/peloton/pkg/hostmgr/scalar"
 	"github.com/uber/peloton/pkg/hostmgr/summary"
@@ -73,6 +77,8 @@ var (
 	errEmptyTaskList                     = errors.New("empty task list")
 	errEmptyAgentID                      = errors.New("empty agent id")
 	errEmptyHostName                     = errors.New("empty hostname")
+	errEmptyPodID                        = errors.New("empty pod id")
+	errEmptyCommand                      = errors.New("empty command")
 	errEmptyHostOfferID                  = errors.New("empty host offer")
 	errNilReservation                    = errors.New("reservation is nil")
 	errLaunchOperationIsNotLastOperation = errors.New("launch operation is not the last operation")
@@ -96,6 +102,10 @@ type ServiceHandler struct {
 	watchProcessor         watchevent.WatchProcessor
 	disableKillTasks       atomic.Bool
 	hostPoolManager        manager.HostPoolManager
+	reconciler             reconcile.TaskReconciler
+	logBackend             logs.Backend
+	execBackend            podexec.Backend
+	usageCollector         podusage.Collector
 }
 
 // NewServiceHandler creates a new ServiceHandler.
@@ -113,6 +123,10 @@ func NewServiceHandler(
 	maintenanceHostInfoMap host.MaintenanceHostInfoMap,
 	watchProcessor watchevent.WatchProcessor,
 	hostPoolManager manager.HostPoolManager,
+	reconciler reconcile.TaskReconciler,
+	logBackend logs.Backend,
+	execBackend podexec.Backend,
+	usageCollector podusage.Collector,
 ) *ServiceHandler {
 
 	handler := &ServiceHandler{
@@ -128,6 +142,10 @@ func NewServiceHandler(
 		maintenanceHostInfoMap: maintenanceHostInfoMap,
 		watchProcessor:         watchProcessor,
 		hostPoolManager:        hostPoolManager,
+		reconciler:             reconciler,
+		logBackend:             logBackend,
+		execBackend:            execBackend,
+		usageCollector:         usageCollector,
 	}
 	// Creating Reserver object for handler
 	handler.reserver = reserver.NewReserver(
@@ -1560,6 +1578,66 @@ func (h *ServiceHandler) ReleaseHostsHeldForTasks(
 	return &hostsvc.ReleaseHostsHeldForTasksResponse{}, nil
 }
 
+// ReconcileTasks implements InternalHostService.ReconcileTasks
+// Explicitly reconciles the tasks of a job, of a host or all tasks with
+// Mesos master, reports the tasks on which Peloton and Mesos disagree and
+// optionally kills the orphan tasks.
+func (h *ServiceHandler) ReconcileTasks(
+	ctx context.Context,
+	req *hostsvc.ReconcileTasksRequest,
+) (*hostsvc.ReconcileTasksResponse, error) {
+	report, err := h.reconciler.ReconcileTasks(
+		ctx,
+		req.GetJobId(),
+		req.GetHostname())
+	if err != nil {
+		log.WithError(err).
+			WithFields(log.Fields{
+				"job_id":   req.GetJobId().GetValue(),
+				"hostname": req.GetHostname(),
+			}).Error("failed to reconcile tasks")
+		return &hostsvc.ReconcileTasksResponse{
+			Error: &hostsvc.ReconcileTasksResponse_Error{
+				Message: err.Error(),
+			},
+		}, nil
+	}
+
+	if !req.GetKillOrphans() || len(report.GetOrphans()) == 0 {
+		return &hostsvc.ReconcileTasksResponse{Report: report}, nil
+	}
+
+	var taskIDs []*mesos.TaskID
+	for _, orphan := range report.GetOrphans() {
+		taskIDs = append(taskIDs, orphan.GetTaskId())
+	}
+
+	_, killFailure := h.killTasks(ctx, taskIDs)
+	if killFailure == nil {
+		report.KilledOrphans = taskIDs
+		return &hostsvc.ReconcileTasksResponse{Report: report}, nil
+	}
+
+	// A kill failure without task ids means no kill was sent at all.
+	failed := make(map[string]bool)
+	for _, taskID := range killFailure.GetTaskIds() {
+		failed[taskID.GetValue()] = true
+	}
+	if len(failed) > 0 {
+		for _, taskID := range taskIDs {
+			if !failed[taskID.GetValue()] {
+				report.KilledOrphans = append(report.KilledOrphans, taskID)
+			}
+		}
+	}
+	return &hostsvc.ReconcileTasksResponse{
+		Error: &hostsvc.ReconcileTasksResponse_Error{
+			Message: killFailure.GetMessage(),
+		},
+		Report: report,
+	}, nil
+}
+
 func (h *ServiceHandler) releaseHostsHeldForTasks(taskIDs []*peloton.TaskID) error {
 	var errs []error
 	hostHeldForTasks := make(map[string][]*peloton.TaskID)
@@ -1633,3 +1711,137 @@ func parseTaskIDsFromMesosTaskIDs(ids []*mesos.TaskID) []*peloton.TaskID {
 
 	return result
 }
+
+// GetPodLogs implements InternalHostService.GetPodLogs
+// Streams the logs of a pod from the underlying cluster manager.
+func (h *ServiceHandler) GetPodLogs(
+	req *hostsvc.GetPodLogsRequest,
+	stream hostsvc.InternalHostServiceServiceGetPodLogsYARPCServer,
+) error {
+	if len(req.GetHostname()) == 0 {
+		return yarpcerrors.InvalidArgumentErrorf("%v", errEmptyHostName)
+	}
+	if len(req.GetPodId()) == 0 {
+		return yarpcerrors.InvalidArgumentErrorf("%v", errEmptyPodID)
+	}
+
+	err := h.logBackend.StreamLogs(
+		stream.Context(),
+		&logs.Request{
+			Hostname: req.GetHostname(),
+			PodID:    req.GetPodId(),
+			Stream:   req.GetStream(),
+			Offset:   req.GetOffset(),
+			Length:   req.GetLength(),
+			Follow:   req.GetFollow(),
+		},
+		func(chunk *logs.Chunk) error {
+			return stream.Send(&hostsvc.GetPodLogsResponse{
+				Data:   chunk.Data,
+				Offset: chunk.Offset,
+			})
+		})
+	if err != nil {
+		log.WithError(err).
+			WithField("hostname", req.GetHostname()).
+			WithField("pod_id", req.GetPodId()).
+			Warn("failed to stream pod logs")
+		return err
+	}
+	return nil
+}
+
+// ExecPod runs a command inside a pod using the cluster manager the pod
+// runs on, and streams the output of the command followed by its exit code.
+func (h *ServiceHandler) ExecPod(
+	stream hostsvc.InternalHostServiceServiceExecPodYARPCServer,
+) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if len(req.GetHostname()) == 0 {
+		return yarpcerrors.InvalidArgumentErrorf("%v", errEmptyHostName)
+	}
+	if len(req.GetPodId()) == 0 {
+		return yarpcerrors.InvalidArgumentErrorf("%v", errEmptyPodID)
+	}
+	if len(req.GetCommand()) == 0 {
+		return yarpcerrors.InvalidArgumentErrorf("%v", errEmptyCommand)
+	}
+
+	stdin := podexec.NewStdinReader(
+		req.GetStdin(),
+		func() ([]byte, error) {
+			in, err := stream.Recv()
+			if err != nil {
+				return nil, err
+			}
+			return in.GetStdin(), nil
+		})
+	stdout, stderr := podexec.NewOutputWriters(
+		func(s hostsvc.LogStream, data []byte) error {
+			return stream.Send(&hostsvc.ExecPodResponse{
+				Stream: s,
+				Data:   data,
+			})
+		})
+
+	exitCode, err := h.execBackend.Exec(
+		stream.Context(),
+		&podexec.Request{
+			Hostname: req.GetHostname(),
+			PodID:    req.GetPodId(),
+			Command:  req.GetCommand(),
+			TTY:      req.GetTty(),
+		},
+		&podexec.Streams{
+			Stdin:  stdin,
+			Stdout: stdout,
+			Stderr: stderr,
+		})
+	if err != nil {
+		log.WithError(err).
+			WithField("hostname", req.GetHostname()).
+			WithField("pod_id", req.GetPodId()).
+			Warn("failed to exec in pod")
+		return err
+	}
+
+	return stream.Send(&hostsvc.ExecPodResponse{
+		Exited:   true,
+		ExitCode: exitCode,
+	})
+}
+
+// GetPodUsage returns the recent resource usage of pods collected from the
+// cluster manager the pods run on.
+func (h *ServiceHandler) GetPodUsage(
+	ctx context.Context,
+	req *hostsvc.GetPodUsageRequest,
+) (*hostsvc.GetPodUsageResponse, error) {
+	if len(req.GetPodIds()) == 0 {
+		return nil, yarpcerrors.InvalidArgumentErrorf("%v", errEmptyPodID)
+	}
+
+	usage := h.usageCollector.GetUsage(req.GetPodIds())
+
+	resp := &hostsvc.GetPodUsageResponse{}
+	for _, podID := range req.GetPodIds() {
+		samples, ok := usage[podID]
+		if !ok {
+			continue
+		}
+		podUsage := &hostsvc.PodUsage{PodId: podID}
+		for _, s := range samples {
+			podUsage.Samples = append(podUsage.Samples, &hostsvc.PodUsageSample{
+				Timestamp: s.Timestamp.Unix(),
+				Cpus:      s.CPUs,
+				MemBytes:  s.MemBytes,
+				DiskBytes: s.DiskBytes,
+			})
+		}
+		resp.Usages = append(resp.Usages, podUsage)
+	}
+	return resp, nil
+}
@@ -17,6 +17,8 @@ package hostmgr
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math/rand"
 	"sort"
 	"strings"
@@ -44,11 +46,18 @@ import (
 	hm "github.com/uber/peloton/pkg/hostmgr/host/mocks"
 	hostpool_manager_mocks "github.com/uber/peloton/pkg/hostmgr/hostpool/manager/mocks"
 	hostmgr_hostpool_mocks "github.com/uber/peloton/pkg/hostmgr/hostpool/mocks"
+	"github.com/uber/peloton/pkg/hostmgr/logs"
+	logs_mocks "github.com/uber/peloton/pkg/hostmgr/logs/mocks"
 	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
 	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/metrics"
 	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
+	"github.com/uber/peloton/pkg/hostmgr/podexec"
+	podexec_mocks "github.com/uber/peloton/pkg/hostmgr/podexec/mocks"
+	"github.com/uber/peloton/pkg/hostmgr/podusage"
+	podusage_mocks "github.com/uber/peloton/pkg/hostmgr/podusage/mocks"
 	qm "github.com/uber/peloton/pkg/hostmgr/queue/mocks"
+	reconciler_mocks "github.com/uber/peloton/pkg/hostmgr/reconcile/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/reserver"
 	reserver_mocks "github.com/uber/peloton/pkg/hostmgr/reserver/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/summary"
@@ -213,6 +222,12 @@ type HostMgrHandlerTestSuite struct {
 	mockedCQosClient       *cqosmocks.MockQoSAdvisorServiceYARPCClient
 	metric                 *metrics.Metrics
 	hostPoolManager        *hostpool_manager_mocks.MockHostPoolManager
+	reconciler             *reconciler_mocks.MockTaskReconciler
+	logBackend             *logs_mocks.MockBackend
+	podLogsServer          *hostsvcmocks.MockInternalHostServiceServiceGetPodLogsYARPCServer
+	execBackend            *podexec_mocks.MockBackend
+	execPodServer          *hostsvcmocks.MockInternalHostServiceServiceExecPodYARPCServer
+	usageCollector         *podusage_mocks.MockCollector
 }
 
 func (suite *HostMgrHandlerTestSuite) SetupSuite() {
@@ -251,6 +266,12 @@ func (suite *HostMgrHandlerTestSuite) SetupTest() {
 	suite.watchHostSummaryServer = hostsvcmocks.NewMockInternalHostServiceServiceWatchHostSummaryEventYARPCServer(suite.ctrl)
 	suite.topicsSupported = []watchevent.Topic{watchevent.EventStream, watchevent.HostSummary}
 	suite.hostPoolManager = hostpool_manager_mocks.NewMockHostPoolManager(suite.ctrl)
+	suite.reconciler = reconciler_mocks.NewMockTaskReconciler(suite.ctrl)
+	suite.logBackend = logs_mocks.NewMockBackend(suite.ctrl)
+	suite.podLogsServer = hostsvcmocks.NewMockInternalHostServiceServiceGetPodLogsYARPCServer(suite.ctrl)
+	suite.execBackend = podexec_mocks.NewMockBackend(suite.ctrl)
+	suite.execPodServer = hostsvcmocks.NewMockInternalHostServiceServiceExecPodYARPCServer(suite.ctrl)
+	suite.usageCollector = podusage_mocks.NewMockCollector(suite.ctrl)
 
 	mockValidValue := new(string)
 	*mockValidValue = _frameworkID
@@ -285,6 +306,10 @@ func (suite *HostMgrHandlerTestSuite) SetupTest() {
 		maintenanceHostInfoMap: suite.maintenanceHostInfoMap,
 		watchProcessor:         suite.watchProcessor,
 		hostPoolManager:        suite.hostPoolManager,
+		reconciler:             suite.reconciler,
+		logBackend:             suite.logBackend,
+		execBackend:            suite.execBackend,
+		usageCollector:         suite.usageCollector,
 	}
 	suite.handler.reserver = reserver.NewReserver(
 		metrics.NewMetrics(suite.testScope),
@@ -2035,6 +2060,112 @@ func (suite *HostMgrHandlerTestSuite) TestEnqueueReservationError() {
 		errReservationNotFound.Error())
 }
 
+// TestReconcileTasks tests reconciling tasks without killing orphans.
+func (suite *HostMgrHandlerTestSuite) TestReconcileTasks() {
+	defer suite.ctrl.Finish()
+
+	jobID := &peloton.JobID{Value: _testJobID}
+	orphan := fmt.Sprintf(_taskIDFmt, 0)
+	report := &hostsvc.ReconcileTasksReport{
+		Orphans: []*hostsvc.ReconciledTask{
+			{TaskId: &mesos.TaskID{Value: &orphan}},
+		},
+	}
+	suite.reconciler.EXPECT().
+		ReconcileTasks(gomock.Any(), jobID, "").
+		Return(report, nil)
+
+	resp, err := suite.handler.ReconcileTasks(
+		rootCtx,
+		&hostsvc.ReconcileTasksRequest{JobId: jobID})
+	suite.NoError(err)
+	suite.Nil(resp.GetError())
+	suite.Equal(report, resp.GetReport())
+	suite.Empty(resp.GetReport().GetKilledOrphans())
+
+	suite.reconciler.EXPECT().
+		ReconcileTasks(gomock.Any(), jobID, "").
+		Return(nil, errors.New("fake reconcile error"))
+	resp, err = suite.handler.ReconcileTasks(
+		rootCtx,
+		&hostsvc.ReconcileTasksRequest{JobId: jobID})
+	suite.NoError(err)
+	suite.NotNil(resp.GetError())
+	suite.Nil(resp.GetReport())
+}
+
+// TestReconcileTasksKillOrphans tests killing the orphans found
+// reconciling tasks.
+func (suite *HostMgrHandlerTestSuite) TestReconcileTasksKillOrphans() {
+	defer suite.ctrl.Finish()
+
+	t1 := fmt.Sprintf(_taskIDFmt, 0)
+	t2 := fmt.Sprintf(_taskIDFmt, 1)
+	newReport := func() *hostsvc.ReconcileTasksReport {
+		return &hostsvc.ReconcileTasksReport{
+			Orphans: []*hostsvc.ReconciledTask{
+				{TaskId: &mesos.TaskID{Value: &t1}},
+				{TaskId: &mesos.TaskID{Value: &t2}},
+			},
+		}
+	}
+	req := &hostsvc.ReconcileTasksRequest{
+		Hostname:    "hostname-0",
+		KillOrphans: true,
+	}
+
+	suite.provider.EXPECT().GetFrameworkID(gomock.Any()).
+		Return(suite.frameworkID).AnyTimes()
+	suite.provider.EXPECT().GetMesosStreamID(gomock.Any()).
+		Return(_streamID).AnyTimes()
+
+	// all orphans killed
+	suite.reconciler.EXPECT().
+		ReconcileTasks(gomock.Any(), nil, "hostname-0").
+		Return(newReport(), nil)
+	suite.schedulerClient.EXPECT().
+		Call(_streamID, gomock.Any()).
+		Return(nil).
+		Times(2)
+
+	resp, err := suite.handler.ReconcileTasks(rootCtx, req)
+	suite.NoError(err)
+	suite.Nil(resp.GetError())
+	suite.Len(resp.GetReport().GetKilledOrphans(), 2)
+
+	// one orphan fails to be killed
+	suite.reconciler.EXPECT().
+		ReconcileTasks(gomock.Any(), nil, "hostname-0").
+		Return(newReport(), nil)
+	suite.schedulerClient.EXPECT().
+		Call(_streamID, gomock.Any()).
+		DoAndReturn(func(_ string, msg proto.Message) error {
+			if msg.(*sched.Call).GetKill().GetTaskId().GetValue() == t1 {
+				return errors.New("fake kill error")
+			}
+			return nil
+		}).
+		Times(2)
+
+	resp, err = suite.handler.ReconcileTasks(rootCtx, req)
+	suite.NoError(err)
+	suite.NotNil(resp.GetError())
+	suite.Len(resp.GetReport().GetKilledOrphans(), 1)
+	suite.Equal(t2, resp.GetReport().GetKilledOrphans()[0].GetValue())
+
+	// kill tasks disabled
+	suite.reconciler.EXPECT().
+		ReconcileTasks(gomock.Any(), nil, "hostname-0").
+		Return(newReport(), nil)
+	suite.handler.disableKillTasks.Store(true)
+
+	resp, err = suite.handler.ReconcileTasks(rootCtx, req)
+	suite.NoError(err)
+	suite.NotNil(resp.GetError())
+	suite.Empty(resp.GetReport().GetKilledOrphans())
+	suite.Len(resp.GetReport().GetOrphans(), 2)
+}
+
 func TestHostManagerTestSuite(t *testing.T) {
 	suite.Run(t, new(HostMgrHandlerTestSuite))
 }
@@ -2823,3 +2954,209 @@ func (suite *HostMgrHandlerTestSuite) TestCancel_NotFoundTask() {
 	suite.Error(err)
 	suite.True(yarpcerrors.IsNotFound(err))
 }
+
+// TestGetPodLogs tests streaming the logs of a pod
+func (suite *HostMgrHandlerTestSuite) TestGetPodLogs() {
+	req := &hostsvc.GetPodLogsRequest{
+		Hostname: "hostname",
+		PodId:    "pod-id",
+		Stream:   hostsvc.LogStream_LOG_STREAM_STDERR,
+		Offset:   -10,
+		Length:   100,
+		Follow:   true,
+	}
+
+	suite.podLogsServer.EXPECT().
+		Context().
+		Return(suite.ctx)
+	suite.logBackend.EXPECT().
+		StreamLogs(suite.ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			logsReq *logs.Request,
+			send logs.Sender,
+		) error {
+			suite.Equal(&logs.Request{
+				Hostname: "hostname",
+				PodID:    "pod-id",
+				Stream:   hostsvc.LogStream_LOG_STREAM_STDERR,
+				Offset:   -10,
+				Length:   100,
+				Follow:   true,
+			}, logsReq)
+			return send(&logs.Chunk{Data: []byte("logs"), Offset: 90})
+		})
+	suite.podLogsServer.EXPECT().
+		Send(&hostsvc.GetPodLogsResponse{
+			Data:   []byte("logs"),
+			Offset: 90,
+		}).
+		Return(nil)
+
+	suite.NoError(suite.handler.GetPodLogs(req, suite.podLogsServer))
+}
+
+// TestGetPodLogsFailure tests failures to stream the logs of a pod
+func (suite *HostMgrHandlerTestSuite) TestGetPodLogsFailure() {
+	err := suite.handler.GetPodLogs(
+		&hostsvc.GetPodLogsRequest{PodId: "pod-id"},
+		suite.podLogsServer)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	err = suite.handler.GetPodLogs(
+		&hostsvc.GetPodLogsRequest{Hostname: "hostname"},
+		suite.podLogsServer)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	suite.podLogsServer.EXPECT().
+		Context().
+		Return(suite.ctx)
+	suite.logBackend.EXPECT().
+		StreamLogs(suite.ctx, gomock.Any(), gomock.Any()).
+		Return(yarpcerrors.NotFoundErrorf("pod not found"))
+
+	err = suite.handler.GetPodLogs(
+		&hostsvc.GetPodLogsRequest{Hostname: "hostname", PodId: "pod-id"},
+		suite.podLogsServer)
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestExecPod tests running a command inside a pod
+func (suite *HostMgrHandlerTestSuite) TestExecPod() {
+	gomock.InOrder(
+		suite.execPodServer.EXPECT().
+			Recv().
+			Return(&hostsvc.ExecPodRequest{
+				Hostname: "hostname",
+				PodId:    "pod-id",
+				Command:  []string{"cat"},
+				Stdin:    []byte("hello "),
+			}, nil),
+		suite.execPodServer.EXPECT().
+			Context().
+			Return(suite.ctx),
+	)
+	suite.execPodServer.EXPECT().
+		Recv().
+		Return(&hostsvc.ExecPodRequest{Stdin: []byte("world")}, nil)
+	suite.execPodServer.EXPECT().
+		Recv().
+		Return(&hostsvc.ExecPodRequest{}, nil)
+
+	suite.execBackend.EXPECT().
+		Exec(suite.ctx, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			req *podexec.Request,
+			streams *podexec.Streams,
+		) (int32, error) {
+			suite.Equal(&podexec.Request{
+				Hostname: "hostname",
+				PodID:    "pod-id",
+				Command:  []string{"cat"},
+			}, req)
+
+			stdin, err := ioutil.ReadAll(streams.Stdin)
+			suite.NoError(err)
+			streams.Stdout.Write(stdin)
+			streams.Stderr.Write([]byte("done"))
+			return 1, nil
+		})
+
+	gomock.InOrder(
+		suite.execPodServer.EXPECT().
+			Send(&hostsvc.ExecPodResponse{
+				Stream: hostsvc.LogStream_LOG_STREAM_STDOUT,
+				Data:   []byte("hello world"),
+			}).
+			Return(nil),
+		suite.execPodServer.EXPECT().
+			Send(&hostsvc.ExecPodResponse{
+				Stream: hostsvc.LogStream_LOG_STREAM_STDERR,
+				Data:   []byte("done"),
+			}).
+			Return(nil),
+		suite.execPodServer.EXPECT().
+			Send(&hostsvc.ExecPodResponse{
+				Exited:   true,
+				ExitCode: 1,
+			}).
+			Return(nil),
+	)
+
+	suite.NoError(suite.handler.ExecPod(suite.execPodServer))
+}
+
+// TestExecPodFailure tests failures to run a command inside a pod
+func (suite *HostMgrHandlerTestSuite) TestExecPodFailure() {
+	invalidRequests := []*hostsvc.ExecPodRequest{
+		{PodId: "pod-id", Command: []string{"ls"}},
+		{Hostname: "hostname", Command: []string{"ls"}},
+		{Hostname: "hostname", PodId: "pod-id"},
+	}
+	for _, req := range invalidRequests {
+		suite.execPodServer.EXPECT().Recv().Return(req, nil)
+		err := suite.handler.ExecPod(suite.execPodServer)
+		suite.True(yarpcerrors.IsInvalidArgument(err))
+	}
+
+	suite.execPodServer.EXPECT().
+		Recv().
+		Return(nil, io.EOF)
+	suite.Equal(io.EOF, suite.handler.ExecPod(suite.execPodServer))
+
+	suite.execPodServer.EXPECT().
+		Recv().
+		Return(&hostsvc.ExecPodRequest{
+			Hostname: "hostname",
+			PodId:    "pod-id",
+			Command:  []string{"ls"},
+		}, nil)
+	suite.execPodServer.EXPECT().
+		Context().
+		Return(suite.ctx)
+	suite.execBackend.EXPECT().
+		Exec(suite.ctx, gomock.Any(), gomock.Any()).
+		Return(int32(0), yarpcerrors.NotFoundErrorf("pod not found"))
+
+	err := suite.handler.ExecPod(suite.execPodServer)
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestGetPodUsage tests getting the resource usage of pods
+func (suite *HostMgrHandlerTestSuite) TestGetPodUsage() {
+	now := time.Unix(1000, 0)
+	suite.usageCollector.EXPECT().
+		GetUsage([]string{"pod-1", "pod-2"}).
+		Return(map[string][]*podusage.Sample{
+			"pod-1": {
+				{PodID: "pod-1", Timestamp: now, CPUs: 0.5, MemBytes: 1024},
+				{PodID: "pod-1", Timestamp: now.Add(time.Minute), CPUs: 1},
+			},
+		})
+
+	resp, err := suite.handler.GetPodUsage(
+		suite.ctx,
+		&hostsvc.GetPodUsageRequest{PodIds: []string{"pod-1", "pod-2"}},
+	)
+	suite.NoError(err)
+	suite.Equal([]*hostsvc.PodUsage{
+		{
+			PodId: "pod-1",
+			Samples: []*hostsvc.PodUsageSample{
+				{Timestamp: 1000, Cpus: 0.5, MemBytes: 1024},
+				{Timestamp: 1060, Cpus: 1},
+			},
+		},
+	}, resp.GetUsages())
+}
+
+// TestGetPodUsageInvalidRequest tests getting the resource usage
+// without any pod
+func (suite *HostMgrHandlerTestSuite) TestGetPodUsageInvalidRequest() {
+	_, err := suite.handler.GetPodUsage(
+		suite.ctx,
+		&hostsvc.GetPodUsageRequest{},
+	)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"fmt"
+
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	common_util "github.com/uber/peloton/pkg/common/util"
+)
+
+// _mesosAgentDefaultPort is the port of the Mesos agent API used if the
+// PID of the agent does not have one.
+const _mesosAgentDefaultPort = "5051"
+
+// GetRegisteredAgent returns the registered Mesos agent with the given
+// hostname, or nil if there is no such agent.
+func GetRegisteredAgent(hostname string) *mesos_master.Response_GetAgents_Agent {
+	agentMap := GetAgentMap()
+	if agentMap == nil {
+		return nil
+	}
+	return agentMap.RegisteredAgents[hostname]
+}
+
+// GetRegisteredAgents returns all the registered Mesos agents.
+func GetRegisteredAgents() []*mesos_master.Response_GetAgents_Agent {
+	agentMap := GetAgentMap()
+	if agentMap == nil {
+		return nil
+	}
+	var agents []*mesos_master.Response_GetAgents_Agent
+	for _, agent := range agentMap.RegisteredAgents {
+		agents = append(agents, agent)
+	}
+	return agents
+}
+
+// GetAgentAddress returns the host:port address of the API of a Mesos
+// agent. The IP address and port of the agent PID are used, if possible,
+// because the hostname may not be resolvable on the network.
+func GetAgentAddress(agent *mesos_master.Response_GetAgents_Agent) string {
+	agentIP := agent.GetAgentInfo().GetHostname()
+	agentPort := _mesosAgentDefaultPort
+	ip, port, err := common_util.ExtractIPAndPortFromMesosAgentPID(agent.GetPid())
+	if err == nil {
+		agentIP = ip
+		if port != "" {
+			agentPort = port
+		}
+	}
+	return fmt.Sprintf("%s:%s", agentIP, agentPort)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRegisteredAgent(t *testing.T) {
+	hostname := "hostname1"
+	agent := &mesos_master.Response_GetAgents_Agent{
+		AgentInfo: &mesos.AgentInfo{Hostname: &hostname},
+	}
+	agentInfoMap.Store(&AgentMap{
+		RegisteredAgents: map[string]*mesos_master.Response_GetAgents_Agent{
+			hostname: agent,
+		},
+	})
+	defer agentInfoMap.Store(&AgentMap{})
+
+	assert.Equal(t, agent, GetRegisteredAgent(hostname))
+	assert.Nil(t, GetRegisteredAgent("hostname2"))
+	assert.Equal(t,
+		[]*mesos_master.Response_GetAgents_Agent{agent},
+		GetRegisteredAgents())
+}
+
+func TestGetAgentAddress(t *testing.T) {
+	hostname := "hostname1"
+	pid := "slave(1)@1.2.3.4:31000"
+	invalidPID := "invalid"
+
+	tt := []struct {
+		pid      *string
+		expected string
+	}{
+		{&pid, "1.2.3.4:31000"},
+		{&invalidPID, "hostname1:5051"},
+		{nil, "hostname1:5051"},
+	}
+	for _, test := range tt {
+		assert.Equal(t, test.expected, GetAgentAddress(
+			&mesos_master.Response_GetAgents_Agent{
+				AgentInfo: &mesos.AgentInfo{Hostname: &hostname},
+				Pid:       test.pid,
+			}))
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosthealth
+
+import (
+	"time"
+)
+
+const (
+	_defaultWindow                 = 30 * time.Minute
+	_defaultMinSamples             = 10
+	_defaultFailureRateThreshold   = 0.8
+	_defaultLaunchFailureThreshold = 5
+	_defaultQuarantineDuration     = 5 * time.Minute
+	_defaultMaxQuarantineDuration  = 2 * time.Hour
+	_defaultMaxQuarantinedHosts    = 10
+	_defaultReleaseCheckPeriod     = 30 * time.Second
+)
+
+// Config for host health tracking
+type Config struct {
+	// Enable quarantine of unhealthy hosts. When disabled, host health
+	// is not tracked and no host is excluded from placement.
+	Enabled bool `yaml:"enabled"`
+
+	// Sliding window over which task outcomes on a host are scored.
+	Window time.Duration `yaml:"window"`
+
+	// Minimum number of terminal tasks on a host within the window before
+	// the failure rate of the host is considered.
+	MinSamples int `yaml:"min_samples"`
+
+	// Fraction of FAILED and LOST tasks over all terminal tasks on a host
+	// at which the host gets quarantined.
+	FailureRateThreshold float64 `yaml:"failure_rate_threshold"`
+
+	// Number of tasks failing before ever reaching RUNNING on a host at
+	// which the host gets quarantined.
+	LaunchFailureThreshold int `yaml:"launch_failure_threshold"`
+
+	// Duration of the first quarantine of a host. Each consecutive
+	// quarantine of the same host doubles the duration.
+	QuarantineDuration time.Duration `yaml:"quarantine_duration"`
+
+	// Upper bound of the quarantine duration. A host which stays healthy
+	// for this long after a release starts over from QuarantineDuration.
+	MaxQuarantineDuration time.Duration `yaml:"max_quarantine_duration"`
+
+	// Maximum number of hosts quarantined at the same time. Protects the
+	// cluster from quarantining every host because of a single bad job.
+	MaxQuarantinedHosts int `yaml:"max_quarantined_hosts"`
+
+	// Period at which expired quarantines are released.
+	ReleaseCheckPeriod time.Duration `yaml:"release_check_period"`
+}
+
+func (c *Config) normalize() {
+	if c.Window <= 0 {
+		c.Window = _defaultWindow
+	}
+	if c.MinSamples <= 0 {
+		c.MinSamples = _defaultMinSamples
+	}
+	if c.FailureRateThreshold <= 0 || c.FailureRateThreshold > 1 {
+		c.FailureRateThreshold = _defaultFailureRateThreshold
+	}
+	if c.LaunchFailureThreshold <= 0 {
+		c.LaunchFailureThreshold = _defaultLaunchFailureThreshold
+	}
+	if c.QuarantineDuration <= 0 {
+		c.QuarantineDuration = _defaultQuarantineDuration
+	}
+	if c.MaxQuarantineDuration < c.QuarantineDuration {
+		c.MaxQuarantineDuration = _defaultMaxQuarantineDuration
+		if c.MaxQuarantineDuration < c.QuarantineDuration {
+			c.MaxQuarantineDuration = c.QuarantineDuration
+		}
+	}
+	if c.MaxQuarantinedHosts <= 0 {
+		c.MaxQuarantinedHosts = _defaultMaxQuarantinedHosts
+	}
+	if c.ReleaseCheckPeriod <= 0 {
+		c.ReleaseCheckPeriod = _defaultReleaseCheckPeriod
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosthealth
+
+import (
+	"github.com/uber-go/tally"
+)
+
+// Metrics is placeholder for all metrics in hostmgr/hosthealth package.
+type Metrics struct {
+	scope tally.Scope
+
+	TaskFailures    tally.Counter
+	TaskLost        tally.Counter
+	LaunchFailures  tally.Counter
+	Quarantines     tally.Counter
+	QuarantineSkips tally.Counter
+	Releases        tally.Counter
+
+	QuarantinedHosts tally.Gauge
+	TrackedHosts     tally.Gauge
+}
+
+// NewMetrics returns a new Metrics struct, with all metrics
+// initialized and rooted at the given tally.Scope
+func NewMetrics(scope tally.Scope) *Metrics {
+	return &Metrics{
+		scope: scope,
+
+		TaskFailures:    scope.Counter("task_failures"),
+		TaskLost:        scope.Counter("task_lost"),
+		LaunchFailures:  scope.Counter("launch_failures"),
+		Quarantines:     scope.Counter("quarantines"),
+		QuarantineSkips: scope.Counter("quarantine_skips"),
+		Releases:        scope.Counter("releases"),
+
+		QuarantinedHosts: scope.Gauge("quarantined_hosts"),
+		TrackedHosts:     scope.Gauge("tracked_hosts"),
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosthealth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/host"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/pkg/common/util"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+// Tracker scores the health of hosts from the status updates of the tasks
+// running on them, and quarantines hosts on which tasks keep failing.
+// A quarantined host is excluded from placement until its quarantine is
+// released. Consecutive quarantines of the same host back off exponentially.
+type Tracker interface {
+	// RecordTaskState records a state transition of a task on a host.
+	RecordTaskState(hostname string, taskID string, state task.TaskState)
+
+	// IsQuarantined returns true if the host is currently quarantined.
+	IsQuarantined(hostname string) bool
+
+	// GetHostHealth returns the health of the host, or nil if nothing has
+	// been recorded for the host.
+	GetHostHealth(hostname string) *host.HostHealth
+
+	// ReleaseExpiredQuarantines releases the hosts whose quarantine has
+	// expired and returns the hostnames which got released. It also
+	// forgets the hosts which have nothing left to track.
+	ReleaseExpiredQuarantines(now time.Time) []string
+
+	// RemoveHost forgets the tasks and outcomes of a host which was
+	// removed from the cluster. Its quarantine state is kept until it
+	// expires, so that the host is not placed on if it comes back.
+	RemoveHost(hostname string)
+}
+
+// outcome is the terminal state of a task on a host.
+type outcome struct {
+	time          time.Time
+	state         task.TaskState
+	launchFailure bool
+}
+
+// hostHealth is the health state of a single host.
+type hostHealth struct {
+	// Terminal task outcomes within the scoring window, oldest first.
+	outcomes []outcome
+
+	// Non-terminal tasks on the host, value is true once the task
+	// has been seen RUNNING. Tasks which fail before running count as
+	// launch failures.
+	tasks map[string]bool
+
+	// Time until which the host is quarantined, zero if not quarantined.
+	quarantinedUntil time.Time
+
+	// Time when the host was last released from quarantine.
+	releasedAt time.Time
+
+	// Number of consecutive quarantines of the host.
+	quarantineCount uint32
+}
+
+// tracker implements Tracker.
+type tracker struct {
+	sync.RWMutex
+
+	config  Config
+	metrics *Metrics
+
+	// Map of hostname to its health state.
+	hosts map[string]*hostHealth
+
+	// Number of hosts currently quarantined.
+	numQuarantined int
+
+	nowFunc func() time.Time
+}
+
+// NewTracker returns a new host health tracker.
+func NewTracker(config Config, parent tally.Scope) Tracker {
+	config.normalize()
+	return &tracker{
+		config:  config,
+		metrics: NewMetrics(parent.SubScope("host_health")),
+		hosts:   make(map[string]*hostHealth),
+		nowFunc: time.Now,
+	}
+}
+
+// RecordTaskState records a state transition of a task on a host. Terminal
+// states are scored, and the host gets quarantined when its score crosses
+// the configured thresholds.
+func (t *tracker) RecordTaskState(
+	hostname string,
+	taskID string,
+	state task.TaskState) {
+	if len(hostname) == 0 || len(taskID) == 0 {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	h, ok := t.hosts[hostname]
+
+	switch state {
+	case task.TaskState_LAUNCHED, task.TaskState_STARTING,
+		task.TaskState_RUNNING:
+		if !ok {
+			h = &hostHealth{tasks: make(map[string]bool)}
+			t.hosts[hostname] = h
+			t.metrics.TrackedHosts.Update(float64(len(t.hosts)))
+		}
+		if state == task.TaskState_RUNNING {
+			h.tasks[taskID] = true
+		} else if _, ok := h.tasks[taskID]; !ok {
+			h.tasks[taskID] = false
+		}
+		return
+	}
+
+	// Only terminal updates of tasks known to be on the host are scored,
+	// so that duplicate status updates are not scored twice.
+	if !ok || !util.IsPelotonStateTerminal(state) {
+		return
+	}
+	running, ok := h.tasks[taskID]
+	if !ok {
+		return
+	}
+	delete(h.tasks, taskID)
+
+	switch state {
+	case task.TaskState_SUCCEEDED, task.TaskState_KILLED,
+		task.TaskState_FAILED, task.TaskState_LOST:
+	default:
+		// a deleted task says nothing about the health of the host
+		return
+	}
+
+	now := t.nowFunc()
+
+	o := outcome{time: now, state: state}
+	switch state {
+	case task.TaskState_FAILED:
+		t.metrics.TaskFailures.Inc(1)
+	case task.TaskState_LOST:
+		t.metrics.TaskLost.Inc(1)
+	}
+	if isFailure(state) && !running {
+		o.launchFailure = true
+		t.metrics.LaunchFailures.Inc(1)
+	}
+	h.outcomes = append(h.outcomes, o)
+	h.prune(now.Add(-t.config.Window))
+
+	if !h.isQuarantined(now) && t.isUnhealthy(h) {
+		t.quarantine(hostname, h, now)
+	}
+}
+
+// IsQuarantined returns true if the host is currently quarantined.
+func (t *tracker) IsQuarantined(hostname string) bool {
+	t.RLock()
+	defer t.RUnlock()
+
+	h, ok := t.hosts[hostname]
+	if !ok {
+		return false
+	}
+	return h.isQuarantined(t.nowFunc())
+}
+
+// GetHostHealth returns the health of the host.
+func (t *tracker) GetHostHealth(hostname string) *host.HostHealth {
+	t.RLock()
+	defer t.RUnlock()
+
+	h, ok := t.hosts[hostname]
+	if !ok {
+		return nil
+	}
+
+	now := t.nowFunc()
+	windowStart := now.Add(-t.config.Window)
+	health := &host.HostHealth{
+		QuarantineCount: h.quarantineCount,
+	}
+	for _, o := range h.outcomes {
+		if o.time.Before(windowStart) {
+			continue
+		}
+		health.TerminalTasks++
+		switch o.state {
+		case task.TaskState_FAILED:
+			health.FailedTasks++
+		case task.TaskState_LOST:
+			health.LostTasks++
+		}
+		if o.launchFailure {
+			health.LaunchFailures++
+		}
+	}
+	if h.isQuarantined(now) {
+		health.Quarantined = true
+		health.QuarantineReleaseTime = h.quarantinedUntil.UTC().
+			Format(time.RFC3339)
+	}
+	return health
+}
+
+// ReleaseExpiredQuarantines releases the hosts whose quarantine has expired
+// and returns the hostnames which got released.
+func (t *tracker) ReleaseExpiredQuarantines(now time.Time) []string {
+	t.Lock()
+	defer t.Unlock()
+
+	var released []string
+	for hostname, h := range t.hosts {
+		if h.quarantinedUntil.IsZero() || h.isQuarantined(now) {
+			continue
+		}
+		h.quarantinedUntil = time.Time{}
+		h.releasedAt = now
+		t.numQuarantined--
+		released = append(released, hostname)
+
+		log.WithFields(log.Fields{
+			"hostname":         hostname,
+			"quarantine_count": h.quarantineCount,
+		}).Info("host released from quarantine")
+	}
+
+	// Forget the hosts which have nothing left to track, such as the
+	// hosts removed from the cluster.
+	windowStart := now.Add(-t.config.Window)
+	for hostname, h := range t.hosts {
+		h.prune(windowStart)
+		if h.isIdle(now, t.config.MaxQuarantineDuration) {
+			delete(t.hosts, hostname)
+		}
+	}
+
+	t.metrics.Releases.Inc(int64(len(released)))
+	t.metrics.QuarantinedHosts.Update(float64(t.numQuarantined))
+	t.metrics.TrackedHosts.Update(float64(len(t.hosts)))
+	return released
+}
+
+// RemoveHost forgets the tasks and outcomes of a host which was removed
+// from the cluster.
+func (t *tracker) RemoveHost(hostname string) {
+	t.Lock()
+	defer t.Unlock()
+
+	h, ok := t.hosts[hostname]
+	if !ok {
+		return
+	}
+	h.tasks = make(map[string]bool)
+	h.outcomes = nil
+	if h.isIdle(t.nowFunc(), t.config.MaxQuarantineDuration) {
+		delete(t.hosts, hostname)
+		t.metrics.TrackedHosts.Update(float64(len(t.hosts)))
+	}
+}
+
+// isUnhealthy returns true if the score of the host crosses either the
+// launch failure or the failure rate threshold.
+func (t *tracker) isUnhealthy(h *hostHealth) bool {
+	var failures, launchFailures int
+	for _, o := range h.outcomes {
+		if isFailure(o.state) {
+			failures++
+		}
+		if o.launchFailure {
+			launchFailures++
+		}
+	}
+
+	if launchFailures >= t.config.LaunchFailureThreshold {
+		return true
+	}
+	if len(h.outcomes) < t.config.MinSamples {
+		return false
+	}
+	return float64(failures)/float64(len(h.outcomes)) >=
+		t.config.FailureRateThreshold
+}
+
+// quarantine quarantines the host, doubling the quarantine duration for
+// each consecutive quarantine of the host.
+func (t *tracker) quarantine(hostname string, h *hostHealth, now time.Time) {
+	if t.numQuarantined >= t.config.MaxQuarantinedHosts {
+		t.metrics.QuarantineSkips.Inc(1)
+		log.WithFields(log.Fields{
+			"hostname":              hostname,
+			"max_quarantined_hosts": t.config.MaxQuarantinedHosts,
+		}).Warn("host is unhealthy but too many hosts are quarantined")
+		return
+	}
+
+	// A host which stayed healthy long enough since its last release
+	// starts over from the initial quarantine duration.
+	if !h.releasedAt.IsZero() &&
+		now.Sub(h.releasedAt) > t.config.MaxQuarantineDuration {
+		h.quarantineCount = 0
+	}
+
+	duration := t.config.QuarantineDuration
+	for i := uint32(0); i < h.quarantineCount; i++ {
+		duration *= 2
+		if duration >= t.config.MaxQuarantineDuration {
+			duration = t.config.MaxQuarantineDuration
+			break
+		}
+	}
+
+	h.quarantineCount++
+	h.quarantinedUntil = now.Add(duration)
+	// Start scoring the host afresh once it is released.
+	h.outcomes = nil
+	t.numQuarantined++
+
+	t.metrics.Quarantines.Inc(1)
+	t.metrics.QuarantinedHosts.Update(float64(t.numQuarantined))
+	log.WithFields(log.Fields{
+		"hostname":          hostname,
+		"quarantine_count":  h.quarantineCount,
+		"quarantined_until": h.quarantinedUntil,
+	}).Warn("host quarantined due to task failures")
+}
+
+// prune drops the outcomes recorded before the start of the window.
+func (h *hostHealth) prune(windowStart time.Time) {
+	i := 0
+	for i < len(h.outcomes) && h.outcomes[i].time.Before(windowStart) {
+		i++
+	}
+	h.outcomes = h.outcomes[i:]
+}
+
+// isIdle returns true if nothing is left to track for the host: it has no
+// tasks or outcomes, is not quarantined, and its quarantine count would be
+// reset by its next quarantine.
+func (h *hostHealth) isIdle(
+	now time.Time,
+	maxQuarantineDuration time.Duration) bool {
+	if len(h.tasks) > 0 || len(h.outcomes) > 0 ||
+		!h.quarantinedUntil.IsZero() {
+		return false
+	}
+	return h.quarantineCount == 0 ||
+		now.Sub(h.releasedAt) > maxQuarantineDuration
+}
+
+// isQuarantined returns true if the host is quarantined at the given time.
+func (h *hostHealth) isQuarantined(now time.Time) bool {
+	return now.Before(h.quarantinedUntil)
+}
+
+// isFailure returns true if the terminal task state counts against the
+// health of the host.
+func isFailure(state task.TaskState) bool {
+	return state == task.TaskState_FAILED || state == task.TaskState_LOST
+}
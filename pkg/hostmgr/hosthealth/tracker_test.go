@@ -0,0 +1,302 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosthealth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const _testHost = "host-0"
+
+type TrackerTestSuite struct {
+	suite.Suite
+
+	now     time.Time
+	tracker *tracker
+}
+
+func (suite *TrackerTestSuite) SetupTest() {
+	suite.now = time.Now()
+	config := Config{
+		Enabled:                true,
+		Window:                 10 * time.Minute,
+		MinSamples:             4,
+		FailureRateThreshold:   0.5,
+		LaunchFailureThreshold: 3,
+		QuarantineDuration:     time.Minute,
+		MaxQuarantineDuration:  3 * time.Minute,
+		MaxQuarantinedHosts:    2,
+	}
+	suite.tracker = NewTracker(config, tally.NoopScope).(*tracker)
+	suite.tracker.nowFunc = func() time.Time { return suite.now }
+}
+
+func TestTrackerTestSuite(t *testing.T) {
+	suite.Run(t, new(TrackerTestSuite))
+}
+
+// runTask records a task which runs and then ends in the given state.
+func (suite *TrackerTestSuite) runTask(
+	hostname string,
+	taskID string,
+	state task.TaskState) {
+	suite.tracker.RecordTaskState(hostname, taskID, task.TaskState_LAUNCHED)
+	suite.tracker.RecordTaskState(hostname, taskID, task.TaskState_RUNNING)
+	suite.tracker.RecordTaskState(hostname, taskID, state)
+}
+
+// failLaunch records a task which fails before it starts running.
+func (suite *TrackerTestSuite) failLaunch(hostname string, taskID string) {
+	suite.tracker.RecordTaskState(hostname, taskID, task.TaskState_LAUNCHED)
+	suite.tracker.RecordTaskState(hostname, taskID, task.TaskState_FAILED)
+}
+
+// TestConfigNormalize tests config is correctly normalized
+func (suite *TrackerTestSuite) TestConfigNormalize() {
+	c := &Config{}
+	c.normalize()
+	suite.True(c.Window > 0)
+	suite.True(c.MinSamples > 0)
+	suite.True(c.FailureRateThreshold > 0)
+	suite.True(c.LaunchFailureThreshold > 0)
+	suite.True(c.QuarantineDuration > 0)
+	suite.True(c.MaxQuarantineDuration >= c.QuarantineDuration)
+	suite.True(c.MaxQuarantinedHosts > 0)
+	suite.True(c.ReleaseCheckPeriod > 0)
+}
+
+// TestHealthyHost tests that successful and killed tasks do not
+// quarantine a host
+func (suite *TrackerTestSuite) TestHealthyHost() {
+	for i := 0; i < 10; i++ {
+		suite.runTask(_testHost, fmt.Sprintf("task-%d", i), task.TaskState_SUCCEEDED)
+		suite.runTask(_testHost, fmt.Sprintf("killed-%d", i), task.TaskState_KILLED)
+	}
+	suite.False(suite.tracker.IsQuarantined(_testHost))
+
+	health := suite.tracker.GetHostHealth(_testHost)
+	suite.Equal(uint32(20), health.GetTerminalTasks())
+	suite.Equal(uint32(0), health.GetFailedTasks())
+	suite.False(health.GetQuarantined())
+}
+
+// TestUnknownHost tests that nothing is reported for an unknown host
+func (suite *TrackerTestSuite) TestUnknownHost() {
+	suite.False(suite.tracker.IsQuarantined("unknown"))
+	suite.Nil(suite.tracker.GetHostHealth("unknown"))
+
+	// Empty hostname or task id is ignored.
+	suite.tracker.RecordTaskState("", "task", task.TaskState_FAILED)
+	suite.tracker.RecordTaskState(_testHost, "", task.TaskState_FAILED)
+	suite.Empty(suite.tracker.hosts)
+}
+
+// TestDuplicateTerminalUpdates tests that terminal updates of tasks not
+// known to be on the host are not scored
+func (suite *TrackerTestSuite) TestDuplicateTerminalUpdates() {
+	suite.failLaunch(_testHost, "task-0")
+	suite.tracker.RecordTaskState(_testHost, "task-0", task.TaskState_FAILED)
+	suite.tracker.RecordTaskState(_testHost, "task-1", task.TaskState_LOST)
+
+	health := suite.tracker.GetHostHealth(_testHost)
+	suite.Equal(uint32(1), health.GetTerminalTasks())
+	suite.Equal(uint32(1), health.GetLaunchFailures())
+	suite.Equal(uint32(0), health.GetLostTasks())
+}
+
+// TestTerminalTasksForgotten tests that the tasks of a host are forgotten
+// once they reach any terminal state, and that terminal updates do not
+// start tracking a host
+func (suite *TrackerTestSuite) TestTerminalTasksForgotten() {
+	suite.tracker.RecordTaskState(_testHost, "task-0", task.TaskState_LAUNCHED)
+	suite.tracker.RecordTaskState(_testHost, "task-1", task.TaskState_RUNNING)
+	suite.Len(suite.tracker.hosts[_testHost].tasks, 2)
+
+	// a deleted task is forgotten without being scored
+	suite.tracker.RecordTaskState(_testHost, "task-0", task.TaskState_DELETED)
+	suite.tracker.RecordTaskState(_testHost, "task-1", task.TaskState_SUCCEEDED)
+	suite.Empty(suite.tracker.hosts[_testHost].tasks)
+	suite.Equal(
+		uint32(1),
+		suite.tracker.GetHostHealth(_testHost).GetTerminalTasks())
+
+	suite.tracker.RecordTaskState("other", "task-2", task.TaskState_LOST)
+	suite.Nil(suite.tracker.GetHostHealth("other"))
+}
+
+// TestRemoveHost tests that a removed host is forgotten, unless it is
+// quarantined, in which case it is forgotten once its quarantine is
+// released and its quarantine count is reset
+func (suite *TrackerTestSuite) TestRemoveHost() {
+	suite.tracker.RecordTaskState(_testHost, "task-0", task.TaskState_RUNNING)
+	suite.tracker.RemoveHost(_testHost)
+	suite.Empty(suite.tracker.hosts)
+
+	// removing an unknown host is a no-op
+	suite.tracker.RemoveHost("unknown")
+
+	for j := 0; j < 3; j++ {
+		suite.failLaunch(_testHost, fmt.Sprintf("task-%d", j))
+	}
+	suite.tracker.RecordTaskState(_testHost, "task-3", task.TaskState_RUNNING)
+	suite.tracker.RemoveHost(_testHost)
+	suite.True(suite.tracker.IsQuarantined(_testHost))
+	suite.Empty(suite.tracker.hosts[_testHost].tasks)
+
+	suite.now = suite.now.Add(time.Minute)
+	suite.Equal(
+		[]string{_testHost},
+		suite.tracker.ReleaseExpiredQuarantines(suite.now))
+	suite.Len(suite.tracker.hosts, 1)
+
+	suite.now = suite.now.Add(4 * time.Minute)
+	suite.Empty(suite.tracker.ReleaseExpiredQuarantines(suite.now))
+	suite.Empty(suite.tracker.hosts)
+}
+
+// TestIdleHostsForgotten tests that the hosts whose outcomes are all
+// outside of the window are forgotten
+func (suite *TrackerTestSuite) TestIdleHostsForgotten() {
+	suite.runTask(_testHost, "task-0", task.TaskState_SUCCEEDED)
+	suite.tracker.RecordTaskState("host-1", "task-1", task.TaskState_RUNNING)
+
+	suite.now = suite.now.Add(5 * time.Minute)
+	suite.tracker.ReleaseExpiredQuarantines(suite.now)
+	suite.Len(suite.tracker.hosts, 2)
+
+	// the running task keeps host-1 tracked
+	suite.now = suite.now.Add(10 * time.Minute)
+	suite.tracker.ReleaseExpiredQuarantines(suite.now)
+	suite.Nil(suite.tracker.GetHostHealth(_testHost))
+	suite.NotNil(suite.tracker.GetHostHealth("host-1"))
+}
+
+// TestQuarantineOnFailureRate tests that a host is quarantined once the
+// rate of failed and lost tasks crosses the threshold
+func (suite *TrackerTestSuite) TestQuarantineOnFailureRate() {
+	suite.runTask(_testHost, "task-0", task.TaskState_SUCCEEDED)
+	suite.runTask(_testHost, "task-1", task.TaskState_FAILED)
+	suite.runTask(_testHost, "task-2", task.TaskState_SUCCEEDED)
+	suite.False(suite.tracker.IsQuarantined(_testHost))
+
+	health := suite.tracker.GetHostHealth(_testHost)
+	suite.Equal(uint32(3), health.GetTerminalTasks())
+	suite.Equal(uint32(1), health.GetFailedTasks())
+
+	suite.runTask(_testHost, "task-3", task.TaskState_LOST)
+	suite.True(suite.tracker.IsQuarantined(_testHost))
+
+	health = suite.tracker.GetHostHealth(_testHost)
+	suite.True(health.GetQuarantined())
+	suite.Equal(uint32(1), health.GetQuarantineCount())
+	suite.Equal(
+		suite.now.Add(time.Minute).UTC().Format(time.RFC3339),
+		health.GetQuarantineReleaseTime())
+}
+
+// TestQuarantineOnLaunchFailures tests that a host is quarantined once
+// enough tasks fail before running, even below the minimum samples
+func (suite *TrackerTestSuite) TestQuarantineOnLaunchFailures() {
+	suite.failLaunch(_testHost, "task-0")
+	suite.failLaunch(_testHost, "task-1")
+	suite.False(suite.tracker.IsQuarantined(_testHost))
+	suite.Equal(
+		uint32(2),
+		suite.tracker.GetHostHealth(_testHost).GetLaunchFailures())
+
+	suite.failLaunch(_testHost, "task-2")
+	suite.True(suite.tracker.IsQuarantined(_testHost))
+}
+
+// TestOutcomesOutsideWindow tests that outcomes older than the window
+// are not scored
+func (suite *TrackerTestSuite) TestOutcomesOutsideWindow() {
+	suite.failLaunch(_testHost, "task-0")
+	suite.failLaunch(_testHost, "task-1")
+
+	suite.now = suite.now.Add(11 * time.Minute)
+	suite.failLaunch(_testHost, "task-2")
+	suite.False(suite.tracker.IsQuarantined(_testHost))
+	suite.Equal(
+		uint32(1),
+		suite.tracker.GetHostHealth(_testHost).GetLaunchFailures())
+}
+
+// TestExponentialRelease tests that consecutive quarantines of a host
+// double in duration up to the maximum
+func (suite *TrackerTestSuite) TestExponentialRelease() {
+	durations := []time.Duration{
+		time.Minute,
+		2 * time.Minute,
+		3 * time.Minute,
+		3 * time.Minute,
+	}
+	for i, d := range durations {
+		for j := 0; j < 3; j++ {
+			suite.failLaunch(_testHost, fmt.Sprintf("task-%d-%d", i, j))
+		}
+		suite.True(suite.tracker.IsQuarantined(_testHost))
+
+		suite.now = suite.now.Add(d - time.Second)
+		suite.Empty(suite.tracker.ReleaseExpiredQuarantines(suite.now))
+		suite.True(suite.tracker.IsQuarantined(_testHost))
+
+		suite.now = suite.now.Add(time.Second)
+		suite.False(suite.tracker.IsQuarantined(_testHost))
+		suite.Equal(
+			[]string{_testHost},
+			suite.tracker.ReleaseExpiredQuarantines(suite.now))
+	}
+
+	// Host stays healthy for longer than the max quarantine duration,
+	// so the next quarantine starts over from the initial duration.
+	suite.now = suite.now.Add(4 * time.Minute)
+	for j := 0; j < 3; j++ {
+		suite.failLaunch(_testHost, fmt.Sprintf("task-last-%d", j))
+	}
+	health := suite.tracker.GetHostHealth(_testHost)
+	suite.Equal(uint32(1), health.GetQuarantineCount())
+	suite.Equal(
+		suite.now.Add(time.Minute).UTC().Format(time.RFC3339),
+		health.GetQuarantineReleaseTime())
+}
+
+// TestMaxQuarantinedHosts tests that no more than the configured number
+// of hosts are quarantined at the same time
+func (suite *TrackerTestSuite) TestMaxQuarantinedHosts() {
+	for i := 0; i < 3; i++ {
+		hostname := fmt.Sprintf("host-%d", i)
+		for j := 0; j < 3; j++ {
+			suite.failLaunch(hostname, fmt.Sprintf("task-%d", j))
+		}
+	}
+	suite.True(suite.tracker.IsQuarantined("host-0"))
+	suite.True(suite.tracker.IsQuarantined("host-1"))
+	suite.False(suite.tracker.IsQuarantined("host-2"))
+
+	// Once a quarantine is released, the next failure on the skipped host
+	// quarantines it.
+	suite.now = suite.now.Add(time.Minute)
+	suite.Len(suite.tracker.ReleaseExpiredQuarantines(suite.now), 2)
+	suite.failLaunch("host-2", "task-3")
+	suite.True(suite.tracker.IsQuarantined("host-2"))
+}
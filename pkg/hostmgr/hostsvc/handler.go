@@ -28,10 +28,12 @@ import (
 	"github.com/uber/peloton/pkg/common/stringset"
 	"github.com/uber/peloton/pkg/common/util"
 	"github.com/uber/peloton/pkg/hostmgr/host"
+	"github.com/uber/peloton/pkg/hostmgr/hosthealth"
 	hostpool_mgr "github.com/uber/peloton/pkg/hostmgr/hostpool/manager"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/queue"
 
+	"github.com/gogo/protobuf/proto"
 	log "github.com/sirupsen/logrus"
 	"github.com/uber-go/tally"
 	"go.uber.org/multierr"
@@ -46,6 +48,7 @@ type serviceHandler struct {
 	operatorMasterClient   mpb.MasterOperatorClient
 	maintenanceHostInfoMap host.MaintenanceHostInfoMap
 	hostPoolManager        hostpool_mgr.HostPoolManager
+	hostHealthTracker      hosthealth.Tracker
 }
 
 // InitServiceHandler initializes the HostService
@@ -55,13 +58,15 @@ func InitServiceHandler(
 	operatorMasterClient mpb.MasterOperatorClient,
 	maintenanceQueue queue.MaintenanceQueue,
 	hostInfoMap host.MaintenanceHostInfoMap,
-	hostPoolManager hostpool_mgr.HostPoolManager) {
+	hostPoolManager hostpool_mgr.HostPoolManager,
+	hostHealthTracker hosthealth.Tracker) {
 	handler := &serviceHandler{
 		maintenanceQueue:       maintenanceQueue,
 		metrics:                NewMetrics(parent.SubScope("hostsvc")),
 		operatorMasterClient:   operatorMasterClient,
 		maintenanceHostInfoMap: hostInfoMap,
 		hostPoolManager:        hostPoolManager,
+		hostHealthTracker:      hostHealthTracker,
 	}
 	d.Register(host_svc.BuildHostServiceYARPCProcedures(handler))
 	log.Info("Hostsvc handler initialized")
@@ -74,6 +79,8 @@ func InitServiceHandler(
 // 										  there will be no further placement of tasks on the host
 //		3.HostState_HOST_STATE_DRAINED - There are no tasks running on this host and it is ready to be 'DOWN'ed
 // 		4.HostState_HOST_STATE_DOWN - The host is in maintenance.
+// If host health tracking is enabled, the health of each host, including
+// whether it is quarantined, is returned along with its state.
 func (m *serviceHandler) QueryHosts(
 	ctx context.Context,
 	request *host_svc.QueryHostsRequest) (*host_svc.QueryHostsResponse, error) {
@@ -126,6 +133,17 @@ func (m *serviceHandler) QueryHosts(
 		}
 	}
 
+	if m.hostHealthTracker != nil {
+		// Host infos of maintenance hosts are shared with the maintenance
+		// map, so copy them before adding the health.
+		for i, hostInfo := range hostInfos {
+			hostInfo = proto.Clone(hostInfo).(*hpb.HostInfo)
+			hostInfo.Health = m.hostHealthTracker.GetHostHealth(
+				hostInfo.GetHostname())
+			hostInfos[i] = hostInfo
+		}
+	}
+
 	m.metrics.QueryHostsSuccess.Inc(1)
 	return &host_svc.QueryHostsResponse{
 		HostInfos: hostInfos,
@@ -28,6 +28,7 @@ import (
 	"github.com/uber/peloton/pkg/common/stringset"
 	"github.com/uber/peloton/pkg/hostmgr/host"
 	hm "github.com/uber/peloton/pkg/hostmgr/host/mocks"
+	hhm "github.com/uber/peloton/pkg/hostmgr/hosthealth/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/hostpool"
 	hpm_mock "github.com/uber/peloton/pkg/hostmgr/hostpool/manager/mocks"
 	ym "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
@@ -442,6 +443,45 @@ func (suite *HostSvcHandlerTestSuite) TestQueryHosts() {
 	}
 }
 
+// TestQueryHostsWithHealth tests QueryHosts returns the health of hosts
+// without modifying the host infos of the maintenance map
+func (suite *HostSvcHandlerTestSuite) TestQueryHostsWithHealth() {
+	tracker := hhm.NewMockTracker(suite.mockCtrl)
+	suite.handler.hostHealthTracker = tracker
+	defer func() { suite.handler.hostHealthTracker = nil }()
+
+	drainingHostInfo := &hpb.HostInfo{
+		Hostname: suite.drainingMachine.GetHostname(),
+		Ip:       suite.drainingMachine.GetIp(),
+		State:    hpb.HostState_HOST_STATE_DRAINING,
+	}
+	health := &hpb.HostHealth{
+		TerminalTasks:  5,
+		LaunchFailures: 5,
+		Quarantined:    true,
+	}
+
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return([]*hpb.HostInfo{drainingHostInfo})
+	suite.mockMaintenanceMap.EXPECT().
+		GetDownHostInfos([]string{}).
+		Return(nil)
+	tracker.EXPECT().
+		GetHostHealth(suite.drainingMachine.GetHostname()).
+		Return(health)
+
+	resp, err := suite.handler.QueryHosts(suite.ctx, &svcpb.QueryHostsRequest{
+		HostStates: []hpb.HostState{
+			hpb.HostState_HOST_STATE_DRAINING,
+		},
+	})
+	suite.NoError(err)
+	suite.Len(resp.GetHostInfos(), 1)
+	suite.Equal(health, resp.GetHostInfos()[0].GetHealth())
+	suite.Nil(drainingHostInfo.GetHealth())
+}
+
 func (suite *HostSvcHandlerTestSuite) TestQueryHostsError() {
 	// Test ExtractIPFromMesosAgentPID error
 	hostname := "testhost"
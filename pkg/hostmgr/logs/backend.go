@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"context"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+)
+
+// Request to stream the logs of a pod
+type Request struct {
+	// Hostname of the host the pod runs on.
+	Hostname string
+
+	// ID of the pod run, i.e. the Mesos task ID or the Kubernetes pod name.
+	PodID string
+
+	// Output stream to read the logs from.
+	Stream hostsvc.LogStream
+
+	// Byte offset in the log to start reading from. A negative offset
+	// is relative to the end of the log.
+	Offset int64
+
+	// Maximum number of bytes to read, 0 for no limit.
+	Length int64
+
+	// Keep streaming the data appended to the log until the
+	// context is cancelled.
+	Follow bool
+}
+
+// Chunk is a chunk of the logs of a pod
+type Chunk struct {
+	// Log data.
+	Data []byte
+
+	// Byte offset of the data in the log.
+	Offset int64
+}
+
+// Sender sends a chunk of logs to the caller. Streaming stops on the
+// first error returned by the sender.
+type Sender func(chunk *Chunk) error
+
+// Backend streams the logs of pods from an underlying cluster manager.
+type Backend interface {
+	// StreamLogs reads the logs selected by the request and sends them
+	// chunk by chunk, in order, to the sender.
+	StreamLogs(ctx context.Context, req *Request, send Sender) error
+}
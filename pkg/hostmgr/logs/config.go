@@ -0,0 +1,57 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"time"
+)
+
+const (
+	_defaultMesosAgentWorkDir = "/var/lib/mesos/agent"
+	_defaultChunkSize         = 64 * 1024
+	_defaultPollInterval      = time.Second
+	_defaultHTTPTimeout       = 15 * time.Second
+)
+
+// Config for streaming the logs of pods
+type Config struct {
+	// Work dir of the Mesos agents, used to locate the sandbox of the pods.
+	MesosAgentWorkDir string `yaml:"mesos_agent_work_dir"`
+
+	// Maximum number of bytes read from the cluster manager at once.
+	ChunkSize int64 `yaml:"chunk_size"`
+
+	// Period at which the Mesos agent is polled for new data while
+	// following the logs of a pod.
+	PollInterval time.Duration `yaml:"poll_interval"`
+
+	// Timeout of each request to the Mesos agent files API.
+	HTTPTimeout time.Duration `yaml:"http_timeout"`
+}
+
+func (c *Config) normalize() {
+	if c.MesosAgentWorkDir == "" {
+		c.MesosAgentWorkDir = _defaultMesosAgentWorkDir
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = _defaultChunkSize
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = _defaultPollInterval
+	}
+	if c.HTTPTimeout <= 0 {
+		c.HTTPTimeout = _defaultHTTPTimeout
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/hostmgr/p2k/plugins/k8s"
+
+	"go.uber.org/yarpc/yarpcerrors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// k8sBackend streams the logs of pods using the K8s pod log API.
+type k8sBackend struct {
+	cfg        Config
+	kubeClient kubernetes.Interface
+
+	// openLogStream opens the log stream of a pod.
+	openLogStream func(
+		ctx context.Context,
+		podID string,
+		opts *corev1.PodLogOptions,
+	) (io.ReadCloser, error)
+}
+
+// NewK8sBackend returns a Backend streaming the logs of pods running
+// on K8s, using the kubeconfig file at the given path.
+func NewK8sBackend(cfg Config, kubeConfigPath string) (Backend, error) {
+	kubeClient, _, err := k8s.NewKubeClient(kubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return newK8sBackendWithClient(cfg, kubeClient), nil
+}
+
+func newK8sBackendWithClient(
+	cfg Config,
+	kubeClient kubernetes.Interface,
+) *k8sBackend {
+	cfg.normalize()
+	k := &k8sBackend{
+		cfg:        cfg,
+		kubeClient: kubeClient,
+	}
+	k.openLogStream = k.openPodLogStream
+	return k
+}
+
+func (k *k8sBackend) openPodLogStream(
+	ctx context.Context,
+	podID string,
+	opts *corev1.PodLogOptions,
+) (io.ReadCloser, error) {
+	return k.kubeClient.CoreV1().
+		Pods(k8s.PodNamespace).
+		GetLogs(podID, opts).
+		Context(ctx).
+		Stream()
+}
+
+// StreamLogs implements Backend.StreamLogs.
+func (k *k8sBackend) StreamLogs(
+	ctx context.Context,
+	req *Request,
+	send Sender,
+) error {
+	if req.Stream == hostsvc.LogStream_LOG_STREAM_STDERR {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"k8s does not separate the stderr of a pod from its stdout")
+	}
+	if req.Offset < 0 {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"k8s does not support offsets relative to the end of the log")
+	}
+
+	pod, err := k.kubeClient.CoreV1().
+		Pods(k8s.PodNamespace).
+		Get(req.PodID, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return yarpcerrors.NotFoundErrorf("pod %s not found", req.PodID)
+	}
+	if err != nil {
+		return err
+	}
+
+	opts := &corev1.PodLogOptions{Follow: req.Follow}
+	if len(pod.Spec.Containers) > 0 {
+		// logs of the main container of the pod
+		opts.Container = pod.Spec.Containers[0].Name
+	}
+	if req.Length > 0 {
+		limitBytes := req.Offset + req.Length
+		opts.LimitBytes = &limitBytes
+	}
+
+	stream, err := k.openLogStream(ctx, req.PodID, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	// K8s always streams the logs from the beginning,
+	// hence skip the data before the offset
+	if req.Offset > 0 {
+		if _, err := io.CopyN(ioutil.Discard, stream, req.Offset); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+
+	offset := req.Offset
+	buf := make([]byte, k.cfg.ChunkSize)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			data := make([]byte, n)
+			copy(data, buf[:n])
+			if err := send(&Chunk{Data: data, Offset: offset}); err != nil {
+				return err
+			}
+			offset += int64(n)
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/hostmgr/p2k/plugins/k8s"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+const _testPodLogs = "hello world"
+
+type K8sBackendTestSuite struct {
+	suite.Suite
+
+	backend *k8sBackend
+	opts    *corev1.PodLogOptions
+}
+
+func TestK8sBackend(t *testing.T) {
+	suite.Run(t, new(K8sBackendTestSuite))
+}
+
+func (suite *K8sBackendTestSuite) SetupTest() {
+	suite.opts = nil
+	suite.backend = newK8sBackendWithClient(
+		Config{ChunkSize: 4},
+		testclient.NewSimpleClientset(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      _testPodID,
+				Namespace: k8s.PodNamespace,
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "main"},
+					{Name: "sidecar"},
+				},
+			},
+		}),
+	)
+	suite.backend.openLogStream = func(
+		ctx context.Context,
+		podID string,
+		opts *corev1.PodLogOptions,
+	) (io.ReadCloser, error) {
+		suite.Equal(_testPodID, podID)
+		suite.opts = opts
+
+		logs := _testPodLogs
+		if opts.LimitBytes != nil && *opts.LimitBytes < int64(len(logs)) {
+			logs = logs[:*opts.LimitBytes]
+		}
+		return ioutil.NopCloser(strings.NewReader(logs)), nil
+	}
+}
+
+// streamLogs streams the logs of the test pod and returns the data
+// received along with the offsets of the chunks
+func (suite *K8sBackendTestSuite) streamLogs(
+	req *Request,
+) (string, []int64, error) {
+	var buf bytes.Buffer
+	var offsets []int64
+	err := suite.backend.StreamLogs(
+		context.Background(),
+		req,
+		func(chunk *Chunk) error {
+			buf.Write(chunk.Data)
+			offsets = append(offsets, chunk.Offset)
+			return nil
+		})
+	return buf.String(), offsets, err
+}
+
+// TestStreamLogs tests streaming the logs of the main container of a pod
+func (suite *K8sBackendTestSuite) TestStreamLogs() {
+	data, offsets, err := suite.streamLogs(&Request{
+		PodID:  _testPodID,
+		Follow: true,
+	})
+	suite.NoError(err)
+	suite.Equal(_testPodLogs, data)
+	suite.Equal([]int64{0, 4, 8}, offsets)
+	suite.Equal("main", suite.opts.Container)
+	suite.True(suite.opts.Follow)
+	suite.Nil(suite.opts.LimitBytes)
+}
+
+// TestStreamLogsRange tests streaming a byte range of the logs of a pod
+func (suite *K8sBackendTestSuite) TestStreamLogsRange() {
+	data, offsets, err := suite.streamLogs(&Request{
+		PodID:  _testPodID,
+		Offset: 2,
+		Length: 7,
+	})
+	suite.NoError(err)
+	suite.Equal("llo wor", data)
+	suite.Equal([]int64{2, 6}, offsets)
+	suite.Equal(int64(9), *suite.opts.LimitBytes)
+
+	data, _, err = suite.streamLogs(&Request{
+		PodID:  _testPodID,
+		Offset: 100,
+	})
+	suite.NoError(err)
+	suite.Empty(data)
+}
+
+// TestStreamLogsUnsupported tests streaming logs with a selection
+// unsupported by K8s
+func (suite *K8sBackendTestSuite) TestStreamLogsUnsupported() {
+	_, _, err := suite.streamLogs(&Request{
+		PodID:  _testPodID,
+		Stream: hostsvc.LogStream_LOG_STREAM_STDERR,
+	})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	_, _, err = suite.streamLogs(&Request{
+		PodID:  _testPodID,
+		Offset: -5,
+	})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestStreamLogsPodNotFound tests streaming the logs of an unknown pod
+func (suite *K8sBackendTestSuite) TestStreamLogsPodNotFound() {
+	_, _, err := suite.streamLogs(&Request{
+		PodID: "unknown-pod",
+	})
+	suite.True(yarpcerrors.IsNotFound(err))
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
+
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_mesosSandboxFile  = "%s/slaves/%s/frameworks/%s/executors/%s/runs/latest/%s"
+	_mesosFilesReadURL = "http://%s/files/read?%s"
+
+	// Offset to pass to the files API to get the size of a file
+	_mesosFileSizeOffset = -1
+)
+
+// mesosFileData is the response of the Mesos agent files/read endpoint.
+type mesosFileData struct {
+	Data   string `json:"data"`
+	Offset int64  `json:"offset"`
+}
+
+// mesosBackend streams the logs of pods from the sandbox of the Mesos
+// agents, using the agent files API.
+type mesosBackend struct {
+	cfg                   Config
+	client                *http.Client
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider
+
+	// getAgent returns the registered Mesos agent with the given hostname,
+	// or nil if there is no such agent.
+	getAgent func(hostname string) *mesos_master.Response_GetAgents_Agent
+}
+
+// NewMesosBackend returns a Backend streaming the logs of pods
+// running on Mesos agents.
+func NewMesosBackend(
+	cfg Config,
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider,
+) Backend {
+	cfg.normalize()
+	return &mesosBackend{
+		cfg:                   cfg,
+		client:                &http.Client{Timeout: cfg.HTTPTimeout},
+		frameworkInfoProvider: frameworkInfoProvider,
+		getAgent:              host.GetRegisteredAgent,
+	}
+}
+
+// StreamLogs implements Backend.StreamLogs.
+func (m *mesosBackend) StreamLogs(
+	ctx context.Context,
+	req *Request,
+	send Sender,
+) error {
+	agent := m.getAgent(req.Hostname)
+	if agent == nil {
+		return yarpcerrors.NotFoundErrorf(
+			"mesos agent not found on host %s", req.Hostname)
+	}
+
+	agentAddr := host.GetAgentAddress(agent)
+
+	path, size, err := m.locateLogFile(
+		ctx,
+		agentAddr,
+		agent.GetAgentInfo().GetId().GetValue(),
+		m.frameworkInfoProvider.GetFrameworkID(ctx).GetValue(),
+		req.PodID,
+		getLogFileName(req.Stream),
+	)
+	if err != nil {
+		return err
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset += size
+		if offset < 0 {
+			offset = 0
+		}
+	}
+
+	remaining := req.Length
+	for {
+		// streaming stops once the caller cancels the context
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		length := m.cfg.ChunkSize
+		if req.Length > 0 && remaining < length {
+			length = remaining
+		}
+
+		fileData, err := m.readFile(ctx, agentAddr, path, offset, length)
+		if err != nil {
+			return err
+		}
+
+		if len(fileData.Data) > 0 {
+			if err := send(&Chunk{
+				Data:   []byte(fileData.Data),
+				Offset: fileData.Offset,
+			}); err != nil {
+				return err
+			}
+
+			offset = fileData.Offset + int64(len(fileData.Data))
+			if req.Length > 0 {
+				remaining -= int64(len(fileData.Data))
+				if remaining <= 0 {
+					return nil
+				}
+			}
+			continue
+		}
+
+		// reached the end of the log
+		if !req.Follow {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(m.cfg.PollInterval):
+		}
+	}
+}
+
+// locateLogFile returns the path and the size of the log file in the
+// sandbox of the pod.
+func (m *mesosBackend) locateLogFile(
+	ctx context.Context,
+	agentAddr string,
+	agentID string,
+	frameworkID string,
+	podID string,
+	fileName string,
+) (string, int64, error) {
+	// Pods launched by thermos executor have an executor ID
+	// with a prefix of `thermos`
+	executorIDs := []string{
+		podID,
+		common.PelotonAuroraBridgeExecutorIDPrefix + podID,
+	}
+
+	for _, executorID := range executorIDs {
+		path := fmt.Sprintf(
+			_mesosSandboxFile,
+			m.cfg.MesosAgentWorkDir,
+			agentID,
+			frameworkID,
+			executorID,
+			fileName)
+
+		fileData, err := m.readFile(
+			ctx, agentAddr, path, _mesosFileSizeOffset, 0)
+		if yarpcerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		return path, fileData.Offset, nil
+	}
+
+	return "", 0, yarpcerrors.NotFoundErrorf(
+		"%s not found in the sandbox of pod %s", fileName, podID)
+}
+
+// readFile reads length bytes from the file at the given offset, or up to
+// the end of the file if length is 0.
+func (m *mesosBackend) readFile(
+	ctx context.Context,
+	agentAddr string,
+	path string,
+	offset int64,
+	length int64,
+) (*mesosFileData, error) {
+	query := url.Values{}
+	query.Set("path", path)
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	if length > 0 {
+		query.Set("length", strconv.FormatInt(length, 10))
+	}
+	fileURL := fmt.Sprintf(_mesosFilesReadURL, agentAddr, query.Encode())
+
+	httpReq, err := http.NewRequest(http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, yarpcerrors.NotFoundErrorf("%s not found", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP GET failed for %s: %v", fileURL, resp.Status)
+	}
+
+	fileData := &mesosFileData{}
+	if err := json.NewDecoder(resp.Body).Decode(fileData); err != nil {
+		log.WithError(err).
+			WithField("url", fileURL).
+			Warn("failed to decode mesos agent files response")
+		return nil, fmt.Errorf("failed to decode response for %s: %v", fileURL, err)
+	}
+	return fileData, nil
+}
+
+// getLogFileName returns the name of the sandbox file of the output stream.
+func getLogFileName(stream hostsvc.LogStream) string {
+	if stream == hostsvc.LogStream_LOG_STREAM_STDERR {
+		return "stderr"
+	}
+	return "stdout"
+}
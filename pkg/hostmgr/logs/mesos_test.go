@@ -0,0 +1,302 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_testHostname    = "test-hostname"
+	_testAgentID     = "test-agent-id"
+	_testFrameworkID = "test-framework-id"
+	_testPodID       = "test-pod-id"
+	_testWorkDir     = "/var/lib/mesos/agent"
+)
+
+// fakeMesosAgent serves the files/read endpoint of a Mesos agent
+type fakeMesosAgent struct {
+	sync.Mutex
+	files map[string]string
+}
+
+func (a *fakeMesosAgent) setFile(path, content string) {
+	a.Lock()
+	defer a.Unlock()
+	a.files[path] = content
+}
+
+func (a *fakeMesosAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.Lock()
+	defer a.Unlock()
+
+	content, ok := a.files[r.URL.Query().Get("path")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if offset < 0 {
+		json.NewEncoder(w).Encode(&mesosFileData{Offset: int64(len(content))})
+		return
+	}
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+
+	end := int64(len(content))
+	if length := r.URL.Query().Get("length"); length != "" {
+		l, _ := strconv.ParseInt(length, 10, 64)
+		if offset+l < end {
+			end = offset + l
+		}
+	}
+
+	json.NewEncoder(w).Encode(&mesosFileData{
+		Data:   content[offset:end],
+		Offset: offset,
+	})
+}
+
+type MesosBackendTestSuite struct {
+	suite.Suite
+
+	ctrl                  *gomock.Controller
+	frameworkInfoProvider *hostmgr_mesos_mocks.MockFrameworkInfoProvider
+	agent                 *fakeMesosAgent
+	server                *httptest.Server
+	backend               *mesosBackend
+}
+
+func TestMesosBackend(t *testing.T) {
+	suite.Run(t, new(MesosBackendTestSuite))
+}
+
+func (suite *MesosBackendTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.frameworkInfoProvider = hostmgr_mesos_mocks.NewMockFrameworkInfoProvider(suite.ctrl)
+	suite.frameworkInfoProvider.EXPECT().
+		GetFrameworkID(gomock.Any()).
+		Return(&mesos.FrameworkID{Value: &[]string{_testFrameworkID}[0]}).
+		AnyTimes()
+
+	suite.agent = &fakeMesosAgent{files: make(map[string]string)}
+	suite.server = httptest.NewServer(suite.agent)
+
+	agentPID := "slave(1)@" + strings.TrimPrefix(suite.server.URL, "http://")
+	agentID := _testAgentID
+	agent := &mesos_master.Response_GetAgents_Agent{
+		AgentInfo: &mesos.AgentInfo{
+			Id: &mesos.AgentID{Value: &agentID},
+		},
+		Pid: &agentPID,
+	}
+
+	suite.backend = NewMesosBackend(
+		Config{ChunkSize: 4, PollInterval: 10 * time.Millisecond},
+		suite.frameworkInfoProvider,
+	).(*mesosBackend)
+	suite.backend.getAgent = func(
+		hostname string) *mesos_master.Response_GetAgents_Agent {
+		if hostname == _testHostname {
+			return agent
+		}
+		return nil
+	}
+}
+
+func (suite *MesosBackendTestSuite) TearDownTest() {
+	suite.server.Close()
+	suite.ctrl.Finish()
+}
+
+func (suite *MesosBackendTestSuite) sandboxPath(
+	executorID string,
+	fileName string,
+) string {
+	return _testWorkDir + "/slaves/" + _testAgentID +
+		"/frameworks/" + _testFrameworkID +
+		"/executors/" + executorID + "/runs/latest/" + fileName
+}
+
+// streamLogs streams the logs of the test pod and returns the data
+// received along with the offsets of the chunks
+func (suite *MesosBackendTestSuite) streamLogs(
+	req *Request,
+) (string, []int64, error) {
+	var buf bytes.Buffer
+	var offsets []int64
+	err := suite.backend.StreamLogs(
+		context.Background(),
+		req,
+		func(chunk *Chunk) error {
+			buf.Write(chunk.Data)
+			offsets = append(offsets, chunk.Offset)
+			return nil
+		})
+	return buf.String(), offsets, err
+}
+
+// TestStreamLogs tests streaming the whole stdout of a pod
+func (suite *MesosBackendTestSuite) TestStreamLogs() {
+	suite.agent.setFile(suite.sandboxPath(_testPodID, "stdout"), "hello world")
+
+	data, offsets, err := suite.streamLogs(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+	})
+	suite.NoError(err)
+	suite.Equal("hello world", data)
+	suite.Equal([]int64{0, 4, 8}, offsets)
+}
+
+// TestStreamLogsStderr tests streaming the stderr of a pod launched
+// by thermos executor
+func (suite *MesosBackendTestSuite) TestStreamLogsStderr() {
+	suite.agent.setFile(suite.sandboxPath(_testPodID, "stdout"), "out")
+	suite.agent.setFile(
+		suite.sandboxPath("thermos-"+_testPodID, "stderr"), "err")
+
+	data, _, err := suite.streamLogs(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+		Stream:   hostsvc.LogStream_LOG_STREAM_STDERR,
+	})
+	suite.NoError(err)
+	suite.Equal("err", data)
+}
+
+// TestStreamLogsRange tests streaming a byte range of the logs
+func (suite *MesosBackendTestSuite) TestStreamLogsRange() {
+	suite.agent.setFile(suite.sandboxPath(_testPodID, "stdout"), "hello world")
+
+	data, offsets, err := suite.streamLogs(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+		Offset:   2,
+		Length:   7,
+	})
+	suite.NoError(err)
+	suite.Equal("llo wor", data)
+	suite.Equal([]int64{2, 6}, offsets)
+}
+
+// TestStreamLogsTail tests streaming the end of the logs
+func (suite *MesosBackendTestSuite) TestStreamLogsTail() {
+	suite.agent.setFile(suite.sandboxPath(_testPodID, "stdout"), "hello world")
+
+	data, _, err := suite.streamLogs(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+		Offset:   -5,
+	})
+	suite.NoError(err)
+	suite.Equal("world", data)
+
+	data, _, err = suite.streamLogs(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+		Offset:   -100,
+	})
+	suite.NoError(err)
+	suite.Equal("hello world", data)
+}
+
+// TestStreamLogsFollow tests that data appended to the logs is streamed
+// until the context is cancelled
+func (suite *MesosBackendTestSuite) TestStreamLogsFollow() {
+	path := suite.sandboxPath(_testPodID, "stdout")
+	suite.agent.setFile(path, "hello")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var buf bytes.Buffer
+	err := suite.backend.StreamLogs(
+		ctx,
+		&Request{
+			Hostname: _testHostname,
+			PodID:    _testPodID,
+			Follow:   true,
+		},
+		func(chunk *Chunk) error {
+			buf.Write(chunk.Data)
+			switch buf.String() {
+			case "hello":
+				suite.agent.setFile(path, "hello world")
+			case "hello world":
+				cancel()
+			}
+			return nil
+		})
+	suite.NoError(err)
+	suite.Equal("hello world", buf.String())
+}
+
+// TestStreamLogsSendFailure tests that streaming stops on send failure
+func (suite *MesosBackendTestSuite) TestStreamLogsSendFailure() {
+	suite.agent.setFile(suite.sandboxPath(_testPodID, "stdout"), "hello world")
+
+	err := suite.backend.StreamLogs(
+		context.Background(),
+		&Request{
+			Hostname: _testHostname,
+			PodID:    _testPodID,
+		},
+		func(chunk *Chunk) error {
+			return yarpcerrors.UnavailableErrorf("stream closed")
+		})
+	suite.True(yarpcerrors.IsUnavailable(err))
+}
+
+// TestStreamLogsAgentNotFound tests streaming the logs of a pod
+// on an unknown host
+func (suite *MesosBackendTestSuite) TestStreamLogsAgentNotFound() {
+	_, _, err := suite.streamLogs(&Request{
+		Hostname: "unknown-host",
+		PodID:    _testPodID,
+	})
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestStreamLogsFileNotFound tests streaming the logs of a pod
+// without sandbox
+func (suite *MesosBackendTestSuite) TestStreamLogsFileNotFound() {
+	_, _, err := suite.streamLogs(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+	})
+	suite.True(yarpcerrors.IsNotFound(err))
+}
@@ -48,6 +48,7 @@ type MasterOperatorClient interface {
 	StopMaintenance([]*mesos.MachineID) error
 	GetQuota(role string) ([]*mesos.Resource, error)
 	UpdateMaintenanceSchedule(*mesos_v1_maintenance.Schedule) error
+	GetTasks() (*mesos_master.Response_GetTasks, error)
 }
 
 type masterOperatorClient struct {
@@ -447,3 +448,33 @@ func (mo *masterOperatorClient) GetQuota(role string) (
 	}
 	return nil, nil
 }
+
+// GetTasks returns all tasks known to Mesos master with the `GetTasks` API.
+func (mo *masterOperatorClient) GetTasks() (
+	*mesos_master.Response_GetTasks, error) {
+	// GetTasks call only has `Call.Type` and no embedded message.
+	callType := mesos_master.Call_GET_TASKS
+
+	masterMsg := &mesos_master.Call{
+		Type: &callType,
+	}
+
+	// Create context to cancel automatically when Timeout expires
+	ctx, cancel := context.WithTimeout(
+		context.Background(), _timeout,
+	)
+	defer cancel()
+
+	// Make Call
+	response, err := mo.call(ctx, masterMsg)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	getTasks := response.GetGetTasks()
+	if getTasks == nil {
+		return nil, errors.New("no tasks returned from get tasks call")
+	}
+
+	return getTasks, nil
+}
@@ -781,6 +781,69 @@ func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_GetQuota()
 	suite.Nil(resources)
 }
 
+func (suite *masterOperatorClientTestSuite) TestMasterOperatorClient_GetTasks() {
+	taskID := "task"
+	state := mesos.TaskState_TASK_RUNNING
+	callResp := &mesos_master.Response{
+		GetTasks: &mesos_master.Response_GetTasks{
+			Tasks: []*mesos.Task{
+				{
+					TaskId: &mesos.TaskID{Value: &taskID},
+					State:  &state,
+				},
+			},
+		},
+	}
+	wireData, err := proto.Marshal(callResp)
+	suite.NoError(err)
+
+	response := &transport.Response{
+		Body: ioutil.NopCloser(
+			bytes.NewReader(wireData),
+		),
+		Headers: transport.NewHeaders().With("a", "b"),
+	}
+	gomock.InOrder(
+		suite.mockClientCfg.EXPECT().Caller().Return(mockCaller),
+		suite.mockClientCfg.EXPECT().Service().Return(mockSvc),
+		suite.mockClientCfg.EXPECT().GetUnaryOutbound().Return(
+			suite.mockUnaryOutbound,
+		),
+
+		suite.mockUnaryOutbound.EXPECT().Call(
+			gomock.Any(),
+			gomock.Any(),
+		).Return(
+			response,
+			nil,
+		),
+	)
+	tasks, err := suite.masterOperatorClient.GetTasks()
+	suite.NoError(err)
+	suite.Len(tasks.GetTasks(), 1)
+	suite.Equal(taskID, tasks.GetTasks()[0].GetTaskId().GetValue())
+
+	// Test error
+	gomock.InOrder(
+		suite.mockClientCfg.EXPECT().Caller().Return(mockCaller),
+		suite.mockClientCfg.EXPECT().Service().Return(mockSvc),
+		suite.mockClientCfg.EXPECT().GetUnaryOutbound().Return(
+			suite.mockUnaryOutbound,
+		),
+
+		suite.mockUnaryOutbound.EXPECT().Call(
+			gomock.Any(),
+			gomock.Any(),
+		).Return(
+			nil,
+			fmt.Errorf("fake Call error"),
+		),
+	)
+	tasks, err = suite.masterOperatorClient.GetTasks()
+	suite.Error(err)
+	suite.Nil(tasks)
+}
+
 func TestMasterOperatorClientTestSuite(t *testing.T) {
 	suite.Run(t, new(masterOperatorClientTestSuite))
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/uber/peloton/pkg/common/util"
 	"github.com/uber/peloton/pkg/hostmgr/binpacking"
 	"github.com/uber/peloton/pkg/hostmgr/config"
+	"github.com/uber/peloton/pkg/hostmgr/hosthealth"
 	"github.com/uber/peloton/pkg/hostmgr/hostpool/manager"
 	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
@@ -78,6 +79,10 @@ type EventHandler interface {
 	// SetHostPoolManager set host pool manager in the event handler.
 	// It should be called during event handler initialization.
 	SetHostPoolManager(manager manager.HostPoolManager)
+
+	// SetHostHealthTracker set host health tracker in the event handler.
+	// It should be called during event handler initialization.
+	SetHostHealthTracker(tracker hosthealth.Tracker)
 }
 
 // Singleton event handler for offers and mesos status update events
@@ -313,6 +318,12 @@ func (h *eventHandler) SetHostPoolManager(manager manager.HostPoolManager) {
 	h.offerPool.SetHostPoolManager(manager)
 }
 
+// SetHostHealthTracker set host health tracker in the event handler.
+// It should be called during event handler initialization.
+func (h *eventHandler) SetHostHealthTracker(tracker hosthealth.Tracker) {
+	h.offerPool.SetHostHealthTracker(tracker)
+}
+
 // Offers is the mesos callback that sends the offers from master
 func (h *eventHandler) Offers(ctx context.Context, body *sched.Event) error {
 	event := body.GetOffers()
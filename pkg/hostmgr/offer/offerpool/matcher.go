@@ -20,6 +20,7 @@ import (
 
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	"github.com/uber/peloton/pkg/common/constraints"
+	"github.com/uber/peloton/pkg/hostmgr/hosthealth"
 	"github.com/uber/peloton/pkg/hostmgr/hostpool/manager"
 	"github.com/uber/peloton/pkg/hostmgr/summary"
 
@@ -42,6 +43,8 @@ type Matcher struct {
 	evaluator  constraints.Evaluator
 	// hostPoolManager is the manager maintains host to host pool map
 	hostPoolManager manager.HostPoolManager
+	// hostHealthTracker tracks the quarantined hosts, nil if disabled
+	hostHealthTracker hosthealth.Tracker
 	// map of hostname to the host offer
 	hostOffers map[string]*summary.Offer
 
@@ -76,6 +79,10 @@ func (m *Matcher) tryMatchImpl(
 		return hostsvc.HostFilterResult_MATCH
 	}
 
+	if m.hostHealthTracker != nil && m.hostHealthTracker.IsQuarantined(hostname) {
+		return hostsvc.HostFilterResult_MISMATCH_QUARANTINED
+	}
+
 	// Insert host pool into labels for evaluation.
 	var lv constraints.LabelValues
 	var err error
@@ -122,11 +129,13 @@ func NewMatcher(
 	hostFilter *hostsvc.HostFilter,
 	evaluator constraints.Evaluator,
 	hostPoolManager manager.HostPoolManager,
+	hostHealthTracker hosthealth.Tracker,
 ) *Matcher {
 	return &Matcher{
 		hostFilter:         hostFilter,
 		evaluator:          evaluator,
 		hostPoolManager:    hostPoolManager,
+		hostHealthTracker:  hostHealthTracker,
 		hostOffers:         make(map[string]*summary.Offer),
 		filterResultCounts: make(map[string]uint32),
 	}
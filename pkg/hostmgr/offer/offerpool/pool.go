@@ -29,6 +29,7 @@ import (
 	"github.com/uber/peloton/pkg/common/constraints"
 	"github.com/uber/peloton/pkg/common/util"
 	"github.com/uber/peloton/pkg/hostmgr/binpacking"
+	"github.com/uber/peloton/pkg/hostmgr/hosthealth"
 	"github.com/uber/peloton/pkg/hostmgr/hostpool/manager"
 	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
@@ -138,6 +139,9 @@ type Pool interface {
 
 	// SetHostPoolManager set host pool manager in the offer pool.
 	SetHostPoolManager(manager manager.HostPoolManager)
+
+	// SetHostHealthTracker set host health tracker in the offer pool.
+	SetHostHealthTracker(tracker hosthealth.Tracker)
 }
 
 const (
@@ -259,6 +263,10 @@ type offerPool struct {
 	watchProcessor watchevent.WatchProcessor
 
 	hostPoolManager manager.HostPoolManager
+
+	// hostHealthTracker scores hosts by their task failures and
+	// quarantines unhealthy hosts. It is nil if quarantine is disabled.
+	hostHealthTracker hosthealth.Tracker
 }
 
 // ClaimForPlace obtains offers from pool conforming to given constraints.
@@ -278,7 +286,8 @@ func (p *offerPool) ClaimForPlace(
 	matcher := NewMatcher(
 		hostFilter,
 		constraints.NewEvaluator(task.LabelConstraint_HOST),
-		p.hostPoolManager)
+		p.hostPoolManager,
+		p.hostHealthTracker)
 
 	// if host hint is provided, try to return the hosts in hints first
 	for _, filterHints := range hostFilter.GetHint().GetHostHint() {
@@ -403,6 +412,12 @@ func (p *offerPool) ClaimForLaunch(
 
 	for _, launchableTask := range launchableTasks {
 		p.addTaskToHost(launchableTask.GetTaskId().GetValue(), hostname)
+		if p.hostHealthTracker != nil {
+			p.hostHealthTracker.RecordTaskState(
+				hostname,
+				launchableTask.GetTaskId().GetValue(),
+				task.TaskState_LAUNCHED)
+		}
 	}
 
 	return offerMap, nil
@@ -528,6 +543,9 @@ func (p *offerPool) UpdateTasksOnHost(
 	}
 
 	p.hostOfferIndex[hostname].UpdateTasksOnHost(taskID, taskState, taskInfo)
+	if p.hostHealthTracker != nil {
+		p.hostHealthTracker.RecordTaskState(hostname, taskID, taskState)
+	}
 	if util.IsPelotonStateTerminal(taskState) {
 		p.removeTaskToHost(taskID)
 	}
@@ -893,6 +911,11 @@ func (p *offerPool) SetHostPoolManager(manager manager.HostPoolManager) {
 	p.hostPoolManager = manager
 }
 
+// SetHostHealthTracker set host health tracker in the offer pool.
+func (p *offerPool) SetHostHealthTracker(tracker hosthealth.Tracker) {
+	p.hostHealthTracker = tracker
+}
+
 // addTaskHold update the index when a host is held for a task
 func (p *offerPool) addTaskHold(hostname string, id *peloton.TaskID) {
 	oldHost, loaded := p.taskHeldIndex.LoadOrStore(id.GetValue(), hostname)
@@ -32,6 +32,7 @@ import (
 	"github.com/uber/peloton/pkg/common"
 	"github.com/uber/peloton/pkg/common/util"
 	"github.com/uber/peloton/pkg/hostmgr/binpacking"
+	hosthealth_mocks "github.com/uber/peloton/pkg/hostmgr/hosthealth/mocks"
 	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
 	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/metrics"
@@ -1225,6 +1226,65 @@ func (suite *OfferPoolTestSuite) TestClaimForPlaceWithRankHintLoadAware() {
 	suite.NotNil(result[hostName1])
 }
 
+// TestClaimForPlaceSkipsQuarantinedHosts tests ClaimForPlace would
+// not return quarantined hosts, even when hinted
+func (suite *OfferPoolTestSuite) TestClaimForPlaceSkipsQuarantinedHosts() {
+	hostname0 := "hostname0"
+	offer0 := suite.createOffer(hostname0,
+		scalar.Resources{CPU: 1, Mem: 1, Disk: 1})
+	hostname1 := "hostname1"
+	offer1 := suite.createOffer(hostname1,
+		scalar.Resources{CPU: 1, Mem: 1, Disk: 1})
+
+	tracker := hosthealth_mocks.NewMockTracker(suite.ctrl)
+	suite.pool.SetHostHealthTracker(tracker)
+
+	suite.watchProcessor.EXPECT().NotifyEventChange(gomock.Any()).AnyTimes()
+	tracker.EXPECT().IsQuarantined(hostname0).Return(true).AnyTimes()
+	tracker.EXPECT().IsQuarantined(hostname1).Return(false).AnyTimes()
+
+	suite.pool.AddOffers(context.Background(),
+		[]*mesos.Offer{offer0, offer1})
+
+	filter := &hostsvc.HostFilter{
+		Hint:     &hostsvc.FilterHint{HostHint: []*hostsvc.FilterHint_Host{{Hostname: hostname0}}},
+		Quantity: &hostsvc.QuantityControl{MaxHosts: 2},
+	}
+	result, resultCount, err := suite.pool.ClaimForPlace(suite.ctx, filter)
+	suite.NoError(err)
+	suite.Len(result, 1)
+	suite.NotNil(result[hostname1])
+	suite.Equal(uint32(2), resultCount["mismatch_quarantined"])
+}
+
+// TestTaskUpdatesRecordedInHostHealth tests task status updates on
+// a host are recorded in the host health tracker
+func (suite *OfferPoolTestSuite) TestTaskUpdatesRecordedInHostHealth() {
+	tracker := hosthealth_mocks.NewMockTracker(suite.ctrl)
+	suite.pool.SetHostHealthTracker(tracker)
+
+	hostname := "hostname0"
+	suite.watchProcessor.EXPECT().NotifyEventChange(gomock.Any()).AnyTimes()
+	suite.pool.AddOffers(context.Background(), []*mesos.Offer{
+		suite.createOffer(hostname, scalar.Resources{CPU: 1, Mem: 1, Disk: 1}),
+	})
+
+	taskID := "testjob-0-1"
+	suite.pool.addTaskToHost(taskID, hostname)
+
+	gomock.InOrder(
+		tracker.EXPECT().
+			RecordTaskState(hostname, taskID, task.TaskState_RUNNING),
+		tracker.EXPECT().
+			RecordTaskState(hostname, taskID, task.TaskState_FAILED),
+	)
+	suite.pool.UpdateTasksOnHost(taskID, task.TaskState_RUNNING, nil)
+	suite.pool.UpdateTasksOnHost(taskID, task.TaskState_FAILED, nil)
+
+	// Updates for tasks not known to be on any host are not recorded.
+	suite.pool.UpdateTasksOnHost(taskID, task.TaskState_LOST, nil)
+}
+
 func TestOfferPoolTestSuite(t *testing.T) {
 	suite.Run(t, new(OfferPoolTestSuite))
 }
@@ -20,4 +20,9 @@ type K8sConfig struct {
 
 	// Kubeconfig is the path to the kubeconfig file on the local filesystem.
 	Kubeconfig string `yaml:"kubeconfig"`
+
+	// RevocableResources enables reporting the observed usage of nodes
+	// from the metrics API, which is used to offer slack as revocable
+	// resources. Requires metrics-server to be deployed in the cluster.
+	RevocableResources bool `yaml:"revocable_resources"`
 }
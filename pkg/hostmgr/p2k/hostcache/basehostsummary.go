@@ -52,9 +52,8 @@ const (
 )
 
 const (
-	// hostHeldHostStatusTimeout is a timeout for resetting.
+	// hostHeldHostStatusTimeout is the default timeout for resetting.
 	// HeldHost status back to ReadyHost status.
-	// TODO: Make this configurable (T3312219).
 	hostHeldStatusTimeout = 3 * time.Minute
 
 	// emptyLeaseID is used when the host is in READY state.
@@ -67,7 +66,12 @@ type HostSummary interface {
 	TryMatch(filter *hostmgr.HostFilter) Match
 
 	// CompleteLease verifies that the leaseID on this host is still valid.
-	CompleteLease(leaseID string, newPodToResMap map[string]scalar.Resources) error
+	// Pods in newRevocablePodToResMap are placed on revocable resources.
+	CompleteLease(
+		leaseID string,
+		newPodToResMap map[string]scalar.Resources,
+		newRevocablePodToResMap map[string]scalar.Resources,
+	) error
 
 	// CasStatus sets the status to new value if current value is old, otherwise
 	// returns error.
@@ -88,6 +92,15 @@ type HostSummary interface {
 	// SetAvailable sets the available resource of the host.
 	SetAvailable(r scalar.Resources)
 
+	// GetRevocableAvailable returns the revocable resources available on
+	// the host.
+	GetRevocableAvailable() scalar.Resources
+
+	// SetUsage sets the observed usage of non-revocable pods on the host,
+	// and returns the revocable pods which need to be evicted because the
+	// slack shrank below what is allocated to revocable pods.
+	SetUsage(r scalar.Resources) []string
+
 	// GetVersion returns the version of the host.
 	GetVersion() string
 
@@ -110,10 +123,11 @@ type HostSummary interface {
 	// that affects this host.
 	HandlePodEvent(event *p2kscalar.PodEvent) error
 
-	// HoldForPod holds the host for the pod specified.
+	// HoldForPod holds the host for the pod specified until the timeout
+	// expires.
 	// If an error is returned, hostsummary would guarantee that
 	// the host is not held for the task.
-	HoldForPod(id *peloton.PodID) error
+	HoldForPod(id *peloton.PodID, timeout time.Duration) error
 
 	// ReleaseHoldForPod release the hold of host for the pod specified.
 	ReleaseHoldForPod(id *peloton.PodID)
@@ -133,7 +147,10 @@ type HostSummary interface {
 // methods in the interface assumes lock is taken
 type hostStrategy interface {
 	// postCompleteLease handles actions after lease is completed
-	postCompleteLease(newPodToResMap map[string]scalar.Resources) error
+	postCompleteLease(
+		newPodToResMap map[string]scalar.Resources,
+		newRevocablePodToResMap map[string]scalar.Resources,
+	) error
 }
 
 // baseHostSummary is a data struct holding resources and metadata of a host.
@@ -146,8 +163,8 @@ type baseHostSummary struct {
 	// labels on this host
 	labels []*peloton.Label
 
-	// a map of podIDs for which the host is held
-	// key is the podID, value is the expiration time of the hold
+	// a map of pod names for which the host is held
+	// key is the pod name, value is the expiration time of the hold
 	heldPodIDs map[string]time.Time
 
 	// locking status of this host
@@ -177,6 +194,9 @@ type baseHostSummary struct {
 
 	// available resources on the host
 	available scalar.Resources
+
+	// revocable resources available on the host
+	revocableAvailable scalar.Resources
 }
 
 // newBaseHostSummary returns a zero initialized HostSummary object.
@@ -235,8 +255,6 @@ func (a *baseHostSummary) TryMatch(
 		return Match{Result: result}
 	}
 
-	// TODO: Handle oversubscription
-
 	// Setting status to `PlacingHost`: this ensures proper state tracking of
 	// resources on the host and also ensures that this host will not be used by
 	// another placement engine before it is released.
@@ -260,6 +278,7 @@ func (a *baseHostSummary) TryMatch(
 func (a *baseHostSummary) CompleteLease(
 	leaseID string,
 	newPodToResMap map[string]scalar.Resources,
+	newRevocablePodToResMap map[string]scalar.Resources,
 ) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -276,13 +295,17 @@ func (a *baseHostSummary) CompleteLease(
 		return yarpcerrors.InvalidArgumentErrorf("failed to unlock host: %s", err)
 	}
 
-	if err := a.strategy.postCompleteLease(newPodToResMap); err != nil {
+	if err := a.strategy.postCompleteLease(
+		newPodToResMap,
+		newRevocablePodToResMap,
+	); err != nil {
 		return err
 	}
 
 	log.WithFields(log.Fields{
-		"hostname": a.hostname,
-		"pods":     newPodToResMap,
+		"hostname":       a.hostname,
+		"pods":           newPodToResMap,
+		"revocable_pods": newRevocablePodToResMap,
 	}).Debug("pods added to the host for launch")
 
 	return nil
@@ -343,9 +366,10 @@ func (a *baseHostSummary) GetHostLease() *hostmgr.HostLease {
 			Value: a.leaseID,
 		},
 		HostSummary: &pbhost.HostSummary{
-			Hostname:  a.hostname,
-			Resources: scalar.ToPelotonResources(a.available),
-			Labels:    a.labels,
+			Hostname:           a.hostname,
+			Resources:          scalar.ToPelotonResources(a.available),
+			RevocableResources: scalar.ToPelotonResources(a.revocableAvailable),
+			Labels:             a.labels,
 		},
 	}
 }
@@ -391,7 +415,10 @@ func (a *baseHostSummary) GetAllocated() scalar.Resources {
 
 // HoldForPod adds pod to heldPodIDs map when host is not reserved. It is noop
 // if pod ready exists in the map.
-func (a *baseHostSummary) HoldForPod(id *peloton.PodID) error {
+func (a *baseHostSummary) HoldForPod(
+	id *peloton.PodID,
+	timeout time.Duration,
+) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -399,8 +426,9 @@ func (a *baseHostSummary) HoldForPod(id *peloton.PodID) error {
 		return yarpcerrors.InvalidArgumentErrorf("invalid status %v for holding", a.status)
 	}
 
-	if _, ok := a.heldPodIDs[id.GetValue()]; !ok {
-		a.heldPodIDs[id.GetValue()] = time.Now().Add(hostHeldStatusTimeout)
+	key := podHoldKey(id)
+	if _, ok := a.heldPodIDs[key]; !ok {
+		a.heldPodIDs[key] = time.Now().Add(timeout)
 	}
 
 	log.WithFields(log.Fields{
@@ -456,7 +484,8 @@ func (a *baseHostSummary) DeleteExpiredHolds(
 }
 
 func (a *baseHostSummary) releaseHoldForPod(id *peloton.PodID) {
-	if _, ok := a.heldPodIDs[id.GetValue()]; !ok {
+	key := podHoldKey(id)
+	if _, ok := a.heldPodIDs[key]; !ok {
 		// This can happen for various reasons such as a task is launched again
 		// on the same host after timeout.
 		log.WithFields(log.Fields{
@@ -466,7 +495,7 @@ func (a *baseHostSummary) releaseHoldForPod(id *peloton.PodID) {
 		return
 	}
 
-	delete(a.heldPodIDs, id.GetValue())
+	delete(a.heldPodIDs, key)
 
 	log.WithFields(log.Fields{
 		"hostname": a.hostname,
@@ -487,6 +516,20 @@ func (a *baseHostSummary) GetAvailable() scalar.Resources {
 	return a.available
 }
 
+// GetRevocableAvailable returns the revocable resources available on the host.
+func (a *baseHostSummary) GetRevocableAvailable() scalar.Resources {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.revocableAvailable
+}
+
+// SetUsage is a noop for baseHostSummary, corresponding subclasses should
+// overwrite the method
+func (a *baseHostSummary) SetUsage(r scalar.Resources) []string {
+	return nil
+}
+
 // HandlePodEvent is a noop for baseHostSummary, corresponding subclasses should
 // overwrite the method
 func (a *baseHostSummary) HandlePodEvent(event *p2kscalar.PodEvent) error {
@@ -542,7 +585,14 @@ func (a *baseHostSummary) matchHostFilter(
 	if min != nil {
 		// Get min required resources.
 		minRes := scalar.FromResourceSpec(min)
-		if !a.available.Contains(minRes) {
+
+		// Revocable pods are placed on slack resources only.
+		available := a.available
+		if c.GetResourceConstraint().GetRevocable() {
+			available = a.revocableAvailable
+		}
+
+		if !available.Contains(minRes) {
 			return hostmgr.HostFilterResult_HOST_FILTER_INSUFFICIENT_RESOURCES
 		}
 	}
@@ -599,6 +649,9 @@ func (a *baseHostSummary) matchHostFilter(
 
 type noopHostStrategy struct{}
 
-func (s *noopHostStrategy) postCompleteLease(newPodToResMap map[string]scalar.Resources) error {
+func (s *noopHostStrategy) postCompleteLease(
+	newPodToResMap map[string]scalar.Resources,
+	newRevocablePodToResMap map[string]scalar.Resources,
+) error {
 	return nil
 }
@@ -313,7 +313,7 @@ func (suite *HostCacheTestSuite) TestHostSummaryCompleteLease() {
 		s.leaseID = tt.leaseID
 		s.capacity = _capacity
 
-		err := s.CompleteLease(tt.inputLeaseID, nil)
+		err := s.CompleteLease(tt.inputLeaseID, nil, nil)
 		if tt.errExpected {
 			suite.Error(err)
 			suite.Equal(tt.errMsg, err.Error(), "test case: %s", ttName)
@@ -347,7 +347,7 @@ func TestHoldForPod(t *testing.T) {
 			s.status = tc.status
 			s.heldPodIDs = tc.heldPodIDs
 
-			err := s.HoldForPod(tc.id)
+			err := s.HoldForPod(tc.id, hostHeldStatusTimeout)
 			if tc.errStr != "" {
 				require.Error(err, tc.errStr)
 				return
@@ -389,7 +389,7 @@ func TestGetHeldPods(t *testing.T) {
 	ids := map[string]struct{}{}
 	for i := 0; i < 10; i++ {
 		id := &peloton.PodID{Value: uuid.New()}
-		require.NoError(s.HoldForPod(id))
+		require.NoError(s.HoldForPod(id, hostHeldStatusTimeout))
 		ids[id.GetValue()] = struct{}{}
 	}
 	heldPods := s.GetHeldPods()
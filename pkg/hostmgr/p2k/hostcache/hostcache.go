@@ -19,8 +19,12 @@ import (
 	"time"
 
 	peloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	pbpod "github.com/uber/peloton/.gen/peloton/api/v1alpha/pod"
 	hostmgr "github.com/uber/peloton/.gen/peloton/private/hostmgr/v1alpha"
+	"github.com/uber/peloton/pkg/common/api"
 	"github.com/uber/peloton/pkg/common/lifecycle"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/hosthealth"
 	"github.com/uber/peloton/pkg/hostmgr/p2k/plugins"
 	"github.com/uber/peloton/pkg/hostmgr/p2k/scalar"
 	hmscalar "github.com/uber/peloton/pkg/hostmgr/scalar"
@@ -41,8 +45,14 @@ type HostCache interface {
 	TerminateLease(hostname string, leaseID string) error
 
 	// CompleteLease is called when launching pods on a host that has been
-	// previously leased to the Placement engine.
-	CompleteLease(hostname string, leaseID string, podToResMap map[string]hmscalar.Resources) error
+	// previously leased to the Placement engine. Pods in revocablePodToResMap
+	// are launched on revocable resources of the host.
+	CompleteLease(
+		hostname string,
+		leaseID string,
+		podToResMap map[string]hmscalar.Resources,
+		revocablePodToResMap map[string]hmscalar.Resources,
+	) error
 
 	// GetClusterCapacity gets the total capacity and allocation of the cluster.
 	GetClusterCapacity() (capacity, allocation hmscalar.Resources)
@@ -78,9 +88,12 @@ type hostCache struct {
 	// Map of hostname to HostSummary.
 	hostIndex map[string]HostSummary
 
-	// Map of podID to host held.
+	// Map of pod name to host held.
 	podHeldIndex map[string]string
 
+	// Period for which a host is held for a pod.
+	podHoldTimeout time.Duration
+
 	// The event channel on which the underlying cluster manager plugin will send
 	// host events to host cache.
 	hostEventCh chan *scalar.HostEvent
@@ -92,6 +105,10 @@ type hostCache struct {
 	// Cluster manager plugin.
 	plugin plugins.Plugin
 
+	// Host health tracker used to exclude quarantined hosts from leases.
+	// It is nil if quarantine is disabled.
+	hostHealthTracker hosthealth.Tracker
+
 	// Lifecycle manager.
 	lifecycle lifecycle.LifeCycle
 }
@@ -101,13 +118,22 @@ func New(
 	hostEventCh chan *scalar.HostEvent,
 	podEventCh chan *scalar.PodEvent,
 	plugin plugins.Plugin,
+	hostHealthTracker hosthealth.Tracker,
+	podHoldTimeout time.Duration,
 ) HostCache {
+	if podHoldTimeout == 0 {
+		podHoldTimeout = hostHeldStatusTimeout
+	}
+
 	return &hostCache{
-		hostIndex:   make(map[string]HostSummary),
-		hostEventCh: hostEventCh,
-		podEventCh:  podEventCh,
-		plugin:      plugin,
-		lifecycle:   lifecycle.NewLifeCycle(),
+		hostIndex:         make(map[string]HostSummary),
+		podHeldIndex:      make(map[string]string),
+		podHoldTimeout:    podHoldTimeout,
+		hostEventCh:       hostEventCh,
+		podEventCh:        podEventCh,
+		plugin:            plugin,
+		hostHealthTracker: hostHealthTracker,
+		lifecycle:         lifecycle.NewLifeCycle(),
 	}
 }
 
@@ -134,7 +160,7 @@ func (c *hostCache) AcquireLeases(
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	matcher := NewMatcher(hostFilter)
+	matcher := NewMatcher(hostFilter, c.hostHealthTracker)
 
 	// If host hint is provided, try to return the hosts in hints first.
 	for _, filterHints := range hostFilter.GetHint().GetHostHint() {
@@ -213,6 +239,7 @@ func (c *hostCache) CompleteLease(
 	hostname string,
 	leaseID string,
 	podToResMap map[string]hmscalar.Resources,
+	revocablePodToResMap map[string]hmscalar.Resources,
 ) error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -221,7 +248,11 @@ func (c *hostCache) CompleteLease(
 	if err != nil {
 		return err
 	}
-	if err := hs.CompleteLease(leaseID, podToResMap); err != nil {
+	if err := hs.CompleteLease(
+		leaseID,
+		podToResMap,
+		revocablePodToResMap,
+	); err != nil {
 		// TODO: metrics
 		return err
 	}
@@ -270,7 +301,7 @@ func (c *hostCache) GetHostHeldForPod(podID *peloton.PodID) string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	hn, ok := c.podHeldIndex[podID.GetValue()]
+	hn, ok := c.podHeldIndex[podHoldKey(podID)]
 	if !ok {
 		// TODO: this should return an error. But keep it the same way as in
 		// offerpool for now.
@@ -289,7 +320,7 @@ func (c *hostCache) HoldForPods(hostname string, podIDs []*peloton.PodID) error
 	}
 	var errs []error
 	for _, id := range podIDs {
-		if err := hs.HoldForPod(id); err != nil {
+		if err := hs.HoldForPod(id, c.podHoldTimeout); err != nil {
 			errs = append(errs, err)
 			continue
 		}
@@ -318,7 +349,8 @@ func (c *hostCache) ReleaseHoldForPods(hostname string, podIDs []*peloton.PodID)
 
 // addPodHold add a pod to podHeldIndex. Replace the old host if exists.
 func (c *hostCache) addPodHold(hostname string, id *peloton.PodID) {
-	old, ok := c.podHeldIndex[id.GetValue()]
+	key := podHoldKey(id)
+	old, ok := c.podHeldIndex[key]
 	if ok && old != hostname {
 		log.WithFields(log.Fields{
 			"new_host": hostname,
@@ -326,12 +358,24 @@ func (c *hostCache) addPodHold(hostname string, id *peloton.PodID) {
 			"task_id":  id.GetValue(),
 		}).Warn("pod is held by multiple hosts")
 	}
-	c.podHeldIndex[id.GetValue()] = hostname
+	c.podHeldIndex[key] = hostname
 }
 
 // removePodHold deletes id from podHeldIndex regardless of hostname.
 func (c *hostCache) removePodHold(id *peloton.PodID) {
-	delete(c.podHeldIndex, id.GetValue())
+	delete(c.podHeldIndex, podHoldKey(id))
+}
+
+// podHoldKey returns the key a hold for the pod is indexed by. The hold is
+// placed when a run of the pod is killed, and looked up when the next run is
+// launched, so it is keyed by the run-independent pod name (job ID and
+// instance ID). IDs which do not carry a run ID are used as is.
+func podHoldKey(id *peloton.PodID) string {
+	podName, err := util.ParseTaskIDFromMesosTaskID(id.GetValue())
+	if err != nil {
+		return id.GetValue()
+	}
+	return podName
 }
 
 // getSummary returns host summary given name. If the host does not exist,
@@ -362,6 +406,8 @@ func (c *hostCache) waitForHostEvents() {
 				c.deleteHost(event)
 			case scalar.UpdateHostAvailableRes:
 				c.updateHostAvailable(event)
+			case scalar.UpdateHostUsage:
+				c.evictPods(c.updateHostUsage(event))
 			}
 		case <-c.lifecycle.StopCh():
 			return
@@ -390,6 +436,15 @@ func (c *hostCache) handlePodEvent(event *scalar.PodEvent) {
 	defer c.mu.Unlock()
 
 	hostname := event.Event.GetHostname()
+	if c.hostHealthTracker != nil {
+		c.hostHealthTracker.RecordTaskState(
+			hostname,
+			event.Event.GetPodId().GetValue(),
+			api.ConvertPodStateToTaskState(
+				pbpod.PodState(pbpod.PodState_value[event.Event.GetActualState()])),
+		)
+	}
+
 	summary, found := c.hostIndex[hostname]
 	if !found {
 		// TODO(pourchet): Figure out how to handle this.
@@ -524,6 +579,9 @@ func (c *hostCache) deleteHost(event *scalar.HostEvent) {
 	}
 
 	delete(c.hostIndex, hostInfo.GetHostName())
+	if c.hostHealthTracker != nil {
+		c.hostHealthTracker.RemoveHost(hostInfo.GetHostName())
+	}
 	log.WithFields(log.Fields{
 		"hostname": hostInfo.GetHostName(),
 		"capacity": hostInfo.GetCapacity(),
@@ -559,6 +617,46 @@ func (c *hostCache) updateHostAvailable(event *scalar.HostEvent) {
 	}).Debug("update host in cache")
 }
 
+// updateHostUsage updates the observed usage of non-revocable pods on the
+// host, and returns the revocable pods which need to be evicted.
+func (c *hostCache) updateHostUsage(event *scalar.HostEvent) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	hostInfo := event.GetHostInfo()
+
+	hs, ok := c.hostIndex[hostInfo.GetHostName()]
+	if !ok {
+		log.WithFields(log.Fields{
+			"hostname": hostInfo.GetHostName(),
+			"usage":    hostInfo.GetUsage(),
+		}).Debug("ignore usage event, host not found in cache")
+		return nil
+	}
+
+	r := hmscalar.FromPelotonResources(hostInfo.GetUsage())
+	evicted := hs.SetUsage(r)
+	log.WithFields(log.Fields{
+		"hostname":            hostInfo.GetHostName(),
+		"usage":               hostInfo.GetUsage(),
+		"revocable_available": hs.GetRevocableAvailable(),
+	}).Debug("update host usage in cache")
+	return evicted
+}
+
+// evictPods kills the given revocable pods using the plugin. The resources
+// of these pods have already been released from the host summary, so
+// failures are only logged.
+func (c *hostCache) evictPods(podIDs []string) {
+	for _, podID := range podIDs {
+		if err := c.plugin.KillPod(podID); err != nil {
+			log.WithField("pod_id", podID).
+				WithError(err).
+				Error("failed to evict revocable pod")
+		}
+	}
+}
+
 // Start will start the goroutine that listens for host events.
 func (c *hostCache) Start() {
 	if !c.lifecycle.Start() {
@@ -25,8 +25,12 @@ import (
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/pod"
 	hostmgr "github.com/uber/peloton/.gen/peloton/private/hostmgr/v1alpha"
+	hosthealth_mocks "github.com/uber/peloton/pkg/hostmgr/hosthealth/mocks"
+	plugins_mocks "github.com/uber/peloton/pkg/hostmgr/p2k/plugins/mocks"
+	p2kscalar "github.com/uber/peloton/pkg/hostmgr/p2k/scalar"
 	"github.com/uber/peloton/pkg/hostmgr/scalar"
 
+	"github.com/golang/mock/gomock"
 	"github.com/pborman/uuid"
 	"github.com/stretchr/testify/require"
 )
@@ -133,6 +137,126 @@ func (suite *HostCacheTestSuite) TestAcquireLeases() {
 	}
 }
 
+// TestAcquireLeasesSkipsQuarantinedHosts tests that AcquireLeases does not
+// return leases on quarantined hosts
+func (suite *HostCacheTestSuite) TestAcquireLeasesSkipsQuarantinedHosts() {
+	ctrl := gomock.NewController(suite.T())
+	defer ctrl.Finish()
+
+	tracker := hosthealth_mocks.NewMockTracker(ctrl)
+	hc := &hostCache{
+		hostIndex:         make(map[string]HostSummary),
+		hostHealthTracker: tracker,
+	}
+	for _, s := range generateHostSummaries(2) {
+		hc.hostIndex[s.GetHostname()] = s
+	}
+
+	quarantined := fmt.Sprintf("%v%v", _hostname, 0)
+	healthy := fmt.Sprintf("%v%v", _hostname, 1)
+	tracker.EXPECT().IsQuarantined(quarantined).Return(true)
+	tracker.EXPECT().IsQuarantined(healthy).Return(false)
+
+	leases, filterResult := hc.AcquireLeases(&hostmgr.HostFilter{
+		ResourceConstraint: &hostmgr.ResourceConstraint{
+			Minimum: &pod.ResourceSpec{
+				CpuLimit:   1.0,
+				MemLimitMb: 1.0,
+			},
+		},
+	})
+	suite.Len(leases, 1)
+	suite.Equal(healthy, leases[0].GetHostSummary().GetHostname())
+	suite.Equal(map[string]uint32{
+		strings.ToLower("HOST_FILTER_MATCH"):                1,
+		strings.ToLower("HOST_FILTER_MISMATCH_QUARANTINED"): 1,
+	}, filterResult)
+}
+
+// TestUpdateHostUsage tests host usage events update revocable resources and
+// evict revocable pods under resource pressure.
+func (suite *HostCacheTestSuite) TestUpdateHostUsage() {
+	ctrl := gomock.NewController(suite.T())
+	defer ctrl.Finish()
+
+	plugin := plugins_mocks.NewMockPlugin(ctrl)
+	hc := &hostCache{
+		hostIndex: make(map[string]HostSummary),
+		plugin:    plugin,
+	}
+	hs := newKubeletHostSummary(
+		_hostname,
+		&peloton.Resources{Cpu: 10, MemMb: 100},
+		_version,
+	)
+	hc.hostIndex[_hostname] = hs
+
+	hs.CasStatus(ReadyHost, PlacingHost)
+	suite.NoError(hc.CompleteLease(
+		_hostname,
+		hs.GetHostLease().GetLeaseId().GetValue(),
+		map[string]scalar.Resources{"pod-1": {CPU: 8, Mem: 80}},
+		nil,
+	))
+
+	// Usage event for unknown host is ignored.
+	suite.Empty(hc.updateHostUsage(
+		p2kscalar.BuildHostEventFromUsage("unknown", nil)))
+
+	suite.Empty(hc.updateHostUsage(p2kscalar.BuildHostEventFromUsage(
+		_hostname, &peloton.Resources{Cpu: 2, MemMb: 20})))
+	suite.Equal(scalar.Resources{CPU: 6, Mem: 60}, hs.GetRevocableAvailable())
+
+	// Acquire revocable resources on the host.
+	leases, _ := hc.AcquireLeases(&hostmgr.HostFilter{
+		ResourceConstraint: &hostmgr.ResourceConstraint{
+			Minimum: &pod.ResourceSpec{
+				CpuLimit:   4,
+				MemLimitMb: 40,
+			},
+			Revocable: true,
+		},
+	})
+	suite.Len(leases, 1)
+	suite.NoError(hc.CompleteLease(
+		_hostname,
+		leases[0].GetLeaseId().GetValue(),
+		nil,
+		map[string]scalar.Resources{"revocable-1": {CPU: 4, Mem: 40}},
+	))
+
+	// Usage grows and the revocable pod gets evicted.
+	evicted := hc.updateHostUsage(p2kscalar.BuildHostEventFromUsage(
+		_hostname, &peloton.Resources{Cpu: 6, MemMb: 60}))
+	suite.Equal([]string{"revocable-1"}, evicted)
+
+	plugin.EXPECT().KillPod("revocable-1").Return(nil)
+	hc.evictPods(evicted)
+}
+
+// TestDeleteHost tests that a deleted host is removed from the cache and
+// forgotten by the host health tracker
+func (suite *HostCacheTestSuite) TestDeleteHost() {
+	ctrl := gomock.NewController(suite.T())
+	defer ctrl.Finish()
+
+	tracker := hosthealth_mocks.NewMockTracker(ctrl)
+	hc := &hostCache{
+		hostIndex:         make(map[string]HostSummary),
+		hostHealthTracker: tracker,
+	}
+	hc.hostIndex[_hostname] = newKubeletHostSummary(
+		_hostname,
+		&peloton.Resources{Cpu: 10, MemMb: 100},
+		"",
+	)
+
+	tracker.EXPECT().RemoveHost(_hostname)
+	hc.deleteHost(p2kscalar.BuildHostEventFromResource(
+		_hostname, nil, p2kscalar.DeleteHost))
+	suite.Empty(hc.hostIndex)
+}
+
 // TestGetClusterCapacity tests the host cache GetClusterCapacity API
 func (suite *HostCacheTestSuite) TestGetClusterCapacity() {
 	hosts := generateHostSummaries(10)
@@ -243,6 +367,7 @@ func (suite *HostCacheTestSuite) TestTerminateLease() {
 				lease.GetHostSummary().GetHostname(),
 				lease.GetLeaseId().GetValue(),
 				tt.podToResMap,
+				nil,
 			)
 			suite.Error(err, "test case %s", ttName)
 		}
@@ -302,6 +427,7 @@ func (suite *HostCacheTestSuite) TestCompleteLease() {
 				lease.GetHostSummary().GetHostname(),
 				lease.GetLeaseId().GetValue(),
 				tt.podToResMap,
+				nil,
 			)
 			suite.NoError(err, "test case %s", ttName)
 		}
@@ -366,6 +492,7 @@ func (suite *HostCacheTestSuite) TestCompleteLeaseErrors() {
 			tt.hostname,
 			tt.leaseID,
 			podToResMap,
+			nil,
 		)
 
 		suite.Error(err, "test case %s", ttName)
@@ -484,6 +611,29 @@ func TestReleaseHoldForPods(t *testing.T) {
 	require.Empty(hc.podHeldIndex)
 }
 
+// TestHoldForPodsNextRun tests the host held when run N of a pod is killed
+// is found and released when run N+1 of the pod is launched.
+func TestHoldForPodsNextRun(t *testing.T) {
+	require := require.New(t)
+	hs := generateHostSummaries(1)[0]
+	podName := uuid.New() + "-0"
+	killedPodID := &peloton.PodID{Value: podName + "-1"}
+	launchedPodID := &peloton.PodID{Value: podName + "-2"}
+	hc := &hostCache{
+		hostIndex:    map[string]HostSummary{hs.GetHostname(): hs},
+		podHeldIndex: map[string]string{},
+	}
+
+	require.NoError(hc.HoldForPods(hs.GetHostname(), []*peloton.PodID{killedPodID}))
+	require.Equal(map[string]string{podName: hs.GetHostname()}, hc.podHeldIndex)
+	require.Equal(hs.GetHostname(), hc.GetHostHeldForPod(launchedPodID))
+
+	require.NoError(hc.ReleaseHoldForPods(hs.GetHostname(), []*peloton.PodID{launchedPodID}))
+	require.Empty(hc.podHeldIndex)
+	require.Empty(hs.GetHeldPods())
+	require.Empty(hc.GetHostHeldForPod(killedPodID))
+}
+
 // TODO: move to use mock after host summary is moved to a different package.
 func TestResetExpiredHeldHostSummaries(t *testing.T) {
 	require := require.New(t)
@@ -502,3 +652,22 @@ func TestResetExpiredHeldHostSummaries(t *testing.T) {
 	require.Equal(hs.GetHostname(), ret[0])
 	require.Empty(hc.podHeldIndex)
 }
+
+// TestHoldForPodsTimeout tests the host is held for the pod until the
+// configured timeout expires.
+func TestHoldForPodsTimeout(t *testing.T) {
+	require := require.New(t)
+	hs := generateHostSummaries(1)[0]
+	podID := &peloton.PodID{Value: uuid.New()}
+	hc := New(nil, nil, nil, nil, 10*time.Minute).(*hostCache)
+	hc.hostIndex[hs.GetHostname()] = hs
+
+	now := time.Now()
+	require.NoError(hc.HoldForPods(hs.GetHostname(), []*peloton.PodID{podID}))
+	require.Empty(hc.ResetExpiredHeldHostSummaries(now.Add(5 * time.Minute)))
+	require.Equal(hs.GetHostname(), hc.GetHostHeldForPod(podID))
+
+	ret := hc.ResetExpiredHeldHostSummaries(now.Add(11 * time.Minute))
+	require.Equal([]string{hs.GetHostname()}, ret)
+	require.Empty(hc.GetHostHeldForPod(podID))
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
 	p2kscalar "github.com/uber/peloton/pkg/hostmgr/p2k/scalar"
@@ -38,6 +39,22 @@ type kubeletHostSummary struct {
 
 	// pod map of PodID to resources for pods that run on this host
 	podToResMap map[string]scalar.Resources
+
+	// pod map of PodID to resources for revocable pods that run on this
+	// host. Revocable pods are placed on slack resources, and are not
+	// counted in allocated.
+	revocablePodToResMap map[string]scalar.Resources
+
+	// resources allocated to revocable pods on the host. this should always
+	// be equal to the sum of resources in revocablePodToResMap
+	revocableAllocated scalar.Resources
+
+	// observed resource usage of non-revocable pods on the host
+	usage scalar.Resources
+
+	// revocable pods which are being evicted, their resources have already
+	// been released from the host summary
+	evictingPods map[string]struct{}
 }
 
 // newKubeletHostSummary returns a zero initialized HostSummary object.
@@ -48,8 +65,10 @@ func newKubeletHostSummary(
 ) HostSummary {
 	rs := scalar.FromPelotonResources(r)
 	ks := &kubeletHostSummary{
-		podToResMap:     make(map[string]scalar.Resources),
-		baseHostSummary: newBaseHostSummary(hostname, version),
+		podToResMap:          make(map[string]scalar.Resources),
+		revocablePodToResMap: make(map[string]scalar.Resources),
+		evictingPods:         make(map[string]struct{}),
+		baseHostSummary:      newBaseHostSummary(hostname, version),
 	}
 	ks.baseHostSummary.capacity = rs
 	ks.baseHostSummary.strategy = ks
@@ -95,6 +114,32 @@ func (a *kubeletHostSummary) SetCapacity(r scalar.Resources) {
 	a.available = a.calculateAvailable()
 }
 
+// SetUsage sets the observed usage of non-revocable pods on the host.
+// Slack is the part of the resources allocated to non-revocable pods which
+// is not used, and is offered to revocable pods. When usage grows such that
+// the slack can no longer hold the revocable pods, the revocable pods to be
+// evicted are returned. Their resources are released right away so that
+// the host can be used for placement while the pods are being killed.
+func (a *kubeletHostSummary) SetUsage(r scalar.Resources) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.usage = r
+	evicted := a.evictRevocablePods()
+	a.calculateRevocableAvailable()
+
+	if len(evicted) > 0 {
+		log.WithFields(log.Fields{
+			"hostname":            a.hostname,
+			"usage":               a.usage,
+			"allocated":           a.allocated,
+			"revocable_allocated": a.revocableAllocated,
+			"evicted_pods":        evicted,
+		}).Info("evicting revocable pods due to resource pressure")
+	}
+	return evicted
+}
+
 // SetAvailable is noop for k8s agent, since it is calculated on-flight
 func (a *kubeletHostSummary) SetAvailable(r scalar.Resources) {
 	a.mu.RLock()
@@ -116,6 +161,18 @@ func (a *kubeletHostSummary) releasePodResources(
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	if _, ok := a.evictingPods[podID]; ok {
+		// Resources of evicted pods are released at eviction.
+		delete(a.evictingPods, podID)
+		return
+	}
+
+	if _, ok := a.revocablePodToResMap[podID]; ok {
+		delete(a.revocablePodToResMap, podID)
+		a.calculateAllocated()
+		return
+	}
+
 	if _, ok := a.podToResMap[podID]; !ok {
 		// TODO: add failure metric
 		log.WithField("podID", podID).Error("pod not found in host summary")
@@ -125,6 +182,62 @@ func (a *kubeletHostSummary) releasePodResources(
 	a.calculateAllocated()
 }
 
+// calculateSlack returns the resources allocated to non-revocable pods
+// which are not being used.
+// This function assumes baseHostSummary lock is held before calling.
+func (a *kubeletHostSummary) calculateSlack() scalar.Resources {
+	return a.allocated.Subtract(scalar.Minimum(a.allocated, a.usage))
+}
+
+// calculateRevocableAvailable calculates the revocable resources available
+// on the host, which is the slack not yet allocated to revocable pods.
+// This function assumes baseHostSummary lock is held before calling.
+func (a *kubeletHostSummary) calculateRevocableAvailable() {
+	slack := a.calculateSlack()
+	a.revocableAvailable = slack.Subtract(
+		scalar.Minimum(slack, a.revocableAllocated))
+}
+
+// evictRevocablePods picks revocable pods to evict until the remaining
+// revocable pods fit in the slack. Larger pods are picked first to evict as
+// few pods as possible.
+// This function assumes baseHostSummary lock is held before calling.
+func (a *kubeletHostSummary) evictRevocablePods() []string {
+	slack := a.calculateSlack()
+	if slack.Contains(a.revocableAllocated) {
+		return nil
+	}
+
+	podIDs := make([]string, 0, len(a.revocablePodToResMap))
+	for podID := range a.revocablePodToResMap {
+		podIDs = append(podIDs, podID)
+	}
+	sort.Slice(podIDs, func(i, j int) bool {
+		ri := a.revocablePodToResMap[podIDs[i]]
+		rj := a.revocablePodToResMap[podIDs[j]]
+		if ri.GetCPU() != rj.GetCPU() {
+			return ri.GetCPU() > rj.GetCPU()
+		}
+		if ri.GetMem() != rj.GetMem() {
+			return ri.GetMem() > rj.GetMem()
+		}
+		return podIDs[i] < podIDs[j]
+	})
+
+	var evicted []string
+	for _, podID := range podIDs {
+		if slack.Contains(a.revocableAllocated) {
+			break
+		}
+		a.revocableAllocated = a.revocableAllocated.Subtract(
+			a.revocablePodToResMap[podID])
+		delete(a.revocablePodToResMap, podID)
+		a.evictingPods[podID] = struct{}{}
+		evicted = append(evicted, podID)
+	}
+	return evicted
+}
+
 func (a *kubeletHostSummary) calculateAvailable() scalar.Resources {
 	available, ok := a.capacity.TrySubtract(a.allocated)
 	if !ok {
@@ -142,18 +255,24 @@ func (a *kubeletHostSummary) calculateAvailable() scalar.Resources {
 	return available
 }
 
-func (a *kubeletHostSummary) postCompleteLease(newPodToResMap map[string]scalar.Resources) error {
+func (a *kubeletHostSummary) postCompleteLease(
+	newPodToResMap map[string]scalar.Resources,
+	newRevocablePodToResMap map[string]scalar.Resources,
+) error {
 	// At this point the lease is terminated, the host is back in ready/held
 	// status but we need to validate if the new pods can be successfully
 	// launched on this host. Note that the lease has to be terminated before
 	// this step irrespective of the outcome
-	if err := a.validateNewPods(newPodToResMap); err != nil {
+	if err := a.validateNewPods(
+		newPodToResMap,
+		newRevocablePodToResMap,
+	); err != nil {
 		return yarpcerrors.InvalidArgumentErrorf("pod validation failed: %s", err)
 	}
 
 	// Update podToResMap with newPodToResMap for the new pods to be launched
 	// Reduce available resources by the resources required by the new pods
-	a.updatePodToResMap(newPodToResMap)
+	a.updatePodToResMap(newPodToResMap, newRevocablePodToResMap)
 
 	return nil
 }
@@ -161,24 +280,49 @@ func (a *kubeletHostSummary) postCompleteLease(newPodToResMap map[string]scalar.
 // validateNewPods will return an error if:
 // 1. The pod already exists on the host map.
 // 2. The host has insufficient resources to place new pods.
+// 3. The host has insufficient revocable resources to place new revocable
+// pods.
 // This function assumes baseHostSummary lock is held before calling.
 func (a *kubeletHostSummary) validateNewPods(
 	newPodToResMap map[string]scalar.Resources,
+	newRevocablePodToResMap map[string]scalar.Resources,
 ) error {
-	var needed scalar.Resources
+	var needed, revocableNeeded scalar.Resources
 
 	for podID, res := range newPodToResMap {
-		if _, ok := a.podToResMap[podID]; ok {
+		if a.hasPod(podID) {
 			return fmt.Errorf("pod %v already exists on the host", podID)
 		}
 		needed = needed.Add(res)
 	}
+	for podID, res := range newRevocablePodToResMap {
+		if a.hasPod(podID) {
+			return fmt.Errorf("pod %v already exists on the host", podID)
+		}
+		if _, ok := newPodToResMap[podID]; ok {
+			return fmt.Errorf("pod %v is both revocable and non-revocable", podID)
+		}
+		revocableNeeded = revocableNeeded.Add(res)
+	}
 	if !a.available.Contains(needed) {
 		return errors.New("host has insufficient resources")
 	}
+	if !a.revocableAvailable.Contains(revocableNeeded) {
+		return errors.New("host has insufficient revocable resources")
+	}
 	return nil
 }
 
+// hasPod returns true if the pod is already accounted on the host.
+// This function assumes baseHostSummary lock is held before calling.
+func (a *kubeletHostSummary) hasPod(podID string) bool {
+	if _, ok := a.podToResMap[podID]; ok {
+		return true
+	}
+	_, ok := a.revocablePodToResMap[podID]
+	return ok
+}
+
 // calculateAllocated walks through the current list of pods on this host and
 // calculates total allocated resources.
 // This function assumes baseHostSummary lock is held before calling.
@@ -191,6 +335,13 @@ func (a *kubeletHostSummary) calculateAllocated() {
 		allocated = allocated.Add(r)
 	}
 	a.allocated = allocated
+
+	var revocableAllocated scalar.Resources
+	for _, r := range a.revocablePodToResMap {
+		revocableAllocated = revocableAllocated.Add(r)
+	}
+	a.revocableAllocated = revocableAllocated
+	a.calculateRevocableAvailable()
 	a.available, ok = a.capacity.TrySubtract(allocated)
 	if !ok {
 		// continue with available set to scalar.Resources{}. This would
@@ -214,11 +365,15 @@ func (a *kubeletHostSummary) calculateAllocated() {
 // This function assumes baseHostSummary lock is held before calling.
 func (a *kubeletHostSummary) updatePodToResMap(
 	newPodToResMap map[string]scalar.Resources,
+	newRevocablePodToResMap map[string]scalar.Resources,
 ) {
 	// Add new pods to the pods map.
 	for podID, res := range newPodToResMap {
 		a.podToResMap[podID] = res
 	}
+	for podID, res := range newRevocablePodToResMap {
+		a.revocablePodToResMap[podID] = res
+	}
 	a.calculateAllocated()
 
 }
@@ -177,7 +177,7 @@ func (suite *HostCacheTestSuite) TestKubeletHostSummaryHandlePodEvent() {
 					continue
 				}
 				matches.Inc()
-				err := s.CompleteLease(s.leaseID, resMap)
+				err := s.CompleteLease(s.leaseID, resMap, nil)
 				suite.NoError(err)
 
 				if old := eventHandled.Swap(true); old {
@@ -306,7 +306,7 @@ func (suite *HostCacheTestSuite) TestKubeletHostSummaryCompleteLease() {
 			tt.podToResMap[tt.preExistingPodID] = scalar.Resources{}
 		}
 
-		err := s.CompleteLease(tt.inputLeaseID, tt.podToResMap)
+		err := s.CompleteLease(tt.inputLeaseID, tt.podToResMap, nil)
 		if tt.errExpected {
 			suite.Error(err, "test case: %s", ttName)
 			suite.Equal(tt.errMsg, err.Error(), "test case: %s", ttName)
@@ -317,3 +317,125 @@ func (suite *HostCacheTestSuite) TestKubeletHostSummaryCompleteLease() {
 		suite.Equal(tt.afterStatus, s.GetHostStatus(), "test case: %s", ttName)
 	}
 }
+
+// TestKubeletHostSummaryRevocable tests revocable resources are calculated
+// from the observed usage, and revocable pods are accounted separately.
+func (suite *HostCacheTestSuite) TestKubeletHostSummaryRevocable() {
+	s := newKubeletHostSummary(
+		_hostname,
+		&peloton.Resources{Cpu: 10, MemMb: 100},
+		_version,
+	).(*kubeletHostSummary)
+
+	revocableFilter := &hostmgr.HostFilter{
+		ResourceConstraint: &hostmgr.ResourceConstraint{
+			Minimum: &pod.ResourceSpec{
+				CpuLimit:   2,
+				MemLimitMb: 20,
+			},
+			Revocable: true,
+		},
+	}
+
+	// No slack on an empty host.
+	suite.Equal(scalar.Resources{}, s.GetRevocableAvailable())
+	suite.Equal(
+		hostmgr.HostFilterResult_HOST_FILTER_INSUFFICIENT_RESOURCES,
+		s.TryMatch(revocableFilter).Result)
+
+	// Launch a non-revocable pod which uses only part of its allocation.
+	match := s.TryMatch(&hostmgr.HostFilter{})
+	suite.Equal(hostmgr.HostFilterResult_HOST_FILTER_MATCH, match.Result)
+	suite.NoError(s.CompleteLease(s.leaseID, map[string]scalar.Resources{
+		"pod-1": {CPU: 6, Mem: 60},
+	}, nil))
+	suite.Empty(s.SetUsage(scalar.Resources{CPU: 2, Mem: 20}))
+	suite.Equal(scalar.Resources{CPU: 4, Mem: 40}, s.GetRevocableAvailable())
+
+	// Launch revocable pods on the slack.
+	match = s.TryMatch(revocableFilter)
+	suite.Equal(hostmgr.HostFilterResult_HOST_FILTER_MATCH, match.Result)
+	suite.Equal(
+		&peloton.Resources{Cpu: 4, MemMb: 40},
+		s.GetHostLease().GetHostSummary().GetRevocableResources())
+	suite.NoError(s.CompleteLease(s.leaseID, nil, map[string]scalar.Resources{
+		"revocable-1": {CPU: 1, Mem: 10},
+		"revocable-2": {CPU: 2, Mem: 20},
+	}))
+
+	// Revocable pods do not consume non-revocable resources.
+	suite.Equal(scalar.Resources{CPU: 6, Mem: 60}, s.GetAllocated())
+	suite.Equal(scalar.Resources{CPU: 4, Mem: 40}, s.GetAvailable())
+	suite.Equal(scalar.Resources{CPU: 1, Mem: 10}, s.GetRevocableAvailable())
+
+	// Revocable pods cannot exceed the slack.
+	match = s.TryMatch(&hostmgr.HostFilter{})
+	suite.Equal(hostmgr.HostFilterResult_HOST_FILTER_MATCH, match.Result)
+	err := s.CompleteLease(s.leaseID, nil, map[string]scalar.Resources{
+		"revocable-3": {CPU: 2, Mem: 20},
+	})
+	suite.EqualError(
+		err,
+		"code:invalid-argument message:pod validation failed: "+
+			"host has insufficient revocable resources")
+
+	// Deleting a revocable pod gives back the slack.
+	suite.NoError(s.HandlePodEvent(&p2kscalar.PodEvent{
+		EventType: p2kscalar.DeletePod,
+		Event: &pod.PodEvent{
+			PodId: &peloton.PodID{Value: "revocable-1"},
+		},
+	}))
+	suite.Equal(scalar.Resources{CPU: 2, Mem: 20}, s.GetRevocableAvailable())
+}
+
+// TestKubeletHostSummarySetUsageEvictsRevocablePods tests revocable pods are
+// evicted when the slack shrinks under resource pressure.
+func (suite *HostCacheTestSuite) TestKubeletHostSummarySetUsageEvictsRevocablePods() {
+	s := newKubeletHostSummary(
+		_hostname,
+		&peloton.Resources{Cpu: 10, MemMb: 100},
+		_version,
+	).(*kubeletHostSummary)
+
+	s.TryMatch(&hostmgr.HostFilter{})
+	suite.NoError(s.CompleteLease(s.leaseID, map[string]scalar.Resources{
+		"pod-1": {CPU: 8, Mem: 80},
+	}, nil))
+	suite.Empty(s.SetUsage(scalar.Resources{}))
+
+	s.TryMatch(&hostmgr.HostFilter{})
+	suite.NoError(s.CompleteLease(s.leaseID, nil, map[string]scalar.Resources{
+		"revocable-1": {CPU: 1, Mem: 10},
+		"revocable-2": {CPU: 3, Mem: 30},
+		"revocable-3": {CPU: 2, Mem: 20},
+	}))
+
+	// Usage grows so that the slack only fits 3 CPU of revocable pods. The
+	// largest revocable pod is evicted first.
+	suite.Equal(
+		[]string{"revocable-2"},
+		s.SetUsage(scalar.Resources{CPU: 5, Mem: 50}))
+	suite.Equal(scalar.Resources{CPU: 3, Mem: 30}, s.revocableAllocated)
+	suite.Equal(scalar.Resources{}, s.GetRevocableAvailable())
+
+	// The same usage does not evict any more pods.
+	suite.Empty(s.SetUsage(scalar.Resources{CPU: 5, Mem: 50}))
+
+	// The delete event of the evicted pod is a noop.
+	suite.NoError(s.HandlePodEvent(&p2kscalar.PodEvent{
+		EventType: p2kscalar.DeletePod,
+		Event: &pod.PodEvent{
+			PodId: &peloton.PodID{Value: "revocable-2"},
+		},
+	}))
+	suite.Equal(scalar.Resources{CPU: 3, Mem: 30}, s.revocableAllocated)
+	suite.Empty(s.evictingPods)
+
+	// Non-revocable pods use all their allocation, evict all revocable pods.
+	suite.ElementsMatch(
+		[]string{"revocable-1", "revocable-3"},
+		s.SetUsage(scalar.Resources{CPU: 8, Mem: 80}))
+	suite.Equal(scalar.Resources{}, s.revocableAllocated)
+	suite.Equal(scalar.Resources{CPU: 2, Mem: 20}, s.GetAvailable())
+}
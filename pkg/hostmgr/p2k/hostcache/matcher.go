@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	hostmgr "github.com/uber/peloton/.gen/peloton/private/hostmgr/v1alpha"
+	"github.com/uber/peloton/pkg/hostmgr/hosthealth"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -46,6 +47,9 @@ type Match struct {
 type Matcher struct {
 	hostFilter *hostmgr.HostFilter
 
+	// Tracker of quarantined hosts, nil if quarantine is disabled.
+	hostHealthTracker hosthealth.Tracker
+
 	// List of host names that match the filter.
 	hostNames []string
 
@@ -75,6 +79,10 @@ func (m *Matcher) tryMatchImpl(
 		return hostmgr.HostFilterResult_HOST_FILTER_MISMATCH_MAX_HOST_LIMIT
 	}
 
+	if m.hostHealthTracker != nil && m.hostHealthTracker.IsQuarantined(hostname) {
+		return hostmgr.HostFilterResult_HOST_FILTER_MISMATCH_QUARANTINED
+	}
+
 	// try to match host filter with this particular host
 	match := s.TryMatch(m.hostFilter)
 	log.WithFields(log.Fields{
@@ -97,9 +105,13 @@ func (m *Matcher) hostLimitReached() bool {
 }
 
 // NewMatcher returns a new instance of Matcher.
-func NewMatcher(hostFilter *hostmgr.HostFilter) *Matcher {
+func NewMatcher(
+	hostFilter *hostmgr.HostFilter,
+	hostHealthTracker hosthealth.Tracker,
+) *Matcher {
 	return &Matcher{
-		hostFilter:   hostFilter,
-		filterCounts: make(map[string]uint32),
+		hostFilter:        hostFilter,
+		hostHealthTracker: hostHealthTracker,
+		filterCounts:      make(map[string]uint32),
 	}
 }
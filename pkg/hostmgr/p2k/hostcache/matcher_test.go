@@ -137,7 +137,7 @@ func (suite *HostCacheTestSuite) TestTryMatch() {
 		// are in ReadyHost state
 		hosts := generateHostSummaries(10)
 
-		matcher := NewMatcher(tt.filter)
+		matcher := NewMatcher(tt.filter, nil)
 
 		// Run matcher on all hosts
 		for _, hs := range hosts {
@@ -78,7 +78,10 @@ func (a *mesosHostSummary) SetAvailable(r scalar.Resources) {
 }
 
 // postCompleteLease handles actions after lease is completed
-func (a *mesosHostSummary) postCompleteLease(newPodToResMap map[string]scalar.Resources) error {
+func (a *mesosHostSummary) postCompleteLease(
+	newPodToResMap map[string]scalar.Resources,
+	newRevocablePodToResMap map[string]scalar.Resources,
+) error {
 	// noop for mesos
 	return nil
 }
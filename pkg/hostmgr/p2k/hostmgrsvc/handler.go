@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
 	hostmgr "github.com/uber/peloton/.gen/peloton/private/hostmgr/v1alpha"
 	v1alpha "github.com/uber/peloton/.gen/peloton/private/hostmgr/v1alpha"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/v1alpha/svc"
@@ -120,14 +121,25 @@ func (h *ServiceHandler) LaunchPods(
 		return nil, err
 	}
 
-	// TODO: handle held pods for in-place updates.
+	// Pods held on other hosts are not launched in-place, release the hold
+	// on these hosts.
+	var podIDs []*peloton.PodID
+	for _, pod := range req.GetPods() {
+		podIDs = append(podIDs, pod.GetPodId())
+	}
+	h.releaseHostsHeldForPods(podIDs, req.GetHostname())
 
 	// Convert LaunchablePods to a map of podID to scalar resources before
-	// completing the lease.
+	// completing the lease. Revocable pods are accounted separately.
 	podToResMap := make(map[string]scalar.Resources)
+	revocablePodToResMap := make(map[string]scalar.Resources)
 	for _, pod := range req.GetPods() {
 		// TODO: Should we check for repeat podID here?
-		podToResMap[pod.GetPodId().GetValue()] = scalar.FromPodSpec(
+		resMap := podToResMap
+		if pod.GetSpec().GetRevocable() {
+			resMap = revocablePodToResMap
+		}
+		resMap[pod.GetPodId().GetValue()] = scalar.FromPodSpec(
 			pod.GetSpec(),
 		)
 	}
@@ -136,14 +148,16 @@ func (h *ServiceHandler) LaunchPods(
 		req.GetHostname(),
 		req.GetLeaseId().GetValue(),
 		podToResMap,
+		revocablePodToResMap,
 	); err != nil {
 		return nil, err
 	}
 
 	log.WithFields(log.Fields{
-		"lease_id": req.GetLeaseId().GetValue(),
-		"hostname": req.GetHostname(),
-		"pods":     podToResMap,
+		"lease_id":       req.GetLeaseId().GetValue(),
+		"hostname":       req.GetHostname(),
+		"pods":           podToResMap,
+		"revocable_pods": revocablePodToResMap,
 	}).Debug("LaunchPods success")
 
 	// Resource accounting done. Now launch pod.
@@ -166,6 +180,9 @@ func (h *ServiceHandler) LaunchPods(
 		}
 	}
 
+	// Pods held on the host are now launched in-place, release the hold.
+	h.releaseHostsHeldForPods(podIDs, "")
+
 	return &svc.LaunchPodsResponse{}, nil
 }
 
@@ -186,6 +203,12 @@ func (h *ServiceHandler) KillPods(
 		"pod_id": req.GetPodIds(),
 	}).Debug("KillPods success")
 
+	// Release the hold on hosts even if some kill fails, because it is not
+	// certain if the kill request does go through. Worst case for releasing
+	// the host when a pod is not killed is in-place update fails to place
+	// the pod on the desired host.
+	defer h.releaseHostsHeldForPods(req.GetPodIds(), "")
+
 	for _, podID := range req.GetPodIds() {
 		err := h.plugin.KillPod(podID.GetValue())
 		if err != nil {
@@ -195,6 +218,63 @@ func (h *ServiceHandler) KillPods(
 	return &svc.KillPodsResponse{}, nil
 }
 
+// KillAndHoldPods implements HostManagerService.KillAndHoldPods.
+func (h *ServiceHandler) KillAndHoldPods(
+	ctx context.Context,
+	req *svc.KillAndHoldPodsRequest,
+) (resp *svc.KillAndHoldPodsResponse, err error) {
+	defer func() {
+		if err != nil {
+			log.WithField("entries", req.GetEntries()).
+				WithError(err).
+				Warn("HostMgr.KillAndHoldPods failed")
+		}
+	}()
+
+	heldHostToPodIDs := make(map[string][]*peloton.PodID)
+	for _, entry := range req.GetEntries() {
+		if entry.GetHostToHold() == "" {
+			continue
+		}
+		heldHostToPodIDs[entry.GetHostToHold()] = append(
+			heldHostToPodIDs[entry.GetHostToHold()], entry.GetPodId())
+	}
+
+	// Hold the hosts first. Failure to hold a host should not fail the
+	// kill, it just fails the in-place update and moves on to kill the pod.
+	for hostname, podIDs := range heldHostToPodIDs {
+		if err := h.hostCache.HoldForPods(hostname, podIDs); err != nil {
+			log.WithFields(log.Fields{
+				"hostname": hostname,
+				"pod_ids":  podIDs,
+			}).WithError(err).
+				Warn("fail to hold the host")
+		}
+	}
+
+	// Then kill the pods.
+	for _, entry := range req.GetEntries() {
+		if err = h.plugin.KillPod(entry.GetPodId().GetValue()); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		return &svc.KillAndHoldPodsResponse{}, nil
+	}
+
+	// If pod kill fails, release the hold on the hosts.
+	for hostname, podIDs := range heldHostToPodIDs {
+		if err := h.hostCache.ReleaseHoldForPods(hostname, podIDs); err != nil {
+			log.WithFields(log.Fields{
+				"hostname": hostname,
+				"pod_ids":  podIDs,
+			}).WithError(err).
+				Warn("fail to release hold on host after pod kill fail")
+		}
+	}
+	return nil, err
+}
+
 // ClusterCapacity implements HostManagerService.ClusterCapacity.
 func (h *ServiceHandler) ClusterCapacity(
 	ctx context.Context,
@@ -268,6 +348,32 @@ func (h *ServiceHandler) GetHostCache(
 	return resp, nil
 }
 
+// releaseHostsHeldForPods releases the hosts held for the given pods. If
+// skipHostname is set, pods held on that host keep their hold.
+func (h *ServiceHandler) releaseHostsHeldForPods(
+	podIDs []*peloton.PodID,
+	skipHostname string,
+) {
+	hostToPodIDs := make(map[string][]*peloton.PodID)
+	for _, podID := range podIDs {
+		hostHeld := h.hostCache.GetHostHeldForPod(podID)
+		if hostHeld == "" || hostHeld == skipHostname {
+			continue
+		}
+		hostToPodIDs[hostHeld] = append(hostToPodIDs[hostHeld], podID)
+	}
+
+	for hostname, podIDs := range hostToPodIDs {
+		if err := h.hostCache.ReleaseHoldForPods(hostname, podIDs); err != nil {
+			log.WithFields(log.Fields{
+				"pod_ids":   podIDs,
+				"host_held": hostname,
+			}).WithError(err).
+				Warn("fail to release held host")
+		}
+	}
+}
+
 // validateLaunchPodsRequest does some sanity checks on launch pods request.
 func validateLaunchPodsRequest(req *svc.LaunchPodsRequest) error {
 	if len(req.Pods) <= 0 {
@@ -34,6 +34,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
 	"golang.org/x/net/context"
 )
 
@@ -233,7 +234,11 @@ func (suite *HostMgrHandlerTestSuite) TestLaunchPods() {
 			Pods:     tt.launchablePods,
 		}
 		suite.hostCache.EXPECT().
-			CompleteLease(tt.hostname, tt.leaseID.GetValue(), gomock.Any()).
+			GetHostHeldForPod(gomock.Any()).
+			Return("").
+			AnyTimes()
+		suite.hostCache.EXPECT().
+			CompleteLease(tt.hostname, tt.leaseID.GetValue(), gomock.Any(), gomock.Any()).
 			Return(nil)
 		for _, pod := range tt.launchablePods {
 			suite.plugin.EXPECT().
@@ -254,6 +259,172 @@ func (suite *HostMgrHandlerTestSuite) TestLaunchPods() {
 	}
 }
 
+// TestLaunchPodsRevocable tests LaunchPods API accounts revocable pods
+// separately when completing the lease.
+func (suite *HostMgrHandlerTestSuite) TestLaunchPodsRevocable() {
+	defer suite.ctrl.Finish()
+
+	hostname := "host-name"
+	leaseID := &hostmgr.LeaseID{Value: uuid.New()}
+	pods := generateLaunchablePods(2)
+	pods[1].Spec.Revocable = true
+
+	res := scalar.FromPodSpec(pods[0].GetSpec())
+	suite.hostCache.EXPECT().
+		GetHostHeldForPod(gomock.Any()).
+		Return("").
+		AnyTimes()
+	suite.hostCache.EXPECT().
+		CompleteLease(
+			hostname,
+			leaseID.GetValue(),
+			map[string]scalar.Resources{
+				pods[0].GetPodId().GetValue(): res,
+			},
+			map[string]scalar.Resources{
+				pods[1].GetPodId().GetValue(): res,
+			}).
+		Return(nil)
+	for _, pod := range pods {
+		suite.plugin.EXPECT().
+			LaunchPod(pod.GetSpec(), pod.GetPodId().GetValue(), hostname).
+			Return(nil)
+	}
+
+	resp, err := suite.handler.LaunchPods(rootCtx, &svc.LaunchPodsRequest{
+		LeaseId:  leaseID,
+		Hostname: hostname,
+		Pods:     pods,
+	})
+	suite.NoError(err)
+	suite.Equal(&svc.LaunchPodsResponse{}, resp)
+}
+
+// TestLaunchPodsReleasesHeldHosts tests LaunchPods API releases the hold on
+// hosts for the launched pods.
+func (suite *HostMgrHandlerTestSuite) TestLaunchPodsReleasesHeldHosts() {
+	defer suite.ctrl.Finish()
+
+	hostname := "host-name"
+	otherHostname := "other-host-name"
+	leaseID := &hostmgr.LeaseID{Value: uuid.New()}
+	pods := generateLaunchablePods(3)
+
+	// pod 0 is held on the launch host, pod 1 is held on another host,
+	// pod 2 is not held.
+	heldHosts := map[string]string{
+		pods[0].GetPodId().GetValue(): hostname,
+		pods[1].GetPodId().GetValue(): otherHostname,
+	}
+	suite.hostCache.EXPECT().
+		GetHostHeldForPod(gomock.Any()).
+		DoAndReturn(func(podID *peloton.PodID) string {
+			return heldHosts[podID.GetValue()]
+		}).
+		AnyTimes()
+
+	gomock.InOrder(
+		suite.hostCache.EXPECT().
+			ReleaseHoldForPods(
+				otherHostname,
+				[]*peloton.PodID{pods[1].GetPodId()}).
+			Do(func(string, []*peloton.PodID) {
+				delete(heldHosts, pods[1].GetPodId().GetValue())
+			}).
+			Return(nil),
+		suite.hostCache.EXPECT().
+			CompleteLease(hostname, leaseID.GetValue(), gomock.Any(), gomock.Any()).
+			Return(nil),
+		suite.hostCache.EXPECT().
+			ReleaseHoldForPods(
+				hostname,
+				[]*peloton.PodID{pods[0].GetPodId()}).
+			Return(nil),
+	)
+	suite.plugin.EXPECT().
+		LaunchPod(gomock.Any(), gomock.Any(), hostname).
+		Return(nil).
+		Times(len(pods))
+
+	_, err := suite.handler.LaunchPods(rootCtx, &svc.LaunchPodsRequest{
+		LeaseId:  leaseID,
+		Hostname: hostname,
+		Pods:     pods,
+	})
+	suite.NoError(err)
+}
+
+// TestKillPods tests KillPods API kills the pods and releases the hold on
+// their hosts.
+func (suite *HostMgrHandlerTestSuite) TestKillPods() {
+	defer suite.ctrl.Finish()
+
+	podID := &peloton.PodID{Value: fmt.Sprintf(_podIDFmt, 0)}
+	suite.plugin.EXPECT().KillPod(podID.GetValue()).Return(nil)
+	suite.hostCache.EXPECT().GetHostHeldForPod(podID).Return("host-name")
+	suite.hostCache.EXPECT().
+		ReleaseHoldForPods("host-name", []*peloton.PodID{podID}).
+		Return(nil)
+
+	resp, err := suite.handler.KillPods(rootCtx, &svc.KillPodsRequest{
+		PodIds: []*peloton.PodID{podID},
+	})
+	suite.NoError(err)
+	suite.Equal(&svc.KillPodsResponse{}, resp)
+}
+
+// TestKillAndHoldPods tests KillAndHoldPods API holds the host and kills
+// the pod.
+func (suite *HostMgrHandlerTestSuite) TestKillAndHoldPods() {
+	defer suite.ctrl.Finish()
+
+	podID := &peloton.PodID{Value: fmt.Sprintf(_podIDFmt, 0)}
+	gomock.InOrder(
+		suite.hostCache.EXPECT().
+			HoldForPods("host-name", []*peloton.PodID{podID}).
+			Return(nil),
+		suite.plugin.EXPECT().KillPod(podID.GetValue()).Return(nil),
+	)
+
+	resp, err := suite.handler.KillAndHoldPods(
+		rootCtx,
+		&svc.KillAndHoldPodsRequest{
+			Entries: []*svc.KillAndHoldPodsRequest_Entry{
+				{PodId: podID, HostToHold: "host-name"},
+			},
+		})
+	suite.NoError(err)
+	suite.Equal(&svc.KillAndHoldPodsResponse{}, resp)
+}
+
+// TestKillAndHoldPodsKillFailure tests KillAndHoldPods API releases the hold
+// on the host if the pod kill fails.
+func (suite *HostMgrHandlerTestSuite) TestKillAndHoldPodsKillFailure() {
+	defer suite.ctrl.Finish()
+
+	podID := &peloton.PodID{Value: fmt.Sprintf(_podIDFmt, 0)}
+	gomock.InOrder(
+		suite.hostCache.EXPECT().
+			HoldForPods("host-name", []*peloton.PodID{podID}).
+			Return(nil),
+		suite.plugin.EXPECT().
+			KillPod(podID.GetValue()).
+			Return(yarpcerrors.InternalErrorf("test error")),
+		suite.hostCache.EXPECT().
+			ReleaseHoldForPods("host-name", []*peloton.PodID{podID}).
+			Return(nil),
+	)
+
+	_, err := suite.handler.KillAndHoldPods(
+		rootCtx,
+		&svc.KillAndHoldPodsRequest{
+			Entries: []*svc.KillAndHoldPodsRequest_Entry{
+				{PodId: podID, HostToHold: "host-name"},
+			},
+		})
+	suite.Error(err)
+}
+
 func (suite *HostMgrHandlerTestSuite) TestTerminateLease() {
 	defer suite.ctrl.Finish()
 
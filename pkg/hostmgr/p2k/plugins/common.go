@@ -25,10 +25,16 @@ import (
 // NewK8sPlugin returns a new instance of k8s plugin.
 func NewK8sPlugin(
 	configPath string,
+	revocableResources bool,
 	podEventsCh chan<- *scalar.PodEvent,
 	hostEventCh chan<- *scalar.HostEvent,
 ) (Plugin, error) {
-	return k8s.NewK8sManager(configPath, podEventsCh, hostEventCh)
+	return k8s.NewK8sManager(
+		configPath,
+		revocableResources,
+		podEventsCh,
+		hostEventCh,
+	)
 }
 
 func NewNoopPlugin() Plugin {
@@ -17,7 +17,9 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
 	pbpod "github.com/uber/peloton/.gen/peloton/api/v1alpha/pod"
 
 	"github.com/uber/peloton/pkg/common"
@@ -35,19 +37,28 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 const EventChanSize = 1000
 
+// PodNamespace is the K8s namespace in which Peloton pods are created.
+const PodNamespace = "default"
+
 // K8SManager implements the plugin for the Kubernetes cluster manager.
 type K8SManager struct {
 	// K8s client.
 	kubeClient kubernetes.Interface
 
+	// K8s metrics API client, used to observe usage of nodes for revocable
+	// resources. It is nil if revocable resources are disabled.
+	metricsClient metricsclient.Interface
+
 	// Internal K8S client structs that provide pod and node watch
 	// functionality.
 	informerFactory informers.SharedInformerFactory
 	nodeLister      corelisters.NodeLister
+	podLister       corelisters.PodLister
 
 	// Pod events channel.
 	podEventCh chan<- *scalar.PodEvent
@@ -62,28 +73,48 @@ type K8SManager struct {
 // NewK8sManager returns a new instance of K8SManager
 func NewK8sManager(
 	configPath string,
+	revocableResources bool,
 	podEventCh chan<- *scalar.PodEvent,
 	hostEventCh chan<- *scalar.HostEvent,
 ) (*K8SManager, error) {
 	// Initialize k8s client.
+	kubeClient, kubeConfig, err := NewKubeClient(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	k := newK8sManagerWithClient(
+		kubeClient,
+		podEventCh,
+		hostEventCh,
+	)
+
+	if revocableResources {
+		k.metricsClient, err = metricsclient.NewForConfig(kubeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("error creating metrics client: %v", err)
+		}
+	}
+
+	return k, nil
+}
+
+// NewKubeClient returns a K8s client along with its config, loaded from
+// the kubeconfig file at the given path.
+func NewKubeClient(configPath string) (kubernetes.Interface, *rest.Config, error) {
 	kubeConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		&clientcmd.ClientConfigLoadingRules{ExplicitPath: configPath},
 		&clientcmd.ConfigOverrides{}).ClientConfig()
 	if err != nil {
-		return nil, fmt.Errorf("error creating kube config: %v", err)
+		return nil, nil, fmt.Errorf("error creating kube config: %v", err)
 	}
 
 	kubeClient, err := kubernetes.NewForConfig(
 		rest.AddUserAgent(kubeConfig, "peloton-scheduler"))
 	if err != nil {
-		return nil, fmt.Errorf("error creating kube client: %v", err)
+		return nil, nil, fmt.Errorf("error creating kube client: %v", err)
 	}
-
-	return newK8sManagerWithClient(
-		kubeClient,
-		podEventCh,
-		hostEventCh,
-	), nil
+	return kubeClient, kubeConfig, nil
 }
 
 // newK8sManagerWithClient returns a new instance of K8SManager with given k8s
@@ -99,11 +130,13 @@ func newK8sManagerWithClient(
 		_defaultResyncInterval,
 	)
 	nodeLister := informerFactory.Core().V1().Nodes().Lister()
+	podLister := informerFactory.Core().V1().Pods().Lister()
 
 	return &K8SManager{
 		kubeClient:      kubeClient,
 		informerFactory: informerFactory,
 		nodeLister:      nodeLister,
+		podLister:       podLister,
 		podEventCh:      podEventCh,
 		hostEventCh:     hostEventCh,
 		lifecycle:       lifecycle.NewLifeCycle(),
@@ -145,6 +178,10 @@ func (k *K8SManager) Start() error {
 		return yarpcerrors.InternalErrorf("timed out waiting for cache to sync")
 	}
 
+	if k.metricsClient != nil {
+		go k.pollHostUsage()
+	}
+
 	return nil
 }
 
@@ -191,6 +228,75 @@ func (k *K8SManager) listNodes() ([]*corev1.Node, error) {
 	return k.nodeLister.List(labels.Everything())
 }
 
+// K8s node usage.
+
+// pollHostUsage periodically reports the observed usage of nodes to the host
+// cache, which uses it to calculate the revocable resources.
+func (k *K8SManager) pollHostUsage() {
+	ticker := time.NewTicker(_defaultUsagePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := k.reportHostUsage(); err != nil {
+				log.WithError(err).Warn("failed to report host usage")
+			}
+		case <-k.lifecycle.StopCh():
+			return
+		}
+	}
+}
+
+// reportHostUsage sums up the usage of non-revocable peloton pods on each
+// node from the metrics API, and sends it as a host usage event.
+func (k *K8SManager) reportHostUsage() error {
+	podMetricsList, err := k.metricsClient.MetricsV1beta1().
+		PodMetricses(PodNamespace).
+		List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	usage := make(map[string]*peloton.Resources)
+	for _, podMetrics := range podMetricsList.Items {
+		pod, err := k.podLister.Pods(podMetrics.Namespace).Get(podMetrics.Name)
+		if err != nil {
+			// The pod may be gone already, its usage is not relevant.
+			continue
+		}
+		if pod.Spec.SchedulerName != common.PelotonRole ||
+			pod.Spec.NodeName == "" ||
+			isRevocablePod(pod) {
+			continue
+		}
+
+		r, ok := usage[pod.Spec.NodeName]
+		if !ok {
+			r = &peloton.Resources{}
+			usage[pod.Spec.NodeName] = r
+		}
+		for _, c := range podMetrics.Containers {
+			r.Cpu += float64(c.Usage.Cpu().MilliValue()) / 1000
+			r.MemMb += float64(c.Usage.Memory().Value()) / _bytesPerMb
+		}
+	}
+
+	// Report usage of all nodes, so that nodes without running pods report
+	// zero usage.
+	nodes, err := k.listNodes()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		k.hostEventCh <- scalar.BuildHostEventFromUsage(
+			node.Name,
+			usage[node.Name],
+		)
+	}
+	return nil
+}
+
 // K8s NodeInformer callbacks.
 
 // NodeInformer add function.s
@@ -330,7 +436,7 @@ func (k *K8SManager) LaunchPod(
 	pod.Name = podID
 
 	// Create the pod
-	_, err := k.kubeClient.CoreV1().Pods("default").Create(pod)
+	_, err := k.kubeClient.CoreV1().Pods(PodNamespace).Create(pod)
 	return err
 }
 
@@ -341,6 +447,6 @@ func (k *K8SManager) KillPod(podID string) error {
 	// and just delete it from the API server. Special considerations need to be
 	// made for getting the logs of terminal pods, out of scope for Peloton.
 	return k.kubeClient.CoreV1().
-		Pods("default").
+		Pods(PodNamespace).
 		Delete(podID, &metav1.DeleteOptions{})
 }
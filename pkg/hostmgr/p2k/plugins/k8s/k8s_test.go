@@ -25,7 +25,11 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	testclient "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
 )
 
 func TestLaunchAndKillPod(t *testing.T) {
@@ -168,3 +172,73 @@ func TestHostEventHandlers(t *testing.T) {
 	default:
 	}
 }
+
+func TestReportHostUsage(t *testing.T) {
+	require := require.New(t)
+
+	testHostName := "test_host"
+	podEventCh := make(chan *scalar.PodEvent, 1000)
+	hostEventCh := make(chan *scalar.HostEvent, 1000)
+	fakeClient := testclient.NewSimpleClientset()
+	testManager := newK8sManagerWithClient(
+		fakeClient,
+		podEventCh,
+		hostEventCh,
+	)
+	testManager.Start()
+
+	fakeClient.CoreV1().Nodes().Create(newTestK8sNode(testHostName))
+	evt := <-hostEventCh
+	require.Equal(scalar.AddHost, evt.GetEventType())
+
+	// Launch one non-revocable and one revocable pod.
+	require.NoError(testManager.LaunchPod(
+		newTestPelotonPodSpec("pod"), "pod", testHostName))
+	revocableSpec := newTestPelotonPodSpec("revocable_pod")
+	revocableSpec.Revocable = true
+	require.NoError(testManager.LaunchPod(
+		revocableSpec, "revocable_pod", testHostName))
+	<-podEventCh
+	<-podEventCh
+
+	podMetrics := func(podName string) metricsv1beta1.PodMetrics {
+		return metricsv1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      podName,
+				Namespace: "default",
+			},
+			Containers: []metricsv1beta1.ContainerMetrics{
+				{
+					Name: podName,
+					Usage: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("500m"),
+						corev1.ResourceMemory: resource.MustParse("50Mi"),
+					},
+				},
+			},
+		}
+	}
+	metricsClient := metricsfake.NewSimpleClientset()
+	metricsClient.PrependReactor(
+		"list",
+		"pods",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			return true, &metricsv1beta1.PodMetricsList{
+				Items: []metricsv1beta1.PodMetrics{
+					podMetrics("pod"),
+					podMetrics("revocable_pod"),
+					podMetrics("unknown_pod"),
+				},
+			}, nil
+		})
+	testManager.metricsClient = metricsClient
+
+	// Only usage of the non-revocable pod is reported.
+	require.NoError(testManager.reportHostUsage())
+	evt = <-hostEventCh
+	require.Equal(scalar.UpdateHostUsage, evt.GetEventType())
+	require.Equal(testHostName, evt.GetHostInfo().GetHostName())
+	require.Equal(
+		&peloton.Resources{Cpu: 0.5, MemMb: 50},
+		evt.GetHostInfo().GetUsage())
+}
@@ -31,25 +31,39 @@ const (
 	// K8S enforces minimum mem limit for container to be 4MB. KinD enforces
 	// this limit as 100MB.
 	_defaultMinMemMb = 100.0
+	// Label set on pods which are launched on revocable resources.
+	_revocableLabelKey = "peloton.revocable"
+	// Number of bytes in a MiB, the unit of memory in Peloton.
+	_bytesPerMb = 1024 * 1024
 )
 
 // K8S node and pod informers will resync all nodes and pods at this
 // interval. This will be used for reconciliation of pods and hostcache.
 var _defaultResyncInterval = 30 * time.Second
 
+// Observed usage of nodes is polled from the metrics API at this interval.
+var _defaultUsagePollInterval = 30 * time.Second
+
 // Convert peloton container specs to k8s container specs
 func toK8SContainerSpecs(
 	containerSpecs []*pbpod.ContainerSpec,
+	revocable bool,
 ) []corev1.Container {
 	var containers []corev1.Container
 	for _, c := range containerSpecs {
-		containers = append(containers, toK8SContainerSpec(c))
+		containers = append(containers, toK8SContainerSpec(c, revocable))
 	}
 	return containers
 }
 
-// Convert peloton container spec to k8s container spec
-func toK8SContainerSpec(c *pbpod.ContainerSpec) corev1.Container {
+// Convert peloton container spec to k8s container spec. The containers of
+// revocable pods do not request any resources, so that the pods run in the
+// BestEffort QoS class and are the first ones evicted when the slack they
+// run on is reclaimed.
+func toK8SContainerSpec(
+	c *pbpod.ContainerSpec,
+	revocable bool,
+) corev1.Container {
 	// TODO:
 	// add ports, health check, readiness check, affinity
 	var kEnvs []corev1.EnvVar
@@ -78,38 +92,33 @@ func toK8SContainerSpec(c *pbpod.ContainerSpec) corev1.Container {
 		cimage = _defaultImageName
 	}
 
-	memMb := c.GetResource().GetMemLimitMb()
-	if memMb < _defaultMinMemMb {
-		memMb = _defaultMinMemMb
-	}
-
 	k8sSpec := corev1.Container{
 		Name:  cname,
 		Image: cimage,
 		Env:   kEnvs,
 		Ports: ports,
-		Resources: corev1.ResourceRequirements{
-			Limits: corev1.ResourceList{
-				corev1.ResourceCPU: *resource.NewMilliQuantity(
-					int64(c.GetResource().GetCpuLimit()*1000),
-					resource.DecimalSI,
-				),
-				corev1.ResourceMemory: *resource.NewMilliQuantity(
-					int64(memMb*1000000000),
-					resource.DecimalSI,
-				),
-			},
-			Requests: corev1.ResourceList{
-				corev1.ResourceCPU: *resource.NewMilliQuantity(
-					int64(c.GetResource().GetCpuLimit()*1000),
-					resource.DecimalSI,
-				),
-				corev1.ResourceMemory: *resource.NewMilliQuantity(
-					int64(memMb*1000000000),
-					resource.DecimalSI,
-				),
-			},
-		},
+	}
+
+	if !revocable {
+		memMb := c.GetResource().GetMemLimitMb()
+		if memMb < _defaultMinMemMb {
+			memMb = _defaultMinMemMb
+		}
+
+		resources := corev1.ResourceList{
+			corev1.ResourceCPU: *resource.NewMilliQuantity(
+				int64(c.GetResource().GetCpuLimit()*1000),
+				resource.DecimalSI,
+			),
+			corev1.ResourceMemory: *resource.NewQuantity(
+				int64(memMb*_bytesPerMb),
+				resource.BinarySI,
+			),
+		}
+		k8sSpec.Resources = corev1.ResourceRequirements{
+			Limits:   resources,
+			Requests: resources.DeepCopy(),
+		}
 	}
 
 	if c.GetEntrypoint().GetValue() != "" {
@@ -127,6 +136,9 @@ func toK8SPodSpec(podSpec *pbpod.PodSpec) *corev1.Pod {
 	for _, label := range podSpec.GetLabels() {
 		labels[label.GetKey()] = label.GetValue()
 	}
+	if podSpec.GetRevocable() {
+		labels[_revocableLabelKey] = "true"
+	}
 
 	termGracePeriod := int64(podSpec.GetKillGracePeriodSeconds())
 
@@ -135,8 +147,8 @@ func toK8SPodSpec(podSpec *pbpod.PodSpec) *corev1.Pod {
 			Labels: labels,
 		},
 		Spec: corev1.PodSpec{
-			Containers:                    toK8SContainerSpecs(podSpec.GetContainers()),
-			InitContainers:                toK8SContainerSpecs(podSpec.GetInitContainers()),
+			Containers:                    toK8SContainerSpecs(podSpec.GetContainers(), podSpec.GetRevocable()),
+			InitContainers:                toK8SContainerSpecs(podSpec.GetInitContainers(), podSpec.GetRevocable()),
 			RestartPolicy:                 "Never",
 			TerminationGracePeriodSeconds: &termGracePeriod,
 		},
@@ -148,3 +160,8 @@ func toK8SPodSpec(podSpec *pbpod.PodSpec) *corev1.Pod {
 		Spec:       podTemp.Spec,
 	}
 }
+
+// isRevocablePod returns true if the pod is launched on revocable resources.
+func isRevocablePod(pod *corev1.Pod) bool {
+	return pod.Labels[_revocableLabelKey] == "true"
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/pborman/uuid"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func TestToK8SPodSpec(t *testing.T) {
@@ -51,4 +52,35 @@ func TestToK8SPodSpec(t *testing.T) {
 	cname := uuid.Parse(returnedPod.Spec.Containers[0].Name)
 	require.NotNil(cname)
 	require.Equal(returnedPod.Spec.Containers[0].Image, _defaultImageName)
+
+	// the pod requests as much as its limits, memory in MiB
+	resources := returnedPod.Spec.Containers[0].Resources
+	require.Equal(int64(1000), resources.Limits.Cpu().MilliValue())
+	require.Equal(
+		resource.MustParse("100Mi").Value(),
+		resources.Limits.Memory().Value())
+	require.Equal(resources.Limits, resources.Requests)
+}
+
+func TestToK8SPodSpecRevocable(t *testing.T) {
+	require := require.New(t)
+
+	returnedPod := toK8SPodSpec(&pbpod.PodSpec{})
+	require.False(isRevocablePod(returnedPod))
+
+	// revocable pods do not request any resources
+	returnedPod = toK8SPodSpec(&pbpod.PodSpec{
+		Revocable: true,
+		Containers: []*pbpod.ContainerSpec{
+			{
+				Resource: &pbpod.ResourceSpec{
+					CpuLimit:   1.0,
+					MemLimitMb: 200.0,
+				},
+			},
+		},
+	})
+	require.True(isRevocablePod(returnedPod))
+	require.Empty(returnedPod.Spec.Containers[0].Resources.Requests)
+	require.Empty(returnedPod.Spec.Containers[0].Resources.Limits)
 }
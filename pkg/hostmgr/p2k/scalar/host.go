@@ -21,6 +21,9 @@ const (
 	DeleteHost
 	// UpdateHostAvailableRes event type, used by mesos only
 	UpdateHostAvailableRes
+	// UpdateHostUsage event type, carries the observed resource usage of
+	// non-revocable pods on the host.
+	UpdateHostUsage
 )
 
 // HostEvent contains information about the host, event type and resource
@@ -55,6 +58,8 @@ type HostInfo struct {
 	resourceVersion string
 	// capacity available on the host
 	available *peloton.Resources
+	// observed resource usage of non-revocable pods on the host
+	usage *peloton.Resources
 }
 
 // GetHostName is helper function to get name of the host.
@@ -82,11 +87,23 @@ func (h *HostInfo) GetAvailable() *peloton.Resources {
 	return h.available
 }
 
+// GetUsage is helper function to get observed usage of non-revocable pods
+// on the host.
+func (h *HostInfo) GetUsage() *peloton.Resources {
+	return h.usage
+}
+
 // Initialize each host disk capacity to 1T by default for k8s.
 // This is because k8s does not have concept of disk resource.
 func getDefaultDiskMbPerHost() float64 {
 	r := resource.MustParse("1Ti")
-	return float64(r.MilliValue() / 1000000000)
+	return toMb(r)
+}
+
+// toMb returns a quantity of bytes in MiB, the unit
+// of memory and disk in Peloton.
+func toMb(q resource.Quantity) float64 {
+	return float64(q.Value()) / (1 << 20)
 }
 
 // BuildHostEventFromNode builds a host event from underlying k8s node object.
@@ -108,8 +125,7 @@ func BuildHostEventFromNode(
 			capacity: &peloton.Resources{
 				Cpu: float64(
 					node.Status.Capacity.Cpu().MilliValue()) / 1000,
-				MemMb: float64(
-					node.Status.Capacity.Memory().MilliValue()) / 1000000000,
+				MemMb:  toMb(*node.Status.Capacity.Memory()),
 				DiskMb: getDefaultDiskMbPerHost(),
 				Gpu:    0,
 			},
@@ -141,6 +157,26 @@ func BuildHostEventFromResource(
 	}
 }
 
+// BuildHostEventFromUsage builds a host event from the observed resource
+// usage of non-revocable pods on a host.
+func BuildHostEventFromUsage(
+	hostname string,
+	usage *peloton.Resources,
+) *HostEvent {
+	if usage == nil {
+		usage = &peloton.Resources{}
+	}
+
+	return &HostEvent{
+		hostInfo: &HostInfo{
+			hostname: hostname,
+			podMap:   make(map[string]*peloton.Resources),
+			usage:    usage,
+		},
+		eventType: UpdateHostUsage,
+	}
+}
+
 // IsOldVersion is a very k8s specific check.
 // TODO: make this an interface with a noop impl for Mesos.
 // Check if the event has already been received. When we start k8s node
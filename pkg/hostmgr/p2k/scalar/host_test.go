@@ -42,10 +42,9 @@ func TestBuildHostEventFromNode(t *testing.T) {
 			hostname: "test-node",
 			podMap:   map[string]*peloton.Resources{},
 			capacity: &peloton.Resources{
-				Cpu: float64(32),
-				MemMb: float64(
-					node.Status.Capacity.Memory().MilliValue()) / 1000000000,
-				DiskMb: getDefaultDiskMbPerHost(),
+				Cpu:    float64(32),
+				MemMb:  float64(96 * 1024),
+				DiskMb: float64(1024 * 1024),
 				Gpu:    0,
 			},
 		},
@@ -56,3 +55,17 @@ func TestBuildHostEventFromNode(t *testing.T) {
 	require.Nil(err)
 	require.True(reflect.DeepEqual(expectedHostEvent, hostEvent))
 }
+
+func TestBuildHostEventFromUsage(t *testing.T) {
+	require := require.New(t)
+
+	usage := &peloton.Resources{Cpu: 2, MemMb: 1024}
+	hostEvent := BuildHostEventFromUsage("test-node", usage)
+	require.Equal(UpdateHostUsage, hostEvent.GetEventType())
+	require.Equal("test-node", hostEvent.GetHostInfo().GetHostName())
+	require.Equal(usage, hostEvent.GetHostInfo().GetUsage())
+
+	// nil usage is reported as zero usage.
+	hostEvent = BuildHostEventFromUsage("test-node", nil)
+	require.Equal(&peloton.Resources{}, hostEvent.GetHostInfo().GetUsage())
+}
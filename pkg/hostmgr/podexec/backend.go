@@ -0,0 +1,47 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podexec
+
+import (
+	"context"
+	"io"
+)
+
+// Request to run a command inside a pod
+type Request struct {
+	// Hostname of the host the pod runs on
+	Hostname string
+	// ID of the pod run
+	PodID string
+	// Command to run, along with its arguments
+	Command []string
+	// Allocate a TTY for the command
+	TTY bool
+}
+
+// Streams connected to a command run inside a pod
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Backend runs commands inside the pods, using the cluster manager
+// the pods run on.
+type Backend interface {
+	// Exec runs the command inside the pod with the given streams
+	// connected, and returns the exit code of the command once it exits.
+	Exec(ctx context.Context, req *Request, streams *Streams) (int32, error)
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podexec
+
+import (
+	"time"
+)
+
+const (
+	_defaultHTTPTimeout = 15 * time.Second
+)
+
+// Config for running commands inside pods
+type Config struct {
+	// Timeout of the requests to the Mesos agent operator API, except for
+	// the nested container session which lasts as long as the command.
+	HTTPTimeout time.Duration `yaml:"http_timeout"`
+}
+
+func (c *Config) normalize() {
+	if c.HTTPTimeout <= 0 {
+		c.HTTPTimeout = _defaultHTTPTimeout
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podexec
+
+import (
+	"context"
+
+	"github.com/uber/peloton/pkg/hostmgr/p2k/plugins/k8s"
+
+	"go.uber.org/yarpc/yarpcerrors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// k8sBackend runs commands inside the pods using the K8s pod exec API.
+type k8sBackend struct {
+	kubeClient kubernetes.Interface
+	restConfig *rest.Config
+
+	// newExecutor returns the executor running a command inside a pod.
+	newExecutor func(
+		podID string,
+		opts *corev1.PodExecOptions,
+	) (remotecommand.Executor, error)
+}
+
+// NewK8sBackend returns a Backend running commands inside the pods
+// running on K8s, using the kubeconfig file at the given path.
+func NewK8sBackend(kubeConfigPath string) (Backend, error) {
+	kubeClient, restConfig, err := k8s.NewKubeClient(kubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return newK8sBackendWithClient(kubeClient, restConfig), nil
+}
+
+func newK8sBackendWithClient(
+	kubeClient kubernetes.Interface,
+	restConfig *rest.Config,
+) *k8sBackend {
+	k := &k8sBackend{
+		kubeClient: kubeClient,
+		restConfig: restConfig,
+	}
+	k.newExecutor = k.newPodExecutor
+	return k
+}
+
+func (k *k8sBackend) newPodExecutor(
+	podID string,
+	opts *corev1.PodExecOptions,
+) (remotecommand.Executor, error) {
+	req := k.kubeClient.CoreV1().RESTClient().
+		Post().
+		Resource("pods").
+		Namespace(k8s.PodNamespace).
+		Name(podID).
+		SubResource("exec").
+		VersionedParams(opts, scheme.ParameterCodec)
+	return remotecommand.NewSPDYExecutor(k.restConfig, "POST", req.URL())
+}
+
+// Exec implements Backend.Exec.
+func (k *k8sBackend) Exec(
+	ctx context.Context,
+	req *Request,
+	streams *Streams,
+) (int32, error) {
+	pod, err := k.kubeClient.CoreV1().
+		Pods(k8s.PodNamespace).
+		Get(req.PodID, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return 0, yarpcerrors.NotFoundErrorf("pod %s not found", req.PodID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return 0, yarpcerrors.AbortedErrorf(
+			"pod %s is not running: %s", req.PodID, pod.Status.Phase)
+	}
+
+	opts := &corev1.PodExecOptions{
+		Command: req.Command,
+		Stdin:   streams.Stdin != nil,
+		Stdout:  streams.Stdout != nil,
+		// the output of a TTY is always written to stdout
+		Stderr: streams.Stderr != nil && !req.TTY,
+		TTY:    req.TTY,
+	}
+	if len(pod.Spec.Containers) > 0 {
+		// run the command in the main container of the pod
+		opts.Container = pod.Spec.Containers[0].Name
+	}
+
+	executor, err := k.newExecutor(req.PodID, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	streamOpts := remotecommand.StreamOptions{
+		Stdin:  streams.Stdin,
+		Stdout: streams.Stdout,
+		Tty:    req.TTY,
+	}
+	if opts.Stderr {
+		streamOpts.Stderr = streams.Stderr
+	}
+
+	// TODO: cancel the command once the context is cancelled, the
+	// executor of this client-go version does not take a context
+	err = executor.Stream(streamOpts)
+	if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.Exited() {
+		return int32(exitErr.ExitStatus()), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
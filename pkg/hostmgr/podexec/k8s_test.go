@@ -0,0 +1,188 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/uber/peloton/pkg/hostmgr/p2k/plugins/k8s"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// fakeExecutor writes its standard input, if any, to stdout
+// and fails with the configured error.
+type fakeExecutor struct {
+	err error
+}
+
+func (e *fakeExecutor) Stream(opts remotecommand.StreamOptions) error {
+	if opts.Stdin != nil {
+		io.Copy(opts.Stdout, opts.Stdin)
+	}
+	if opts.Stderr != nil {
+		io.WriteString(opts.Stderr, "world")
+	}
+	return e.err
+}
+
+type K8sBackendTestSuite struct {
+	suite.Suite
+
+	backend  *k8sBackend
+	executor *fakeExecutor
+	opts     *corev1.PodExecOptions
+}
+
+func TestK8sBackend(t *testing.T) {
+	suite.Run(t, new(K8sBackendTestSuite))
+}
+
+func (suite *K8sBackendTestSuite) SetupTest() {
+	suite.opts = nil
+	suite.executor = &fakeExecutor{}
+	suite.backend = newK8sBackendWithClient(
+		testclient.NewSimpleClientset(
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      _testPodID,
+					Namespace: k8s.PodNamespace,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "main"},
+						{Name: "sidecar"},
+					},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pending-pod",
+					Namespace: k8s.PodNamespace,
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodPending},
+			},
+		),
+		nil,
+	)
+	suite.backend.newExecutor = func(
+		podID string,
+		opts *corev1.PodExecOptions,
+	) (remotecommand.Executor, error) {
+		suite.Equal(_testPodID, podID)
+		suite.opts = opts
+		return suite.executor, nil
+	}
+}
+
+// TestExec tests running a command in the main container of a pod
+func (suite *K8sBackendTestSuite) TestExec() {
+	var stdout, stderr bytes.Buffer
+	exitCode, err := suite.backend.Exec(
+		context.Background(),
+		&Request{PodID: _testPodID, Command: []string{"cat"}},
+		&Streams{
+			Stdin:  strings.NewReader("hello"),
+			Stdout: &stdout,
+			Stderr: &stderr,
+		},
+	)
+	suite.NoError(err)
+	suite.Equal(int32(0), exitCode)
+	suite.Equal("hello", stdout.String())
+	suite.Equal("world", stderr.String())
+
+	suite.Equal("main", suite.opts.Container)
+	suite.Equal([]string{"cat"}, suite.opts.Command)
+	suite.True(suite.opts.Stdin)
+	suite.True(suite.opts.Stdout)
+	suite.True(suite.opts.Stderr)
+	suite.False(suite.opts.TTY)
+}
+
+// TestExecTTY tests running a command with a TTY, whose output
+// is written to stdout only
+func (suite *K8sBackendTestSuite) TestExecTTY() {
+	var stdout, stderr bytes.Buffer
+	_, err := suite.backend.Exec(
+		context.Background(),
+		&Request{PodID: _testPodID, Command: []string{"sh"}, TTY: true},
+		&Streams{Stdout: &stdout, Stderr: &stderr},
+	)
+	suite.NoError(err)
+	suite.Empty(stderr.String())
+	suite.False(suite.opts.Stdin)
+	suite.False(suite.opts.Stderr)
+	suite.True(suite.opts.TTY)
+}
+
+// TestExecExitCode tests the exit code of a command exiting with an error
+func (suite *K8sBackendTestSuite) TestExecExitCode() {
+	suite.executor.err = utilexec.CodeExitError{
+		Err:  errors.New("command terminated with exit code 2"),
+		Code: 2,
+	}
+
+	exitCode, err := suite.backend.Exec(
+		context.Background(),
+		&Request{PodID: _testPodID, Command: []string{"false"}},
+		&Streams{Stdout: ioutil.Discard, Stderr: ioutil.Discard},
+	)
+	suite.NoError(err)
+	suite.Equal(int32(2), exitCode)
+}
+
+// TestExecStreamFailure tests failure of the exec API
+func (suite *K8sBackendTestSuite) TestExecStreamFailure() {
+	suite.executor.err = errors.New("connection refused")
+
+	_, err := suite.backend.Exec(
+		context.Background(),
+		&Request{PodID: _testPodID, Command: []string{"ls"}},
+		&Streams{Stdout: ioutil.Discard, Stderr: ioutil.Discard},
+	)
+	suite.Error(err)
+}
+
+// TestExecPodNotRunning tests running a command in a pod which
+// is not found or not running
+func (suite *K8sBackendTestSuite) TestExecPodNotRunning() {
+	_, err := suite.backend.Exec(
+		context.Background(),
+		&Request{PodID: "unknown-pod", Command: []string{"ls"}},
+		&Streams{Stdout: ioutil.Discard},
+	)
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	_, err = suite.backend.Exec(
+		context.Background(),
+		&Request{PodID: "pending-pod", Command: []string{"ls"}},
+		&Streams{Stdout: ioutil.Discard},
+	)
+	suite.True(yarpcerrors.IsAborted(err))
+}
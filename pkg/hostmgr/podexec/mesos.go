@@ -0,0 +1,540 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podexec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
+
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_mesosAgentAPIURL = "http://%s/api/v1"
+
+	_contentTypeJSON     = "application/json"
+	_contentTypeRecordIO = "application/recordio"
+
+	// Mesos agent operator API call types
+	_callGetContainers                = "GET_CONTAINERS"
+	_callLaunchNestedContainerSession = "LAUNCH_NESTED_CONTAINER_SESSION"
+	_callAttachContainerInput         = "ATTACH_CONTAINER_INPUT"
+	_callWaitNestedContainer          = "WAIT_NESTED_CONTAINER"
+
+	// Mesos agent process IO types
+	_attachContainerID = "CONTAINER_ID"
+	_attachProcessIO   = "PROCESS_IO"
+	_processIOData     = "DATA"
+	_processIOStdin    = "STDIN"
+	_processIOStdout   = "STDOUT"
+	_processIOStderr   = "STDERR"
+
+	_containerTypeMesos = "MESOS"
+
+	// Maximum number of bytes of the standard input sent at once
+	_stdinChunkSize = 4 * 1024
+)
+
+// JSON representation of the subset of the Mesos agent operator API
+// used to run commands in nested containers.
+// http://mesos.apache.org/documentation/latest/operator-http-api/#agent-api
+
+type mesosValue struct {
+	Value string `json:"value"`
+}
+
+type mesosContainerID struct {
+	Value  string            `json:"value"`
+	Parent *mesosContainerID `json:"parent,omitempty"`
+}
+
+type mesosContainer struct {
+	FrameworkID mesosValue       `json:"framework_id"`
+	ExecutorID  mesosValue       `json:"executor_id"`
+	ContainerID mesosContainerID `json:"container_id"`
+}
+
+type mesosCommandInfo struct {
+	Shell     bool     `json:"shell"`
+	Value     string   `json:"value"`
+	Arguments []string `json:"arguments,omitempty"`
+}
+
+type mesosTTYInfo struct{}
+
+type mesosContainerInfo struct {
+	Type    string        `json:"type"`
+	TTYInfo *mesosTTYInfo `json:"tty_info,omitempty"`
+}
+
+type mesosProcessIOData struct {
+	Type string `json:"type"`
+	Data []byte `json:"data"`
+}
+
+type mesosProcessIO struct {
+	Type string              `json:"type"`
+	Data *mesosProcessIOData `json:"data,omitempty"`
+}
+
+type mesosLaunchNestedContainerSession struct {
+	ContainerID mesosContainerID    `json:"container_id"`
+	Command     mesosCommandInfo    `json:"command"`
+	Container   *mesosContainerInfo `json:"container,omitempty"`
+}
+
+type mesosAttachContainerInput struct {
+	Type        string            `json:"type"`
+	ContainerID *mesosContainerID `json:"container_id,omitempty"`
+	ProcessIO   *mesosProcessIO   `json:"process_io,omitempty"`
+}
+
+type mesosWaitNestedContainer struct {
+	ContainerID mesosContainerID `json:"container_id"`
+}
+
+type mesosCall struct {
+	Type                         string                             `json:"type"`
+	LaunchNestedContainerSession *mesosLaunchNestedContainerSession `json:"launch_nested_container_session,omitempty"`
+	AttachContainerInput         *mesosAttachContainerInput         `json:"attach_container_input,omitempty"`
+	WaitNestedContainer          *mesosWaitNestedContainer          `json:"wait_nested_container,omitempty"`
+}
+
+type mesosResponse struct {
+	GetContainers *struct {
+		Containers []mesosContainer `json:"containers"`
+	} `json:"get_containers,omitempty"`
+	WaitNestedContainer *struct {
+		ExitStatus *int32 `json:"exit_status,omitempty"`
+	} `json:"wait_nested_container,omitempty"`
+}
+
+// mesosBackend runs commands inside the pods running on Mesos agents,
+// using nested container sessions of the agent operator API.
+type mesosBackend struct {
+	cfg                   Config
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider
+
+	// client for the calls returning a response right away
+	client *http.Client
+	// client for the calls streaming data while the command runs
+	streamClient *http.Client
+
+	// getAgent returns the registered Mesos agent with the given hostname,
+	// or nil if there is no such agent.
+	getAgent func(hostname string) *mesos_master.Response_GetAgents_Agent
+}
+
+// NewMesosBackend returns a Backend running commands inside the pods
+// running on Mesos agents.
+func NewMesosBackend(
+	cfg Config,
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider,
+) Backend {
+	cfg.normalize()
+	return &mesosBackend{
+		cfg:                   cfg,
+		frameworkInfoProvider: frameworkInfoProvider,
+		client:                &http.Client{Timeout: cfg.HTTPTimeout},
+		streamClient:          &http.Client{},
+		getAgent:              host.GetRegisteredAgent,
+	}
+}
+
+// Exec implements Backend.Exec.
+func (m *mesosBackend) Exec(
+	ctx context.Context,
+	req *Request,
+	streams *Streams,
+) (int32, error) {
+	agent := m.getAgent(req.Hostname)
+	if agent == nil {
+		return 0, yarpcerrors.NotFoundErrorf(
+			"mesos agent not found on host %s", req.Hostname)
+	}
+
+	apiURL := fmt.Sprintf(_mesosAgentAPIURL, host.GetAgentAddress(agent))
+
+	parent, err := m.findPodContainer(ctx, apiURL, req.PodID)
+	if err != nil {
+		return 0, err
+	}
+
+	// the nested container is destroyed by the agent once the
+	// connection of the session is closed
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	containerID := mesosContainerID{Value: uuid.New(), Parent: parent}
+	output, err := m.launchSession(ctx, apiURL, containerID, req)
+	if err != nil {
+		return 0, err
+	}
+	defer output.Close()
+
+	if streams.Stdin != nil {
+		go func() {
+			if err := m.attachInput(
+				ctx, apiURL, containerID, streams.Stdin); err != nil {
+				log.WithError(err).
+					WithField("pod_id", req.PodID).
+					WithField("container_id", containerID.Value).
+					Info("failed to attach standard input of nested container")
+			}
+		}()
+	}
+
+	reader := bufio.NewReader(output)
+	for {
+		record, err := readRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		processIO := &mesosProcessIO{}
+		if err := json.Unmarshal(record, processIO); err != nil {
+			return 0, err
+		}
+		// control messages, e.g. heartbeats, are ignored
+		if processIO.Type != _processIOData || processIO.Data == nil {
+			continue
+		}
+
+		var w io.Writer
+		switch processIO.Data.Type {
+		case _processIOStdout:
+			w = streams.Stdout
+		case _processIOStderr:
+			w = streams.Stderr
+		}
+		if w == nil {
+			continue
+		}
+		if _, err := w.Write(processIO.Data.Data); err != nil {
+			return 0, err
+		}
+	}
+
+	return m.waitContainer(ctx, apiURL, containerID)
+}
+
+// findPodContainer returns the ID of the container of the executor of the
+// pod, which is the parent of the nested container running the command.
+func (m *mesosBackend) findPodContainer(
+	ctx context.Context,
+	apiURL string,
+	podID string,
+) (*mesosContainerID, error) {
+	resp := &mesosResponse{}
+	if err := m.call(
+		ctx, apiURL, &mesosCall{Type: _callGetContainers}, resp); err != nil {
+		return nil, err
+	}
+	if resp.GetContainers == nil {
+		return nil, fmt.Errorf("invalid response to %s", _callGetContainers)
+	}
+
+	// Pods launched by thermos executor have an executor ID
+	// with a prefix of `thermos`
+	executorIDs := map[string]bool{
+		podID: true,
+		common.PelotonAuroraBridgeExecutorIDPrefix + podID: true,
+	}
+	frameworkID := m.frameworkInfoProvider.GetFrameworkID(ctx).GetValue()
+
+	for _, container := range resp.GetContainers.Containers {
+		if container.FrameworkID.Value != frameworkID {
+			continue
+		}
+		if executorIDs[container.ExecutorID.Value] {
+			containerID := container.ContainerID
+			return &containerID, nil
+		}
+	}
+
+	return nil, yarpcerrors.NotFoundErrorf(
+		"container of pod %s not found", podID)
+}
+
+// launchSession launches the nested container running the command,
+// and returns the stream of the output of the command.
+func (m *mesosBackend) launchSession(
+	ctx context.Context,
+	apiURL string,
+	containerID mesosContainerID,
+	req *Request,
+) (io.ReadCloser, error) {
+	launch := &mesosLaunchNestedContainerSession{
+		ContainerID: containerID,
+		Command: mesosCommandInfo{
+			Shell:     false,
+			Value:     req.Command[0],
+			Arguments: req.Command,
+		},
+	}
+	if req.TTY {
+		launch.Container = &mesosContainerInfo{
+			Type:    _containerTypeMesos,
+			TTYInfo: &mesosTTYInfo{},
+		}
+	}
+
+	body, err := json.Marshal(&mesosCall{
+		Type:                         _callLaunchNestedContainerSession,
+		LaunchNestedContainerSession: launch,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(
+		http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", _contentTypeJSON)
+	httpReq.Header.Set("Accept", _contentTypeRecordIO)
+	httpReq.Header.Set("Message-Accept", _contentTypeJSON)
+
+	resp, err := m.streamClient.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp, _callLaunchNestedContainerSession); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// attachInput streams the standard input to the nested container.
+func (m *mesosBackend) attachInput(
+	ctx context.Context,
+	apiURL string,
+	containerID mesosContainerID,
+	stdin io.Reader,
+) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeInput(pw, containerID, stdin))
+	}()
+	defer pr.Close()
+
+	httpReq, err := http.NewRequest(http.MethodPost, apiURL, pr)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", _contentTypeRecordIO)
+	httpReq.Header.Set("Message-Content-Type", _contentTypeJSON)
+	httpReq.Header.Set("Accept", _contentTypeJSON)
+
+	resp, err := m.streamClient.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, _callAttachContainerInput)
+}
+
+// writeInput writes the records of an ATTACH_CONTAINER_INPUT call,
+// i.e. the container to attach to, followed by the standard input
+// and an empty record marking the end of the input.
+func writeInput(
+	w io.Writer,
+	containerID mesosContainerID,
+	stdin io.Reader,
+) error {
+	if err := writeRecord(w, &mesosCall{
+		Type: _callAttachContainerInput,
+		AttachContainerInput: &mesosAttachContainerInput{
+			Type:        _attachContainerID,
+			ContainerID: &containerID,
+		},
+	}); err != nil {
+		return err
+	}
+
+	writeData := func(data []byte) error {
+		return writeRecord(w, &mesosCall{
+			Type: _callAttachContainerInput,
+			AttachContainerInput: &mesosAttachContainerInput{
+				Type: _attachProcessIO,
+				ProcessIO: &mesosProcessIO{
+					Type: _processIOData,
+					Data: &mesosProcessIOData{
+						Type: _processIOStdin,
+						Data: data,
+					},
+				},
+			},
+		})
+	}
+
+	buf := make([]byte, _stdinChunkSize)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			if err := writeData(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return writeData([]byte{})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// waitContainer waits for the nested container to exit,
+// and returns the exit code of the command.
+func (m *mesosBackend) waitContainer(
+	ctx context.Context,
+	apiURL string,
+	containerID mesosContainerID,
+) (int32, error) {
+	resp := &mesosResponse{}
+	if err := m.call(ctx, apiURL, &mesosCall{
+		Type: _callWaitNestedContainer,
+		WaitNestedContainer: &mesosWaitNestedContainer{
+			ContainerID: containerID,
+		},
+	}, resp); err != nil {
+		return 0, err
+	}
+
+	if resp.WaitNestedContainer == nil ||
+		resp.WaitNestedContainer.ExitStatus == nil {
+		return 0, fmt.Errorf(
+			"exit status of nested container %s not available",
+			containerID.Value)
+	}
+	return exitCode(*resp.WaitNestedContainer.ExitStatus), nil
+}
+
+// call makes a call to the agent operator API and decodes the response.
+func (m *mesosBackend) call(
+	ctx context.Context,
+	apiURL string,
+	call *mesosCall,
+	resp *mesosResponse,
+) error {
+	body, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(
+		http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", _contentTypeJSON)
+	httpReq.Header.Set("Accept", _contentTypeJSON)
+
+	httpResp, err := m.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if err := checkResponse(httpResp, call.Type); err != nil {
+		return err
+	}
+	return json.NewDecoder(httpResp.Body).Decode(resp)
+}
+
+// checkResponse returns an error if the agent failed to process a call.
+func checkResponse(resp *http.Response, callType string) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	msg := strings.TrimSpace(string(body))
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return yarpcerrors.NotFoundErrorf("%s failed: %s", callType, msg)
+	case http.StatusBadRequest:
+		return yarpcerrors.InvalidArgumentErrorf("%s failed: %s", callType, msg)
+	}
+	return fmt.Errorf("%s failed: %v %s", callType, resp.Status, msg)
+}
+
+// readRecord reads a record of a RecordIO stream,
+// i.e. the length of the record followed by a newline and the record.
+func readRecord(r *bufio.Reader) ([]byte, error) {
+	header, err := r.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && len(header) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	size, err := strconv.ParseUint(strings.TrimSpace(header), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record header %q: %v", header, err)
+	}
+
+	record := make([]byte, size)
+	if _, err := io.ReadFull(r, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// writeRecord writes a message as a record of a RecordIO stream.
+func writeRecord(w io.Writer, msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%d\n", len(data)); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// exitCode returns the exit code of a command given its exit status
+// as returned by waitpid(2), following the shell convention of
+// 128 + signal number for commands killed by a signal.
+func exitCode(status int32) int32 {
+	if signal := status & 0x7f; signal != 0 {
+		return 128 + signal
+	}
+	return (status >> 8) & 0xff
+}
@@ -0,0 +1,338 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podexec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_testHostname    = "test-hostname"
+	_testFrameworkID = "test-framework-id"
+	_testPodID       = "test-pod-id"
+	_testContainerID = "test-container-id"
+)
+
+// fakeMesosAgent serves the subset of the Mesos agent operator API used
+// to run commands in nested containers. The command run by the fake agent
+// writes a fixed output, followed by its standard input if echoStdin is
+// set, and exits with the configured exit status.
+type fakeMesosAgent struct {
+	sync.Mutex
+
+	containers []mesosContainer
+	launch     *mesosLaunchNestedContainerSession
+	stdout     string
+	stderr     string
+	exitStatus int32
+	echoStdin  bool
+
+	stdin     bytes.Buffer
+	stdinDone chan struct{}
+}
+
+func (a *fakeMesosAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") == _contentTypeRecordIO {
+		a.serveAttachInput(w, r)
+		return
+	}
+
+	call := &mesosCall{}
+	if err := json.NewDecoder(r.Body).Decode(call); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch call.Type {
+	case _callGetContainers:
+		resp := &mesosResponse{}
+		resp.GetContainers = &struct {
+			Containers []mesosContainer `json:"containers"`
+		}{Containers: a.containers}
+		json.NewEncoder(w).Encode(resp)
+
+	case _callLaunchNestedContainerSession:
+		a.Lock()
+		a.launch = call.LaunchNestedContainerSession
+		a.Unlock()
+
+		w.Header().Set("Content-Type", _contentTypeRecordIO)
+		writeOutput(w, _processIOStdout, a.stdout)
+		writeOutput(w, _processIOStderr, a.stderr)
+		if a.echoStdin {
+			select {
+			case <-a.stdinDone:
+			case <-time.After(5 * time.Second):
+			}
+			a.Lock()
+			writeOutput(w, _processIOStdout, a.stdin.String())
+			a.Unlock()
+		}
+
+	case _callWaitNestedContainer:
+		status := a.exitStatus
+		resp := &mesosResponse{}
+		resp.WaitNestedContainer = &struct {
+			ExitStatus *int32 `json:"exit_status,omitempty"`
+		}{ExitStatus: &status}
+		json.NewEncoder(w).Encode(resp)
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (a *fakeMesosAgent) serveAttachInput(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	defer close(a.stdinDone)
+
+	reader := bufio.NewReader(r.Body)
+	for {
+		record, err := readRecord(reader)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		call := &mesosCall{}
+		json.Unmarshal(record, call)
+		input := call.AttachContainerInput
+		if input.Type != _attachProcessIO {
+			continue
+		}
+		if len(input.ProcessIO.Data.Data) == 0 {
+			return
+		}
+
+		a.Lock()
+		a.stdin.Write(input.ProcessIO.Data.Data)
+		a.Unlock()
+	}
+}
+
+func writeOutput(w http.ResponseWriter, stream string, data string) {
+	if len(data) == 0 {
+		return
+	}
+	writeRecord(w, &mesosProcessIO{
+		Type: _processIOData,
+		Data: &mesosProcessIOData{Type: stream, Data: []byte(data)},
+	})
+	w.(http.Flusher).Flush()
+}
+
+type MesosBackendTestSuite struct {
+	suite.Suite
+
+	ctrl                  *gomock.Controller
+	frameworkInfoProvider *hostmgr_mesos_mocks.MockFrameworkInfoProvider
+	agent                 *fakeMesosAgent
+	server                *httptest.Server
+	backend               *mesosBackend
+}
+
+func TestMesosBackend(t *testing.T) {
+	suite.Run(t, new(MesosBackendTestSuite))
+}
+
+func (suite *MesosBackendTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.frameworkInfoProvider = hostmgr_mesos_mocks.NewMockFrameworkInfoProvider(suite.ctrl)
+	suite.frameworkInfoProvider.EXPECT().
+		GetFrameworkID(gomock.Any()).
+		Return(&mesos.FrameworkID{Value: &[]string{_testFrameworkID}[0]}).
+		AnyTimes()
+
+	suite.agent = &fakeMesosAgent{
+		containers: []mesosContainer{
+			{
+				FrameworkID: mesosValue{Value: "other-framework-id"},
+				ExecutorID:  mesosValue{Value: _testPodID},
+				ContainerID: mesosContainerID{Value: "other-container-id"},
+			},
+			{
+				FrameworkID: mesosValue{Value: _testFrameworkID},
+				ExecutorID:  mesosValue{Value: _testPodID},
+				ContainerID: mesosContainerID{Value: _testContainerID},
+			},
+		},
+		stdout:    "hello",
+		stderr:    "world",
+		stdinDone: make(chan struct{}),
+	}
+	suite.server = httptest.NewServer(suite.agent)
+
+	agentPID := "slave(1)@" + strings.TrimPrefix(suite.server.URL, "http://")
+	agent := &mesos_master.Response_GetAgents_Agent{
+		Pid: &agentPID,
+	}
+
+	suite.backend = NewMesosBackend(
+		Config{},
+		suite.frameworkInfoProvider,
+	).(*mesosBackend)
+	suite.backend.getAgent = func(
+		hostname string) *mesos_master.Response_GetAgents_Agent {
+		if hostname == _testHostname {
+			return agent
+		}
+		return nil
+	}
+}
+
+func (suite *MesosBackendTestSuite) TearDownTest() {
+	suite.server.Close()
+	suite.ctrl.Finish()
+}
+
+// exec runs a command in the test pod and returns its output
+func (suite *MesosBackendTestSuite) exec(
+	req *Request,
+	stdin string,
+) (string, string, int32, error) {
+	var stdout, stderr bytes.Buffer
+	streams := &Streams{Stdout: &stdout, Stderr: &stderr}
+	if len(stdin) > 0 {
+		streams.Stdin = strings.NewReader(stdin)
+	}
+
+	exitCode, err := suite.backend.Exec(context.Background(), req, streams)
+	return stdout.String(), stderr.String(), exitCode, err
+}
+
+// TestExec tests running a command in a nested container
+func (suite *MesosBackendTestSuite) TestExec() {
+	suite.agent.exitStatus = 3 << 8
+
+	stdout, stderr, exitCode, err := suite.exec(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+		Command:  []string{"ls", "-l"},
+	}, "")
+	suite.NoError(err)
+	suite.Equal("hello", stdout)
+	suite.Equal("world", stderr)
+	suite.Equal(int32(3), exitCode)
+
+	launch := suite.agent.launch
+	suite.Equal(_testContainerID, launch.ContainerID.Parent.Value)
+	suite.NotEmpty(launch.ContainerID.Value)
+	suite.Equal("ls", launch.Command.Value)
+	suite.Equal([]string{"ls", "-l"}, launch.Command.Arguments)
+	suite.False(launch.Command.Shell)
+	suite.Nil(launch.Container)
+}
+
+// TestExecStdin tests running a command with a standard input and a TTY
+// in the nested container of a pod launched by thermos executor
+func (suite *MesosBackendTestSuite) TestExecStdin() {
+	suite.agent.containers[1].ExecutorID.Value = "thermos-" + _testPodID
+	suite.agent.echoStdin = true
+
+	stdout, _, exitCode, err := suite.exec(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+		Command:  []string{"cat"},
+		TTY:      true,
+	}, "some input")
+	suite.NoError(err)
+	suite.Equal("hellosome input", stdout)
+	suite.Equal(int32(0), exitCode)
+	suite.NotNil(suite.agent.launch.Container.TTYInfo)
+}
+
+// TestExecKilled tests the exit code of a command killed by a signal
+func (suite *MesosBackendTestSuite) TestExecKilled() {
+	suite.agent.exitStatus = 9
+
+	_, _, exitCode, err := suite.exec(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+		Command:  []string{"sleep", "100"},
+	}, "")
+	suite.NoError(err)
+	suite.Equal(int32(137), exitCode)
+}
+
+// TestExecAgentNotFound tests running a command on an unknown host
+func (suite *MesosBackendTestSuite) TestExecAgentNotFound() {
+	_, _, _, err := suite.exec(&Request{
+		Hostname: "unknown-host",
+		PodID:    _testPodID,
+		Command:  []string{"ls"},
+	}, "")
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestExecContainerNotFound tests running a command in a pod which
+// does not run on the agent
+func (suite *MesosBackendTestSuite) TestExecContainerNotFound() {
+	_, _, _, err := suite.exec(&Request{
+		Hostname: _testHostname,
+		PodID:    "unknown-pod-id",
+		Command:  []string{"ls"},
+	}, "")
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestExecLaunchFailure tests failure of launching the nested container
+func (suite *MesosBackendTestSuite) TestExecLaunchFailure() {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if strings.Contains(string(body), _callGetContainers) {
+				suite.agent.ServeHTTP(
+					w, httptest.NewRequest(
+						http.MethodPost, "/api/v1", bytes.NewReader(body)))
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+	defer server.Close()
+
+	agentPID := "slave(1)@" + strings.TrimPrefix(server.URL, "http://")
+	suite.backend.getAgent = func(
+		hostname string) *mesos_master.Response_GetAgents_Agent {
+		return &mesos_master.Response_GetAgents_Agent{Pid: &agentPID}
+	}
+
+	_, _, _, err := suite.exec(&Request{
+		Hostname: _testHostname,
+		PodID:    _testPodID,
+		Command:  []string{"ls"},
+	}, "")
+	suite.Error(err)
+}
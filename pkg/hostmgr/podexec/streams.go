@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podexec
+
+import (
+	"io"
+	"sync"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+)
+
+// stdinReader reads the standard input of a command from the messages
+// received on a stream.
+type stdinReader struct {
+	recv func() ([]byte, error)
+	buf  []byte
+	eof  bool
+}
+
+// NewStdinReader returns a reader of the standard input of a command,
+// starting with the given data and then receiving data with the given
+// function, until it returns an error or empty data.
+func NewStdinReader(data []byte, recv func() ([]byte, error)) io.Reader {
+	return &stdinReader{recv: recv, buf: data}
+}
+
+// Read implements io.Reader.Read.
+func (r *stdinReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		data, err := r.recv()
+		if err != nil || len(data) == 0 {
+			// the stream is closed or the client closed the
+			// standard input, either way there is no more input
+			r.eof = true
+			continue
+		}
+		r.buf = data
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// outputWriter sends the data written to an output stream of a command.
+type outputWriter struct {
+	// lock serializes the sends of stdout and stderr,
+	// which share the same stream
+	lock   *sync.Mutex
+	stream hostsvc.LogStream
+	send   func(stream hostsvc.LogStream, data []byte) error
+}
+
+// NewOutputWriters returns the writers of the standard output and the
+// standard error of a command, sending the data with the given function.
+func NewOutputWriters(
+	send func(stream hostsvc.LogStream, data []byte) error,
+) (stdout io.Writer, stderr io.Writer) {
+	lock := &sync.Mutex{}
+	stdout = &outputWriter{
+		lock:   lock,
+		stream: hostsvc.LogStream_LOG_STREAM_STDOUT,
+		send:   send,
+	}
+	stderr = &outputWriter{
+		lock:   lock,
+		stream: hostsvc.LogStream_LOG_STREAM_STDERR,
+		send:   send,
+	}
+	return stdout, stderr
+}
+
+// Write implements io.Writer.Write.
+func (w *outputWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// the caller may reuse p once Write returns
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.send(w.stream, data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podexec
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdinReader(t *testing.T) {
+	messages := [][]byte{[]byte("world"), {}, []byte("ignored")}
+	r := NewStdinReader([]byte("hello "), func() ([]byte, error) {
+		msg := messages[0]
+		messages = messages[1:]
+		return msg, nil
+	})
+
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	assert.Len(t, messages, 1)
+}
+
+func TestStdinReaderStreamClosed(t *testing.T) {
+	r := NewStdinReader(nil, func() ([]byte, error) {
+		return nil, io.EOF
+	})
+
+	buf := make([]byte, 10)
+	n, err := r.Read(buf)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestOutputWriters(t *testing.T) {
+	var streams []hostsvc.LogStream
+	var data []string
+	stdout, stderr := NewOutputWriters(
+		func(stream hostsvc.LogStream, d []byte) error {
+			streams = append(streams, stream)
+			data = append(data, string(d))
+			return nil
+		})
+
+	buf := []byte("hello")
+	n, err := stdout.Write(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	copy(buf, "xxxxx")
+
+	_, err = stderr.Write([]byte("world"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []hostsvc.LogStream{
+		hostsvc.LogStream_LOG_STREAM_STDOUT,
+		hostsvc.LogStream_LOG_STREAM_STDERR,
+	}, streams)
+	assert.Equal(t, []string{"hello", "world"}, data)
+}
+
+func TestOutputWritersSendFailure(t *testing.T) {
+	stdout, _ := NewOutputWriters(
+		func(stream hostsvc.LogStream, d []byte) error {
+			return errors.New("send failed")
+		})
+
+	_, err := stdout.Write([]byte("hello"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podusage
+
+import (
+	"context"
+	"time"
+)
+
+// Sample is the resource usage of a pod at a point in time
+type Sample struct {
+	// ID of the pod run, i.e. the Mesos task ID or the Kubernetes pod name.
+	PodID string
+
+	// Time the usage was measured at.
+	Timestamp time.Time
+
+	// Number of CPUs used, averaged since the previous measure.
+	CPUs float64
+
+	// Memory used, in bytes.
+	MemBytes uint64
+
+	// Disk used, in bytes.
+	DiskBytes uint64
+}
+
+// Backend collects the resource usage of pods from an underlying
+// cluster manager.
+type Backend interface {
+	// Collect returns the current resource usage of the pods running
+	// in the cluster.
+	Collect(ctx context.Context) ([]*Sample, error)
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podusage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/background"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/atomic"
+	"github.com/uber-go/tally"
+)
+
+const _collectorWorkName = "podUsageCollector"
+
+// Collector periodically collects the resource usage of the pods and
+// keeps a short time series of samples per pod.
+type Collector interface {
+	// Register registers the periodic collection in the background manager.
+	Register(manager background.Manager) error
+
+	// GetUsage returns the samples kept for the given pods, oldest first.
+	// Pods without any sample are omitted.
+	GetUsage(podIDs []string) map[string][]*Sample
+}
+
+// collector implements Collector.
+type collector struct {
+	sync.RWMutex
+
+	cfg     Config
+	backend Backend
+	metrics *metrics
+
+	// samples of each pod, oldest first
+	samples map[string][]*Sample
+}
+
+// metrics of the collection of the resource usage of the pods
+type metrics struct {
+	collectSuccess tally.Counter
+	collectFail    tally.Counter
+	collectLatency tally.Timer
+	pods           tally.Gauge
+}
+
+// NewCollector returns a Collector collecting the resource usage of the
+// pods from the backend.
+func NewCollector(cfg Config, backend Backend, parent tally.Scope) Collector {
+	cfg.normalize()
+	scope := parent.SubScope("pod_usage")
+	return &collector{
+		cfg:     cfg,
+		backend: backend,
+		metrics: &metrics{
+			collectSuccess: scope.Counter("collect_success"),
+			collectFail:    scope.Counter("collect_fail"),
+			collectLatency: scope.Timer("collect_latency"),
+			pods:           scope.Gauge("pods"),
+		},
+		samples: make(map[string][]*Sample),
+	}
+}
+
+// Register implements Collector.Register.
+func (c *collector) Register(manager background.Manager) error {
+	return manager.RegisterWorks(
+		background.Work{
+			Name:   _collectorWorkName,
+			Func:   c.collect,
+			Period: c.cfg.CollectPeriod,
+		},
+	)
+}
+
+// GetUsage implements Collector.GetUsage.
+func (c *collector) GetUsage(podIDs []string) map[string][]*Sample {
+	c.RLock()
+	defer c.RUnlock()
+
+	usage := make(map[string][]*Sample)
+	for _, podID := range podIDs {
+		if samples, ok := c.samples[podID]; ok {
+			usage[podID] = append([]*Sample(nil), samples...)
+		}
+	}
+	return usage
+}
+
+func (c *collector) collect(_ *atomic.Bool) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), c.cfg.CollectPeriod)
+	defer cancel()
+
+	start := time.Now()
+	samples, err := c.backend.Collect(ctx)
+	c.metrics.collectLatency.Record(time.Since(start))
+	if err != nil {
+		log.WithError(err).Warn("failed to collect pod resource usage")
+		c.metrics.collectFail.Inc(1)
+		return
+	}
+	c.metrics.collectSuccess.Inc(1)
+	c.add(samples, time.Now())
+}
+
+// add adds the samples collected to the time series of the pods, and drops
+// the samples older than the retention, e.g. those of terminated pods.
+func (c *collector) add(samples []*Sample, now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, sample := range samples {
+		series := append(c.samples[sample.PodID], sample)
+		if len(series) > c.cfg.Retention {
+			series = series[len(series)-c.cfg.Retention:]
+		}
+		c.samples[sample.PodID] = series
+	}
+
+	cutoff := now.Add(-time.Duration(c.cfg.Retention) * c.cfg.CollectPeriod)
+	for podID, series := range c.samples {
+		i := 0
+		for i < len(series) && series[i].Timestamp.Before(cutoff) {
+			i++
+		}
+		if i == len(series) {
+			delete(c.samples, podID)
+			continue
+		}
+		c.samples[podID] = series[i:]
+	}
+	c.metrics.pods.Update(float64(len(c.samples)))
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podusage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/background"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/atomic"
+	"github.com/uber-go/tally"
+)
+
+// fakeBackend returns the samples or error set by the test
+type fakeBackend struct {
+	samples []*Sample
+	err     error
+}
+
+func (b *fakeBackend) Collect(ctx context.Context) ([]*Sample, error) {
+	return b.samples, b.err
+}
+
+type CollectorTestSuite struct {
+	suite.Suite
+
+	backend   *fakeBackend
+	scope     tally.TestScope
+	collector *collector
+}
+
+func TestCollector(t *testing.T) {
+	suite.Run(t, new(CollectorTestSuite))
+}
+
+func (suite *CollectorTestSuite) SetupTest() {
+	suite.backend = &fakeBackend{}
+	suite.scope = tally.NewTestScope("", nil)
+	suite.collector = NewCollector(
+		Config{CollectPeriod: time.Minute, Retention: 3},
+		suite.backend,
+		suite.scope,
+	).(*collector)
+}
+
+// TestCollect tests collecting the usage of the pods periodically.
+func (suite *CollectorTestSuite) TestCollect() {
+	now := time.Now()
+	suite.backend.samples = []*Sample{
+		{PodID: "pod-1", Timestamp: now, CPUs: 1},
+		{PodID: "pod-2", Timestamp: now, CPUs: 2},
+	}
+	suite.collector.collect(atomic.NewBool(true))
+
+	usage := suite.collector.GetUsage([]string{"pod-1", "pod-3"})
+	suite.Equal(map[string][]*Sample{
+		"pod-1": {{PodID: "pod-1", Timestamp: now, CPUs: 1}},
+	}, usage)
+	suite.Equal(int64(1),
+		suite.scope.Snapshot().Counters()["pod_usage.collect_success+"].Value())
+}
+
+// TestCollectFailure tests that the samples are kept when the collection
+// fails.
+func (suite *CollectorTestSuite) TestCollectFailure() {
+	now := time.Now()
+	suite.collector.add([]*Sample{{PodID: "pod-1", Timestamp: now}}, now)
+
+	suite.backend.err = errors.New("collection failed")
+	suite.collector.collect(atomic.NewBool(true))
+
+	suite.Len(suite.collector.GetUsage([]string{"pod-1"}), 1)
+	suite.Equal(int64(1),
+		suite.scope.Snapshot().Counters()["pod_usage.collect_fail+"].Value())
+}
+
+// TestRetention tests that only the most recent samples are kept, and that
+// the pods whose samples all expired are dropped.
+func (suite *CollectorTestSuite) TestRetention() {
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		now := start.Add(time.Duration(i) * time.Minute)
+		samples := []*Sample{{PodID: "pod-1", Timestamp: now, CPUs: float64(i)}}
+		if i == 0 {
+			samples = append(samples, &Sample{PodID: "pod-2", Timestamp: now})
+		}
+		suite.collector.add(samples, now)
+	}
+
+	usage := suite.collector.GetUsage([]string{"pod-1", "pod-2"})
+	suite.Len(usage, 1)
+	suite.Len(usage["pod-1"], 3)
+	suite.Equal(2.0, usage["pod-1"][0].CPUs)
+	suite.Equal(4.0, usage["pod-1"][2].CPUs)
+}
+
+// TestRegister tests registering the collection as a background work.
+func (suite *CollectorTestSuite) TestRegister() {
+	manager := background.NewManager()
+	suite.NoError(suite.collector.Register(manager))
+	suite.Error(suite.collector.Register(manager))
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podusage
+
+import (
+	"time"
+)
+
+const (
+	_defaultCollectPeriod = 30 * time.Second
+	_defaultRetention     = 20
+	_defaultHTTPTimeout   = 10 * time.Second
+	_defaultConcurrency   = 16
+)
+
+// Config for collecting the resource usage of pods
+type Config struct {
+	// Period of the collection of the resource usage of the pods.
+	CollectPeriod time.Duration `yaml:"collect_period"`
+
+	// Number of samples of resource usage kept per pod.
+	Retention int `yaml:"retention"`
+
+	// Timeout of the requests to the Mesos agents or the K8s metrics API.
+	HTTPTimeout time.Duration `yaml:"http_timeout"`
+
+	// Maximum number of Mesos agents polled concurrently.
+	Concurrency int `yaml:"concurrency"`
+}
+
+func (c *Config) normalize() {
+	if c.CollectPeriod <= 0 {
+		c.CollectPeriod = _defaultCollectPeriod
+	}
+	if c.Retention <= 0 {
+		c.Retention = _defaultRetention
+	}
+	if c.HTTPTimeout <= 0 {
+		c.HTTPTimeout = _defaultHTTPTimeout
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = _defaultConcurrency
+	}
+}
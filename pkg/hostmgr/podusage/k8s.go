@@ -0,0 +1,73 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podusage
+
+import (
+	"context"
+
+	"github.com/uber/peloton/pkg/hostmgr/p2k/plugins/k8s"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// k8sBackend collects the resource usage of pods from the K8s metrics API,
+// served by the metrics server of the cluster.
+type k8sBackend struct {
+	metricsClient metricsclient.Interface
+}
+
+// NewK8sBackend returns a Backend collecting the resource usage of pods
+// running on K8s, using the kubeconfig file at the given path.
+func NewK8sBackend(kubeConfigPath string) (Backend, error) {
+	_, kubeConfig, err := k8s.NewKubeClient(kubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	metricsClient, err := metricsclient.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newK8sBackendWithClient(metricsClient), nil
+}
+
+func newK8sBackendWithClient(metricsClient metricsclient.Interface) *k8sBackend {
+	return &k8sBackend{metricsClient: metricsClient}
+}
+
+// Collect implements Backend.Collect. The metrics API does not report
+// the disk usage of pods.
+func (k *k8sBackend) Collect(ctx context.Context) ([]*Sample, error) {
+	podMetricsList, err := k.metricsClient.MetricsV1beta1().
+		PodMetricses(k8s.PodNamespace).
+		List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []*Sample
+	for _, podMetrics := range podMetricsList.Items {
+		sample := &Sample{
+			PodID:     podMetrics.Name,
+			Timestamp: podMetrics.Timestamp.Time,
+		}
+		for _, c := range podMetrics.Containers {
+			sample.CPUs += float64(c.Usage.Cpu().MilliValue()) / 1000
+			sample.MemBytes += uint64(c.Usage.Memory().Value())
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
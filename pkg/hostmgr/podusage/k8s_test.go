@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podusage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/hostmgr/p2k/plugins/k8s"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestK8sBackendCollect(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1000, 0)
+	containerMetrics := func(cpu, mem string) metricsv1beta1.ContainerMetrics {
+		return metricsv1beta1.ContainerMetrics{
+			Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(mem),
+			},
+		}
+	}
+
+	metricsClient := metricsfake.NewSimpleClientset()
+	metricsClient.PrependReactor(
+		"list",
+		"pods",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			require.Equal(k8s.PodNamespace, action.GetNamespace())
+			return true, &metricsv1beta1.PodMetricsList{
+				Items: []metricsv1beta1.PodMetrics{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "pod"},
+						Timestamp:  metav1.NewTime(now),
+						Containers: []metricsv1beta1.ContainerMetrics{
+							containerMetrics("500m", "50M"),
+							containerMetrics("250m", "10M"),
+						},
+					},
+				},
+			}, nil
+		})
+
+	backend := newK8sBackendWithClient(metricsClient)
+	samples, err := backend.Collect(context.Background())
+	require.NoError(err)
+	require.Equal([]*Sample{
+		{
+			PodID:     "pod",
+			Timestamp: now,
+			CPUs:      0.75,
+			MemBytes:  60000000,
+		},
+	}, samples)
+}
+
+func TestK8sBackendCollectFailure(t *testing.T) {
+	metricsClient := metricsfake.NewSimpleClientset()
+	metricsClient.PrependReactor(
+		"list",
+		"pods",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("metrics server unavailable")
+		})
+
+	backend := newK8sBackendWithClient(metricsClient)
+	_, err := backend.Collect(context.Background())
+	require.Error(t, err)
+}
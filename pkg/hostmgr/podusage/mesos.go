@@ -0,0 +1,197 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podusage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	"github.com/uber/peloton/pkg/hostmgr/host"
+	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	_mesosStatisticsURL = "http://%s/monitor/statistics"
+
+	// Prefix of the executor ID of the pods run by the Thermos executor
+	_thermosExecutorIDPrefix = "thermos-"
+)
+
+// mesosStatistics is an entry of the Mesos agent monitor/statistics
+// endpoint, i.e. the resource usage of an executor.
+type mesosStatistics struct {
+	FrameworkID string `json:"framework_id"`
+	ExecutorID  string `json:"executor_id"`
+	Statistics  struct {
+		Timestamp          float64 `json:"timestamp"`
+		CPUsUserTimeSecs   float64 `json:"cpus_user_time_secs"`
+		CPUsSystemTimeSecs float64 `json:"cpus_system_time_secs"`
+		MemRSSBytes        uint64  `json:"mem_rss_bytes"`
+		DiskUsedBytes      uint64  `json:"disk_used_bytes"`
+	} `json:"statistics"`
+}
+
+// cpuTime is the cumulative CPU time of a pod at a point in time.
+type cpuTime struct {
+	timestamp float64
+	secs      float64
+}
+
+// mesosBackend collects the resource usage of pods from the statistics
+// of the executors on the Mesos agents.
+type mesosBackend struct {
+	cfg                   Config
+	client                *http.Client
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider
+
+	// getAgents returns the registered Mesos agents.
+	getAgents func() []*mesos_master.Response_GetAgents_Agent
+
+	sync.Mutex
+	// cumulative CPU time of the pods at the previous collection, to
+	// compute the CPU usage between two collections
+	cpuTimes map[string]cpuTime
+}
+
+// NewMesosBackend returns a Backend collecting the resource usage of
+// pods running on Mesos agents.
+func NewMesosBackend(
+	cfg Config,
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider,
+) Backend {
+	cfg.normalize()
+	return &mesosBackend{
+		cfg:                   cfg,
+		client:                &http.Client{Timeout: cfg.HTTPTimeout},
+		frameworkInfoProvider: frameworkInfoProvider,
+		getAgents:             host.GetRegisteredAgents,
+		cpuTimes:              make(map[string]cpuTime),
+	}
+}
+
+// Collect implements Backend.Collect.
+func (m *mesosBackend) Collect(ctx context.Context) ([]*Sample, error) {
+	frameworkID := m.frameworkInfoProvider.GetFrameworkID(ctx).GetValue()
+	agents := m.getAgents()
+
+	var lock sync.Mutex
+	var stats []*mesosStatistics
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, m.cfg.Concurrency)
+	for _, agent := range agents {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(agent *mesos_master.Response_GetAgents_Agent) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			agentStats, err := m.getStatistics(ctx, host.GetAgentAddress(agent))
+			if err != nil {
+				// the pods of the agent are collected at the next period
+				log.WithError(err).
+					WithField("hostname", agent.GetAgentInfo().GetHostname()).
+					Info("failed to get executor statistics of mesos agent")
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			for _, s := range agentStats {
+				if s.FrameworkID == frameworkID {
+					stats = append(stats, s)
+				}
+			}
+		}(agent)
+	}
+	wg.Wait()
+
+	return m.toSamples(stats), nil
+}
+
+// toSamples converts the executor statistics to samples. The CPU usage is
+// averaged since the previous collection, so pods observed for the first
+// time are only sampled from the next collection on.
+func (m *mesosBackend) toSamples(stats []*mesosStatistics) []*Sample {
+	m.Lock()
+	defer m.Unlock()
+
+	var samples []*Sample
+	cpuTimes := make(map[string]cpuTime)
+	for _, s := range stats {
+		podID := strings.TrimPrefix(s.ExecutorID, _thermosExecutorIDPrefix)
+		current := cpuTime{
+			timestamp: s.Statistics.Timestamp,
+			secs:      s.Statistics.CPUsUserTimeSecs + s.Statistics.CPUsSystemTimeSecs,
+		}
+		cpuTimes[podID] = current
+
+		prev, ok := m.cpuTimes[podID]
+		if !ok || current.timestamp <= prev.timestamp {
+			continue
+		}
+
+		secs, frac := math.Modf(current.timestamp)
+		samples = append(samples, &Sample{
+			PodID:     podID,
+			Timestamp: time.Unix(int64(secs), int64(frac*float64(time.Second))),
+			CPUs: math.Max(0, (current.secs-prev.secs)/
+				(current.timestamp-prev.timestamp)),
+			MemBytes:  s.Statistics.MemRSSBytes,
+			DiskBytes: s.Statistics.DiskUsedBytes,
+		})
+	}
+	m.cpuTimes = cpuTimes
+	return samples
+}
+
+// getStatistics returns the resource usage of the executors running on a
+// Mesos agent.
+func (m *mesosBackend) getStatistics(
+	ctx context.Context,
+	agentAddr string,
+) ([]*mesosStatistics, error) {
+	req, err := http.NewRequest(
+		http.MethodGet, fmt.Sprintf(_mesosStatisticsURL, agentAddr), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"mesos agent statistics request failed: %s", resp.Status)
+	}
+
+	var stats []*mesosStatistics
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
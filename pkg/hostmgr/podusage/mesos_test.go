@@ -0,0 +1,189 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podusage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+
+	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	_testFrameworkID = "test-framework-id"
+	_testPodID       = "test-pod-id"
+)
+
+// fakeMesosAgent serves the monitor/statistics endpoint of a Mesos agent
+type fakeMesosAgent struct {
+	sync.Mutex
+	stats []*mesosStatistics
+}
+
+func (a *fakeMesosAgent) setStatistics(stats ...*mesosStatistics) {
+	a.Lock()
+	defer a.Unlock()
+	a.stats = stats
+}
+
+func (a *fakeMesosAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.Lock()
+	defer a.Unlock()
+
+	if r.URL.Path != "/monitor/statistics" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(a.stats)
+}
+
+func newMesosStatistics(
+	frameworkID, executorID string,
+	timestamp, cpuSecs float64,
+	memBytes uint64,
+) *mesosStatistics {
+	s := &mesosStatistics{
+		FrameworkID: frameworkID,
+		ExecutorID:  executorID,
+	}
+	s.Statistics.Timestamp = timestamp
+	s.Statistics.CPUsUserTimeSecs = cpuSecs / 2
+	s.Statistics.CPUsSystemTimeSecs = cpuSecs / 2
+	s.Statistics.MemRSSBytes = memBytes
+	s.Statistics.DiskUsedBytes = 2 * memBytes
+	return s
+}
+
+type MesosBackendTestSuite struct {
+	suite.Suite
+
+	ctrl                  *gomock.Controller
+	frameworkInfoProvider *hostmgr_mesos_mocks.MockFrameworkInfoProvider
+	agent                 *fakeMesosAgent
+	server                *httptest.Server
+	backend               *mesosBackend
+}
+
+func TestMesosBackend(t *testing.T) {
+	suite.Run(t, new(MesosBackendTestSuite))
+}
+
+func (suite *MesosBackendTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.frameworkInfoProvider = hostmgr_mesos_mocks.NewMockFrameworkInfoProvider(suite.ctrl)
+	suite.frameworkInfoProvider.EXPECT().
+		GetFrameworkID(gomock.Any()).
+		Return(&mesos.FrameworkID{Value: &[]string{_testFrameworkID}[0]}).
+		AnyTimes()
+
+	suite.agent = &fakeMesosAgent{}
+	suite.server = httptest.NewServer(suite.agent)
+
+	agentPID := "slave(1)@" + strings.TrimPrefix(suite.server.URL, "http://")
+	agent := &mesos_master.Response_GetAgents_Agent{
+		AgentInfo: &mesos.AgentInfo{},
+		Pid:       &agentPID,
+	}
+	unreachablePID := "slave(1)@127.0.0.1:1"
+	unreachableAgent := &mesos_master.Response_GetAgents_Agent{
+		AgentInfo: &mesos.AgentInfo{},
+		Pid:       &unreachablePID,
+	}
+
+	suite.backend = NewMesosBackend(
+		Config{},
+		suite.frameworkInfoProvider,
+	).(*mesosBackend)
+	suite.backend.getAgents = func() []*mesos_master.Response_GetAgents_Agent {
+		return []*mesos_master.Response_GetAgents_Agent{agent, unreachableAgent}
+	}
+}
+
+func (suite *MesosBackendTestSuite) TearDownTest() {
+	suite.server.Close()
+	suite.ctrl.Finish()
+}
+
+// TestCollect tests collecting the usage of the pods of the framework,
+// the CPU usage being averaged between two collections.
+func (suite *MesosBackendTestSuite) TestCollect() {
+	suite.agent.setStatistics(
+		newMesosStatistics(_testFrameworkID, _testPodID, 100, 10, 1024),
+		newMesosStatistics(_testFrameworkID, "thermos-thermos-pod-id", 100, 10, 1024),
+		newMesosStatistics("other-framework-id", "other-pod-id", 100, 10, 1024),
+	)
+
+	// pods observed for the first time are not sampled
+	samples, err := suite.backend.Collect(context.Background())
+	suite.NoError(err)
+	suite.Empty(samples)
+
+	suite.agent.setStatistics(
+		newMesosStatistics(_testFrameworkID, _testPodID, 110, 15, 2048),
+		newMesosStatistics(_testFrameworkID, "thermos-thermos-pod-id", 110, 30, 4096),
+		newMesosStatistics("other-framework-id", "other-pod-id", 110, 15, 2048),
+	)
+
+	samples, err = suite.backend.Collect(context.Background())
+	suite.NoError(err)
+	suite.Len(samples, 2)
+
+	byPod := make(map[string]*Sample)
+	for _, s := range samples {
+		byPod[s.PodID] = s
+	}
+	suite.Equal(0.5, byPod[_testPodID].CPUs)
+	suite.Equal(uint64(2048), byPod[_testPodID].MemBytes)
+	suite.Equal(uint64(4096), byPod[_testPodID].DiskBytes)
+	suite.Equal(int64(110), byPod[_testPodID].Timestamp.Unix())
+	suite.Equal(2.0, byPod["thermos-pod-id"].CPUs)
+}
+
+// TestCollectRestartedPod tests that the CPU usage of a pod is never
+// negative, e.g. if its cumulative CPU time is reset.
+func (suite *MesosBackendTestSuite) TestCollectRestartedPod() {
+	suite.agent.setStatistics(
+		newMesosStatistics(_testFrameworkID, _testPodID, 100, 10, 1024))
+	_, err := suite.backend.Collect(context.Background())
+	suite.NoError(err)
+
+	suite.agent.setStatistics(
+		newMesosStatistics(_testFrameworkID, _testPodID, 110, 1, 1024))
+	samples, err := suite.backend.Collect(context.Background())
+	suite.NoError(err)
+	suite.Len(samples, 1)
+	suite.Equal(0.0, samples[0].CPUs)
+}
+
+// TestCollectNoAgents tests collecting the usage without any agents.
+func (suite *MesosBackendTestSuite) TestCollectNoAgents() {
+	suite.backend.getAgents = func() []*mesos_master.Response_GetAgents_Agent {
+		return nil
+	}
+	samples, err := suite.backend.Collect(context.Background())
+	suite.NoError(err)
+	suite.Empty(samples)
+}
@@ -27,6 +27,11 @@ type Metrics struct {
 	ReconcileExplicitlyAbort tally.Counter
 	ReconcileExplicitlyFail  tally.Counter
 	ReconcileGetTasksFail    tally.Counter
+	ReconcileOnDemand        tally.Counter
+	ReconcileOnDemandFail    tally.Counter
+
+	MissingInMesos tally.Gauge
+	MesosOrphans   tally.Gauge
 
 	ExplicitTasksPerRun tally.Gauge
 }
@@ -42,6 +47,11 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		ReconcileExplicitlyAbort: failScope.Counter("explicitly_abort_total"),
 		ReconcileExplicitlyFail:  failScope.Counter("explicitly_total"),
 		ReconcileGetTasksFail:    failScope.Counter("explicitly_gettasks_total"),
+		ReconcileOnDemand:        successScope.Counter("on_demand_total"),
+		ReconcileOnDemandFail:    failScope.Counter("on_demand_total"),
+
+		MissingInMesos: scope.Gauge("missing_in_mesos"),
+		MesosOrphans:   scope.Gauge("mesos_orphans"),
 
 		ExplicitTasksPerRun: scope.Gauge("explicit_tasks_per_run"),
 	}
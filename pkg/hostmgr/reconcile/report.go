@@ -0,0 +1,271 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/hostmgr/host"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var errJobAndHostFilter = errors.New(
+	"only one of job and hostname can be set for reconciliation")
+
+// mesosTask is a task known to Mesos master along with its state.
+type mesosTask struct {
+	task  *mesos.Task
+	state mesos.TaskState
+}
+
+// ReconcileTasks explicitly reconciles the non-terminal tasks of a job,
+// of a host or all of them if neither is set, and reports the tasks on
+// which Peloton and Mesos master disagree.
+func (r *taskReconciler) ReconcileTasks(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	hostname string,
+) (*hostsvc.ReconcileTasksReport, error) {
+	if jobID.GetValue() != "" && hostname != "" {
+		return nil, errJobAndHostFilter
+	}
+
+	report, err := r.reconcileTasks(ctx, jobID, hostname)
+	if err != nil {
+		r.metrics.ReconcileOnDemandFail.Inc(1)
+		return nil, err
+	}
+
+	r.metrics.ReconcileOnDemand.Inc(1)
+	r.metrics.MissingInMesos.Update(float64(len(report.GetMissingInMesos())))
+	r.metrics.MesosOrphans.Update(float64(len(report.GetOrphans())))
+	log.WithFields(log.Fields{
+		"job_id":           jobID.GetValue(),
+		"hostname":         hostname,
+		"peloton_tasks":    report.GetPelotonTasks(),
+		"mesos_tasks":      report.GetMesosTasks(),
+		"missing_in_mesos": len(report.GetMissingInMesos()),
+		"orphans":          len(report.GetOrphans()),
+	}).Info("Reconcile tasks on demand returned.")
+	return report, nil
+}
+
+func (r *taskReconciler) reconcileTasks(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	hostname string,
+) (*hostsvc.ReconcileTasksReport, error) {
+	var agentID string
+	if hostname != "" {
+		agentID = host.GetAgentInfo(hostname).GetId().GetValue()
+		if agentID == "" {
+			return nil, errors.Errorf("host %s is not registered", hostname)
+		}
+	}
+
+	// Mesos tasks are read before Peloton tasks, so that a task launched
+	// in between is reported as missing in Mesos instead of as an orphan,
+	// which would get it killed.
+	mesosTasks, err := r.getMesosTasks(ctx, jobID, agentID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get tasks from Mesos master")
+	}
+
+	pelotonTasks, err := r.getPelotonTasks(ctx, jobID, hostname)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get tasks from datastore")
+	}
+
+	// Let Mesos master send the latest status of the tasks, so that
+	// the state in Peloton converges to the one in Mesos.
+	var reconcileTasks []*sched.Call_Reconcile_Task
+	for _, taskInfo := range pelotonTasks {
+		reconcileTasks = append(reconcileTasks, toReconcileTask(taskInfo))
+	}
+	for i := 0; i < len(reconcileTasks); i += r.explicitReconcileBatchSize {
+		end := i + r.explicitReconcileBatchSize
+		if end > len(reconcileTasks) {
+			end = len(reconcileTasks)
+		}
+		if err := r.callReconcile(ctx, reconcileTasks[i:end]); err != nil {
+			return nil, errors.Wrap(err, "failed to reconcile tasks explicitly")
+		}
+	}
+
+	agentHostnames := getAgentHostnames()
+	report := &hostsvc.ReconcileTasksReport{
+		PelotonTasks: uint32(len(pelotonTasks)),
+	}
+	for taskID, taskInfo := range pelotonTasks {
+		mt, ok := mesosTasks[taskID]
+		if ok && !isMesosStateTerminal(mt.state) {
+			continue
+		}
+		missing := &hostsvc.ReconciledTask{
+			TaskId:       taskInfo.GetRuntime().GetMesosTaskId(),
+			AgentId:      taskInfo.GetRuntime().GetAgentID(),
+			Hostname:     taskInfo.GetRuntime().GetHost(),
+			PelotonState: taskInfo.GetRuntime().GetState().String(),
+		}
+		if ok {
+			missing.MesosState = mt.state.String()
+		}
+		report.MissingInMesos = append(report.MissingInMesos, missing)
+	}
+
+	for taskID, mt := range mesosTasks {
+		if isMesosStateTerminal(mt.state) {
+			continue
+		}
+		report.MesosTasks++
+		if _, ok := pelotonTasks[taskID]; ok {
+			continue
+		}
+		report.Orphans = append(report.Orphans, &hostsvc.ReconciledTask{
+			TaskId:     mt.task.GetTaskId(),
+			AgentId:    mt.task.GetAgentId(),
+			Hostname:   agentHostnames[mt.task.GetAgentId().GetValue()],
+			MesosState: mt.state.String(),
+		})
+	}
+	return report, nil
+}
+
+// getPelotonTasks returns the non-terminal tasks of the job, or of all
+// active jobs, by Mesos task id. The tasks are filtered by hostname if
+// it is set.
+func (r *taskReconciler) getPelotonTasks(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	hostname string,
+) (map[string]*task.TaskInfo, error) {
+	var jobIDs []*peloton.JobID
+	if jobID.GetValue() != "" {
+		jobIDs = []*peloton.JobID{jobID}
+	} else {
+		activeJobIDs, err := r.activeJobsOps.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jobIDs = activeJobIDs
+	}
+
+	tasks := make(map[string]*task.TaskInfo)
+	for _, id := range jobIDs {
+		nonTerminalTasks, err := r.getNonTerminalTasks(ctx, id)
+		if err != nil {
+			r.metrics.ReconcileGetTasksFail.Inc(1)
+			return nil, err
+		}
+		for _, taskInfo := range nonTerminalTasks {
+			if hostname != "" && taskInfo.GetRuntime().GetHost() != hostname {
+				continue
+			}
+			tasks[taskInfo.GetRuntime().GetMesosTaskId().GetValue()] = taskInfo
+		}
+	}
+	return tasks, nil
+}
+
+// getMesosTasks returns the tasks of the Peloton framework known to
+// Mesos master by Mesos task id. The tasks are filtered by job and
+// agent id if set.
+func (r *taskReconciler) getMesosTasks(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	agentID string,
+) (map[string]*mesosTask, error) {
+	resp, err := r.masterOperatorClient.GetTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	frameworkID := r.frameworkInfoProvider.GetFrameworkID(ctx).GetValue()
+	tasks := make(map[string]*mesosTask)
+	add := func(mesosTasks []*mesos.Task, defaultState mesos.TaskState) {
+		for _, t := range mesosTasks {
+			if t.GetFrameworkId().GetValue() != frameworkID {
+				continue
+			}
+			if agentID != "" && t.GetAgentId().GetValue() != agentID {
+				continue
+			}
+			if jobID.GetValue() != "" {
+				taskJobID, _, err := util.ParseJobAndInstanceID(
+					t.GetTaskId().GetValue())
+				if err != nil || taskJobID != jobID.GetValue() {
+					continue
+				}
+			}
+			state := defaultState
+			if t.State != nil {
+				state = t.GetState()
+			}
+			tasks[t.GetTaskId().GetValue()] = &mesosTask{
+				task:  t,
+				state: state,
+			}
+		}
+	}
+
+	// Completed tasks are added first, so that a task which is also
+	// reported as active in Mesos master is not considered terminal.
+	add(resp.GetCompletedTasks(), mesos.TaskState_TASK_FINISHED)
+	add(resp.GetUnreachableTasks(), mesos.TaskState_TASK_UNREACHABLE)
+	add(resp.GetPendingTasks(), mesos.TaskState_TASK_STAGING)
+	add(resp.GetOrphanTasks(), mesos.TaskState_TASK_RUNNING)
+	add(resp.GetTasks(), mesos.TaskState_TASK_RUNNING)
+	return tasks, nil
+}
+
+// getAgentHostnames returns the hostnames of the registered agents by
+// agent id.
+func getAgentHostnames() map[string]string {
+	hostnames := make(map[string]string)
+	agentMap := host.GetAgentMap()
+	if agentMap == nil {
+		return hostnames
+	}
+	for hostname, agent := range agentMap.RegisteredAgents {
+		hostnames[agent.GetAgentInfo().GetId().GetValue()] = hostname
+	}
+	return hostnames
+}
+
+// isMesosStateTerminal returns true if the Mesos task state is terminal.
+func isMesosStateTerminal(state mesos.TaskState) bool {
+	switch state {
+	case mesos.TaskState_TASK_FINISHED,
+		mesos.TaskState_TASK_FAILED,
+		mesos.TaskState_TASK_KILLED,
+		mesos.TaskState_TASK_LOST,
+		mesos.TaskState_TASK_ERROR,
+		mesos.TaskState_TASK_DROPPED,
+		mesos.TaskState_TASK_GONE,
+		mesos.TaskState_TASK_GONE_BY_OPERATOR:
+		return true
+	default:
+		return false
+	}
+}
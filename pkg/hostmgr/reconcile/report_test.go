@@ -0,0 +1,221 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+)
+
+// newMesosTask returns a task of the given framework as reported by
+// Mesos master.
+func newMesosTask(
+	taskID string,
+	framework string,
+	state mesos.TaskState) *mesos.Task {
+	return &mesos.Task{
+		TaskId:      &mesos.TaskID{Value: &taskID},
+		FrameworkId: &mesos.FrameworkID{Value: &framework},
+		AgentId:     &mesos.AgentID{Value: util.PtrPrintf(testAgentID)},
+		State:       &state,
+	}
+}
+
+// newPelotonTask returns a task of the job in the given state.
+func newPelotonTask(
+	jobID *peloton.JobID,
+	instanceID uint32,
+	state task.TaskState) *task.TaskInfo {
+	return &task.TaskInfo{
+		Runtime: &task.RuntimeInfo{
+			MesosTaskId: util.CreateMesosTaskID(jobID, instanceID, 1),
+			State:       state,
+			AgentID:     &mesos.AgentID{Value: util.PtrPrintf(testAgentID)},
+		},
+		InstanceId: instanceID,
+		JobId:      jobID,
+	}
+}
+
+// TestReconcileTasksForJob tests reconciling the tasks of a single job
+// and the resulting report.
+func (suite *TaskReconcilerTestSuite) TestReconcileTasksForJob() {
+	jobID := &peloton.JobID{Value: uuid.New()}
+	otherJobID := &peloton.JobID{Value: uuid.New()}
+
+	pelotonTasks := map[uint32]*task.TaskInfo{
+		0: newPelotonTask(jobID, 0, task.TaskState_RUNNING),
+		1: newPelotonTask(jobID, 1, task.TaskState_RUNNING),
+		2: newPelotonTask(jobID, 2, task.TaskState_LAUNCHED),
+	}
+	taskID := func(id *peloton.JobID, instanceID uint32) string {
+		return util.CreateMesosTaskID(id, instanceID, 1).GetValue()
+	}
+
+	mesosTasks := &mesos_master.Response_GetTasks{
+		Tasks: []*mesos.Task{
+			newMesosTask(
+				taskID(jobID, 0), frameworkID, mesos.TaskState_TASK_RUNNING),
+			// orphan of the job
+			newMesosTask(
+				taskID(jobID, 3), frameworkID, mesos.TaskState_TASK_RUNNING),
+			// task of another job, filtered out
+			newMesosTask(
+				taskID(otherJobID, 0), frameworkID, mesos.TaskState_TASK_RUNNING),
+			// task of another framework, filtered out
+			newMesosTask(
+				taskID(jobID, 4), "otherFramework", mesos.TaskState_TASK_RUNNING),
+		},
+		CompletedTasks: []*mesos.Task{
+			newMesosTask(
+				taskID(jobID, 2), frameworkID, mesos.TaskState_TASK_FAILED),
+		},
+	}
+
+	gomock.InOrder(
+		suite.operatorClient.EXPECT().GetTasks().Return(mesosTasks, nil),
+		suite.mockTaskStore.EXPECT().
+			GetTasksForJobAndStates(gomock.Any(), jobID, gomock.Any()).
+			Return(pelotonTasks, nil),
+		suite.schedulerClient.EXPECT().
+			Call(streamID, gomock.Any()).
+			Do(func(_ string, msg *sched.Call) {
+				suite.Equal(sched.Call_RECONCILE, msg.GetType())
+				suite.Len(msg.GetReconcile().GetTasks(), len(pelotonTasks))
+			}).
+			Return(nil),
+	)
+
+	report, err := suite.reconciler.ReconcileTasks(
+		context.Background(), jobID, "")
+	suite.NoError(err)
+	suite.Equal(uint32(3), report.GetPelotonTasks())
+	suite.Equal(uint32(2), report.GetMesosTasks())
+
+	missing := make(map[string]string)
+	for _, t := range report.GetMissingInMesos() {
+		missing[t.GetTaskId().GetValue()] = t.GetMesosState()
+	}
+	suite.Equal(map[string]string{
+		taskID(jobID, 1): "",
+		taskID(jobID, 2): mesos.TaskState_TASK_FAILED.String(),
+	}, missing)
+
+	suite.Len(report.GetOrphans(), 1)
+	suite.Equal(taskID(jobID, 3), report.GetOrphans()[0].GetTaskId().GetValue())
+	suite.Equal(
+		mesos.TaskState_TASK_RUNNING.String(),
+		report.GetOrphans()[0].GetMesosState())
+	suite.Empty(report.GetOrphans()[0].GetPelotonState())
+}
+
+// TestReconcileTasksAllJobs tests reconciling the tasks of all active jobs.
+func (suite *TaskReconcilerTestSuite) TestReconcileTasksAllJobs() {
+	jobID := &peloton.JobID{Value: uuid.New()}
+	pelotonTasks := map[uint32]*task.TaskInfo{
+		0: newPelotonTask(jobID, 0, task.TaskState_RUNNING),
+	}
+
+	gomock.InOrder(
+		suite.operatorClient.EXPECT().GetTasks().Return(
+			&mesos_master.Response_GetTasks{
+				Tasks: []*mesos.Task{
+					newMesosTask(
+						util.CreateMesosTaskID(jobID, 0, 1).GetValue(),
+						frameworkID,
+						mesos.TaskState_TASK_RUNNING),
+				},
+			}, nil),
+		suite.mockActiveJobsOps.EXPECT().
+			GetAll(gomock.Any()).
+			Return([]*peloton.JobID{jobID}, nil),
+		suite.mockTaskStore.EXPECT().
+			GetTasksForJobAndStates(gomock.Any(), jobID, gomock.Any()).
+			Return(pelotonTasks, nil),
+		suite.schedulerClient.EXPECT().
+			Call(streamID, gomock.Any()).
+			Return(nil),
+	)
+
+	report, err := suite.reconciler.ReconcileTasks(
+		context.Background(), nil, "")
+	suite.NoError(err)
+	suite.Equal(uint32(1), report.GetPelotonTasks())
+	suite.Equal(uint32(1), report.GetMesosTasks())
+	suite.Empty(report.GetMissingInMesos())
+	suite.Empty(report.GetOrphans())
+}
+
+// TestReconcileTasksErrors tests the failures of reconciling tasks.
+func (suite *TaskReconcilerTestSuite) TestReconcileTasksErrors() {
+	jobID := &peloton.JobID{Value: uuid.New()}
+
+	// both job and host filter
+	_, err := suite.reconciler.ReconcileTasks(
+		context.Background(), jobID, "host")
+	suite.Equal(errJobAndHostFilter, err)
+
+	// unknown host
+	_, err = suite.reconciler.ReconcileTasks(
+		context.Background(), nil, "unknown-host")
+	suite.Error(err)
+
+	// Mesos master failure
+	suite.operatorClient.EXPECT().GetTasks().
+		Return(nil, fmt.Errorf("fake GetTasks error"))
+	_, err = suite.reconciler.ReconcileTasks(
+		context.Background(), jobID, "")
+	suite.Error(err)
+
+	// datastore failure
+	gomock.InOrder(
+		suite.operatorClient.EXPECT().GetTasks().
+			Return(&mesos_master.Response_GetTasks{}, nil),
+		suite.mockTaskStore.EXPECT().
+			GetTasksForJobAndStates(gomock.Any(), jobID, gomock.Any()).
+			Return(nil, fmt.Errorf("fake GetTasksForJobAndStates error")),
+	)
+	_, err = suite.reconciler.ReconcileTasks(
+		context.Background(), jobID, "")
+	suite.Error(err)
+
+	// reconcile call failure
+	gomock.InOrder(
+		suite.operatorClient.EXPECT().GetTasks().
+			Return(&mesos_master.Response_GetTasks{}, nil),
+		suite.mockTaskStore.EXPECT().
+			GetTasksForJobAndStates(gomock.Any(), jobID, gomock.Any()).
+			Return(map[uint32]*task.TaskInfo{
+				0: newPelotonTask(jobID, 0, task.TaskState_RUNNING),
+			}, nil),
+		suite.schedulerClient.EXPECT().
+			Call(streamID, gomock.Any()).
+			Return(fmt.Errorf("fake Call error")),
+	)
+	_, err = suite.reconciler.ReconcileTasks(
+		context.Background(), jobID, "")
+	suite.Error(err)
+}
@@ -23,7 +23,9 @@ import (
 	"github.com/uber-go/tally"
 
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
 	"github.com/uber/peloton/pkg/common/util"
 	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
@@ -36,6 +38,14 @@ import (
 type TaskReconciler interface {
 	Reconcile(running *atomic.Bool)
 	SetExplicitReconcileTurn(flag bool)
+	// ReconcileTasks explicitly reconciles the non-terminal tasks of a job,
+	// of a host or all of them if neither is set, and reports the tasks on
+	// which Peloton and Mesos master disagree.
+	ReconcileTasks(
+		ctx context.Context,
+		jobID *peloton.JobID,
+		hostname string,
+	) (*hostsvc.ReconcileTasksReport, error)
 }
 
 // taskReconciler implements TaskReconciler.
@@ -43,6 +53,7 @@ type taskReconciler struct {
 	metrics *Metrics
 
 	schedulerClient       mpb.SchedulerClient
+	masterOperatorClient  mpb.MasterOperatorClient
 	taskStore             storage.TaskStore
 	activeJobsOps         ormobjects.ActiveJobsOps
 	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider
@@ -58,6 +69,7 @@ type taskReconciler struct {
 // NewTaskReconciler initialize the task reconciler.
 func NewTaskReconciler(
 	client mpb.SchedulerClient,
+	masterOperatorClient mpb.MasterOperatorClient,
 	parent tally.Scope,
 	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider,
 	activeJobsOps ormobjects.ActiveJobsOps,
@@ -66,6 +78,7 @@ func NewTaskReconciler(
 
 	reconciler := &taskReconciler{
 		schedulerClient:       client,
+		masterOperatorClient:  masterOperatorClient,
 		activeJobsOps:         activeJobsOps,
 		taskStore:             taskStore,
 		metrics:               NewMetrics(parent.SubScope("reconcile")),
@@ -141,9 +154,6 @@ func (r *taskReconciler) reconcileExplicitly(
 	log.WithField("reconcile_tasks_total", reconcileTasksLen).
 		Info("Total number of tasks to reconcile explicitly.")
 
-	frameworkID := r.frameworkInfoProvider.GetFrameworkID(ctx)
-	streamID := r.frameworkInfoProvider.GetMesosStreamID(ctx)
-	callType := sched.Call_RECONCILE
 	explicitTasksPerRun := 0
 	for i := 0; i < reconcileTasksLen; i += r.explicitReconcileBatchSize {
 		if !running.Load() {
@@ -161,14 +171,7 @@ func (r *taskReconciler) reconcileExplicitly(
 			currBatch = reconcileTasks[i : i+r.explicitReconcileBatchSize]
 		}
 		explicitTasksPerRun += len(currBatch)
-		msg := &sched.Call{
-			FrameworkId: frameworkID,
-			Type:        &callType,
-			Reconcile: &sched.Call_Reconcile{
-				Tasks: currBatch,
-			},
-		}
-		err = r.schedulerClient.Call(streamID, msg)
+		err = r.callReconcile(ctx, currBatch)
 		if err != nil {
 			r.metrics.ExplicitTasksPerRun.Update(float64(explicitTasksPerRun))
 			r.metrics.ReconcileExplicitlyFail.Inc(1)
@@ -184,6 +187,24 @@ func (r *taskReconciler) reconcileExplicitly(
 	log.Info("Reconcile tasks explicitly returned.")
 }
 
+// callReconcile sends one explicit reconcile call for the given tasks
+// to Mesos master.
+func (r *taskReconciler) callReconcile(
+	ctx context.Context,
+	tasks []*sched.Call_Reconcile_Task) error {
+	callType := sched.Call_RECONCILE
+	msg := &sched.Call{
+		FrameworkId: r.frameworkInfoProvider.GetFrameworkID(ctx),
+		Type:        &callType,
+		Reconcile: &sched.Call_Reconcile{
+			Tasks: tasks,
+		},
+	}
+	return r.schedulerClient.Call(
+		r.frameworkInfoProvider.GetMesosStreamID(ctx),
+		msg)
+}
+
 // getReconcileTasks queries datastore and get
 // all the non-terminal tasks in Mesos.
 func (r *taskReconciler) getReconcileTasks(ctx context.Context) (
@@ -200,17 +221,7 @@ func (r *taskReconciler) getReconcileTasks(ctx context.Context) (
 	log.WithField("job_ids", jobIDs).Info("explicit reconcile job ids.")
 
 	for _, jobID := range jobIDs {
-		// Mesos TaskState: TASK_STAGING -> Peloton: TaskState_LAUNCHED
-		nonTerminalTasks, getTasksErr := r.taskStore.GetTasksForJobAndStates(
-			ctx,
-			&jobID,
-			[]task.TaskState{
-				task.TaskState_LAUNCHED,
-				task.TaskState_STARTING,
-				task.TaskState_RUNNING,
-				task.TaskState_KILLING,
-			},
-		)
+		nonTerminalTasks, getTasksErr := r.getNonTerminalTasks(ctx, &jobID)
 		if getTasksErr != nil {
 			log.WithError(getTasksErr).WithFields(log.Fields{
 				"job": jobID,
@@ -218,17 +229,38 @@ func (r *taskReconciler) getReconcileTasks(ctx context.Context) (
 			r.metrics.ReconcileGetTasksFail.Inc(1)
 			continue
 		}
-		if len(nonTerminalTasks) != 0 {
-			for _, taskInfo := range nonTerminalTasks {
-				reconcileTasks = append(
-					reconcileTasks,
-					&sched.Call_Reconcile_Task{
-						TaskId:  taskInfo.GetRuntime().GetMesosTaskId(),
-						AgentId: taskInfo.GetRuntime().GetAgentID(),
-					},
-				)
-			}
+		for _, taskInfo := range nonTerminalTasks {
+			reconcileTasks = append(
+				reconcileTasks,
+				toReconcileTask(taskInfo),
+			)
 		}
 	}
 	return reconcileTasks, nil
 }
+
+// getNonTerminalTasks queries datastore and gets all the tasks of a job
+// which are non-terminal in Mesos.
+func (r *taskReconciler) getNonTerminalTasks(
+	ctx context.Context,
+	jobID *peloton.JobID) (map[uint32]*task.TaskInfo, error) {
+	// Mesos TaskState: TASK_STAGING -> Peloton: TaskState_LAUNCHED
+	return r.taskStore.GetTasksForJobAndStates(
+		ctx,
+		jobID,
+		[]task.TaskState{
+			task.TaskState_LAUNCHED,
+			task.TaskState_STARTING,
+			task.TaskState_RUNNING,
+			task.TaskState_KILLING,
+		},
+	)
+}
+
+// toReconcileTask returns the explicit reconcile entry of a task.
+func toReconcileTask(taskInfo *task.TaskInfo) *sched.Call_Reconcile_Task {
+	return &sched.Call_Reconcile_Task{
+		TaskId:  taskInfo.GetRuntime().GetMesosTaskId(),
+		AgentId: taskInfo.GetRuntime().GetAgentID(),
+	}
+}
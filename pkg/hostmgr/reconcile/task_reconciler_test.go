@@ -69,6 +69,7 @@ type TaskReconcilerTestSuite struct {
 	ctrl                *gomock.Controller
 	testScope           tally.TestScope
 	schedulerClient     *mock_mpb.MockSchedulerClient
+	operatorClient      *mock_mpb.MockMasterOperatorClient
 	reconciler          *taskReconciler
 	mockTaskStore       *store_mocks.MockTaskStore
 	mockActiveJobsOps   *objectmocks.MockActiveJobsOps
@@ -83,6 +84,7 @@ func (suite *TaskReconcilerTestSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 	suite.testScope = tally.NewTestScope("", map[string]string{})
 	suite.schedulerClient = mock_mpb.NewMockSchedulerClient(suite.ctrl)
+	suite.operatorClient = mock_mpb.NewMockMasterOperatorClient(suite.ctrl)
 	suite.mockActiveJobsOps = objectmocks.NewMockActiveJobsOps(suite.ctrl)
 	suite.mockTaskStore = store_mocks.NewMockTaskStore(suite.ctrl)
 	suite.testJobID = &peloton.JobID{
@@ -114,6 +116,7 @@ func (suite *TaskReconcilerTestSuite) SetupTest() {
 
 	suite.reconciler = &taskReconciler{
 		schedulerClient:                suite.schedulerClient,
+		masterOperatorClient:           suite.operatorClient,
 		metrics:                        NewMetrics(suite.testScope),
 		frameworkInfoProvider:          &mockFrameworkInfoProvider{},
 		activeJobsOps:                  suite.mockActiveJobsOps,
@@ -156,6 +159,7 @@ func TestTaskReconcilerTestSuite(t *testing.T) {
 func (suite *TaskReconcilerTestSuite) TestNewTaskReconciler() {
 	reconciler := NewTaskReconciler(
 		suite.schedulerClient,
+		suite.operatorClient,
 		suite.testScope,
 		&mockFrameworkInfoProvider{},
 		suite.mockActiveJobsOps,
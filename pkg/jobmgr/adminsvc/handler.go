@@ -17,11 +17,17 @@ package adminsvc
 import (
 	"context"
 
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	adminsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/admin/svc"
+	"github.com/uber/peloton/pkg/common/util"
 	yarpcutil "github.com/uber/peloton/pkg/common/util/yarpc"
 	"github.com/uber/peloton/pkg/jobmgr/goalstate"
+	"github.com/uber/peloton/pkg/jobmgr/secret"
+	jobmgrtask "github.com/uber/peloton/pkg/jobmgr/task"
 	"github.com/uber/peloton/pkg/middleware/inbound"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/yarpcerrors"
@@ -32,13 +38,20 @@ func InitServiceHandler(
 	d *yarpc.Dispatcher,
 	goalStateDriver goalstate.Driver,
 	apiLock inbound.APILockInterface,
+	ormStore *ormobjects.Store,
+	secretProvider secret.Provider,
 ) {
 	handler := createServiceHandler(goalStateDriver, apiLock)
+	handler.jobConfigOps = ormobjects.NewJobConfigOps(ormStore)
+	handler.secretProvider = secretProvider
 	d.Register(adminsvc.BuildAdminServiceYARPCProcedures(handler))
 }
 
 func createServiceHandler(goalStateDriver goalstate.Driver, apiLock inbound.APILockInterface) *serviceHandler {
-	handler := &serviceHandler{components: make(map[adminsvc.Component]lockableComponent)}
+	handler := &serviceHandler{
+		goalStateDriver: goalStateDriver,
+		components:      make(map[adminsvc.Component]lockableComponent),
+	}
 
 	for _, component := range createLockableComponents(goalStateDriver, apiLock) {
 		handler.components[component.component()] = component
@@ -50,6 +63,8 @@ func createServiceHandler(goalStateDriver goalstate.Driver, apiLock inbound.APIL
 type serviceHandler struct {
 	goalStateDriver goalstate.Driver
 	components      map[adminsvc.Component]lockableComponent
+	jobConfigOps    ormobjects.JobConfigOps
+	secretProvider  secret.Provider
 }
 
 // Lockdown locks the components requested in LockdownRequest
@@ -148,6 +163,68 @@ func (h *serviceHandler) RemoveLockdown(
 	return
 }
 
+// GetJobSecrets returns the secrets mounted by a job along with their data.
+// The data is returned in plaintext, base64 encoded only. The caller is
+// responsible for protecting it, the CLI encrypts it before writing the
+// cluster state bundle. Secrets from an external secret store are returned
+// as references only, their data stays in that store.
+func (h *serviceHandler) GetJobSecrets(
+	ctx context.Context,
+	request *adminsvc.GetJobSecretsRequest,
+) (response *adminsvc.GetJobSecretsResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+
+		if err != nil {
+			err = yarpcutil.ConvertToYARPCError(err)
+			log.WithField("headers", headers).
+				WithField("job_id", request.GetJobId().GetValue()).
+				WithError(err).
+				Warn("AdminService.GetJobSecrets failed")
+			return
+		}
+
+		// Never log the response, it holds the secret data.
+		log.WithField("headers", headers).
+			WithField("job_id", request.GetJobId().GetValue()).
+			WithField("num_secrets", len(response.GetSecrets())).
+			Info("AdminService.GetJobSecrets succeeded")
+	}()
+
+	jobConfig, _, err := h.jobConfigOps.GetCurrentVersion(
+		ctx,
+		&peloton.JobID{Value: request.GetJobId().GetValue()},
+	)
+	if yarpcerrors.IsNotFound(errors.Cause(err)) {
+		return nil, yarpcerrors.NotFoundErrorf("job not found: %v", err)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get job config")
+	}
+
+	response = &adminsvc.GetJobSecretsResponse{}
+	for _, volume := range util.RemoveSecretVolumesFromJobConfig(jobConfig) {
+		ref := secret.VolumeReference(volume)
+		if ref == nil {
+			continue
+		}
+		if ref.IsExternal() {
+			response.Secrets = append(response.Secrets,
+				jobmgrtask.CreateV1AlphaSecretProto(
+					ref.String(), volume.GetContainerPath(), nil))
+			continue
+		}
+		s, err := h.secretProvider.GetSecret(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		response.Secrets = append(response.Secrets,
+			jobmgrtask.CreateV1AlphaSecretProto(
+				ref.String(), volume.GetContainerPath(), s.Data))
+	}
+	return response, nil
+}
+
 func (h *serviceHandler) getLockableComponents(components []adminsvc.Component) ([]lockableComponent, error) {
 	var result []lockableComponent
 
@@ -16,15 +16,27 @@ package adminsvc
 
 import (
 	"context"
+	"encoding/base64"
 	"testing"
 
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	adminsvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/admin/svc"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
 
+	"github.com/uber/peloton/pkg/common/util"
 	goalstatemocks "github.com/uber/peloton/pkg/jobmgr/goalstate/mocks"
+	"github.com/uber/peloton/pkg/jobmgr/secret"
+	secretmocks "github.com/uber/peloton/pkg/jobmgr/secret/mocks"
 	lifecyclemgrmocks "github.com/uber/peloton/pkg/jobmgr/task/lifecyclemgr/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
 
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
 )
 
 type adminServiceHandlerTestSuite struct {
@@ -34,6 +46,8 @@ type adminServiceHandlerTestSuite struct {
 
 	ctrl            *gomock.Controller
 	goalStateDriver *goalstatemocks.MockDriver
+	jobConfigOps    *objectmocks.MockJobConfigOps
+	secretProvider  *secretmocks.MockProvider
 }
 
 func (suite *adminServiceHandlerTestSuite) SetupTest() {
@@ -42,6 +56,10 @@ func (suite *adminServiceHandlerTestSuite) SetupTest() {
 	lockable := lifecyclemgrmocks.NewMockLockable(suite.ctrl)
 	suite.goalStateDriver.EXPECT().GetLockable().Return(lockable).AnyTimes()
 	suite.handler = createServiceHandler(suite.goalStateDriver, nil)
+	suite.jobConfigOps = objectmocks.NewMockJobConfigOps(suite.ctrl)
+	suite.secretProvider = secretmocks.NewMockProvider(suite.ctrl)
+	suite.handler.jobConfigOps = suite.jobConfigOps
+	suite.handler.secretProvider = suite.secretProvider
 }
 
 func (suite *adminServiceHandlerTestSuite) TearDownTest() {
@@ -89,3 +107,99 @@ func (suite *adminServiceHandlerTestSuite) TestRemoveLockdownFailDueToUnknownCom
 	suite.Error(err)
 	suite.Empty(resp.GetSuccesses())
 }
+
+func (suite *adminServiceHandlerTestSuite) TestGetJobSecrets() {
+	jobID := &peloton.JobID{Value: "job"}
+	config := &job.JobConfig{
+		DefaultConfig: &task.TaskConfig{
+			Container: &mesos.ContainerInfo{
+				Volumes: []*mesos.Volume{
+					util.CreateSecretVolume("/tmp/secret", "secret-id"),
+					util.CreateSecretVolume("/tmp/vault", "vault:db/password@3"),
+				},
+			},
+		},
+	}
+
+	suite.jobConfigOps.EXPECT().
+		GetCurrentVersion(gomock.Any(), jobID).
+		Return(config, nil, nil)
+	suite.secretProvider.EXPECT().
+		GetSecret(gomock.Any(), &secret.Reference{Path: "secret-id"}).
+		Return(&secret.Secret{Data: []byte("data"), Version: "1"}, nil)
+
+	resp, err := suite.handler.GetJobSecrets(
+		context.Background(),
+		&adminsvc.GetJobSecretsRequest{
+			JobId: &v1alphapeloton.JobID{Value: jobID.GetValue()},
+		})
+	suite.NoError(err)
+	suite.Len(resp.GetSecrets(), 2)
+	suite.Equal("secret-id", resp.GetSecrets()[0].GetSecretId().GetValue())
+	suite.Equal("/tmp/secret", resp.GetSecrets()[0].GetPath())
+	suite.Equal(
+		base64.StdEncoding.EncodeToString([]byte("data")),
+		string(resp.GetSecrets()[0].GetValue().GetData()))
+	// secrets from an external secret store are returned as references
+	suite.Equal(
+		"vault:db/password@3", resp.GetSecrets()[1].GetSecretId().GetValue())
+	suite.Equal("/tmp/vault", resp.GetSecrets()[1].GetPath())
+	suite.Empty(resp.GetSecrets()[1].GetValue().GetData())
+}
+
+func (suite *adminServiceHandlerTestSuite) TestGetJobSecretsJobNotFound() {
+	suite.jobConfigOps.EXPECT().
+		GetCurrentVersion(gomock.Any(), gomock.Any()).
+		Return(nil, nil, errors.Wrap(
+			yarpcerrors.NotFoundErrorf("not found"),
+			"Failed to get Job Runtime"))
+
+	_, err := suite.handler.GetJobSecrets(
+		context.Background(),
+		&adminsvc.GetJobSecretsRequest{
+			JobId: &v1alphapeloton.JobID{Value: "job"},
+		})
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestGetJobSecretsJobReadFailure tests that failing to read the job
+// config is not reported as a missing job
+func (suite *adminServiceHandlerTestSuite) TestGetJobSecretsJobReadFailure() {
+	suite.jobConfigOps.EXPECT().
+		GetCurrentVersion(gomock.Any(), gomock.Any()).
+		Return(nil, nil, yarpcerrors.UnavailableErrorf("cassandra unavailable"))
+
+	_, err := suite.handler.GetJobSecrets(
+		context.Background(),
+		&adminsvc.GetJobSecretsRequest{
+			JobId: &v1alphapeloton.JobID{Value: "job"},
+		})
+	suite.Error(err)
+	suite.False(yarpcerrors.IsNotFound(err))
+}
+
+func (suite *adminServiceHandlerTestSuite) TestGetJobSecretsReadFailure() {
+	config := &job.JobConfig{
+		DefaultConfig: &task.TaskConfig{
+			Container: &mesos.ContainerInfo{
+				Volumes: []*mesos.Volume{
+					util.CreateSecretVolume("/tmp/secret", "secret-id"),
+				},
+			},
+		},
+	}
+
+	suite.jobConfigOps.EXPECT().
+		GetCurrentVersion(gomock.Any(), gomock.Any()).
+		Return(config, nil, nil)
+	suite.secretProvider.EXPECT().
+		GetSecret(gomock.Any(), gomock.Any()).
+		Return(nil, yarpcerrors.InternalErrorf("cassandra unavailable"))
+
+	_, err := suite.handler.GetJobSecrets(
+		context.Background(),
+		&adminsvc.GetJobSecretsRequest{
+			JobId: &v1alphapeloton.JobID{Value: "job"},
+		})
+	suite.Error(err)
+}
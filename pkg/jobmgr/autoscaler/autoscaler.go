@@ -0,0 +1,393 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	"github.com/uber/peloton/pkg/common/background"
+	"github.com/uber/peloton/pkg/common/taskconfig"
+	"github.com/uber/peloton/pkg/common/util"
+	versionutil "github.com/uber/peloton/pkg/common/util/entityversion"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	"github.com/uber/peloton/pkg/jobmgr/goalstate"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/atomic"
+)
+
+const (
+	_autoscalerName = "autoscaler"
+
+	// timeout to evaluate the autoscaling policy of a single job
+	_autoscaleJobTimeout = 30 * time.Second
+
+	// utilization within this fraction of the target does not
+	// trigger scaling, to avoid flapping around the target
+	_scaleTolerance = 0.1
+)
+
+// Autoscaler periodically evaluates the autoscaling policy of stateless
+// jobs. When the observed utilization of a job is off its target, the
+// autoscaler changes the instance count of the job through an update
+// workflow, so that instances are added or removed in batches.
+type Autoscaler struct {
+	JobFactory         cached.JobFactory
+	GoalStateDriver    goalstate.Driver
+	JobConfigOps       ormobjects.JobConfigOps
+	JobUpdateEventsOps ormobjects.JobUpdateEventsOps
+	Source             MetricsSource
+	Metrics            *Metrics
+	Config             *Config
+}
+
+// Register registers the autoscaler as a background work, if the
+// autoscaler is enabled.
+func (a *Autoscaler) Register(manager background.Manager) error {
+	if a.Config == nil {
+		a.Config = &Config{}
+	}
+
+	a.Config.normalize()
+	if !a.Config.Enabled {
+		return nil
+	}
+
+	return manager.RegisterWorks(
+		background.Work{
+			Name: _autoscalerName,
+			Func: func(_ *atomic.Bool) {
+				a.Run()
+			},
+			Period: a.Config.AutoscalePeriod,
+		},
+	)
+}
+
+// Run evaluates the autoscaling policy of all stateless jobs once.
+func (a *Autoscaler) Run() {
+	stopWatch := a.Metrics.ProcessDuration.Start()
+	defer stopWatch.Stop()
+
+	for _, cachedJob := range a.JobFactory.GetAllJobs() {
+		if cachedJob.GetJobType() != pbjob.JobType_SERVICE {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(
+			context.Background(),
+			_autoscaleJobTimeout,
+		)
+		if err := a.autoscaleJob(ctx, cachedJob); err != nil {
+			a.Metrics.ScaleFail.Inc(1)
+			log.WithField("job_id", cachedJob.ID().GetValue()).
+				WithError(err).
+				Info("failed to autoscale job")
+		}
+		cancel()
+	}
+}
+
+// autoscaleJob evaluates the autoscaling policy of the job, and scales
+// the job if needed.
+func (a *Autoscaler) autoscaleJob(
+	ctx context.Context,
+	cachedJob cached.Job,
+) error {
+	config, err := cachedJob.GetConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get job config")
+	}
+
+	policy := config.GetAutoscaling()
+	if policy == nil {
+		return nil
+	}
+
+	runtime, err := cachedJob.GetRuntime(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get job runtime")
+	}
+
+	if runtime.GetGoalState() != pbjob.JobState_RUNNING {
+		return nil
+	}
+
+	if !a.isCooledDown(cachedJob, runtime.GetUpdateID(), policy) {
+		return nil
+	}
+
+	result, err := a.JobConfigOps.GetResult(
+		ctx,
+		cachedJob.ID(),
+		runtime.GetConfigurationVersion(),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to get job config")
+	}
+
+	utilization, ok, err := a.getUtilization(
+		ctx,
+		cachedJob,
+		result.JobConfig,
+		policy.GetMetric(),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to get job utilization")
+	}
+	if !ok {
+		return nil
+	}
+
+	current := result.JobConfig.GetInstanceCount()
+	desired := getDesiredInstanceCount(current, utilization, policy)
+	if desired == current {
+		return nil
+	}
+
+	return a.scaleJob(ctx, cachedJob, runtime, result, desired, utilization)
+}
+
+// isCooledDown returns true if no workflow is running on the job, and the
+// last workflow of the job finished at least cooldown ago. Both user
+// updates and updates created by the autoscaler reset the cooldown.
+func (a *Autoscaler) isCooledDown(
+	cachedJob cached.Job,
+	updateID *peloton.UpdateID,
+	policy *pbjob.AutoscalingConfig,
+) bool {
+	if len(updateID.GetValue()) == 0 {
+		return true
+	}
+
+	cachedWorkflow := cachedJob.GetWorkflow(updateID)
+	if cachedWorkflow == nil {
+		return true
+	}
+
+	if cached.IsUpdateStateActive(cachedWorkflow.GetState().State) {
+		return false
+	}
+
+	cooldown := a.Config.DefaultCooldown
+	if policy.GetCooldownSeconds() != 0 {
+		cooldown = time.Duration(policy.GetCooldownSeconds()) * time.Second
+	}
+	return time.Since(cachedWorkflow.GetLastUpdateTime()) >= cooldown
+}
+
+// getUtilization returns the utilization of the running instances of the
+// job for the metric, as the ratio of their total usage to their total
+// resource limit. It returns false if no usage is observed for the job.
+func (a *Autoscaler) getUtilization(
+	ctx context.Context,
+	cachedJob cached.Job,
+	jobConfig *pbjob.JobConfig,
+	metric pbjob.AutoscalingConfig_Metric,
+) (float64, bool, error) {
+	var podIDs []*v1alphapeloton.PodID
+	limits := make(map[string]float64)
+	for instID, cachedTask := range cachedJob.GetAllTasks() {
+		runtime, err := cachedTask.GetRuntime(ctx)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if runtime.GetState() != pbtask.TaskState_RUNNING ||
+			runtime.GetMesosTaskId() == nil {
+			continue
+		}
+
+		taskConfig := taskconfig.Merge(
+			jobConfig.GetDefaultConfig(),
+			jobConfig.GetInstanceConfig()[instID],
+		)
+		podID := util.CreatePodIDFromMesosTaskID(runtime.GetMesosTaskId())
+		podIDs = append(podIDs, podID)
+		limits[podID.GetValue()] = getMetricValue(
+			metric,
+			taskConfig.GetResource().GetCpuLimit(),
+			taskConfig.GetResource().GetMemLimitMb(),
+		)
+	}
+
+	if len(podIDs) == 0 {
+		return 0, false, nil
+	}
+
+	podUsage, err := a.Source.GetPodUsage(ctx, podIDs)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var totalUsage, totalLimit float64
+	for podName, usage := range podUsage {
+		limit, ok := limits[podName]
+		if !ok {
+			continue
+		}
+		totalUsage += getMetricValue(metric, usage.GetCpu(), usage.GetMemMb())
+		totalLimit += limit
+	}
+
+	if totalLimit == 0 {
+		return 0, false, nil
+	}
+	return totalUsage / totalLimit, true, nil
+}
+
+// scaleJob creates an update workflow which changes the instance count
+// of the job to desired, and records the scaling decision as a workflow
+// event.
+func (a *Autoscaler) scaleJob(
+	ctx context.Context,
+	cachedJob cached.Job,
+	runtime *pbjob.RuntimeInfo,
+	result *ormobjects.JobConfigOpsResult,
+	desired uint32,
+	utilization float64,
+) error {
+	jobConfig := result.JobConfig
+	policy := jobConfig.GetAutoscaling()
+	current := jobConfig.GetInstanceCount()
+
+	// copy the config with the current version, so that the update
+	// fails if the config is changed concurrently
+	newConfig := *jobConfig
+	now := time.Now()
+	newConfig.InstanceCount = desired
+	newConfig.ChangeLog = &peloton.ChangeLog{
+		Version:   jobConfig.GetChangeLog().GetVersion(),
+		CreatedAt: uint64(now.UnixNano()),
+		UpdatedAt: uint64(now.UnixNano()),
+	}
+
+	var newSpec *stateless.JobSpec
+	if result.JobSpec != nil {
+		spec := *result.JobSpec
+		spec.InstanceCount = desired
+		spec.Revision = &v1alphapeloton.Revision{
+			Version:   newConfig.GetChangeLog().GetVersion(),
+			CreatedAt: newConfig.GetChangeLog().GetCreatedAt(),
+			UpdatedAt: newConfig.GetChangeLog().GetUpdatedAt(),
+		}
+		newSpec = &spec
+	}
+
+	updateID, _, err := cachedJob.CreateWorkflow(
+		ctx,
+		models.WorkflowType_UPDATE,
+		&pbupdate.UpdateConfig{
+			BatchSize: policy.GetBatchSize(),
+		},
+		versionutil.GetJobEntityVersion(
+			runtime.GetConfigurationVersion(),
+			runtime.GetDesiredStateVersion(),
+			runtime.GetWorkflowVersion(),
+		),
+		cached.WithConfig(
+			&newConfig,
+			jobConfig,
+			result.ConfigAddOn,
+			newSpec,
+		),
+		cached.WithOpaqueData(nil),
+	)
+
+	// In case of error, since it is not clear if job runtime was
+	// persisted with the update ID or not, enqueue the update to
+	// the goal state, which either runs or aborts the update.
+	if len(updateID.GetValue()) > 0 {
+		a.GoalStateDriver.EnqueueUpdate(cachedJob.ID(), updateID, time.Now())
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create update workflow")
+	}
+
+	if desired > current {
+		a.Metrics.ScaleUp.Inc(1)
+	} else {
+		a.Metrics.ScaleDown.Inc(1)
+	}
+
+	message := fmt.Sprintf(
+		"autoscaler scaled from %d to %d instances: %s utilization %.2f, target %.2f",
+		current,
+		desired,
+		policy.GetMetric().String(),
+		utilization,
+		policy.GetTargetUtilization(),
+	)
+	log.WithFields(log.Fields{
+		"job_id":    cachedJob.ID().GetValue(),
+		"update_id": updateID.GetValue(),
+	}).Info(message)
+
+	return a.JobUpdateEventsOps.CreateWithMessage(
+		ctx,
+		updateID,
+		models.WorkflowType_UPDATE,
+		pbupdate.State_INITIALIZED,
+		message,
+	)
+}
+
+// getDesiredInstanceCount returns the instance count which brings the
+// utilization of the job to the target, bounded by the policy.
+func getDesiredInstanceCount(
+	current uint32,
+	utilization float64,
+	policy *pbjob.AutoscalingConfig,
+) uint32 {
+	desired := current
+	ratio := utilization / policy.GetTargetUtilization()
+	if math.Abs(ratio-1) > _scaleTolerance {
+		desired = uint32(math.Ceil(float64(current) * ratio))
+	}
+
+	if desired < policy.GetMinInstances() {
+		desired = policy.GetMinInstances()
+	}
+	if desired > policy.GetMaxInstances() {
+		desired = policy.GetMaxInstances()
+	}
+	return desired
+}
+
+// getMetricValue returns the value of the metric out of cpu and memory.
+func getMetricValue(
+	metric pbjob.AutoscalingConfig_Metric,
+	cpu float64,
+	memMb float64,
+) float64 {
+	if metric == pbjob.AutoscalingConfig_MEMORY {
+		return memMb
+	}
+	return cpu
+}
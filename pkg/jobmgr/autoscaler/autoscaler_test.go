@@ -0,0 +1,426 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	"github.com/uber/peloton/pkg/common/util"
+	versionutil "github.com/uber/peloton/pkg/common/util/entityversion"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	backgroundmocks "github.com/uber/peloton/pkg/common/background/mocks"
+	autoscalermocks "github.com/uber/peloton/pkg/jobmgr/autoscaler/mocks"
+	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
+	goalstatemocks "github.com/uber/peloton/pkg/jobmgr/goalstate/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const (
+	_testConfigVersion       = uint64(2)
+	_testDesiredStateVersion = uint64(3)
+	_testWorkflowVersion     = uint64(4)
+)
+
+type AutoscalerTestSuite struct {
+	suite.Suite
+	mockCtrl *gomock.Controller
+
+	testScope          tally.TestScope
+	jobFactory         *cachedmocks.MockJobFactory
+	cachedJob          *cachedmocks.MockJob
+	cachedConfig       *cachedmocks.MockJobConfigCache
+	goalStateDriver    *goalstatemocks.MockDriver
+	jobConfigOps       *objectmocks.MockJobConfigOps
+	jobUpdateEventsOps *objectmocks.MockJobUpdateEventsOps
+	source             *autoscalermocks.MockMetricsSource
+	autoscaler         *Autoscaler
+
+	jobID   *peloton.JobID
+	policy  *pbjob.AutoscalingConfig
+	runtime *pbjob.RuntimeInfo
+}
+
+func (s *AutoscalerTestSuite) SetupTest() {
+	s.mockCtrl = gomock.NewController(s.T())
+
+	s.testScope = tally.NewTestScope("", nil)
+	s.jobFactory = cachedmocks.NewMockJobFactory(s.mockCtrl)
+	s.cachedJob = cachedmocks.NewMockJob(s.mockCtrl)
+	s.cachedConfig = cachedmocks.NewMockJobConfigCache(s.mockCtrl)
+	s.goalStateDriver = goalstatemocks.NewMockDriver(s.mockCtrl)
+	s.jobConfigOps = objectmocks.NewMockJobConfigOps(s.mockCtrl)
+	s.jobUpdateEventsOps = objectmocks.NewMockJobUpdateEventsOps(s.mockCtrl)
+	s.source = autoscalermocks.NewMockMetricsSource(s.mockCtrl)
+
+	config := &Config{Enabled: true}
+	config.normalize()
+
+	s.autoscaler = &Autoscaler{
+		JobFactory:         s.jobFactory,
+		GoalStateDriver:    s.goalStateDriver,
+		JobConfigOps:       s.jobConfigOps,
+		JobUpdateEventsOps: s.jobUpdateEventsOps,
+		Source:             s.source,
+		Metrics:            NewMetrics(s.testScope),
+		Config:             config,
+	}
+
+	s.jobID = &peloton.JobID{Value: "b3d5d7c8-1d5c-4c3a-9f5e-2a8d3c1e5f7a"}
+	s.policy = &pbjob.AutoscalingConfig{
+		MinInstances:      2,
+		MaxInstances:      10,
+		Metric:            pbjob.AutoscalingConfig_CPU,
+		TargetUtilization: 0.5,
+		BatchSize:         2,
+	}
+	s.runtime = &pbjob.RuntimeInfo{
+		GoalState:            pbjob.JobState_RUNNING,
+		ConfigurationVersion: _testConfigVersion,
+		DesiredStateVersion:  _testDesiredStateVersion,
+		WorkflowVersion:      _testWorkflowVersion,
+	}
+}
+
+func (s *AutoscalerTestSuite) TearDownTest() {
+	s.mockCtrl.Finish()
+}
+
+func TestAutoscalerTestSuite(t *testing.T) {
+	suite.Run(t, new(AutoscalerTestSuite))
+}
+
+// expectJob sets up the expectations to read the policy and runtime of
+// the job under test.
+func (s *AutoscalerTestSuite) expectJob() {
+	s.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{s.jobID.GetValue(): s.cachedJob})
+	s.cachedJob.EXPECT().ID().Return(s.jobID).AnyTimes()
+	s.cachedJob.EXPECT().GetJobType().Return(pbjob.JobType_SERVICE)
+	s.cachedJob.EXPECT().GetConfig(gomock.Any()).Return(s.cachedConfig, nil)
+	s.cachedConfig.EXPECT().GetAutoscaling().Return(s.policy)
+	s.cachedJob.EXPECT().GetRuntime(gomock.Any()).Return(s.runtime, nil)
+}
+
+// expectUtilization sets up the expectations to read the config of the
+// job with instanceCount instances, each of them running with 1 cpu
+// limit and using cpuUsage cpus.
+func (s *AutoscalerTestSuite) expectUtilization(
+	instanceCount uint32,
+	cpuUsage float64,
+) *ormobjects.JobConfigOpsResult {
+	result := &ormobjects.JobConfigOpsResult{
+		JobConfig: &pbjob.JobConfig{
+			Type:          pbjob.JobType_SERVICE,
+			InstanceCount: instanceCount,
+			ChangeLog:     &peloton.ChangeLog{Version: _testConfigVersion},
+			DefaultConfig: &pbtask.TaskConfig{
+				Resource: &pbtask.ResourceConfig{CpuLimit: 1, MemLimitMb: 100},
+			},
+			Autoscaling: s.policy,
+		},
+		JobSpec: &stateless.JobSpec{
+			InstanceCount: instanceCount,
+		},
+	}
+	s.jobConfigOps.EXPECT().
+		GetResult(gomock.Any(), s.jobID, _testConfigVersion).
+		Return(result, nil)
+
+	tasks := make(map[uint32]cached.Task)
+	var podIDs []*v1alphapeloton.PodID
+	usage := make(map[string]*v1alphapeloton.Resources)
+	for i := uint32(0); i < instanceCount; i++ {
+		cachedTask := cachedmocks.NewMockTask(s.mockCtrl)
+		mesosTaskID := util.CreateMesosTaskID(s.jobID, i, 1)
+		cachedTask.EXPECT().
+			GetRuntime(gomock.Any()).
+			Return(&pbtask.RuntimeInfo{
+				State:       pbtask.TaskState_RUNNING,
+				MesosTaskId: mesosTaskID,
+			}, nil)
+		tasks[i] = cachedTask
+		podID := util.CreatePodIDFromMesosTaskID(mesosTaskID)
+		podIDs = append(podIDs, podID)
+		usage[podID.GetValue()] = &v1alphapeloton.Resources{
+			Cpu:   cpuUsage,
+			MemMb: 10,
+		}
+	}
+	s.cachedJob.EXPECT().GetAllTasks().Return(tasks)
+	s.source.EXPECT().
+		GetPodUsage(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, ids []*v1alphapeloton.PodID) {
+			s.ElementsMatch(podIDs, ids)
+		}).
+		Return(usage, nil)
+	return result
+}
+
+// TestRegister tests the autoscaler is only registered when enabled
+func (s *AutoscalerTestSuite) TestRegister() {
+	mockBackgroundManager := backgroundmocks.NewMockManager(s.mockCtrl)
+	mockBackgroundManager.EXPECT().RegisterWorks(gomock.Any()).Return(nil)
+	s.NoError(s.autoscaler.Register(mockBackgroundManager))
+
+	s.autoscaler.Config.Enabled = false
+	s.NoError(s.autoscaler.Register(mockBackgroundManager))
+}
+
+// TestRunScaleUp tests the autoscaler scales up a job whose utilization
+// is above the target through an update workflow
+func (s *AutoscalerTestSuite) TestRunScaleUp() {
+	updateID := &peloton.UpdateID{Value: "update-1"}
+	s.expectJob()
+	result := s.expectUtilization(2, 0.9)
+
+	s.cachedJob.EXPECT().
+		CreateWorkflow(
+			gomock.Any(),
+			models.WorkflowType_UPDATE,
+			&pbupdate.UpdateConfig{BatchSize: 2},
+			versionutil.GetJobEntityVersion(
+				_testConfigVersion,
+				_testDesiredStateVersion,
+				_testWorkflowVersion,
+			),
+			gomock.Any(),
+			gomock.Any(),
+		).
+		Do(func(
+			_ context.Context,
+			_ models.WorkflowType,
+			_ *pbupdate.UpdateConfig,
+			_ *v1alphapeloton.EntityVersion,
+			opts ...cached.Option,
+		) {
+			// the config stored in DB is not modified
+			s.Equal(uint32(2), result.JobConfig.GetInstanceCount())
+			s.Equal(uint32(2), result.JobSpec.GetInstanceCount())
+		}).
+		Return(updateID, nil, nil)
+	s.goalStateDriver.EXPECT().EnqueueUpdate(s.jobID, updateID, gomock.Any())
+	s.jobUpdateEventsOps.EXPECT().
+		CreateWithMessage(
+			gomock.Any(),
+			updateID,
+			models.WorkflowType_UPDATE,
+			pbupdate.State_INITIALIZED,
+			"autoscaler scaled from 2 to 4 instances: CPU utilization 0.90, target 0.50",
+		).
+		Return(nil)
+
+	s.autoscaler.Run()
+	s.Equal(int64(1),
+		s.testScope.Snapshot().Counters()["autoscaler.scale_up+"].Value())
+}
+
+// TestRunScaleDownToMin tests the autoscaler does not scale a job below
+// the minimum instances of the policy
+func (s *AutoscalerTestSuite) TestRunScaleDownToMin() {
+	updateID := &peloton.UpdateID{Value: "update-1"}
+	s.expectJob()
+	s.expectUtilization(4, 0.1)
+
+	s.cachedJob.EXPECT().
+		CreateWorkflow(
+			gomock.Any(),
+			models.WorkflowType_UPDATE,
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+		).
+		Return(updateID, nil, nil)
+	s.goalStateDriver.EXPECT().EnqueueUpdate(s.jobID, updateID, gomock.Any())
+	s.jobUpdateEventsOps.EXPECT().
+		CreateWithMessage(
+			gomock.Any(),
+			updateID,
+			models.WorkflowType_UPDATE,
+			pbupdate.State_INITIALIZED,
+			"autoscaler scaled from 4 to 2 instances: CPU utilization 0.10, target 0.50",
+		).
+		Return(nil)
+
+	s.autoscaler.Run()
+	s.Equal(int64(1),
+		s.testScope.Snapshot().Counters()["autoscaler.scale_down+"].Value())
+}
+
+// TestRunWithinTolerance tests the autoscaler does not scale a job whose
+// utilization is close to the target
+func (s *AutoscalerTestSuite) TestRunWithinTolerance() {
+	s.expectJob()
+	s.expectUtilization(4, 0.52)
+
+	s.autoscaler.Run()
+}
+
+// TestRunCreateWorkflowFailure tests the update is enqueued into goal
+// state even if creating the workflow fails
+func (s *AutoscalerTestSuite) TestRunCreateWorkflowFailure() {
+	updateID := &peloton.UpdateID{Value: "update-1"}
+	s.expectJob()
+	s.expectUtilization(2, 0.9)
+
+	s.cachedJob.EXPECT().
+		CreateWorkflow(
+			gomock.Any(),
+			models.WorkflowType_UPDATE,
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+		).
+		Return(updateID, nil, errors.New("test error"))
+	s.goalStateDriver.EXPECT().EnqueueUpdate(s.jobID, updateID, gomock.Any())
+
+	s.autoscaler.Run()
+	s.Equal(int64(1),
+		s.testScope.Snapshot().Counters()["autoscaler.scale_fail+"].Value())
+}
+
+// TestRunSkipsActiveWorkflow tests the autoscaler does not scale a job
+// while a workflow is running on it
+func (s *AutoscalerTestSuite) TestRunSkipsActiveWorkflow() {
+	cachedWorkflow := cachedmocks.NewMockUpdate(s.mockCtrl)
+	s.runtime.UpdateID = &peloton.UpdateID{Value: "update-1"}
+	s.expectJob()
+
+	s.cachedJob.EXPECT().
+		GetWorkflow(s.runtime.GetUpdateID()).
+		Return(cachedWorkflow)
+	cachedWorkflow.EXPECT().
+		GetState().
+		Return(&cached.UpdateStateVector{
+			State: pbupdate.State_ROLLING_FORWARD,
+		})
+
+	s.autoscaler.Run()
+}
+
+// TestRunSkipsDuringCooldown tests the autoscaler does not scale a job
+// until cooldown passed since the last workflow of the job
+func (s *AutoscalerTestSuite) TestRunSkipsDuringCooldown() {
+	cachedWorkflow := cachedmocks.NewMockUpdate(s.mockCtrl)
+	s.runtime.UpdateID = &peloton.UpdateID{Value: "update-1"}
+	s.policy.CooldownSeconds = 600
+	s.expectJob()
+
+	s.cachedJob.EXPECT().
+		GetWorkflow(s.runtime.GetUpdateID()).
+		Return(cachedWorkflow)
+	cachedWorkflow.EXPECT().
+		GetState().
+		Return(&cached.UpdateStateVector{
+			State: pbupdate.State_SUCCEEDED,
+		})
+	cachedWorkflow.EXPECT().
+		GetLastUpdateTime().
+		Return(time.Now().Add(-5 * time.Minute))
+
+	s.autoscaler.Run()
+}
+
+// TestRunSkipsJobsWithoutPolicy tests the autoscaler ignores batch jobs
+// and stateless jobs without autoscaling policy
+func (s *AutoscalerTestSuite) TestRunSkipsJobsWithoutPolicy() {
+	batchJob := cachedmocks.NewMockJob(s.mockCtrl)
+	s.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{
+			"batch":     batchJob,
+			"stateless": s.cachedJob,
+		})
+	batchJob.EXPECT().GetJobType().Return(pbjob.JobType_BATCH)
+	s.cachedJob.EXPECT().GetJobType().Return(pbjob.JobType_SERVICE)
+	s.cachedJob.EXPECT().GetConfig(gomock.Any()).Return(s.cachedConfig, nil)
+	s.cachedConfig.EXPECT().GetAutoscaling().Return(nil)
+
+	s.autoscaler.Run()
+}
+
+// TestRunGetUsageFailure tests the autoscaler does not scale a job if
+// the usage of its pods can not be read
+func (s *AutoscalerTestSuite) TestRunGetUsageFailure() {
+	s.expectJob()
+	s.jobConfigOps.EXPECT().
+		GetResult(gomock.Any(), s.jobID, _testConfigVersion).
+		Return(&ormobjects.JobConfigOpsResult{
+			JobConfig: &pbjob.JobConfig{InstanceCount: 1},
+		}, nil)
+
+	cachedTask := cachedmocks.NewMockTask(s.mockCtrl)
+	cachedTask.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbtask.RuntimeInfo{
+			State:       pbtask.TaskState_RUNNING,
+			MesosTaskId: util.CreateMesosTaskID(s.jobID, 0, 1),
+		}, nil)
+	s.cachedJob.EXPECT().
+		GetAllTasks().
+		Return(map[uint32]cached.Task{0: cachedTask})
+	s.source.EXPECT().
+		GetPodUsage(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("test error"))
+
+	s.autoscaler.Run()
+	s.Equal(int64(1),
+		s.testScope.Snapshot().Counters()["autoscaler.scale_fail+"].Value())
+}
+
+// TestGetDesiredInstanceCount tests the desired instance count is
+// proportional to the utilization and bounded by the policy
+func (s *AutoscalerTestSuite) TestGetDesiredInstanceCount() {
+	tests := []struct {
+		current     uint32
+		utilization float64
+		desired     uint32
+	}{
+		{current: 4, utilization: 0.5, desired: 4},
+		{current: 4, utilization: 0.54, desired: 4},
+		{current: 4, utilization: 1, desired: 8},
+		{current: 4, utilization: 0.3, desired: 3},
+		{current: 8, utilization: 1, desired: 10},
+		{current: 4, utilization: 0, desired: 2},
+		{current: 12, utilization: 0.5, desired: 10},
+	}
+
+	for _, tt := range tests {
+		s.Equal(
+			tt.desired,
+			getDesiredInstanceCount(tt.current, tt.utilization, s.policy),
+			"current %d utilization %v", tt.current, tt.utilization,
+		)
+	}
+}
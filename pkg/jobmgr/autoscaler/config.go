@@ -0,0 +1,46 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaler
+
+import "time"
+
+const (
+	_defaultAutoscalePeriod = time.Minute
+	_defaultCooldown        = 5 * time.Minute
+)
+
+// Config is the configuration of the autoscaler.
+type Config struct {
+	// Enabled is set to run the autoscaler in job manager.
+	Enabled bool `yaml:"enabled"`
+
+	// AutoscalePeriod is the period at which the autoscaling policy of
+	// the jobs is evaluated.
+	AutoscalePeriod time.Duration `yaml:"autoscale_period"`
+
+	// DefaultCooldown is the cooldown used for jobs whose autoscaling
+	// policy does not set one.
+	DefaultCooldown time.Duration `yaml:"default_cooldown"`
+}
+
+func (c *Config) normalize() {
+	if c.AutoscalePeriod == time.Duration(0) {
+		c.AutoscalePeriod = _defaultAutoscalePeriod
+	}
+
+	if c.DefaultCooldown == time.Duration(0) {
+		c.DefaultCooldown = _defaultCooldown
+	}
+}
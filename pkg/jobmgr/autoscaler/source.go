@@ -0,0 +1,90 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaler
+
+import (
+	"context"
+
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"go.uber.org/yarpc"
+)
+
+// _bytesPerMb is the number of bytes in a megabyte
+const _bytesPerMb = 1024 * 1024
+
+// MetricsSource provides the observed resource usage of pods, from which
+// the autoscaler computes the utilization of a job.
+type MetricsSource interface {
+	// GetPodUsage returns the observed resource usage of the pods, keyed
+	// by pod name. Pods without observed usage are not present in the
+	// result.
+	GetPodUsage(
+		ctx context.Context,
+		podIDs []*peloton.PodID,
+	) (map[string]*peloton.Resources, error)
+}
+
+// hostMgrSource is a MetricsSource which reads the pod usage that host
+// manager collects from the Mesos agents or the K8s metrics API.
+type hostMgrSource struct {
+	hostMgrClient hostsvc.InternalHostServiceYARPCClient
+}
+
+// NewHostMgrSource returns a MetricsSource backed by host manager.
+func NewHostMgrSource(
+	d *yarpc.Dispatcher,
+	hostManagerClientName string,
+) MetricsSource {
+	return &hostMgrSource{
+		hostMgrClient: hostsvc.NewInternalHostServiceYARPCClient(
+			d.ClientConfig(hostManagerClientName)),
+	}
+}
+
+// GetPodUsage returns the latest pod usage collected by host manager.
+func (s *hostMgrSource) GetPodUsage(
+	ctx context.Context,
+	podIDs []*peloton.PodID,
+) (map[string]*peloton.Resources, error) {
+	var ids []string
+	for _, podID := range podIDs {
+		ids = append(ids, podID.GetValue())
+	}
+
+	resp, err := s.hostMgrClient.GetPodUsage(
+		ctx,
+		&hostsvc.GetPodUsageRequest{PodIds: ids},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]*peloton.Resources)
+	for _, podUsage := range resp.GetUsages() {
+		samples := podUsage.GetSamples()
+		if len(samples) == 0 {
+			continue
+		}
+		latest := samples[len(samples)-1]
+		usage[podUsage.GetPodId()] = &peloton.Resources{
+			Cpu:    latest.GetCpus(),
+			MemMb:  float64(latest.GetMemBytes()) / _bytesPerMb,
+			DiskMb: float64(latest.GetDiskBytes()) / _bytesPerMb,
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHostMgrSourceGetPodUsage tests the latest pod usage is read from
+// host manager
+func TestHostMgrSourceGetPodUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hostMgrClient := hostmocks.NewMockInternalHostServiceYARPCClient(ctrl)
+	source := &hostMgrSource{hostMgrClient: hostMgrClient}
+
+	podIDs := []*peloton.PodID{{Value: "pod-1"}, {Value: "pod-2"}}
+
+	hostMgrClient.EXPECT().
+		GetPodUsage(
+			gomock.Any(),
+			&hostsvc.GetPodUsageRequest{PodIds: []string{"pod-1", "pod-2"}},
+		).
+		Return(&hostsvc.GetPodUsageResponse{
+			Usages: []*hostsvc.PodUsage{
+				{
+					PodId: "pod-1",
+					Samples: []*hostsvc.PodUsageSample{
+						{Timestamp: 1, Cpus: 2, MemBytes: 200 * 1024 * 1024},
+						{Timestamp: 2, Cpus: 1, MemBytes: 100 * 1024 * 1024},
+					},
+				},
+				{PodId: "pod-2"},
+			},
+		}, nil)
+	result, err := source.GetPodUsage(context.Background(), podIDs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*peloton.Resources{
+		"pod-1": {Cpu: 1, MemMb: 100},
+	}, result)
+
+	hostMgrClient.EXPECT().
+		GetPodUsage(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("test error"))
+	_, err = source.GetPodUsage(context.Background(), podIDs)
+	assert.Error(t, err)
+}
@@ -106,6 +106,12 @@ type Job interface {
 	// added to the cache either.
 	AddTask(ctx context.Context, id uint32) (Task, error)
 
+	// AddTasks adds the given tasks to the job like AddTask, but
+	// recovers the runtimes of the tasks missing from the cache
+	// from DB in a single batch. The tasks which could not be
+	// added are returned in the error map.
+	AddTasks(ctx context.Context, ids []uint32) (map[uint32]Task, map[uint32]error)
+
 	// GetTask from the task id.
 	GetTask(id uint32) Task
 
@@ -177,7 +183,8 @@ type Job interface {
 	) (jobmgrcommon.JobConfig, error)
 
 	// CompareAndSetTask replaces the existing task runtime in DB and cache.
-	// It uses RuntimeInfo.Revision.Version for concurrency control, and it would
+	// It uses RuntimeInfo.Revision.Version for concurrency control, by writing
+	// to DB only if the version in DB is still the same, and it would
 	// update RuntimeInfo.Revision.Version automatically upon success.
 	// Caller should not manually modify the value of RuntimeInfo.Revision.Version.
 	// The `force` flag affects only stateless jobs. By default (with force flag
@@ -304,6 +311,14 @@ type WorkflowOps interface {
 		option ...Option,
 	) (*peloton.UpdateID, *v1alphapeloton.EntityVersion, error)
 
+	// PromoteWorkflow promotes the canary phase of the
+	// current workflow, if any
+	PromoteWorkflow(
+		ctx context.Context,
+		entityVersion *v1alphapeloton.EntityVersion,
+		option ...Option,
+	) (*peloton.UpdateID, *v1alphapeloton.EntityVersion, error)
+
 	// AbortWorkflow aborts the current workflow, if any
 	AbortWorkflow(
 		ctx context.Context,
@@ -359,15 +374,20 @@ func newJob(id *peloton.JobID, jobFactory *jobFactory) *job {
 
 // cachedConfig structure holds the config fields need to be cached
 type cachedConfig struct {
-	instanceCount     uint32                  // Instance count in the job configuration
-	sla               *pbjob.SlaConfig        // SLA configuration in the job configuration
-	jobType           pbjob.JobType           // Job type (batch or service) in the job configuration
-	changeLog         *peloton.ChangeLog      // ChangeLog in the job configuration
-	respoolID         *peloton.ResourcePoolID // Resource Pool ID in the job configuration
-	hasControllerTask bool                    // if the job contains any task which is controller task
-	labels            []*peloton.Label        // Label of the job
-	name              string                  // Name of the job
-	placementStrategy pbjob.PlacementStrategy // Placement strategy
+	instanceCount     uint32                   // Instance count in the job configuration
+	sla               *pbjob.SlaConfig         // SLA configuration in the job configuration
+	jobType           pbjob.JobType            // Job type (batch or service) in the job configuration
+	changeLog         *peloton.ChangeLog       // ChangeLog in the job configuration
+	respoolID         *peloton.ResourcePoolID  // Resource Pool ID in the job configuration
+	hasControllerTask bool                     // if the job contains any task which is controller task
+	labels            []*peloton.Label         // Label of the job
+	name              string                   // Name of the job
+	owner             string                   // Owner of the job
+	owningTeam        string                   // Owning team of the job
+	placementStrategy pbjob.PlacementStrategy  // Placement strategy
+	autoscaling       *pbjob.AutoscalingConfig // Autoscaling policy
+	completionPolicy  *pbjob.CompletionPolicy  // Completion policy
+	instanceGroups    []*pbjob.InstanceGroup   // Instance groups
 }
 
 // job structure holds the information about a given active job
@@ -465,6 +485,82 @@ func (j *job) AddTask(
 	return t, nil
 }
 
+func (j *job) AddTasks(
+	ctx context.Context,
+	ids []uint32,
+) (map[uint32]Task, map[uint32]error) {
+	tasks := make(map[uint32]Task)
+	errs := make(map[uint32]error)
+
+	var missing []uint32
+	for _, id := range ids {
+		if t := j.GetTask(id); t != nil {
+			tasks[id] = t
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return tasks, errs
+	}
+
+	// fetch the runtimes of all the missing tasks
+	runtimes, readErrs := j.jobFactory.taskStore.GetTaskRuntimes(
+		ctx, j.ID(), missing)
+	if readErrs == nil {
+		readErrs = make(map[uint32]error)
+	}
+
+	var added []uint32
+	j.Lock()
+	for _, id := range missing {
+		// the task may have been added since it was looked up
+		if t, ok := j.tasks[id]; ok {
+			tasks[id] = t
+			continue
+		}
+
+		runtime, ok := runtimes[id]
+		if !ok {
+			if _, failed := readErrs[id]; !failed {
+				readErrs[id] = yarpcerrors.NotFoundErrorf(
+					"task runtime not found")
+			}
+			continue
+		}
+		t := newTask(j.ID(), id, j.jobFactory, j.jobType)
+		t.runtime = runtime
+		j.tasks[id] = t
+		tasks[id] = t
+		added = append(added, id)
+	}
+
+	for id, err := range readErrs {
+		// a task added concurrently is returned even if it could
+		// not be read here
+		if _, ok := tasks[id]; ok {
+			continue
+		}
+		errs[id] = err
+		if !yarpcerrors.IsNotFound(err) {
+			continue
+		}
+		// validate that the task being added is within
+		// the instance count of the job.
+		if err := j.populateCurrentJobConfig(ctx); err != nil {
+			errs[id] = err
+			continue
+		}
+		if j.config.GetInstanceCount() <= id {
+			errs[id] = InstanceIDExceedsInstanceCountError
+		}
+	}
+	j.Unlock()
+
+	j.updateInstanceAvailabilityInfoForInstances(ctx, added, len(errs) > 0)
+	return tasks, errs
+}
+
 // CreateTaskConfigs creates task configurations in the DB
 func (j *job) CreateTaskConfigs(
 	ctx context.Context,
@@ -589,12 +685,14 @@ func (j *job) PatchTasks(
 
 	instancesSucceeded = getIdsFromDiffs(runtimesToPatch)
 
+	// recover the tasks missing from the cache in a single batch
+	tasks, addErrs := j.AddTasks(ctx, instancesSucceeded)
+
 	patchSingleTask := func(id uint32) error {
-		t, err := j.AddTask(ctx, id)
-		if err != nil {
+		if err, ok := addErrs[id]; ok {
 			return err
 		}
-		return t.(*task).patchTask(ctx, runtimeDiffs[id])
+		return tasks[id].(*task).patchTask(ctx, runtimeDiffs[id])
 	}
 
 	err = taskutil.RunInParallel(
@@ -1794,12 +1892,17 @@ func (j *job) populateJobConfigCache(config *pbjob.JobConfig) {
 	}
 
 	j.config.name = config.GetName()
+	j.config.owner = config.GetOwner()
+	j.config.owningTeam = config.GetOwningTeam()
 
 	j.config.hasControllerTask = hasControllerTask(config)
 
 	j.config.jobType = config.GetType()
 	j.jobType = j.config.jobType
 	j.config.placementStrategy = config.GetPlacementStrategy()
+	j.config.autoscaling = config.GetAutoscaling()
+	j.config.completionPolicy = config.GetCompletionPolicy()
+	j.config.instanceGroups = config.GetInstanceGroups()
 }
 
 // getUpdatedJobRuntimeCache validates the runtime input and
@@ -2529,6 +2632,43 @@ func (j *job) ResumeWorkflow(
 	return currentWorkflow.ID(), newEntityVersion, err
 }
 
+func (j *job) PromoteWorkflow(
+	ctx context.Context,
+	entityVersion *v1alphapeloton.EntityVersion,
+	options ...Option,
+) (*peloton.UpdateID, *v1alphapeloton.EntityVersion, error) {
+	j.Lock()
+	defer j.Unlock()
+
+	currentWorkflow, err := j.getCurrentWorkflow(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if currentWorkflow == nil {
+		return nil, nil, yarpcerrors.NotFoundErrorf("no workflow found")
+	}
+
+	opts := &workflowOpts{}
+	for _, option := range options {
+		option.apply(opts)
+	}
+
+	// update workflow version before mutating workflow, so
+	// when workflow state changes, entity version must be changed
+	// as well
+	if err := j.updateWorkflowVersion(ctx, entityVersion); err != nil {
+		return nil, nil, err
+	}
+
+	newEntityVersion := versionutil.GetJobEntityVersion(
+		j.runtime.GetConfigurationVersion(),
+		j.runtime.GetDesiredStateVersion(),
+		j.runtime.GetWorkflowVersion(),
+	)
+	err = currentWorkflow.Promote(ctx, opts.opaqueData)
+	return currentWorkflow.ID(), newEntityVersion, err
+}
+
 func (j *job) AbortWorkflow(
 	ctx context.Context,
 	entityVersion *v1alphapeloton.EntityVersion,
@@ -2932,10 +3072,30 @@ func (c *cachedConfig) GetName() string {
 	return c.name
 }
 
+func (c *cachedConfig) GetOwner() string {
+	return c.owner
+}
+
+func (c *cachedConfig) GetOwningTeam() string {
+	return c.owningTeam
+}
+
 func (c *cachedConfig) GetPlacementStrategy() pbjob.PlacementStrategy {
 	return c.placementStrategy
 }
 
+func (c *cachedConfig) GetAutoscaling() *pbjob.AutoscalingConfig {
+	return c.autoscaling
+}
+
+func (c *cachedConfig) GetCompletionPolicy() *pbjob.CompletionPolicy {
+	return c.completionPolicy
+}
+
+func (c *cachedConfig) GetInstanceGroups() []*pbjob.InstanceGroup {
+	return c.instanceGroups
+}
+
 // HasControllerTask returns if a job has controller task in it,
 // it can accept both cachedConfig and full JobConfig
 func HasControllerTask(config jobmgrcommon.JobConfig) bool {
@@ -71,6 +71,7 @@ type jobFactory struct {
 	jobNameToIDOps     ormobjects.JobNameToIDOps     // DB ops for job_name_to_id table
 	jobUpdateEventsOps ormobjects.JobUpdateEventsOps // DB ops for job_update_events table
 	taskConfigV2Ops    ormobjects.TaskConfigV2Ops    // DB ops for task_config_v2 table
+	taskRuntimeOps     ormobjects.TaskRuntimeOps     // DB ops for task_runtime table
 	mtx                *Metrics                      // cache metrics
 	taskMetrics        *TaskMetrics                  // task metrics
 	// Job/task listeners. This list is immutable after object is created.
@@ -103,6 +104,7 @@ func InitJobFactory(
 		jobNameToIDOps:     ormobjects.NewJobNameToIDOps(ormStore),
 		jobUpdateEventsOps: ormobjects.NewJobUpdateEventsOps(ormStore),
 		taskConfigV2Ops:    ormobjects.NewTaskConfigV2Ops(ormStore),
+		taskRuntimeOps:     ormobjects.NewTaskRuntimeOps(ormStore),
 		mtx:                NewMetrics(parentScope.SubScope("cache")),
 		taskMetrics:        NewTaskMetrics(parentScope.SubScope("task")),
 		listeners:          listeners,
@@ -67,6 +67,7 @@ type jobTestSuite struct {
 	jobRuntimeOps      *objectmocks.MockJobRuntimeOps
 	jobUpdateEventsOps *objectmocks.MockJobUpdateEventsOps
 	taskConfigV2Ops    *objectmocks.MockTaskConfigV2Ops
+	taskRuntimeOps     *objectmocks.MockTaskRuntimeOps
 
 	jobID     *peloton.JobID
 	job       *job
@@ -89,6 +90,7 @@ func (suite *jobTestSuite) SetupTest() {
 	suite.jobRuntimeOps = objectmocks.NewMockJobRuntimeOps(suite.ctrl)
 	suite.jobUpdateEventsOps = objectmocks.NewMockJobUpdateEventsOps(suite.ctrl)
 	suite.taskConfigV2Ops = objectmocks.NewMockTaskConfigV2Ops(suite.ctrl)
+	suite.taskRuntimeOps = objectmocks.NewMockTaskRuntimeOps(suite.ctrl)
 	suite.jobID = &peloton.JobID{Value: uuid.NewRandom().String()}
 	suite.listeners = append(suite.listeners,
 		new(FakeJobListener),
@@ -138,6 +140,7 @@ func (suite *jobTestSuite) initializeJob(
 			jobRuntimeOps:      jobRuntimeOps,
 			jobUpdateEventsOps: jobUpdateEventsOps,
 			taskConfigV2Ops:    taskConfigV2Ops,
+			taskRuntimeOps:     suite.taskRuntimeOps,
 			running:            true,
 			jobs:               map[string]*job{},
 		},
@@ -325,6 +328,78 @@ func (suite *jobTestSuite) TestJobAddTaskJobConfigGetError() {
 	suite.Nil(t)
 }
 
+// TestJobAddTasks tests adding tasks in a batch, recovering the
+// runtimes of the tasks missing from the cache in one DB call
+func (suite *jobTestSuite) TestJobAddTasks() {
+	cachedTask := newTask(suite.jobID, 0, suite.job.jobFactory, suite.job.jobType)
+	suite.job.tasks[0] = cachedTask
+	runtime := &pbtask.RuntimeInfo{
+		State: pbtask.TaskState_RUNNING,
+	}
+
+	suite.taskStore.EXPECT().
+		GetTaskRuntimes(gomock.Any(), suite.jobID, []uint32{1, 2}).
+		Return(
+			map[uint32]*pbtask.RuntimeInfo{1: runtime},
+			map[uint32]error{2: fmt.Errorf("fake db error")})
+
+	tasks, errs := suite.job.AddTasks(context.Background(), []uint32{0, 1, 2})
+	suite.Len(tasks, 2)
+	suite.Equal(cachedTask, tasks[0])
+	suite.Equal(runtime, (tasks[1].(*task)).runtime)
+	suite.Equal(tasks[1], suite.job.tasks[1])
+	suite.Len(errs, 1)
+	suite.EqualError(errs[2], "fake db error")
+	suite.Nil(suite.job.tasks[2])
+}
+
+// TestJobAddTasksAddedConcurrently tests adding tasks in a batch when
+// a task failing to be read is added to the cache concurrently
+func (suite *jobTestSuite) TestJobAddTasksAddedConcurrently() {
+	cachedTask := newTask(suite.jobID, 1, suite.job.jobFactory, suite.job.jobType)
+
+	suite.taskStore.EXPECT().
+		GetTaskRuntimes(gomock.Any(), suite.jobID, []uint32{1}).
+		DoAndReturn(func(
+			_ context.Context,
+			_ *peloton.JobID,
+			_ []uint32,
+		) (map[uint32]*pbtask.RuntimeInfo, map[uint32]error) {
+			suite.job.tasks[1] = cachedTask
+			return map[uint32]*pbtask.RuntimeInfo{},
+				map[uint32]error{1: fmt.Errorf("fake db error")}
+		})
+
+	tasks, errs := suite.job.AddTasks(context.Background(), []uint32{1})
+	suite.Len(tasks, 1)
+	suite.Equal(cachedTask, tasks[1])
+	suite.Empty(errs)
+}
+
+// TestJobAddTasksNotFound tests adding tasks in a batch when
+// a task beyond the instance count of the job is not in DB
+func (suite *jobTestSuite) TestJobAddTasksNotFound() {
+	suite.job.config = nil
+	jobConfig := &pbjob.JobConfig{
+		InstanceCount: 2,
+		Type:          pbjob.JobType_SERVICE,
+	}
+	suite.jobConfigOps.EXPECT().Get(
+		gomock.Any(),
+		suite.jobID,
+		suite.job.runtime.GetConfigurationVersion()).
+		Return(jobConfig, &models.ConfigAddOn{}, nil)
+	suite.taskStore.EXPECT().
+		GetTaskRuntimes(gomock.Any(), suite.jobID, []uint32{5}).
+		Return(
+			map[uint32]*pbtask.RuntimeInfo{},
+			map[uint32]error{5: yarpcerrors.NotFoundErrorf("not found")})
+
+	tasks, errs := suite.job.AddTasks(context.Background(), []uint32{5})
+	suite.Empty(tasks)
+	suite.Equal(InstanceIDExceedsInstanceCountError, errs[5])
+}
+
 // TestJobSetAndFetchConfigAndRuntime tests setting and fetching
 // job configuration and runtime.
 func (suite *jobTestSuite) TestJobSetAndFetchConfigAndRuntime() {
@@ -1637,8 +1712,10 @@ func (suite *jobTestSuite) TestJobGetConfigSuccess() {
 		ChangeLog: &peloton.ChangeLog{
 			Version: suite.job.runtime.ConfigurationVersion,
 		},
-		Name:   testName,
-		Labels: testLabels,
+		Name:       testName,
+		Owner:      "testOwner",
+		OwningTeam: "testTeam",
+		Labels:     testLabels,
 	}
 
 	suite.jobConfigOps.EXPECT().Get(
@@ -1652,6 +1729,8 @@ func (suite *jobTestSuite) TestJobGetConfigSuccess() {
 
 	suite.Equal(config.GetInstanceCount(), jobConfig.GetInstanceCount())
 	suite.Equal(config.GetName(), jobConfig.GetName())
+	suite.Equal(config.GetOwner(), jobConfig.GetOwner())
+	suite.Equal(config.GetOwningTeam(), jobConfig.GetOwningTeam())
 	suite.Equal(config.GetLabels(), jobConfig.GetLabels())
 
 	suite.Nil(config.GetSLA())
@@ -1859,8 +1938,8 @@ func (suite *jobTestSuite) TestPatchTasks_SetGetTasksSingle() {
 		jobmgrcommon.StateField: pbtask.TaskState_RUNNING,
 	}
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			gomock.Any(),
@@ -1868,11 +1947,20 @@ func (suite *jobTestSuite) TestPatchTasks_SetGetTasksSingle() {
 			gomock.Any()).
 		Return(nil).Times(int(instanceCount))
 	suite.taskStore.EXPECT().
-		GetTaskRuntime(gomock.Any(), suite.jobID, gomock.Any()).
-		Return(&pbtask.RuntimeInfo{
-			Revision: &peloton.ChangeLog{Version: 1},
-			State:    pbtask.TaskState_INITIALIZED,
-		}, nil).Times(int(instanceCount))
+		GetTaskRuntimes(gomock.Any(), suite.jobID, gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			_ *peloton.JobID,
+			instanceIDs []uint32,
+		) (map[uint32]*pbtask.RuntimeInfo, map[uint32]error) {
+			suite.Len(instanceIDs, 1)
+			return map[uint32]*pbtask.RuntimeInfo{
+				instanceIDs[0]: {
+					Revision: &peloton.ChangeLog{Version: 1},
+					State:    pbtask.TaskState_INITIALIZED,
+				},
+			}, nil
+		}).Times(int(instanceCount))
 	suite.taskConfigV2Ops.EXPECT().
 		GetTaskConfig(gomock.Any(), suite.jobID, gomock.Any(), gomock.Any()).
 		Return(nil, nil, nil).
@@ -1924,12 +2012,25 @@ func (suite *jobTestSuite) TestPatchTasksSetGetTasksMultiple() {
 
 	diffs := initializeDiffs(instanceCount, pbtask.TaskState_RUNNING)
 
+	// the runtimes of all the tasks are read in a single batch
+	oldRuntimes := make(map[uint32]*pbtask.RuntimeInfo)
 	for i := uint32(0); i < instanceCount; i++ {
-		oldRuntime := initializeCurrentRuntime(pbtask.TaskState_LAUNCHED)
-		suite.taskStore.EXPECT().
-			GetTaskRuntime(gomock.Any(), suite.jobID, i).Return(oldRuntime, nil)
-		suite.taskStore.EXPECT().
-			UpdateTaskRuntime(
+		oldRuntimes[i] = initializeCurrentRuntime(pbtask.TaskState_LAUNCHED)
+	}
+	suite.taskStore.EXPECT().
+		GetTaskRuntimes(gomock.Any(), suite.jobID, gomock.Any()).
+		Do(func(
+			_ context.Context,
+			_ *peloton.JobID,
+			instanceIDs []uint32,
+		) {
+			suite.Len(instanceIDs, int(instanceCount))
+		}).
+		Return(oldRuntimes, nil)
+
+	for i := uint32(0); i < instanceCount; i++ {
+		suite.taskRuntimeOps.EXPECT().
+			CompareAndSet(
 				gomock.Any(),
 				suite.jobID,
 				i,
@@ -1965,8 +2066,8 @@ func (suite *jobTestSuite) TestPatchTasksDBError() {
 			State: pbtask.TaskState_LAUNCHED,
 		}
 		// Simulate fake DB error
-		suite.taskStore.EXPECT().
-			UpdateTaskRuntime(
+		suite.taskRuntimeOps.EXPECT().
+			CompareAndSet(
 				gomock.Any(),
 				suite.jobID,
 				i,
@@ -1992,8 +2093,8 @@ func (suite *jobTestSuite) TestPatchTasksSingleTask() {
 	}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2048,8 +2149,8 @@ func (suite *jobTestSuite) TestPatchTasksSLAAwareNoViolation() {
 	tt.config = &taskConfigCache{}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2107,8 +2208,8 @@ func (suite *jobTestSuite) TestPatchTasksForUpdate() {
 	tt.config = &taskConfigCache{}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2220,8 +2321,8 @@ func (suite *jobTestSuite) TestPatchTasksForcePatch() {
 	tt.config = &taskConfigCache{}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2281,8 +2382,8 @@ func (suite *jobTestSuite) TestPatchTasksSLAAwareInstanceRestart() {
 	tt.config = &taskConfigCache{}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2339,8 +2440,8 @@ func (suite *jobTestSuite) TestPatchTasksSLAAwareMesosTaskIDChange() {
 	tt.config = &taskConfigCache{}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2393,8 +2494,8 @@ func (suite *jobTestSuite) TestPatchTasksSLAAwareDesiredConfigVersionChange() {
 	tt.config = &taskConfigCache{}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2457,8 +2558,8 @@ func (suite *jobTestSuite) TestPatchTasksSLAAwareInstanceDelete() {
 	tt.config = &taskConfigCache{}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2515,8 +2616,8 @@ func (suite *jobTestSuite) TestPatchTasksSLAAwareInstanceKill() {
 	tt.config = &taskConfigCache{}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2627,8 +2728,8 @@ func (suite *jobTestSuite) TestPatchTasksSLAAwareInstanceReinitialize() {
 	tt.config = &taskConfigCache{}
 
 	// Update task runtime of only one task
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			gomock.Any(),
 			gomock.Any(),
@@ -2915,6 +3016,7 @@ func (suite *jobTestSuite) TestCompareAndSetTaskSuccess() {
 			taskMetrics:     NewTaskMetrics(tally.NoopScope),
 			taskStore:       suite.taskStore,
 			taskConfigV2Ops: suite.taskConfigV2Ops,
+			taskRuntimeOps:  suite.taskRuntimeOps,
 			running:         true,
 			jobs:            map[string]*job{},
 		},
@@ -2935,8 +3037,8 @@ func (suite *jobTestSuite) TestCompareAndSetTaskSuccess() {
 		Labels: labels,
 	}
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			uint32(0),
@@ -2947,7 +3049,7 @@ func (suite *jobTestSuite) TestCompareAndSetTaskSuccess() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_RUNNING)
 			suite.Equal(runtime.Revision.Version, uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_SUCCEEDED)
@@ -4021,6 +4123,81 @@ func (suite *jobTestSuite) TestResumeWorkflowSuccess() {
 	suite.Equal(updateIDResult, updateID)
 }
 
+// TestPromoteWorkflowSuccess tests the success case
+// of promoting the canary phase of a workflow
+func (suite *jobTestSuite) TestPromoteWorkflowSuccess() {
+	oldConfigVersion := suite.job.runtime.GetConfigurationVersion()
+	oldWorkflowVersion := suite.job.runtime.GetWorkflowVersion()
+	opaque := "test"
+	desiredStateVersion := suite.job.runtime.GetDesiredStateVersion()
+	entityVersion := versionutil.GetJobEntityVersion(
+		oldConfigVersion,
+		desiredStateVersion,
+		oldWorkflowVersion,
+	)
+
+	updateID := &peloton.UpdateID{Value: testUpdateID}
+	suite.job.runtime.UpdateID = updateID
+	suite.job.workflows[updateID.GetValue()] = &update{
+		id:         updateID,
+		jobFactory: suite.job.jobFactory,
+		state:      pbupdate.State_ROLLING_FORWARD,
+		updateConfig: &pbupdate.UpdateConfig{
+			Canary: &pbupdate.CanaryConfig{InstanceCount: 1},
+		},
+	}
+
+	gomock.InOrder(
+		suite.jobRuntimeOps.EXPECT().
+			Upsert(gomock.Any(), suite.job.ID(), gomock.Any()).
+			Do(func(_ context.Context, _ *peloton.JobID, runtime *pbjob.RuntimeInfo) {
+				suite.Equal(runtime.GetConfigurationVersion(), oldConfigVersion)
+				suite.Equal(runtime.GetWorkflowVersion(), oldWorkflowVersion+1)
+			}).Return(nil),
+		suite.jobIndexOps.EXPECT().
+			Update(gomock.Any(), suite.jobID, gomock.Any(), gomock.Any()).
+			Return(nil),
+		suite.updateStore.EXPECT().
+			WriteUpdateProgress(gomock.Any(), gomock.Any()).
+			Do(func(ctx context.Context, updateInfo *models.UpdateModel) {
+				suite.True(updateInfo.GetCanaryPromoted())
+				suite.Equal(updateInfo.GetOpaqueData().GetData(), opaque)
+			}).
+			Return(nil),
+	)
+
+	updateIDResult, newEntityVersion, err := suite.job.PromoteWorkflow(
+		context.Background(),
+		entityVersion,
+		WithOpaqueData(&peloton.OpaqueData{Data: opaque}),
+	)
+
+	suite.NoError(err)
+	suite.Equal(
+		versionutil.GetJobEntityVersion(oldConfigVersion, desiredStateVersion, oldWorkflowVersion+1),
+		newEntityVersion,
+	)
+	suite.Equal(updateIDResult, updateID)
+}
+
+// TestPromoteWorkflowNoUpdate tests the failure case of promoting
+// a workflow when the job has no workflow
+func (suite *jobTestSuite) TestPromoteWorkflowNoUpdate() {
+	suite.job.runtime.UpdateID = nil
+
+	updateID, newEntityVersion, err := suite.job.PromoteWorkflow(
+		context.Background(),
+		versionutil.GetJobEntityVersion(
+			suite.job.runtime.GetConfigurationVersion(),
+			suite.job.runtime.GetDesiredStateVersion(),
+			suite.job.runtime.GetWorkflowVersion(),
+		),
+	)
+	suite.True(yarpcerrors.IsNotFound(err))
+	suite.Nil(newEntityVersion)
+	suite.Nil(updateID)
+}
+
 // TestResumeWorkflowNilEntityVersionFailure tests the failure case
 // of resuming a workflow due to nil entity version provided
 func (suite *jobTestSuite) TestResumeWorkflowNilEntityVersionFailure() {
@@ -396,17 +396,23 @@ func (t *task) patchTask(ctx context.Context, diff jobmgrcommon.RuntimeDiff) err
 		return nil
 	}
 
+	// the write only succeeds if the runtime in DB is still
+	// the one which has been patched
+	expectedVersion := newRuntimePtr.GetRevision().GetVersion()
 	t.updateRevision(newRuntimePtr)
 
-	err := t.jobFactory.taskStore.UpdateTaskRuntime(
+	err := t.jobFactory.taskRuntimeOps.CompareAndSet(
 		ctx,
 		t.jobID,
 		t.id,
 		newRuntimePtr,
-		t.jobType)
+		expectedVersion)
 	if err != nil {
 		// clean the runtime in cache on DB write failure
 		t.cleanTaskCache()
+		if yarpcerrors.IsAborted(err) {
+			return jobmgrcommon.UnexpectedVersionError
+		}
 		return err
 	}
 
@@ -425,7 +431,8 @@ func (t *task) patchTask(ctx context.Context, diff jobmgrcommon.RuntimeDiff) err
 }
 
 // compareAndSetTask replaces the existing task runtime in DB and cache.
-// It uses RuntimeInfo.Revision.Version for concurrency control, and it would
+// It uses RuntimeInfo.Revision.Version for concurrency control with a
+// conditional write in DB, and it would
 // update RuntimeInfo.Revision.Version automatically upon success.
 // Caller should not manually modify the value of RuntimeInfo.Revision.Version.
 func (t *task) compareAndSetTask(
@@ -464,7 +471,8 @@ func (t *task) compareAndSetTask(
 		}
 	}
 
-	// validate that the input version is the same as the version in cache
+	// validate that the input version is the same as the version in cache,
+	// so that a stale runtime is rejected without a round trip to DB
 	if runtime.GetRevision().GetVersion() !=
 		t.runtime.GetRevision().GetVersion() {
 		t.cleanTaskCache()
@@ -479,18 +487,23 @@ func (t *task) compareAndSetTask(
 	}
 
 	// bump up the changelog version
+	expectedVersion := runtime.GetRevision().GetVersion()
 	t.updateRevision(runtime)
 
-	// update the DB
-	err := t.jobFactory.taskStore.UpdateTaskRuntime(
+	// update the DB only if the runtime in DB has not been changed
+	// by another writer since it was read
+	err := t.jobFactory.taskRuntimeOps.CompareAndSet(
 		ctx,
 		t.jobID,
 		t.id,
 		runtime,
-		jobType)
+		expectedVersion)
 	if err != nil {
 		// clean the runtime in cache on DB write failure
 		t.cleanTaskCache()
+		if yarpcerrors.IsAborted(err) {
+			return nil, jobmgrcommon.UnexpectedVersionError
+		}
 		return nil, err
 	}
 
@@ -66,6 +66,7 @@ type taskTestSuite struct {
 	instanceID      uint32
 	taskStore       *storemocks.MockTaskStore
 	taskConfigV2Ops *objectmocks.MockTaskConfigV2Ops
+	taskRuntimeOps  *objectmocks.MockTaskRuntimeOps
 	listeners       []*FakeTaskListener
 
 	testTaskScope tally.TestScope
@@ -81,6 +82,7 @@ func (suite *taskTestSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 	suite.taskStore = storemocks.NewMockTaskStore(suite.ctrl)
 	suite.taskConfigV2Ops = objectmocks.NewMockTaskConfigV2Ops(suite.ctrl)
+	suite.taskRuntimeOps = objectmocks.NewMockTaskRuntimeOps(suite.ctrl)
 	suite.listeners = append(suite.listeners,
 		new(FakeTaskListener),
 		new(FakeTaskListener))
@@ -119,6 +121,7 @@ func (suite *taskTestSuite) initializeTask(
 			mtx:             NewMetrics(tally.NoopScope),
 			taskMetrics:     NewTaskMetrics(suite.testTaskScope),
 			taskConfigV2Ops: taskConfigV2Ops,
+			taskRuntimeOps:  suite.taskRuntimeOps,
 			taskStore:       taskStore,
 			running:         true,
 			jobs:            map[string]*job{},
@@ -215,8 +218,8 @@ func (suite *taskTestSuite) TestPatchTask() {
 		jobmgrcommon.ConfigVersionField: version,
 	}
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -227,11 +230,11 @@ func (suite *taskTestSuite) TestPatchTask() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_RUNNING)
 			suite.Equal(runtime.Revision.Version, uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_SUCCEEDED)
-			suite.Equal(suite.task.jobType, jobType)
+			suite.Equal(uint64(2), expectedVersion)
 		}).
 		Return(nil)
 
@@ -273,8 +276,8 @@ func (suite *taskTestSuite) TestPatchTask_WithInitializedState() {
 		},
 	}
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -285,7 +288,7 @@ func (suite *taskTestSuite) TestPatchTask_WithInitializedState() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_INITIALIZED)
 			suite.Equal(uint64(runtime.Revision.Version), uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_SUCCEEDED)
@@ -318,8 +321,8 @@ func (suite *taskTestSuite) TestPatchTask_KillInitializedTask() {
 		jobmgrcommon.GoalStateField: pbtask.TaskState_KILLED,
 	}
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -330,7 +333,7 @@ func (suite *taskTestSuite) TestPatchTask_KillInitializedTask() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_INITIALIZED)
 			suite.Equal(runtime.Revision.Version, uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_KILLED)
@@ -364,8 +367,8 @@ func (suite *taskTestSuite) TestPatchTask_NoRuntimeInCache() {
 	suite.taskStore.EXPECT().
 		GetTaskRuntime(gomock.Any(), suite.jobID, suite.instanceID).Return(runtime, nil)
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -375,7 +378,7 @@ func (suite *taskTestSuite) TestPatchTask_NoRuntimeInCache() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_RUNNING)
 			suite.Equal(runtime.Revision.Version, uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_SUCCEEDED)
@@ -405,8 +408,8 @@ func (suite *taskTestSuite) TestPatchTask_DBError() {
 		jobmgrcommon.StateField: pbtask.TaskState_RUNNING,
 	}
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -419,6 +422,32 @@ func (suite *taskTestSuite) TestPatchTask_DBError() {
 	suite.checkListenersNotCalled()
 }
 
+// TestPatchTask_VersionConflict tests updating the task runtime when
+// the runtime in DB has been changed by another writer
+func (suite *taskTestSuite) TestPatchTask_VersionConflict() {
+	runtime := initializeTaskRuntime(pbtask.TaskState_LAUNCHED, 2)
+	runtime.GoalState = pbtask.TaskState_SUCCEEDED
+	suite.task.runtime = runtime
+
+	diff := jobmgrcommon.RuntimeDiff{
+		jobmgrcommon.StateField: pbtask.TaskState_RUNNING,
+	}
+
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
+			gomock.Any(),
+			suite.jobID,
+			suite.instanceID,
+			gomock.Any(),
+			uint64(2)).
+		Return(yarpcerrors.AbortedErrorf("update condition not met"))
+
+	err := suite.task.patchTask(context.Background(), diff)
+	suite.Equal(jobmgrcommon.UnexpectedVersionError, err)
+	suite.Nil(suite.task.runtime)
+	suite.checkListenersNotCalled()
+}
+
 // TestPatchTaskGetConfigDBError tests updating the task runtime
 // with a DB error when getting the task config
 func (suite *taskTestSuite) TestPatchTaskGetConfigDBError() {
@@ -433,8 +462,8 @@ func (suite *taskTestSuite) TestPatchTaskGetConfigDBError() {
 		jobmgrcommon.ConfigVersionField: version,
 	}
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -445,11 +474,11 @@ func (suite *taskTestSuite) TestPatchTaskGetConfigDBError() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_RUNNING)
 			suite.Equal(runtime.Revision.Version, uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_SUCCEEDED)
-			suite.Equal(suite.task.jobType, jobType)
+			suite.Equal(uint64(2), expectedVersion)
 		}).
 		Return(nil)
 
@@ -505,8 +534,8 @@ func (suite *taskTestSuite) TestCompareAndSetTask() {
 		Labels: labels,
 	}
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -517,7 +546,7 @@ func (suite *taskTestSuite) TestCompareAndSetTask() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_RUNNING)
 			suite.Equal(runtime.Revision.Version, uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_SUCCEEDED)
@@ -589,8 +618,8 @@ func (suite *taskTestSuite) TestCompareAndSetTaskLoadRuntime() {
 		GetTaskRuntime(gomock.Any(), suite.jobID, suite.instanceID).
 		Return(runtime, nil)
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -601,7 +630,7 @@ func (suite *taskTestSuite) TestCompareAndSetTaskLoadRuntime() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_RUNNING)
 			suite.Equal(runtime.Revision.Version, uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_SUCCEEDED)
@@ -679,8 +708,8 @@ func (suite *taskTestSuite) TestCompareAndSetTaskDBError() {
 	newRuntime := initializeTaskRuntime(pbtask.TaskState_RUNNING, 2)
 	newRuntime.GoalState = pbtask.TaskState_SUCCEEDED
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -691,7 +720,7 @@ func (suite *taskTestSuite) TestCompareAndSetTaskDBError() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_RUNNING)
 			suite.Equal(runtime.Revision.Version, uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_SUCCEEDED)
@@ -707,6 +736,36 @@ func (suite *taskTestSuite) TestCompareAndSetTaskDBError() {
 	suite.checkListenersNotCalled()
 }
 
+// TestCompareAndSetTaskVersionConflict tests changing the task runtime
+// using compare and set when the runtime in DB has been changed by
+// another writer
+func (suite *taskTestSuite) TestCompareAndSetTaskVersionConflict() {
+	runtime := initializeTaskRuntime(pbtask.TaskState_LAUNCHED, 2)
+	runtime.GoalState = pbtask.TaskState_SUCCEEDED
+	suite.task.runtime = runtime
+
+	newRuntime := initializeTaskRuntime(pbtask.TaskState_RUNNING, 2)
+	newRuntime.GoalState = pbtask.TaskState_SUCCEEDED
+
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
+			gomock.Any(),
+			suite.jobID,
+			suite.instanceID,
+			gomock.Any(),
+			uint64(2)).
+		Return(yarpcerrors.AbortedErrorf("update condition not met"))
+
+	_, err := suite.task.compareAndSetTask(
+		context.Background(),
+		newRuntime,
+		pbjob.JobType_BATCH,
+	)
+	suite.Equal(jobmgrcommon.UnexpectedVersionError, err)
+	suite.Nil(suite.task.runtime)
+	suite.checkListenersNotCalled()
+}
+
 // TestCompareAndSetTaskConfigDBError tests changing the task runtime
 // using compare and set but getting an error while fetching the task config
 func (suite *taskTestSuite) TestCompareAndSetTaskConfigDBError() {
@@ -720,8 +779,8 @@ func (suite *taskTestSuite) TestCompareAndSetTaskConfigDBError() {
 	newRuntime.GoalState = pbtask.TaskState_SUCCEEDED
 	newRuntime.ConfigVersion = version
 
-	suite.taskStore.EXPECT().
-		UpdateTaskRuntime(
+	suite.taskRuntimeOps.EXPECT().
+		CompareAndSet(
 			gomock.Any(),
 			suite.jobID,
 			suite.instanceID,
@@ -732,7 +791,7 @@ func (suite *taskTestSuite) TestCompareAndSetTaskConfigDBError() {
 			jobID *peloton.JobID,
 			instanceID uint32,
 			runtime *pbtask.RuntimeInfo,
-			jobType pbjob.JobType) {
+			expectedVersion uint64) {
 			suite.Equal(runtime.GetState(), pbtask.TaskState_RUNNING)
 			suite.Equal(runtime.Revision.Version, uint64(3))
 			suite.Equal(runtime.GetGoalState(), pbtask.TaskState_SUCCEEDED)
@@ -1127,8 +1186,8 @@ func (suite *taskTestSuite) TestStateTransitionMetrics() {
 					gomock.Any(),
 					gomock.Any()).
 				Return(nil)
-			suite.taskStore.EXPECT().
-				UpdateTaskRuntime(
+			suite.taskRuntimeOps.EXPECT().
+				CompareAndSet(
 					gomock.Any(),
 					suite.jobID,
 					suite.instanceID,
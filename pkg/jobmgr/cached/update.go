@@ -83,6 +83,15 @@ type Update interface {
 	// to the state before pause
 	Resume(ctx context.Context, opaqueData *peloton.OpaqueData) error
 
+	// CompleteCanary records the time at which the canary instances
+	// of the update finished updating
+	CompleteCanary(ctx context.Context) error
+
+	// Promote promotes the canary phase of the update, so that the
+	// update continues to the remaining instances after the canary
+	// instances have baked successfully
+	Promote(ctx context.Context, opaqueData *peloton.OpaqueData) error
+
 	// Recover recovers the update from DB into the cache
 	Recover(ctx context.Context) error
 
@@ -129,6 +138,15 @@ type Update interface {
 
 	GetWorkflowType() models.WorkflowType
 
+	// GetCanaryCompletionTime returns the time at which the canary
+	// instances finished updating, or zero time if the canary phase
+	// has not completed yet
+	GetCanaryCompletionTime() time.Time
+
+	// IsCanaryPromoted returns true if the canary phase of the
+	// update has been promoted
+	IsCanaryPromoted() bool
+
 	// IsTaskInUpdateProgress returns true if a given task is
 	// in progress for the given update, else returns false
 	IsTaskInUpdateProgress(instanceID uint32) bool
@@ -227,6 +245,11 @@ type update struct {
 	jobPrevVersion uint64 // previous job configuration version
 
 	lastUpdateTime time.Time // last update time of update object
+
+	// time at which the canary instances finished updating
+	canaryCompletionTime time.Time
+	// whether the canary phase has been promoted
+	canaryPromoted bool
 }
 
 func (u *update) ID() *peloton.UpdateID {
@@ -456,6 +479,74 @@ func (u *update) Resume(ctx context.Context, opaqueData *peloton.OpaqueData) err
 	)
 }
 
+func (u *update) CompleteCanary(ctx context.Context) error {
+	u.Lock()
+	defer u.Unlock()
+
+	// TODO: do recovery automatically when read state
+	if err := u.recover(ctx); err != nil {
+		return err
+	}
+
+	// canary already completed, do nothing
+	if !u.canaryCompletionTime.IsZero() {
+		return nil
+	}
+
+	now := time.Now()
+	if err := u.jobFactory.updateStore.WriteUpdateProgress(
+		ctx,
+		&models.UpdateModel{
+			UpdateID:             u.id,
+			UpdateTime:           now.Format(time.RFC3339Nano),
+			CanaryCompletionTime: now.Format(time.RFC3339Nano),
+		}); err != nil {
+		u.clearCache()
+		return err
+	}
+
+	u.canaryCompletionTime = now
+	u.lastUpdateTime = now
+	return nil
+}
+
+func (u *update) Promote(ctx context.Context, opaqueData *peloton.OpaqueData) error {
+	u.Lock()
+	defer u.Unlock()
+
+	// TODO: do recovery automatically when read state
+	if err := u.recover(ctx); err != nil {
+		return err
+	}
+
+	if !HasCanary(u.updateConfig) {
+		return yarpcerrors.FailedPreconditionErrorf(
+			"workflow does not have a canary phase")
+	}
+
+	// already promoted or no longer running, do nothing
+	if u.canaryPromoted || IsUpdateStateTerminal(u.state) {
+		return nil
+	}
+
+	now := time.Now()
+	if err := u.jobFactory.updateStore.WriteUpdateProgress(
+		ctx,
+		&models.UpdateModel{
+			UpdateID:       u.id,
+			UpdateTime:     now.Format(time.RFC3339Nano),
+			OpaqueData:     opaqueData,
+			CanaryPromoted: true,
+		}); err != nil {
+		u.clearCache()
+		return err
+	}
+
+	u.canaryPromoted = true
+	u.lastUpdateTime = now
+	return nil
+}
+
 // writeProgress write update progress into cache and db,
 // it is not concurrency safe and must be called with lock held.
 func (u *update) writeProgress(
@@ -846,6 +937,20 @@ func (u *update) GetWorkflowType() models.WorkflowType {
 	return u.workflowType
 }
 
+func (u *update) GetCanaryCompletionTime() time.Time {
+	u.RLock()
+	defer u.RUnlock()
+
+	return u.canaryCompletionTime
+}
+
+func (u *update) IsCanaryPromoted() bool {
+	u.RLock()
+	defer u.RUnlock()
+
+	return u.canaryPromoted
+}
+
 // IsTaskInUpdateProgress returns true if a given task is
 // in progress for the given update, else returns false
 func (u *update) IsTaskInUpdateProgress(instanceID uint32) bool {
@@ -890,6 +995,8 @@ func (u *update) populateCache(updateModel *models.UpdateModel) {
 	u.instancesTotal = append(u.instancesTotal, updateModel.GetInstancesRemoved()...)
 	u.WorkflowStrategy = getWorkflowStrategy(updateModel.GetState(), updateModel.GetType())
 	u.lastUpdateTime, _ = time.Parse(time.RFC3339Nano, updateModel.GetUpdateTime())
+	u.canaryCompletionTime, _ = time.Parse(time.RFC3339Nano, updateModel.GetCanaryCompletionTime())
+	u.canaryPromoted = updateModel.GetCanaryPromoted()
 }
 
 func (u *update) clearCache() {
@@ -902,6 +1009,14 @@ func (u *update) clearCache() {
 	u.instancesAdded = nil
 	u.instancesUpdated = nil
 	u.instancesRemoved = nil
+	u.canaryCompletionTime = time.Time{}
+	u.canaryPromoted = false
+}
+
+// HasCanary returns true if the update config requests a canary phase
+func HasCanary(updateConfig *pbupdate.UpdateConfig) bool {
+	return updateConfig.GetCanary().GetInstanceCount() > 0 ||
+		updateConfig.GetCanary().GetInstancePercentage() > 0
 }
 
 // GetUpdateProgress iterates through instancesToCheck and check if they are running and
@@ -676,6 +676,90 @@ func (suite *UpdateTestSuite) TestResumeRecoverFail() {
 	suite.Error(suite.update.Resume(context.Background(), nil))
 }
 
+// TestCompleteCanary tests recording the completion of the canary phase
+func (suite *UpdateTestSuite) TestCompleteCanary() {
+	suite.update.state = pbupdate.State_ROLLING_FORWARD
+
+	suite.updateStore.EXPECT().
+		WriteUpdateProgress(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, updateModel *models.UpdateModel) {
+			suite.Equal(suite.updateID, updateModel.UpdateID)
+			suite.Equal(pbupdate.State_INVALID, updateModel.State)
+			suite.NotEmpty(updateModel.GetCanaryCompletionTime())
+		}).
+		Return(nil)
+
+	suite.NoError(suite.update.CompleteCanary(context.Background()))
+	suite.False(suite.update.GetCanaryCompletionTime().IsZero())
+
+	// completing the canary again is a no-op
+	completionTime := suite.update.GetCanaryCompletionTime()
+	suite.NoError(suite.update.CompleteCanary(context.Background()))
+	suite.Equal(completionTime, suite.update.GetCanaryCompletionTime())
+}
+
+// TestCompleteCanaryDBError tests the failure case of
+// recording the completion of the canary phase due to DB error
+func (suite *UpdateTestSuite) TestCompleteCanaryDBError() {
+	suite.update.state = pbupdate.State_ROLLING_FORWARD
+
+	suite.updateStore.EXPECT().
+		WriteUpdateProgress(gomock.Any(), gomock.Any()).
+		Return(yarpcerrors.UnavailableErrorf("test error"))
+
+	suite.Error(suite.update.CompleteCanary(context.Background()))
+	suite.True(suite.update.GetCanaryCompletionTime().IsZero())
+	suite.Equal(pbupdate.State_INVALID, suite.update.state)
+}
+
+// TestPromoteSuccess tests successfully promoting the canary of an update
+func (suite *UpdateTestSuite) TestPromoteSuccess() {
+	suite.update.state = pbupdate.State_ROLLING_FORWARD
+	suite.update.updateConfig = &pbupdate.UpdateConfig{
+		Canary: &pbupdate.CanaryConfig{InstanceCount: 1},
+	}
+	opaque := "test"
+
+	suite.updateStore.EXPECT().
+		WriteUpdateProgress(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, updateModel *models.UpdateModel) {
+			suite.Equal(suite.updateID, updateModel.UpdateID)
+			suite.True(updateModel.GetCanaryPromoted())
+			suite.Equal(opaque, updateModel.GetOpaqueData().GetData())
+		}).
+		Return(nil)
+
+	suite.NoError(suite.update.Promote(
+		context.Background(),
+		&peloton.OpaqueData{Data: opaque}),
+	)
+	suite.True(suite.update.IsCanaryPromoted())
+
+	// promoting again is a no-op
+	suite.NoError(suite.update.Promote(context.Background(), nil))
+}
+
+// TestPromoteWithoutCanary tests promoting an update
+// which does not have a canary phase
+func (suite *UpdateTestSuite) TestPromoteWithoutCanary() {
+	suite.update.state = pbupdate.State_ROLLING_FORWARD
+	suite.update.updateConfig = &pbupdate.UpdateConfig{}
+
+	err := suite.update.Promote(context.Background(), nil)
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+	suite.False(suite.update.IsCanaryPromoted())
+}
+
+// TestPromoteRecoverFail tests the failure case of
+// promoting an update due to recover failure
+func (suite *UpdateTestSuite) TestPromoteRecoverFail() {
+	suite.updateStore.EXPECT().
+		GetUpdate(gomock.Any(), suite.updateID).
+		Return(nil, yarpcerrors.InternalErrorf("test error"))
+
+	suite.Error(suite.update.Promote(context.Background(), nil))
+}
+
 // TestCancelValid tests successfully canceling a job update
 func (suite *UpdateTestSuite) TestCancelValid() {
 	instancesDone := []uint32{1, 2, 3, 4, 5}
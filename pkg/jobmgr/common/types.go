@@ -63,8 +63,18 @@ type JobConfig interface {
 	GetLabels() []*peloton.Label
 	// GetName returns the name of the job stored in the cache
 	GetName() string
+	// GetOwner returns the owner of the job stored in the cache
+	GetOwner() string
+	// GetOwningTeam returns the owning team of the job stored in the cache
+	GetOwningTeam() string
 	// GetPlacementStrategy returns the placement strategy
 	GetPlacementStrategy() pbjob.PlacementStrategy
+	// GetAutoscaling returns the autoscaling policy of the job
+	GetAutoscaling() *pbjob.AutoscalingConfig
+	// GetCompletionPolicy returns the completion policy of the job
+	GetCompletionPolicy() *pbjob.CompletionPolicy
+	// GetInstanceGroups returns the instance groups of the job
+	GetInstanceGroups() []*pbjob.InstanceGroup
 }
 
 // RuntimeDiff to be applied to the runtime struct.
@@ -19,8 +19,11 @@ import (
 
 	"github.com/uber/peloton/pkg/common/api"
 	"github.com/uber/peloton/pkg/common/config"
+	"github.com/uber/peloton/pkg/jobmgr/autoscaler"
+	"github.com/uber/peloton/pkg/jobmgr/dashboard"
 	"github.com/uber/peloton/pkg/jobmgr/goalstate"
 	"github.com/uber/peloton/pkg/jobmgr/jobsvc"
+	"github.com/uber/peloton/pkg/jobmgr/retention"
 	"github.com/uber/peloton/pkg/jobmgr/task/deadline"
 	"github.com/uber/peloton/pkg/jobmgr/task/placement"
 	"github.com/uber/peloton/pkg/jobmgr/task/preemptor"
@@ -62,6 +65,15 @@ type Config struct {
 	// WorkflowProgressCheck specific configuration
 	WorkflowProgressCheck progress.Config `yaml:"workflow_progress_check"`
 
+	// Autoscaler specific configuration
+	Autoscaler autoscaler.Config `yaml:"autoscaler"`
+
+	// Retention compactor specific configuration
+	Retention retention.Config `yaml:"retention"`
+
+	// Dashboard aggregator specific configuration
+	Dashboard dashboard.Config `yaml:"dashboard"`
+
 	// Period in sec for updating active cache
 	ActiveTaskUpdatePeriod time.Duration `yaml:"active_task_update_period"`
 
@@ -0,0 +1,528 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common/background"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/atomic"
+)
+
+const (
+	_aggregatorName = "dashboardAggregator"
+
+	// timeout to read a job and its tasks from the cache, or
+	// a resource pool from resource manager
+	_syncTimeout = 10 * time.Second
+
+	// pending reason used when the runtime of a pending pod
+	// carries neither a reason nor a message
+	_unknownReason = "unknown"
+)
+
+// Aggregator maintains per resource pool and per owner rollups of the
+// jobs in the cache. It is notified of the job and task runtime changes
+// as a cached.JobTaskListener, and rebuilds the rollups from the cache
+// periodically, which is also when new jobs are picked up.
+type Aggregator struct {
+	respoolClient respool.ResourceManagerYARPCClient
+	jobFactory    cached.JobFactory
+	metrics       *Metrics
+	config        *Config
+
+	// lock protects the fields below. It is never held while
+	// calling the job cache or resource manager.
+	lock sync.RWMutex
+
+	jobs         map[string]*jobEntry
+	respools     map[string]*group
+	owners       map[string]*group
+	respoolInfos map[string]*respool.ResourcePoolInfo
+
+	// version is bumped on every change to the rollups, so that
+	// views are only rebuilt when something has changed
+	version uint64
+
+	viewLock sync.Mutex
+	views    map[viewKey]*view
+}
+
+// jobEntry is the state of a job tracked by the aggregator.
+type jobEntry struct {
+	id string
+	// resolved is set once the config of the job has been read from the
+	// cache. Only resolved jobs are counted in the rollups.
+	resolved  bool
+	name      string
+	owner     string
+	respoolID string
+
+	state    pbjob.JobState
+	revision uint64
+	pods     map[uint32]*podEntry
+	// number of pods of the job in a pending state
+	pending int
+}
+
+// podEntry is the state of a pod tracked by the aggregator.
+type podEntry struct {
+	state pbtask.TaskState
+	// reason for which the pod is pending, empty if it is not pending
+	reason   string
+	revision uint64
+}
+
+// group holds the counters of a set of jobs, either all the jobs
+// of a resource pool or all the jobs of an owner.
+type group struct {
+	jobStates      map[pbjob.JobState]int
+	podStates      map[pbtask.TaskState]int
+	pendingReasons map[string]int
+	jobs           map[string]*jobEntry
+}
+
+// NewAggregator returns a new dashboard aggregator.
+func NewAggregator(
+	respoolClient respool.ResourceManagerYARPCClient,
+	metrics *Metrics,
+	config *Config,
+) *Aggregator {
+	if config == nil {
+		config = &Config{}
+	}
+	config.normalize()
+
+	return &Aggregator{
+		respoolClient: respoolClient,
+		metrics:       metrics,
+		config:        config,
+		jobs:          make(map[string]*jobEntry),
+		respools:      make(map[string]*group),
+		owners:        make(map[string]*group),
+		respoolInfos:  make(map[string]*respool.ResourcePoolInfo),
+		views:         make(map[viewKey]*view),
+	}
+}
+
+// Register registers the periodic sync of the aggregator with the
+// job cache as a background work.
+func (a *Aggregator) Register(
+	manager background.Manager,
+	jobFactory cached.JobFactory,
+) error {
+	a.jobFactory = jobFactory
+	return manager.RegisterWorks(
+		background.Work{
+			Name: _aggregatorName,
+			Func: func(_ *atomic.Bool) {
+				a.Sync()
+			},
+			Period: a.config.SyncPeriod,
+		},
+	)
+}
+
+// Name returns the name of the listener.
+func (a *Aggregator) Name() string {
+	return _aggregatorName
+}
+
+// JobRuntimeChanged updates the job state counters of the rollups
+// the job belongs to.
+func (a *Aggregator) JobRuntimeChanged(
+	jobID *peloton.JobID,
+	jobType pbjob.JobType,
+	runtime *pbjob.RuntimeInfo,
+) {
+	if jobID == nil || runtime == nil {
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	e := a.getOrAddJob(jobID.GetValue())
+	revision := runtime.GetRevision().GetVersion()
+	if revision < e.revision {
+		return
+	}
+
+	for _, g := range a.groupsOf(e, false) {
+		incJobState(g.jobStates, e.state, -1)
+		incJobState(g.jobStates, runtime.GetState(), 1)
+	}
+	e.state = runtime.GetState()
+	e.revision = revision
+	a.version++
+}
+
+// TaskRuntimeChanged updates the pod state and pending reason counters
+// of the rollups the job of the task belongs to.
+func (a *Aggregator) TaskRuntimeChanged(
+	jobID *peloton.JobID,
+	instanceID uint32,
+	jobType pbjob.JobType,
+	runtime *pbtask.RuntimeInfo,
+	labels []*peloton.Label,
+) {
+	if jobID == nil || runtime == nil {
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	e := a.getOrAddJob(jobID.GetValue())
+	pod := newPodEntry(runtime)
+	if old, ok := e.pods[instanceID]; ok && pod.revision < old.revision {
+		return
+	}
+
+	if runtime.GetState() == pbtask.TaskState_DELETED {
+		pod = nil
+	}
+	a.setPod(e, instanceID, pod)
+}
+
+// Sync rebuilds the state of all the jobs in the cache, drops the jobs
+// which are no longer in the cache and refreshes the resource pools.
+func (a *Aggregator) Sync() {
+	stopWatch := a.metrics.SyncDuration.Start()
+	defer stopWatch.Stop()
+
+	jobs := a.jobFactory.GetAllJobs()
+	for _, cachedJob := range jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), _syncTimeout)
+		e, err := snapshotJob(ctx, cachedJob)
+		cancel()
+		if err != nil {
+			log.WithError(err).
+				WithField("job_id", cachedJob.ID().GetValue()).
+				Info("failed to read job for dashboard")
+			a.metrics.SyncFail.Inc(1)
+			continue
+		}
+
+		a.lock.Lock()
+		a.setJob(e)
+		a.lock.Unlock()
+	}
+
+	a.lock.Lock()
+	for id, e := range a.jobs {
+		if _, ok := jobs[id]; !ok {
+			a.countJob(e, -1)
+			delete(a.jobs, id)
+			a.version++
+		}
+	}
+	var respoolIDs []string
+	for id := range a.respools {
+		respoolIDs = append(respoolIDs, id)
+	}
+	a.lock.Unlock()
+
+	respoolInfos := make(map[string]*respool.ResourcePoolInfo)
+	for _, id := range respoolIDs {
+		info, err := a.getRespoolInfo(id)
+		if err != nil {
+			log.WithError(err).
+				WithField("respool_id", id).
+				Info("failed to get resource pool for dashboard")
+			a.metrics.SyncFail.Inc(1)
+			continue
+		}
+		respoolInfos[id] = info
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for id, info := range respoolInfos {
+		a.respoolInfos[id] = info
+	}
+	for id := range a.respoolInfos {
+		if _, ok := a.respools[id]; !ok {
+			delete(a.respoolInfos, id)
+		}
+	}
+	a.version++
+
+	a.metrics.NumJobs.Update(float64(len(a.jobs)))
+	a.metrics.NumRespools.Update(float64(len(a.respools)))
+	a.metrics.NumOwners.Update(float64(len(a.owners)))
+}
+
+// getRespoolInfo reads a resource pool from resource manager.
+func (a *Aggregator) getRespoolInfo(
+	id string,
+) (*respool.ResourcePoolInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), _syncTimeout)
+	defer cancel()
+
+	resp, err := a.respoolClient.GetResourcePool(
+		ctx,
+		&respool.GetRequest{
+			Id: &peloton.ResourcePoolID{Value: id},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetError() != nil {
+		return nil, errors.New(resp.GetError().GetNotFound().GetMessage())
+	}
+	return resp.GetPoolinfo(), nil
+}
+
+// snapshotJob reads the state of a job and its pods from the cache.
+func snapshotJob(
+	ctx context.Context,
+	cachedJob cached.Job,
+) (*jobEntry, error) {
+	config, err := cachedJob.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime, err := cachedJob.GetRuntime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e := newJobEntry(cachedJob.ID().GetValue())
+	e.resolved = true
+	e.name = config.GetName()
+	e.owner = config.GetOwningTeam()
+	if e.owner == "" {
+		e.owner = config.GetOwner()
+	}
+	e.respoolID = config.GetRespoolID().GetValue()
+	e.state = runtime.GetState()
+	e.revision = runtime.GetRevision().GetVersion()
+
+	for instanceID, cachedTask := range cachedJob.GetAllTasks() {
+		taskRuntime, err := cachedTask.GetRuntime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if taskRuntime.GetState() == pbtask.TaskState_DELETED {
+			continue
+		}
+		e.addPod(instanceID, newPodEntry(taskRuntime))
+	}
+	return e, nil
+}
+
+// setJob replaces the state of a job with the one read from the cache,
+// keeping the job and pod states which have been updated by the listener
+// since the cache was read.
+func (a *Aggregator) setJob(e *jobEntry) {
+	if old, ok := a.jobs[e.id]; ok {
+		if old.revision > e.revision {
+			e.state = old.state
+			e.revision = old.revision
+		}
+		for instanceID, pod := range e.pods {
+			if oldPod, ok := old.pods[instanceID]; ok &&
+				oldPod.revision > pod.revision {
+				e.removePod(instanceID)
+				e.addPod(instanceID, oldPod)
+			}
+		}
+		a.countJob(old, -1)
+	}
+
+	a.jobs[e.id] = e
+	a.countJob(e, 1)
+	a.version++
+}
+
+// setPod sets the state of a pod of a job, or removes the pod if
+// the given state is nil.
+func (a *Aggregator) setPod(e *jobEntry, instanceID uint32, pod *podEntry) {
+	groups := a.groupsOf(e, false)
+	if old, ok := e.pods[instanceID]; ok {
+		for _, g := range groups {
+			g.countPod(old, -1)
+		}
+		e.removePod(instanceID)
+	}
+
+	if pod != nil {
+		for _, g := range groups {
+			g.countPod(pod, 1)
+		}
+		e.addPod(instanceID, pod)
+	}
+	a.version++
+}
+
+// countJob adds (delta 1) or removes (delta -1) a job and its pods
+// to/from the rollups the job belongs to.
+func (a *Aggregator) countJob(e *jobEntry, delta int) {
+	for key, g := range a.groupsOf(e, delta > 0) {
+		incJobState(g.jobStates, e.state, delta)
+		for _, pod := range e.pods {
+			g.countPod(pod, delta)
+		}
+
+		if delta > 0 {
+			g.jobs[e.id] = e
+			continue
+		}
+		delete(g.jobs, e.id)
+		if len(g.jobs) > 0 {
+			continue
+		}
+		if key.isRespool {
+			delete(a.respools, key.value)
+		} else {
+			delete(a.owners, key.value)
+		}
+	}
+}
+
+// groupKey identifies one of the groups a job belongs to.
+type groupKey struct {
+	isRespool bool
+	// resource pool ID or owner
+	value string
+}
+
+// groupsOf returns the groups a resolved job belongs to, creating
+// them if create is set.
+func (a *Aggregator) groupsOf(e *jobEntry, create bool) map[groupKey]*group {
+	groups := make(map[groupKey]*group)
+	if !e.resolved {
+		return groups
+	}
+
+	if g := getGroup(a.respools, e.respoolID, create); g != nil {
+		groups[groupKey{isRespool: true, value: e.respoolID}] = g
+	}
+	if g := getGroup(a.owners, e.owner, create); g != nil {
+		groups[groupKey{value: e.owner}] = g
+	}
+	return groups
+}
+
+func getGroup(groups map[string]*group, key string, create bool) *group {
+	g, ok := groups[key]
+	if !ok && create {
+		g = &group{
+			jobStates:      make(map[pbjob.JobState]int),
+			podStates:      make(map[pbtask.TaskState]int),
+			pendingReasons: make(map[string]int),
+			jobs:           make(map[string]*jobEntry),
+		}
+		groups[key] = g
+	}
+	return g
+}
+
+// getOrAddJob returns the entry of a job, adding an unresolved entry
+// if the job is not known yet.
+func (a *Aggregator) getOrAddJob(id string) *jobEntry {
+	e, ok := a.jobs[id]
+	if !ok {
+		e = newJobEntry(id)
+		a.jobs[id] = e
+	}
+	return e
+}
+
+func newJobEntry(id string) *jobEntry {
+	return &jobEntry{
+		id:   id,
+		pods: make(map[uint32]*podEntry),
+	}
+}
+
+func (e *jobEntry) addPod(instanceID uint32, pod *podEntry) {
+	e.pods[instanceID] = pod
+	if pod.reason != "" {
+		e.pending++
+	}
+}
+
+func (e *jobEntry) removePod(instanceID uint32) {
+	if pod, ok := e.pods[instanceID]; ok {
+		if pod.reason != "" {
+			e.pending--
+		}
+		delete(e.pods, instanceID)
+	}
+}
+
+func newPodEntry(runtime *pbtask.RuntimeInfo) *podEntry {
+	pod := &podEntry{
+		state:    runtime.GetState(),
+		revision: runtime.GetRevision().GetVersion(),
+	}
+
+	if isPending(pod.state) {
+		pod.reason = runtime.GetReason()
+		if pod.reason == "" {
+			pod.reason = runtime.GetMessage()
+		}
+		if pod.reason == "" {
+			pod.reason = _unknownReason
+		}
+	}
+	return pod
+}
+
+// isPending returns true if a pod in the given state is waiting
+// to be placed and launched.
+func isPending(state pbtask.TaskState) bool {
+	return state == pbtask.TaskState_INITIALIZED ||
+		state == pbtask.TaskState_PENDING
+}
+
+func (g *group) countPod(pod *podEntry, delta int) {
+	incTaskState(g.podStates, pod.state, delta)
+	if pod.reason != "" {
+		g.pendingReasons[pod.reason] += delta
+		if g.pendingReasons[pod.reason] <= 0 {
+			delete(g.pendingReasons, pod.reason)
+		}
+	}
+}
+
+func incJobState(counts map[pbjob.JobState]int, state pbjob.JobState, delta int) {
+	counts[state] += delta
+	if counts[state] <= 0 {
+		delete(counts, state)
+	}
+}
+
+func incTaskState(counts map[pbtask.TaskState]int, state pbtask.TaskState, delta int) {
+	counts[state] += delta
+	if counts[state] <= 0 {
+		delete(counts, state)
+	}
+}
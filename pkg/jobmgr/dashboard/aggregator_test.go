@@ -0,0 +1,382 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/dashboardsvc"
+
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+
+	backgroundmocks "github.com/uber/peloton/pkg/common/background/mocks"
+	leadermocks "github.com/uber/peloton/pkg/common/leader/mocks"
+	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_testRespoolID   = "8b3d0f6e-2c4a-4e7b-9f1d-5a6c8e2b4d7f"
+	_testRespoolPath = "/infra/compute"
+	_testOwningTeam  = "compute"
+)
+
+type AggregatorTestSuite struct {
+	suite.Suite
+	mockCtrl *gomock.Controller
+
+	jobFactory    *cachedmocks.MockJobFactory
+	cachedJob     *cachedmocks.MockJob
+	cachedConfig  *cachedmocks.MockJobConfigCache
+	respoolClient *respoolmocks.MockResourceManagerYARPCClient
+	aggregator    *Aggregator
+
+	jobID *peloton.JobID
+}
+
+func (s *AggregatorTestSuite) SetupTest() {
+	s.mockCtrl = gomock.NewController(s.T())
+
+	s.jobFactory = cachedmocks.NewMockJobFactory(s.mockCtrl)
+	s.cachedJob = cachedmocks.NewMockJob(s.mockCtrl)
+	s.cachedConfig = cachedmocks.NewMockJobConfigCache(s.mockCtrl)
+	s.respoolClient = respoolmocks.NewMockResourceManagerYARPCClient(s.mockCtrl)
+
+	s.aggregator = NewAggregator(
+		s.respoolClient,
+		NewMetrics(tally.NoopScope),
+		&Config{},
+	)
+	s.aggregator.jobFactory = s.jobFactory
+
+	s.jobID = &peloton.JobID{Value: "3e8c1a5f-7d2b-4f9e-a6c4-1b5d9e3f7a2c"}
+	s.cachedJob.EXPECT().ID().Return(s.jobID).AnyTimes()
+}
+
+func (s *AggregatorTestSuite) TearDownTest() {
+	s.mockCtrl.Finish()
+}
+
+func TestAggregator(t *testing.T) {
+	suite.Run(t, new(AggregatorTestSuite))
+}
+
+// taskRuntime returns a task runtime in the given state
+func taskRuntime(
+	state pbtask.TaskState,
+	reason string,
+	version uint64,
+) *pbtask.RuntimeInfo {
+	return &pbtask.RuntimeInfo{
+		State:    state,
+		Reason:   reason,
+		Revision: &peloton.ChangeLog{Version: version},
+	}
+}
+
+// expectSync sets up the cache to return the test job with the
+// given task runtimes.
+func (s *AggregatorTestSuite) expectSync(
+	runtimes map[uint32]*pbtask.RuntimeInfo,
+) {
+	s.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{s.jobID.GetValue(): s.cachedJob})
+	s.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(s.cachedConfig, nil)
+	s.cachedConfig.EXPECT().GetName().Return("test-job").AnyTimes()
+	s.cachedConfig.EXPECT().GetOwner().Return("alice").AnyTimes()
+	s.cachedConfig.EXPECT().GetOwningTeam().Return(_testOwningTeam).AnyTimes()
+	s.cachedConfig.EXPECT().
+		GetRespoolID().
+		Return(&peloton.ResourcePoolID{Value: _testRespoolID}).
+		AnyTimes()
+	s.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:    pbjob.JobState_RUNNING,
+			Revision: &peloton.ChangeLog{Version: 2},
+		}, nil)
+
+	tasks := make(map[uint32]cached.Task)
+	for instanceID, runtime := range runtimes {
+		cachedTask := cachedmocks.NewMockTask(s.mockCtrl)
+		cachedTask.EXPECT().GetRuntime(gomock.Any()).Return(runtime, nil)
+		tasks[instanceID] = cachedTask
+	}
+	s.cachedJob.EXPECT().GetAllTasks().Return(tasks)
+}
+
+// expectGetRespool sets up resource manager to return the test
+// resource pool.
+func (s *AggregatorTestSuite) expectGetRespool() {
+	s.respoolClient.EXPECT().
+		GetResourcePool(gomock.Any(), &respool.GetRequest{
+			Id: &peloton.ResourcePoolID{Value: _testRespoolID},
+		}).
+		Return(&respool.GetResponse{
+			Poolinfo: &respool.ResourcePoolInfo{
+				Id:   &peloton.ResourcePoolID{Value: _testRespoolID},
+				Path: &respool.ResourcePoolPath{Value: _testRespoolPath},
+				Config: &respool.ResourcePoolConfig{
+					Resources: []*respool.ResourceConfig{
+						{Kind: "cpu", Reservation: 10, Limit: 20},
+					},
+				},
+				Usage: []*respool.ResourceUsage{
+					{Kind: "cpu", Allocation: 4, Slack: 1},
+				},
+				Entitlement: []*respool.ResourceEntitlement{
+					{Kind: "cpu", Entitlement: 12},
+				},
+			},
+		}, nil)
+}
+
+// TestRegister tests registering the sync of the aggregator
+// as a background work
+func (s *AggregatorTestSuite) TestRegister() {
+	manager := backgroundmocks.NewMockManager(s.mockCtrl)
+	manager.EXPECT().RegisterWorks(gomock.Any()).Return(nil)
+	s.NoError(s.aggregator.Register(manager, s.jobFactory))
+	s.Equal(_defaultSyncPeriod, s.aggregator.config.SyncPeriod)
+}
+
+// TestSync tests building the rollups from the job cache
+func (s *AggregatorTestSuite) TestSync() {
+	s.expectSync(map[uint32]*pbtask.RuntimeInfo{
+		0: taskRuntime(pbtask.TaskState_RUNNING, "", 3),
+		1: taskRuntime(pbtask.TaskState_PENDING, "insufficient cpu", 2),
+		2: taskRuntime(pbtask.TaskState_INITIALIZED, "", 1),
+		3: taskRuntime(pbtask.TaskState_DELETED, "", 5),
+	})
+	s.expectGetRespool()
+	s.aggregator.Sync()
+
+	rollups := s.aggregator.RespoolRollups(10)
+	s.Len(rollups, 1)
+	r := rollups[0]
+	s.Equal(_testRespoolID, r.Key)
+	s.Equal(_testRespoolPath, r.Path)
+	s.Equal(map[string]uint32{"RUNNING": 1}, r.JobStates)
+	s.Equal(map[string]uint32{
+		"RUNNING":     1,
+		"PENDING":     1,
+		"INITIALIZED": 1,
+	}, r.PodStates)
+	s.Equal(map[string]uint32{
+		"insufficient cpu": 1,
+		_unknownReason:     1,
+	}, r.PendingReasons)
+	s.Equal([]*dashboardsvc.ResourceRollup{{
+		Kind:        "cpu",
+		Allocation:  4,
+		Slack:       1,
+		Entitlement: 12,
+		Reservation: 10,
+		Limit:       20,
+	}}, r.Resources)
+	s.Equal([]*dashboardsvc.PendingJob{{
+		JobId:       s.jobID.GetValue(),
+		Name:        "test-job",
+		Owner:       _testOwningTeam,
+		PendingPods: 2,
+	}}, r.TopPendingJobs)
+
+	rollups = s.aggregator.OwnerRollups(10)
+	s.Len(rollups, 1)
+	s.Equal(_testOwningTeam, rollups[0].Key)
+	s.Empty(rollups[0].Path)
+	s.Empty(rollups[0].Resources)
+	s.Equal(r.PodStates, rollups[0].PodStates)
+
+	s.Empty(s.aggregator.OwnerRollups(0)[0].TopPendingJobs)
+}
+
+// TestListener tests updating the rollups on job and task
+// runtime changes
+func (s *AggregatorTestSuite) TestListener() {
+	s.expectSync(map[uint32]*pbtask.RuntimeInfo{
+		0: taskRuntime(pbtask.TaskState_PENDING, "insufficient cpu", 2),
+		1: taskRuntime(pbtask.TaskState_PENDING, "insufficient cpu", 2),
+	})
+	s.expectGetRespool()
+	s.aggregator.Sync()
+	rollups := s.aggregator.RespoolRollups(10)
+	s.Equal(map[string]uint32{"PENDING": 2}, rollups[0].PodStates)
+
+	// pod gets launched
+	s.aggregator.TaskRuntimeChanged(
+		s.jobID, 0, pbjob.JobType_BATCH,
+		taskRuntime(pbtask.TaskState_LAUNCHED, "", 3), nil)
+	// stale change is ignored
+	s.aggregator.TaskRuntimeChanged(
+		s.jobID, 1, pbjob.JobType_BATCH,
+		taskRuntime(pbtask.TaskState_PENDING, "", 1), nil)
+	s.aggregator.JobRuntimeChanged(
+		s.jobID, pbjob.JobType_BATCH,
+		&pbjob.RuntimeInfo{
+			State:    pbjob.JobState_KILLING,
+			Revision: &peloton.ChangeLog{Version: 3},
+		})
+
+	rollups = s.aggregator.RespoolRollups(10)
+	s.Equal(map[string]uint32{"KILLING": 1}, rollups[0].JobStates)
+	s.Equal(map[string]uint32{"PENDING": 1, "LAUNCHED": 1}, rollups[0].PodStates)
+	s.Equal(map[string]uint32{"insufficient cpu": 1}, rollups[0].PendingReasons)
+	s.Equal(uint32(1), rollups[0].TopPendingJobs[0].PendingPods)
+
+	// pods get deleted
+	for _, instanceID := range []uint32{0, 1} {
+		s.aggregator.TaskRuntimeChanged(
+			s.jobID, instanceID, pbjob.JobType_BATCH,
+			taskRuntime(pbtask.TaskState_DELETED, "", 4), nil)
+	}
+	rollups = s.aggregator.OwnerRollups(10)
+	s.Empty(rollups[0].PodStates)
+	s.Empty(rollups[0].PendingReasons)
+	s.Empty(rollups[0].TopPendingJobs)
+
+	// changes of jobs not synced yet are not counted in the rollups
+	s.aggregator.JobRuntimeChanged(
+		&peloton.JobID{Value: "9f2a4c6e-8b1d-4a3f-b5e7-2c4d6f8a1b3e"},
+		pbjob.JobType_BATCH,
+		&pbjob.RuntimeInfo{State: pbjob.JobState_PENDING})
+	rollups = s.aggregator.OwnerRollups(10)
+	s.Equal(map[string]uint32{"KILLING": 1}, rollups[0].JobStates)
+}
+
+// TestSyncRemovesJobs tests that jobs which are no longer in the
+// cache are removed from the rollups
+func (s *AggregatorTestSuite) TestSyncRemovesJobs() {
+	s.expectSync(map[uint32]*pbtask.RuntimeInfo{
+		0: taskRuntime(pbtask.TaskState_RUNNING, "", 2),
+	})
+	s.expectGetRespool()
+	s.aggregator.Sync()
+	s.Len(s.aggregator.RespoolRollups(10), 1)
+
+	s.jobFactory.EXPECT().GetAllJobs().Return(map[string]cached.Job{})
+	s.aggregator.Sync()
+	s.Empty(s.aggregator.RespoolRollups(10))
+	s.Empty(s.aggregator.OwnerRollups(10))
+}
+
+// TestSyncFailures tests that jobs and resource pools which fail to
+// be read are skipped
+func (s *AggregatorTestSuite) TestSyncFailures() {
+	s.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{s.jobID.GetValue(): s.cachedJob})
+	s.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(nil, errors.New("test error"))
+	s.aggregator.Sync()
+	s.Empty(s.aggregator.RespoolRollups(10))
+
+	s.expectSync(nil)
+	s.respoolClient.EXPECT().
+		GetResourcePool(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("test error"))
+	s.aggregator.Sync()
+
+	rollups := s.aggregator.RespoolRollups(10)
+	s.Len(rollups, 1)
+	s.Empty(rollups[0].Path)
+	s.Empty(rollups[0].Resources)
+}
+
+// TestServiceHandler tests serving the rollups over YARPC
+func (s *AggregatorTestSuite) TestServiceHandler() {
+	s.expectSync(map[uint32]*pbtask.RuntimeInfo{
+		0: taskRuntime(pbtask.TaskState_PENDING, "", 2),
+	})
+	s.expectGetRespool()
+	s.aggregator.Sync()
+
+	candidate := leadermocks.NewMockCandidate(s.mockCtrl)
+	candidate.EXPECT().IsLeader().Return(true).Times(4)
+	handler := &serviceHandler{
+		aggregator: s.aggregator,
+		candidate:  candidate,
+	}
+
+	respoolResp, err := handler.GetRespoolRollups(
+		context.Background(),
+		&dashboardsvc.GetRespoolRollupsRequest{},
+	)
+	s.NoError(err)
+	s.Len(respoolResp.GetRollups(), 1)
+	s.Len(respoolResp.GetRollups()[0].GetTopPendingJobs(), 1)
+
+	respoolResp, err = handler.GetRespoolRollups(
+		context.Background(),
+		&dashboardsvc.GetRespoolRollupsRequest{RespoolId: "unknown"},
+	)
+	s.NoError(err)
+	s.Empty(respoolResp.GetRollups())
+
+	ownerResp, err := handler.GetOwnerRollups(
+		context.Background(),
+		&dashboardsvc.GetOwnerRollupsRequest{Owner: _testOwningTeam},
+	)
+	s.NoError(err)
+	s.Len(ownerResp.GetRollups(), 1)
+	s.Equal(_testOwningTeam, ownerResp.GetRollups()[0].GetKey())
+
+	ownerResp, err = handler.GetOwnerRollups(
+		context.Background(),
+		&dashboardsvc.GetOwnerRollupsRequest{Top: 1},
+	)
+	s.NoError(err)
+	s.Len(ownerResp.GetRollups(), 1)
+}
+
+// TestServiceHandlerNonLeader tests that the rollups are not
+// served on non-leaders
+func (s *AggregatorTestSuite) TestServiceHandlerNonLeader() {
+	candidate := leadermocks.NewMockCandidate(s.mockCtrl)
+	candidate.EXPECT().IsLeader().Return(false).Times(2)
+	handler := &serviceHandler{
+		aggregator: s.aggregator,
+		candidate:  candidate,
+	}
+
+	respoolResp, err := handler.GetRespoolRollups(
+		context.Background(),
+		&dashboardsvc.GetRespoolRollupsRequest{},
+	)
+	s.Nil(respoolResp)
+	s.True(yarpcerrors.IsUnavailable(err))
+
+	ownerResp, err := handler.GetOwnerRollups(
+		context.Background(),
+		&dashboardsvc.GetOwnerRollupsRequest{},
+	)
+	s.Nil(ownerResp)
+	s.True(yarpcerrors.IsUnavailable(err))
+}
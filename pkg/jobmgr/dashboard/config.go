@@ -0,0 +1,45 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import "time"
+
+const (
+	_defaultSyncPeriod     = time.Minute
+	_defaultTopPendingJobs = 10
+)
+
+// Config is the configuration of the dashboard aggregator.
+type Config struct {
+	// SyncPeriod is the period at which the aggregated views are
+	// rebuilt from the job cache and the resource pools are refreshed
+	// from resource manager. In between, the views are updated
+	// incrementally from the job and task runtime changes.
+	SyncPeriod time.Duration `yaml:"sync_period"`
+
+	// TopPendingJobs is the default number of jobs with the most
+	// pending pods returned in each aggregated view.
+	TopPendingJobs int `yaml:"top_pending_jobs"`
+}
+
+func (c *Config) normalize() {
+	if c.SyncPeriod == time.Duration(0) {
+		c.SyncPeriod = _defaultSyncPeriod
+	}
+
+	if c.TopPendingJobs == 0 {
+		c.TopPendingJobs = _defaultTopPendingJobs
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"context"
+
+	"github.com/uber/peloton/.gen/peloton/private/dashboardsvc"
+
+	"github.com/uber/peloton/pkg/common/leader"
+	yarpcutil "github.com/uber/peloton/pkg/common/util/yarpc"
+
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type serviceHandler struct {
+	aggregator *Aggregator
+	candidate  leader.Candidate
+}
+
+// InitServiceHandler initializes the dashboard service handler,
+// serving the rollups of the given aggregator.
+func InitServiceHandler(
+	d *yarpc.Dispatcher,
+	aggregator *Aggregator,
+	candidate leader.Candidate,
+) {
+	handler := &serviceHandler{
+		aggregator: aggregator,
+		candidate:  candidate,
+	}
+	d.Register(dashboardsvc.BuildDashboardServiceYARPCProcedures(handler))
+}
+
+// GetRespoolRollups returns the rollups of the resource pools.
+func (h *serviceHandler) GetRespoolRollups(
+	ctx context.Context,
+	req *dashboardsvc.GetRespoolRollupsRequest,
+) (resp *dashboardsvc.GetRespoolRollupsResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("DashboardSVC.GetRespoolRollups failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("num_of_results", len(resp.GetRollups())).
+			WithField("headers", headers).
+			Debug("DashboardSVC.GetRespoolRollups succeeded")
+	}()
+
+	h.aggregator.metrics.APIRespools.Inc(1)
+
+	// the rollups are only maintained by the leader
+	if !h.candidate.IsLeader() {
+		return nil, yarpcerrors.UnavailableErrorf(
+			"DashboardSVC.GetRespoolRollups is not supported on non-leader")
+	}
+
+	rollups := h.aggregator.RespoolRollups(h.top(req.GetTop()))
+	return &dashboardsvc.GetRespoolRollupsResponse{
+		Rollups: filterRollups(rollups, req.GetRespoolId()),
+	}, nil
+}
+
+// GetOwnerRollups returns the rollups of the owners.
+func (h *serviceHandler) GetOwnerRollups(
+	ctx context.Context,
+	req *dashboardsvc.GetOwnerRollupsRequest,
+) (resp *dashboardsvc.GetOwnerRollupsResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("DashboardSVC.GetOwnerRollups failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("num_of_results", len(resp.GetRollups())).
+			WithField("headers", headers).
+			Debug("DashboardSVC.GetOwnerRollups succeeded")
+	}()
+
+	h.aggregator.metrics.APIOwners.Inc(1)
+
+	// the rollups are only maintained by the leader
+	if !h.candidate.IsLeader() {
+		return nil, yarpcerrors.UnavailableErrorf(
+			"DashboardSVC.GetOwnerRollups is not supported on non-leader")
+	}
+
+	rollups := h.aggregator.OwnerRollups(h.top(req.GetTop()))
+	return &dashboardsvc.GetOwnerRollupsResponse{
+		Rollups: filterRollups(rollups, req.GetOwner()),
+	}, nil
+}
+
+// top returns the number of top pending jobs to list per rollup,
+// falling back to the configured default if unset.
+func (h *serviceHandler) top(top uint32) int {
+	if top == 0 {
+		return h.aggregator.config.TopPendingJobs
+	}
+	return int(top)
+}
+
+// filterRollups returns the rollup with the given key, or all the
+// rollups if the key is empty.
+func filterRollups(
+	rollups []*dashboardsvc.Rollup,
+	key string,
+) []*dashboardsvc.Rollup {
+	if key == "" {
+		return rollups
+	}
+
+	for _, rollup := range rollups {
+		if rollup.GetKey() == key {
+			return []*dashboardsvc.Rollup{rollup}
+		}
+	}
+	return nil
+}
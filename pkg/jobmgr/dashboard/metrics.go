@@ -0,0 +1,49 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import "github.com/uber-go/tally"
+
+// Metrics is the struct containing all the metrics that track the
+// dashboard aggregator.
+type Metrics struct {
+	SyncDuration tally.Timer
+	SyncFail     tally.Counter
+
+	NumJobs     tally.Gauge
+	NumRespools tally.Gauge
+	NumOwners   tally.Gauge
+
+	APIRespools tally.Counter
+	APIOwners   tally.Counter
+}
+
+// NewMetrics returns a new Metrics struct, with all metrics initialized
+// and rooted at the given tally.Scope
+func NewMetrics(scope tally.Scope) *Metrics {
+	dashboardScope := scope.SubScope("dashboard")
+	apiScope := dashboardScope.SubScope("api")
+	return &Metrics{
+		SyncDuration: dashboardScope.Timer("sync_duration"),
+		SyncFail:     dashboardScope.Counter("sync_fail"),
+
+		NumJobs:     dashboardScope.Gauge("num_jobs"),
+		NumRespools: dashboardScope.Gauge("num_respools"),
+		NumOwners:   dashboardScope.Gauge("num_owners"),
+
+		APIRespools: apiScope.Counter("respools"),
+		APIOwners:   apiScope.Counter("owners"),
+	}
+}
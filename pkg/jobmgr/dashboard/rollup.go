@@ -0,0 +1,170 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"sort"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	"github.com/uber/peloton/.gen/peloton/private/dashboardsvc"
+)
+
+// viewKey identifies a cached list of rollups.
+type viewKey struct {
+	isRespool bool
+	top       int
+}
+
+// view is a cached list of rollups, built at the given version
+// of the aggregator state.
+type view struct {
+	version uint64
+	rollups []*dashboardsvc.Rollup
+}
+
+// RespoolRollups returns the rollups of all the resource pools, sorted
+// by path, each listing up to top jobs with the most pending pods.
+func (a *Aggregator) RespoolRollups(top int) []*dashboardsvc.Rollup {
+	return a.getView(viewKey{isRespool: true, top: top})
+}
+
+// OwnerRollups returns the rollups of all the owners, sorted by owner,
+// each listing up to top jobs with the most pending pods.
+func (a *Aggregator) OwnerRollups(top int) []*dashboardsvc.Rollup {
+	return a.getView(viewKey{top: top})
+}
+
+// getView returns the cached rollups for the given key, rebuilding
+// them if the aggregator state has changed since they were built.
+func (a *Aggregator) getView(key viewKey) []*dashboardsvc.Rollup {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	a.viewLock.Lock()
+	defer a.viewLock.Unlock()
+
+	if v, ok := a.views[key]; ok && v.version == a.version {
+		return v.rollups
+	}
+
+	var rollups []*dashboardsvc.Rollup
+	if key.isRespool {
+		for id, g := range a.respools {
+			r := g.rollup(id, key.top)
+			addRespoolInfo(r, a.respoolInfos[id])
+			rollups = append(rollups, r)
+		}
+		sort.Slice(rollups, func(i, j int) bool {
+			if rollups[i].Path != rollups[j].Path {
+				return rollups[i].Path < rollups[j].Path
+			}
+			return rollups[i].Key < rollups[j].Key
+		})
+	} else {
+		for owner, g := range a.owners {
+			rollups = append(rollups, g.rollup(owner, key.top))
+		}
+		sort.Slice(rollups, func(i, j int) bool {
+			return rollups[i].Key < rollups[j].Key
+		})
+	}
+
+	a.views[key] = &view{version: a.version, rollups: rollups}
+	return rollups
+}
+
+// rollup builds the rollup of a group.
+func (g *group) rollup(key string, top int) *dashboardsvc.Rollup {
+	r := &dashboardsvc.Rollup{
+		Key:            key,
+		JobStates:      make(map[string]uint32),
+		PodStates:      make(map[string]uint32),
+		PendingReasons: make(map[string]uint32),
+	}
+	for state, count := range g.jobStates {
+		r.JobStates[state.String()] = uint32(count)
+	}
+	for state, count := range g.podStates {
+		r.PodStates[state.String()] = uint32(count)
+	}
+	for reason, count := range g.pendingReasons {
+		r.PendingReasons[reason] = uint32(count)
+	}
+
+	var pendingJobs []*dashboardsvc.PendingJob
+	for _, e := range g.jobs {
+		if e.pending == 0 {
+			continue
+		}
+		pendingJobs = append(pendingJobs, &dashboardsvc.PendingJob{
+			JobId:       e.id,
+			Name:        e.name,
+			Owner:       e.owner,
+			PendingPods: uint32(e.pending),
+		})
+	}
+	sort.Slice(pendingJobs, func(i, j int) bool {
+		if pendingJobs[i].PendingPods != pendingJobs[j].PendingPods {
+			return pendingJobs[i].PendingPods > pendingJobs[j].PendingPods
+		}
+		return pendingJobs[i].JobId < pendingJobs[j].JobId
+	})
+	if len(pendingJobs) > top {
+		pendingJobs = pendingJobs[:top]
+	}
+	r.TopPendingJobs = pendingJobs
+	return r
+}
+
+// addRespoolInfo adds the path and the resources of a resource pool
+// to its rollup, reporting the allocation of each resource kind against
+// the entitlement computed by resource manager.
+func addRespoolInfo(r *dashboardsvc.Rollup, info *respool.ResourcePoolInfo) {
+	if info == nil {
+		return
+	}
+	r.Path = info.GetPath().GetValue()
+
+	resources := make(map[string]*dashboardsvc.ResourceRollup)
+	getResource := func(kind string) *dashboardsvc.ResourceRollup {
+		resource, ok := resources[kind]
+		if !ok {
+			resource = &dashboardsvc.ResourceRollup{Kind: kind}
+			resources[kind] = resource
+		}
+		return resource
+	}
+	for _, usage := range info.GetUsage() {
+		resource := getResource(usage.GetKind())
+		resource.Allocation = usage.GetAllocation()
+		resource.Slack = usage.GetSlack()
+	}
+	for _, entitlement := range info.GetEntitlement() {
+		resource := getResource(entitlement.GetKind())
+		resource.Entitlement = entitlement.GetEntitlement()
+	}
+	for _, config := range info.GetConfig().GetResources() {
+		resource := getResource(config.GetKind())
+		resource.Reservation = config.GetReservation()
+		resource.Limit = config.GetLimit()
+	}
+
+	for _, resource := range resources {
+		r.Resources = append(r.Resources, resource)
+	}
+	sort.Slice(r.Resources, func(i, j int) bool {
+		return r.Resources[i].Kind < r.Resources[j].Kind
+	})
+}
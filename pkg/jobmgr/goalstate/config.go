@@ -28,6 +28,7 @@ const (
 	_defaultJobRuntimeUpdateInterval = 1 * time.Second
 	_defaultInitialTaskBackoff       = 30 * time.Second
 	_defaultMaxTaskBackoff           = 60 * time.Minute
+	_defaultHealthGateTimeout        = 30 * time.Second
 
 	// Job worker threads should be small because job create and job kill
 	// actions create 1000 parallel threads to update the DB, and if too
@@ -94,6 +95,11 @@ type Config struct {
 
 	// RateLimiterConfig defines rate limiter config
 	RateLimiterConfig RateLimiterConfig `yaml:"rate_limit"`
+
+	// HealthGateTimeout is the time to wait for a health gate of an
+	// update to be evaluated, if the gate does not set its own timeout.
+	// Default to 30s.
+	HealthGateTimeout time.Duration `yaml:"health_gate_timeout"`
 }
 
 type RateLimiterConfig struct {
@@ -155,6 +161,10 @@ func (c *Config) normalize() {
 		c.MaxTaskBackoff = _defaultMaxTaskBackoff
 	}
 
+	if c.HealthGateTimeout == 0 {
+		c.HealthGateTimeout = _defaultHealthGateTimeout
+	}
+
 	if c.RateLimiterConfig.TaskKill.Rate <= 0 || c.RateLimiterConfig.TaskKill.Burst <= 0 {
 		c.RateLimiterConfig.TaskKill.Rate = rate.Inf
 	}
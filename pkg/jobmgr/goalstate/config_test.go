@@ -34,4 +34,5 @@ func TestConfigNormalize(t *testing.T) {
 	assert.Equal(t, _defaultJobWorkerThreads, c.NumWorkerJobThreads)
 	assert.Equal(t, _defaultTaskWorkerThreads, c.NumWorkerTaskThreads)
 	assert.Equal(t, _defaultUpdateWorkerThreads, c.NumWorkerUpdateThreads)
+	assert.Equal(t, _defaultHealthGateTimeout, c.HealthGateTimeout)
 }
@@ -16,6 +16,7 @@ package goalstate
 
 import (
 	"context"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -31,6 +32,7 @@ import (
 	"github.com/uber/peloton/pkg/common/recovery"
 	"github.com/uber/peloton/pkg/jobmgr/cached"
 	"github.com/uber/peloton/pkg/jobmgr/task/lifecyclemgr"
+	"github.com/uber/peloton/pkg/jobmgr/workflow/healthgate"
 	"github.com/uber/peloton/pkg/storage"
 	ormobjects "github.com/uber/peloton/pkg/storage/objects"
 
@@ -156,11 +158,14 @@ func NewDriver(
 		jobIndexOps:     ormobjects.NewJobIndexOps(ormStore),
 		jobRuntimeOps:   ormobjects.NewJobRuntimeOps(ormStore),
 		taskConfigV2Ops: ormobjects.NewTaskConfigV2Ops(ormStore),
-		jobFactory:      jobFactory,
-		mtx:             NewMetrics(scope),
-		cfg:             &cfg,
-		jobType:         jobType,
-		jobScope:        jobScope,
+		jobUpdateEventsOps: ormobjects.NewJobUpdateEventsOps(
+			ormStore),
+		healthGateFactory: healthgate.NewFactory(&http.Client{}),
+		jobFactory:        jobFactory,
+		mtx:               NewMetrics(scope),
+		cfg:               &cfg,
+		jobType:           jobType,
+		jobScope:          jobScope,
 		taskKillRateLimiter: rate.NewLimiter(
 			cfg.RateLimiterConfig.TaskKill.Rate,
 			cfg.RateLimiterConfig.TaskKill.Burst),
@@ -220,6 +225,13 @@ type driver struct {
 	jobRuntimeOps   ormobjects.JobRuntimeOps   // DB ops for job_runtime table
 	jobIndexOps     ormobjects.JobIndexOps     // DB ops for job_index table
 	taskConfigV2Ops ormobjects.TaskConfigV2Ops // DB ops for task_config_v2_table
+	// DB ops for job_update_events table
+	jobUpdateEventsOps ormobjects.JobUpdateEventsOps
+
+	// healthGateFactory creates the health gates consulted by updates
+	healthGateFactory healthgate.Factory
+	// healthGateChecks tracks the health gates being consulted
+	healthGateChecks healthGateChecks
 
 	// jobFactory is the in-memory cache object fpr jobs and tasks
 	jobFactory cached.JobFactory
@@ -367,16 +379,21 @@ func (d *driver) recoverTasks(
 		return
 	}
 
+	// the tasks missing from the cache are added in a single batch
+	tasksToAdd := make(map[uint32]*task.TaskInfo)
 	for instanceID, taskInfo := range taskInfos {
-		d.mtx.taskMetrics.TaskRecovered.Inc(1)
-		runtime := taskInfo.GetRuntime()
 		// Do not add the task again if it already exists
 		if cachedJob.GetTask(instanceID) == nil {
-			cachedJob.ReplaceTasks(
-				map[uint32]*task.TaskInfo{instanceID: taskInfo},
-				false,
-			)
+			tasksToAdd[instanceID] = taskInfo
 		}
+	}
+	if len(tasksToAdd) > 0 {
+		cachedJob.ReplaceTasks(tasksToAdd, false)
+	}
+
+	for instanceID, taskInfo := range taskInfos {
+		d.mtx.taskMetrics.TaskRecovered.Inc(1)
+		runtime := taskInfo.GetRuntime()
 
 		// Do not evaluate goal state for tasks which will be evaluated using job create tasks action.
 		if runtime.GetState() != task.TaskState_INITIALIZED || jobRuntime.GetState() != job.JobState_INITIALIZED {
@@ -448,7 +448,11 @@ func (suite *DriverTestSuite) TestSyncFromDBWithMaxRunningInstancesSLA() {
 		GetTask(instanceID2).Return(nil)
 
 	suite.cachedJob.EXPECT().
-		ReplaceTasks(gomock.Any(), false).Return(nil).Times(2)
+		ReplaceTasks(gomock.Any(), false).
+		Do(func(taskInfos map[uint32]*task.TaskInfo, _ bool) {
+			suite.Len(taskInfos, 2)
+		}).
+		Return(nil)
 
 	suite.taskGoalStateEngine.EXPECT().
 		Enqueue(gomock.Any(), gomock.Any()).
@@ -606,7 +610,11 @@ func (suite *DriverTestSuite) TestSyncFromDBRecoverUpdate() {
 		GetTask(instanceID2).Return(nil)
 
 	suite.cachedJob.EXPECT().
-		ReplaceTasks(gomock.Any(), false).Return(nil).Times(2)
+		ReplaceTasks(gomock.Any(), false).
+		Do(func(taskInfos map[uint32]*task.TaskInfo, _ bool) {
+			suite.Len(taskInfos, 2)
+		}).
+		Return(nil)
 
 	suite.taskGoalStateEngine.EXPECT().
 		Enqueue(gomock.Any(), gomock.Any()).
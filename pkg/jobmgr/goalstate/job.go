@@ -47,6 +47,8 @@ const (
 	RuntimeUpdateAction JobAction = "runtime_update"
 	// EvaluateSLAAction evaluates job SLA
 	EvaluateSLAAction JobAction = "evaluate_sla"
+	// StartInstanceGroupsAction starts instance groups whose dependencies are up
+	StartInstanceGroupsAction JobAction = "start_instance_groups"
 	// RecoverAction attempts to recover a partially created job
 	RecoverAction JobAction = "recover"
 	// DeleteFromActiveJobsAction deletes a jobID from active jobs list if
@@ -227,6 +229,11 @@ func (j *jobEntity) GetActionList(
 			Name:    string(EvaluateSLAAction),
 			Execute: JobEvaluateMaxRunningInstancesSLA,
 		})
+
+		actions = append(actions, goalstate.Action{
+			Name:    string(StartInstanceGroupsAction),
+			Execute: JobStartInstanceGroups,
+		})
 	}
 
 	return context.Background(), nil, actions
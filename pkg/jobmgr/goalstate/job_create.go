@@ -266,7 +266,9 @@ func recoverTasks(
 					EnqueueJobWithDefaultDelay(
 						jobID, goalStateDriver, cachedJob)
 				} else {
-					tasks = append(tasks, taskInfo)
+					if !hasStartDependency(jobConfig.GetInstanceGroups(), i) {
+						tasks = append(tasks, taskInfo)
+					}
 					// add task to cache if not already present
 					if cachedJob.GetTask(i) == nil {
 						replaceTaskInfo := make(map[uint32]*task.TaskInfo)
@@ -286,7 +288,8 @@ func recoverTasks(
 		runtime := jobmgr_task.CreateInitializingTask(jobID, i, jobConfig)
 		taskRuntimeInfoMap[i] = runtime
 
-		if maxRunningInstances == 0 {
+		if maxRunningInstances == 0 &&
+			!hasStartDependency(jobConfig.GetInstanceGroups(), i) {
 			taskInfo := &task.TaskInfo{
 				JobId:      jobID,
 				InstanceId: i,
@@ -358,7 +361,19 @@ func createAndEnqueueTasks(
 		}
 		return sendTasksToResMgr(ctx, jobID, uTasks, jobConfig, goalStateDriver)
 	}
-	return sendTasksToResMgr(ctx, jobID, tasks, jobConfig, goalStateDriver)
+
+	// Tasks in instance groups with dependencies are started later by the
+	// job goal state once the instance groups they depend on are up
+	var readyTasks []*task.TaskInfo
+	for _, t := range tasks {
+		if !hasStartDependency(jobConfig.GetInstanceGroups(), t.GetInstanceId()) {
+			readyTasks = append(readyTasks, t)
+		}
+	}
+	if len(readyTasks) != len(tasks) {
+		EnqueueJobWithDefaultDelay(jobID, goalStateDriver, cachedJob)
+	}
+	return sendTasksToResMgr(ctx, jobID, readyTasks, jobConfig, goalStateDriver)
 }
 
 // transitTasksToPending moves tasks state to PENDING
@@ -171,6 +171,93 @@ func (suite *JobCreateTestSuite) TestJobCreateTasks() {
 	suite.NoError(err)
 }
 
+// TestJobCreateTasksWithInstanceGroups tests that only the instances
+// without start dependencies are enqueued to resource manager on job create
+func (suite *JobCreateTestSuite) TestJobCreateTasksWithInstanceGroups() {
+	emptyTaskInfo := make(map[uint32]*pbtask.TaskInfo)
+	suite.jobConfig.InstanceGroups = []*pbjob.InstanceGroup{
+		{
+			Name:   "ps",
+			Ranges: []*pbtask.InstanceRange{{From: 0, To: 1}},
+		},
+		{
+			Name:      "worker",
+			Ranges:    []*pbtask.InstanceRange{{From: 1, To: suite.instanceCount}},
+			DependsOn: []string{"ps"},
+		},
+	}
+
+	suite.taskStore.EXPECT().
+		GetTasksForJob(gomock.Any(), suite.jobID).
+		Return(emptyTaskInfo, nil)
+
+	suite.jobConfigOps.EXPECT().
+		GetResultCurrentVersion(gomock.Any(), suite.jobID).
+		Return(&ormobjects.JobConfigOpsResult{
+			JobConfig:   suite.jobConfig,
+			ConfigAddOn: &models.ConfigAddOn{},
+		}, nil)
+
+	suite.cachedJob.EXPECT().
+		CreateTaskConfigs(
+			gomock.Any(),
+			suite.jobID,
+			gomock.Any(),
+			gomock.Any(),
+			nil).
+		Return(nil)
+
+	suite.jobFactory.EXPECT().
+		AddJob(suite.jobID).
+		Return(suite.cachedJob)
+
+	suite.cachedJob.EXPECT().
+		CreateTaskRuntimes(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	suite.cachedJob.EXPECT().
+		GetJobType().
+		Return(pbjob.JobType_BATCH)
+
+	suite.jobGoalStateEngine.EXPECT().
+		Enqueue(gomock.Any(), gomock.Any()).
+		Return()
+
+	suite.cachedJob.EXPECT().
+		Update(
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			nil,
+			cached.UpdateCacheAndDB).
+		Return(nil)
+
+	suite.resmgrClient.EXPECT().
+		EnqueueGangs(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *resmgrsvc.EnqueueGangsRequest) {
+			suite.Len(req.GetGangs(), 1)
+		}).
+		Return(&resmgrsvc.EnqueueGangsResponse{}, nil)
+
+	suite.jobFactory.EXPECT().
+		GetJob(suite.jobID).
+		Return(suite.cachedJob).
+		Times(2)
+
+	suite.cachedJob.EXPECT().
+		PatchTasks(gomock.Any(), gomock.Any(), false).
+		Do(func(_ context.Context,
+			runtimeDiffs map[uint32]jobmgrcommon.RuntimeDiff,
+			_ bool) {
+			suite.Len(runtimeDiffs, 1)
+			suite.NotNil(runtimeDiffs[0])
+		}).
+		Return(nil, nil, nil)
+
+	err := JobCreateTasks(context.Background(), suite.jobEnt)
+	suite.NoError(err)
+}
+
 func (suite *JobCreateTestSuite) TestJobCreateGetConfigFailure() {
 	suite.jobConfigOps.EXPECT().
 		GetResultCurrentVersion(gomock.Any(), suite.jobID).
@@ -0,0 +1,318 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goalstate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common/goalstate"
+	"github.com/uber/peloton/pkg/common/taskconfig"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+	jobconfig "github.com/uber/peloton/pkg/jobmgr/job/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	_instanceGroupKillMessage    = "Killed as instance group %s failed"
+	_instanceGroupRestartMessage = "Restarted as instance group %s failed"
+)
+
+// hasStartDependency returns true if the instance belongs to an instance
+// group which depends on other instance groups to be up before it can start
+func hasStartDependency(
+	groups []*job.InstanceGroup,
+	instanceID uint32,
+) bool {
+	return len(jobconfig.GetInstanceGroup(groups, instanceID).GetDependsOn()) > 0
+}
+
+// isInstanceGroupUp returns true if all instances in the instance group
+// are either running and not unhealthy, or have already succeeded
+func isInstanceGroupUp(
+	ctx context.Context,
+	cachedJob cached.Job,
+	group *job.InstanceGroup,
+) (bool, error) {
+	for _, instanceID := range jobconfig.GetInstanceGroupInstances(group) {
+		cachedTask := cachedJob.GetTask(instanceID)
+		if cachedTask == nil {
+			return false, nil
+		}
+
+		runtime, err := cachedTask.GetRuntime(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		switch runtime.GetState() {
+		case task.TaskState_SUCCEEDED:
+			continue
+		case task.TaskState_RUNNING:
+			if runtime.GetHealthy() == task.HealthState_HEALTH_UNKNOWN ||
+				runtime.GetHealthy() == task.HealthState_UNHEALTHY {
+				return false, nil
+			}
+		default:
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// JobStartInstanceGroups enqueues the initialized instances of the
+// instance groups whose dependencies are all up to resource manager.
+func JobStartInstanceGroups(ctx context.Context, entity goalstate.Entity) error {
+	id := entity.GetID()
+	jobID := &peloton.JobID{Value: id}
+	goalStateDriver := entity.(*jobEntity).driver
+	cachedJob := goalStateDriver.jobFactory.AddJob(jobID)
+	cachedConfig, err := cachedJob.GetConfig(ctx)
+	if err != nil {
+		log.WithError(err).
+			WithField("job_id", id).
+			Error("Failed to get job config")
+		return err
+	}
+
+	groups := cachedConfig.GetInstanceGroups()
+	if len(groups) == 0 {
+		return nil
+	}
+
+	runtime, err := cachedJob.GetRuntime(ctx)
+	if err != nil {
+		log.WithError(err).
+			WithField("job_id", id).
+			Error("Failed to get job runtime during start instance groups")
+		return err
+	}
+
+	if runtime.GetGoalState() == job.JobState_KILLED ||
+		runtime.GetGoalState() == job.JobState_DELETED {
+		return nil
+	}
+
+	var instancesToStart []uint32
+	for _, group := range groups {
+		if len(group.GetDependsOn()) == 0 {
+			continue
+		}
+
+		ready := true
+		for _, dependency := range group.GetDependsOn() {
+			up, err := isInstanceGroupUp(
+				ctx,
+				cachedJob,
+				jobconfig.GetInstanceGroupByName(groups, dependency))
+			if err != nil {
+				return err
+			}
+			if !up {
+				ready = false
+				break
+			}
+		}
+		if !ready {
+			continue
+		}
+
+		for _, instanceID := range jobconfig.GetInstanceGroupInstances(group) {
+			cachedTask := cachedJob.GetTask(instanceID)
+			if cachedTask == nil {
+				continue
+			}
+
+			taskRuntime, err := cachedTask.GetRuntime(ctx)
+			if err != nil {
+				return err
+			}
+
+			if taskRuntime.GetState() != task.TaskState_INITIALIZED ||
+				taskRuntime.GetGoalState() == task.TaskState_KILLED ||
+				goalStateDriver.IsScheduledTask(jobID, instanceID) {
+				continue
+			}
+			instancesToStart = append(instancesToStart, instanceID)
+		}
+	}
+
+	if len(instancesToStart) == 0 {
+		return nil
+	}
+
+	jobConfig, _, err :=
+		goalStateDriver.jobConfigOps.GetCurrentVersion(ctx, jobID)
+	if err != nil {
+		log.WithError(err).
+			WithField("job_id", id).
+			Error("Failed to get job config in start instance groups")
+		return err
+	}
+
+	var tasks []*task.TaskInfo
+	for _, instanceID := range instancesToStart {
+		taskRuntime, err := cachedJob.GetTask(instanceID).GetRuntime(ctx)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, &task.TaskInfo{
+			JobId:      jobID,
+			InstanceId: instanceID,
+			Runtime:    taskRuntime,
+			Config: taskconfig.Merge(
+				jobConfig.GetDefaultConfig(),
+				jobConfig.GetInstanceConfig()[instanceID]),
+		})
+	}
+
+	log.WithField("job_id", id).
+		WithField("instances", instancesToStart).
+		Info("starting instance groups with dependencies up")
+
+	return sendTasksToResMgr(ctx, jobID, tasks, jobConfig, goalStateDriver)
+}
+
+// killInstanceGroup kills all instances of the instance group, and of the
+// instance groups depending on it, after one of its instances failed.
+func killInstanceGroup(
+	ctx context.Context,
+	cachedJob cached.Job,
+	groups []*job.InstanceGroup,
+	group *job.InstanceGroup,
+	goalStateDriver *driver,
+) error {
+	instances := jobconfig.GetInstanceGroupInstances(group)
+	for _, dependent := range jobconfig.GetDependentInstanceGroups(
+		groups, group.GetName()) {
+		instances = append(
+			instances, jobconfig.GetInstanceGroupInstances(dependent)...)
+	}
+
+	runtimeDiffs := make(map[uint32]jobmgrcommon.RuntimeDiff)
+	for _, instanceID := range instances {
+		cachedTask := cachedJob.GetTask(instanceID)
+		if cachedTask == nil {
+			continue
+		}
+
+		runtime, err := cachedTask.GetRuntime(ctx)
+		if err != nil {
+			return err
+		}
+		if runtime.GetGoalState() == task.TaskState_KILLED {
+			continue
+		}
+
+		runtimeDiffs[instanceID] = jobmgrcommon.RuntimeDiff{
+			jobmgrcommon.GoalStateField: task.TaskState_KILLED,
+			jobmgrcommon.MessageField: fmt.Sprintf(
+				_instanceGroupKillMessage, group.GetName()),
+			jobmgrcommon.ReasonField: "",
+			jobmgrcommon.TerminationStatusField: &task.TerminationStatus{
+				Reason: task.TerminationStatus_TERMINATION_STATUS_REASON_KILLED_ON_REQUEST,
+			},
+			jobmgrcommon.DesiredHostField: "",
+		}
+	}
+
+	if len(runtimeDiffs) == 0 {
+		return nil
+	}
+
+	if _, _, err := cachedJob.PatchTasks(ctx, runtimeDiffs, false); err != nil {
+		return err
+	}
+
+	for instanceID := range runtimeDiffs {
+		goalStateDriver.EnqueueTask(cachedJob.ID(), instanceID, time.Now())
+	}
+	EnqueueJobWithDefaultDelay(cachedJob.ID(), goalStateDriver, cachedJob)
+	return nil
+}
+
+// restartInstanceGroup restarts the other running instances of the
+// instance group after one of its instances failed.
+func restartInstanceGroup(
+	ctx context.Context,
+	cachedJob cached.Job,
+	group *job.InstanceGroup,
+	failedInstanceID uint32,
+	goalStateDriver *driver,
+) error {
+	runtimeDiffs := make(map[uint32]jobmgrcommon.RuntimeDiff)
+	for _, instanceID := range jobconfig.GetInstanceGroupInstances(group) {
+		if instanceID == failedInstanceID {
+			continue
+		}
+
+		cachedTask := cachedJob.GetTask(instanceID)
+		if cachedTask == nil {
+			continue
+		}
+
+		runtime, err := cachedTask.GetRuntime(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch runtime.GetState() {
+		case task.TaskState_LAUNCHED,
+			task.TaskState_STARTING,
+			task.TaskState_RUNNING:
+		default:
+			continue
+		}
+
+		// a restart is already in progress for the instance
+		if runtime.GetDesiredMesosTaskId().GetValue() !=
+			runtime.GetMesosTaskId().GetValue() {
+			continue
+		}
+
+		prevRunID, err := util.ParseRunID(runtime.GetMesosTaskId().GetValue())
+		if err != nil {
+			prevRunID = 0
+		}
+
+		runtimeDiffs[instanceID] = jobmgrcommon.RuntimeDiff{
+			jobmgrcommon.DesiredMesosTaskIDField: util.CreateMesosTaskID(
+				cachedJob.ID(), instanceID, prevRunID+1),
+			jobmgrcommon.MessageField: fmt.Sprintf(
+				_instanceGroupRestartMessage, group.GetName()),
+		}
+	}
+
+	if len(runtimeDiffs) == 0 {
+		return nil
+	}
+
+	if _, _, err := cachedJob.PatchTasks(ctx, runtimeDiffs, false); err != nil {
+		return err
+	}
+
+	for instanceID := range runtimeDiffs {
+		goalStateDriver.EnqueueTask(cachedJob.ID(), instanceID, time.Now())
+	}
+	return nil
+}
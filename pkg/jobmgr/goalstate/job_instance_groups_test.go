@@ -0,0 +1,391 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goalstate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	mesosv1 "github.com/uber/peloton/.gen/mesos/v1"
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+
+	resmocks "github.com/uber/peloton/.gen/peloton/private/resmgrsvc/mocks"
+	goalstatemocks "github.com/uber/peloton/pkg/common/goalstate/mocks"
+	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
+	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type JobInstanceGroupsTestSuite struct {
+	suite.Suite
+	ctrl *gomock.Controller
+
+	jobFactory          *cachedmocks.MockJobFactory
+	jobGoalStateEngine  *goalstatemocks.MockEngine
+	taskGoalStateEngine *goalstatemocks.MockEngine
+	resmgrClient        *resmocks.MockResourceManagerServiceYARPCClient
+	jobConfigOps        *objectmocks.MockJobConfigOps
+	taskConfigV2Ops     *objectmocks.MockTaskConfigV2Ops
+	goalStateDriver     *driver
+
+	jobID        *peloton.JobID
+	jobEnt       *jobEntity
+	cachedJob    *cachedmocks.MockJob
+	cachedConfig *cachedmocks.MockJobConfigCache
+	cachedTasks  map[uint32]*cachedmocks.MockTask
+
+	instanceCount uint32
+	groups        []*pbjob.InstanceGroup
+}
+
+func TestJobInstanceGroups(t *testing.T) {
+	suite.Run(t, new(JobInstanceGroupsTestSuite))
+}
+
+func (suite *JobInstanceGroupsTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.jobFactory = cachedmocks.NewMockJobFactory(suite.ctrl)
+	suite.jobGoalStateEngine = goalstatemocks.NewMockEngine(suite.ctrl)
+	suite.taskGoalStateEngine = goalstatemocks.NewMockEngine(suite.ctrl)
+	suite.resmgrClient = resmocks.NewMockResourceManagerServiceYARPCClient(suite.ctrl)
+	suite.jobConfigOps = objectmocks.NewMockJobConfigOps(suite.ctrl)
+	suite.taskConfigV2Ops = objectmocks.NewMockTaskConfigV2Ops(suite.ctrl)
+	suite.goalStateDriver = &driver{
+		jobFactory:      suite.jobFactory,
+		jobEngine:       suite.jobGoalStateEngine,
+		taskEngine:      suite.taskGoalStateEngine,
+		resmgrClient:    suite.resmgrClient,
+		jobConfigOps:    suite.jobConfigOps,
+		taskConfigV2Ops: suite.taskConfigV2Ops,
+		mtx:             NewMetrics(tally.NoopScope),
+		cfg:             &Config{},
+	}
+	suite.goalStateDriver.cfg.normalize()
+
+	suite.jobID = &peloton.JobID{Value: uuid.NewRandom().String()}
+	suite.jobEnt = &jobEntity{
+		id:     suite.jobID,
+		driver: suite.goalStateDriver,
+	}
+	suite.cachedJob = cachedmocks.NewMockJob(suite.ctrl)
+	suite.cachedConfig = cachedmocks.NewMockJobConfigCache(suite.ctrl)
+
+	// instances 0 and 1 are parameter servers, and instances 2 and 3
+	// are workers which can start only after parameter servers are up
+	suite.instanceCount = 4
+	suite.groups = []*pbjob.InstanceGroup{
+		{
+			Name:   "ps",
+			Ranges: []*pbtask.InstanceRange{{From: 0, To: 2}},
+		},
+		{
+			Name:      "worker",
+			Ranges:    []*pbtask.InstanceRange{{From: 2, To: 4}},
+			DependsOn: []string{"ps"},
+		},
+	}
+
+	suite.cachedTasks = make(map[uint32]*cachedmocks.MockTask)
+	for i := uint32(0); i < suite.instanceCount; i++ {
+		cachedTask := cachedmocks.NewMockTask(suite.ctrl)
+		suite.cachedTasks[i] = cachedTask
+		suite.cachedJob.EXPECT().
+			GetTask(i).
+			Return(cachedTask).
+			AnyTimes()
+	}
+
+	suite.cachedJob.EXPECT().
+		ID().
+		Return(suite.jobID).
+		AnyTimes()
+	suite.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(suite.cachedConfig, nil).
+		AnyTimes()
+}
+
+func (suite *JobInstanceGroupsTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+// setTaskRuntimes sets the runtimes returned for each instance in the job
+func (suite *JobInstanceGroupsTestSuite) setTaskRuntimes(
+	runtimes map[uint32]*pbtask.RuntimeInfo) {
+	for i, runtime := range runtimes {
+		suite.cachedTasks[i].EXPECT().
+			GetRuntime(gomock.Any()).
+			Return(runtime, nil).
+			AnyTimes()
+	}
+}
+
+func (suite *JobInstanceGroupsTestSuite) mesosTaskID(
+	instanceID uint32,
+	runID int,
+) *mesosv1.TaskID {
+	id := fmt.Sprintf("%s-%d-%d", suite.jobID.GetValue(), instanceID, runID)
+	return &mesosv1.TaskID{Value: &id}
+}
+
+// TestJobStartInstanceGroupsNoGroups tests that nothing is done
+// for a job without instance groups
+func (suite *JobInstanceGroupsTestSuite) TestJobStartInstanceGroupsNoGroups() {
+	suite.jobFactory.EXPECT().
+		AddJob(suite.jobID).
+		Return(suite.cachedJob)
+	suite.cachedConfig.EXPECT().
+		GetInstanceGroups().
+		Return(nil)
+
+	suite.NoError(JobStartInstanceGroups(context.Background(), suite.jobEnt))
+}
+
+// TestJobStartInstanceGroupsKilledJob tests that no instance group
+// is started for a job being killed
+func (suite *JobInstanceGroupsTestSuite) TestJobStartInstanceGroupsKilledJob() {
+	suite.jobFactory.EXPECT().
+		AddJob(suite.jobID).
+		Return(suite.cachedJob)
+	suite.cachedConfig.EXPECT().
+		GetInstanceGroups().
+		Return(suite.groups)
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:     pbjob.JobState_RUNNING,
+			GoalState: pbjob.JobState_KILLED,
+		}, nil)
+
+	suite.NoError(JobStartInstanceGroups(context.Background(), suite.jobEnt))
+}
+
+// TestJobStartInstanceGroupsDependencyNotUp tests that instances are
+// not started while the instance groups they depend on are not up
+func (suite *JobInstanceGroupsTestSuite) TestJobStartInstanceGroupsDependencyNotUp() {
+	suite.jobFactory.EXPECT().
+		AddJob(suite.jobID).
+		Return(suite.cachedJob)
+	suite.cachedConfig.EXPECT().
+		GetInstanceGroups().
+		Return(suite.groups)
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:     pbjob.JobState_RUNNING,
+			GoalState: pbjob.JobState_SUCCEEDED,
+		}, nil)
+	suite.setTaskRuntimes(map[uint32]*pbtask.RuntimeInfo{
+		0: {State: pbtask.TaskState_RUNNING, Healthy: pbtask.HealthState_HEALTHY},
+		1: {State: pbtask.TaskState_RUNNING, Healthy: pbtask.HealthState_HEALTH_UNKNOWN},
+		2: {State: pbtask.TaskState_INITIALIZED},
+		3: {State: pbtask.TaskState_INITIALIZED},
+	})
+
+	suite.NoError(JobStartInstanceGroups(context.Background(), suite.jobEnt))
+}
+
+// TestJobStartInstanceGroups tests that instances are sent to resource
+// manager once the instance groups they depend on are up
+func (suite *JobInstanceGroupsTestSuite) TestJobStartInstanceGroups() {
+	jobConfig := &pbjob.JobConfig{
+		InstanceCount:  suite.instanceCount,
+		Type:           pbjob.JobType_BATCH,
+		InstanceGroups: suite.groups,
+	}
+
+	suite.jobFactory.EXPECT().
+		AddJob(suite.jobID).
+		Return(suite.cachedJob)
+	suite.cachedConfig.EXPECT().
+		GetInstanceGroups().
+		Return(suite.groups)
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:     pbjob.JobState_RUNNING,
+			GoalState: pbjob.JobState_SUCCEEDED,
+		}, nil)
+	suite.setTaskRuntimes(map[uint32]*pbtask.RuntimeInfo{
+		0: {State: pbtask.TaskState_RUNNING, Healthy: pbtask.HealthState_DISABLED},
+		1: {State: pbtask.TaskState_SUCCEEDED},
+		2: {State: pbtask.TaskState_INITIALIZED, GoalState: pbtask.TaskState_SUCCEEDED},
+		3: {State: pbtask.TaskState_INITIALIZED, GoalState: pbtask.TaskState_SUCCEEDED},
+	})
+	suite.taskGoalStateEngine.EXPECT().
+		IsScheduled(gomock.Any()).
+		Return(false).
+		Times(2)
+	suite.jobConfigOps.EXPECT().
+		GetCurrentVersion(gomock.Any(), suite.jobID).
+		Return(jobConfig, &models.ConfigAddOn{}, nil)
+	suite.resmgrClient.EXPECT().
+		EnqueueGangs(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *resmgrsvc.EnqueueGangsRequest) {
+			suite.Len(req.GetGangs(), 2)
+		}).
+		Return(&resmgrsvc.EnqueueGangsResponse{}, nil)
+	suite.jobFactory.EXPECT().
+		GetJob(suite.jobID).
+		Return(suite.cachedJob)
+	suite.cachedJob.EXPECT().
+		PatchTasks(gomock.Any(), gomock.Any(), false).
+		Do(func(_ context.Context,
+			runtimeDiffs map[uint32]jobmgrcommon.RuntimeDiff,
+			_ bool) {
+			suite.Len(runtimeDiffs, 2)
+			suite.Equal(pbtask.TaskState_PENDING,
+				runtimeDiffs[2][jobmgrcommon.StateField])
+			suite.Equal(pbtask.TaskState_PENDING,
+				runtimeDiffs[3][jobmgrcommon.StateField])
+		}).
+		Return(nil, nil, nil)
+
+	suite.NoError(JobStartInstanceGroups(context.Background(), suite.jobEnt))
+}
+
+// TestTaskFailRetryGangKill tests that failure of an instance in an
+// instance group with kill policy kills the instance group along
+// with the instance groups depending on it
+func (suite *JobInstanceGroupsTestSuite) TestTaskFailRetryGangKill() {
+	suite.groups[0].FailurePolicy = pbjob.GangFailurePolicy_GANG_FAILURE_POLICY_KILL
+	taskEnt := &taskEntity{
+		jobID:      suite.jobID,
+		instanceID: 0,
+		driver:     suite.goalStateDriver,
+	}
+
+	suite.jobFactory.EXPECT().
+		GetJob(suite.jobID).
+		Return(suite.cachedJob)
+	suite.setTaskRuntimes(map[uint32]*pbtask.RuntimeInfo{
+		0: {State: pbtask.TaskState_FAILED, GoalState: pbtask.TaskState_SUCCEEDED},
+		1: {State: pbtask.TaskState_RUNNING, GoalState: pbtask.TaskState_SUCCEEDED},
+		2: {State: pbtask.TaskState_RUNNING, GoalState: pbtask.TaskState_SUCCEEDED},
+		3: {State: pbtask.TaskState_KILLED, GoalState: pbtask.TaskState_KILLED},
+	})
+	suite.taskConfigV2Ops.EXPECT().
+		GetTaskConfig(gomock.Any(), suite.jobID, uint32(0), gomock.Any()).
+		Return(&pbtask.TaskConfig{
+			RestartPolicy: &pbtask.RestartPolicy{MaxFailures: 3},
+		}, &models.ConfigAddOn{}, nil)
+	suite.cachedConfig.EXPECT().
+		GetInstanceGroups().
+		Return(suite.groups)
+	suite.cachedJob.EXPECT().
+		PatchTasks(gomock.Any(), gomock.Any(), false).
+		Do(func(_ context.Context,
+			runtimeDiffs map[uint32]jobmgrcommon.RuntimeDiff,
+			_ bool) {
+			suite.Len(runtimeDiffs, 3)
+			for _, i := range []uint32{0, 1, 2} {
+				suite.Equal(pbtask.TaskState_KILLED,
+					runtimeDiffs[i][jobmgrcommon.GoalStateField])
+			}
+		}).
+		Return(nil, nil, nil)
+	suite.taskGoalStateEngine.EXPECT().
+		Enqueue(gomock.Any(), gomock.Any()).
+		Times(3)
+	suite.cachedJob.EXPECT().
+		GetJobType().
+		Return(pbjob.JobType_BATCH)
+	suite.jobGoalStateEngine.EXPECT().
+		Enqueue(gomock.Any(), gomock.Any())
+
+	suite.NoError(TaskFailRetry(context.Background(), taskEnt))
+}
+
+// TestTaskFailRetryGangRestart tests that failure of an instance in an
+// instance group with restart policy restarts the other running instances
+// of the instance group, and retries the failed instance
+func (suite *JobInstanceGroupsTestSuite) TestTaskFailRetryGangRestart() {
+	suite.groups[1].FailurePolicy = pbjob.GangFailurePolicy_GANG_FAILURE_POLICY_RESTART
+	taskEnt := &taskEntity{
+		jobID:      suite.jobID,
+		instanceID: 2,
+		driver:     suite.goalStateDriver,
+	}
+
+	suite.jobFactory.EXPECT().
+		GetJob(suite.jobID).
+		Return(suite.cachedJob)
+	suite.setTaskRuntimes(map[uint32]*pbtask.RuntimeInfo{
+		2: {
+			State:              pbtask.TaskState_FAILED,
+			GoalState:          pbtask.TaskState_SUCCEEDED,
+			MesosTaskId:        suite.mesosTaskID(2, 1),
+			DesiredMesosTaskId: suite.mesosTaskID(2, 1),
+		},
+		3: {
+			State:              pbtask.TaskState_RUNNING,
+			GoalState:          pbtask.TaskState_SUCCEEDED,
+			MesosTaskId:        suite.mesosTaskID(3, 1),
+			DesiredMesosTaskId: suite.mesosTaskID(3, 1),
+		},
+	})
+	suite.taskConfigV2Ops.EXPECT().
+		GetTaskConfig(gomock.Any(), suite.jobID, uint32(2), gomock.Any()).
+		Return(&pbtask.TaskConfig{
+			RestartPolicy: &pbtask.RestartPolicy{MaxFailures: 3},
+		}, &models.ConfigAddOn{}, nil)
+	suite.cachedConfig.EXPECT().
+		GetInstanceGroups().
+		Return(suite.groups)
+
+	gomock.InOrder(
+		suite.cachedJob.EXPECT().
+			PatchTasks(gomock.Any(), gomock.Any(), false).
+			Do(func(_ context.Context,
+				runtimeDiffs map[uint32]jobmgrcommon.RuntimeDiff,
+				_ bool) {
+				suite.Len(runtimeDiffs, 1)
+				suite.Equal(
+					suite.mesosTaskID(3, 2).GetValue(),
+					runtimeDiffs[3][jobmgrcommon.DesiredMesosTaskIDField].(*mesosv1.TaskID).GetValue())
+			}).
+			Return(nil, nil, nil),
+		suite.cachedJob.EXPECT().
+			PatchTasks(gomock.Any(), gomock.Any(), false).
+			Do(func(_ context.Context,
+				runtimeDiffs map[uint32]jobmgrcommon.RuntimeDiff,
+				_ bool) {
+				suite.Len(runtimeDiffs, 1)
+				suite.Equal(pbtask.TaskState_INITIALIZED,
+					runtimeDiffs[2][jobmgrcommon.StateField])
+			}).
+			Return(nil, nil, nil),
+	)
+	suite.taskGoalStateEngine.EXPECT().
+		Enqueue(gomock.Any(), gomock.Any()).
+		Times(2)
+	suite.cachedJob.EXPECT().
+		GetJobType().
+		Return(pbjob.JobType_BATCH)
+	suite.jobGoalStateEngine.EXPECT().
+		Enqueue(gomock.Any(), gomock.Any())
+
+	suite.NoError(TaskFailRetry(context.Background(), taskEnt))
+}
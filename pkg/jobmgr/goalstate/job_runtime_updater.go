@@ -293,6 +293,33 @@ func (d *jobStateDeterminer) getState(
 		return job.JobState_SUCCEEDED, nil
 	}
 
+	if policy := d.config.GetCompletionPolicy(); policy != nil {
+		failedCount := d.stateCounts[task.TaskState_FAILED.String()] +
+			d.stateCounts[task.TaskState_LOST.String()]
+
+		// too many failures, and the remaining instances have been
+		// killed -> failed
+		if exceedsMaxFailures(policy, failedCount) &&
+			d.stateCounts[task.TaskState_SUCCEEDED.String()]+failedCount+
+				d.stateCounts[task.TaskState_KILLED.String()] >= totalInstanceCount {
+			return job.JobState_FAILED, nil
+		}
+
+		// some succeeded, some failed, some lost -> succeeded
+		// if the success threshold is met, otherwise failed
+		if d.stateCounts[task.TaskState_SUCCEEDED.String()]+
+			failedCount >= totalInstanceCount {
+			successThreshold := policy.GetSuccessThreshold()
+			if successThreshold == 0 {
+				successThreshold = totalInstanceCount
+			}
+			if d.stateCounts[task.TaskState_SUCCEEDED.String()] >= successThreshold {
+				return job.JobState_SUCCEEDED, nil
+			}
+			return job.JobState_FAILED, nil
+		}
+	}
+
 	// some succeeded, some failed, some lost -> failed
 	if d.stateCounts[task.TaskState_SUCCEEDED.String()]+
 		d.stateCounts[task.TaskState_FAILED.String()]+
@@ -591,6 +618,21 @@ func JobRuntimeUpdater(ctx context.Context, entity goalstate.Entity) error {
 
 	jobRuntimeUpdate.TaskStatsByConfigurationVersion = configVersionStateStats
 
+	// kill the remaining instances of a batch job once more instances
+	// have failed than its completion policy tolerates
+	if exceedsMaxFailures(
+		config.GetCompletionPolicy(),
+		stateCounts[task.TaskState_FAILED.String()]+
+			stateCounts[task.TaskState_LOST.String()]) &&
+		!util.IsPelotonJobStateTerminal(jobState) &&
+		jobRuntime.GetGoalState() != job.JobState_KILLED {
+		log.WithField("job_id", id).
+			WithField("task_stats", stateCounts).
+			Info("batch job exceeded max failures, killing the job")
+		jobRuntimeUpdate.GoalState = job.JobState_KILLED
+		jobRuntimeUpdate.DesiredStateVersion = jobRuntime.GetDesiredStateVersion() + 1
+	}
+
 	// add to active jobs list BEFORE writing state to job runtime table.
 	// Also write to active jobs list only when the job is being transitioned
 	// from a terminal to active state. For active to active transitions, we
@@ -622,10 +664,12 @@ func JobRuntimeUpdater(ctx context.Context, entity goalstate.Entity) error {
 	// 1. job state is terminal and no more task updates will arrive, or
 	// 2. job is partially created and need to create additional tasks
 	// (we may have no additional tasks coming in when job is
-	// partially created)
+	// partially created), or
+	// 3. job needs to be killed because of too many failures
 	if util.IsPelotonJobStateTerminal(jobRuntimeUpdate.GetState()) ||
 		(cachedJob.IsPartiallyCreated(config) &&
-			!updateutil.HasUpdate(jobRuntime)) {
+			!updateutil.HasUpdate(jobRuntime)) ||
+		jobRuntimeUpdate.GetGoalState() == job.JobState_KILLED {
 		goalStateDriver.EnqueueJob(jobID, time.Now())
 	}
 
@@ -637,6 +681,14 @@ func JobRuntimeUpdater(ctx context.Context, entity goalstate.Entity) error {
 	return nil
 }
 
+// exceedsMaxFailures returns true if the number of failed instances
+// is more than the completion policy of a batch job tolerates.
+func exceedsMaxFailures(
+	policy *job.CompletionPolicy,
+	failedCount uint32) bool {
+	return policy.GetMaxFailures() > 0 && failedCount > policy.GetMaxFailures()
+}
+
 func getTotalInstanceCount(stateCounts map[string]uint32) uint32 {
 	totalInstanceCount := uint32(0)
 	for _, state := range task.TaskState_name {
@@ -116,6 +116,8 @@ func (suite *JobRuntimeUpdaterTestSuite) SetupTest() {
 	suite.cachedJob.EXPECT().
 		GetResourceUsage().Return(
 		jobmgrtask.CreateEmptyResourceUsageMap()).AnyTimes()
+	suite.cachedConfig.EXPECT().
+		GetCompletionPolicy().Return(nil).AnyTimes()
 }
 
 func (suite *JobRuntimeUpdaterTestSuite) TearDownTest() {
@@ -2365,6 +2367,7 @@ func (suite *JobRuntimeUpdaterTestSuite) TestDetermineBatchJobRuntimeState() {
 			State: test.currentState,
 		}
 		cachedConfig := cachedmocks.NewMockJobConfigCache(ctrl)
+		cachedConfig.EXPECT().GetCompletionPolicy().Return(nil).AnyTimes()
 		cachedJob := cachedmocks.NewMockJob(ctrl)
 
 		cachedConfig.EXPECT().GetType().Return(pbjob.JobType_BATCH).AnyTimes()
@@ -2395,6 +2398,209 @@ func (suite *JobRuntimeUpdaterTestSuite) TestDetermineBatchJobRuntimeState() {
 	}
 }
 
+// TestDetermineBatchJobRuntimeStateWithCompletionPolicy tests determining
+// JobRuntimeState for batch jobs with a completion policy
+func (suite *JobRuntimeUpdaterTestSuite) TestDetermineBatchJobRuntimeStateWithCompletionPolicy() {
+	var instanceCount uint32 = 10
+	tests := []struct {
+		stateCounts   map[string]uint32
+		policy        *pbjob.CompletionPolicy
+		currentState  pbjob.JobState
+		expectedState pbjob.JobState
+		message       string
+	}{
+		{
+			map[string]uint32{
+				pbtask.TaskState_FAILED.String():    2,
+				pbtask.TaskState_SUCCEEDED.String(): 8,
+			},
+			&pbjob.CompletionPolicy{SuccessThreshold: 8},
+			pbjob.JobState_RUNNING,
+			pbjob.JobState_SUCCEEDED,
+			"Batch job meeting the success threshold should be SUCCEEDED",
+		},
+		{
+			map[string]uint32{
+				pbtask.TaskState_LOST.String():      3,
+				pbtask.TaskState_SUCCEEDED.String(): 7,
+			},
+			&pbjob.CompletionPolicy{SuccessThreshold: 8},
+			pbjob.JobState_RUNNING,
+			pbjob.JobState_FAILED,
+			"Batch job missing the success threshold should be FAILED",
+		},
+		{
+			map[string]uint32{
+				pbtask.TaskState_FAILED.String():    1,
+				pbtask.TaskState_SUCCEEDED.String(): 9,
+			},
+			&pbjob.CompletionPolicy{MaxFailures: 1},
+			pbjob.JobState_RUNNING,
+			pbjob.JobState_FAILED,
+			"Batch job without success threshold requires all instances to succeed",
+		},
+		{
+			map[string]uint32{
+				pbtask.TaskState_FAILED.String():    3,
+				pbtask.TaskState_SUCCEEDED.String(): 2,
+				pbtask.TaskState_KILLED.String():    5,
+			},
+			&pbjob.CompletionPolicy{MaxFailures: 2},
+			pbjob.JobState_KILLING,
+			pbjob.JobState_FAILED,
+			"Batch job killed after exceeding max failures should be FAILED",
+		},
+		{
+			map[string]uint32{
+				pbtask.TaskState_FAILED.String():  3,
+				pbtask.TaskState_RUNNING.String(): 7,
+			},
+			&pbjob.CompletionPolicy{MaxFailures: 2},
+			pbjob.JobState_RUNNING,
+			pbjob.JobState_RUNNING,
+			"Batch job exceeding max failures with running tasks should be RUNNING",
+		},
+		{
+			map[string]uint32{
+				pbtask.TaskState_FAILED.String(): 2,
+				pbtask.TaskState_KILLED.String(): 8,
+			},
+			&pbjob.CompletionPolicy{MaxFailures: 2},
+			pbjob.JobState_KILLING,
+			pbjob.JobState_KILLED,
+			"Batch job killed within max failures should be KILLED",
+		},
+	}
+
+	for index, test := range tests {
+		ctrl := gomock.NewController(suite.T())
+		jobRuntime := &pbjob.RuntimeInfo{
+			State: test.currentState,
+		}
+		cachedConfig := cachedmocks.NewMockJobConfigCache(ctrl)
+		cachedJob := cachedmocks.NewMockJob(ctrl)
+
+		cachedConfig.EXPECT().GetCompletionPolicy().Return(test.policy).AnyTimes()
+		cachedConfig.EXPECT().GetType().Return(pbjob.JobType_BATCH).AnyTimes()
+		cachedJob.EXPECT().GetJobType().Return(pbjob.JobType_BATCH).AnyTimes()
+		cachedConfig.EXPECT().GetInstanceCount().
+			Return(instanceCount).AnyTimes()
+		cachedConfig.EXPECT().HasControllerTask().Return(false).AnyTimes()
+		cachedJob.EXPECT().IsPartiallyCreated(gomock.Any()).
+			Return(false).AnyTimes()
+
+		jobState, _, _ := determineJobRuntimeStateAndCounts(
+			context.Background(),
+			jobRuntime,
+			test.stateCounts,
+			cachedConfig,
+			suite.goalStateDriver,
+			cachedJob,
+		)
+
+		suite.Equal(test.expectedState, jobState, "Test %d: %s", index, test.message)
+
+		ctrl.Finish()
+	}
+}
+
+// TestJobRuntimeUpdater_Batch_ExceedMaxFailures tests that a batch job
+// is killed once more instances have failed than its completion
+// policy tolerates
+func (suite *JobRuntimeUpdaterTestSuite) TestJobRuntimeUpdater_Batch_ExceedMaxFailures() {
+	instanceCount := uint32(10)
+	jobRuntime := pbjob.RuntimeInfo{
+		State:               pbjob.JobState_RUNNING,
+		GoalState:           pbjob.JobState_SUCCEEDED,
+		DesiredStateVersion: 2,
+	}
+	cachedConfig := cachedmocks.NewMockJobConfigCache(suite.ctrl)
+
+	cachedTasks := make(map[uint32]cached.Task)
+	for i := uint32(0); i < instanceCount; i++ {
+		cachedTasks[i] = suite.cachedTask
+	}
+	suite.cachedJob.EXPECT().
+		GetAllTasks().
+		Return(cachedTasks).AnyTimes()
+
+	for i := uint32(0); i < 3; i++ {
+		suite.cachedTask.EXPECT().CurrentState().Return(cached.TaskStateVector{
+			State: pbtask.TaskState_FAILED,
+		})
+	}
+	for i := uint32(3); i < instanceCount; i++ {
+		suite.cachedTask.EXPECT().CurrentState().Return(cached.TaskStateVector{
+			State: pbtask.TaskState_RUNNING,
+		})
+	}
+
+	cachedConfig.EXPECT().
+		GetCompletionPolicy().
+		Return(&pbjob.CompletionPolicy{MaxFailures: 2}).
+		AnyTimes()
+	cachedConfig.EXPECT().
+		GetInstanceCount().
+		Return(instanceCount).
+		AnyTimes()
+	cachedConfig.EXPECT().
+		HasControllerTask().
+		Return(false)
+	cachedConfig.EXPECT().
+		GetType().
+		Return(pbjob.JobType_BATCH).
+		AnyTimes()
+
+	suite.jobFactory.EXPECT().
+		AddJob(suite.jobID).
+		Return(suite.cachedJob)
+
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&jobRuntime, nil)
+
+	suite.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(cachedConfig, nil)
+
+	suite.cachedJob.EXPECT().
+		RepopulateInstanceAvailabilityInfo(gomock.Any()).
+		Return(nil)
+
+	suite.cachedJob.EXPECT().
+		GetFirstTaskUpdateTime().
+		Return(suite.lastUpdateTs)
+
+	suite.cachedJob.EXPECT().
+		IsPartiallyCreated(gomock.Any()).
+		Return(false).
+		AnyTimes()
+
+	suite.cachedJob.EXPECT().
+		Update(
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			nil,
+			cached.UpdateCacheAndDB).
+		Do(func(_ context.Context,
+			jobInfo *pbjob.JobInfo,
+			_ *models.ConfigAddOn,
+			_ *stateless.JobSpec,
+			_ cached.UpdateRequest) {
+			suite.Equal(pbjob.JobState_RUNNING, jobInfo.Runtime.State)
+			suite.Equal(pbjob.JobState_KILLED, jobInfo.Runtime.GoalState)
+			suite.Equal(uint64(3), jobInfo.Runtime.DesiredStateVersion)
+		}).Return(nil)
+
+	suite.jobGoalStateEngine.EXPECT().
+		Enqueue(gomock.Any(), gomock.Any()).
+		Return()
+
+	err := JobRuntimeUpdater(context.Background(), suite.jobEnt)
+	suite.NoError(err)
+}
+
 // TestDetermineServiceJobRuntimeState tests determining JobRuntimeState for service jobs
 func (suite *JobRuntimeUpdaterTestSuite) TestDetermineServiceJobRuntimeState() {
 	var instanceCount uint32 = 100
@@ -2493,6 +2699,7 @@ func (suite *JobRuntimeUpdaterTestSuite) TestDetermineServiceJobRuntimeState() {
 			State: test.currentState,
 		}
 		cachedConfig := cachedmocks.NewMockJobConfigCache(ctrl)
+		cachedConfig.EXPECT().GetCompletionPolicy().Return(nil).AnyTimes()
 		cachedJob := cachedmocks.NewMockJob(ctrl)
 		cachedTasks := make(map[uint32]cached.Task)
 		taskStateCounts := make(map[string]uint32)
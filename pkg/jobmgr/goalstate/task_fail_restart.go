@@ -19,12 +19,14 @@ import (
 	"math"
 	"time"
 
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 
 	"github.com/uber/peloton/pkg/common"
 	"github.com/uber/peloton/pkg/common/goalstate"
 	"github.com/uber/peloton/pkg/jobmgr/cached"
 	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+	jobconfig "github.com/uber/peloton/pkg/jobmgr/job/config"
 	taskutil "github.com/uber/peloton/pkg/jobmgr/util/task"
 
 	log "github.com/sirupsen/logrus"
@@ -188,11 +190,35 @@ func TaskFailRetry(ctx context.Context, entity goalstate.Entity) error {
 		goalStateDriver.mtx.taskMetrics.RetryFailedLaunchTotal.Inc(1)
 	}
 
+	cachedConfig, err := cachedJob.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	groups := cachedConfig.GetInstanceGroups()
+	group := jobconfig.GetInstanceGroup(groups, taskEnt.instanceID)
+
+	if group.GetFailurePolicy() == job.GangFailurePolicy_GANG_FAILURE_POLICY_KILL {
+		// failure of one member kills the whole instance group
+		// along with the instance groups depending on it
+		return killInstanceGroup(ctx, cachedJob, groups, group, goalStateDriver)
+	}
+
 	if runtime.GetFailureCount() >= maxAttempts {
 		// do not retry the task
 		return nil
 	}
 
+	if group.GetFailurePolicy() == job.GangFailurePolicy_GANG_FAILURE_POLICY_RESTART {
+		if err := restartInstanceGroup(
+			ctx,
+			cachedJob,
+			group,
+			taskEnt.instanceID,
+			goalStateDriver); err != nil {
+			return err
+		}
+	}
+
 	return rescheduleTask(
 		ctx,
 		cachedJob,
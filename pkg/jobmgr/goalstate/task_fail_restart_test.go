@@ -56,6 +56,7 @@ type TaskFailRetryTestSuite struct {
 	cachedJob    *cachedmocks.MockJob
 	cachedUpdate *cachedmocks.MockUpdate
 	cachedTask   *cachedmocks.MockTask
+	cachedConfig *cachedmocks.MockJobConfigCache
 
 	jobRuntime      *pbjob.RuntimeInfo
 	taskRuntime     *pbtask.RuntimeInfo
@@ -83,6 +84,7 @@ func (suite *TaskFailRetryTestSuite) SetupTest() {
 	suite.cachedJob = cachedmocks.NewMockJob(suite.ctrl)
 	suite.cachedTask = cachedmocks.NewMockTask(suite.ctrl)
 	suite.cachedUpdate = cachedmocks.NewMockUpdate(suite.ctrl)
+	suite.cachedConfig = cachedmocks.NewMockJobConfigCache(suite.ctrl)
 	suite.taskConfigV2Ops = objectmocks.NewMockTaskConfigV2Ops(suite.ctrl)
 	suite.goalStateDriver = &driver{
 		jobEngine:       suite.jobGoalStateEngine,
@@ -122,6 +124,14 @@ func (suite *TaskFailRetryTestSuite) SetupTest() {
 	suite.jobRuntime = &pbjob.RuntimeInfo{
 		UpdateID: suite.updateID,
 	}
+	suite.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(suite.cachedConfig, nil).
+		AnyTimes()
+	suite.cachedConfig.EXPECT().
+		GetInstanceGroups().
+		Return(nil).
+		AnyTimes()
 }
 
 // TestTaskFailNoRetry tests when restart policy is set to 0 for batch task
@@ -58,13 +58,17 @@ func TaskStart(ctx context.Context, entity goalstate.Entity) error {
 		return err
 	}
 
-	if cachedConfig.GetSLA().GetMaximumRunningInstances() > 0 {
+	if cachedConfig.GetSLA().GetMaximumRunningInstances() > 0 ||
+		hasStartDependency(
+			cachedConfig.GetInstanceGroups(), taskEnt.instanceID) {
 		// Tasks are enqueued into goal state in INITIALiZED state either
 		// during recovery or due to task restart due to failure/task lost
 		// or due to launch/starting state timeouts. In all these cases,
 		// job is enqueued into goal state as well. So, this is merely a safety
 		// check, hence enqueue with a large delay to prevent too many
 		// enqueues of the same job during recovery.
+		// Tasks in instance groups with dependencies are started by the job
+		// once all the instance groups they depend on are up.
 		goalStateDriver.EnqueueJob(taskEnt.jobID, time.Now().Add(
 			_jobEnqueueMultiplierOnTaskStart*
 				goalStateDriver.JobRuntimeDuration(cachedConfig.GetType())))
@@ -84,7 +88,8 @@ func TaskStart(ctx context.Context, entity goalstate.Entity) error {
 		return fmt.Errorf("task info not found for %v", taskID)
 	}
 
-	// TODO: Investigate how to create proper gangs for scheduling (currently, task are treat independently)
+	// Ordering between tasks is handled via instance groups, hence the task
+	// is enqueued as a gang of its own here.
 	response, err := jobmgr_task.EnqueueGangs(
 		ctx,
 		[]*task.TaskInfo{taskInfo},
@@ -75,6 +75,10 @@ func (suite *TaskStartTestSuite) SetupTest() {
 	suite.jobFactory = cachedmocks.NewMockJobFactory(suite.ctrl)
 	suite.cachedJob = cachedmocks.NewMockJob(suite.ctrl)
 	suite.cachedConfig = cachedmocks.NewMockJobConfigCache(suite.ctrl)
+	suite.cachedConfig.EXPECT().
+		GetInstanceGroups().
+		Return(nil).
+		AnyTimes()
 	suite.cachedTask = cachedmocks.NewMockTask(suite.ctrl)
 	suite.mockVolumeStore = storemocks.NewMockPersistentVolumeStore(suite.ctrl)
 
@@ -132,25 +132,26 @@ func stopInitializedTask(ctx context.Context, taskEnt *taskEntity) error {
 		return nil
 	}
 
-	// TODO: this is write after read, should use optimistic concurrency control
-	runtimeDiff := jobmgrcommon.RuntimeDiff{
-		jobmgrcommon.StateField:   task.TaskState_KILLED,
-		jobmgrcommon.MessageField: "Non-running task killed",
-		jobmgrcommon.ReasonField:  "",
-	}
+	runtime.State = task.TaskState_KILLED
+	runtime.Message = "Non-running task killed"
+	runtime.Reason = ""
 	if runtime.GetConfigVersion() != runtime.GetDesiredConfigVersion() {
 		// Kill is due to update, reset failure count
-		runtimeDiff[jobmgrcommon.FailureCountField] = uint32(0)
+		runtime.FailureCount = 0
 	}
 
-	// we do not need to handle `instancesToBeRetried` here since the task
-	// is being requeued to the goalstate. Goalstate will reload the task
-	// runtime when the task is evaluated the next time
-	_, _, err = cachedJob.PatchTasks(
+	// the runtime is written only if it has not changed since it was read
+	_, err = cachedJob.CompareAndSetTask(
 		ctx,
-		map[uint32]jobmgrcommon.RuntimeDiff{taskEnt.instanceID: runtimeDiff},
+		taskEnt.instanceID,
+		runtime,
 		false,
 	)
+	if err == jobmgrcommon.UnexpectedVersionError {
+		// If it had changed, re-evaluate the task with the current runtime.
+		goalStateDriver.EnqueueTask(taskEnt.jobID, taskEnt.instanceID, time.Now())
+		return nil
+	}
 	if err == nil {
 		goalStateDriver.EnqueueTask(taskEnt.jobID, taskEnt.instanceID, time.Now())
 		EnqueueJobWithDefaultDelay(taskEnt.jobID, goalStateDriver, cachedJob)
@@ -308,8 +308,14 @@ func TestTaskStopIfInitializedCallsKillOnResmgr(t *testing.T) {
 		GetRuntime(gomock.Any()).Return(runtime, nil)
 
 	cachedJob.EXPECT().
-		PatchTasks(gomock.Any(), gomock.Any(), false).
-		Return(nil, nil, nil)
+		CompareAndSetTask(gomock.Any(), instanceID, gomock.Any(), false).
+		Do(func(_ context.Context,
+			_ uint32,
+			runtime *pbtask.RuntimeInfo,
+			_ bool) {
+			assert.Equal(t, pbtask.TaskState_KILLED, runtime.GetState())
+		}).
+		Return(nil, nil)
 
 	cachedJob.EXPECT().
 		GetJobType().Return(pbjob.JobType_BATCH)
@@ -402,8 +408,14 @@ func TestTaskStopIfPendingCallsKillOnResmgr(t *testing.T) {
 		GetRuntime(gomock.Any()).Return(runtime, nil)
 
 	cachedJob.EXPECT().
-		PatchTasks(gomock.Any(), gomock.Any(), false).
-		Return(nil, nil, nil)
+		CompareAndSetTask(gomock.Any(), instanceID, gomock.Any(), false).
+		Do(func(_ context.Context,
+			_ uint32,
+			runtime *pbtask.RuntimeInfo,
+			_ bool) {
+			assert.Equal(t, pbtask.TaskState_KILLED, runtime.GetState())
+		}).
+		Return(nil, nil)
 
 	cachedJob.EXPECT().
 		GetJobType().Return(pbjob.JobType_BATCH)
@@ -419,3 +431,99 @@ func TestTaskStopIfPendingCallsKillOnResmgr(t *testing.T) {
 	err := TaskStop(context.Background(), taskEnt)
 	assert.NoError(t, err)
 }
+
+// TestTaskStopIfPendingVersionConflict tests killing a pending task whose
+// runtime has changed since it was read
+func TestTaskStopIfPendingVersionConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	jobGoalStateEngine := goalstatemocks.NewMockEngine(ctrl)
+	taskGoalStateEngine := goalstatemocks.NewMockEngine(ctrl)
+	taskStore := storemocks.NewMockTaskStore(ctrl)
+	jobFactory := cachedmocks.NewMockJobFactory(ctrl)
+	cachedJob := cachedmocks.NewMockJob(ctrl)
+	cachedTask := cachedmocks.NewMockTask(ctrl)
+	mockResmgr := resmocks.NewMockResourceManagerServiceYARPCClient(ctrl)
+
+	goalStateDriver := &driver{
+		jobEngine:    jobGoalStateEngine,
+		taskEngine:   taskGoalStateEngine,
+		taskStore:    taskStore,
+		jobFactory:   jobFactory,
+		resmgrClient: mockResmgr,
+		mtx:          NewMetrics(tally.NoopScope),
+		cfg:          &Config{},
+	}
+	goalStateDriver.cfg.normalize()
+
+	jobID := &peloton.JobID{Value: "3c8a3c3e-71e3-49c5-9aed-2929823f595c"}
+	instanceID := uint32(7)
+	taskID := &peloton.TaskID{Value: "3c8a3c3e-71e3-49c5-9aed-2929823f595c-7"}
+
+	taskEnt := &taskEntity{
+		jobID:      jobID,
+		instanceID: instanceID,
+		driver:     goalStateDriver,
+	}
+
+	runtime := &pbtask.RuntimeInfo{
+		State: pbtask.TaskState_PENDING,
+	}
+
+	var killResponseErr []*resmgrsvc.KillTasksResponse_Error
+	killResponseErr = append(killResponseErr,
+		&resmgrsvc.KillTasksResponse_Error{
+			NotFound: &resmgrsvc.TasksNotFound{
+				Message: "Tasks Not Found",
+				Task:    taskID,
+			},
+		})
+	res := &resmgrsvc.KillTasksResponse{
+		Error: killResponseErr,
+	}
+
+	jobFactory.EXPECT().
+		GetJob(jobID).Return(cachedJob)
+
+	cachedJob.EXPECT().
+		GetTask(instanceID).Return(cachedTask)
+
+	cachedTask.EXPECT().
+		GetRuntime(gomock.Any()).Return(runtime, nil)
+
+	jobFactory.EXPECT().
+		GetJob(jobID).Return(cachedJob)
+
+	mockResmgr.EXPECT().KillTasks(gomock.Any(), &resmgrsvc.KillTasksRequest{
+		Tasks: []*peloton.TaskID{
+			{
+				Value: "3c8a3c3e-71e3-49c5-9aed-2929823f595c-7",
+			},
+		},
+	}).Return(res, nil)
+
+	cachedJob.EXPECT().
+		GetTask(instanceID).Return(cachedTask)
+
+	cachedTask.EXPECT().
+		GetRuntime(gomock.Any()).Return(runtime, nil)
+
+	cachedJob.EXPECT().
+		CompareAndSetTask(gomock.Any(), instanceID, gomock.Any(), false).
+		Do(func(_ context.Context,
+			_ uint32,
+			runtime *pbtask.RuntimeInfo,
+			_ bool) {
+			assert.Equal(t, pbtask.TaskState_KILLED, runtime.GetState())
+		}).
+		Return(nil, jobmgrcommon.UnexpectedVersionError)
+
+	// only the task is re-evaluated with its current runtime
+	taskGoalStateEngine.EXPECT().
+		Enqueue(gomock.Any(), gomock.Any()).
+		Return()
+
+	err := TaskStop(context.Background(), taskEnt)
+	assert.NoError(t, err)
+}
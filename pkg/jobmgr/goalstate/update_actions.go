@@ -158,6 +158,7 @@ func UpdateUntrack(ctx context.Context, entity goalstate.Entity) error {
 
 	// clean up the update from cache and goal state
 	goalStateDriver.DeleteUpdate(jobID, updateEnt.id)
+	goalStateDriver.healthGateChecks.delete(updateEnt.id.GetValue())
 	cachedJob.ClearWorkflow(updateEnt.id)
 	goalStateDriver.mtx.updateMetrics.UpdateUntrack.Inc(1)
 
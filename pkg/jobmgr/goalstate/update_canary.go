@@ -0,0 +1,216 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goalstate
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// processCanary runs the canary phase of an update. The canary instances
+// are updated first, then left to bake for the configured period before
+// their health is checked. A failed canary rolls the update back, while
+// a healthy canary is either promoted automatically or waits for an
+// explicit promotion.
+// It returns true if the rest of the update must wait for the canary
+// phase, in which case no more instances should be processed in this run.
+func processCanary(
+	ctx context.Context,
+	cachedJob cached.Job,
+	cachedUpdate cached.Update,
+	instancesDone []uint32,
+	instancesFailed []uint32,
+	instancesCurrent []uint32,
+	driver *driver,
+) (bool, error) {
+	if !isCanaryInProgress(cachedUpdate) {
+		return false, nil
+	}
+
+	canaryConfig := cachedUpdate.GetUpdateConfig().GetCanary()
+	if uint32(len(instancesFailed)) > canaryConfig.GetMaxFailureInstances() {
+		return true, processFailedCanary(
+			ctx,
+			cachedJob,
+			cachedUpdate,
+			instancesDone,
+			instancesFailed,
+			instancesCurrent,
+			driver,
+		)
+	}
+
+	// canary instances are still being updated
+	if len(instancesDone)+len(instancesFailed) < int(getCanarySize(cachedUpdate)) {
+		return false, nil
+	}
+
+	// record the progress of the canary instances, since no more
+	// progress is written until the canary phase is over
+	if err := cachedUpdate.WriteProgress(
+		ctx,
+		cachedUpdate.GetState().State,
+		instancesDone,
+		instancesFailed,
+		instancesCurrent,
+	); err != nil {
+		return true, err
+	}
+
+	if cachedUpdate.GetCanaryCompletionTime().IsZero() {
+		if err := cachedUpdate.CompleteCanary(ctx); err != nil {
+			return true, err
+		}
+		log.WithFields(log.Fields{
+			"update_id": cachedUpdate.ID().GetValue(),
+			"job_id":    cachedJob.ID().GetValue(),
+		}).Info("canary instances updated, baking")
+	}
+
+	bakeEndTime := cachedUpdate.GetCanaryCompletionTime().Add(
+		time.Duration(canaryConfig.GetBakePeriodSeconds()) * time.Second)
+	if time.Now().Before(bakeEndTime) {
+		driver.EnqueueUpdate(cachedJob.ID(), cachedUpdate.ID(), bakeEndTime)
+		return true, nil
+	}
+
+	if !isCanaryHealthy(ctx, cachedJob, cachedUpdate, instancesDone) {
+		return true, processFailedCanary(
+			ctx,
+			cachedJob,
+			cachedUpdate,
+			instancesDone,
+			instancesFailed,
+			instancesCurrent,
+			driver,
+		)
+	}
+
+	if !canaryConfig.GetAutoPromote() {
+		log.WithFields(log.Fields{
+			"update_id": cachedUpdate.ID().GetValue(),
+			"job_id":    cachedJob.ID().GetValue(),
+		}).Debug("canary succeeded, waiting for promotion")
+		return true, nil
+	}
+
+	if err := cachedUpdate.Promote(ctx, nil); err != nil {
+		return true, err
+	}
+
+	log.WithFields(log.Fields{
+		"update_id": cachedUpdate.ID().GetValue(),
+		"job_id":    cachedJob.ID().GetValue(),
+	}).Info("canary succeeded, update promoted")
+	return false, nil
+}
+
+// processFailedCanary rolls back an update whose canary instances
+// failed, regardless of RollbackOnFailure in the update config.
+func processFailedCanary(
+	ctx context.Context,
+	cachedJob cached.Job,
+	cachedUpdate cached.Update,
+	instancesDone []uint32,
+	instancesFailed []uint32,
+	instancesCurrent []uint32,
+	driver *driver,
+) error {
+	log.WithFields(log.Fields{
+		"update_id":        cachedUpdate.ID().GetValue(),
+		"job_id":           cachedJob.ID().GetValue(),
+		"instances_failed": instancesFailed,
+	}).Info("canary failed")
+
+	if err := rollbackUpdate(
+		ctx,
+		cachedJob,
+		cachedUpdate,
+		instancesDone,
+		instancesFailed,
+		instancesCurrent,
+	); err != nil {
+		return err
+	}
+
+	driver.EnqueueUpdate(cachedJob.ID(), cachedUpdate.ID(), time.Now())
+	return nil
+}
+
+// isCanaryHealthy returns true if none of the canary instances
+// still in the job is unavailable after the bake period.
+func isCanaryHealthy(
+	ctx context.Context,
+	cachedJob cached.Job,
+	cachedUpdate cached.Update,
+	instancesDone []uint32,
+) bool {
+	// removed instances are not expected to be running
+	canaryInstances := util.SubtractSlice(
+		instancesDone,
+		cachedUpdate.GetInstancesRemoved(),
+	)
+	if len(canaryInstances) == 0 {
+		return true
+	}
+
+	for instID, availability := range cachedJob.GetInstanceAvailabilityType(
+		ctx,
+		canaryInstances...,
+	) {
+		if availability == jobmgrcommon.InstanceAvailability_UNAVAILABLE {
+			log.WithFields(log.Fields{
+				"update_id":   cachedUpdate.ID().GetValue(),
+				"job_id":      cachedJob.ID().GetValue(),
+				"instance_id": instID,
+			}).Info("canary instance unavailable after bake period")
+			return false
+		}
+	}
+	return true
+}
+
+// isCanaryInProgress returns true if the update has a canary phase
+// which has not been promoted yet. A canary which covers all of the
+// instances in the update is not treated as a separate phase.
+func isCanaryInProgress(cachedUpdate cached.Update) bool {
+	return cached.HasCanary(cachedUpdate.GetUpdateConfig()) &&
+		!cachedUpdate.IsCanaryPromoted() &&
+		cachedUpdate.GetWorkflowType() == models.WorkflowType_UPDATE &&
+		!isUpdateRollback(cachedUpdate) &&
+		int(getCanarySize(cachedUpdate)) <
+			len(cachedUpdate.GetGoalState().Instances)
+}
+
+// getCanarySize returns the number of instances in the canary phase
+// of the update. The percentage takes precedence over the instance count.
+func getCanarySize(cachedUpdate cached.Update) uint32 {
+	canaryConfig := cachedUpdate.GetUpdateConfig().GetCanary()
+	if canaryConfig.GetInstancePercentage() > 0 {
+		total := float64(len(cachedUpdate.GetGoalState().Instances))
+		return uint32(math.Ceil(
+			total * canaryConfig.GetInstancePercentage() / 100))
+	}
+	return canaryConfig.GetInstanceCount()
+}
@@ -0,0 +1,397 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goalstate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	goalstatemocks "github.com/uber/peloton/pkg/common/goalstate/mocks"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
+	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type UpdateCanaryTestSuite struct {
+	suite.Suite
+	ctrl                  *gomock.Controller
+	updateGoalStateEngine *goalstatemocks.MockEngine
+	goalStateDriver       *driver
+	jobID                 *peloton.JobID
+	updateID              *peloton.UpdateID
+	cachedJob             *cachedmocks.MockJob
+	cachedUpdate          *cachedmocks.MockUpdate
+	instancesTotal        []uint32
+}
+
+func TestUpdateCanary(t *testing.T) {
+	suite.Run(t, new(UpdateCanaryTestSuite))
+}
+
+func (suite *UpdateCanaryTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.updateGoalStateEngine = goalstatemocks.NewMockEngine(suite.ctrl)
+	suite.goalStateDriver = &driver{
+		updateEngine: suite.updateGoalStateEngine,
+		mtx:          NewMetrics(tally.NoopScope),
+		cfg:          &Config{},
+	}
+	suite.goalStateDriver.cfg.normalize()
+
+	suite.jobID = &peloton.JobID{Value: uuid.NewRandom().String()}
+	suite.updateID = &peloton.UpdateID{Value: uuid.NewRandom().String()}
+	suite.cachedJob = cachedmocks.NewMockJob(suite.ctrl)
+	suite.cachedUpdate = cachedmocks.NewMockUpdate(suite.ctrl)
+	suite.instancesTotal = []uint32{0, 1, 2, 3, 4}
+
+	suite.cachedJob.EXPECT().ID().Return(suite.jobID).AnyTimes()
+	suite.cachedUpdate.EXPECT().ID().Return(suite.updateID).AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		GetWorkflowType().
+		Return(models.WorkflowType_UPDATE).
+		AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		GetState().
+		Return(&cached.UpdateStateVector{State: pbupdate.State_ROLLING_FORWARD}).
+		AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		GetGoalState().
+		Return(&cached.UpdateStateVector{Instances: suite.instancesTotal}).
+		AnyTimes()
+}
+
+func (suite *UpdateCanaryTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+// expectCanaryConfig sets up the update to have a canary phase
+// with the given config which has not been promoted
+func (suite *UpdateCanaryTestSuite) expectCanaryConfig(
+	canaryConfig *pbupdate.CanaryConfig,
+) {
+	suite.cachedUpdate.EXPECT().
+		GetUpdateConfig().
+		Return(&pbupdate.UpdateConfig{Canary: canaryConfig}).
+		AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		IsCanaryPromoted().
+		Return(false).
+		AnyTimes()
+}
+
+// TestProcessCanaryNoCanary tests that an update without
+// canary phase does not wait for the canary
+func (suite *UpdateCanaryTestSuite) TestProcessCanaryNoCanary() {
+	suite.cachedUpdate.EXPECT().
+		GetUpdateConfig().
+		Return(&pbupdate.UpdateConfig{BatchSize: 1})
+
+	wait, err := processCanary(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		nil,
+		nil,
+		nil,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.False(wait)
+}
+
+// TestProcessCanaryCoversAllInstances tests that a canary which covers
+// all of the instances in the update is not treated as a separate phase
+func (suite *UpdateCanaryTestSuite) TestProcessCanaryCoversAllInstances() {
+	suite.expectCanaryConfig(&pbupdate.CanaryConfig{InstancePercentage: 100})
+
+	wait, err := processCanary(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		nil,
+		nil,
+		nil,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.False(wait)
+}
+
+// TestProcessCanaryInstancesInProgress tests that the update
+// keeps running while the canary instances are being updated
+func (suite *UpdateCanaryTestSuite) TestProcessCanaryInstancesInProgress() {
+	suite.expectCanaryConfig(&pbupdate.CanaryConfig{InstanceCount: 2})
+
+	wait, err := processCanary(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		[]uint32{0},
+		nil,
+		[]uint32{1},
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.False(wait)
+}
+
+// TestProcessCanaryStartBake tests that the canary completion time
+// is recorded and the update is enqueued at the end of the bake period
+func (suite *UpdateCanaryTestSuite) TestProcessCanaryStartBake() {
+	bakePeriod := 10 * time.Minute
+	suite.expectCanaryConfig(&pbupdate.CanaryConfig{
+		InstanceCount:     2,
+		BakePeriodSeconds: uint32(bakePeriod.Seconds()),
+	})
+	now := time.Now()
+
+	gomock.InOrder(
+		suite.cachedUpdate.EXPECT().
+			WriteProgress(
+				gomock.Any(),
+				pbupdate.State_ROLLING_FORWARD,
+				[]uint32{0, 1},
+				[]uint32(nil),
+				[]uint32(nil)).
+			Return(nil),
+		suite.cachedUpdate.EXPECT().
+			GetCanaryCompletionTime().
+			Return(time.Time{}),
+		suite.cachedUpdate.EXPECT().
+			CompleteCanary(gomock.Any()).
+			Return(nil),
+		suite.cachedUpdate.EXPECT().
+			GetCanaryCompletionTime().
+			Return(now),
+		suite.updateGoalStateEngine.EXPECT().
+			Enqueue(gomock.Any(), now.Add(bakePeriod)),
+	)
+
+	wait, err := processCanary(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		[]uint32{0, 1},
+		nil,
+		nil,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.True(wait)
+}
+
+// TestProcessCanaryWaitForPromotion tests that a healthy canary
+// waits for an explicit promotion if auto promote is not set
+func (suite *UpdateCanaryTestSuite) TestProcessCanaryWaitForPromotion() {
+	suite.expectCanaryConfig(&pbupdate.CanaryConfig{
+		InstanceCount:     2,
+		BakePeriodSeconds: 60,
+	})
+
+	suite.cachedUpdate.EXPECT().
+		WriteProgress(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.cachedUpdate.EXPECT().
+		GetCanaryCompletionTime().
+		Return(time.Now().Add(-time.Hour)).
+		AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		GetInstancesRemoved().
+		Return(nil)
+	suite.cachedJob.EXPECT().
+		GetInstanceAvailabilityType(gomock.Any(), []uint32{0, 1}).
+		Return(map[uint32]jobmgrcommon.InstanceAvailability_Type{
+			0: jobmgrcommon.InstanceAvailability_AVAILABLE,
+			1: jobmgrcommon.InstanceAvailability_AVAILABLE,
+		})
+
+	wait, err := processCanary(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		[]uint32{0, 1},
+		nil,
+		nil,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.True(wait)
+}
+
+// TestProcessCanaryAutoPromote tests that a healthy canary is
+// promoted automatically if auto promote is set
+func (suite *UpdateCanaryTestSuite) TestProcessCanaryAutoPromote() {
+	suite.expectCanaryConfig(&pbupdate.CanaryConfig{
+		InstanceCount: 2,
+		AutoPromote:   true,
+	})
+
+	suite.cachedUpdate.EXPECT().
+		WriteProgress(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.cachedUpdate.EXPECT().
+		GetCanaryCompletionTime().
+		Return(time.Now().Add(-time.Second)).
+		AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		GetInstancesRemoved().
+		Return(nil)
+	suite.cachedJob.EXPECT().
+		GetInstanceAvailabilityType(gomock.Any(), []uint32{0, 1}).
+		Return(map[uint32]jobmgrcommon.InstanceAvailability_Type{
+			0: jobmgrcommon.InstanceAvailability_AVAILABLE,
+			1: jobmgrcommon.InstanceAvailability_AVAILABLE,
+		})
+	suite.cachedUpdate.EXPECT().
+		Promote(gomock.Any(), gomock.Nil()).
+		Return(nil)
+
+	wait, err := processCanary(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		[]uint32{0, 1},
+		nil,
+		nil,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.False(wait)
+}
+
+// TestProcessCanaryUnhealthyRollsBack tests that the update is
+// rolled back if a canary instance is unavailable after baking
+func (suite *UpdateCanaryTestSuite) TestProcessCanaryUnhealthyRollsBack() {
+	suite.expectCanaryConfig(&pbupdate.CanaryConfig{InstanceCount: 2})
+
+	suite.cachedUpdate.EXPECT().
+		WriteProgress(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(2)
+	suite.cachedUpdate.EXPECT().
+		GetCanaryCompletionTime().
+		Return(time.Now().Add(-time.Second)).
+		AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		GetInstancesRemoved().
+		Return(nil)
+	suite.cachedJob.EXPECT().
+		GetInstanceAvailabilityType(gomock.Any(), []uint32{0, 1}).
+		Return(map[uint32]jobmgrcommon.InstanceAvailability_Type{
+			0: jobmgrcommon.InstanceAvailability_AVAILABLE,
+			1: jobmgrcommon.InstanceAvailability_UNAVAILABLE,
+		})
+	suite.cachedJob.EXPECT().
+		RollbackWorkflow(gomock.Any()).
+		Return(yarpcerrors.UnavailableErrorf("test error"))
+
+	wait, err := processCanary(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		[]uint32{0, 1},
+		nil,
+		nil,
+		suite.goalStateDriver,
+	)
+	suite.Error(err)
+	suite.True(wait)
+}
+
+// TestProcessCanaryFailedInstancesRollsBack tests that the update
+// is rolled back once the canary failures exceed the tolerable limit,
+// even if rollback on failure is not set
+func (suite *UpdateCanaryTestSuite) TestProcessCanaryFailedInstancesRollsBack() {
+	suite.expectCanaryConfig(&pbupdate.CanaryConfig{
+		InstanceCount:       3,
+		MaxFailureInstances: 1,
+	})
+
+	gomock.InOrder(
+		suite.cachedUpdate.EXPECT().
+			WriteProgress(
+				gomock.Any(),
+				pbupdate.State_ROLLING_FORWARD,
+				[]uint32(nil),
+				[]uint32{0, 1},
+				[]uint32{2}).
+			Return(nil),
+		suite.cachedJob.EXPECT().
+			RollbackWorkflow(gomock.Any()).
+			Return(yarpcerrors.UnavailableErrorf("test error")),
+	)
+
+	wait, err := processCanary(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		nil,
+		[]uint32{0, 1},
+		[]uint32{2},
+		suite.goalStateDriver,
+	)
+	suite.Error(err)
+	suite.True(wait)
+}
+
+// TestGetInstancesForUpdateRunCanary tests that only the canary
+// instances are processed during the canary phase
+func (suite *UpdateCanaryTestSuite) TestGetInstancesForUpdateRunCanary() {
+	suite.expectCanaryConfig(&pbupdate.CanaryConfig{InstancePercentage: 30})
+
+	suite.cachedUpdate.EXPECT().
+		GetInstancesAdded().
+		Return(nil)
+	suite.cachedUpdate.EXPECT().
+		GetInstancesUpdated().
+		Return(suite.instancesTotal)
+	suite.cachedUpdate.EXPECT().
+		GetInstancesRemoved().
+		Return(nil)
+	suite.cachedJob.EXPECT().
+		GetInstanceAvailabilityType(gomock.Any(), []uint32{1, 2, 3, 4}).
+		Return(map[uint32]jobmgrcommon.InstanceAvailability_Type{
+			1: jobmgrcommon.InstanceAvailability_AVAILABLE,
+			2: jobmgrcommon.InstanceAvailability_AVAILABLE,
+			3: jobmgrcommon.InstanceAvailability_AVAILABLE,
+			4: jobmgrcommon.InstanceAvailability_AVAILABLE,
+		})
+
+	// 30% of 5 instances rounds up to 2 canary instances,
+	// and instance 0 is already being updated
+	instancesToAdd, instancesToUpdate, instancesToRemove :=
+		getInstancesForUpdateRun(
+			context.Background(),
+			suite.cachedJob,
+			suite.cachedUpdate,
+			[]uint32{0},
+			nil,
+			nil,
+		)
+	suite.Empty(instancesToAdd)
+	suite.Equal([]uint32{1}, instancesToUpdate)
+	suite.Empty(instancesToRemove)
+}
@@ -0,0 +1,331 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goalstate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+
+	versionutil "github.com/uber/peloton/pkg/common/util/entityversion"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	"github.com/uber/peloton/pkg/jobmgr/workflow/healthgate"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// healthGateCheck is the check of the health gates of an update
+// before a batch is started.
+type healthGateCheck struct {
+	// batch identifies the batch the gates are checked for
+	batch string
+	// done is set once all the gates have been consulted
+	done bool
+	// gate which failed, nil if all the gates passed
+	failedGate *pbupdate.HealthGateConfig
+	// reason the gate failed
+	reason error
+}
+
+// healthGateChecks tracks the health gate checks of the updates. The
+// gates are consulted outside of the update goal state workers, so that
+// a slow gate does not stall the processing of the other updates.
+type healthGateChecks struct {
+	sync.Mutex
+	// update ID -> check
+	checks map[string]*healthGateCheck
+}
+
+// getOrStart returns the check of an update for the given batch, and
+// records a new check if the update has none for the batch yet, in
+// which case started is set and the caller must run the check.
+func (c *healthGateChecks) getOrStart(
+	updateID string,
+	batch string,
+) (check healthGateCheck, started bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.checks == nil {
+		c.checks = make(map[string]*healthGateCheck)
+	}
+
+	if current, ok := c.checks[updateID]; ok && current.batch == batch {
+		return *current, false
+	}
+
+	current := &healthGateCheck{batch: batch}
+	c.checks[updateID] = current
+	return *current, true
+}
+
+// complete records the result of the check of an update for the
+// given batch, unless the update has moved on to another check.
+func (c *healthGateChecks) complete(
+	updateID string,
+	batch string,
+	failedGate *pbupdate.HealthGateConfig,
+	reason error,
+) {
+	c.Lock()
+	defer c.Unlock()
+
+	current, ok := c.checks[updateID]
+	if !ok || current.batch != batch {
+		return
+	}
+	current.done = true
+	current.failedGate = failedGate
+	current.reason = reason
+}
+
+// delete forgets the check of an update.
+func (c *healthGateChecks) delete(updateID string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.checks, updateID)
+}
+
+// processHealthGates consults the health gates of the update before
+// the next batch of instances is started. The gates are consulted
+// asynchronously, and the update is enqueued again once they have all
+// been consulted. A failed gate pauses or rolls back the update, as
+// configured by the gate, and the reason is recorded in the workflow
+// events.
+// It returns true if the next batch must not be started in this run,
+// either because the gates are still being consulted or because a gate
+// failed.
+func processHealthGates(
+	ctx context.Context,
+	cachedJob cached.Job,
+	cachedUpdate cached.Update,
+	instancesDone []uint32,
+	instancesFailed []uint32,
+	instancesCurrent []uint32,
+	instancesToProcess int,
+	driver *driver,
+) (bool, error) {
+	// gates are only consulted between batches, i.e. once the previous
+	// batch has finished and before the next batch is started
+	if len(instancesCurrent) != 0 ||
+		len(instancesDone)+len(instancesFailed) == 0 ||
+		instancesToProcess == 0 {
+		return false, nil
+	}
+
+	gateConfigs := cachedUpdate.GetUpdateConfig().GetHealthGates()
+	if len(gateConfigs) == 0 || isUpdateRollback(cachedUpdate) {
+		return false, nil
+	}
+
+	// instances only move to done or failed, so the number of
+	// instances in each identifies the batch
+	updateID := cachedUpdate.ID().GetValue()
+	batch := fmt.Sprintf("%d/%d", len(instancesDone), len(instancesFailed))
+	check, started := driver.healthGateChecks.getOrStart(updateID, batch)
+	if started {
+		req := &healthgate.CheckRequest{
+			JobID:           cachedJob.ID().GetValue(),
+			UpdateID:        updateID,
+			JobVersion:      cachedUpdate.GetGoalState().JobVersion,
+			InstancesTotal:  uint32(len(cachedUpdate.GetGoalState().Instances)),
+			InstancesDone:   instancesDone,
+			InstancesFailed: instancesFailed,
+		}
+		go runHealthGates(
+			cachedJob.ID(),
+			cachedUpdate.ID(),
+			batch,
+			gateConfigs,
+			req,
+			driver,
+		)
+		return true, nil
+	}
+
+	if !check.done {
+		return true, nil
+	}
+
+	if check.failedGate == nil {
+		return false, nil
+	}
+
+	err := processFailedHealthGate(
+		ctx,
+		cachedJob,
+		cachedUpdate,
+		check.failedGate,
+		check.reason,
+		instancesDone,
+		instancesFailed,
+		instancesCurrent,
+		driver,
+	)
+	if err == nil {
+		// the gates are consulted again if the update is resumed
+		driver.healthGateChecks.delete(updateID)
+	}
+	return true, err
+}
+
+// runHealthGates consults the health gates of an update one after the
+// other, stopping at the first failed gate, then records the result and
+// enqueues the update so that it is acted upon.
+func runHealthGates(
+	jobID *peloton.JobID,
+	updateID *peloton.UpdateID,
+	batch string,
+	gateConfigs []*pbupdate.HealthGateConfig,
+	req *healthgate.CheckRequest,
+	driver *driver,
+) {
+	var failedGate *pbupdate.HealthGateConfig
+	var reason error
+	for _, gateConfig := range gateConfigs {
+		if err := checkHealthGate(
+			context.Background(),
+			gateConfig,
+			req,
+			driver,
+		); err != nil {
+			failedGate = gateConfig
+			reason = err
+			break
+		}
+	}
+
+	if failedGate != nil {
+		driver.mtx.updateMetrics.UpdateHealthGateFail.Inc(1)
+	} else {
+		driver.mtx.updateMetrics.UpdateHealthGatePass.Inc(1)
+	}
+
+	driver.healthGateChecks.complete(
+		updateID.GetValue(),
+		batch,
+		failedGate,
+		reason,
+	)
+	driver.EnqueueUpdate(jobID, updateID, time.Now())
+}
+
+// checkHealthGate evaluates a single health gate within its timeout
+func checkHealthGate(
+	ctx context.Context,
+	gateConfig *pbupdate.HealthGateConfig,
+	req *healthgate.CheckRequest,
+	driver *driver,
+) error {
+	gate, err := driver.healthGateFactory.Create(gateConfig)
+	if err != nil {
+		return err
+	}
+
+	timeout := driver.cfg.HealthGateTimeout
+	if gateConfig.GetTimeoutSeconds() != 0 {
+		timeout = time.Duration(gateConfig.GetTimeoutSeconds()) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return gate.Check(ctx, req)
+}
+
+// processFailedHealthGate records the reason a health gate failed in
+// the workflow events, then pauses or rolls back the update.
+func processFailedHealthGate(
+	ctx context.Context,
+	cachedJob cached.Job,
+	cachedUpdate cached.Update,
+	gateConfig *pbupdate.HealthGateConfig,
+	reason error,
+	instancesDone []uint32,
+	instancesFailed []uint32,
+	instancesCurrent []uint32,
+	driver *driver,
+) error {
+	message := fmt.Sprintf("health gate %s failed: %v",
+		gateConfig.GetName(), reason)
+
+	log.WithFields(log.Fields{
+		"update_id":   cachedUpdate.ID().GetValue(),
+		"job_id":      cachedJob.ID().GetValue(),
+		"health_gate": gateConfig.GetName(),
+		"on_failure":  gateConfig.GetOnFailure().String(),
+	}).WithError(reason).Info("health gate failed")
+
+	if err := driver.jobUpdateEventsOps.CreateWithMessage(
+		ctx,
+		cachedUpdate.ID(),
+		cachedUpdate.GetWorkflowType(),
+		cachedUpdate.GetState().State,
+		message,
+	); err != nil {
+		return err
+	}
+
+	if gateConfig.GetOnFailure() == pbupdate.HealthGateConfig_ROLLBACK {
+		if err := rollbackUpdate(
+			ctx,
+			cachedJob,
+			cachedUpdate,
+			instancesDone,
+			instancesFailed,
+			instancesCurrent,
+		); err != nil {
+			return err
+		}
+		driver.EnqueueUpdate(cachedJob.ID(), cachedUpdate.ID(), time.Now())
+		return nil
+	}
+
+	// record the progress of the last batch, since the
+	// update is not processed again until it is resumed
+	if err := cachedUpdate.WriteProgress(
+		ctx,
+		cachedUpdate.GetState().State,
+		instancesDone,
+		instancesFailed,
+		instancesCurrent,
+	); err != nil {
+		return err
+	}
+
+	// pause through the job, so that the workflow version is bumped
+	// as for any other pause of the workflow
+	runtime, err := cachedJob.GetRuntime(ctx)
+	if err != nil {
+		return err
+	}
+	if runtime.GetUpdateID().GetValue() != cachedUpdate.ID().GetValue() {
+		return nil
+	}
+	if _, _, err := cachedJob.PauseWorkflow(
+		ctx,
+		versionutil.GetJobEntityVersion(
+			runtime.GetConfigurationVersion(),
+			runtime.GetDesiredStateVersion(),
+			runtime.GetWorkflowVersion()),
+	); err != nil {
+		return err
+	}
+	driver.EnqueueUpdate(cachedJob.ID(), cachedUpdate.ID(), time.Now())
+	return nil
+}
@@ -0,0 +1,513 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goalstate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	"github.com/uber/peloton/pkg/common/goalstate"
+	goalstatemocks "github.com/uber/peloton/pkg/common/goalstate/mocks"
+	versionutil "github.com/uber/peloton/pkg/common/util/entityversion"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
+	"github.com/uber/peloton/pkg/jobmgr/workflow/healthgate"
+	healthgatemocks "github.com/uber/peloton/pkg/jobmgr/workflow/healthgate/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type UpdateHealthGateTestSuite struct {
+	suite.Suite
+	ctrl                  *gomock.Controller
+	updateGoalStateEngine *goalstatemocks.MockEngine
+	jobUpdateEventsOps    *objectmocks.MockJobUpdateEventsOps
+	healthGateFactory     *healthgatemocks.MockFactory
+	healthGate            *healthgatemocks.MockUpdateHealthGate
+	goalStateDriver       *driver
+	jobID                 *peloton.JobID
+	updateID              *peloton.UpdateID
+	cachedJob             *cachedmocks.MockJob
+	cachedUpdate          *cachedmocks.MockUpdate
+	instancesTotal        []uint32
+}
+
+func TestUpdateHealthGate(t *testing.T) {
+	suite.Run(t, new(UpdateHealthGateTestSuite))
+}
+
+func (suite *UpdateHealthGateTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.updateGoalStateEngine = goalstatemocks.NewMockEngine(suite.ctrl)
+	suite.jobUpdateEventsOps = objectmocks.NewMockJobUpdateEventsOps(suite.ctrl)
+	suite.healthGateFactory = healthgatemocks.NewMockFactory(suite.ctrl)
+	suite.healthGate = healthgatemocks.NewMockUpdateHealthGate(suite.ctrl)
+	suite.goalStateDriver = &driver{
+		updateEngine:       suite.updateGoalStateEngine,
+		jobUpdateEventsOps: suite.jobUpdateEventsOps,
+		healthGateFactory:  suite.healthGateFactory,
+		mtx:                NewMetrics(tally.NoopScope),
+		cfg:                &Config{},
+	}
+	suite.goalStateDriver.cfg.normalize()
+
+	suite.jobID = &peloton.JobID{Value: uuid.NewRandom().String()}
+	suite.updateID = &peloton.UpdateID{Value: uuid.NewRandom().String()}
+	suite.cachedJob = cachedmocks.NewMockJob(suite.ctrl)
+	suite.cachedUpdate = cachedmocks.NewMockUpdate(suite.ctrl)
+	suite.instancesTotal = []uint32{0, 1, 2, 3, 4}
+
+	suite.cachedJob.EXPECT().ID().Return(suite.jobID).AnyTimes()
+	suite.cachedUpdate.EXPECT().ID().Return(suite.updateID).AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		GetWorkflowType().
+		Return(models.WorkflowType_UPDATE).
+		AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		GetState().
+		Return(&cached.UpdateStateVector{State: pbupdate.State_ROLLING_FORWARD}).
+		AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		GetGoalState().
+		Return(&cached.UpdateStateVector{
+			Instances:  suite.instancesTotal,
+			JobVersion: uint64(3),
+		}).
+		AnyTimes()
+}
+
+func (suite *UpdateHealthGateTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+// expectHealthGate sets up the update to have a single health gate
+// with the given failure action
+func (suite *UpdateHealthGateTestSuite) expectHealthGate(
+	onFailure pbupdate.HealthGateConfig_FailureAction,
+) *pbupdate.HealthGateConfig {
+	gateConfig := &pbupdate.HealthGateConfig{
+		Name:      "error-rate",
+		OnFailure: onFailure,
+		Webhook: &pbupdate.WebhookGateConfig{
+			Url: "http://localhost/check",
+		},
+	}
+	suite.cachedUpdate.EXPECT().
+		GetUpdateConfig().
+		Return(&pbupdate.UpdateConfig{
+			BatchSize:   1,
+			HealthGates: []*pbupdate.HealthGateConfig{gateConfig},
+		}).
+		AnyTimes()
+	return gateConfig
+}
+
+// TestProcessHealthGatesBatchInProgress tests that the health gates
+// are not consulted while a batch is still in progress
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesBatchInProgress() {
+	gateFailed, err := processHealthGates(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		[]uint32{0},
+		nil,
+		[]uint32{1},
+		1,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.False(gateFailed)
+}
+
+// TestProcessHealthGatesLastBatch tests that the health gates are
+// not consulted once there are no more instances to process
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesLastBatch() {
+	gateFailed, err := processHealthGates(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		suite.instancesTotal,
+		nil,
+		nil,
+		0,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.False(gateFailed)
+}
+
+// TestProcessHealthGatesNoGates tests an update without health gates
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesNoGates() {
+	suite.cachedUpdate.EXPECT().
+		GetUpdateConfig().
+		Return(&pbupdate.UpdateConfig{BatchSize: 1})
+
+	gateFailed, err := processHealthGates(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		[]uint32{0},
+		nil,
+		nil,
+		1,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.False(gateFailed)
+}
+
+// expectEnqueue sets up the update goal state engine to expect the
+// update to be enqueued, and returns a channel notified when it is
+func (suite *UpdateHealthGateTestSuite) expectEnqueue() chan struct{} {
+	enqueued := make(chan struct{}, 1)
+	suite.updateGoalStateEngine.EXPECT().
+		Enqueue(gomock.Any(), gomock.Any()).
+		Do(func(_ goalstate.Entity, _ time.Time) {
+			enqueued <- struct{}{}
+		})
+	return enqueued
+}
+
+// runHealthGates runs processHealthGates until the health gates have
+// been consulted, and returns the result of the last run
+func (suite *UpdateHealthGateTestSuite) runHealthGates(
+	instancesDone []uint32,
+	instancesFailed []uint32,
+) (bool, error) {
+	enqueued := suite.expectEnqueue()
+
+	// the gates are consulted asynchronously
+	gateFailed, err := processHealthGates(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		instancesDone,
+		instancesFailed,
+		nil,
+		1,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.True(gateFailed)
+	<-enqueued
+
+	return processHealthGates(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		instancesDone,
+		instancesFailed,
+		nil,
+		1,
+		suite.goalStateDriver,
+	)
+}
+
+// expectPause sets up the job to expect the update to be paused
+func (suite *UpdateHealthGateTestSuite) expectPause() []*gomock.Call {
+	entityVersion := versionutil.GetJobEntityVersion(2, 1, 5)
+	return []*gomock.Call{
+		suite.cachedJob.EXPECT().
+			GetRuntime(gomock.Any()).
+			Return(&pbjob.RuntimeInfo{
+				UpdateID:             suite.updateID,
+				ConfigurationVersion: 2,
+				DesiredStateVersion:  1,
+				WorkflowVersion:      5,
+			}, nil),
+		suite.cachedJob.EXPECT().
+			PauseWorkflow(gomock.Any(), entityVersion).
+			Return(suite.updateID, nil, nil),
+		suite.updateGoalStateEngine.EXPECT().
+			Enqueue(gomock.Any(), gomock.Any()),
+	}
+}
+
+// TestProcessHealthGatesPass tests that the update continues
+// when all of the health gates pass
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesPass() {
+	gateConfig := suite.expectHealthGate(pbupdate.HealthGateConfig_PAUSE)
+
+	suite.healthGateFactory.EXPECT().
+		Create(gateConfig).
+		Return(suite.healthGate, nil)
+	suite.healthGate.EXPECT().
+		Check(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, req *healthgate.CheckRequest) {
+			_, ok := ctx.Deadline()
+			suite.True(ok)
+			suite.Equal(&healthgate.CheckRequest{
+				JobID:           suite.jobID.GetValue(),
+				UpdateID:        suite.updateID.GetValue(),
+				JobVersion:      uint64(3),
+				InstancesTotal:  uint32(len(suite.instancesTotal)),
+				InstancesDone:   []uint32{0},
+				InstancesFailed: []uint32{1},
+			}, req)
+		}).
+		Return(nil)
+
+	gateFailed, err := suite.runHealthGates([]uint32{0}, []uint32{1})
+	suite.NoError(err)
+	suite.False(gateFailed)
+
+	// the gates are not consulted again for the same batch
+	gateFailed, err = processHealthGates(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		[]uint32{0},
+		[]uint32{1},
+		nil,
+		1,
+		suite.goalStateDriver,
+	)
+	suite.NoError(err)
+	suite.False(gateFailed)
+}
+
+// TestProcessHealthGatesInProgress tests that the next batch is not
+// started while the health gates are being consulted
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesInProgress() {
+	gateConfig := suite.expectHealthGate(pbupdate.HealthGateConfig_PAUSE)
+
+	release := make(chan struct{})
+	suite.healthGateFactory.EXPECT().
+		Create(gateConfig).
+		Return(suite.healthGate, nil)
+	suite.healthGate.EXPECT().
+		Check(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, _ *healthgate.CheckRequest) {
+			<-release
+		}).
+		Return(nil)
+	enqueued := suite.expectEnqueue()
+
+	for i := 0; i < 2; i++ {
+		gateFailed, err := processHealthGates(
+			context.Background(),
+			suite.cachedJob,
+			suite.cachedUpdate,
+			[]uint32{0},
+			nil,
+			nil,
+			1,
+			suite.goalStateDriver,
+		)
+		suite.NoError(err)
+		suite.True(gateFailed)
+	}
+
+	close(release)
+	<-enqueued
+}
+
+// TestProcessHealthGatesFailPause tests that a failed health gate
+// pauses the update and records the reason in the workflow events
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesFailPause() {
+	gateConfig := suite.expectHealthGate(pbupdate.HealthGateConfig_PAUSE)
+
+	suite.healthGateFactory.EXPECT().
+		Create(gateConfig).
+		Return(suite.healthGate, nil)
+	suite.healthGate.EXPECT().
+		Check(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("error rate too high"))
+
+	calls := []*gomock.Call{
+		suite.jobUpdateEventsOps.EXPECT().
+			CreateWithMessage(
+				gomock.Any(),
+				suite.updateID,
+				models.WorkflowType_UPDATE,
+				pbupdate.State_ROLLING_FORWARD,
+				"health gate error-rate failed: error rate too high",
+			).
+			Return(nil),
+		suite.cachedUpdate.EXPECT().
+			WriteProgress(
+				gomock.Any(),
+				pbupdate.State_ROLLING_FORWARD,
+				[]uint32{0, 1},
+				[]uint32(nil),
+				[]uint32(nil),
+			).
+			Return(nil),
+	}
+	gomock.InOrder(append(calls, suite.expectPause()...)...)
+
+	gateFailed, err := suite.runHealthGates([]uint32{0, 1}, nil)
+	suite.NoError(err)
+	suite.True(gateFailed)
+
+	// the gates are consulted again once the update is resumed
+	suite.healthGateFactory.EXPECT().
+		Create(gateConfig).
+		Return(suite.healthGate, nil)
+	suite.healthGate.EXPECT().
+		Check(gomock.Any(), gomock.Any()).
+		Return(nil)
+	gateFailed, err = suite.runHealthGates([]uint32{0, 1}, nil)
+	suite.NoError(err)
+	suite.False(gateFailed)
+}
+
+// TestProcessHealthGatesFailPauseNotCurrent tests that a failed health
+// gate does not pause another workflow than the current one of the job
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesFailPauseNotCurrent() {
+	gateConfig := suite.expectHealthGate(pbupdate.HealthGateConfig_PAUSE)
+
+	suite.healthGateFactory.EXPECT().
+		Create(gateConfig).
+		Return(suite.healthGate, nil)
+	suite.healthGate.EXPECT().
+		Check(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("error rate too high"))
+	suite.jobUpdateEventsOps.EXPECT().
+		CreateWithMessage(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.cachedUpdate.EXPECT().
+		WriteProgress(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			UpdateID: &peloton.UpdateID{Value: uuid.NewRandom().String()},
+		}, nil)
+
+	gateFailed, err := suite.runHealthGates([]uint32{0}, nil)
+	suite.NoError(err)
+	suite.True(gateFailed)
+}
+
+// TestProcessHealthGatesFailRollback tests that a failed health gate
+// configured to roll back rolls back the update
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesFailRollback() {
+	gateConfig := suite.expectHealthGate(pbupdate.HealthGateConfig_ROLLBACK)
+
+	suite.healthGateFactory.EXPECT().
+		Create(gateConfig).
+		Return(suite.healthGate, nil)
+	suite.healthGate.EXPECT().
+		Check(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("error rate too high"))
+
+	gomock.InOrder(
+		suite.jobUpdateEventsOps.EXPECT().
+			CreateWithMessage(
+				gomock.Any(),
+				suite.updateID,
+				models.WorkflowType_UPDATE,
+				pbupdate.State_ROLLING_FORWARD,
+				"health gate error-rate failed: error rate too high",
+			).
+			Return(nil),
+		suite.cachedUpdate.EXPECT().
+			WriteProgress(
+				gomock.Any(),
+				pbupdate.State_ROLLING_FORWARD,
+				[]uint32{0, 1},
+				[]uint32(nil),
+				[]uint32(nil),
+			).
+			Return(nil),
+		suite.cachedJob.EXPECT().
+			RollbackWorkflow(gomock.Any()).
+			Return(yarpcerrors.UnavailableErrorf("test error")),
+	)
+
+	gateFailed, err := suite.runHealthGates([]uint32{0, 1}, nil)
+	suite.Error(err)
+	suite.True(gateFailed)
+}
+
+// TestProcessHealthGatesInvalidGate tests that a health gate
+// which cannot be created is treated as failed
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesInvalidGate() {
+	gateConfig := suite.expectHealthGate(pbupdate.HealthGateConfig_PAUSE)
+
+	suite.healthGateFactory.EXPECT().
+		Create(gateConfig).
+		Return(nil, yarpcerrors.InvalidArgumentErrorf("no webhook url"))
+
+	suite.jobUpdateEventsOps.EXPECT().
+		CreateWithMessage(
+			gomock.Any(),
+			suite.updateID,
+			models.WorkflowType_UPDATE,
+			pbupdate.State_ROLLING_FORWARD,
+			gomock.Any(),
+		).
+		Return(nil)
+	suite.cachedUpdate.EXPECT().
+		WriteProgress(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.expectPause()
+
+	gateFailed, err := suite.runHealthGates([]uint32{0}, nil)
+	suite.NoError(err)
+	suite.True(gateFailed)
+}
+
+// TestProcessHealthGatesEventWriteFail tests that the update is
+// not paused if the reason of the failure cannot be recorded, and
+// that the failure is acted upon again in the next run
+func (suite *UpdateHealthGateTestSuite) TestProcessHealthGatesEventWriteFail() {
+	gateConfig := suite.expectHealthGate(pbupdate.HealthGateConfig_PAUSE)
+
+	suite.healthGateFactory.EXPECT().
+		Create(gateConfig).
+		Return(suite.healthGate, nil)
+	suite.healthGate.EXPECT().
+		Check(gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("error rate too high"))
+	gomock.InOrder(
+		suite.jobUpdateEventsOps.EXPECT().
+			CreateWithMessage(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(yarpcerrors.UnavailableErrorf("test error")),
+		suite.jobUpdateEventsOps.EXPECT().
+			CreateWithMessage(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(yarpcerrors.UnavailableErrorf("test error")),
+	)
+
+	gateFailed, err := suite.runHealthGates([]uint32{0}, nil)
+	suite.Error(err)
+	suite.True(gateFailed)
+
+	gateFailed, err = processHealthGates(
+		context.Background(),
+		suite.cachedJob,
+		suite.cachedUpdate,
+		[]uint32{0},
+		nil,
+		nil,
+		1,
+		suite.goalStateDriver,
+	)
+	suite.Error(err)
+	suite.True(gateFailed)
+}
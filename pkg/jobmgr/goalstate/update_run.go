@@ -78,6 +78,26 @@ func UpdateRun(ctx context.Context, entity goalstate.Entity) error {
 		cachedWorkflow.GetInstancesDone(),
 		instancesDoneFromLastRun...)
 
+	// the canary phase, if any, holds the rest of the update until
+	// the canary instances have baked and the canary is promoted
+	waitForCanary, err := processCanary(
+		ctx,
+		cachedJob,
+		cachedWorkflow,
+		instancesDone,
+		instancesFailed,
+		instancesCurrent,
+		goalStateDriver,
+	)
+	if err != nil {
+		goalStateDriver.mtx.updateMetrics.UpdateRunFail.Inc(1)
+		return err
+	}
+	if waitForCanary {
+		goalStateDriver.mtx.updateMetrics.UpdateRun.Inc(1)
+		return nil
+	}
+
 	// number of failed instances in the workflow exceeds limit and
 	// max instance retries is set, process the failed workflow and
 	// return directly
@@ -110,6 +130,26 @@ func UpdateRun(ctx context.Context, entity goalstate.Entity) error {
 			instancesFailed,
 		)
 
+	// the health gates, if any, must pass before the next batch is started
+	gateFailed, err := processHealthGates(
+		ctx,
+		cachedJob,
+		cachedWorkflow,
+		instancesDone,
+		instancesFailed,
+		instancesCurrent,
+		len(instancesToAdd)+len(instancesToUpdate)+len(instancesToRemove),
+		goalStateDriver,
+	)
+	if err != nil {
+		goalStateDriver.mtx.updateMetrics.UpdateRunFail.Inc(1)
+		return err
+	}
+	if gateFailed {
+		goalStateDriver.mtx.updateMetrics.UpdateRun.Inc(1)
+		return nil
+	}
+
 	instancesToAdd, instancesToUpdate, instancesToRemove, instancesRemovedDone, err :=
 		confirmInstancesStatus(
 			ctx,
@@ -202,53 +242,16 @@ func processFailedUpdate(
 	// the update itself is not a rollback
 	if cachedUpdate.GetUpdateConfig().RollbackOnFailure &&
 		!isUpdateRollback(cachedUpdate) {
-		// write the progress first, because when rollback happens,
-		// workflow does not know the newly finished/failed instances.
-		cachedUpdate.WriteProgress(
+		if err := rollbackUpdate(
 			ctx,
-			cachedUpdate.GetState().State,
+			cachedJob,
+			cachedUpdate,
 			instancesDone,
 			instancesFailed,
 			instancesCurrent,
-		)
-
-		if err := cachedJob.RollbackWorkflow(ctx); err != nil {
-			log.WithFields(log.Fields{
-				"update_id": cachedUpdate.ID().GetValue(),
-				"job_id":    cachedJob.ID().GetValue(),
-			}).WithError(err).
-				Info("fail to rollback update")
-			return err
-		}
-
-		cachedConfig, err := cachedJob.GetConfig(ctx)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"update_id": cachedUpdate.ID().GetValue(),
-				"job_id":    cachedJob.ID().GetValue(),
-			}).WithError(err).
-				Info("fail to get job config to rollback update")
-			return err
-		}
-
-		if err := handleUnchangedInstancesInUpdate(
-			ctx,
-			cachedUpdate,
-			cachedJob,
-			cachedConfig,
 		); err != nil {
-			log.WithFields(log.Fields{
-				"update_id": cachedUpdate.ID().GetValue(),
-				"job_id":    cachedJob.ID().GetValue(),
-			}).WithError(err).
-				Info("fail to update unchanged instances to rollback update")
 			return err
 		}
-
-		log.WithFields(log.Fields{
-			"update_id": cachedUpdate.ID().GetValue(),
-			"job_id":    cachedJob.ID().GetValue(),
-		}).Info("update rolling back")
 	} else {
 		if err := cachedUpdate.WriteProgress(
 			ctx,
@@ -265,6 +268,65 @@ func processFailedUpdate(
 	return nil
 }
 
+// rollbackUpdate rolls back the update to the previous job configuration
+func rollbackUpdate(
+	ctx context.Context,
+	cachedJob cached.Job,
+	cachedUpdate cached.Update,
+	instancesDone []uint32,
+	instancesFailed []uint32,
+	instancesCurrent []uint32,
+) error {
+	// write the progress first, because when rollback happens,
+	// workflow does not know the newly finished/failed instances.
+	cachedUpdate.WriteProgress(
+		ctx,
+		cachedUpdate.GetState().State,
+		instancesDone,
+		instancesFailed,
+		instancesCurrent,
+	)
+
+	if err := cachedJob.RollbackWorkflow(ctx); err != nil {
+		log.WithFields(log.Fields{
+			"update_id": cachedUpdate.ID().GetValue(),
+			"job_id":    cachedJob.ID().GetValue(),
+		}).WithError(err).
+			Info("fail to rollback update")
+		return err
+	}
+
+	cachedConfig, err := cachedJob.GetConfig(ctx)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"update_id": cachedUpdate.ID().GetValue(),
+			"job_id":    cachedJob.ID().GetValue(),
+		}).WithError(err).
+			Info("fail to get job config to rollback update")
+		return err
+	}
+
+	if err := handleUnchangedInstancesInUpdate(
+		ctx,
+		cachedUpdate,
+		cachedJob,
+		cachedConfig,
+	); err != nil {
+		log.WithFields(log.Fields{
+			"update_id": cachedUpdate.ID().GetValue(),
+			"job_id":    cachedJob.ID().GetValue(),
+		}).WithError(err).
+			Info("fail to update unchanged instances to rollback update")
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"update_id": cachedUpdate.ID().GetValue(),
+		"job_id":    cachedJob.ID().GetValue(),
+	}).Info("update rolling back")
+	return nil
+}
+
 // isUpdateRollback returns if an update is a rolling back to a
 // previous version
 func isUpdateRollback(cachedUpdate cached.Update) bool {
@@ -798,15 +860,32 @@ func getInstancesForUpdateRun(
 		)
 	}
 
+	canaryInProgress := isCanaryInProgress(update)
+	batchSize := update.GetUpdateConfig().GetBatchSize()
+
 	// if batch size is 0 or updateConfig is nil, update all of the instances
-	if update.GetUpdateConfig().GetBatchSize() == 0 {
+	if batchSize == 0 && !canaryInProgress {
 		return unprocessedInstancesToAdd,
 			unprocessedInstancesToUpdate,
 			unprocessedInstancesToRemove
 	}
 
-	maxNumOfInstancesToProcess :=
-		int(update.GetUpdateConfig().GetBatchSize()) - len(instancesCurrent)
+	maxNumOfInstancesToProcess := len(unprocessedInstancesToAdd) +
+		len(unprocessedInstancesToUpdate) +
+		len(unprocessedInstancesToRemove)
+	if batchSize != 0 {
+		maxNumOfInstancesToProcess = int(batchSize) - len(instancesCurrent)
+	}
+
+	// during the canary phase, only the canary instances can be processed
+	if canaryInProgress {
+		canaryInstancesRemaining := int(getCanarySize(update)) -
+			len(instancesDone) - len(instancesFailed) - len(instancesCurrent)
+		if canaryInstancesRemaining < maxNumOfInstancesToProcess {
+			maxNumOfInstancesToProcess = canaryInstancesRemaining
+		}
+	}
+
 	// if instances being updated are more than batch size, do not update anything
 	if maxNumOfInstancesToProcess <= 0 {
 		return nil, nil, nil
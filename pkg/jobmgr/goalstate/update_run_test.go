@@ -232,7 +232,7 @@ func (suite *UpdateRunTestSuite) TestRunningUpdate() {
 	suite.cachedUpdate.EXPECT().
 		GetUpdateConfig().
 		Return(updateConfig).
-		Times(3)
+		Times(5)
 
 	for _, instID := range instancesTotal {
 		suite.cachedJob.EXPECT().
@@ -867,7 +867,7 @@ func (suite *UpdateRunTestSuite) TestCompletedUpdate() {
 		Return(&pbupdate.UpdateConfig{
 			BatchSize: 0,
 		}).
-		Times(3)
+		Times(5)
 
 	suite.cachedJob.EXPECT().
 		ID().
@@ -1075,7 +1075,7 @@ func (suite *UpdateRunTestSuite) TestUpdateProgressDBError() {
 		Return(&pbupdate.UpdateConfig{
 			BatchSize: 0,
 		}).
-		Times(3)
+		Times(5)
 
 	for _, instID := range instancesTotal {
 		suite.taskStore.EXPECT().
@@ -2694,7 +2694,7 @@ func (suite *UpdateRunTestSuite) TestRunningUpdateFailed() {
 	suite.cachedUpdate.EXPECT().
 		GetUpdateConfig().
 		Return(updateConfig).
-		Times(4)
+		Times(5)
 
 	for i, instID := range instancesTotal {
 		if uint32(i) < failedInstances {
@@ -2826,7 +2826,7 @@ func (suite *UpdateRunTestSuite) TestRunningUpdateRolledBack() {
 	suite.cachedUpdate.EXPECT().
 		GetUpdateConfig().
 		Return(updateConfig).
-		Times(4)
+		Times(5)
 
 	for i, instID := range totalInstancesToUpdate {
 		if uint32(i) < failedInstances {
@@ -2987,7 +2987,7 @@ func (suite *UpdateRunTestSuite) TestRunningUpdateRolledBackFail() {
 	suite.cachedUpdate.EXPECT().
 		GetUpdateConfig().
 		Return(updateConfig).
-		Times(4)
+		Times(5)
 
 	for i, instID := range totalInstancesToUpdate {
 		if uint32(i) < failedInstances {
@@ -3125,7 +3125,7 @@ func (suite *UpdateRunTestSuite) TestUpdateRollingBackFailed() {
 	suite.cachedUpdate.EXPECT().
 		GetUpdateConfig().
 		Return(updateConfig).
-		Times(4)
+		Times(5)
 
 	for i, instID := range instancesTotal {
 		if uint32(i) < failedInstances {
@@ -3280,7 +3280,7 @@ func (suite *UpdateRunTestSuite) TestRunningUpdatePartialFailure() {
 	suite.cachedUpdate.EXPECT().
 		GetUpdateConfig().
 		Return(updateConfig).
-		Times(4)
+		Times(6)
 
 	for i, instID := range instancesTotal {
 		if uint32(i) < failedInstances {
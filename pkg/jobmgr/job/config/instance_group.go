@@ -0,0 +1,163 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobconfig
+
+import (
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// GetInstanceGroup returns the instance group which the instance
+// belongs to, or nil if the instance is not in any instance group
+func GetInstanceGroup(
+	groups []*job.InstanceGroup,
+	instanceID uint32,
+) *job.InstanceGroup {
+	for _, group := range groups {
+		for _, r := range group.GetRanges() {
+			if instanceID >= r.GetFrom() && instanceID < r.GetTo() {
+				return group
+			}
+		}
+	}
+	return nil
+}
+
+// GetInstanceGroupByName returns the instance group with the given name,
+// or nil if there is no such instance group
+func GetInstanceGroupByName(
+	groups []*job.InstanceGroup,
+	name string,
+) *job.InstanceGroup {
+	for _, group := range groups {
+		if group.GetName() == name {
+			return group
+		}
+	}
+	return nil
+}
+
+// GetInstanceGroupInstances returns the instances in an instance group
+func GetInstanceGroupInstances(group *job.InstanceGroup) []uint32 {
+	var instances []uint32
+	for _, r := range group.GetRanges() {
+		for i := r.GetFrom(); i < r.GetTo(); i++ {
+			instances = append(instances, i)
+		}
+	}
+	return instances
+}
+
+// GetDependentInstanceGroups returns the instance groups which depend on
+// the given instance group, directly or transitively
+func GetDependentInstanceGroups(
+	groups []*job.InstanceGroup,
+	name string,
+) []*job.InstanceGroup {
+	var result []*job.InstanceGroup
+	visited := map[string]bool{name: true}
+	pending := []string{name}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		for _, group := range groups {
+			if visited[group.GetName()] {
+				continue
+			}
+			for _, dependency := range group.GetDependsOn() {
+				if dependency == current {
+					visited[group.GetName()] = true
+					result = append(result, group)
+					pending = append(pending, group.GetName())
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// validateInstanceGroups validates the instance groups of a job
+func validateInstanceGroups(jobConfig *job.JobConfig) error {
+	groups := jobConfig.GetInstanceGroups()
+	if len(groups) == 0 {
+		return nil
+	}
+
+	if jobConfig.GetSLA().GetMaximumRunningInstances() != 0 {
+		return errInstanceGroupsWithMaxInstances
+	}
+
+	names := make(map[string]bool)
+	instances := make(map[uint32]string)
+	for _, group := range groups {
+		name := group.GetName()
+		if len(name) == 0 {
+			return errInstanceGroupNameMissing
+		}
+		if names[name] {
+			return yarpcerrors.InvalidArgumentErrorf(
+				"Instance group %s is specified more than once", name)
+		}
+		names[name] = true
+
+		for _, r := range group.GetRanges() {
+			if r.GetFrom() >= r.GetTo() ||
+				r.GetTo() > jobConfig.GetInstanceCount() {
+				return yarpcerrors.InvalidArgumentErrorf(
+					"Instance group %s has invalid range [%d, %d)",
+					name, r.GetFrom(), r.GetTo())
+			}
+			for i := r.GetFrom(); i < r.GetTo(); i++ {
+				if other, ok := instances[i]; ok {
+					return yarpcerrors.InvalidArgumentErrorf(
+						"Instance %d is in both instance group %s and %s",
+						i, other, name)
+				}
+				instances[i] = name
+			}
+		}
+	}
+
+	for _, group := range groups {
+		for _, dependency := range group.GetDependsOn() {
+			if !names[dependency] {
+				return yarpcerrors.InvalidArgumentErrorf(
+					"Instance group %s depends on unknown instance group %s",
+					group.GetName(), dependency)
+			}
+		}
+	}
+
+	// a group depending on itself, directly or transitively,
+	// would never be started
+	for _, group := range groups {
+		dependents := map[string]bool{group.GetName(): true}
+		for _, dependent := range GetDependentInstanceGroups(
+			groups, group.GetName()) {
+			dependents[dependent.GetName()] = true
+		}
+		for _, dependency := range group.GetDependsOn() {
+			if dependents[dependency] {
+				return yarpcerrors.InvalidArgumentErrorf(
+					"Instance group %s has a circular dependency",
+					group.GetName())
+			}
+		}
+	}
+
+	return nil
+}
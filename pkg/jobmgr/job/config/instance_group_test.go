@@ -0,0 +1,186 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobconfig
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func testInstanceGroups() []*job.InstanceGroup {
+	return []*job.InstanceGroup{
+		{
+			Name:   "ps",
+			Ranges: []*task.InstanceRange{{From: 0, To: 2}},
+		},
+		{
+			Name:      "worker",
+			Ranges:    []*task.InstanceRange{{From: 2, To: 8}},
+			DependsOn: []string{"ps"},
+		},
+		{
+			Name:      "evaluator",
+			Ranges:    []*task.InstanceRange{{From: 8, To: 9}},
+			DependsOn: []string{"worker"},
+		},
+	}
+}
+
+// TestGetInstanceGroup tests finding the instance group of an instance
+func TestGetInstanceGroup(t *testing.T) {
+	groups := testInstanceGroups()
+
+	assert.Equal(t, "ps", GetInstanceGroup(groups, 1).GetName())
+	assert.Equal(t, "worker", GetInstanceGroup(groups, 2).GetName())
+	assert.Equal(t, "evaluator", GetInstanceGroup(groups, 8).GetName())
+	assert.Nil(t, GetInstanceGroup(groups, 9))
+	assert.Nil(t, GetInstanceGroup(nil, 0))
+
+	assert.Equal(t, "worker", GetInstanceGroupByName(groups, "worker").GetName())
+	assert.Nil(t, GetInstanceGroupByName(groups, "unknown"))
+
+	assert.Equal(t,
+		[]uint32{0, 1},
+		GetInstanceGroupInstances(GetInstanceGroupByName(groups, "ps")))
+}
+
+// TestGetDependentInstanceGroups tests finding the instance groups
+// which depend on a instance group transitively
+func TestGetDependentInstanceGroups(t *testing.T) {
+	groups := testInstanceGroups()
+
+	dependents := GetDependentInstanceGroups(groups, "ps")
+	assert.Len(t, dependents, 2)
+	assert.Equal(t, "worker", dependents[0].GetName())
+	assert.Equal(t, "evaluator", dependents[1].GetName())
+
+	assert.Empty(t, GetDependentInstanceGroups(groups, "evaluator"))
+}
+
+// TestValidateInstanceGroups tests the validation of instance groups
+func TestValidateInstanceGroups(t *testing.T) {
+	assert.NoError(t, validateBatchJobConfig(&job.JobConfig{
+		InstanceCount:  10,
+		InstanceGroups: testInstanceGroups(),
+	}))
+
+	assert.Equal(t, errInstanceGroupsWithMaxInstances, validateBatchJobConfig(
+		&job.JobConfig{
+			InstanceCount:  10,
+			SLA:            &job.SlaConfig{MaximumRunningInstances: 2},
+			InstanceGroups: testInstanceGroups(),
+		}))
+
+	assert.Equal(t, errInstanceGroupNameMissing, validateBatchJobConfig(
+		&job.JobConfig{
+			InstanceCount: 10,
+			InstanceGroups: []*job.InstanceGroup{
+				{Ranges: []*task.InstanceRange{{From: 0, To: 2}}},
+			},
+		}))
+
+	testCases := []struct {
+		description string
+		groups      []*job.InstanceGroup
+	}{
+		{
+			description: "duplicate name",
+			groups: []*job.InstanceGroup{
+				{Name: "ps", Ranges: []*task.InstanceRange{{From: 0, To: 2}}},
+				{Name: "ps", Ranges: []*task.InstanceRange{{From: 2, To: 4}}},
+			},
+		},
+		{
+			description: "range beyond instance count",
+			groups: []*job.InstanceGroup{
+				{Name: "ps", Ranges: []*task.InstanceRange{{From: 0, To: 11}}},
+			},
+		},
+		{
+			description: "empty range",
+			groups: []*job.InstanceGroup{
+				{Name: "ps", Ranges: []*task.InstanceRange{{From: 2, To: 2}}},
+			},
+		},
+		{
+			description: "overlapping groups",
+			groups: []*job.InstanceGroup{
+				{Name: "ps", Ranges: []*task.InstanceRange{{From: 0, To: 3}}},
+				{Name: "worker", Ranges: []*task.InstanceRange{{From: 2, To: 4}}},
+			},
+		},
+		{
+			description: "unknown dependency",
+			groups: []*job.InstanceGroup{
+				{
+					Name:      "worker",
+					Ranges:    []*task.InstanceRange{{From: 0, To: 2}},
+					DependsOn: []string{"ps"},
+				},
+			},
+		},
+		{
+			description: "self dependency",
+			groups: []*job.InstanceGroup{
+				{
+					Name:      "ps",
+					Ranges:    []*task.InstanceRange{{From: 0, To: 2}},
+					DependsOn: []string{"ps"},
+				},
+			},
+		},
+		{
+			description: "circular dependency",
+			groups: []*job.InstanceGroup{
+				{
+					Name:      "ps",
+					Ranges:    []*task.InstanceRange{{From: 0, To: 2}},
+					DependsOn: []string{"evaluator"},
+				},
+				{
+					Name:      "worker",
+					Ranges:    []*task.InstanceRange{{From: 2, To: 4}},
+					DependsOn: []string{"ps"},
+				},
+				{
+					Name:      "evaluator",
+					Ranges:    []*task.InstanceRange{{From: 4, To: 5}},
+					DependsOn: []string{"worker"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		err := validateBatchJobConfig(&job.JobConfig{
+			InstanceCount:  10,
+			InstanceGroups: tc.groups,
+		})
+		assert.True(t, yarpcerrors.IsInvalidArgument(err), tc.description)
+	}
+
+	// stateless job does not support instance groups
+	assert.Equal(t, errInstanceGroupsNotSupported, validateStatelessJobConfig(
+		&job.JobConfig{
+			InstanceCount:  10,
+			DefaultConfig:  &task.TaskConfig{},
+			InstanceGroups: testInstanceGroups(),
+		}))
+}
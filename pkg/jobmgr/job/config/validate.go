@@ -62,6 +62,24 @@ var (
 		"Data field not set in executor config")
 	errIncorrectRevocableSLA = yarpcerrors.InvalidArgumentErrorf(
 		"revocable job must be preemptible")
+	errAutoscalingNotSupported = yarpcerrors.InvalidArgumentErrorf(
+		"Autoscaling is only supported for stateless job")
+	errIncorrectAutoscalingInstances = yarpcerrors.InvalidArgumentErrorf(
+		"Autoscaling maxInstances should be > 0 and >= minInstances")
+	errIncorrectAutoscalingMetric = yarpcerrors.InvalidArgumentErrorf(
+		"Autoscaling metric is not set")
+	errIncorrectAutoscalingTarget = yarpcerrors.InvalidArgumentErrorf(
+		"Autoscaling targetUtilization should be in (0, 1]")
+	errCompletionPolicyNotSupported = yarpcerrors.InvalidArgumentErrorf(
+		"Completion policy is only supported for batch job")
+	errIncorrectSuccessThreshold = yarpcerrors.InvalidArgumentErrorf(
+		"Completion policy successThreshold should be <= instanceCount")
+	errInstanceGroupsNotSupported = yarpcerrors.InvalidArgumentErrorf(
+		"Instance groups are only supported for batch job")
+	errInstanceGroupsWithMaxInstances = yarpcerrors.InvalidArgumentErrorf(
+		"Instance groups can't be used with MaximumRunningInstances")
+	errInstanceGroupNameMissing = yarpcerrors.InvalidArgumentErrorf(
+		"Instance group name is missing")
 	errInvalidPreemptionOverride = yarpcerrors.InvalidArgumentErrorf(
 		"can't override the preemption policy of a task" +
 			" which is going to be a part of a gang having tasks with" +
@@ -129,6 +147,11 @@ func ValidateUpdatedConfig(oldConfig *job.JobConfig,
 			fmt.Errorf(_updateNotSupported, "DefaultConfig"))
 	}
 
+	if !reflect.DeepEqual(oldConfig.InstanceGroups, newConfig.InstanceGroups) {
+		errs = multierror.Append(errs,
+			fmt.Errorf(_updateNotSupported, "InstanceGroups"))
+	}
+
 	if newConfig.InstanceCount < oldConfig.InstanceCount {
 		errs = multierror.Append(errs,
 			errors.New("new instance count can't be less"))
@@ -323,7 +346,14 @@ func validateBatchTaskConfig(taskConfig *task.TaskConfig) error {
 
 // validateBatchJobConfig validate jobconfig for batch job
 func validateBatchJobConfig(jobConfig *job.JobConfig) error {
-	return nil
+	if jobConfig.GetAutoscaling() != nil {
+		return errAutoscalingNotSupported
+	}
+	if jobConfig.GetCompletionPolicy().GetSuccessThreshold() >
+		jobConfig.GetInstanceCount() {
+		return errIncorrectSuccessThreshold
+	}
+	return validateInstanceGroups(jobConfig)
 }
 
 // validateStatelessTaskConfig validate task config for stateless job
@@ -369,5 +399,36 @@ func validateStatelessJobConfig(jobConfig *job.JobConfig) error {
 		return errIncorrectRevocableSLA
 	}
 
+	if jobConfig.GetCompletionPolicy() != nil {
+		return errCompletionPolicyNotSupported
+	}
+
+	if len(jobConfig.GetInstanceGroups()) != 0 {
+		return errInstanceGroupsNotSupported
+	}
+
+	return validateAutoscalingConfig(jobConfig.GetAutoscaling())
+}
+
+// validateAutoscalingConfig validates the autoscaling policy of a job
+func validateAutoscalingConfig(config *job.AutoscalingConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	if config.GetMaxInstances() == 0 ||
+		config.GetMinInstances() > config.GetMaxInstances() {
+		return errIncorrectAutoscalingInstances
+	}
+
+	if config.GetMetric() == job.AutoscalingConfig_INVALID {
+		return errIncorrectAutoscalingMetric
+	}
+
+	if config.GetTargetUtilization() <= 0 ||
+		config.GetTargetUtilization() > 1 {
+		return errIncorrectAutoscalingTarget
+	}
+
 	return nil
 }
@@ -551,6 +551,92 @@ func TestValidateStatelessJobConfig(t *testing.T) {
 
 }
 
+func TestValidateAutoscalingConfig(t *testing.T) {
+	validConfig := job.AutoscalingConfig{
+		MinInstances:      1,
+		MaxInstances:      10,
+		Metric:            job.AutoscalingConfig_CPU,
+		TargetUtilization: 0.5,
+	}
+
+	testCases := []struct {
+		update func(config *job.AutoscalingConfig)
+		error
+	}{
+		{
+			update: func(config *job.AutoscalingConfig) {
+				config.MaxInstances = 0
+				config.MinInstances = 0
+			},
+			error: errIncorrectAutoscalingInstances,
+		},
+		{
+			update: func(config *job.AutoscalingConfig) {
+				config.MinInstances = 11
+			},
+			error: errIncorrectAutoscalingInstances,
+		},
+		{
+			update: func(config *job.AutoscalingConfig) {
+				config.Metric = job.AutoscalingConfig_INVALID
+			},
+			error: errIncorrectAutoscalingMetric,
+		},
+		{
+			update: func(config *job.AutoscalingConfig) {
+				config.TargetUtilization = 0
+			},
+			error: errIncorrectAutoscalingTarget,
+		},
+		{
+			update: func(config *job.AutoscalingConfig) {
+				config.TargetUtilization = 1.5
+			},
+			error: errIncorrectAutoscalingTarget,
+		},
+		{
+			update: func(config *job.AutoscalingConfig) {},
+		},
+	}
+
+	for _, testCase := range testCases {
+		autoscaling := validConfig
+		testCase.update(&autoscaling)
+		jobConfig := job.JobConfig{
+			Name:          "TestJob_1",
+			InstanceCount: 10,
+			DefaultConfig: &task.TaskConfig{},
+			Autoscaling:   &autoscaling,
+		}
+		assert.Equal(t, testCase.error, validateStatelessJobConfig(&jobConfig))
+	}
+
+	// batch job does not support autoscaling
+	assert.Equal(t, errAutoscalingNotSupported, validateBatchJobConfig(
+		&job.JobConfig{Autoscaling: &validConfig}))
+}
+
+func TestValidateCompletionPolicy(t *testing.T) {
+	assert.NoError(t, validateBatchJobConfig(&job.JobConfig{
+		InstanceCount:    10,
+		CompletionPolicy: &job.CompletionPolicy{SuccessThreshold: 10},
+	}))
+
+	assert.Equal(t, errIncorrectSuccessThreshold, validateBatchJobConfig(
+		&job.JobConfig{
+			InstanceCount:    10,
+			CompletionPolicy: &job.CompletionPolicy{SuccessThreshold: 11},
+		}))
+
+	// stateless job does not support completion policy
+	assert.Equal(t, errCompletionPolicyNotSupported, validateStatelessJobConfig(
+		&job.JobConfig{
+			InstanceCount:    10,
+			DefaultConfig:    &task.TaskConfig{},
+			CompletionPolicy: &job.CompletionPolicy{MaxFailures: 1},
+		}))
+}
+
 func TestValidateStatelessTaskConfig(t *testing.T) {
 	testCases := []struct {
 		task.PreemptionPolicy
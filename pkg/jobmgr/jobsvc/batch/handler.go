@@ -0,0 +1,597 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"time"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch/svc"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	v1alphaquery "github.com/uber/peloton/.gen/peloton/api/v1alpha/query"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/api"
+	"github.com/uber/peloton/pkg/common/leader"
+	"github.com/uber/peloton/pkg/common/util"
+	versionutil "github.com/uber/peloton/pkg/common/util/entityversion"
+	yarpcutil "github.com/uber/peloton/pkg/common/util/yarpc"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+	"github.com/uber/peloton/pkg/jobmgr/goalstate"
+	jobconfig "github.com/uber/peloton/pkg/jobmgr/job/config"
+	"github.com/uber/peloton/pkg/jobmgr/jobsvc"
+	jobutil "github.com/uber/peloton/pkg/jobmgr/util/job"
+	"github.com/uber/peloton/pkg/storage"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/gocql/gocql"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type serviceHandler struct {
+	jobStore        storage.JobStore
+	taskStore       storage.TaskStore
+	activeJobsOps   ormobjects.ActiveJobsOps
+	jobIndexOps     ormobjects.JobIndexOps
+	jobConfigOps    ormobjects.JobConfigOps
+	jobRuntimeOps   ormobjects.JobRuntimeOps
+	respoolClient   respool.ResourceManagerYARPCClient
+	jobFactory      cached.JobFactory
+	goalStateDriver goalstate.Driver
+	candidate       leader.Candidate
+	jobSvcCfg       jobsvc.Config
+}
+
+var (
+	errNullResourcePoolID   = yarpcerrors.InvalidArgumentErrorf("resource pool ID is null")
+	errResourcePoolNotFound = yarpcerrors.NotFoundErrorf("resource pool not found")
+	errRootResourcePoolID   = yarpcerrors.InvalidArgumentErrorf("cannot submit jobs to the `root` resource pool")
+	errNonLeafResourcePool  = yarpcerrors.InvalidArgumentErrorf("cannot submit jobs to a non leaf resource pool")
+	errNotBatchJob          = yarpcerrors.InvalidArgumentErrorf("job is not a batch job")
+)
+
+// InitV1AlphaBatchJobServiceHandler initializes the Job Manager V1Alpha
+// Batch Job Service Handler
+func InitV1AlphaBatchJobServiceHandler(
+	d *yarpc.Dispatcher,
+	jobStore storage.JobStore,
+	taskStore storage.TaskStore,
+	ormStore *ormobjects.Store,
+	jobFactory cached.JobFactory,
+	goalStateDriver goalstate.Driver,
+	candidate leader.Candidate,
+	jobSvcCfg jobsvc.Config,
+) {
+	handler := &serviceHandler{
+		jobStore:      jobStore,
+		taskStore:     taskStore,
+		activeJobsOps: ormobjects.NewActiveJobsOps(ormStore),
+		jobIndexOps:   ormobjects.NewJobIndexOps(ormStore),
+		jobConfigOps:  ormobjects.NewJobConfigOps(ormStore),
+		jobRuntimeOps: ormobjects.NewJobRuntimeOps(ormStore),
+		respoolClient: respool.NewResourceManagerYARPCClient(
+			d.ClientConfig(common.PelotonResourceManager),
+		),
+		jobFactory:      jobFactory,
+		goalStateDriver: goalStateDriver,
+		candidate:       candidate,
+		jobSvcCfg:       jobSvcCfg,
+	}
+	d.Register(svc.BuildJobServiceYARPCProcedures(handler))
+}
+
+func (h *serviceHandler) CreateJob(
+	ctx context.Context,
+	req *svc.CreateJobRequest,
+) (resp *svc.CreateJobResponse, err error) {
+	defer func() {
+		jobID := req.GetJobId().GetValue()
+		instanceCount := req.GetSpec().GetInstanceCount()
+		headers := yarpcutil.GetHeaders(ctx)
+
+		if err != nil {
+			log.WithField("job_id", jobID).
+				WithField("instance_count", instanceCount).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("BatchJobSVC.CreateJob failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("job_id", jobID).
+			WithField("response", resp).
+			WithField("instance_count", instanceCount).
+			WithField("headers", headers).
+			Info("BatchJobSVC.CreateJob succeeded")
+	}()
+
+	if !h.candidate.IsLeader() {
+		return nil,
+			yarpcerrors.UnavailableErrorf("BatchJobSVC.CreateJob is not supported on non-leader")
+	}
+
+	pelotonJobID := &peloton.JobID{Value: req.GetJobId().GetValue()}
+
+	// It is possible that jobId is nil since protobuf doesn't enforce it
+	if len(pelotonJobID.GetValue()) == 0 {
+		pelotonJobID = &peloton.JobID{Value: uuid.New()}
+	}
+
+	if uuid.Parse(pelotonJobID.GetValue()) == nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf("jobID is not valid UUID")
+	}
+
+	respoolPath, err := h.validateResourcePoolForJobCreation(
+		ctx, req.GetSpec().GetRespoolId())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to validate resource pool")
+	}
+
+	jobConfig, err := api.ConvertBatchJobSpecToJobConfig(req.GetSpec())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert job spec")
+	}
+
+	// Validate job config with default task configs
+	err = jobconfig.ValidateConfig(
+		jobConfig,
+		h.jobSvcCfg.MaxTasksPerJob,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid job spec")
+	}
+
+	// Create job in cache and db
+	cachedJob := h.jobFactory.AddJob(pelotonJobID)
+
+	systemLabels := jobutil.ConstructSystemLabels(jobConfig, respoolPath.GetValue())
+	configAddOn := &models.ConfigAddOn{
+		SystemLabels: systemLabels,
+	}
+
+	err = cachedJob.Create(ctx, jobConfig, configAddOn, nil)
+
+	// enqueue the job into goal state engine even in failure case.
+	// Because the job may be partially created, let goal state
+	// engine decide what to do
+	h.goalStateDriver.EnqueueJob(pelotonJobID, time.Now())
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create job in db")
+	}
+
+	runtimeInfo, err := cachedJob.GetRuntime(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get job runtime from cache")
+	}
+
+	return &svc.CreateJobResponse{
+		JobId: &v1alphapeloton.JobID{Value: pelotonJobID.GetValue()},
+		Version: versionutil.GetJobEntityVersion(
+			runtimeInfo.GetConfigurationVersion(),
+			runtimeInfo.GetDesiredStateVersion(),
+			runtimeInfo.GetWorkflowVersion(),
+		),
+	}, nil
+}
+
+func (h *serviceHandler) GetJob(
+	ctx context.Context,
+	req *svc.GetJobRequest,
+) (resp *svc.GetJobResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("BatchJobSVC.GetJob failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("headers", headers).
+			Debug("BatchJobSVC.GetJob succeeded")
+	}()
+
+	pelotonJobID := &peloton.JobID{Value: req.GetJobId().GetValue()}
+
+	// Get the summary only
+	if req.GetSummaryOnly() {
+		jobSummary, err := h.jobIndexOps.GetSummary(ctx, pelotonJobID)
+		if err != nil {
+			if err == gocql.ErrNotFound {
+				return nil, yarpcerrors.NotFoundErrorf(
+					"job:%s not found", pelotonJobID.GetValue())
+			}
+			return nil, errors.Wrap(err, "failed to get job summary from DB")
+		}
+
+		if jobSummary.GetType() != pbjob.JobType_BATCH {
+			return nil, errNotBatchJob
+		}
+
+		return &svc.GetJobResponse{
+			Summary: api.ConvertJobSummaryToBatchJobSummary(jobSummary),
+		}, nil
+	}
+
+	jobRuntime, err := h.jobRuntimeOps.Get(ctx, pelotonJobID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get job status")
+	}
+
+	jobConfig, _, err := h.jobConfigOps.Get(
+		ctx,
+		pelotonJobID,
+		jobRuntime.GetConfigurationVersion(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get job spec")
+	}
+
+	if jobConfig.GetType() != pbjob.JobType_BATCH {
+		return nil, errNotBatchJob
+	}
+
+	// Do not display the secret volumes in defaultconfig.
+	// They should remain internal to peloton logic.
+	util.RemoveSecretVolumesFromJobConfig(jobConfig)
+
+	return &svc.GetJobResponse{
+		JobInfo: &batch.JobInfo{
+			JobId:  req.GetJobId(),
+			Spec:   api.ConvertJobConfigToBatchJobSpec(jobConfig),
+			Status: api.ConvertRuntimeInfoToBatchJobStatus(jobRuntime),
+		},
+	}, nil
+}
+
+// QueryJobs returns the batch jobs matching the query spec. The total
+// in the pagination result is computed before jobs of other types are
+// filtered out.
+func (h *serviceHandler) QueryJobs(
+	ctx context.Context,
+	req *svc.QueryJobsRequest,
+) (resp *svc.QueryJobsResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("BatchJobSVC.QueryJobs failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("headers", headers).
+			WithField("num_of_results", len(resp.GetRecords())).
+			Debug("BatchJobSVC.QueryJobs succeeded")
+	}()
+
+	var respoolID *peloton.ResourcePoolID
+	if len(req.GetSpec().GetRespool().GetValue()) > 0 {
+		respoolResp, err := h.respoolClient.LookupResourcePoolID(ctx, &respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: req.GetSpec().GetRespool().GetValue()},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get respool id")
+		}
+		respoolID = respoolResp.GetId()
+	}
+
+	// the job type is filtered by the store, so that the total and the
+	// pagination only count batch jobs
+	querySpec := api.ConvertBatchQuerySpecToJobQuerySpec(req.GetSpec())
+	querySpec.JobTypes = []pbjob.JobType{pbjob.JobType_BATCH}
+
+	_, jobSummaries, total, err := h.jobStore.QueryJobs(
+		ctx,
+		respoolID,
+		querySpec,
+		true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get job summaries")
+	}
+
+	var batchJobSummaries []*batch.JobSummary
+	for _, jobSummary := range jobSummaries {
+		batchJobSummaries = append(
+			batchJobSummaries,
+			api.ConvertJobSummaryToBatchJobSummary(jobSummary),
+		)
+	}
+
+	return &svc.QueryJobsResponse{
+		Records: batchJobSummaries,
+		Pagination: &v1alphaquery.Pagination{
+			Offset: req.GetSpec().GetPagination().GetOffset(),
+			Limit:  req.GetSpec().GetPagination().GetLimit(),
+			Total:  total,
+		},
+		Spec: req.GetSpec(),
+	}, nil
+}
+
+func (h *serviceHandler) KillJob(
+	ctx context.Context,
+	req *svc.KillJobRequest,
+) (resp *svc.KillJobResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("BatchJobSVC.KillJob failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("headers", headers).
+			WithField("response", resp).
+			Info("BatchJobSVC.KillJob succeeded")
+	}()
+
+	if !h.candidate.IsLeader() {
+		return nil,
+			yarpcerrors.UnavailableErrorf("BatchJobSVC.KillJob is not supported on non-leader")
+	}
+
+	cachedJob, err := h.getBatchJob(ctx, req.GetJobId())
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for {
+		jobRuntime, err := cachedJob.GetRuntime(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "fail to get runtime")
+		}
+
+		if err := validateEntityVersion(jobRuntime, req.GetVersion()); err != nil {
+			return nil, err
+		}
+
+		jobRuntime.GoalState = pbjob.JobState_KILLED
+		jobRuntime.DesiredStateVersion++
+
+		if jobRuntime, err = cachedJob.CompareAndSetRuntime(ctx, jobRuntime); err != nil {
+			if err == jobmgrcommon.UnexpectedVersionError {
+				// concurrency error; retry MaxConcurrencyErrorRetry times
+				count = count + 1
+				if count < jobmgrcommon.MaxConcurrencyErrorRetry {
+					continue
+				}
+			}
+			// it is uncertain whether job runtime is updated successfully,
+			// let goal state engine figure it out.
+			h.goalStateDriver.EnqueueJob(cachedJob.ID(), time.Now())
+			return nil, errors.Wrap(err, "fail to update job runtime")
+		}
+
+		h.goalStateDriver.EnqueueJob(cachedJob.ID(), time.Now())
+		return &svc.KillJobResponse{
+			Version: versionutil.GetJobEntityVersion(
+				jobRuntime.GetConfigurationVersion(),
+				jobRuntime.GetDesiredStateVersion(),
+				jobRuntime.GetWorkflowVersion(),
+			),
+		}, nil
+	}
+}
+
+func (h *serviceHandler) DeleteJob(
+	ctx context.Context,
+	req *svc.DeleteJobRequest,
+) (resp *svc.DeleteJobResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("BatchJobSVC.DeleteJob failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("headers", headers).
+			Info("BatchJobSVC.DeleteJob succeeded")
+	}()
+
+	if !h.candidate.IsLeader() {
+		return nil,
+			yarpcerrors.UnavailableErrorf("BatchJobSVC.DeleteJob is not supported on non-leader")
+	}
+
+	cachedJob, err := h.getBatchJob(ctx, req.GetJobId())
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for {
+		jobRuntime, err := cachedJob.GetRuntime(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get job runtime")
+		}
+
+		if err := validateEntityVersion(jobRuntime, req.GetVersion()); err != nil {
+			return nil, err
+		}
+
+		if !util.IsPelotonJobStateTerminal(jobRuntime.GetState()) {
+			return nil, yarpcerrors.FailedPreconditionErrorf(
+				"job is not in a terminal state: %s", jobRuntime.GetState())
+		}
+
+		jobRuntime.GoalState = pbjob.JobState_DELETED
+		jobRuntime.DesiredStateVersion++
+
+		if _, err = cachedJob.CompareAndSetRuntime(ctx, jobRuntime); err != nil {
+			if err == jobmgrcommon.UnexpectedVersionError {
+				// concurrency error; retry MaxConcurrencyErrorRetry times
+				count = count + 1
+				if count < jobmgrcommon.MaxConcurrencyErrorRetry {
+					continue
+				}
+			}
+			// it is uncertain whether job runtime is updated successfully,
+			// let goal state engine figure it out.
+			h.goalStateDriver.EnqueueJob(cachedJob.ID(), time.Now())
+			return nil, errors.Wrap(err, "fail to update job runtime")
+		}
+
+		h.goalStateDriver.EnqueueJob(cachedJob.ID(), time.Now())
+		return &svc.DeleteJobResponse{}, nil
+	}
+}
+
+func (h *serviceHandler) RestartFailedInstances(
+	ctx context.Context,
+	req *svc.RestartFailedInstancesRequest,
+) (resp *svc.RestartFailedInstancesResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("BatchJobSVC.RestartFailedInstances failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("headers", headers).
+			WithField("response", resp).
+			Info("BatchJobSVC.RestartFailedInstances succeeded")
+	}()
+
+	if !h.candidate.IsLeader() {
+		return nil,
+			yarpcerrors.UnavailableErrorf("BatchJobSVC.RestartFailedInstances is not supported on non-leader")
+	}
+
+	cachedJob := h.jobFactory.AddJob(&peloton.JobID{
+		Value: req.GetJobId().GetValue(),
+	})
+
+	jobRuntime, instanceIDs, err := jobutil.RestartFailedInstances(
+		ctx,
+		cachedJob,
+		req.GetVersion(),
+		h.taskStore,
+		h.activeJobsOps,
+		h.goalStateDriver,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &svc.RestartFailedInstancesResponse{
+		Version: versionutil.GetJobEntityVersion(
+			jobRuntime.GetConfigurationVersion(),
+			jobRuntime.GetDesiredStateVersion(),
+			jobRuntime.GetWorkflowVersion(),
+		),
+		InstanceIds: instanceIDs,
+	}, nil
+}
+
+// getBatchJob returns the cached job of a batch job, and an error
+// if the job is not a batch job
+func (h *serviceHandler) getBatchJob(
+	ctx context.Context,
+	jobID *v1alphapeloton.JobID,
+) (cached.Job, error) {
+	cachedJob := h.jobFactory.AddJob(&peloton.JobID{Value: jobID.GetValue()})
+	jobConfig, err := cachedJob.GetConfig(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get job config")
+	}
+
+	if jobConfig.GetType() != pbjob.JobType_BATCH {
+		return nil, errNotBatchJob
+	}
+	return cachedJob, nil
+}
+
+func (h *serviceHandler) validateResourcePoolForJobCreation(
+	ctx context.Context,
+	respoolID *v1alphapeloton.ResourcePoolID,
+) (*respool.ResourcePoolPath, error) {
+	if respoolID == nil {
+		return nil, errNullResourcePoolID
+	}
+
+	if respoolID.GetValue() == common.RootResPoolID {
+		return nil, errRootResourcePoolID
+	}
+
+	request := &respool.GetRequest{
+		Id: &peloton.ResourcePoolID{Value: respoolID.GetValue()},
+	}
+	response, err := h.respoolClient.GetResourcePool(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.GetPoolinfo().GetId() == nil ||
+		response.GetPoolinfo().GetId().GetValue() != respoolID.GetValue() {
+		return nil, errResourcePoolNotFound
+	}
+
+	if len(response.GetPoolinfo().GetChildren()) > 0 {
+		return nil, errNonLeafResourcePool
+	}
+
+	return response.GetPoolinfo().GetPath(), nil
+}
+
+// validateEntityVersion returns an error if the entity version
+// provided does not match the current version of the job
+func validateEntityVersion(
+	jobRuntime *pbjob.RuntimeInfo,
+	version *v1alphapeloton.EntityVersion,
+) error {
+	entityVersion := versionutil.GetJobEntityVersion(
+		jobRuntime.GetConfigurationVersion(),
+		jobRuntime.GetDesiredStateVersion(),
+		jobRuntime.GetWorkflowVersion(),
+	)
+	if entityVersion.GetValue() != version.GetValue() {
+		return jobmgrcommon.InvalidEntityVersionError
+	}
+	return nil
+}
@@ -0,0 +1,372 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"testing"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/batch/svc"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	v1alphaquery "github.com/uber/peloton/.gen/peloton/api/v1alpha/query"
+
+	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	leadermocks "github.com/uber/peloton/pkg/common/leader/mocks"
+	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
+	goalstatemocks "github.com/uber/peloton/pkg/jobmgr/goalstate/mocks"
+	storemocks "github.com/uber/peloton/pkg/storage/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/gocql/gocql"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	testJobID         = "481d565e-28da-457d-8434-f6bb7faa0e95"
+	testEntityVersion = "2-3-4"
+)
+
+type batchHandlerTestSuite struct {
+	suite.Suite
+
+	handler *serviceHandler
+
+	ctrl            *gomock.Controller
+	cachedJob       *cachedmocks.MockJob
+	cachedConfig    *cachedmocks.MockJobConfigCache
+	jobFactory      *cachedmocks.MockJobFactory
+	candidate       *leadermocks.MockCandidate
+	respoolClient   *respoolmocks.MockResourceManagerYARPCClient
+	goalStateDriver *goalstatemocks.MockDriver
+	jobStore        *storemocks.MockJobStore
+	taskStore       *storemocks.MockTaskStore
+	activeJobsOps   *objectmocks.MockActiveJobsOps
+	jobIndexOps     *objectmocks.MockJobIndexOps
+	jobConfigOps    *objectmocks.MockJobConfigOps
+	jobRuntimeOps   *objectmocks.MockJobRuntimeOps
+}
+
+func (suite *batchHandlerTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.cachedJob = cachedmocks.NewMockJob(suite.ctrl)
+	suite.cachedConfig = cachedmocks.NewMockJobConfigCache(suite.ctrl)
+	suite.jobFactory = cachedmocks.NewMockJobFactory(suite.ctrl)
+	suite.candidate = leadermocks.NewMockCandidate(suite.ctrl)
+	suite.respoolClient = respoolmocks.NewMockResourceManagerYARPCClient(suite.ctrl)
+	suite.goalStateDriver = goalstatemocks.NewMockDriver(suite.ctrl)
+	suite.jobStore = storemocks.NewMockJobStore(suite.ctrl)
+	suite.taskStore = storemocks.NewMockTaskStore(suite.ctrl)
+	suite.activeJobsOps = objectmocks.NewMockActiveJobsOps(suite.ctrl)
+	suite.jobIndexOps = objectmocks.NewMockJobIndexOps(suite.ctrl)
+	suite.jobConfigOps = objectmocks.NewMockJobConfigOps(suite.ctrl)
+	suite.jobRuntimeOps = objectmocks.NewMockJobRuntimeOps(suite.ctrl)
+	suite.handler = &serviceHandler{
+		jobStore:        suite.jobStore,
+		taskStore:       suite.taskStore,
+		activeJobsOps:   suite.activeJobsOps,
+		jobIndexOps:     suite.jobIndexOps,
+		jobConfigOps:    suite.jobConfigOps,
+		jobRuntimeOps:   suite.jobRuntimeOps,
+		respoolClient:   suite.respoolClient,
+		jobFactory:      suite.jobFactory,
+		goalStateDriver: suite.goalStateDriver,
+		candidate:       suite.candidate,
+	}
+}
+
+func (suite *batchHandlerTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestBatchServiceHandler(t *testing.T) {
+	suite.Run(t, new(batchHandlerTestSuite))
+}
+
+// expectCachedJob sets up the expectations for looking up a cached job
+// of the given type
+func (suite *batchHandlerTestSuite) expectCachedJob(jobType pbjob.JobType) {
+	suite.jobFactory.EXPECT().
+		AddJob(&peloton.JobID{Value: testJobID}).
+		Return(suite.cachedJob)
+	suite.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(suite.cachedConfig, nil)
+	suite.cachedConfig.EXPECT().
+		GetType().
+		Return(jobType)
+}
+
+// TestGetJobSummarySuccess tests getting the summary of a batch job
+func (suite *batchHandlerTestSuite) TestGetJobSummarySuccess() {
+	suite.jobIndexOps.EXPECT().
+		GetSummary(gomock.Any(), &peloton.JobID{Value: testJobID}).
+		Return(&pbjob.JobSummary{
+			Id:            &peloton.JobID{Value: testJobID},
+			Name:          "test-job",
+			Type:          pbjob.JobType_BATCH,
+			InstanceCount: 3,
+		}, nil)
+
+	resp, err := suite.handler.GetJob(context.Background(), &svc.GetJobRequest{
+		JobId:       &v1alphapeloton.JobID{Value: testJobID},
+		SummaryOnly: true,
+	})
+	suite.NoError(err)
+	suite.Equal("test-job", resp.GetSummary().GetName())
+	suite.Equal(uint32(3), resp.GetSummary().GetInstanceCount())
+	suite.Nil(resp.GetJobInfo())
+}
+
+// TestGetJobSummaryNotFound tests getting the summary of a job
+// which does not exist
+func (suite *batchHandlerTestSuite) TestGetJobSummaryNotFound() {
+	suite.jobIndexOps.EXPECT().
+		GetSummary(gomock.Any(), &peloton.JobID{Value: testJobID}).
+		Return(nil, gocql.ErrNotFound)
+
+	_, err := suite.handler.GetJob(context.Background(), &svc.GetJobRequest{
+		JobId:       &v1alphapeloton.JobID{Value: testJobID},
+		SummaryOnly: true,
+	})
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestGetJobNotBatchJob tests getting a job which is not a batch job
+func (suite *batchHandlerTestSuite) TestGetJobNotBatchJob() {
+	suite.jobRuntimeOps.EXPECT().
+		Get(gomock.Any(), &peloton.JobID{Value: testJobID}).
+		Return(&pbjob.RuntimeInfo{ConfigurationVersion: 2}, nil)
+	suite.jobConfigOps.EXPECT().
+		Get(gomock.Any(), &peloton.JobID{Value: testJobID}, uint64(2)).
+		Return(&pbjob.JobConfig{Type: pbjob.JobType_SERVICE}, nil, nil)
+
+	_, err := suite.handler.GetJob(context.Background(), &svc.GetJobRequest{
+		JobId: &v1alphapeloton.JobID{Value: testJobID},
+	})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestGetJobSuccess tests getting the spec and status of a batch job
+func (suite *batchHandlerTestSuite) TestGetJobSuccess() {
+	suite.jobRuntimeOps.EXPECT().
+		Get(gomock.Any(), &peloton.JobID{Value: testJobID}).
+		Return(&pbjob.RuntimeInfo{
+			State:                pbjob.JobState_RUNNING,
+			ConfigurationVersion: 2,
+		}, nil)
+	suite.jobConfigOps.EXPECT().
+		Get(gomock.Any(), &peloton.JobID{Value: testJobID}, uint64(2)).
+		Return(&pbjob.JobConfig{
+			Type:          pbjob.JobType_BATCH,
+			InstanceCount: 5,
+		}, nil, nil)
+
+	resp, err := suite.handler.GetJob(context.Background(), &svc.GetJobRequest{
+		JobId: &v1alphapeloton.JobID{Value: testJobID},
+	})
+	suite.NoError(err)
+	suite.Equal(uint32(5), resp.GetJobInfo().GetSpec().GetInstanceCount())
+	suite.Equal(
+		batch.JobState_JOB_STATE_RUNNING,
+		resp.GetJobInfo().GetStatus().GetState(),
+	)
+}
+
+// TestQueryJobsBatchJobsOnly tests that query looks up batch jobs only
+func (suite *batchHandlerTestSuite) TestQueryJobsBatchJobsOnly() {
+	suite.jobStore.EXPECT().
+		QueryJobs(gomock.Any(), nil, gomock.Any(), true).
+		Do(func(
+			_ context.Context,
+			_ *peloton.ResourcePoolID,
+			spec *pbjob.QuerySpec,
+			_ bool) {
+			suite.Equal([]pbjob.JobType{pbjob.JobType_BATCH}, spec.GetJobTypes())
+		}).
+		Return(nil, []*pbjob.JobSummary{
+			{Id: &peloton.JobID{Value: testJobID}, Type: pbjob.JobType_BATCH},
+		}, uint32(1), nil)
+
+	resp, err := suite.handler.QueryJobs(context.Background(), &svc.QueryJobsRequest{
+		Spec: &batch.QuerySpec{
+			Pagination: &v1alphaquery.PaginationSpec{Limit: 10},
+		},
+	})
+	suite.NoError(err)
+	suite.Len(resp.GetRecords(), 1)
+	suite.Equal(testJobID, resp.GetRecords()[0].GetJobId().GetValue())
+	suite.Equal(uint32(1), resp.GetPagination().GetTotal())
+}
+
+// TestKillJobNonLeader tests killing a job on a non-leader
+func (suite *batchHandlerTestSuite) TestKillJobNonLeader() {
+	suite.candidate.EXPECT().IsLeader().Return(false)
+
+	_, err := suite.handler.KillJob(context.Background(), &svc.KillJobRequest{
+		JobId:   &v1alphapeloton.JobID{Value: testJobID},
+		Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+	})
+	suite.True(yarpcerrors.IsUnavailable(err))
+}
+
+// TestKillJobSuccess tests killing a batch job
+func (suite *batchHandlerTestSuite) TestKillJobSuccess() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.expectCachedJob(pbjob.JobType_BATCH)
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:                pbjob.JobState_RUNNING,
+			GoalState:            pbjob.JobState_SUCCEEDED,
+			ConfigurationVersion: 2,
+			DesiredStateVersion:  3,
+			WorkflowVersion:      4,
+		}, nil)
+	suite.cachedJob.EXPECT().
+		CompareAndSetRuntime(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			runtime *pbjob.RuntimeInfo,
+		) (*pbjob.RuntimeInfo, error) {
+			suite.Equal(pbjob.JobState_KILLED, runtime.GetGoalState())
+			suite.Equal(uint64(4), runtime.GetDesiredStateVersion())
+			return runtime, nil
+		})
+	suite.cachedJob.EXPECT().ID().Return(&peloton.JobID{Value: testJobID})
+	suite.goalStateDriver.EXPECT().EnqueueJob(gomock.Any(), gomock.Any())
+
+	resp, err := suite.handler.KillJob(context.Background(), &svc.KillJobRequest{
+		JobId:   &v1alphapeloton.JobID{Value: testJobID},
+		Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+	})
+	suite.NoError(err)
+	suite.Equal("2-4-4", resp.GetVersion().GetValue())
+}
+
+// TestKillJobInvalidEntityVersion tests killing a batch job with
+// a stale entity version
+func (suite *batchHandlerTestSuite) TestKillJobInvalidEntityVersion() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.expectCachedJob(pbjob.JobType_BATCH)
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			ConfigurationVersion: 2,
+			DesiredStateVersion:  5,
+			WorkflowVersion:      4,
+		}, nil)
+
+	_, err := suite.handler.KillJob(context.Background(), &svc.KillJobRequest{
+		JobId:   &v1alphapeloton.JobID{Value: testJobID},
+		Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+	})
+	suite.Equal(jobmgrcommon.InvalidEntityVersionError, err)
+}
+
+// TestKillJobNotBatchJob tests killing a job which is not a batch job
+func (suite *batchHandlerTestSuite) TestKillJobNotBatchJob() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.expectCachedJob(pbjob.JobType_SERVICE)
+
+	_, err := suite.handler.KillJob(context.Background(), &svc.KillJobRequest{
+		JobId:   &v1alphapeloton.JobID{Value: testJobID},
+		Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+	})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestDeleteJobNotTerminal tests deleting a batch job which is
+// not in a terminal state
+func (suite *batchHandlerTestSuite) TestDeleteJobNotTerminal() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.expectCachedJob(pbjob.JobType_BATCH)
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:                pbjob.JobState_RUNNING,
+			ConfigurationVersion: 2,
+			DesiredStateVersion:  3,
+			WorkflowVersion:      4,
+		}, nil)
+
+	_, err := suite.handler.DeleteJob(context.Background(), &svc.DeleteJobRequest{
+		JobId:   &v1alphapeloton.JobID{Value: testJobID},
+		Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+	})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+}
+
+// TestDeleteJobSuccess tests deleting a terminal batch job
+func (suite *batchHandlerTestSuite) TestDeleteJobSuccess() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.expectCachedJob(pbjob.JobType_BATCH)
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:                pbjob.JobState_SUCCEEDED,
+			GoalState:            pbjob.JobState_SUCCEEDED,
+			ConfigurationVersion: 2,
+			DesiredStateVersion:  3,
+			WorkflowVersion:      4,
+		}, nil)
+	suite.cachedJob.EXPECT().
+		CompareAndSetRuntime(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			runtime *pbjob.RuntimeInfo,
+		) (*pbjob.RuntimeInfo, error) {
+			suite.Equal(pbjob.JobState_DELETED, runtime.GetGoalState())
+			return runtime, nil
+		})
+	suite.cachedJob.EXPECT().ID().Return(&peloton.JobID{Value: testJobID})
+	suite.goalStateDriver.EXPECT().EnqueueJob(gomock.Any(), gomock.Any())
+
+	_, err := suite.handler.DeleteJob(context.Background(), &svc.DeleteJobRequest{
+		JobId:   &v1alphapeloton.JobID{Value: testJobID},
+		Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+	})
+	suite.NoError(err)
+}
+
+// TestRestartFailedInstancesNonLeader tests restarting failed instances
+// on a non-leader
+func (suite *batchHandlerTestSuite) TestRestartFailedInstancesNonLeader() {
+	suite.candidate.EXPECT().IsLeader().Return(false)
+
+	_, err := suite.handler.RestartFailedInstances(
+		context.Background(),
+		&svc.RestartFailedInstancesRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+		})
+	suite.True(yarpcerrors.IsUnavailable(err))
+}
+
+// TestCreateJobNullRespool tests creating a batch job without a respool
+func (suite *batchHandlerTestSuite) TestCreateJobNullRespool() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+
+	_, err := suite.handler.CreateJob(context.Background(), &svc.CreateJobRequest{
+		JobId: &v1alphapeloton.JobID{Value: testJobID},
+		Spec:  &batch.JobSpec{InstanceCount: 1},
+	})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
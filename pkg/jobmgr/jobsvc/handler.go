@@ -39,6 +39,7 @@ import (
 	"github.com/uber/peloton/pkg/jobmgr/cached"
 	"github.com/uber/peloton/pkg/jobmgr/goalstate"
 	"github.com/uber/peloton/pkg/jobmgr/job/config"
+	jobmgrsecret "github.com/uber/peloton/pkg/jobmgr/secret"
 	jobmgrtask "github.com/uber/peloton/pkg/jobmgr/task"
 	"github.com/uber/peloton/pkg/jobmgr/util/handler"
 	jobutil "github.com/uber/peloton/pkg/jobmgr/util/job"
@@ -72,12 +73,14 @@ func InitServiceHandler(
 	goalStateDriver goalstate.Driver,
 	candidate leader.Candidate,
 	clientName string,
-	jobSvcCfg Config) {
+	jobSvcCfg Config,
+	secretProvider jobmgrsecret.Provider) {
 
 	jobSvcCfg.normalize()
 	handler := &serviceHandler{
 		jobStore:        jobStore,
 		taskStore:       taskStore,
+		activeJobsOps:   ormobjects.NewActiveJobsOps(ormStore),
 		jobIndexOps:     ormobjects.NewJobIndexOps(ormStore),
 		jobConfigOps:    ormobjects.NewJobConfigOps(ormStore),
 		jobRuntimeOps:   ormobjects.NewJobRuntimeOps(ormStore),
@@ -90,6 +93,7 @@ func InitServiceHandler(
 		candidate:       candidate,
 		metrics:         NewMetrics(parent.SubScope("jobmgr").SubScope("job")),
 		jobSvcCfg:       jobSvcCfg,
+		secretProvider:  secretProvider,
 	}
 
 	d.Register(job.BuildJobManagerYARPCProcedures(handler))
@@ -112,6 +116,7 @@ type serviceHandler struct {
 	candidate       leader.Candidate
 	metrics         *Metrics
 	jobSvcCfg       Config
+	secretProvider  jobmgrsecret.Provider
 }
 
 // Create creates a job object for a given job configuration and
@@ -605,14 +610,19 @@ func (h *serviceHandler) Query(ctx context.Context, req *job.QueryRequest) (resp
 		}, nil
 	}
 
+	// the spec was validated by the query, including its page token
+	offset, _ := ormobjects.JobQueryOffset(req.GetSpec().GetPagination())
+
 	h.metrics.JobQuery.Inc(1)
 	resp = &job.QueryResponse{
 		Records: jobConfigs,
 		Results: jobSummary,
 		Pagination: &query.Pagination{
-			Offset: req.GetSpec().GetPagination().GetOffset(),
+			Offset: offset,
 			Limit:  req.GetSpec().GetPagination().GetLimit(),
 			Total:  total,
+			NextPageToken: ormobjects.NextJobQueryPageToken(
+				offset, uint32(len(jobSummary)), total),
 		},
 		Spec: req.GetSpec(),
 	}
@@ -1009,6 +1019,58 @@ func (h *serviceHandler) GetActiveJobs(
 	}, nil
 }
 
+// RestartFailedInstances restarts the FAILED and LOST instances of a
+// terminal batch job and moves the job back to an active state
+func (h *serviceHandler) RestartFailedInstances(
+	ctx context.Context,
+	req *job.RestartFailedInstancesRequest,
+) (resp *job.RestartFailedInstancesResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		jobID := req.GetId().GetValue()
+
+		if err != nil {
+			log.WithField("job_id", jobID).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("JobManager.RestartFailedInstances failed")
+			return
+		}
+
+		log.WithField("job_id", jobID).
+			WithField("headers", headers).
+			WithField("instance_ids", resp.GetInstanceIds()).
+			Info("JobManager.RestartFailedInstances succeeded")
+	}()
+
+	h.metrics.JobAPIRestartFailedInstances.Inc(1)
+
+	if !h.candidate.IsLeader() {
+		h.metrics.JobRestartFailedInstancesFail.Inc(1)
+		return nil, yarpcerrors.UnavailableErrorf(
+			"Job RestartFailedInstances API not suppported on non-leader")
+	}
+
+	cachedJob := h.jobFactory.AddJob(req.GetId())
+	_, instanceIDs, err := jobutil.RestartFailedInstances(
+		ctx,
+		cachedJob,
+		nil,
+		h.taskStore,
+		h.activeJobsOps,
+		h.goalStateDriver,
+	)
+	if err != nil {
+		h.metrics.JobRestartFailedInstancesFail.Inc(1)
+		return nil, err
+	}
+
+	h.metrics.JobRestartFailedInstances.Inc(1)
+	return &job.RestartFailedInstancesResponse{
+		InstanceIds: instanceIDs,
+	}, nil
+}
+
 // validateResourcePool validates the resource pool before submitting job
 func (h *serviceHandler) validateResourcePool(
 	respoolID *peloton.ResourcePoolID,
@@ -1077,6 +1139,23 @@ func (h *serviceHandler) validateSecretsAndConfig(
 			return yarpcerrors.InvalidArgumentErrorf(
 				"secret does not have a path")
 		}
+		// The data of secrets referencing an external secret store
+		// is managed by that store.
+		if ref := jobmgrsecret.ParseReference(
+			secret.GetId().GetValue()); ref.IsExternal() {
+			if len(secret.GetValue().GetData()) != 0 {
+				return yarpcerrors.InvalidArgumentErrorf(
+					"secret %s from an external secret store cannot have a value",
+					secret.GetId().GetValue())
+			}
+			// jobmgr reads the secret on behalf of the job, make sure
+			// that the owner of the job may reference it
+			if err := h.secretProvider.CheckAccess(
+				ref, config.GetOwner(), config.GetOwningTeam()); err != nil {
+				return err
+			}
+			continue
+		}
 		// Validate that secret is base64 encoded
 		_, err := base64.StdEncoding.DecodeString(
 			string(secret.GetValue().GetData()))
@@ -1168,8 +1247,15 @@ func (h *serviceHandler) addSecretsToDBAndConfig(
 			log.WithField("job_id", secret.GetId().GetValue()).
 				Info("Genarating UUID for empty secret ID")
 		}
-		// store secret in DB
-		if update {
+		// secrets from an external secret store are not stored in DB,
+		// only make sure that they can be read at launch time
+		if ref := jobmgrsecret.ParseReference(
+			secret.GetId().GetValue()); ref.IsExternal() {
+			if _, err := h.secretProvider.GetSecret(ctx, ref); err != nil {
+				return errors.Wrapf(err,
+					"failed to read secret %s", secret.GetId().GetValue())
+			}
+		} else if update {
 			if err := h.secretInfoOps.UpdateSecretData(
 				ctx,
 				jobID.GetValue(),
@@ -25,6 +25,7 @@ import (
 	apierrors "github.com/uber/peloton/.gen/peloton/api/v0/errors"
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
 	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
 	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
@@ -42,8 +43,11 @@ import (
 	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
 	cachedtest "github.com/uber/peloton/pkg/jobmgr/cached/test"
 	goalstatemocks "github.com/uber/peloton/pkg/jobmgr/goalstate/mocks"
+	jobmgrsecret "github.com/uber/peloton/pkg/jobmgr/secret"
+	secretmocks "github.com/uber/peloton/pkg/jobmgr/secret/mocks"
 	jobmgrtask "github.com/uber/peloton/pkg/jobmgr/task"
 	storemocks "github.com/uber/peloton/pkg/storage/mocks"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
 	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
 
 	"github.com/golang/mock/gomock"
@@ -97,6 +101,7 @@ type JobHandlerTestSuite struct {
 	mockedSecretInfoOps   *objectmocks.MockSecretInfoOps
 	mockedJobConfigOps    *objectmocks.MockJobConfigOps
 	mockedJobRuntimeOps   *objectmocks.MockJobRuntimeOps
+	mockedSecretProvider  *secretmocks.MockProvider
 }
 
 // helper to initialize mocks in JobHandlerTestSuite
@@ -117,6 +122,7 @@ func (suite *JobHandlerTestSuite) initializeMocks() {
 	suite.mockedSecretInfoOps = objectmocks.NewMockSecretInfoOps(suite.ctrl)
 	suite.mockedJobConfigOps = objectmocks.NewMockJobConfigOps(suite.ctrl)
 	suite.mockedJobRuntimeOps = objectmocks.NewMockJobRuntimeOps(suite.ctrl)
+	suite.mockedSecretProvider = secretmocks.NewMockProvider(suite.ctrl)
 
 	suite.handler.jobStore = suite.mockedJobStore
 	suite.handler.taskStore = suite.mockedTaskStore
@@ -130,6 +136,7 @@ func (suite *JobHandlerTestSuite) initializeMocks() {
 	suite.handler.respoolClient = suite.mockedRespoolClient
 	suite.handler.resmgrClient = suite.mockedResmgrClient
 	suite.handler.candidate = suite.mockedCandidate
+	suite.handler.secretProvider = suite.mockedSecretProvider
 	suite.handler.jobSvcCfg.EnableSecrets = true
 }
 
@@ -835,6 +842,83 @@ func (suite *JobHandlerTestSuite) TestCreateJobWithSecrets() {
 	suite.Error(err)
 }
 
+// TestCreateJobWithExternalSecrets tests creating a job with a secret held
+// by an external secret store, which is referenced instead of stored in DB
+func (suite *JobHandlerTestSuite) TestCreateJobWithExternalSecrets() {
+	testCmd := "echo test"
+	jobID := &peloton.JobID{
+		Value: uuid.New(),
+	}
+	respoolID := &peloton.ResourcePoolID{
+		Value: "test-respool",
+	}
+	mesosContainerizer := mesos.ContainerInfo_MESOS
+	jobConfig := &job.JobConfig{
+		OwningTeam: "team1",
+		RespoolID:  respoolID,
+		DefaultConfig: &task.TaskConfig{
+			Command:   &mesos.CommandInfo{Value: &testCmd},
+			Container: &mesos.ContainerInfo{Type: &mesosContainerizer},
+		},
+	}
+	secret := jobmgrtask.CreateSecretProto(
+		"vault:db/password@3", testSecretPath, nil)
+	ref := &jobmgrsecret.Reference{
+		Store:   "vault",
+		Path:    "db/password",
+		Version: "3",
+	}
+
+	suite.setupMocks(jobID, respoolID)
+
+	suite.mockedSecretProvider.EXPECT().
+		CheckAccess(ref, "", "team1").
+		Return(nil)
+	suite.mockedSecretProvider.EXPECT().
+		GetSecret(gomock.Any(), ref).
+		Return(&jobmgrsecret.Secret{
+			Data:    []byte(testSecretStr),
+			Version: "3",
+		}, nil)
+	suite.mockedCachedJob.EXPECT().
+		Create(gomock.Any(), gomock.Any(), gomock.Any(), nil).
+		Do(func(
+			_ context.Context,
+			config *job.JobConfig,
+			_ *models.ConfigAddOn,
+			_ *stateless.JobSpec) {
+			volumes := config.GetDefaultConfig().GetContainer().GetVolumes()
+			suite.Len(volumes, 1)
+			suite.Equal(
+				"vault:db/password@3",
+				string(volumes[0].GetSource().GetSecret().GetValue().GetData()))
+		}).
+		Return(nil)
+
+	req := &job.CreateRequest{
+		Id:      jobID,
+		Config:  jobConfig,
+		Secrets: []*peloton.Secret{secret},
+	}
+	resp, err := suite.handler.Create(suite.context, req)
+	suite.NoError(err)
+	suite.Equal(jobID, resp.GetJobId())
+
+	// the owner of the job may not reference the secret
+	jobConfig.GetDefaultConfig().GetContainer().Volumes = nil
+	suite.mockedSecretProvider.EXPECT().
+		CheckAccess(ref, "", "team1").
+		Return(yarpcerrors.PermissionDeniedErrorf("not allowed"))
+	_, err = suite.handler.Create(suite.context, req)
+	suite.True(yarpcerrors.IsPermissionDenied(err))
+
+	// secrets from an external secret store cannot have a value
+	secret.Value.Data = []byte(
+		base64.StdEncoding.EncodeToString([]byte(testSecretStr)))
+	_, err = suite.handler.Create(suite.context, req)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
 func (suite *JobHandlerTestSuite) TestSubmitTasksToResmgr() {
 	var tasksInfo []*task.TaskInfo
 	for _, v := range suite.taskInfos {
@@ -1719,6 +1803,46 @@ func (suite *JobHandlerTestSuite) TestJobQuery() {
 	suite.NotNil(resp)
 }
 
+// TestJobQueryPageToken tests that Job Query API returns the token of
+// the next page of the query result
+func (suite *JobHandlerTestSuite) TestJobQueryPageToken() {
+	spec := &job.QuerySpec{
+		Pagination: &query.PaginationSpec{
+			Limit:     2,
+			PageToken: ormobjects.EncodeJobQueryPageToken(2),
+		},
+	}
+	summaries := []*job.JobSummary{
+		{Id: &peloton.JobID{Value: uuid.New()}},
+		{Id: &peloton.JobID{Value: uuid.New()}},
+	}
+
+	suite.mockedJobStore.EXPECT().QueryJobs(suite.context, nil, spec, true).
+		Return(nil, summaries, uint32(5), nil)
+	resp, err := suite.handler.Query(suite.context, &job.QueryRequest{
+		Spec:        spec,
+		SummaryOnly: true,
+	})
+	suite.NoError(err)
+	suite.Equal(summaries, resp.GetResults())
+	suite.Equal(uint32(2), resp.GetPagination().GetOffset())
+	suite.Equal(uint32(5), resp.GetPagination().GetTotal())
+	suite.Equal(
+		ormobjects.EncodeJobQueryPageToken(4),
+		resp.GetPagination().GetNextPageToken())
+
+	// no token is returned for the last page
+	spec.Pagination.PageToken = ormobjects.EncodeJobQueryPageToken(4)
+	suite.mockedJobStore.EXPECT().QueryJobs(suite.context, nil, spec, true).
+		Return(nil, summaries[:1], uint32(5), nil)
+	resp, err = suite.handler.Query(suite.context, &job.QueryRequest{
+		Spec:        spec,
+		SummaryOnly: true,
+	})
+	suite.NoError(err)
+	suite.Empty(resp.GetPagination().GetNextPageToken())
+}
+
 // TestJobQuery tests failure case for Job Query API
 // This is fairly minimal, all interesting test cases are in the unit tests
 // for store.QueryJobs()
@@ -2627,3 +2751,88 @@ func (suite *JobHandlerTestSuite) TestStopJobSuccess() {
 	suite.Equal(resp.GetResourceVersion(),
 		newConfig.GetChangeLog().GetVersion())
 }
+
+// TestRestartFailedInstancesNonLeaderFailure tests restarting the failed
+// instances of a job fails because jobmgr is not leader
+func (suite *JobHandlerTestSuite) TestRestartFailedInstancesNonLeaderFailure() {
+	suite.mockedCandidate.EXPECT().
+		IsLeader().
+		Return(false)
+
+	resp, err := suite.handler.RestartFailedInstances(
+		context.Background(),
+		&job.RestartFailedInstancesRequest{Id: suite.testJobID},
+	)
+	suite.True(yarpcerrors.IsUnavailable(err))
+	suite.Nil(resp)
+}
+
+// TestRestartFailedInstancesSuccess tests restarting the failed
+// instances of a terminal batch job
+func (suite *JobHandlerTestSuite) TestRestartFailedInstancesSuccess() {
+	cachedConfig := cachedmocks.NewMockJobConfigCache(suite.ctrl)
+	taskInfos := map[uint32]*task.TaskInfo{
+		0: suite.createTestTaskInfo(task.TaskState_SUCCEEDED, 0),
+		1: suite.createTestTaskInfo(task.TaskState_FAILED, 1),
+		2: suite.createTestTaskInfo(task.TaskState_LOST, 2),
+	}
+
+	suite.mockedCandidate.EXPECT().
+		IsLeader().
+		Return(true)
+	suite.mockedJobFactory.EXPECT().
+		AddJob(suite.testJobID).
+		Return(suite.mockedCachedJob)
+	suite.mockedCachedJob.EXPECT().
+		ID().
+		Return(suite.testJobID).
+		AnyTimes()
+	suite.mockedCachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(cachedConfig, nil)
+	cachedConfig.EXPECT().
+		GetType().
+		Return(job.JobType_BATCH)
+	cachedConfig.EXPECT().
+		GetChangeLog().
+		Return(&peloton.ChangeLog{Version: 2})
+	suite.mockedTaskStore.EXPECT().
+		GetTasksForJob(gomock.Any(), suite.testJobID).
+		Return(taskInfos, nil)
+	suite.mockedCachedJob.EXPECT().
+		ReplaceTasks(taskInfos, false).
+		Return(nil)
+	suite.mockedCachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&job.RuntimeInfo{
+			State:     job.JobState_FAILED,
+			GoalState: job.JobState_SUCCEEDED,
+		}, nil)
+	suite.mockedActiveJobsOps.EXPECT().
+		Create(gomock.Any(), suite.testJobID).
+		Return(nil)
+	suite.mockedCachedJob.EXPECT().
+		CompareAndSetRuntime(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			runtime *job.RuntimeInfo,
+		) (*job.RuntimeInfo, error) {
+			suite.Equal(job.JobState_PENDING, runtime.GetState())
+			return runtime, nil
+		})
+	suite.mockedCachedJob.EXPECT().
+		PatchTasks(gomock.Any(), gomock.Any(), false).
+		Return([]uint32{1, 2}, nil, nil)
+	suite.mockedGoalStateDriver.EXPECT().
+		EnqueueTask(suite.testJobID, gomock.Any(), gomock.Any()).
+		Times(2)
+	suite.mockedGoalStateDriver.EXPECT().
+		EnqueueJob(suite.testJobID, gomock.Any())
+
+	resp, err := suite.handler.RestartFailedInstances(
+		context.Background(),
+		&job.RestartFailedInstancesRequest{Id: suite.testJobID},
+	)
+	suite.NoError(err)
+	suite.Equal([]uint32{1, 2}, resp.GetInstanceIds())
+}
@@ -49,6 +49,10 @@ type Metrics struct {
 	JobStop        tally.Counter
 	JobStopFail    tally.Counter
 
+	JobAPIRestartFailedInstances  tally.Counter
+	JobRestartFailedInstances     tally.Counter
+	JobRestartFailedInstancesFail tally.Counter
+
 	JobAPIGetByRespoolID  tally.Counter
 	JobGetByRespoolID     tally.Counter
 	JobGetByRespoolIDFail tally.Counter
@@ -105,6 +109,10 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		JobStop:        jobSuccessScope.Counter("stop"),
 		JobStopFail:    jobFailScope.Counter("stop"),
 
+		JobAPIRestartFailedInstances:  jobAPIScope.Counter("restart_failed_instances"),
+		JobRestartFailedInstances:     jobSuccessScope.Counter("restart_failed_instances"),
+		JobRestartFailedInstancesFail: jobFailScope.Counter("restart_failed_instances"),
+
 		JobQueryHandlerDuration: jobAPIScope.Timer("job_query_duration"),
 
 		JobAPIGetByRespoolID:  jobAPIScope.Counter("get_by_respool_id"),
@@ -32,6 +32,7 @@ import (
 	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/pod"
 	v1alphaquery "github.com/uber/peloton/.gen/peloton/api/v1alpha/query"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	"github.com/uber/peloton/.gen/peloton/private/models"
 	"github.com/uber/peloton/pkg/common/api"
 	"github.com/uber/peloton/pkg/common/concurrency"
@@ -46,6 +47,7 @@ import (
 	"github.com/uber/peloton/pkg/jobmgr/goalstate"
 	jobconfig "github.com/uber/peloton/pkg/jobmgr/job/config"
 	"github.com/uber/peloton/pkg/jobmgr/jobsvc"
+	jobmgrsecret "github.com/uber/peloton/pkg/jobmgr/secret"
 	jobmgrtask "github.com/uber/peloton/pkg/jobmgr/task"
 	"github.com/uber/peloton/pkg/jobmgr/task/activermtask"
 	handlerutil "github.com/uber/peloton/pkg/jobmgr/util/handler"
@@ -54,6 +56,7 @@ import (
 	ormobjects "github.com/uber/peloton/pkg/storage/objects"
 
 	"github.com/gocql/gocql"
+	"github.com/gogo/protobuf/proto"
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -73,12 +76,14 @@ type serviceHandler struct {
 	secretInfoOps      ormobjects.SecretInfoOps
 	taskConfigV2Ops    ormobjects.TaskConfigV2Ops
 	respoolClient      respool.ResourceManagerYARPCClient
+	hostMgrClient      hostsvc.InternalHostServiceYARPCClient
 	jobFactory         cached.JobFactory
 	goalStateDriver    goalstate.Driver
 	candidate          leader.Candidate
 	rootCtx            context.Context
 	jobSvcCfg          jobsvc.Config
 	activeRMTasks      activermtask.ActiveRMTasks
+	secretProvider     jobmgrsecret.Provider
 }
 
 var (
@@ -86,6 +91,7 @@ var (
 	errResourcePoolNotFound = yarpcerrors.NotFoundErrorf("resource pool not found")
 	errRootResourcePoolID   = yarpcerrors.InvalidArgumentErrorf("cannot submit jobs to the `root` resource pool")
 	errNonLeafResourcePool  = yarpcerrors.InvalidArgumentErrorf("cannot submit jobs to a non leaf resource pool")
+	errActiveWorkflow       = yarpcerrors.FailedPreconditionErrorf("job has an active workflow")
 )
 
 const (
@@ -105,6 +111,7 @@ func InitV1AlphaJobServiceHandler(
 	candidate leader.Candidate,
 	jobSvcCfg jobsvc.Config,
 	activeRMTasks activermtask.ActiveRMTasks,
+	secretProvider jobmgrsecret.Provider,
 ) {
 	handler := &serviceHandler{
 		jobStore:           jobStore,
@@ -120,11 +127,15 @@ func InitV1AlphaJobServiceHandler(
 		respoolClient: respool.NewResourceManagerYARPCClient(
 			d.ClientConfig(common.PelotonResourceManager),
 		),
+		hostMgrClient: hostsvc.NewInternalHostServiceYARPCClient(
+			d.ClientConfig(common.PelotonHostManager),
+		),
 		jobFactory:      jobFactory,
 		goalStateDriver: goalStateDriver,
 		candidate:       candidate,
 		jobSvcCfg:       jobSvcCfg,
 		activeRMTasks:   activeRMTasks,
+		secretProvider:  secretProvider,
 	}
 	d.Register(svc.BuildJobServiceYARPCProcedures(handler))
 }
@@ -325,6 +336,10 @@ func (h *serviceHandler) ReplaceJob(
 		return nil, errors.Wrap(err, "invalid job spec")
 	}
 
+	if err = h.validateSecretVolumes(jobConfig); err != nil {
+		return nil, errors.Wrap(err, "invalid secret volume")
+	}
+
 	jobID := &peloton.JobID{Value: req.GetJobId().GetValue()}
 
 	cachedJob := h.jobFactory.AddJob(jobID)
@@ -616,6 +631,55 @@ func (h *serviceHandler) ResumeJobWorkflow(
 	return &svc.ResumeJobWorkflowResponse{Version: newEntityVersion}, nil
 }
 
+func (h *serviceHandler) PromoteJobWorkflow(
+	ctx context.Context,
+	req *svc.PromoteJobWorkflowRequest) (resp *svc.PromoteJobWorkflowResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("JobSVC.PromoteJobWorkflow failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("response", resp).
+			WithField("headers", headers).
+			Info("JobSVC.PromoteJobWorkflow succeeded")
+	}()
+
+	if !h.candidate.IsLeader() {
+		return nil, yarpcerrors.UnavailableErrorf("JobSVC.PromoteJobWorkflow is not supported on non-leader")
+	}
+
+	cachedJob := h.jobFactory.AddJob(&peloton.JobID{Value: req.GetJobId().GetValue()})
+	opaque := cached.WithOpaqueData(nil)
+	if req.GetOpaqueData() != nil {
+		opaque = cached.WithOpaqueData(&peloton.OpaqueData{
+			Data: req.GetOpaqueData().GetData(),
+		})
+	}
+
+	updateID, newEntityVersion, err := cachedJob.PromoteWorkflow(
+		ctx,
+		req.GetVersion(),
+		opaque,
+	)
+
+	if len(updateID.GetValue()) > 0 {
+		h.goalStateDriver.EnqueueUpdate(cachedJob.ID(), updateID, time.Now())
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to promote workload")
+	}
+
+	return &svc.PromoteJobWorkflowResponse{Version: newEntityVersion}, nil
+}
+
 func (h *serviceHandler) AbortJobWorkflow(
 	ctx context.Context,
 	req *svc.AbortJobWorkflowRequest) (resp *svc.AbortJobWorkflowResponse, err error) {
@@ -1657,6 +1721,430 @@ func (h *serviceHandler) GetReplaceJobDiff(
 	}, nil
 }
 
+func (h *serviceHandler) RotateSecret(
+	ctx context.Context,
+	req *svc.RotateSecretRequest) (resp *svc.RotateSecretResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		// do not log the request which may contain the secret data
+		if err != nil {
+			log.WithField("secret", req.GetSecret()).
+				WithField("version", req.GetVersion()).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("JobSVC.RotateSecret failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("secret", req.GetSecret()).
+			WithField("version", req.GetVersion()).
+			WithField("response", resp).
+			WithField("headers", headers).
+			Info("JobSVC.RotateSecret succeeded")
+	}()
+
+	if !h.candidate.IsLeader() {
+		return nil,
+			yarpcerrors.UnavailableErrorf("JobSVC.RotateSecret is not supported on non-leader")
+	}
+
+	if !h.jobSvcCfg.EnableSecrets {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"secrets not enabled in cluster")
+	}
+
+	ref := jobmgrsecret.ParseReference(req.GetSecret())
+	if ref.Path == "" {
+		return nil, yarpcerrors.InvalidArgumentErrorf("secret is not set")
+	}
+
+	if ref.IsExternal() {
+		if req.GetValue() != nil {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"secret from an external secret store cannot have a value")
+		}
+		// make sure that the jobs are restarted with a secret
+		// they can read
+		if _, err := h.secretProvider.GetSecret(
+			ctx,
+			&jobmgrsecret.Reference{
+				Store:   ref.Store,
+				Path:    ref.Path,
+				Version: req.GetVersion(),
+			}); err != nil {
+			return nil, errors.Wrap(err, "failed to read rotated secret")
+		}
+	} else {
+		if req.GetVersion() != "" {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"secret stored by Peloton cannot have a version")
+		}
+		if err := h.rotateSecretData(
+			ctx, ref.Path, req.GetValue().GetData()); err != nil {
+			return nil, err
+		}
+	}
+
+	resp = &svc.RotateSecretResponse{}
+	for _, cachedJob := range h.jobFactory.GetAllJobs() {
+		restarted, err := h.restartJobForSecret(
+			ctx,
+			cachedJob,
+			ref,
+			req.GetVersion(),
+			req.GetBatchSize(),
+		)
+		if err == errActiveWorkflow {
+			// restarting the job would abort its workflow
+			resp.DeferredJobIds = append(resp.DeferredJobIds,
+				&v1alphapeloton.JobID{Value: cachedJob.ID().GetValue()})
+			continue
+		}
+		if err != nil {
+			// keep restarting the other jobs, the secret has
+			// already been rotated
+			log.WithField("job_id", cachedJob.ID().GetValue()).
+				WithField("secret", req.GetSecret()).
+				WithError(err).
+				Warn("failed to restart job for rotated secret")
+			resp.Failures = append(resp.Failures, &svc.RotateSecretFailure{
+				JobId:   &v1alphapeloton.JobID{Value: cachedJob.ID().GetValue()},
+				Message: err.Error(),
+			})
+			continue
+		}
+		if restarted {
+			resp.JobIds = append(resp.JobIds,
+				&v1alphapeloton.JobID{Value: cachedJob.ID().GetValue()})
+		}
+	}
+	return resp, nil
+}
+
+// rotateSecretData replaces the data of a secret stored by Peloton.
+func (h *serviceHandler) rotateSecretData(
+	ctx context.Context,
+	secretID string,
+	data []byte,
+) error {
+	if len(data) == 0 {
+		return yarpcerrors.InvalidArgumentErrorf("secret value is not set")
+	}
+	// Validate that secret is base64 encoded
+	if _, err := base64.StdEncoding.DecodeString(string(data)); err != nil {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"failed to decode secret with error: %v", err)
+	}
+	secretInfo, err := h.secretInfoOps.GetSecret(ctx, secretID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get secret")
+	}
+	if err := h.secretInfoOps.UpdateSecretDataAndVersion(
+		ctx, secretID, string(data), secretInfo.Version+1); err != nil {
+		return errors.Wrap(err, "failed to update secret")
+	}
+	return nil
+}
+
+// restartJobForSecret restarts all the instances of a running service job
+// which mounts the secret, pinning the secret volumes to version if set.
+// It returns true if the job was restarted.
+func (h *serviceHandler) restartJobForSecret(
+	ctx context.Context,
+	cachedJob cached.Job,
+	ref *jobmgrsecret.Reference,
+	version string,
+	batchSize uint32,
+) (bool, error) {
+	runtime, err := cachedJob.GetRuntime(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "fail to get job runtime")
+	}
+	// stopped jobs read the secret when they are started again
+	if util.IsPelotonJobStateTerminal(runtime.GetGoalState()) {
+		return false, nil
+	}
+
+	obj, err := h.jobConfigOps.GetResult(
+		ctx,
+		cachedJob.ID(),
+		runtime.GetConfigurationVersion(),
+	)
+	if err != nil {
+		return false, errors.Wrap(err, "fail to get job config")
+	}
+	if obj.JobConfig.GetType() != pbjob.JobType_SERVICE {
+		return false, nil
+	}
+
+	newConfig := proto.Clone(obj.JobConfig).(*pbjob.JobConfig)
+	found := jobmgrsecret.RewriteReferences(
+		newConfig.GetDefaultConfig().GetContainer().GetVolumes(), ref, version)
+	for _, taskConfig := range newConfig.GetInstanceConfig() {
+		if jobmgrsecret.RewriteReferences(
+			taskConfig.GetContainer().GetVolumes(), ref, version) {
+			found = true
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	// a restart would abort the active workflow of the job
+	if hasActiveWorkflow(cachedJob, runtime.GetUpdateID()) {
+		return false, errActiveWorkflow
+	}
+
+	now := time.Now()
+	newConfig.ChangeLog = &peloton.ChangeLog{
+		Version:   obj.JobConfig.GetChangeLog().GetVersion(),
+		CreatedAt: uint64(now.UnixNano()),
+		UpdatedAt: uint64(now.UnixNano()),
+	}
+
+	newSpec := &stateless.JobSpec{}
+	if obj.JobSpec != nil {
+		newSpec = proto.Clone(obj.JobSpec).(*stateless.JobSpec)
+		for _, container := range newSpec.GetDefaultSpec().GetContainers() {
+			jobmgrsecret.RewriteReferences(
+				container.GetContainer().GetVolumes(), ref, version)
+		}
+		for _, podSpec := range newSpec.GetInstanceSpec() {
+			for _, container := range podSpec.GetContainers() {
+				jobmgrsecret.RewriteReferences(
+					container.GetContainer().GetVolumes(), ref, version)
+			}
+		}
+		newSpec.Revision = &v1alphapeloton.Revision{
+			Version:   newConfig.GetChangeLog().GetVersion(),
+			CreatedAt: newConfig.GetChangeLog().GetCreatedAt(),
+			UpdatedAt: newConfig.GetChangeLog().GetUpdatedAt(),
+		}
+	}
+
+	var instancesToUpdate []uint32
+	for i := uint32(0); i < newConfig.GetInstanceCount(); i++ {
+		instancesToUpdate = append(instancesToUpdate, i)
+	}
+
+	updateID, _, err := cachedJob.CreateWorkflow(
+		ctx,
+		models.WorkflowType_RESTART,
+		&pbupdate.UpdateConfig{
+			BatchSize: batchSize,
+		},
+		versionutil.GetJobEntityVersion(
+			runtime.GetConfigurationVersion(),
+			runtime.GetDesiredStateVersion(),
+			runtime.GetWorkflowVersion(),
+		),
+		cached.WithInstanceToProcess(
+			nil,
+			instancesToUpdate,
+			nil),
+		cached.WithConfig(
+			newConfig,
+			obj.JobConfig,
+			obj.ConfigAddOn,
+			newSpec,
+		),
+		cached.WithOpaqueData(nil),
+	)
+
+	// enqueue the update even on error, see RestartJob
+	if len(updateID.GetValue()) > 0 {
+		h.goalStateDriver.EnqueueUpdate(cachedJob.ID(), updateID, time.Now())
+	}
+
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// hasActiveWorkflow returns true if the workflow updateID of the job
+// is active.
+func hasActiveWorkflow(cachedJob cached.Job, updateID *peloton.UpdateID) bool {
+	if len(updateID.GetValue()) == 0 {
+		return false
+	}
+	cachedWorkflow := cachedJob.GetWorkflow(updateID)
+	if cachedWorkflow == nil {
+		return false
+	}
+	return cached.IsUpdateStateActive(cachedWorkflow.GetState().State)
+}
+
+func (h *serviceHandler) GetJobUsage(
+	ctx context.Context,
+	req *svc.GetJobUsageRequest) (resp *svc.GetJobUsageResponse, err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(ctx)
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("JobSVC.GetJobUsage failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("response", resp).
+			WithField("headers", headers).
+			Debug("JobSVC.GetJobUsage succeeded")
+	}()
+
+	if !h.candidate.IsLeader() {
+		return nil,
+			yarpcerrors.UnavailableErrorf("JobSVC.GetJobUsage is not supported on non-leader")
+	}
+
+	jobID := req.GetJobId().GetValue()
+	respoolID := req.GetRespoolId().GetValue()
+	if (len(jobID) == 0) == (len(respoolID) == 0) {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"exactly one of job id and respool id must be set")
+	}
+
+	var cachedJobs []cached.Job
+	var respoolIDs map[string]bool
+	if len(jobID) != 0 {
+		cachedJob := h.jobFactory.GetJob(&peloton.JobID{Value: jobID})
+		if cachedJob == nil {
+			return nil, yarpcerrors.NotFoundErrorf("job not found")
+		}
+		cachedJobs = append(cachedJobs, cachedJob)
+	} else {
+		// jobs are only submitted to leaf resource pools, so include the
+		// jobs of all the pools in the subtree of the requested pool
+		respoolIDs, err = h.getResourcePoolSubtree(ctx, respoolID)
+		if err != nil {
+			return nil, err
+		}
+		for _, cachedJob := range h.jobFactory.GetAllJobs() {
+			cachedJobs = append(cachedJobs, cachedJob)
+		}
+	}
+
+	// pod run ID -> usage of the job the pod belongs to
+	jobUsages := make(map[string]*svc.JobUsage)
+	var podIDs []string
+	resp = &svc.GetJobUsageResponse{Total: &pod.ResourceUsage{}}
+	for _, cachedJob := range cachedJobs {
+		config, err := cachedJob.GetConfig(ctx)
+		if err != nil {
+			if len(jobID) == 0 {
+				// the job may be getting deleted, skip it rather than
+				// failing the whole respool
+				log.WithField("job_id", cachedJob.ID().GetValue()).
+					WithError(err).
+					Info("failed to get job config for usage")
+				continue
+			}
+			return nil, errors.Wrapf(err,
+				"failed to get config of job %s", cachedJob.ID().GetValue())
+		}
+		if len(respoolID) != 0 &&
+			!respoolIDs[config.GetRespoolID().GetValue()] {
+			continue
+		}
+
+		jobUsage := &svc.JobUsage{
+			JobId: &v1alphapeloton.JobID{Value: cachedJob.ID().GetValue()},
+			RespoolId: &v1alphapeloton.ResourcePoolID{
+				Value: config.GetRespoolID().GetValue()},
+			Usage: &pod.ResourceUsage{},
+		}
+		resp.Jobs = append(resp.Jobs, jobUsage)
+
+		for _, cachedTask := range cachedJob.GetAllTasks() {
+			runtime, err := cachedTask.GetRuntime(ctx)
+			if err != nil {
+				return nil, errors.Wrapf(err,
+					"failed to get runtime of pod %s-%d",
+					cachedJob.ID().GetValue(), cachedTask.ID())
+			}
+			if runtime.GetState() != task.TaskState_RUNNING {
+				continue
+			}
+			podID := runtime.GetMesosTaskId().GetValue()
+			jobUsages[podID] = jobUsage
+			podIDs = append(podIDs, podID)
+		}
+	}
+
+	if len(podIDs) == 0 {
+		return resp, nil
+	}
+
+	usageResp, err := h.hostMgrClient.GetPodUsage(
+		ctx,
+		&hostsvc.GetPodUsageRequest{PodIds: podIDs},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pod usage")
+	}
+
+	for _, podUsage := range usageResp.GetUsages() {
+		jobUsage, ok := jobUsages[podUsage.GetPodId()]
+		samples := podUsage.GetSamples()
+		if !ok || len(samples) == 0 {
+			continue
+		}
+		latest := api.ConvertPodUsageSampleToResourceUsage(
+			samples[len(samples)-1])
+		jobUsage.PodCount++
+		addResourceUsage(jobUsage.Usage, latest)
+		addResourceUsage(resp.Total, latest)
+	}
+	return resp, nil
+}
+
+// getResourcePoolSubtree returns the IDs of the resource pool respoolID
+// and of all its descendant pools.
+func (h *serviceHandler) getResourcePoolSubtree(
+	ctx context.Context,
+	respoolID string,
+) (map[string]bool, error) {
+	resp, err := h.respoolClient.Query(ctx, &respool.QueryRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query resource pools")
+	}
+
+	// resource pool ID -> IDs of its child pools
+	children := make(map[string][]*peloton.ResourcePoolID)
+	for _, info := range resp.GetResourcePools() {
+		children[info.GetId().GetValue()] = info.GetChildren()
+	}
+	if _, ok := children[respoolID]; !ok {
+		return nil, yarpcerrors.NotFoundErrorf("resource pool not found")
+	}
+
+	subtree := make(map[string]bool)
+	pending := []string{respoolID}
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if subtree[id] {
+			continue
+		}
+		subtree[id] = true
+		for _, child := range children[id] {
+			pending = append(pending, child.GetValue())
+		}
+	}
+	return subtree, nil
+}
+
+// addResourceUsage adds the resource usage u to total.
+func addResourceUsage(total *pod.ResourceUsage, u *pod.ResourceUsage) {
+	total.Cpu += u.GetCpu()
+	total.MemMb += u.GetMemMb()
+	total.DiskMb += u.GetDiskMb()
+}
+
 func (h *serviceHandler) RefreshJob(
 	ctx context.Context,
 	req *svc.RefreshJobRequest) (resp *svc.RefreshJobResponse, err error) {
@@ -1915,6 +2403,23 @@ func (h *serviceHandler) validateSecretsAndConfig(
 			return yarpcerrors.InvalidArgumentErrorf(
 				"secret does not have a path")
 		}
+		// The data of secrets referencing an external secret store
+		// is managed by that store.
+		if ref := jobmgrsecret.ParseReference(
+			secret.GetSecretId().GetValue()); ref.IsExternal() {
+			if len(secret.GetValue().GetData()) != 0 {
+				return yarpcerrors.InvalidArgumentErrorf(
+					"secret %s from an external secret store cannot have a value",
+					secret.GetSecretId().GetValue())
+			}
+			// jobmgr reads the secret on behalf of the job, make sure
+			// that the owner of the job may reference it
+			if err := h.secretProvider.CheckAccess(
+				ref, spec.GetOwner(), spec.GetOwningTeam()); err != nil {
+				return err
+			}
+			continue
+		}
 		// Validate that secret is base64 encoded
 		_, err := base64.StdEncoding.DecodeString(
 			string(secret.GetValue().GetData()))
@@ -1927,6 +2432,29 @@ func (h *serviceHandler) validateSecretsAndConfig(
 	return nil
 }
 
+// validateSecretVolumes returns an error if the config mounts a secret
+// from an external secret store which the owner of the job may not
+// reference.
+func (h *serviceHandler) validateSecretVolumes(config *pbjob.JobConfig) error {
+	taskConfigs := []*task.TaskConfig{config.GetDefaultConfig()}
+	for _, taskConfig := range config.GetInstanceConfig() {
+		taskConfigs = append(taskConfigs, taskConfig)
+	}
+	for _, taskConfig := range taskConfigs {
+		for _, volume := range taskConfig.GetContainer().GetVolumes() {
+			ref := jobmgrsecret.VolumeReference(volume)
+			if ref == nil || !ref.IsExternal() {
+				continue
+			}
+			if err := h.secretProvider.CheckAccess(
+				ref, config.GetOwner(), config.GetOwningTeam()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // validateMesosContainerizerForSecrets returns error if default config doesn't
 // use mesos containerizer. Secrets will be common for all instances in a job.
 // They will be a part of default container config. This means that if a job is
@@ -1979,8 +2507,15 @@ func (h *serviceHandler) addSecretsToDBAndConfig(
 			log.WithField("job_id", secret.GetId().GetValue()).
 				Info("Genarating UUID for empty secret ID")
 		}
-		// store secret in DB
-		if update {
+		// secrets from an external secret store are not stored in DB,
+		// only make sure that they can be read at launch time
+		if ref := jobmgrsecret.ParseReference(
+			secret.GetId().GetValue()); ref.IsExternal() {
+			if _, err := h.secretProvider.GetSecret(ctx, ref); err != nil {
+				return errors.Wrapf(err,
+					"failed to read secret %s", secret.GetId().GetValue())
+			}
+		} else if update {
 			if err := h.secretInfoOps.UpdateSecretData(
 				ctx,
 				jobID,
@@ -2001,7 +2536,7 @@ func (h *serviceHandler) addSecretsToDBAndConfig(
 			}
 		}
 		// Add volume/secret to default container config with this secret
-		// Use secretID instead of secret data when storing as
+		// Use secret reference instead of secret data when storing as
 		// part of default config in DB.
 		// This is done to prevent secrets leaks via logging/API etc.
 		// At the time of task launch, launcher will read the
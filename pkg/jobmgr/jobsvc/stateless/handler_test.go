@@ -35,6 +35,7 @@ import (
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/pod"
 	v1alphaquery "github.com/uber/peloton/.gen/peloton/api/v1alpha/query"
 	v1alpharespool "github.com/uber/peloton/.gen/peloton/api/v1alpha/respool"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	"github.com/uber/peloton/.gen/peloton/private/models"
 	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
 
@@ -45,12 +46,15 @@ import (
 	"github.com/uber/peloton/pkg/jobmgr/cached"
 	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
 	"github.com/uber/peloton/pkg/jobmgr/jobsvc"
+	jobmgrsecret "github.com/uber/peloton/pkg/jobmgr/secret"
 	ormobjects "github.com/uber/peloton/pkg/storage/objects"
 
 	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	hostmocks "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc/mocks"
 	leadermocks "github.com/uber/peloton/pkg/common/leader/mocks"
 	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
 	goalstatemocks "github.com/uber/peloton/pkg/jobmgr/goalstate/mocks"
+	secretmocks "github.com/uber/peloton/pkg/jobmgr/secret/mocks"
 	activermtaskmocks "github.com/uber/peloton/pkg/jobmgr/task/activermtask/mocks"
 	storemocks "github.com/uber/peloton/pkg/storage/mocks"
 	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
@@ -115,6 +119,8 @@ type statelessHandlerTestSuite struct {
 	jobUpdateEventsOps *objectmocks.MockJobUpdateEventsOps
 	taskConfigV2Ops    *objectmocks.MockTaskConfigV2Ops
 	activeRMTasks      *activermtaskmocks.MockActiveRMTasks
+	secretProvider     *secretmocks.MockProvider
+	hostMgrClient      *hostmocks.MockInternalHostServiceYARPCClient
 }
 
 func (suite *statelessHandlerTestSuite) SetupTest() {
@@ -140,6 +146,8 @@ func (suite *statelessHandlerTestSuite) SetupTest() {
 	suite.listJobsServer.EXPECT().Context().Return(context.Background()).AnyTimes()
 	suite.listPodsServer.EXPECT().Context().Return(context.Background()).AnyTimes()
 	suite.activeRMTasks = activermtaskmocks.NewMockActiveRMTasks(suite.ctrl)
+	suite.secretProvider = secretmocks.NewMockProvider(suite.ctrl)
+	suite.hostMgrClient = hostmocks.NewMockInternalHostServiceYARPCClient(suite.ctrl)
 	suite.handler = &serviceHandler{
 		jobFactory:         suite.jobFactory,
 		candidate:          suite.candidate,
@@ -155,12 +163,14 @@ func (suite *statelessHandlerTestSuite) SetupTest() {
 		taskConfigV2Ops:    suite.taskConfigV2Ops,
 		secretInfoOps:      suite.secretInfoOps,
 		respoolClient:      suite.respoolClient,
+		hostMgrClient:      suite.hostMgrClient,
 		rootCtx:            context.Background(),
 		jobSvcCfg: jobsvc.Config{
 			EnableSecrets:  true,
 			MaxTasksPerJob: 100000,
 		},
-		activeRMTasks: suite.activeRMTasks,
+		activeRMTasks:  suite.activeRMTasks,
+		secretProvider: suite.secretProvider,
 	}
 }
 
@@ -1305,6 +1315,45 @@ func (suite *statelessHandlerTestSuite) TestReplaceJobGetJobConfigFailure() {
 	suite.Nil(resp)
 }
 
+// TestReplaceJobSecretNotAllowed tests the failure case of replacing job
+// due to a secret volume the owner of the job may not reference
+func (suite *statelessHandlerTestSuite) TestReplaceJobSecretNotAllowed() {
+	suite.candidate.EXPECT().
+		IsLeader().
+		Return(true)
+
+	suite.secretProvider.EXPECT().
+		CheckAccess(
+			&jobmgrsecret.Reference{Store: "vault", Path: "db/password"},
+			"user1",
+			"team1").
+		Return(yarpcerrors.PermissionDeniedErrorf("secret not allowed"))
+
+	resp, err := suite.handler.ReplaceJob(
+		context.Background(),
+		&statelesssvc.ReplaceJobRequest{
+			JobId:   &v1alphapeloton.JobID{Value: testJobID},
+			Version: &v1alphapeloton.EntityVersion{Value: testEntityVersion},
+			Spec: &stateless.JobSpec{
+				Owner:      "user1",
+				OwningTeam: "team1",
+				DefaultSpec: &pod.PodSpec{
+					Containers: []*pod.ContainerSpec{{
+						Container: &mesos.ContainerInfo{
+							Volumes: []*mesos.Volume{
+								util.CreateSecretVolume(
+									testSecretPath, "vault:db/password"),
+							},
+						},
+					}},
+				},
+			},
+		},
+	)
+	suite.True(yarpcerrors.IsPermissionDenied(err))
+	suite.Nil(resp)
+}
+
 // TestGetReplaceJobDiffSuccess tests the success case of getting the
 // difference in configuration for ReplaceJob API
 func (suite *statelessHandlerTestSuite) TestGetReplaceJobDiffSuccess() {
@@ -1570,6 +1619,88 @@ func (suite *statelessHandlerTestSuite) TestResumeJobWorkflowResumeWorkflowFailu
 	suite.Nil(resp)
 }
 
+// TestPromoteJobWorkflowFailNonLeader tests the failure case of promote
+// workflow due to jobmgr is not leader
+func (suite *statelessHandlerTestSuite) TestPromoteJobWorkflowFailNonLeader() {
+	suite.candidate.EXPECT().IsLeader().Return(false)
+
+	resp, err := suite.handler.PromoteJobWorkflow(context.Background(),
+		&statelesssvc.PromoteJobWorkflowRequest{
+			JobId:   &v1alphapeloton.JobID{Value: testJobID},
+			Version: &v1alphapeloton.EntityVersion{Value: "1-1-1"},
+		})
+	suite.Error(err)
+	suite.Nil(resp)
+}
+
+// TestPromoteJobWorkflowSuccess tests the success case of promote workflow
+func (suite *statelessHandlerTestSuite) TestPromoteJobWorkflowSuccess() {
+	entityVersion := &v1alphapeloton.EntityVersion{Value: "1-1-1"}
+	newEntityVersion := &v1alphapeloton.EntityVersion{Value: "1-1-2"}
+	opaque := "test"
+
+	suite.candidate.EXPECT().IsLeader().Return(true)
+
+	suite.jobFactory.EXPECT().
+		AddJob(&peloton.JobID{Value: testJobID}).
+		Return(suite.cachedJob)
+
+	suite.cachedJob.EXPECT().
+		PromoteWorkflow(gomock.Any(), entityVersion, gomock.Any()).
+		Return(&peloton.UpdateID{Value: testUpdateID}, newEntityVersion, nil)
+
+	suite.cachedJob.EXPECT().
+		ID().
+		Return(&peloton.JobID{Value: testJobID})
+
+	suite.goalStateDriver.EXPECT().
+		EnqueueUpdate(&peloton.JobID{Value: testJobID}, &peloton.UpdateID{Value: testUpdateID}, gomock.Any())
+
+	resp, err := suite.handler.PromoteJobWorkflow(context.Background(),
+		&statelesssvc.PromoteJobWorkflowRequest{
+			JobId:      &v1alphapeloton.JobID{Value: testJobID},
+			Version:    entityVersion,
+			OpaqueData: &v1alphapeloton.OpaqueData{Data: opaque},
+		})
+	suite.NoError(err)
+	suite.Equal(resp.GetVersion(), newEntityVersion)
+}
+
+// TestPromoteJobWorkflowNoCanaryFailure tests the failure case of promote
+// workflow when the workflow does not have a canary phase
+func (suite *statelessHandlerTestSuite) TestPromoteJobWorkflowNoCanaryFailure() {
+	entityVersion := &v1alphapeloton.EntityVersion{Value: "1-1-1"}
+	newEntityVersion := &v1alphapeloton.EntityVersion{Value: "1-1-2"}
+
+	suite.candidate.EXPECT().IsLeader().Return(true)
+
+	suite.jobFactory.EXPECT().
+		AddJob(&peloton.JobID{Value: testJobID}).
+		Return(suite.cachedJob)
+
+	suite.cachedJob.EXPECT().
+		PromoteWorkflow(gomock.Any(), entityVersion, gomock.Any()).
+		Return(
+			&peloton.UpdateID{Value: testUpdateID},
+			newEntityVersion,
+			yarpcerrors.FailedPreconditionErrorf("workflow does not have a canary phase"))
+
+	suite.cachedJob.EXPECT().
+		ID().
+		Return(&peloton.JobID{Value: testJobID})
+
+	suite.goalStateDriver.EXPECT().
+		EnqueueUpdate(&peloton.JobID{Value: testJobID}, &peloton.UpdateID{Value: testUpdateID}, gomock.Any())
+
+	resp, err := suite.handler.PromoteJobWorkflow(context.Background(),
+		&statelesssvc.PromoteJobWorkflowRequest{
+			JobId:   &v1alphapeloton.JobID{Value: testJobID},
+			Version: entityVersion,
+		})
+	suite.True(yarpcerrors.IsFailedPrecondition(err))
+	suite.Nil(resp)
+}
+
 // TestAbortJobWorkflowSuccess tests the success case of abort workflow
 func (suite *statelessHandlerTestSuite) TestAbortJobWorkflowSuccess() {
 	entityVersion := &v1alphapeloton.EntityVersion{Value: "1-1-1"}
@@ -4463,6 +4594,823 @@ func (suite *statelessHandlerTestSuite) TestStartJobConcurrencyErrorRetry() {
 		versionutil.GetJobEntityVersion(configVersion, desiredStateVersion+1, workflowVersion))
 }
 
+// TestCreateJobWithExternalSecretsSuccess tests creating a job with a
+// secret held by an external secret store
+func (suite *statelessHandlerTestSuite) TestCreateJobWithExternalSecretsSuccess() {
+	mesosContainerizer := mesos.ContainerInfo_MESOS
+	jobSpec := &stateless.JobSpec{
+		DefaultSpec: &pod.PodSpec{
+			Containers: []*pod.ContainerSpec{
+				{
+					Command:   &mesos.CommandInfo{Value: &testCmd},
+					Container: &mesos.ContainerInfo{Type: &mesosContainerizer},
+				},
+			},
+		},
+		RespoolId:  testRespoolID,
+		OwningTeam: "team1",
+	}
+	secret := &v1alphapeloton.Secret{
+		SecretId: &v1alphapeloton.SecretID{Value: "vault:db/password@3"},
+		Path:     testSecretPath,
+	}
+
+	gomock.InOrder(
+		suite.candidate.EXPECT().IsLeader().Return(true),
+
+		suite.respoolClient.EXPECT().
+			GetResourcePool(gomock.Any(), gomock.Any()).
+			Return(&respool.GetResponse{
+				Poolinfo: &respool.ResourcePoolInfo{
+					Id: &peloton.ResourcePoolID{Value: testRespoolID.GetValue()},
+				},
+			}, nil),
+
+		suite.secretProvider.EXPECT().
+			CheckAccess(&jobmgrsecret.Reference{
+				Store:   "vault",
+				Path:    "db/password",
+				Version: "3",
+			}, "", "team1").
+			Return(nil),
+
+		suite.secretProvider.EXPECT().
+			GetSecret(gomock.Any(), &jobmgrsecret.Reference{
+				Store:   "vault",
+				Path:    "db/password",
+				Version: "3",
+			}).
+			Return(&jobmgrsecret.Secret{
+				Data:    []byte(testSecretStr),
+				Version: "3",
+			}, nil),
+
+		suite.jobFactory.EXPECT().
+			AddJob(gomock.Any()).
+			Return(suite.cachedJob),
+
+		suite.cachedJob.EXPECT().
+			RollingCreate(
+				gomock.Any(),
+				gomock.Any(),
+				gomock.Any(),
+				gomock.Any(),
+				gomock.Any(),
+				gomock.Any()).
+			Do(func(
+				_ context.Context,
+				_ *pbjob.JobConfig,
+				_ *models.ConfigAddOn,
+				spec *stateless.JobSpec,
+				_ *pbupdate.UpdateConfig,
+				_ *peloton.OpaqueData) {
+				volumes := spec.GetDefaultSpec().GetContainers()[0].
+					GetContainer().GetVolumes()
+				suite.Len(volumes, 1)
+				suite.Equal(
+					"vault:db/password@3",
+					string(volumes[0].GetSource().GetSecret().GetValue().GetData()))
+			}).
+			Return(nil),
+
+		suite.goalStateDriver.EXPECT().
+			EnqueueJob(gomock.Any(), gomock.Any()),
+
+		suite.cachedJob.EXPECT().
+			GetRuntime(gomock.Any()).
+			Return(&pbjob.RuntimeInfo{
+				ConfigurationVersion: testConfigurationVersion,
+			}, nil),
+	)
+
+	response, err := suite.handler.CreateJob(
+		context.Background(),
+		&statelesssvc.CreateJobRequest{
+			JobId:   &v1alphapeloton.JobID{Value: testJobID},
+			Spec:    jobSpec,
+			Secrets: []*v1alphapeloton.Secret{secret},
+		})
+	suite.NoError(err)
+	suite.Equal(testJobID, response.GetJobId().GetValue())
+}
+
+// TestCreateJobWithExternalSecretsFailure tests failing to create a job
+// with an invalid secret held by an external secret store
+func (suite *statelessHandlerTestSuite) TestCreateJobWithExternalSecretsFailure() {
+	mesosContainerizer := mesos.ContainerInfo_MESOS
+	jobSpec := &stateless.JobSpec{
+		DefaultSpec: &pod.PodSpec{
+			Containers: []*pod.ContainerSpec{
+				{
+					Command:   &mesos.CommandInfo{Value: &testCmd},
+					Container: &mesos.ContainerInfo{Type: &mesosContainerizer},
+				},
+			},
+		},
+		RespoolId: testRespoolID,
+	}
+
+	suite.candidate.EXPECT().IsLeader().Return(true).Times(3)
+	suite.respoolClient.EXPECT().
+		GetResourcePool(gomock.Any(), gomock.Any()).
+		Return(&respool.GetResponse{
+			Poolinfo: &respool.ResourcePoolInfo{
+				Id: &peloton.ResourcePoolID{Value: testRespoolID.GetValue()},
+			},
+		}, nil).
+		Times(3)
+
+	// external secrets cannot have a value
+	_, err := suite.handler.CreateJob(
+		context.Background(),
+		&statelesssvc.CreateJobRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+			Spec:  jobSpec,
+			Secrets: []*v1alphapeloton.Secret{{
+				SecretId: &v1alphapeloton.SecretID{Value: "vault:db/password"},
+				Path:     testSecretPath,
+				Value: &v1alphapeloton.Secret_Value{
+					Data: []byte(base64.StdEncoding.EncodeToString(
+						[]byte(testSecretStr))),
+				},
+			}},
+		})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	// external secrets must be allowed for the owner of the job
+	suite.secretProvider.EXPECT().
+		CheckAccess(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(yarpcerrors.PermissionDeniedErrorf("secret not allowed"))
+
+	_, err = suite.handler.CreateJob(
+		context.Background(),
+		&statelesssvc.CreateJobRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+			Spec:  jobSpec,
+			Secrets: []*v1alphapeloton.Secret{{
+				SecretId: &v1alphapeloton.SecretID{Value: "vault:db/password"},
+				Path:     testSecretPath,
+			}},
+		})
+	suite.True(yarpcerrors.IsPermissionDenied(err))
+
+	// external secrets must be readable
+	suite.secretProvider.EXPECT().
+		CheckAccess(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+	suite.secretProvider.EXPECT().
+		GetSecret(gomock.Any(), gomock.Any()).
+		Return(nil, yarpcerrors.NotFoundErrorf("secret not found"))
+
+	_, err = suite.handler.CreateJob(
+		context.Background(),
+		&statelesssvc.CreateJobRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+			Spec:  jobSpec,
+			Secrets: []*v1alphapeloton.Secret{{
+				SecretId: &v1alphapeloton.SecretID{Value: "vault:db/password"},
+				Path:     testSecretPath,
+			}},
+		})
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// secretJobConfig returns the config of a service job mounting the
+// secret ref, and the config of its instance 0
+func secretJobConfig(ref string) *pbjob.JobConfig {
+	mesosContainerizer := mesos.ContainerInfo_MESOS
+	return &pbjob.JobConfig{
+		Type:          pbjob.JobType_SERVICE,
+		InstanceCount: 2,
+		ChangeLog:     &peloton.ChangeLog{Version: testConfigurationVersion},
+		DefaultConfig: &pbtask.TaskConfig{
+			Container: &mesos.ContainerInfo{
+				Type: &mesosContainerizer,
+				Volumes: []*mesos.Volume{
+					util.CreateSecretVolume(testSecretPath, ref),
+				},
+			},
+		},
+		InstanceConfig: map[uint32]*pbtask.TaskConfig{
+			0: {
+				Container: &mesos.ContainerInfo{
+					Type: &mesosContainerizer,
+					Volumes: []*mesos.Volume{
+						util.CreateSecretVolume(testSecretPath, ref),
+					},
+				},
+			},
+		},
+	}
+}
+
+// expectSecretJob sets the expectations to read the runtime and config
+// of a running job
+func (suite *statelessHandlerTestSuite) expectSecretJob(
+	cachedJob *cachedmocks.MockJob,
+	jobID string,
+	config *pbjob.JobConfig,
+) {
+	cachedJob.EXPECT().ID().Return(&peloton.JobID{Value: jobID}).AnyTimes()
+	cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:                pbjob.JobState_RUNNING,
+			GoalState:            pbjob.JobState_RUNNING,
+			ConfigurationVersion: testConfigurationVersion,
+			DesiredStateVersion:  testDesiredStateVersion,
+			WorkflowVersion:      testWorkflowVersion,
+		}, nil)
+	suite.jobConfigOps.EXPECT().
+		GetResult(
+			gomock.Any(),
+			&peloton.JobID{Value: jobID},
+			testConfigurationVersion,
+		).
+		Return(&ormobjects.JobConfigOpsResult{
+			JobConfig: config,
+			JobSpec:   api.ConvertJobConfigToJobSpec(config),
+		}, nil)
+}
+
+// TestRotateSecretSuccess tests rotating a secret stored by Peloton
+func (suite *statelessHandlerTestSuite) TestRotateSecretSuccess() {
+	otherJobID := "5a6dc68a-4f8f-4b5c-9a77-6ad8e4b6b2cd"
+	otherJob := cachedmocks.NewMockJob(suite.ctrl)
+	value := []byte(base64.StdEncoding.EncodeToString([]byte(testSecretStr)))
+
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.secretInfoOps.EXPECT().
+		GetSecret(gomock.Any(), "secret-id").
+		Return(&ormobjects.SecretInfoObject{SecretID: "secret-id", Version: 1}, nil)
+	suite.secretInfoOps.EXPECT().
+		UpdateSecretDataAndVersion(gomock.Any(), "secret-id", string(value), int64(2)).
+		Return(nil)
+	suite.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{
+			testJobID:  suite.cachedJob,
+			otherJobID: otherJob,
+		})
+
+	suite.expectSecretJob(suite.cachedJob, testJobID,
+		secretJobConfig("secret-id"))
+	suite.expectSecretJob(otherJob, otherJobID,
+		secretJobConfig("other-secret-id"))
+
+	suite.cachedJob.EXPECT().
+		CreateWorkflow(
+			gomock.Any(),
+			models.WorkflowType_RESTART,
+			&pbupdate.UpdateConfig{BatchSize: 1},
+			&v1alphapeloton.EntityVersion{Value: testEntityVersion},
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any()).
+		Return(&peloton.UpdateID{Value: testUpdateID}, nil, nil)
+	suite.goalStateDriver.EXPECT().
+		EnqueueUpdate(
+			testPelotonJobID,
+			&peloton.UpdateID{Value: testUpdateID},
+			gomock.Any(),
+		)
+
+	resp, err := suite.handler.RotateSecret(
+		context.Background(),
+		&statelesssvc.RotateSecretRequest{
+			Secret:    "secret-id",
+			Value:     &v1alphapeloton.Secret_Value{Data: value},
+			BatchSize: 1,
+		})
+	suite.NoError(err)
+	suite.Equal([]*v1alphapeloton.JobID{{Value: testJobID}}, resp.GetJobIds())
+}
+
+// TestRotateSecretExternalSuccess tests rotating a secret held by
+// an external secret store and pinning the jobs to its new version
+func (suite *statelessHandlerTestSuite) TestRotateSecretExternalSuccess() {
+	ref := &jobmgrsecret.Reference{
+		Store:   "vault",
+		Path:    "db/password",
+		Version: "4",
+	}
+
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.secretProvider.EXPECT().
+		GetSecret(gomock.Any(), ref).
+		Return(&jobmgrsecret.Secret{Data: []byte(testSecretStr), Version: "4"}, nil)
+	suite.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{testJobID: suite.cachedJob})
+	config := secretJobConfig("vault:db/password@3")
+	suite.expectSecretJob(suite.cachedJob, testJobID, config)
+
+	suite.cachedJob.EXPECT().
+		CreateWorkflow(
+			gomock.Any(),
+			models.WorkflowType_RESTART,
+			&pbupdate.UpdateConfig{},
+			&v1alphapeloton.EntityVersion{Value: testEntityVersion},
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any()).
+		Return(&peloton.UpdateID{Value: testUpdateID}, nil, nil)
+	suite.goalStateDriver.EXPECT().
+		EnqueueUpdate(testPelotonJobID, gomock.Any(), gomock.Any())
+
+	resp, err := suite.handler.RotateSecret(
+		context.Background(),
+		&statelesssvc.RotateSecretRequest{
+			Secret:  "vault:db/password",
+			Version: "4",
+		})
+	suite.NoError(err)
+	suite.Len(resp.GetJobIds(), 1)
+
+	// the new version is pinned in a copy of the previous config
+	suite.Equal(
+		"vault:db/password@3",
+		string(config.GetDefaultConfig().GetContainer().GetVolumes()[0].
+			GetSource().GetSecret().GetValue().GetData()))
+}
+
+// TestRotateSecretSkipJobs tests that rotating a secret does not restart
+// stopped jobs and batch jobs
+func (suite *statelessHandlerTestSuite) TestRotateSecretSkipJobs() {
+	batchJobID := "5a6dc68a-4f8f-4b5c-9a77-6ad8e4b6b2cd"
+	batchJob := cachedmocks.NewMockJob(suite.ctrl)
+	batchConfig := secretJobConfig("vault:db/password")
+	batchConfig.Type = pbjob.JobType_BATCH
+
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.secretProvider.EXPECT().
+		GetSecret(gomock.Any(), gomock.Any()).
+		Return(&jobmgrsecret.Secret{Data: []byte(testSecretStr)}, nil)
+	suite.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{
+			testJobID:  suite.cachedJob,
+			batchJobID: batchJob,
+		})
+	suite.expectSecretJob(batchJob, batchJobID, batchConfig)
+	suite.cachedJob.EXPECT().
+		ID().
+		Return(testPelotonJobID).
+		AnyTimes()
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:     pbjob.JobState_KILLED,
+			GoalState: pbjob.JobState_KILLED,
+		}, nil)
+
+	resp, err := suite.handler.RotateSecret(
+		context.Background(),
+		&statelesssvc.RotateSecretRequest{Secret: "vault:db/password"})
+	suite.NoError(err)
+	suite.Empty(resp.GetJobIds())
+}
+
+// TestRotateSecretActiveWorkflow tests that rotating a secret does not
+// restart jobs with an active workflow and reports them as deferred
+func (suite *statelessHandlerTestSuite) TestRotateSecretActiveWorkflow() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.secretProvider.EXPECT().
+		GetSecret(gomock.Any(), gomock.Any()).
+		Return(&jobmgrsecret.Secret{Data: []byte(testSecretStr)}, nil)
+	suite.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{testJobID: suite.cachedJob})
+	suite.cachedJob.EXPECT().
+		ID().
+		Return(testPelotonJobID).
+		AnyTimes()
+	suite.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			State:                pbjob.JobState_RUNNING,
+			GoalState:            pbjob.JobState_RUNNING,
+			ConfigurationVersion: testConfigurationVersion,
+			UpdateID:             &peloton.UpdateID{Value: testUpdateID},
+		}, nil)
+	suite.jobConfigOps.EXPECT().
+		GetResult(gomock.Any(), testPelotonJobID, testConfigurationVersion).
+		Return(&ormobjects.JobConfigOpsResult{
+			JobConfig: secretJobConfig("vault:db/password"),
+		}, nil)
+	suite.cachedJob.EXPECT().
+		GetWorkflow(&peloton.UpdateID{Value: testUpdateID}).
+		Return(suite.cachedWorkflow)
+	suite.cachedWorkflow.EXPECT().
+		GetState().
+		Return(&cached.UpdateStateVector{State: pbupdate.State_ROLLING_FORWARD})
+
+	resp, err := suite.handler.RotateSecret(
+		context.Background(),
+		&statelesssvc.RotateSecretRequest{Secret: "vault:db/password"})
+	suite.NoError(err)
+	suite.Empty(resp.GetJobIds())
+	suite.Empty(resp.GetFailures())
+	suite.Equal(
+		[]*v1alphapeloton.JobID{{Value: testJobID}},
+		resp.GetDeferredJobIds())
+}
+
+// TestRotateSecretFailure tests the failure cases of rotating a secret
+func (suite *statelessHandlerTestSuite) TestRotateSecretFailure() {
+	value := &v1alphapeloton.Secret_Value{
+		Data: []byte(base64.StdEncoding.EncodeToString([]byte(testSecretStr))),
+	}
+
+	suite.candidate.EXPECT().IsLeader().Return(false)
+	_, err := suite.handler.RotateSecret(
+		context.Background(),
+		&statelesssvc.RotateSecretRequest{Secret: "secret-id", Value: value})
+	suite.True(yarpcerrors.IsUnavailable(err))
+
+	suite.candidate.EXPECT().IsLeader().Return(true).AnyTimes()
+	for _, req := range []*statelesssvc.RotateSecretRequest{
+		{},
+		{Secret: "secret-id"},
+		{Secret: "secret-id", Value: &v1alphapeloton.Secret_Value{Data: []byte("%")}},
+		{Secret: "secret-id", Value: value, Version: "1"},
+		{Secret: "vault:db/password", Value: value},
+	} {
+		_, err := suite.handler.RotateSecret(context.Background(), req)
+		suite.True(yarpcerrors.IsInvalidArgument(err), req.String())
+	}
+
+	// unknown secret stored by Peloton
+	suite.secretInfoOps.EXPECT().
+		GetSecret(gomock.Any(), "secret-id").
+		Return(nil, yarpcerrors.NotFoundErrorf("secret not found"))
+	_, err = suite.handler.RotateSecret(
+		context.Background(),
+		&statelesssvc.RotateSecretRequest{Secret: "secret-id", Value: value})
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	// unknown version of an external secret
+	suite.secretProvider.EXPECT().
+		GetSecret(gomock.Any(), gomock.Any()).
+		Return(nil, yarpcerrors.NotFoundErrorf("secret not found"))
+	_, err = suite.handler.RotateSecret(
+		context.Background(),
+		&statelesssvc.RotateSecretRequest{
+			Secret:  "vault:db/password",
+			Version: "5",
+		})
+	suite.True(yarpcerrors.IsNotFound(err))
+
+}
+
+// TestRotateSecretRestartFailure tests that a job failing to restart
+// is reported without stopping the restart of the other jobs
+func (suite *statelessHandlerTestSuite) TestRotateSecretRestartFailure() {
+	failedJobID := "5a6dc68a-4f8f-4b5c-9a77-6ad8e4b6b2cd"
+	failedJob := cachedmocks.NewMockJob(suite.ctrl)
+
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.secretProvider.EXPECT().
+		GetSecret(gomock.Any(), gomock.Any()).
+		Return(&jobmgrsecret.Secret{Data: []byte(testSecretStr)}, nil)
+	suite.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{
+			testJobID:   suite.cachedJob,
+			failedJobID: failedJob,
+		})
+
+	suite.expectSecretJob(suite.cachedJob, testJobID,
+		secretJobConfig("vault:db/password"))
+	suite.cachedJob.EXPECT().
+		CreateWorkflow(
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any()).
+		Return(&peloton.UpdateID{Value: testUpdateID}, nil, nil)
+	suite.goalStateDriver.EXPECT().
+		EnqueueUpdate(testPelotonJobID, gomock.Any(), gomock.Any())
+
+	suite.expectSecretJob(failedJob, failedJobID,
+		secretJobConfig("vault:db/password"))
+	failedJob.EXPECT().
+		CreateWorkflow(
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any()).
+		Return(nil, nil, yarpcerrors.AbortedErrorf("concurrency error"))
+
+	resp, err := suite.handler.RotateSecret(
+		context.Background(),
+		&statelesssvc.RotateSecretRequest{Secret: "vault:db/password"})
+	suite.NoError(err)
+	suite.Equal([]*v1alphapeloton.JobID{{Value: testJobID}}, resp.GetJobIds())
+	suite.Len(resp.GetFailures(), 1)
+	suite.Equal(failedJobID, resp.GetFailures()[0].GetJobId().GetValue())
+	suite.Contains(resp.GetFailures()[0].GetMessage(), "concurrency error")
+}
+
 func TestStatelessServiceHandler(t *testing.T) {
 	suite.Run(t, new(statelessHandlerTestSuite))
 }
+
+// expectUsageTask sets the expectations to get the runtime of a
+// cached task of a job for its resource usage
+func (suite *statelessHandlerTestSuite) expectUsageTask(
+	state pbtask.TaskState,
+	mesosTaskID string,
+) cached.Task {
+	cachedTask := cachedmocks.NewMockTask(suite.ctrl)
+	cachedTask.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbtask.RuntimeInfo{
+			State:       state,
+			MesosTaskId: &mesos.TaskID{Value: &mesosTaskID},
+		}, nil)
+	return cachedTask
+}
+
+// TestGetJobUsageSuccess tests getting the resource usage of a job
+func (suite *statelessHandlerTestSuite) TestGetJobUsageSuccess() {
+	cachedConfig := cachedmocks.NewMockJobConfigCache(suite.ctrl)
+
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.jobFactory.EXPECT().
+		GetJob(testPelotonJobID).
+		Return(suite.cachedJob)
+	suite.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(cachedConfig, nil)
+	cachedConfig.EXPECT().
+		GetRespoolID().
+		Return(&peloton.ResourcePoolID{Value: testRespoolID.GetValue()}).
+		AnyTimes()
+	suite.cachedJob.EXPECT().ID().Return(testPelotonJobID).AnyTimes()
+	suite.cachedJob.EXPECT().
+		GetAllTasks().
+		Return(map[uint32]cached.Task{
+			0: suite.expectUsageTask(pbtask.TaskState_RUNNING, testPrevMesosTaskID),
+			1: suite.expectUsageTask(pbtask.TaskState_RUNNING, testMesosTaskID),
+			2: suite.expectUsageTask(pbtask.TaskState_KILLED, "killed-task-id"),
+		})
+	suite.hostMgrClient.EXPECT().
+		GetPodUsage(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *hostsvc.GetPodUsageRequest) {
+			suite.ElementsMatch(
+				[]string{testPrevMesosTaskID, testMesosTaskID},
+				req.GetPodIds())
+		}).
+		Return(&hostsvc.GetPodUsageResponse{
+			Usages: []*hostsvc.PodUsage{
+				{
+					PodId: testPrevMesosTaskID,
+					Samples: []*hostsvc.PodUsageSample{
+						{Timestamp: 1, Cpus: 5, MemBytes: 1024 * 1024 * 1024},
+						{Timestamp: 2, Cpus: 1, MemBytes: 100 * 1024 * 1024},
+					},
+				},
+				{
+					PodId: testMesosTaskID,
+					Samples: []*hostsvc.PodUsageSample{
+						{Timestamp: 2, Cpus: 0.5, MemBytes: 50 * 1024 * 1024},
+					},
+				},
+			},
+		}, nil)
+
+	resp, err := suite.handler.GetJobUsage(
+		context.Background(),
+		&statelesssvc.GetJobUsageRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+		})
+	suite.NoError(err)
+	suite.Len(resp.GetJobs(), 1)
+	suite.Equal(testJobID, resp.GetJobs()[0].GetJobId().GetValue())
+	suite.Equal(testRespoolID.GetValue(),
+		resp.GetJobs()[0].GetRespoolId().GetValue())
+	suite.Equal(uint32(2), resp.GetJobs()[0].GetPodCount())
+	suite.Equal(1.5, resp.GetJobs()[0].GetUsage().GetCpu())
+	suite.Equal(float64(150), resp.GetJobs()[0].GetUsage().GetMemMb())
+	suite.Equal(resp.GetJobs()[0].GetUsage(), resp.GetTotal())
+}
+
+// TestGetJobUsageRespool tests getting the resource usage of all the
+// jobs in the subtree of a resource pool
+func (suite *statelessHandlerTestSuite) TestGetJobUsageRespool() {
+	childJobID := "0e7c3a0d-5d3c-4bb4-9a3b-2f0f3c2d1f6e"
+	otherJobID := "5a6dc68a-4f8f-4b5c-9a77-6ad8e4b6b2cd"
+	childJob := cachedmocks.NewMockJob(suite.ctrl)
+	otherJob := cachedmocks.NewMockJob(suite.ctrl)
+	cachedConfig := cachedmocks.NewMockJobConfigCache(suite.ctrl)
+	childConfig := cachedmocks.NewMockJobConfigCache(suite.ctrl)
+	otherConfig := cachedmocks.NewMockJobConfigCache(suite.ctrl)
+
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.respoolClient.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{
+			ResourcePools: []*respool.ResourcePoolInfo{
+				{
+					Id: &peloton.ResourcePoolID{Value: common.RootResPoolID},
+					Children: []*peloton.ResourcePoolID{
+						{Value: testRespoolID.GetValue()},
+						{Value: "other-respool"},
+					},
+				},
+				{
+					Id: &peloton.ResourcePoolID{Value: testRespoolID.GetValue()},
+					Children: []*peloton.ResourcePoolID{
+						{Value: "child-respool"},
+					},
+				},
+				{Id: &peloton.ResourcePoolID{Value: "child-respool"}},
+				{Id: &peloton.ResourcePoolID{Value: "other-respool"}},
+			},
+		}, nil)
+	suite.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{
+			testJobID:  suite.cachedJob,
+			childJobID: childJob,
+			otherJobID: otherJob,
+		})
+	suite.cachedJob.EXPECT().ID().Return(testPelotonJobID).AnyTimes()
+	childJob.EXPECT().ID().Return(&peloton.JobID{Value: childJobID}).AnyTimes()
+	otherJob.EXPECT().ID().Return(&peloton.JobID{Value: otherJobID}).AnyTimes()
+	suite.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(cachedConfig, nil)
+	childJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(childConfig, nil)
+	otherJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(otherConfig, nil)
+	cachedConfig.EXPECT().
+		GetRespoolID().
+		Return(&peloton.ResourcePoolID{Value: testRespoolID.GetValue()}).
+		AnyTimes()
+	childConfig.EXPECT().
+		GetRespoolID().
+		Return(&peloton.ResourcePoolID{Value: "child-respool"}).
+		AnyTimes()
+	otherConfig.EXPECT().
+		GetRespoolID().
+		Return(&peloton.ResourcePoolID{Value: "other-respool"}).
+		AnyTimes()
+	suite.cachedJob.EXPECT().
+		GetAllTasks().
+		Return(map[uint32]cached.Task{
+			0: suite.expectUsageTask(pbtask.TaskState_RUNNING, testMesosTaskID),
+		})
+	childJob.EXPECT().
+		GetAllTasks().
+		Return(map[uint32]cached.Task{
+			0: suite.expectUsageTask(pbtask.TaskState_RUNNING, "child-task-id"),
+		})
+	suite.hostMgrClient.EXPECT().
+		GetPodUsage(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *hostsvc.GetPodUsageRequest) {
+			suite.ElementsMatch(
+				[]string{testMesosTaskID, "child-task-id"},
+				req.GetPodIds())
+		}).
+		Return(&hostsvc.GetPodUsageResponse{
+			Usages: []*hostsvc.PodUsage{
+				{
+					PodId: "child-task-id",
+					Samples: []*hostsvc.PodUsageSample{
+						{Timestamp: 1, Cpus: 2, MemBytes: 10 * 1024 * 1024},
+					},
+				},
+			},
+		}, nil)
+
+	resp, err := suite.handler.GetJobUsage(
+		context.Background(),
+		&statelesssvc.GetJobUsageRequest{
+			RespoolId: testRespoolID,
+		})
+	suite.NoError(err)
+	suite.Len(resp.GetJobs(), 2)
+	jobUsages := make(map[string]*statelesssvc.JobUsage)
+	for _, jobUsage := range resp.GetJobs() {
+		jobUsages[jobUsage.GetJobId().GetValue()] = jobUsage
+	}
+	suite.Zero(jobUsages[testJobID].GetPodCount())
+	suite.Equal("child-respool",
+		jobUsages[childJobID].GetRespoolId().GetValue())
+	suite.Equal(uint32(1), jobUsages[childJobID].GetPodCount())
+	suite.Equal(float64(2), resp.GetTotal().GetCpu())
+	suite.Equal(float64(10), resp.GetTotal().GetMemMb())
+}
+
+// TestGetJobUsageRespoolNotFound tests getting the resource usage of
+// a resource pool which does not exist
+func (suite *statelessHandlerTestSuite) TestGetJobUsageRespoolNotFound() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.respoolClient.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{
+			ResourcePools: []*respool.ResourcePoolInfo{
+				{Id: &peloton.ResourcePoolID{Value: common.RootResPoolID}},
+			},
+		}, nil)
+
+	_, err := suite.handler.GetJobUsage(
+		context.Background(),
+		&statelesssvc.GetJobUsageRequest{
+			RespoolId: testRespoolID,
+		})
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestGetJobUsageRespoolQueryFailure tests the failure to look up the
+// resource pools from resource manager
+func (suite *statelessHandlerTestSuite) TestGetJobUsageRespoolQueryFailure() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.respoolClient.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(nil, yarpcerrors.UnavailableErrorf("test error"))
+
+	_, err := suite.handler.GetJobUsage(
+		context.Background(),
+		&statelesssvc.GetJobUsageRequest{
+			RespoolId: testRespoolID,
+		})
+	suite.True(yarpcerrors.IsUnavailable(err))
+}
+
+// TestGetJobUsageInvalidRequest tests getting the resource usage with
+// none or both of job id and respool id set
+func (suite *statelessHandlerTestSuite) TestGetJobUsageInvalidRequest() {
+	for _, req := range []*statelesssvc.GetJobUsageRequest{
+		{},
+		{
+			JobId:     &v1alphapeloton.JobID{Value: testJobID},
+			RespoolId: testRespoolID,
+		},
+	} {
+		suite.candidate.EXPECT().IsLeader().Return(true)
+		_, err := suite.handler.GetJobUsage(context.Background(), req)
+		suite.True(yarpcerrors.IsInvalidArgument(err))
+	}
+}
+
+// TestGetJobUsageJobNotFound tests getting the resource usage of a job
+// which is not in the cache
+func (suite *statelessHandlerTestSuite) TestGetJobUsageJobNotFound() {
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.jobFactory.EXPECT().
+		GetJob(testPelotonJobID).
+		Return(nil)
+
+	_, err := suite.handler.GetJobUsage(
+		context.Background(),
+		&statelesssvc.GetJobUsageRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+		})
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestGetJobUsageHostMgrFailure tests the failure to get the resource
+// usage of the pods from host manager
+func (suite *statelessHandlerTestSuite) TestGetJobUsageHostMgrFailure() {
+	cachedConfig := cachedmocks.NewMockJobConfigCache(suite.ctrl)
+
+	suite.candidate.EXPECT().IsLeader().Return(true)
+	suite.jobFactory.EXPECT().
+		GetJob(testPelotonJobID).
+		Return(suite.cachedJob)
+	suite.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(cachedConfig, nil)
+	cachedConfig.EXPECT().
+		GetRespoolID().
+		Return(&peloton.ResourcePoolID{Value: testRespoolID.GetValue()}).
+		AnyTimes()
+	suite.cachedJob.EXPECT().ID().Return(testPelotonJobID).AnyTimes()
+	suite.cachedJob.EXPECT().
+		GetAllTasks().
+		Return(map[uint32]cached.Task{
+			0: suite.expectUsageTask(pbtask.TaskState_RUNNING, testMesosTaskID),
+		})
+	suite.hostMgrClient.EXPECT().
+		GetPodUsage(gomock.Any(), gomock.Any()).
+		Return(nil, yarpcerrors.UnavailableErrorf("test error"))
+
+	_, err := suite.handler.GetJobUsage(
+		context.Background(),
+		&statelesssvc.GetJobUsageRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+		})
+	suite.True(yarpcerrors.IsUnavailable(err))
+}
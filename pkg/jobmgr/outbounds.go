@@ -0,0 +1,41 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobmgr
+
+import (
+	"github.com/uber/peloton/pkg/common"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/transport/grpc"
+)
+
+// NewOutbounds returns the outbounds of job manager to the leaders of
+// resource manager and host manager. Host manager is also called over
+// streams, to read the logs of pods and to run commands inside them.
+func NewOutbounds(
+	resmgrOutbound *grpc.Outbound,
+	hostmgrOutbound *grpc.Outbound,
+) yarpc.Outbounds {
+	return yarpc.Outbounds{
+		common.PelotonResourceManager: transport.Outbounds{
+			Unary: resmgrOutbound,
+		},
+		common.PelotonHostManager: transport.Outbounds{
+			Unary:  hostmgrOutbound,
+			Stream: hostmgrOutbound,
+		},
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobmgr
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/common"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/transport/grpc"
+)
+
+// fakeHostService is the host manager service serving the test
+// streams, the other procedures are not implemented.
+type fakeHostService struct {
+	hostsvc.InternalHostServiceYARPCServer
+}
+
+// GetPodLogs streams two lines of logs.
+func (s *fakeHostService) GetPodLogs(
+	req *hostsvc.GetPodLogsRequest,
+	stream hostsvc.InternalHostServiceServiceGetPodLogsYARPCServer,
+) error {
+	for i, data := range []string{"line1\n", "line2\n"} {
+		if err := stream.Send(&hostsvc.GetPodLogsResponse{
+			Data:   []byte(req.GetPodId() + ": " + data),
+			Offset: int64(i),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExecPod echoes the command and its standard input on stdout, and
+// exits once the standard input is closed.
+func (s *fakeHostService) ExecPod(
+	stream hostsvc.InternalHostServiceServiceExecPodYARPCServer,
+) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&hostsvc.ExecPodResponse{
+		Stream: hostsvc.LogStream_LOG_STREAM_STDOUT,
+		Data:   []byte(req.GetPodId() + ": " + strings.Join(req.GetCommand(), " ") + "\n"),
+	}); err != nil {
+		return err
+	}
+
+	stdin := req.GetStdin()
+	for len(stdin) > 0 {
+		if err := stream.Send(&hostsvc.ExecPodResponse{
+			Stream: hostsvc.LogStream_LOG_STREAM_STDOUT,
+			Data:   stdin,
+		}); err != nil {
+			return err
+		}
+
+		in, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		stdin = in.GetStdin()
+	}
+
+	return stream.Send(&hostsvc.ExecPodResponse{
+		Exited:   true,
+		ExitCode: 0,
+	})
+}
+
+type OutboundsTestSuite struct {
+	suite.Suite
+
+	hostmgrDispatcher *yarpc.Dispatcher
+	jobmgrDispatcher  *yarpc.Dispatcher
+	hostmgrClient     hostsvc.InternalHostServiceYARPCClient
+}
+
+func (suite *OutboundsTestSuite) SetupTest() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.NoError(err)
+
+	hostmgrTransport := grpc.NewTransport()
+	suite.hostmgrDispatcher = yarpc.NewDispatcher(yarpc.Config{
+		Name:     common.PelotonHostManager,
+		Inbounds: yarpc.Inbounds{hostmgrTransport.NewInbound(listener)},
+	})
+	suite.hostmgrDispatcher.Register(
+		hostsvc.BuildInternalHostServiceYARPCProcedures(&fakeHostService{}))
+	suite.NoError(suite.hostmgrDispatcher.Start())
+
+	// job manager calls host manager over the outbounds it
+	// is started with
+	addr := listener.Addr().String()
+	jobmgrTransport := grpc.NewTransport()
+	suite.jobmgrDispatcher = yarpc.NewDispatcher(yarpc.Config{
+		Name: common.PelotonJobManager,
+		Outbounds: NewOutbounds(
+			jobmgrTransport.NewSingleOutbound(addr),
+			jobmgrTransport.NewSingleOutbound(addr),
+		),
+	})
+	suite.NoError(suite.jobmgrDispatcher.Start())
+
+	suite.hostmgrClient = hostsvc.NewInternalHostServiceYARPCClient(
+		suite.jobmgrDispatcher.ClientConfig(common.PelotonHostManager))
+}
+
+func (suite *OutboundsTestSuite) TearDownTest() {
+	suite.NoError(suite.jobmgrDispatcher.Stop())
+	suite.NoError(suite.hostmgrDispatcher.Stop())
+}
+
+func TestOutbounds(t *testing.T) {
+	suite.Run(t, new(OutboundsTestSuite))
+}
+
+// TestGetPodLogs tests streaming the logs of a pod from host manager
+// through the job manager outbounds
+func (suite *OutboundsTestSuite) TestGetPodLogs() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := suite.hostmgrClient.GetPodLogs(
+		ctx,
+		&hostsvc.GetPodLogsRequest{PodId: "pod-1"},
+	)
+	suite.NoError(err)
+	defer stream.CloseSend()
+
+	var data []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		suite.NoError(err)
+		if err != nil {
+			return
+		}
+		data = append(data, string(resp.GetData()))
+	}
+	suite.Equal([]string{"pod-1: line1\n", "pod-1: line2\n"}, data)
+}
+
+// TestExecPod tests running a command in a pod on host manager
+// through the job manager outbounds
+func (suite *OutboundsTestSuite) TestExecPod() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := suite.hostmgrClient.ExecPod(ctx)
+	suite.NoError(err)
+
+	suite.NoError(stream.Send(&hostsvc.ExecPodRequest{
+		Hostname: "host-1",
+		PodId:    "pod-1",
+		Command:  []string{"cat", "-"},
+		Stdin:    []byte("input1\n"),
+	}))
+	suite.NoError(stream.Send(&hostsvc.ExecPodRequest{
+		Stdin: []byte("input2\n"),
+	}))
+	suite.NoError(stream.Send(&hostsvc.ExecPodRequest{}))
+
+	var data []string
+	for {
+		resp, err := stream.Recv()
+		suite.NoError(err)
+		if err != nil {
+			return
+		}
+		if resp.GetExited() {
+			suite.Equal(int32(0), resp.GetExitCode())
+			break
+		}
+		suite.Equal(hostsvc.LogStream_LOG_STREAM_STDOUT, resp.GetStream())
+		data = append(data, string(resp.GetData()))
+	}
+	suite.Equal([]string{"pod-1: cat -\n", "input1\n", "input2\n"}, data)
+	suite.NoError(stream.CloseSend())
+}
@@ -16,6 +16,7 @@ package podsvc
 
 import (
 	"context"
+	"io"
 	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
@@ -28,6 +29,7 @@ import (
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
 	"github.com/uber/peloton/pkg/common/api"
+	"github.com/uber/peloton/pkg/common/audit"
 	"github.com/uber/peloton/pkg/common/leader"
 	"github.com/uber/peloton/pkg/common/util"
 	versionutil "github.com/uber/peloton/pkg/common/util/entityversion"
@@ -50,6 +52,10 @@ import (
 
 const (
 	_frameworkName = "Peloton"
+
+	// _execPodProcedure is the procedure recorded in the audit trail
+	// for the calls to ExecPod
+	_execPodProcedure = "peloton.api.v1alpha.pod.svc.PodService::ExecPod"
 )
 
 var _errPodNotInCache = yarpcerrors.InternalErrorf("pod not present in cache, please retry action")
@@ -66,6 +72,7 @@ type serviceHandler struct {
 	logManager         logmanager.LogManager
 	mesosAgentWorkDir  string
 	hostMgrClient      hostsvc.InternalHostServiceYARPCClient
+	auditTrail         audit.Trail
 }
 
 // InitV1AlphaPodServiceHandler initializes the Pod Service Handler
@@ -81,6 +88,7 @@ func InitV1AlphaPodServiceHandler(
 	logManager logmanager.LogManager,
 	mesosAgentWorkDir string,
 	hostMgrClient hostsvc.InternalHostServiceYARPCClient,
+	auditTrail audit.Trail,
 ) {
 	handler := &serviceHandler{
 		jobStore:           jobStore,
@@ -94,6 +102,7 @@ func InitV1AlphaPodServiceHandler(
 		logManager:         logManager,
 		mesosAgentWorkDir:  mesosAgentWorkDir,
 		hostMgrClient:      hostMgrClient,
+		auditTrail:         auditTrail,
 	}
 	d.Register(svc.BuildPodServiceYARPCProcedures(handler))
 }
@@ -462,6 +471,17 @@ func (h *serviceHandler) GetPod(
 		Status: podStatus,
 	}
 
+	if req.GetIncludeUsage() &&
+		taskRuntime.GetState() == pbtask.TaskState_RUNNING {
+		currentPodInfo.Usage, err = h.getPodUsage(
+			ctx,
+			taskRuntime.GetMesosTaskId().GetValue(),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get pod usage")
+		}
+	}
+
 	var prevPodInfos []*pbpod.PodInfo
 	if req.GetLimit() != 1 {
 		prevPodInfos, err = h.getPodInfoForAllPodRuns(
@@ -611,6 +631,204 @@ func (h *serviceHandler) BrowsePodSandbox(
 	return resp, nil
 }
 
+func (h *serviceHandler) GetPodLogs(
+	req *svc.GetPodLogsRequest,
+	stream svc.PodServiceServiceGetPodLogsYARPCServer,
+) (err error) {
+	defer func() {
+		headers := yarpcutil.GetHeaders(stream.Context())
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("PodSVC.GetPodLogs failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("headers", headers).
+			Debug("PodSVC.GetPodLogs succeeded")
+	}()
+
+	jobID, instanceID, err := util.ParseTaskID(req.GetPodName().GetValue())
+	if err != nil {
+		return err
+	}
+
+	hostname, podID, _, err := h.getHostInfo(
+		stream.Context(),
+		jobID,
+		instanceID,
+		req.GetPodId().GetValue(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(hostname) == 0 {
+		return yarpcerrors.AbortedErrorf("pod has not been run")
+	}
+
+	hostStream, err := h.hostMgrClient.GetPodLogs(
+		stream.Context(),
+		&hostsvc.GetPodLogsRequest{
+			Hostname: hostname,
+			PodId:    podID,
+			Stream:   hostsvc.LogStream(req.GetStream()),
+			Offset:   req.GetOffset(),
+			Length:   req.GetLength(),
+			Follow:   req.GetFollow(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer hostStream.CloseSend()
+
+	for {
+		resp, err := hostStream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(&svc.GetPodLogsResponse{
+			Data:   resp.GetData(),
+			Offset: resp.GetOffset(),
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// ExecPod runs a command inside a running pod through the host manager,
+// and records the call in the audit trail.
+func (h *serviceHandler) ExecPod(
+	stream svc.PodServiceServiceExecPodYARPCServer,
+) (err error) {
+	var req *svc.ExecPodRequest
+	var exitCode int32
+	defer func() {
+		headers := yarpcutil.GetHeaders(stream.Context())
+		if req != nil {
+			h.auditTrail.Record(&audit.Event{
+				User:      audit.UserFromHeaders(headers),
+				Procedure: _execPodProcedure,
+				Resource:  req.GetPodName().GetValue(),
+				Details: map[string]interface{}{
+					"pod_id":    req.GetPodId().GetValue(),
+					"command":   req.GetCommand(),
+					"tty":       req.GetTty(),
+					"exit_code": exitCode,
+				},
+				Error: err,
+			})
+		}
+
+		if err != nil {
+			log.WithField("request", req).
+				WithField("headers", headers).
+				WithError(err).
+				Warn("PodSVC.ExecPod failed")
+			err = yarpcutil.ConvertToYARPCError(err)
+			return
+		}
+
+		log.WithField("request", req).
+			WithField("headers", headers).
+			WithField("exit_code", exitCode).
+			Debug("PodSVC.ExecPod succeeded")
+	}()
+
+	req, err = stream.Recv()
+	if err != nil {
+		return err
+	}
+	if len(req.GetCommand()) == 0 {
+		return yarpcerrors.InvalidArgumentErrorf("command is empty")
+	}
+
+	jobID, instanceID, err := util.ParseTaskID(req.GetPodName().GetValue())
+	if err != nil {
+		return err
+	}
+
+	hostname, podID, _, err := h.getHostInfo(
+		stream.Context(),
+		jobID,
+		instanceID,
+		req.GetPodId().GetValue(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(hostname) == 0 {
+		return yarpcerrors.AbortedErrorf("pod is not running")
+	}
+
+	hostStream, err := h.hostMgrClient.ExecPod(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	if err := hostStream.Send(&hostsvc.ExecPodRequest{
+		Hostname: hostname,
+		PodId:    podID,
+		Command:  req.GetCommand(),
+		Tty:      req.GetTty(),
+		Stdin:    req.GetStdin(),
+	}); err != nil {
+		return err
+	}
+
+	// forward the standard input until the client closes it
+	go func() {
+		for {
+			in, err := stream.Recv()
+			if err != nil {
+				hostStream.CloseSend()
+				return
+			}
+			if err := hostStream.Send(&hostsvc.ExecPodRequest{
+				Stdin: in.GetStdin(),
+			}); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		resp, err := hostStream.Recv()
+		if err == io.EOF {
+			return yarpcerrors.InternalErrorf("command output ended before exit")
+		}
+		if err != nil {
+			return err
+		}
+
+		if resp.GetExited() {
+			exitCode = resp.GetExitCode()
+		}
+
+		if err := stream.Send(&svc.ExecPodResponse{
+			Stream:   svc.LogStream(resp.GetStream()),
+			Data:     resp.GetData(),
+			Exited:   resp.GetExited(),
+			ExitCode: resp.GetExitCode(),
+		}); err != nil {
+			return err
+		}
+
+		if resp.GetExited() {
+			return nil
+		}
+	}
+}
+
 func (h *serviceHandler) RefreshPod(
 	ctx context.Context,
 	req *svc.RefreshPodRequest,
@@ -786,6 +1004,28 @@ func (h *serviceHandler) getHostInfo(
 	return hostname, podid, agentID, nil
 }
 
+// getPodUsage returns the recent resource usage of a pod run collected
+// by host manager, or nil if none has been collected yet.
+func (h *serviceHandler) getPodUsage(
+	ctx context.Context,
+	podID string,
+) (*pbpod.PodUsage, error) {
+	resp, err := h.hostMgrClient.GetPodUsage(
+		ctx,
+		&hostsvc.GetPodUsageRequest{PodIds: []string{podID}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, usage := range resp.GetUsages() {
+		if usage.GetPodId() == podID {
+			return api.ConvertHostPodUsageToPodUsage(usage), nil
+		}
+	}
+	return nil, nil
+}
+
 // getSandboxPathInfo - return details such as hostname, agentID,
 // frameworkID and podName to create sandbox path.
 func (h *serviceHandler) getSandboxPathInfo(ctx context.Context,
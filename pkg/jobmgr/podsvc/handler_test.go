@@ -17,6 +17,7 @@ package podsvc
 import (
 	"context"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -28,11 +29,14 @@ import (
 	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/pod"
 	"github.com/uber/peloton/.gen/peloton/api/v1alpha/pod/svc"
+	podsvcmocks "github.com/uber/peloton/.gen/peloton/api/v1alpha/pod/svc/mocks"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 	hostmocks "github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc/mocks"
 	"github.com/uber/peloton/.gen/peloton/private/models"
 
 	"github.com/uber/peloton/pkg/common/api"
+	"github.com/uber/peloton/pkg/common/audit"
+	auditmocks "github.com/uber/peloton/pkg/common/audit/mocks"
 	leadermocks "github.com/uber/peloton/pkg/common/leader/mocks"
 	"github.com/uber/peloton/pkg/common/util"
 	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
@@ -73,6 +77,7 @@ type podHandlerTestSuite struct {
 	frameworkInfoStore  *storemocks.MockFrameworkInfoStore
 	hostmgrClient       *hostmocks.MockInternalHostServiceYARPCClient
 	logmanager          *logmanagermocks.MockLogManager
+	auditTrail          *auditmocks.MockTrail
 	mesosAgentWorkDir   string
 }
 
@@ -87,6 +92,7 @@ func (suite *podHandlerTestSuite) SetupTest() {
 	suite.frameworkInfoStore = storemocks.NewMockFrameworkInfoStore(suite.ctrl)
 	suite.hostmgrClient = hostmocks.NewMockInternalHostServiceYARPCClient(suite.ctrl)
 	suite.logmanager = logmanagermocks.NewMockLogManager(suite.ctrl)
+	suite.auditTrail = auditmocks.NewMockTrail(suite.ctrl)
 	suite.mesosAgentWorkDir = "test"
 	suite.mockedPodEventsOps = objectmocks.NewMockPodEventsOps(suite.ctrl)
 	suite.mockTaskConfigV2Ops = objectmocks.NewMockTaskConfigV2Ops(suite.ctrl)
@@ -101,6 +107,7 @@ func (suite *podHandlerTestSuite) SetupTest() {
 		hostMgrClient:      suite.hostmgrClient,
 		logManager:         suite.logmanager,
 		mesosAgentWorkDir:  suite.mesosAgentWorkDir,
+		auditTrail:         suite.auditTrail,
 	}
 }
 
@@ -1437,6 +1444,116 @@ func (suite *podHandlerTestSuite) TestGetPodCurrentOnly() {
 	suite.Empty(response.GetPrevious())
 }
 
+// TestGetPodWithUsage tests getting the current run of a running pod along
+// with its resource usage
+func (suite *podHandlerTestSuite) TestGetPodWithUsage() {
+	request := &svc.GetPodRequest{
+		PodName: &v1alphapeloton.PodName{
+			Value: testPodName,
+		},
+		StatusOnly:   true,
+		Limit:        uint32(1),
+		IncludeUsage: true,
+	}
+	pelotonJob := &peloton.JobID{Value: testJobID}
+	mesosTaskID := testPodID
+
+	gomock.InOrder(
+		suite.podStore.EXPECT().
+			GetTaskRuntime(gomock.Any(), pelotonJob, uint32(testInstanceID)).
+			Return(
+				&pbtask.RuntimeInfo{
+					State:       pbtask.TaskState_RUNNING,
+					GoalState:   pbtask.TaskState_RUNNING,
+					MesosTaskId: &mesos.TaskID{Value: &mesosTaskID},
+				}, nil),
+
+		suite.hostmgrClient.EXPECT().
+			GetPodUsage(
+				gomock.Any(),
+				&hostsvc.GetPodUsageRequest{PodIds: []string{testPodID}},
+			).Return(&hostsvc.GetPodUsageResponse{
+			Usages: []*hostsvc.PodUsage{
+				{
+					PodId: testPodID,
+					Samples: []*hostsvc.PodUsageSample{
+						{
+							Timestamp: 1546300800,
+							Cpus:      0.5,
+							MemBytes:  128 * 1024 * 1024,
+						},
+					},
+				},
+			},
+		}, nil),
+	)
+
+	response, err := suite.handler.GetPod(context.Background(), request)
+	suite.NoError(err)
+	samples := response.GetCurrent().GetUsage().GetSamples()
+	suite.Len(samples, 1)
+	suite.Equal(0.5, samples[0].GetUsage().GetCpu())
+	suite.Equal(float64(128), samples[0].GetUsage().GetMemMb())
+}
+
+// TestGetPodWithUsageNotRunning tests that the resource usage is not
+// looked up for a pod which is not running
+func (suite *podHandlerTestSuite) TestGetPodWithUsageNotRunning() {
+	request := &svc.GetPodRequest{
+		PodName: &v1alphapeloton.PodName{
+			Value: testPodName,
+		},
+		StatusOnly:   true,
+		Limit:        uint32(1),
+		IncludeUsage: true,
+	}
+	pelotonJob := &peloton.JobID{Value: testJobID}
+
+	suite.podStore.EXPECT().
+		GetTaskRuntime(gomock.Any(), pelotonJob, uint32(testInstanceID)).
+		Return(&pbtask.RuntimeInfo{
+			State:     pbtask.TaskState_KILLED,
+			GoalState: pbtask.TaskState_KILLED,
+		}, nil)
+
+	response, err := suite.handler.GetPod(context.Background(), request)
+	suite.NoError(err)
+	suite.Nil(response.GetCurrent().GetUsage())
+}
+
+// TestGetPodWithUsageFailure tests the failure to get the resource usage
+// of a running pod from host manager
+func (suite *podHandlerTestSuite) TestGetPodWithUsageFailure() {
+	request := &svc.GetPodRequest{
+		PodName: &v1alphapeloton.PodName{
+			Value: testPodName,
+		},
+		StatusOnly:   true,
+		Limit:        uint32(1),
+		IncludeUsage: true,
+	}
+	pelotonJob := &peloton.JobID{Value: testJobID}
+	mesosTaskID := testPodID
+
+	gomock.InOrder(
+		suite.podStore.EXPECT().
+			GetTaskRuntime(gomock.Any(), pelotonJob, uint32(testInstanceID)).
+			Return(
+				&pbtask.RuntimeInfo{
+					State:       pbtask.TaskState_RUNNING,
+					GoalState:   pbtask.TaskState_RUNNING,
+					MesosTaskId: &mesos.TaskID{Value: &mesosTaskID},
+				}, nil),
+
+		suite.hostmgrClient.EXPECT().
+			GetPodUsage(gomock.Any(), gomock.Any()).
+			Return(nil, yarpcerrors.UnavailableErrorf("test error")),
+	)
+
+	_, err := suite.handler.GetPod(context.Background(), request)
+	suite.Error(err)
+}
+
 // TestGetPodSuccessLimit tests the success case of getting pod info with a limit
 func (suite *podHandlerTestSuite) TestGetPodSuccessLimit() {
 	request := &svc.GetPodRequest{
@@ -2095,3 +2212,339 @@ func (suite *podHandlerTestSuite) TestDeletePodEventsStoreError() {
 func TestPodServiceHandler(t *testing.T) {
 	suite.Run(t, new(podHandlerTestSuite))
 }
+
+// TestGetPodLogsSuccess tests streaming pod logs from host manager
+func (suite *podHandlerTestSuite) TestGetPodLogsSuccess() {
+	request := &svc.GetPodLogsRequest{
+		PodName: &v1alphapeloton.PodName{
+			Value: testPodName,
+		},
+		PodId: &v1alphapeloton.PodID{
+			Value: testPodID,
+		},
+		Stream: svc.LogStream_LOG_STREAM_STDERR,
+		Offset: 10,
+		Length: 20,
+	}
+
+	hostname := "hostname"
+	mesosTaskID := testPodID
+	events := []*pbtask.PodEvent{
+		{
+			TaskId: &mesos.TaskID{
+				Value: &mesosTaskID,
+			},
+			ActualState: pbtask.TaskState_RUNNING.String(),
+			Hostname:    hostname,
+			AgentID:     "agentID",
+		},
+	}
+
+	stream := podsvcmocks.NewMockPodServiceServiceGetPodLogsYARPCServer(suite.ctrl)
+	hostStream := hostmocks.NewMockInternalHostServiceServiceGetPodLogsYARPCClient(suite.ctrl)
+	stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+
+	gomock.InOrder(
+		suite.mockedPodEventsOps.EXPECT().
+			GetAll(gomock.Any(), testJobID, uint32(testInstanceID), testPodID).
+			Return(events, nil),
+
+		suite.hostmgrClient.EXPECT().
+			GetPodLogs(
+				gomock.Any(),
+				&hostsvc.GetPodLogsRequest{
+					Hostname: hostname,
+					PodId:    testPodID,
+					Stream:   hostsvc.LogStream_LOG_STREAM_STDERR,
+					Offset:   10,
+					Length:   20,
+				},
+			).Return(hostStream, nil),
+
+		hostStream.EXPECT().Recv().
+			Return(&hostsvc.GetPodLogsResponse{
+				Data:   []byte("test logs"),
+				Offset: 10,
+			}, nil),
+
+		stream.EXPECT().Send(&svc.GetPodLogsResponse{
+			Data:   []byte("test logs"),
+			Offset: 10,
+		}).Return(nil),
+
+		hostStream.EXPECT().Recv().Return(nil, io.EOF),
+
+		hostStream.EXPECT().CloseSend().Return(nil),
+	)
+
+	suite.NoError(suite.handler.GetPodLogs(request, stream))
+}
+
+// TestGetPodLogsInvalidPodName tests GetPodLogs failure due to
+// invalid pod name
+func (suite *podHandlerTestSuite) TestGetPodLogsInvalidPodName() {
+	request := &svc.GetPodLogsRequest{
+		PodName: &v1alphapeloton.PodName{
+			Value: "InvalidPodName",
+		},
+	}
+
+	stream := podsvcmocks.NewMockPodServiceServiceGetPodLogsYARPCServer(suite.ctrl)
+	stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+
+	suite.Error(suite.handler.GetPodLogs(request, stream))
+}
+
+// TestGetPodLogsAbort tests GetPodLogs failure when the pod has
+// not been run
+func (suite *podHandlerTestSuite) TestGetPodLogsAbort() {
+	request := &svc.GetPodLogsRequest{
+		PodName: &v1alphapeloton.PodName{
+			Value: testPodName,
+		},
+	}
+
+	stream := podsvcmocks.NewMockPodServiceServiceGetPodLogsYARPCServer(suite.ctrl)
+	stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+
+	suite.mockedPodEventsOps.EXPECT().
+		GetAll(gomock.Any(), testJobID, uint32(testInstanceID), "").
+		Return(nil, nil)
+
+	err := suite.handler.GetPodLogs(request, stream)
+	suite.Error(err)
+	suite.True(yarpcerrors.IsAborted(err))
+}
+
+// TestGetPodLogsHostStreamFailure tests GetPodLogs failure when
+// receiving from host manager fails
+func (suite *podHandlerTestSuite) TestGetPodLogsHostStreamFailure() {
+	request := &svc.GetPodLogsRequest{
+		PodName: &v1alphapeloton.PodName{
+			Value: testPodName,
+		},
+	}
+
+	mesosTaskID := testPodID
+	events := []*pbtask.PodEvent{
+		{
+			TaskId: &mesos.TaskID{
+				Value: &mesosTaskID,
+			},
+			ActualState: pbtask.TaskState_RUNNING.String(),
+			Hostname:    "hostname",
+		},
+	}
+
+	stream := podsvcmocks.NewMockPodServiceServiceGetPodLogsYARPCServer(suite.ctrl)
+	hostStream := hostmocks.NewMockInternalHostServiceServiceGetPodLogsYARPCClient(suite.ctrl)
+	stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+
+	gomock.InOrder(
+		suite.mockedPodEventsOps.EXPECT().
+			GetAll(gomock.Any(), testJobID, uint32(testInstanceID), "").
+			Return(events, nil),
+
+		suite.hostmgrClient.EXPECT().
+			GetPodLogs(gomock.Any(), gomock.Any()).
+			Return(hostStream, nil),
+
+		hostStream.EXPECT().Recv().
+			Return(nil, yarpcerrors.NotFoundErrorf("log file not found")),
+
+		hostStream.EXPECT().CloseSend().Return(nil),
+	)
+
+	err := suite.handler.GetPodLogs(request, stream)
+	suite.Error(err)
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestExecPodSuccess tests running a command inside a pod
+func (suite *podHandlerTestSuite) TestExecPodSuccess() {
+	mesosTaskID := testPodID
+	events := []*pbtask.PodEvent{
+		{
+			TaskId: &mesos.TaskID{
+				Value: &mesosTaskID,
+			},
+			ActualState: pbtask.TaskState_RUNNING.String(),
+			Hostname:    "hostname",
+		},
+	}
+
+	stream := podsvcmocks.NewMockPodServiceServiceExecPodYARPCServer(suite.ctrl)
+	hostStream := hostmocks.NewMockInternalHostServiceServiceExecPodYARPCClient(suite.ctrl)
+	stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+
+	// the output is received once the standard input is forwarded
+	stdinClosed := make(chan struct{})
+
+	gomock.InOrder(
+		stream.EXPECT().Recv().
+			Return(&svc.ExecPodRequest{
+				PodName: &v1alphapeloton.PodName{Value: testPodName},
+				Command: []string{"cat"},
+				Stdin:   []byte("hello"),
+			}, nil),
+
+		suite.mockedPodEventsOps.EXPECT().
+			GetAll(gomock.Any(), testJobID, uint32(testInstanceID), "").
+			Return(events, nil),
+
+		suite.hostmgrClient.EXPECT().
+			ExecPod(gomock.Any()).
+			Return(hostStream, nil),
+
+		hostStream.EXPECT().
+			Send(&hostsvc.ExecPodRequest{
+				Hostname: "hostname",
+				PodId:    testPodID,
+				Command:  []string{"cat"},
+				Stdin:    []byte("hello"),
+			}).
+			Return(nil),
+
+		stream.EXPECT().Recv().
+			Return(&svc.ExecPodRequest{Stdin: []byte(" world")}, nil),
+		hostStream.EXPECT().
+			Send(&hostsvc.ExecPodRequest{Stdin: []byte(" world")}).
+			Return(nil),
+		stream.EXPECT().Recv().Return(nil, io.EOF),
+		hostStream.EXPECT().CloseSend().
+			Do(func() { close(stdinClosed) }).
+			Return(nil),
+	)
+
+	gomock.InOrder(
+		hostStream.EXPECT().Recv().
+			DoAndReturn(func() (*hostsvc.ExecPodResponse, error) {
+				<-stdinClosed
+				return &hostsvc.ExecPodResponse{
+					Stream: hostsvc.LogStream_LOG_STREAM_STDOUT,
+					Data:   []byte("hello world"),
+				}, nil
+			}),
+		stream.EXPECT().
+			Send(&svc.ExecPodResponse{
+				Stream: svc.LogStream_LOG_STREAM_STDOUT,
+				Data:   []byte("hello world"),
+			}).
+			Return(nil),
+		hostStream.EXPECT().Recv().
+			Return(&hostsvc.ExecPodResponse{Exited: true, ExitCode: 2}, nil),
+		stream.EXPECT().
+			Send(&svc.ExecPodResponse{Exited: true, ExitCode: 2}).
+			Return(nil),
+	)
+
+	suite.auditTrail.EXPECT().
+		Record(gomock.Any()).
+		Do(func(event *audit.Event) {
+			suite.Equal("anonymous", event.User)
+			suite.Equal(_execPodProcedure, event.Procedure)
+			suite.Equal(testPodName, event.Resource)
+			suite.Equal([]string{"cat"}, event.Details["command"])
+			suite.Equal(int32(2), event.Details["exit_code"])
+			suite.NoError(event.Error)
+		})
+
+	suite.NoError(suite.handler.ExecPod(stream))
+}
+
+// TestExecPodInvalidRequest tests ExecPod failure due to an invalid
+// pod name or an empty command
+func (suite *podHandlerTestSuite) TestExecPodInvalidRequest() {
+	stream := podsvcmocks.NewMockPodServiceServiceExecPodYARPCServer(suite.ctrl)
+	stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+
+	requests := []*svc.ExecPodRequest{
+		{
+			PodName: &v1alphapeloton.PodName{Value: "InvalidPodName"},
+			Command: []string{"ls"},
+		},
+		{
+			PodName: &v1alphapeloton.PodName{Value: testPodName},
+		},
+	}
+	for _, req := range requests {
+		stream.EXPECT().Recv().Return(req, nil)
+		suite.auditTrail.EXPECT().
+			Record(gomock.Any()).
+			Do(func(event *audit.Event) {
+				suite.Error(event.Error)
+			})
+		suite.Error(suite.handler.ExecPod(stream))
+	}
+
+	// nothing is audited if the request is never received
+	stream.EXPECT().Recv().Return(nil, io.EOF)
+	suite.Error(suite.handler.ExecPod(stream))
+}
+
+// TestExecPodAbort tests ExecPod failure when the pod is not running
+func (suite *podHandlerTestSuite) TestExecPodAbort() {
+	stream := podsvcmocks.NewMockPodServiceServiceExecPodYARPCServer(suite.ctrl)
+	stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+
+	stream.EXPECT().Recv().
+		Return(&svc.ExecPodRequest{
+			PodName: &v1alphapeloton.PodName{Value: testPodName},
+			Command: []string{"ls"},
+		}, nil)
+	suite.mockedPodEventsOps.EXPECT().
+		GetAll(gomock.Any(), testJobID, uint32(testInstanceID), "").
+		Return(nil, nil)
+	suite.auditTrail.EXPECT().Record(gomock.Any())
+
+	err := suite.handler.ExecPod(stream)
+	suite.True(yarpcerrors.IsAborted(err))
+}
+
+// TestExecPodHostStreamFailure tests ExecPod failure when the host
+// manager fails to run the command
+func (suite *podHandlerTestSuite) TestExecPodHostStreamFailure() {
+	mesosTaskID := testPodID
+	events := []*pbtask.PodEvent{
+		{
+			TaskId: &mesos.TaskID{
+				Value: &mesosTaskID,
+			},
+			ActualState: pbtask.TaskState_RUNNING.String(),
+			Hostname:    "hostname",
+		},
+	}
+
+	stream := podsvcmocks.NewMockPodServiceServiceExecPodYARPCServer(suite.ctrl)
+	hostStream := hostmocks.NewMockInternalHostServiceServiceExecPodYARPCClient(suite.ctrl)
+	stream.EXPECT().Context().Return(context.Background()).AnyTimes()
+
+	stdinClosed := make(chan struct{})
+	gomock.InOrder(
+		stream.EXPECT().Recv().
+			Return(&svc.ExecPodRequest{
+				PodName: &v1alphapeloton.PodName{Value: testPodName},
+				Command: []string{"ls"},
+			}, nil),
+		suite.mockedPodEventsOps.EXPECT().
+			GetAll(gomock.Any(), testJobID, uint32(testInstanceID), "").
+			Return(events, nil),
+		suite.hostmgrClient.EXPECT().
+			ExecPod(gomock.Any()).
+			Return(hostStream, nil),
+		hostStream.EXPECT().Send(gomock.Any()).Return(nil),
+		stream.EXPECT().Recv().Return(nil, io.EOF),
+		hostStream.EXPECT().CloseSend().
+			Do(func() { close(stdinClosed) }).
+			Return(nil),
+	)
+	hostStream.EXPECT().Recv().
+		DoAndReturn(func() (*hostsvc.ExecPodResponse, error) {
+			<-stdinClosed
+			return nil, yarpcerrors.NotFoundErrorf("container not found")
+		})
+	suite.auditTrail.EXPECT().Record(gomock.Any())
+
+	err := suite.handler.ExecPod(stream)
+	suite.True(yarpcerrors.IsNotFound(err))
+}
@@ -0,0 +1,616 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/background"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	"github.com/uber/peloton/pkg/storage"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/atomic"
+	"go.uber.org/yarpc/yarpcerrors"
+	"golang.org/x/time/rate"
+)
+
+const (
+	_compactorName = "retentionCompactor"
+
+	// ReportPath is the HTTP path at which the report of the last
+	// compaction run is served.
+	ReportPath = "/retention/report"
+
+	// timeout to enforce the retention policies on a single job,
+	// including the time spent waiting on the delete rate limiter
+	_compactJobTimeout = 10 * time.Minute
+)
+
+// Compactor periodically enforces the retention policies on the tables
+// which grow over the lifetime of a job, for all the jobs in the cache.
+// Deletes are rate limited, so that compaction does not impact the
+// real-time workload on the DB.
+type Compactor struct {
+	JobFactory      cached.JobFactory
+	TaskStore       storage.TaskStore
+	UpdateStore     storage.UpdateStore
+	JobConfigOps    ormobjects.JobConfigOps
+	TaskConfigV2Ops ormobjects.TaskConfigV2Ops
+	Metrics         *Metrics
+	Config          *Config
+
+	limiter *rate.Limiter
+
+	// compaction state of the jobs, so that the same data is not
+	// deleted again on every run
+	jobStates map[string]*jobState
+
+	lock sync.RWMutex
+	// report of the last compaction run
+	lastReport *Report
+}
+
+// jobState is the compaction state of a job.
+type jobState struct {
+	// run ID before which the pod events have been deleted, per instance
+	podEventsDeletedBefore map[uint32]uint64
+	// workflows whose events have all been deleted
+	compactedWorkflows map[string]bool
+	// version before which all unreferenced config versions
+	// have been deleted
+	configVersionsDeletedBefore uint64
+	// unreferenced config versions, from configVersionsDeletedBefore
+	// onwards, which have been deleted
+	deletedConfigVersions map[uint64]bool
+}
+
+// Report is the summary of a compaction run. In dry-run mode, it lists
+// the data which would have been deleted.
+type Report struct {
+	DryRun    bool      `json:"dry_run"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+
+	// total number of instances whose pod events were trimmed
+	PodEventsInstances int `json:"pod_events_instances"`
+	// total number of workflow instances whose events were trimmed
+	WorkflowEventsInstances int `json:"workflow_events_instances"`
+	// total number of config versions deleted
+	ConfigVersions int `json:"config_versions"`
+
+	Jobs []*JobReport `json:"jobs,omitempty"`
+}
+
+// JobReport is the summary of the compaction of a job.
+type JobReport struct {
+	JobID string `json:"job_id"`
+	// run ID before which the pod events were deleted, per instance
+	PodEvents map[uint32]uint64 `json:"pod_events,omitempty"`
+	// number of instances whose workflow events were trimmed,
+	// per workflow
+	WorkflowEvents map[string]int `json:"workflow_events,omitempty"`
+	// config versions deleted
+	ConfigVersions []uint64 `json:"config_versions,omitempty"`
+	// errors hit during the compaction of the job
+	Errors []string `json:"errors,omitempty"`
+}
+
+func newJobReport(jobID *peloton.JobID) *JobReport {
+	return &JobReport{
+		JobID:          jobID.GetValue(),
+		PodEvents:      make(map[uint32]uint64),
+		WorkflowEvents: make(map[string]int),
+	}
+}
+
+func (r *JobReport) addError(err error) {
+	r.Errors = append(r.Errors, err.Error())
+}
+
+func (r *JobReport) isEmpty() bool {
+	return len(r.PodEvents) == 0 &&
+		len(r.WorkflowEvents) == 0 &&
+		len(r.ConfigVersions) == 0 &&
+		len(r.Errors) == 0
+}
+
+// Register registers the compactor as a background work, if the
+// compactor is enabled.
+func (c *Compactor) Register(manager background.Manager) error {
+	c.setup()
+	if !c.Config.Enabled {
+		return nil
+	}
+
+	return manager.RegisterWorks(
+		background.Work{
+			Name: _compactorName,
+			Func: func(_ *atomic.Bool) {
+				c.Run()
+			},
+			Period: c.Config.CompactionPeriod,
+		},
+	)
+}
+
+func (c *Compactor) setup() {
+	if c.Config == nil {
+		c.Config = &Config{}
+	}
+
+	c.Config.normalize()
+	c.limiter = rate.NewLimiter(rate.Limit(c.Config.MaxDeletesPerSecond), 1)
+	c.jobStates = make(map[string]*jobState)
+}
+
+// Run enforces the retention policies on all the jobs in the cache once,
+// and returns the report of the run.
+func (c *Compactor) Run() *Report {
+	stopWatch := c.Metrics.ProcessDuration.Start()
+	defer stopWatch.Stop()
+
+	report := &Report{
+		DryRun:    c.Config.DryRun,
+		StartTime: time.Now(),
+	}
+
+	jobs := c.JobFactory.GetAllJobs()
+	for _, cachedJob := range jobs {
+		ctx, cancel := context.WithTimeout(
+			context.Background(),
+			_compactJobTimeout,
+		)
+		jobReport := c.compactJob(ctx, cachedJob)
+		cancel()
+
+		if jobReport.isEmpty() {
+			continue
+		}
+		report.PodEventsInstances += len(jobReport.PodEvents)
+		for _, count := range jobReport.WorkflowEvents {
+			report.WorkflowEventsInstances += count
+		}
+		report.ConfigVersions += len(jobReport.ConfigVersions)
+		report.Jobs = append(report.Jobs, jobReport)
+	}
+	report.EndTime = time.Now()
+
+	// forget the state of the jobs which are no longer in the cache
+	for jobID := range c.jobStates {
+		if _, ok := jobs[jobID]; !ok {
+			delete(c.jobStates, jobID)
+		}
+	}
+
+	c.lock.Lock()
+	c.lastReport = report
+	c.lock.Unlock()
+
+	c.Metrics.ReportPodEventsInstances.Update(float64(report.PodEventsInstances))
+	c.Metrics.ReportWorkflowEventsInstances.Update(float64(report.WorkflowEventsInstances))
+	c.Metrics.ReportConfigVersions.Update(float64(report.ConfigVersions))
+
+	log.WithFields(log.Fields{
+		"dry_run":                   report.DryRun,
+		"num_jobs":                  len(report.Jobs),
+		"pod_events_instances":      report.PodEventsInstances,
+		"workflow_events_instances": report.WorkflowEventsInstances,
+		"config_versions":           report.ConfigVersions,
+	}).Info("retention compaction run completed")
+	return report
+}
+
+// LastReport returns the report of the last compaction run, or nil
+// if the compactor has not run yet.
+func (c *Compactor) LastReport() *Report {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lastReport
+}
+
+// ReportHandler serves the report of the last compaction run as JSON.
+func (c *Compactor) ReportHandler(w nethttp.ResponseWriter, _ *nethttp.Request) {
+	report := c.LastReport()
+	if report == nil {
+		nethttp.Error(w, "retention compactor has not run yet", nethttp.StatusNotFound)
+		return
+	}
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		nethttp.Error(w, err.Error(), nethttp.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(nethttp.StatusOK)
+	w.Write(body)
+}
+
+// compactJob enforces the retention policies on a job.
+func (c *Compactor) compactJob(
+	ctx context.Context,
+	cachedJob cached.Job,
+) *JobReport {
+	jobID := cachedJob.ID()
+	jobReport := newJobReport(jobID)
+	state := c.getJobState(jobID)
+
+	tasks, err := c.TaskStore.GetTasksForJob(ctx, jobID)
+	if err != nil {
+		c.readFailed(jobReport, errors.Wrap(err, "failed to get tasks"))
+		return jobReport
+	}
+	c.compactPodEvents(ctx, jobID, tasks, state, jobReport)
+
+	if c.Config.WorkflowEvents.MaxAge == 0 &&
+		!c.Config.JobConfig.DeleteUnreferencedVersions {
+		return jobReport
+	}
+
+	updates, err := c.getUpdates(ctx, jobID)
+	if err != nil {
+		c.readFailed(jobReport, errors.Wrap(err, "failed to get workflows"))
+		return jobReport
+	}
+	c.compactWorkflowEvents(ctx, jobID, updates, state, jobReport)
+	c.compactConfigVersions(ctx, cachedJob, tasks, updates, state, jobReport)
+	return jobReport
+}
+
+// compactPodEvents deletes the pod events of the runs of each instance
+// older than the most recent MaxRunsPerInstance runs.
+func (c *Compactor) compactPodEvents(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	tasks map[uint32]*pbtask.TaskInfo,
+	state *jobState,
+	jobReport *JobReport,
+) {
+	maxRuns := c.Config.PodEvents.MaxRunsPerInstance
+	if maxRuns == 0 {
+		return
+	}
+
+	for instanceID, taskInfo := range tasks {
+		if ctx.Err() != nil {
+			return
+		}
+
+		runID, err := util.ParseRunID(
+			taskInfo.GetRuntime().GetMesosTaskId().GetValue())
+		if err != nil || runID <= maxRuns {
+			continue
+		}
+
+		// keep the pod events of the runs in [runID-maxRuns+1, runID]
+		deleteBefore := runID - maxRuns + 1
+		if state.podEventsDeletedBefore[instanceID] >= deleteBefore {
+			continue
+		}
+
+		if err := c.delete(ctx, func() error {
+			return c.TaskStore.DeletePodEvents(
+				ctx,
+				jobID.GetValue(),
+				instanceID,
+				1,
+				deleteBefore,
+			)
+		}); err != nil {
+			c.Metrics.PodEventsDeleteFail.Inc(1)
+			jobReport.addError(errors.Wrapf(err,
+				"failed to delete pod events of instance %d", instanceID))
+			continue
+		}
+
+		jobReport.PodEvents[instanceID] = deleteBefore
+		if !c.Config.DryRun {
+			c.Metrics.PodEventsDelete.Inc(1)
+			state.podEventsDeletedBefore[instanceID] = deleteBefore
+		}
+	}
+}
+
+// compactWorkflowEvents deletes the workflow events older than MaxAge
+// of the terminated workflows of a job.
+func (c *Compactor) compactWorkflowEvents(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	updates []*models.UpdateModel,
+	state *jobState,
+	jobReport *JobReport,
+) {
+	maxAge := c.Config.WorkflowEvents.MaxAge
+	if maxAge == 0 {
+		return
+	}
+	deleteBefore := time.Now().Add(-maxAge)
+
+	for _, updateModel := range updates {
+		updateID := updateModel.GetUpdateID()
+
+		// the events of active workflows are still used to
+		// track their progress
+		if state.compactedWorkflows[updateID.GetValue()] ||
+			cached.IsUpdateStateActive(updateModel.GetState()) {
+			continue
+		}
+
+		// all the events of the workflow were created after the cutoff
+		creationTime, err := time.Parse(
+			time.RFC3339Nano, updateModel.GetCreationTime())
+		if err == nil && creationTime.After(deleteBefore) {
+			continue
+		}
+
+		instances := append(updateModel.GetInstancesUpdated(),
+			updateModel.GetInstancesAdded()...)
+		instances = append(instances, updateModel.GetInstancesRemoved()...)
+
+		var deleted int
+		for _, instanceID := range instances {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err := c.delete(ctx, func() error {
+				return c.UpdateStore.DeleteWorkflowEventsBefore(
+					ctx,
+					updateID,
+					instanceID,
+					deleteBefore,
+				)
+			}); err != nil {
+				c.Metrics.WorkflowEventsDeleteFail.Inc(1)
+				jobReport.addError(errors.Wrapf(err,
+					"failed to delete events of workflow %s instance %d",
+					updateID.GetValue(), instanceID))
+				continue
+			}
+
+			deleted++
+			if !c.Config.DryRun {
+				c.Metrics.WorkflowEventsDelete.Inc(1)
+			}
+		}
+		if deleted > 0 {
+			jobReport.WorkflowEvents[updateID.GetValue()] = deleted
+		}
+
+		// the workflow has not changed since the cutoff,
+		// so all of its events have been deleted
+		updateTime, err := time.Parse(
+			time.RFC3339Nano, updateModel.GetUpdateTime())
+		if !c.Config.DryRun &&
+			deleted == len(instances) &&
+			err == nil &&
+			updateTime.Before(deleteBefore) {
+			state.compactedWorkflows[updateID.GetValue()] = true
+		}
+	}
+}
+
+// compactConfigVersions deletes the job and task configs of the versions
+// of a job older than its current version, which are not used by any task
+// or workflow of the job.
+func (c *Compactor) compactConfigVersions(
+	ctx context.Context,
+	cachedJob cached.Job,
+	tasks map[uint32]*pbtask.TaskInfo,
+	updates []*models.UpdateModel,
+	state *jobState,
+	jobReport *JobReport,
+) {
+	if !c.Config.JobConfig.DeleteUnreferencedVersions {
+		return
+	}
+
+	jobID := cachedJob.ID()
+	jobRuntime, err := cachedJob.GetRuntime(ctx)
+	if err != nil {
+		c.readFailed(jobReport, errors.Wrap(err, "failed to get job runtime"))
+		return
+	}
+	jobConfig, err := cachedJob.GetConfig(ctx)
+	if err != nil {
+		c.readFailed(jobReport, errors.Wrap(err, "failed to get job config"))
+		return
+	}
+
+	currentVersion := jobRuntime.GetConfigurationVersion()
+	instanceCount := jobConfig.GetInstanceCount()
+
+	// the current version, and the versions used by the tasks
+	// and the workflows of the job are kept
+	minReferenced := currentVersion
+	referenced := make(map[uint64]bool)
+	reference := func(version uint64) {
+		if version == 0 {
+			return
+		}
+		referenced[version] = true
+		if version < minReferenced {
+			minReferenced = version
+		}
+	}
+
+	reference(currentVersion)
+	for instanceID, taskInfo := range tasks {
+		reference(taskInfo.GetRuntime().GetConfigVersion())
+		reference(taskInfo.GetRuntime().GetDesiredConfigVersion())
+		if instanceID >= instanceCount {
+			instanceCount = instanceID + 1
+		}
+	}
+	for _, updateModel := range updates {
+		reference(updateModel.GetJobConfigVersion())
+		reference(updateModel.GetPrevJobConfigVersion())
+	}
+
+	// config versions start at 1
+	version := state.configVersionsDeletedBefore
+	if version == 0 {
+		version = 1
+	}
+	deletedBefore := minReferenced
+	for ; version < currentVersion; version++ {
+		if referenced[version] || state.deletedConfigVersions[version] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := c.deleteConfigVersion(
+			ctx, jobID, version, instanceCount); err != nil {
+			c.Metrics.ConfigVersionDeleteFail.Inc(1)
+			jobReport.addError(errors.Wrapf(err,
+				"failed to delete config version %d", version))
+			if version < deletedBefore {
+				deletedBefore = version
+			}
+			continue
+		}
+
+		jobReport.ConfigVersions = append(jobReport.ConfigVersions, version)
+		if !c.Config.DryRun {
+			c.Metrics.ConfigVersionDelete.Inc(1)
+			state.deletedConfigVersions[version] = true
+		}
+	}
+
+	if c.Config.DryRun {
+		return
+	}
+
+	// all the versions before deletedBefore are now deleted
+	state.configVersionsDeletedBefore = deletedBefore
+	for version := range state.deletedConfigVersions {
+		if version < deletedBefore {
+			delete(state.deletedConfigVersions, version)
+		}
+	}
+}
+
+// deleteConfigVersion deletes the task configs and the job config of
+// a job at the given version.
+func (c *Compactor) deleteConfigVersion(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	version uint64,
+	instanceCount uint32,
+) error {
+	// the job config of the version may already have been deleted
+	// along with an old workflow, leaving its task configs behind
+	jobConfig, _, err := c.JobConfigOps.Get(ctx, jobID, version)
+	if err != nil && !yarpcerrors.IsNotFound(errors.Cause(err)) {
+		return err
+	}
+	jobConfigFound := err == nil
+	if jobConfig.GetInstanceCount() > instanceCount {
+		instanceCount = jobConfig.GetInstanceCount()
+	}
+
+	// delete the task configs before the job config, so that a failure
+	// is retried on the next run
+	instanceIDs := []int64{common.DefaultTaskConfigID}
+	for i := uint32(0); i < instanceCount; i++ {
+		instanceIDs = append(instanceIDs, int64(i))
+	}
+	for _, instanceID := range instanceIDs {
+		if err := c.delete(ctx, func() error {
+			return c.TaskConfigV2Ops.Delete(ctx, jobID, instanceID, version)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if !jobConfigFound {
+		return nil
+	}
+	return c.delete(ctx, func() error {
+		return c.JobConfigOps.Delete(ctx, jobID, version)
+	})
+}
+
+// delete runs the given delete through the rate limiter,
+// unless the compactor runs in dry-run mode.
+func (c *Compactor) delete(ctx context.Context, deleteFunc func() error) error {
+	if c.Config.DryRun {
+		return nil
+	}
+
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return deleteFunc()
+}
+
+// getUpdates returns all the workflows of a job stored in the DB.
+func (c *Compactor) getUpdates(
+	ctx context.Context,
+	jobID *peloton.JobID,
+) ([]*models.UpdateModel, error) {
+	updateIDs, err := c.UpdateStore.GetUpdatesForJob(ctx, jobID.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []*models.UpdateModel
+	for _, updateID := range updateIDs {
+		updateModel, err := c.UpdateStore.GetUpdate(ctx, updateID)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err, "failed to get workflow %s", updateID.GetValue())
+		}
+		updates = append(updates, updateModel)
+	}
+	return updates, nil
+}
+
+// getJobState returns the compaction state of a job.
+func (c *Compactor) getJobState(jobID *peloton.JobID) *jobState {
+	state, ok := c.jobStates[jobID.GetValue()]
+	if !ok {
+		state = &jobState{
+			podEventsDeletedBefore: make(map[uint32]uint64),
+			compactedWorkflows:     make(map[string]bool),
+			deletedConfigVersions:  make(map[uint64]bool),
+		}
+		c.jobStates[jobID.GetValue()] = state
+	}
+	return state
+}
+
+func (c *Compactor) readFailed(jobReport *JobReport, err error) {
+	c.Metrics.ReadFail.Inc(1)
+	jobReport.addError(err)
+	log.WithField("job_id", jobReport.JobID).
+		WithError(err).
+		Info("failed to read job for retention compaction")
+}
@@ -0,0 +1,375 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+
+	backgroundmocks "github.com/uber/peloton/pkg/common/background/mocks"
+	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
+	storemocks "github.com/uber/peloton/pkg/storage/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type CompactorTestSuite struct {
+	suite.Suite
+	mockCtrl *gomock.Controller
+
+	jobFactory      *cachedmocks.MockJobFactory
+	cachedJob       *cachedmocks.MockJob
+	cachedConfig    *cachedmocks.MockJobConfigCache
+	taskStore       *storemocks.MockTaskStore
+	updateStore     *storemocks.MockUpdateStore
+	jobConfigOps    *objectmocks.MockJobConfigOps
+	taskConfigV2Ops *objectmocks.MockTaskConfigV2Ops
+	compactor       *Compactor
+
+	jobID *peloton.JobID
+}
+
+func (s *CompactorTestSuite) SetupTest() {
+	s.mockCtrl = gomock.NewController(s.T())
+
+	s.jobFactory = cachedmocks.NewMockJobFactory(s.mockCtrl)
+	s.cachedJob = cachedmocks.NewMockJob(s.mockCtrl)
+	s.cachedConfig = cachedmocks.NewMockJobConfigCache(s.mockCtrl)
+	s.taskStore = storemocks.NewMockTaskStore(s.mockCtrl)
+	s.updateStore = storemocks.NewMockUpdateStore(s.mockCtrl)
+	s.jobConfigOps = objectmocks.NewMockJobConfigOps(s.mockCtrl)
+	s.taskConfigV2Ops = objectmocks.NewMockTaskConfigV2Ops(s.mockCtrl)
+
+	s.compactor = &Compactor{
+		JobFactory:      s.jobFactory,
+		TaskStore:       s.taskStore,
+		UpdateStore:     s.updateStore,
+		JobConfigOps:    s.jobConfigOps,
+		TaskConfigV2Ops: s.taskConfigV2Ops,
+		Metrics:         NewMetrics(tally.NoopScope),
+		Config: &Config{
+			Enabled:             true,
+			MaxDeletesPerSecond: 1000,
+		},
+	}
+	s.compactor.setup()
+
+	s.jobID = &peloton.JobID{Value: "c7a9e2b4-6f1d-4e8a-b3c5-9d2f4a6e8b1c"}
+	s.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{s.jobID.GetValue(): s.cachedJob}).
+		AnyTimes()
+	s.cachedJob.EXPECT().ID().Return(s.jobID).AnyTimes()
+}
+
+func (s *CompactorTestSuite) TearDownTest() {
+	s.mockCtrl.Finish()
+}
+
+func TestCompactor(t *testing.T) {
+	suite.Run(t, new(CompactorTestSuite))
+}
+
+// taskInfo returns the task info of an instance of the test job
+func (s *CompactorTestSuite) taskInfo(
+	instanceID uint32,
+	runID uint64,
+	configVersion uint64,
+) *pbtask.TaskInfo {
+	return &pbtask.TaskInfo{
+		InstanceId: instanceID,
+		Runtime: &pbtask.RuntimeInfo{
+			MesosTaskId:          util.CreateMesosTaskID(s.jobID, instanceID, runID),
+			ConfigVersion:        configVersion,
+			DesiredConfigVersion: configVersion,
+		},
+	}
+}
+
+// expectUpdates sets up the workflows of the test job read from DB
+func (s *CompactorTestSuite) expectUpdates(updates ...*models.UpdateModel) {
+	var updateIDs []*peloton.UpdateID
+	for _, updateModel := range updates {
+		updateIDs = append(updateIDs, updateModel.GetUpdateID())
+		s.updateStore.EXPECT().
+			GetUpdate(gomock.Any(), updateModel.GetUpdateID()).
+			Return(updateModel, nil)
+	}
+	s.updateStore.EXPECT().
+		GetUpdatesForJob(gomock.Any(), s.jobID.GetValue()).
+		Return(updateIDs, nil)
+}
+
+// TestRegister tests the compactor is only registered when enabled
+func (s *CompactorTestSuite) TestRegister() {
+	mockBackgroundManager := backgroundmocks.NewMockManager(s.mockCtrl)
+	mockBackgroundManager.EXPECT().RegisterWorks(gomock.Any()).Return(nil)
+	s.NoError(s.compactor.Register(mockBackgroundManager))
+
+	s.compactor.Config.Enabled = false
+	s.NoError(s.compactor.Register(mockBackgroundManager))
+}
+
+// TestCompactPodEvents tests deleting the pod events of the runs older
+// than the most recent runs of each instance, once
+func (s *CompactorTestSuite) TestCompactPodEvents() {
+	s.compactor.Config.PodEvents.MaxRunsPerInstance = 100
+	tasks := map[uint32]*pbtask.TaskInfo{
+		0: s.taskInfo(0, 150, 1),
+		1: s.taskInfo(1, 50, 1),
+	}
+	s.taskStore.EXPECT().
+		GetTasksForJob(gomock.Any(), s.jobID).
+		Return(tasks, nil).
+		Times(2)
+	s.taskStore.EXPECT().
+		DeletePodEvents(gomock.Any(), s.jobID.GetValue(), uint32(0), uint64(1), uint64(51)).
+		Return(nil)
+
+	report := s.compactor.Run()
+	s.Equal(1, report.PodEventsInstances)
+	s.Len(report.Jobs, 1)
+	s.Equal(map[uint32]uint64{0: 51}, report.Jobs[0].PodEvents)
+
+	// the pod events already deleted are not deleted again
+	report = s.compactor.Run()
+	s.Equal(0, report.PodEventsInstances)
+	s.Empty(report.Jobs)
+}
+
+// TestCompactWorkflowEvents tests deleting the old events of terminated
+// workflows, while the events of active workflows are kept
+func (s *CompactorTestSuite) TestCompactWorkflowEvents() {
+	s.compactor.Config.WorkflowEvents.MaxAge = 30 * 24 * time.Hour
+	now := time.Now()
+	oldUpdate := &models.UpdateModel{
+		UpdateID:         &peloton.UpdateID{Value: "old-update"},
+		State:            pbupdate.State_SUCCEEDED,
+		InstancesUpdated: []uint32{0},
+		InstancesAdded:   []uint32{1},
+		CreationTime:     now.Add(-60 * 24 * time.Hour).Format(time.RFC3339Nano),
+		UpdateTime:       now.Add(-40 * 24 * time.Hour).Format(time.RFC3339Nano),
+	}
+	activeUpdate := &models.UpdateModel{
+		UpdateID:         &peloton.UpdateID{Value: "active-update"},
+		State:            pbupdate.State_ROLLING_FORWARD,
+		InstancesUpdated: []uint32{0, 1},
+		CreationTime:     now.Add(-60 * 24 * time.Hour).Format(time.RFC3339Nano),
+		UpdateTime:       now.Format(time.RFC3339Nano),
+	}
+
+	s.taskStore.EXPECT().
+		GetTasksForJob(gomock.Any(), s.jobID).
+		Return(map[uint32]*pbtask.TaskInfo{}, nil).
+		Times(2)
+	s.expectUpdates(oldUpdate, activeUpdate)
+	for _, instanceID := range []uint32{0, 1} {
+		s.updateStore.EXPECT().
+			DeleteWorkflowEventsBefore(
+				gomock.Any(), oldUpdate.GetUpdateID(), instanceID, gomock.Any()).
+			Return(nil)
+	}
+
+	report := s.compactor.Run()
+	s.Equal(2, report.WorkflowEventsInstances)
+	s.Equal(map[string]int{"old-update": 2}, report.Jobs[0].WorkflowEvents)
+
+	// the events of the compacted workflow are not deleted again
+	s.expectUpdates(oldUpdate, activeUpdate)
+	report = s.compactor.Run()
+	s.Equal(0, report.WorkflowEventsInstances)
+}
+
+// TestCompactConfigVersions tests deleting the job and task configs of
+// the versions not used by any task or workflow
+func (s *CompactorTestSuite) TestCompactConfigVersions() {
+	s.compactor.Config.JobConfig.DeleteUnreferencedVersions = true
+	tasks := map[uint32]*pbtask.TaskInfo{
+		0: s.taskInfo(0, 1, 5),
+		1: s.taskInfo(1, 1, 4),
+	}
+	update := &models.UpdateModel{
+		UpdateID:             &peloton.UpdateID{Value: "update"},
+		State:                pbupdate.State_SUCCEEDED,
+		JobConfigVersion:     3,
+		PrevJobConfigVersion: 2,
+	}
+
+	s.taskStore.EXPECT().
+		GetTasksForJob(gomock.Any(), s.jobID).
+		Return(tasks, nil).
+		Times(2)
+	s.expectUpdates(update)
+	s.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{ConfigurationVersion: 5}, nil).
+		Times(2)
+	s.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(s.cachedConfig, nil).
+		Times(2)
+	s.cachedConfig.EXPECT().GetInstanceCount().Return(uint32(2)).Times(2)
+
+	// version 1 had 3 instances
+	s.jobConfigOps.EXPECT().
+		Get(gomock.Any(), s.jobID, uint64(1)).
+		Return(&pbjob.JobConfig{InstanceCount: 3}, &models.ConfigAddOn{}, nil)
+	for _, instanceID := range []int64{common.DefaultTaskConfigID, 0, 1, 2} {
+		s.taskConfigV2Ops.EXPECT().
+			Delete(gomock.Any(), s.jobID, instanceID, uint64(1)).
+			Return(nil)
+	}
+	s.jobConfigOps.EXPECT().
+		Delete(gomock.Any(), s.jobID, uint64(1)).
+		Return(nil)
+
+	report := s.compactor.Run()
+	s.Equal(1, report.ConfigVersions)
+	s.Equal([]uint64{1}, report.Jobs[0].ConfigVersions)
+
+	// the deleted versions are not deleted again
+	s.expectUpdates(update)
+	report = s.compactor.Run()
+	s.Equal(0, report.ConfigVersions)
+}
+
+// TestCompactConfigVersionsJobConfigNotFound tests deleting the task
+// configs of a version whose job config has already been deleted
+func (s *CompactorTestSuite) TestCompactConfigVersionsJobConfigNotFound() {
+	s.compactor.Config.JobConfig.DeleteUnreferencedVersions = true
+
+	s.taskStore.EXPECT().
+		GetTasksForJob(gomock.Any(), s.jobID).
+		Return(map[uint32]*pbtask.TaskInfo{0: s.taskInfo(0, 1, 2)}, nil)
+	s.expectUpdates()
+	s.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{ConfigurationVersion: 2}, nil)
+	s.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(s.cachedConfig, nil)
+	s.cachedConfig.EXPECT().GetInstanceCount().Return(uint32(1))
+
+	s.jobConfigOps.EXPECT().
+		Get(gomock.Any(), s.jobID, uint64(1)).
+		Return(nil, nil, yarpcerrors.NotFoundErrorf("not found"))
+	for _, instanceID := range []int64{common.DefaultTaskConfigID, 0} {
+		s.taskConfigV2Ops.EXPECT().
+			Delete(gomock.Any(), s.jobID, instanceID, uint64(1)).
+			Return(nil)
+	}
+
+	report := s.compactor.Run()
+	s.Equal([]uint64{1}, report.Jobs[0].ConfigVersions)
+}
+
+// TestDryRun tests that nothing is deleted in dry-run mode, while the
+// report lists what would have been deleted
+func (s *CompactorTestSuite) TestDryRun() {
+	s.compactor.Config.DryRun = true
+	s.compactor.Config.PodEvents.MaxRunsPerInstance = 10
+	s.compactor.Config.JobConfig.DeleteUnreferencedVersions = true
+
+	s.taskStore.EXPECT().
+		GetTasksForJob(gomock.Any(), s.jobID).
+		Return(map[uint32]*pbtask.TaskInfo{0: s.taskInfo(0, 20, 2)}, nil)
+	s.expectUpdates()
+	s.cachedJob.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{ConfigurationVersion: 2}, nil)
+	s.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(s.cachedConfig, nil)
+	s.cachedConfig.EXPECT().GetInstanceCount().Return(uint32(1))
+	s.jobConfigOps.EXPECT().
+		Get(gomock.Any(), s.jobID, uint64(1)).
+		Return(&pbjob.JobConfig{InstanceCount: 1}, &models.ConfigAddOn{}, nil)
+
+	report := s.compactor.Run()
+	s.True(report.DryRun)
+	s.Equal(map[uint32]uint64{0: 11}, report.Jobs[0].PodEvents)
+	s.Equal([]uint64{1}, report.Jobs[0].ConfigVersions)
+
+	// the report of the last run is served over HTTP
+	w := httptest.NewRecorder()
+	s.compactor.ReportHandler(w, httptest.NewRequest("GET", ReportPath, nil))
+	s.Equal(200, w.Code)
+	served := &Report{}
+	s.NoError(json.Unmarshal(w.Body.Bytes(), served))
+	s.Equal(report.Jobs[0].ConfigVersions, served.Jobs[0].ConfigVersions)
+}
+
+// TestReportHandlerBeforeRun tests the report is not found before the
+// first compaction run
+func (s *CompactorTestSuite) TestReportHandlerBeforeRun() {
+	w := httptest.NewRecorder()
+	s.compactor.ReportHandler(w, httptest.NewRequest("GET", ReportPath, nil))
+	s.Equal(404, w.Code)
+}
+
+// TestReadTasksFailure tests a job is skipped when its tasks
+// cannot be read
+func (s *CompactorTestSuite) TestReadTasksFailure() {
+	s.compactor.Config.PodEvents.MaxRunsPerInstance = 10
+	s.taskStore.EXPECT().
+		GetTasksForJob(gomock.Any(), s.jobID).
+		Return(nil, errors.New("fake db error"))
+
+	report := s.compactor.Run()
+	s.Len(report.Jobs, 1)
+	s.Len(report.Jobs[0].Errors, 1)
+}
+
+// TestDeleteFailure tests a failed delete is retried on the next run
+func (s *CompactorTestSuite) TestDeleteFailure() {
+	s.compactor.Config.PodEvents.MaxRunsPerInstance = 10
+	s.taskStore.EXPECT().
+		GetTasksForJob(gomock.Any(), s.jobID).
+		Return(map[uint32]*pbtask.TaskInfo{0: s.taskInfo(0, 20, 1)}, nil).
+		Times(2)
+	gomock.InOrder(
+		s.taskStore.EXPECT().
+			DeletePodEvents(gomock.Any(), s.jobID.GetValue(), uint32(0), uint64(1), uint64(11)).
+			Return(errors.New("fake db error")),
+		s.taskStore.EXPECT().
+			DeletePodEvents(gomock.Any(), s.jobID.GetValue(), uint32(0), uint64(1), uint64(11)).
+			Return(nil),
+	)
+
+	report := s.compactor.Run()
+	s.Empty(report.Jobs[0].PodEvents)
+	s.Len(report.Jobs[0].Errors, 1)
+
+	report = s.compactor.Run()
+	s.Equal(map[uint32]uint64{0: 11}, report.Jobs[0].PodEvents)
+}
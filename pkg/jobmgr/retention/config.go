@@ -0,0 +1,83 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import "time"
+
+const (
+	_defaultCompactionPeriod    = time.Hour
+	_defaultMaxDeletesPerSecond = 100
+)
+
+// Config is the configuration of the retention compactor.
+type Config struct {
+	// Enabled is set to run the retention compactor in job manager.
+	Enabled bool `yaml:"enabled"`
+
+	// DryRun is set to only report what the retention policies would
+	// delete, without deleting anything.
+	DryRun bool `yaml:"dry_run"`
+
+	// CompactionPeriod is the period at which the retention policies
+	// are enforced.
+	CompactionPeriod time.Duration `yaml:"compaction_period"`
+
+	// MaxDeletesPerSecond is the maximum rate at which the compactor
+	// issues delete statements to the DB.
+	MaxDeletesPerSecond float64 `yaml:"max_deletes_per_second"`
+
+	// PodEvents is the retention policy of the pod_events table.
+	PodEvents PodEventsPolicy `yaml:"pod_events"`
+
+	// WorkflowEvents is the retention policy of the pod_workflow_events
+	// table.
+	WorkflowEvents WorkflowEventsPolicy `yaml:"pod_workflow_events"`
+
+	// JobConfig is the retention policy of the job_config and
+	// task_config_v2 tables.
+	JobConfig JobConfigPolicy `yaml:"job_config"`
+}
+
+// PodEventsPolicy is the retention policy of pod events.
+type PodEventsPolicy struct {
+	// MaxRunsPerInstance is the number of most recent runs of an
+	// instance whose pod events are kept. Zero keeps all the runs.
+	MaxRunsPerInstance uint64 `yaml:"max_runs_per_instance"`
+}
+
+// WorkflowEventsPolicy is the retention policy of workflow events.
+type WorkflowEventsPolicy struct {
+	// MaxAge is the age after which the workflow events of terminated
+	// workflows are deleted. Zero keeps all the events.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// JobConfigPolicy is the retention policy of job config versions.
+type JobConfigPolicy struct {
+	// DeleteUnreferencedVersions is set to delete the job and task
+	// configs of the versions older than the current version of a job,
+	// which are neither used by a task nor by a workflow of the job.
+	DeleteUnreferencedVersions bool `yaml:"delete_unreferenced_versions"`
+}
+
+func (c *Config) normalize() {
+	if c.CompactionPeriod == time.Duration(0) {
+		c.CompactionPeriod = _defaultCompactionPeriod
+	}
+
+	if c.MaxDeletesPerSecond == 0 {
+		c.MaxDeletesPerSecond = _defaultMaxDeletesPerSecond
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import "github.com/uber-go/tally"
+
+// Metrics is the struct containing all the metrics that track the
+// retention compactor.
+type Metrics struct {
+	ProcessDuration tally.Timer
+
+	PodEventsDelete          tally.Counter
+	PodEventsDeleteFail      tally.Counter
+	WorkflowEventsDelete     tally.Counter
+	WorkflowEventsDeleteFail tally.Counter
+	ConfigVersionDelete      tally.Counter
+	ConfigVersionDeleteFail  tally.Counter
+	ReadFail                 tally.Counter
+
+	// number of deletions found by the last compaction run, which are
+	// the ones skipped in dry-run mode
+	ReportPodEventsInstances      tally.Gauge
+	ReportWorkflowEventsInstances tally.Gauge
+	ReportConfigVersions          tally.Gauge
+}
+
+// NewMetrics returns a new Metrics struct, with all metrics initialized
+// and rooted at the given tally.Scope
+func NewMetrics(scope tally.Scope) *Metrics {
+	retentionScope := scope.SubScope("retention")
+	successScope := retentionScope.Tagged(map[string]string{"result": "success"})
+	failScope := retentionScope.Tagged(map[string]string{"result": "fail"})
+	reportScope := retentionScope.SubScope("report")
+	return &Metrics{
+		ProcessDuration: retentionScope.Timer("duration"),
+
+		PodEventsDelete:          successScope.Counter("pod_events_delete"),
+		PodEventsDeleteFail:      failScope.Counter("pod_events_delete"),
+		WorkflowEventsDelete:     successScope.Counter("workflow_events_delete"),
+		WorkflowEventsDeleteFail: failScope.Counter("workflow_events_delete"),
+		ConfigVersionDelete:      successScope.Counter("config_version_delete"),
+		ConfigVersionDeleteFail:  failScope.Counter("config_version_delete"),
+		ReadFail:                 failScope.Counter("read"),
+
+		ReportPodEventsInstances:      reportScope.Gauge("pod_events_instances"),
+		ReportWorkflowEventsInstances: reportScope.Gauge("workflow_events_instances"),
+		ReportConfigVersions:          reportScope.Gauge("config_versions"),
+	}
+}
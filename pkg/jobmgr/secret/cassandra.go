@@ -0,0 +1,51 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+)
+
+// cassandraStore reads the secrets stored by Peloton in the secret_info
+// table. Only the latest version of these secrets is kept.
+type cassandraStore struct {
+	secretInfoOps ormobjects.SecretInfoOps
+}
+
+func newCassandraStore(secretInfoOps ormobjects.SecretInfoOps) store {
+	return &cassandraStore{secretInfoOps: secretInfoOps}
+}
+
+func (s *cassandraStore) getSecret(
+	ctx context.Context,
+	path, _ string,
+) (*Secret, error) {
+	obj, err := s.secretInfoOps.GetSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(obj.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &Secret{
+		Data:    data,
+		Version: strconv.FormatInt(obj.Version, 10),
+	}, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"time"
+)
+
+const (
+	_defaultVaultMount   = "secret"
+	_defaultVaultKey     = "value"
+	_defaultVaultTimeout = 10 * time.Second
+)
+
+// Config of the external secret stores. Secrets stored by Peloton are
+// always available.
+type Config struct {
+	// File backed secret store, for development and testing
+	File FileConfig `yaml:"file"`
+	// Vault secret store
+	Vault VaultConfig `yaml:"vault"`
+}
+
+// FileConfig is the config of the file backed secret store. Version
+// `<version>` of the secret at `<path>` is read from
+// `<root_dir>/<path>/<version>`, versions being positive integers.
+type FileConfig struct {
+	// Directory holding the secrets, the store is disabled if empty.
+	RootDir string `yaml:"root_dir"`
+	// Path prefixes of the secrets each job owner or owning team may
+	// reference, keyed by owner or team.
+	AllowedPaths map[string][]string `yaml:"allowed_paths"`
+}
+
+// VaultConfig is the config of the Vault secret store, reading secrets
+// from a key/value version 2 secrets engine.
+type VaultConfig struct {
+	// Address of the Vault server, the store is disabled if empty.
+	Address string `yaml:"address"`
+	// Path the key/value secrets engine is mounted at.
+	Mount string `yaml:"mount"`
+	// Key of the secret data to read in each Vault secret.
+	Key string `yaml:"key"`
+	// Token used to authenticate to Vault.
+	Token string `yaml:"token"`
+	// File to read the token from, takes precedence over Token.
+	TokenFile string `yaml:"token_file"`
+	// Timeout of the requests to Vault.
+	Timeout time.Duration `yaml:"timeout"`
+	// Path prefixes of the secrets each job owner or owning team may
+	// reference, keyed by owner or team.
+	AllowedPaths map[string][]string `yaml:"allowed_paths"`
+}
+
+func (c *VaultConfig) normalize() {
+	if c.Mount == "" {
+		c.Mount = _defaultVaultMount
+	}
+	if c.Key == "" {
+		c.Key = _defaultVaultKey
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = _defaultVaultTimeout
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// fileStore reads secrets from a directory tree, standing in for a secret
+// store in development and test clusters.
+type fileStore struct {
+	rootDir string
+}
+
+func newFileStore(cfg FileConfig) store {
+	return &fileStore{rootDir: cfg.RootDir}
+}
+
+func (s *fileStore) getSecret(
+	ctx context.Context,
+	path, version string,
+) (*Secret, error) {
+	// Clean the path as rooted so that it cannot escape the root directory.
+	dir := filepath.Join(s.rootDir, filepath.Clean("/"+path))
+
+	if version == "" {
+		latest, err := s.latestVersion(dir)
+		if err != nil {
+			return nil, err
+		}
+		version = strconv.FormatUint(latest, 10)
+	} else if _, err := strconv.ParseUint(version, 10, 64); err != nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"invalid version %q of secret %s", version, path)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, version))
+	if os.IsNotExist(err) {
+		return nil, yarpcerrors.NotFoundErrorf(
+			"version %s of secret %s not found", version, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Secret{Data: data, Version: version}, nil
+}
+
+// latestVersion returns the highest version of the secret in dir.
+func (s *fileStore) latestVersion(dir string) (uint64, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, yarpcerrors.NotFoundErrorf("secret %s not found", dir)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint64
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		v, err := strconv.ParseUint(f.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+		if v > latest {
+			latest = v
+		}
+	}
+	if latest == 0 {
+		return 0, yarpcerrors.NotFoundErrorf("no version of secret %s", dir)
+	}
+	return latest, nil
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// FileStore is the name of the file backed secret store.
+	FileStore = "file"
+	// VaultStore is the name of the Vault secret store.
+	VaultStore = "vault"
+)
+
+// Secret is the data of a secret at a given version.
+type Secret struct {
+	// Data of the secret, decoded.
+	Data []byte
+	// Version of the secret returned.
+	Version string
+}
+
+// Provider resolves secret references to secret data.
+type Provider interface {
+	// GetSecret returns the secret referenced.
+	GetSecret(ctx context.Context, ref *Reference) (*Secret, error)
+	// CheckAccess returns an error unless a job owned by one of owners may
+	// reference the secret. Jobmgr reads external secrets on behalf of the
+	// jobs, so a job may only mount the paths allowed for its owner.
+	CheckAccess(ref *Reference, owners ...string) error
+}
+
+// store fetches secrets from a single secret store.
+type store interface {
+	getSecret(ctx context.Context, path, version string) (*Secret, error)
+}
+
+// provider implements Provider by dispatching references to the store
+// holding the secret.
+type provider struct {
+	peloton store
+	stores  map[string]store
+	// Map of store to the path prefixes allowed for each owner.
+	allowedPaths map[string]map[string][]string
+}
+
+// NewProvider returns a Provider resolving secrets stored by Peloton with
+// secretInfoOps, and external secrets with the stores configured.
+func NewProvider(
+	cfg Config,
+	secretInfoOps ormobjects.SecretInfoOps,
+) (Provider, error) {
+	p := &provider{
+		peloton:      newCassandraStore(secretInfoOps),
+		stores:       make(map[string]store),
+		allowedPaths: make(map[string]map[string][]string),
+	}
+	if cfg.File.RootDir != "" {
+		p.stores[FileStore] = newFileStore(cfg.File)
+		p.allowedPaths[FileStore] = cfg.File.AllowedPaths
+	}
+	if cfg.Vault.Address != "" {
+		s, err := newVaultStore(cfg.Vault)
+		if err != nil {
+			return nil, err
+		}
+		p.stores[VaultStore] = s
+		p.allowedPaths[VaultStore] = cfg.Vault.AllowedPaths
+	}
+	return p, nil
+}
+
+// GetSecret returns the secret referenced.
+func (p *provider) GetSecret(
+	ctx context.Context,
+	ref *Reference,
+) (*Secret, error) {
+	if ref.Path == "" {
+		return nil, yarpcerrors.InvalidArgumentErrorf("empty secret path")
+	}
+	if !ref.IsExternal() {
+		return p.peloton.getSecret(ctx, ref.Path, ref.Version)
+	}
+	s, ok := p.stores[ref.Store]
+	if !ok {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"secret store %q is not configured", ref.Store)
+	}
+	return s.getSecret(ctx, ref.Path, ref.Version)
+}
+
+// CheckAccess returns an error unless a job owned by one of owners may
+// reference the secret. Secrets stored by Peloton belong to the job which
+// created them and are always allowed.
+func (p *provider) CheckAccess(ref *Reference, owners ...string) error {
+	if !ref.IsExternal() {
+		return nil
+	}
+	if _, ok := p.stores[ref.Store]; !ok {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"secret store %q is not configured", ref.Store)
+	}
+	// The path must be clean so that it cannot escape an allowed prefix.
+	secretPath := "/" + strings.TrimLeft(ref.Path, "/")
+	if path.Clean(secretPath) != secretPath {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"secret path %s is not clean", ref.Path)
+	}
+	for _, owner := range owners {
+		if owner == "" {
+			continue
+		}
+		for _, prefix := range p.allowedPaths[ref.Store][owner] {
+			prefix = path.Clean("/" + prefix)
+			if secretPath == prefix ||
+				strings.HasPrefix(secretPath, strings.TrimSuffix(prefix, "/")+"/") {
+				return nil
+			}
+		}
+	}
+	return yarpcerrors.PermissionDeniedErrorf(
+		"secret %s is not allowed for owners %v", ref.String(), owners)
+}
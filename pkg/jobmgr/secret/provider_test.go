@@ -0,0 +1,276 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type providerTestSuite struct {
+	suite.Suite
+
+	ctrl          *gomock.Controller
+	secretInfoOps *objectmocks.MockSecretInfoOps
+	rootDir       string
+	vault         *httptest.Server
+	provider      Provider
+}
+
+func (suite *providerTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.secretInfoOps = objectmocks.NewMockSecretInfoOps(suite.ctrl)
+
+	var err error
+	suite.rootDir, err = ioutil.TempDir("", "secrets")
+	suite.NoError(err)
+	suite.writeFileSecret("db/password", "1", "hunter1")
+	suite.writeFileSecret("db/password", "2", "hunter2")
+
+	suite.vault = httptest.NewServer(http.HandlerFunc(suite.serveVault))
+
+	suite.provider, err = NewProvider(Config{
+		File: FileConfig{
+			RootDir:      suite.rootDir,
+			AllowedPaths: map[string][]string{"team1": {"db"}},
+		},
+		Vault: VaultConfig{
+			Address:      suite.vault.URL,
+			Token:        "token",
+			AllowedPaths: map[string][]string{"user1": {"/db/"}},
+		},
+	}, suite.secretInfoOps)
+	suite.NoError(err)
+}
+
+func (suite *providerTestSuite) TearDownTest() {
+	suite.vault.Close()
+	os.RemoveAll(suite.rootDir)
+	suite.ctrl.Finish()
+}
+
+func TestProvider(t *testing.T) {
+	suite.Run(t, new(providerTestSuite))
+}
+
+func (suite *providerTestSuite) writeFileSecret(path, version, data string) {
+	dir := filepath.Join(suite.rootDir, path)
+	suite.NoError(os.MkdirAll(dir, 0700))
+	suite.NoError(ioutil.WriteFile(
+		filepath.Join(dir, version), []byte(data), 0600))
+}
+
+// serveVault fakes a Vault key/value version 2 secrets engine holding
+// versions 1 to 3 of secret/db/password.
+func (suite *providerTestSuite) serveVault(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	if r.Header.Get(_vaultTokenHeader) != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"errors":["permission denied"]}`)
+		return
+	}
+	if r.URL.Path != "/v1/secret/data/db/password" {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors":[]}`)
+		return
+	}
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		version = "3"
+	}
+	fmt.Fprintf(w,
+		`{"data":{"data":{"value":"vault%s"},"metadata":{"version":%s}}}`,
+		version, version)
+}
+
+// TestCassandraStore tests reading secrets stored by Peloton.
+func (suite *providerTestSuite) TestCassandraStore() {
+	suite.secretInfoOps.EXPECT().
+		GetSecret(gomock.Any(), "secret-id").
+		Return(&ormobjects.SecretInfoObject{
+			SecretID: "secret-id",
+			Data:     base64.StdEncoding.EncodeToString([]byte("data")),
+		}, nil)
+
+	s, err := suite.provider.GetSecret(
+		context.Background(), ParseReference("secret-id"))
+	suite.NoError(err)
+	suite.Equal([]byte("data"), s.Data)
+	suite.Equal("0", s.Version)
+}
+
+// TestCassandraStoreFailure tests failures to read secrets stored by Peloton.
+func (suite *providerTestSuite) TestCassandraStoreFailure() {
+	suite.secretInfoOps.EXPECT().
+		GetSecret(gomock.Any(), "secret-id").
+		Return(nil, errors.New("cassandra error"))
+	_, err := suite.provider.GetSecret(
+		context.Background(), ParseReference("secret-id"))
+	suite.Error(err)
+
+	suite.secretInfoOps.EXPECT().
+		GetSecret(gomock.Any(), "secret-id").
+		Return(&ormobjects.SecretInfoObject{Data: "not base64"}, nil)
+	_, err = suite.provider.GetSecret(
+		context.Background(), ParseReference("secret-id"))
+	suite.Error(err)
+}
+
+// TestFileStore tests reading secrets from files.
+func (suite *providerTestSuite) TestFileStore() {
+	s, err := suite.provider.GetSecret(
+		context.Background(), ParseReference("file:db/password"))
+	suite.NoError(err)
+	suite.Equal([]byte("hunter2"), s.Data)
+	suite.Equal("2", s.Version)
+
+	s, err = suite.provider.GetSecret(
+		context.Background(), ParseReference("file:db/password@1"))
+	suite.NoError(err)
+	suite.Equal([]byte("hunter1"), s.Data)
+	suite.Equal("1", s.Version)
+}
+
+// TestFileStoreFailure tests failures to read secrets from files.
+func (suite *providerTestSuite) TestFileStoreFailure() {
+	for ref, isErr := range map[string]func(error) bool{
+		"file:db/password@3":              yarpcerrors.IsNotFound,
+		"file:db/password@latest":         yarpcerrors.IsInvalidArgument,
+		"file:db/user":                    yarpcerrors.IsNotFound,
+		"file:../../etc/passwd":           yarpcerrors.IsNotFound,
+		"file:../" + suite.rootDir + "@1": yarpcerrors.IsNotFound,
+	} {
+		_, err := suite.provider.GetSecret(
+			context.Background(), ParseReference(ref))
+		suite.True(isErr(err), ref)
+	}
+}
+
+// TestVaultStore tests reading secrets from Vault.
+func (suite *providerTestSuite) TestVaultStore() {
+	s, err := suite.provider.GetSecret(
+		context.Background(), ParseReference("vault:db/password"))
+	suite.NoError(err)
+	suite.Equal([]byte("vault3"), s.Data)
+	suite.Equal("3", s.Version)
+
+	s, err = suite.provider.GetSecret(
+		context.Background(), ParseReference("vault:db/password@2"))
+	suite.NoError(err)
+	suite.Equal([]byte("vault2"), s.Data)
+	suite.Equal("2", s.Version)
+}
+
+// TestVaultStoreFailure tests failures to read secrets from Vault.
+func (suite *providerTestSuite) TestVaultStoreFailure() {
+	_, err := suite.provider.GetSecret(
+		context.Background(), ParseReference("vault:db/user"))
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	provider, err := NewProvider(Config{
+		Vault: VaultConfig{Address: suite.vault.URL, Token: "invalid"},
+	}, suite.secretInfoOps)
+	suite.NoError(err)
+	_, err = provider.GetSecret(
+		context.Background(), ParseReference("vault:db/password"))
+	suite.True(yarpcerrors.IsPermissionDenied(err))
+
+	provider, err = NewProvider(Config{
+		Vault: VaultConfig{Address: suite.vault.URL, Key: "password"},
+	}, suite.secretInfoOps)
+	suite.NoError(err)
+	_, err = provider.GetSecret(
+		context.Background(), ParseReference("vault:db/password"))
+	suite.Error(err)
+
+	_, err = NewProvider(Config{
+		Vault: VaultConfig{
+			Address:   suite.vault.URL,
+			TokenFile: filepath.Join(suite.rootDir, "token"),
+		},
+	}, suite.secretInfoOps)
+	suite.Error(err)
+}
+
+// TestVaultStoreTokenFile tests reading the Vault token from a file.
+func (suite *providerTestSuite) TestVaultStoreTokenFile() {
+	tokenFile := filepath.Join(suite.rootDir, "token")
+	suite.NoError(ioutil.WriteFile(tokenFile, []byte("token\n"), 0600))
+
+	provider, err := NewProvider(Config{
+		Vault: VaultConfig{Address: suite.vault.URL, TokenFile: tokenFile},
+	}, suite.secretInfoOps)
+	suite.NoError(err)
+	_, err = provider.GetSecret(
+		context.Background(), ParseReference("vault:db/password"))
+	suite.NoError(err)
+}
+
+// TestUnknownStore tests references to stores not configured.
+func (suite *providerTestSuite) TestUnknownStore() {
+	provider, err := NewProvider(Config{}, suite.secretInfoOps)
+	suite.NoError(err)
+
+	for _, ref := range []string{"vault:db/password", "file:db/password", ""} {
+		_, err := provider.GetSecret(context.Background(), ParseReference(ref))
+		suite.True(yarpcerrors.IsInvalidArgument(err), ref)
+	}
+}
+
+// TestCheckAccess tests the secrets a job may reference given its owners.
+func (suite *providerTestSuite) TestCheckAccess() {
+	// secrets stored by Peloton
+	suite.NoError(suite.provider.CheckAccess(ParseReference("secret-id")))
+
+	// secrets allowed for the owner or the owning team
+	suite.NoError(suite.provider.CheckAccess(
+		ParseReference("file:db/password@1"), "user1", "team1"))
+	suite.NoError(suite.provider.CheckAccess(
+		ParseReference("vault:db/password"), "user1", "team1"))
+	suite.NoError(suite.provider.CheckAccess(
+		ParseReference("vault:db"), "user1"))
+
+	for _, tc := range []struct {
+		ref    string
+		owners []string
+		check  func(error) bool
+	}{
+		{"vault:db/password", []string{"user2", "team1"}, yarpcerrors.IsPermissionDenied},
+		{"vault:dbadmin/password", []string{"user1"}, yarpcerrors.IsPermissionDenied},
+		{"file:db/password", nil, yarpcerrors.IsPermissionDenied},
+		{"vault:db/../admin/password", []string{"user1"}, yarpcerrors.IsInvalidArgument},
+		{"s3:db/password", []string{"user1"}, yarpcerrors.IsInvalidArgument},
+	} {
+		err := suite.provider.CheckAccess(ParseReference(tc.ref), tc.owners...)
+		suite.True(tc.check(err), "ref %s: %v", tc.ref, err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"strings"
+)
+
+// Reference identifies a secret in one of the secret stores. It is encoded
+// in the data of the secret volumes of the job config as
+// `[<store>:]<path>[@<version>]`. A reference without a store refers to a
+// secret stored by Peloton in the secret_info table, in which case the path
+// is the secret ID.
+type Reference struct {
+	// Store holding the secret, empty for secrets stored by Peloton.
+	Store string
+	// Path of the secret in the store.
+	Path string
+	// Version of the secret, empty for the latest version.
+	Version string
+}
+
+// ParseReference parses a secret reference from its string encoding.
+func ParseReference(s string) *Reference {
+	ref := &Reference{}
+	if i := strings.Index(s, ":"); i > 0 {
+		ref.Store, s = s[:i], s[i+1:]
+	} else {
+		// Secrets stored by Peloton are only referenced by ID.
+		ref.Path = s
+		return ref
+	}
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		ref.Path, ref.Version = s[:i], s[i+1:]
+	} else {
+		ref.Path = s
+	}
+	return ref
+}
+
+// IsExternal returns true if the secret is held by an external secret store
+// rather than by Peloton.
+func (r *Reference) IsExternal() bool {
+	return r.Store != ""
+}
+
+// Matches returns true if both references point to the same secret,
+// regardless of the version.
+func (r *Reference) Matches(other *Reference) bool {
+	return r.Store == other.Store && r.Path == other.Path
+}
+
+// String returns the encoding of the reference stored in secret volumes.
+func (r *Reference) String() string {
+	if !r.IsExternal() {
+		return r.Path
+	}
+	s := r.Store + ":" + r.Path
+	if r.Version != "" {
+		s += "@" + r.Version
+	}
+	return s
+}
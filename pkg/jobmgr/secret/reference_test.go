@@ -0,0 +1,60 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	tt := []struct {
+		s   string
+		ref Reference
+	}{
+		{
+			s:   "d6b3ad5e-1c4f-4c49-9d0f-4e5b3a8e8f01",
+			ref: Reference{Path: "d6b3ad5e-1c4f-4c49-9d0f-4e5b3a8e8f01"},
+		},
+		{
+			s:   "vault:db/password",
+			ref: Reference{Store: "vault", Path: "db/password"},
+		},
+		{
+			s:   "vault:db/password@3",
+			ref: Reference{Store: "vault", Path: "db/password", Version: "3"},
+		},
+		{
+			s:   "file:user@example.com@12",
+			ref: Reference{Store: "file", Path: "user@example.com", Version: "12"},
+		},
+	}
+
+	for _, test := range tt {
+		ref := ParseReference(test.s)
+		assert.Equal(t, test.ref, *ref, test.s)
+		assert.Equal(t, test.s, ref.String())
+		assert.Equal(t, test.ref.Store != "", ref.IsExternal())
+	}
+}
+
+func TestReferenceMatches(t *testing.T) {
+	ref := ParseReference("vault:db/password@3")
+	assert.True(t, ref.Matches(ParseReference("vault:db/password")))
+	assert.True(t, ref.Matches(ParseReference("vault:db/password@4")))
+	assert.False(t, ref.Matches(ParseReference("file:db/password@3")))
+	assert.False(t, ref.Matches(ParseReference("vault:db/user@3")))
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const _vaultTokenHeader = "X-Vault-Token"
+
+// vaultStore reads secrets from a Vault key/value version 2 secrets engine.
+type vaultStore struct {
+	cfg    VaultConfig
+	token  string
+	client *http.Client
+}
+
+// vaultResponse is the subset of the response of Vault to a read of a
+// key/value version 2 secret used by Peloton.
+type vaultResponse struct {
+	Data struct {
+		Data     map[string]interface{} `json:"data"`
+		Metadata struct {
+			Version int64 `json:"version"`
+		} `json:"metadata"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+func newVaultStore(cfg VaultConfig) (store, error) {
+	cfg.normalize()
+
+	token := cfg.Token
+	if cfg.TokenFile != "" {
+		b, err := ioutil.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vault token: %v", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+
+	return &vaultStore{
+		cfg:    cfg,
+		token:  token,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (s *vaultStore) getSecret(
+	ctx context.Context,
+	path, version string,
+) (*Secret, error) {
+	u := fmt.Sprintf("%s/v1/%s/data/%s",
+		strings.TrimRight(s.cfg.Address, "/"),
+		strings.Trim(s.cfg.Mount, "/"),
+		strings.TrimLeft(path, "/"))
+	if version != "" {
+		u += "?" + url.Values{"version": {version}}.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(_vaultTokenHeader, s.token)
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, yarpcerrors.UnavailableErrorf(
+			"failed to read secret %s from vault: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil &&
+		resp.StatusCode == http.StatusOK {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, yarpcerrors.NotFoundErrorf(
+			"secret %s not found in vault", path)
+	case http.StatusForbidden:
+		return nil, yarpcerrors.PermissionDeniedErrorf(
+			"access to secret %s denied by vault", path)
+	default:
+		return nil, yarpcerrors.InternalErrorf(
+			"failed to read secret %s from vault: %d %v",
+			path, resp.StatusCode, body.Errors)
+	}
+
+	value, ok := body.Data.Data[s.cfg.Key].(string)
+	if !ok {
+		return nil, yarpcerrors.NotFoundErrorf(
+			"secret %s has no string key %q", path, s.cfg.Key)
+	}
+	return &Secret{
+		Data:    []byte(value),
+		Version: strconv.FormatInt(body.Data.Metadata.Version, 10),
+	}, nil
+}
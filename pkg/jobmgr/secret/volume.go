@@ -0,0 +1,56 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+
+	"github.com/uber/peloton/pkg/common/util"
+)
+
+// VolumeReference returns the reference of the secret mounted by a secret
+// volume, and nil for other volumes.
+func VolumeReference(volume *mesos.Volume) *Reference {
+	if !util.IsSecretVolume(volume) ||
+		volume.GetSource().GetSecret().GetValue().GetData() == nil {
+		return nil
+	}
+	return ParseReference(
+		string(volume.GetSource().GetSecret().GetValue().GetData()))
+}
+
+// RewriteReferences finds the secret volumes mounting the secret ref and,
+// if version is not empty, pins them to that version of the secret. It
+// returns true if any volume mounts the secret.
+func RewriteReferences(
+	volumes []*mesos.Volume,
+	ref *Reference,
+	version string,
+) bool {
+	found := false
+	for _, volume := range volumes {
+		volumeRef := VolumeReference(volume)
+		if volumeRef == nil || !volumeRef.Matches(ref) {
+			continue
+		}
+		found = true
+		if version != "" {
+			volumeRef.Version = version
+			volume.GetSource().GetSecret().GetValue().Data =
+				[]byte(volumeRef.String())
+		}
+	}
+	return found
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/pkg/common/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteReferences(t *testing.T) {
+	volumes := []*mesos.Volume{
+		util.CreateSecretVolume("/tmp/db", "vault:db/password@3"),
+		util.CreateSecretVolume("/tmp/api", "vault:api/key@1"),
+		util.CreateSecretVolume("/tmp/id", "secret-id"),
+	}
+	data := func(i int) string {
+		return string(volumes[i].GetSource().GetSecret().GetValue().GetData())
+	}
+
+	// Matching without a version leaves the references unchanged.
+	assert.True(t, RewriteReferences(
+		volumes, ParseReference("vault:db/password"), ""))
+	assert.Equal(t, "vault:db/password@3", data(0))
+
+	assert.True(t, RewriteReferences(
+		volumes, ParseReference("vault:db/password"), "4"))
+	assert.Equal(t, "vault:db/password@4", data(0))
+	assert.Equal(t, "vault:api/key@1", data(1))
+	assert.Equal(t, "secret-id", data(2))
+
+	assert.True(t, RewriteReferences(volumes, ParseReference("secret-id"), ""))
+	assert.False(t, RewriteReferences(
+		volumes, ParseReference("file:db/password"), "4"))
+}
@@ -17,7 +17,6 @@ package launcher
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"fmt"
 	"strings"
 	"sync"
@@ -40,6 +39,7 @@ import (
 	"github.com/uber/peloton/pkg/common/util"
 	"github.com/uber/peloton/pkg/jobmgr/cached"
 	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+	"github.com/uber/peloton/pkg/jobmgr/secret"
 	ormobjects "github.com/uber/peloton/pkg/storage/objects"
 
 	"github.com/pkg/errors"
@@ -133,7 +133,7 @@ type launcher struct {
 	hostMgrV1AlphaClient svc.HostManagerServiceYARPCClient
 	jobFactory           cached.JobFactory
 	taskConfigV2Ops      ormobjects.TaskConfigV2Ops
-	secretInfoOps        ormobjects.SecretInfoOps
+	secretProvider       secret.Provider
 	metrics              *Metrics
 	retryPolicy          backoff.RetryPolicy
 	hmVersion            api.Version
@@ -143,8 +143,8 @@ const (
 	// Time out for the function to time out
 	_rpcTimeout = 10 * time.Second
 
-	// default secret operations cassandra timeout
-	_defaultSecretInfoOpsTimeout = 10 * time.Second
+	// default timeout of reading a secret from its secret store
+	_defaultSecretProviderTimeout = 10 * time.Second
 )
 
 var (
@@ -161,6 +161,7 @@ func InitTaskLauncher(
 	hostMgrClientName string,
 	jobFactory cached.JobFactory,
 	ormStore *ormobjects.Store,
+	secretProvider secret.Provider,
 	parent tally.Scope,
 	hmVersion api.Version,
 ) {
@@ -177,7 +178,7 @@ func InitTaskLauncher(
 				d.ClientConfig(hostMgrClientName)),
 			jobFactory:      jobFactory,
 			taskConfigV2Ops: ormobjects.NewTaskConfigV2Ops(ormStore),
-			secretInfoOps:   ormobjects.NewSecretInfoOps(ormStore),
+			secretProvider:  secretProvider,
 			metrics:         NewMetrics(parent.SubScope("jobmgr").SubScope("task")),
 			// TODO: make launch retry policy config.
 			retryPolicy: backoff.NewRetryPolicy(3, 15*time.Second),
@@ -316,6 +317,15 @@ func (l *launcher) GetLaunchableTasks(
 	skippedTasks := make([]*peloton.TaskID, 0)
 	getTaskInfoStart := time.Now()
 
+	// group the tasks by job so that their runtimes and configurations
+	// can be read from DB in batches
+	type launchTarget struct {
+		mtaskID    *mesos.TaskID
+		jobID      string
+		instanceID uint32
+	}
+	var targets []launchTarget
+	mtaskIDsByJob := make(map[string]map[uint32]*mesos.TaskID)
 	for _, mtaskID := range tasks {
 		id, instanceID, err := util.ParseJobAndInstanceID(mtaskID.GetValue())
 		if err != nil {
@@ -325,71 +335,59 @@ func (l *launcher) GetLaunchableTasks(
 			continue
 		}
 
-		jobID := &peloton.JobID{Value: id}
-		ptaskID := &peloton.TaskID{Value: util.CreatePelotonTaskID(id, instanceID)}
+		targets = append(targets, launchTarget{
+			mtaskID:    mtaskID,
+			jobID:      id,
+			instanceID: instanceID,
+		})
+		if _, ok := mtaskIDsByJob[id]; !ok {
+			mtaskIDsByJob[id] = make(map[uint32]*mesos.TaskID)
+		}
+		mtaskIDsByJob[id][instanceID] = mtaskID
+	}
 
-		cachedJob := l.jobFactory.GetJob(jobID)
+	launchInfos := make(map[string]map[uint32]*launchInfo)
+	for id, mtaskIDs := range mtaskIDsByJob {
+		cachedJob := l.jobFactory.GetJob(&peloton.JobID{Value: id})
 		if cachedJob == nil {
-			skippedTasks = append(skippedTasks, ptaskID)
 			continue
 		}
+		launchInfos[id] = l.getLaunchInfos(ctx, cachedJob, mtaskIDs)
+	}
 
-		cachedTask, err := cachedJob.AddTask(ctx, uint32(instanceID))
-		if err != nil {
-			log.WithError(err).
-				WithFields(log.Fields{
-					"job_id":      jobID.GetValue(),
-					"instance_id": uint32(instanceID),
-				}).Error("cannot add and recover task from DB")
+	for _, target := range targets {
+		jobID := &peloton.JobID{Value: target.jobID}
+		ptaskID := &peloton.TaskID{
+			Value: util.CreatePelotonTaskID(target.jobID, target.instanceID),
+		}
+
+		infos, ok := launchInfos[target.jobID]
+		if !ok {
+			skippedTasks = append(skippedTasks, ptaskID)
 			continue
 		}
 
-		cachedRuntime, err := cachedTask.GetRuntime(ctx)
-		if err != nil {
-			log.WithError(err).
-				WithFields(log.Fields{
-					"job_id":      jobID.GetValue(),
-					"instance_id": uint32(instanceID),
-				}).Error("cannot fetch task runtime")
+		info, ok := infos[target.instanceID]
+		if !ok {
+			// the failure has already been logged
 			continue
 		}
+		cachedRuntime := info.runtime
+		taskConfig := info.config
+		configAddOn := info.configAddOn
+		spec := info.spec
 
-		if cachedRuntime.GetMesosTaskId().GetValue() != mtaskID.GetValue() {
+		if cachedRuntime.GetMesosTaskId().GetValue() !=
+			target.mtaskID.GetValue() {
 			log.WithFields(log.Fields{
 				"job_id":        jobID.GetValue(),
-				"instance_id":   uint32(instanceID),
-				"mesos_task_id": mtaskID.GetValue(),
+				"instance_id":   target.instanceID,
+				"mesos_task_id": target.mtaskID.GetValue(),
 			}).Info("skipping launch of old run")
 			skippedTasks = append(skippedTasks, ptaskID)
 			continue
 		}
 
-		// TODO: We need to add batch api's for getting all tasks in one shot
-		taskConfig, configAddOn, err := l.taskConfigV2Ops.GetTaskConfig(
-			ctx,
-			jobID,
-			uint32(instanceID),
-			cachedRuntime.GetConfigVersion())
-		if err != nil {
-			log.WithError(err).WithField("task_id", ptaskID.GetValue()).
-				Error("not able to get task configuration")
-			continue
-		}
-
-		var spec *pbpod.PodSpec
-		if l.hmVersion.IsV1() {
-			spec, err = l.taskConfigV2Ops.GetPodSpec(
-				ctx,
-				jobID,
-				uint32(instanceID),
-				cachedRuntime.GetConfigVersion())
-			if err != nil {
-				log.WithError(err).WithField("task_id", ptaskID.GetValue()).
-					Error("not able to get pod spec")
-				continue
-			}
-		}
-
 		runtimeDiff := make(jobmgrcommon.RuntimeDiff)
 		if cachedRuntime.GetGoalState() != task.TaskState_KILLED {
 			runtimeDiff[jobmgrcommon.HostField] = hostname
@@ -447,6 +445,97 @@ func (l *launcher) GetLaunchableTasks(
 	return launchableTasks, skippedTasks, nil
 }
 
+// launchInfo is the runtime and configuration of a task read from DB
+// to launch it.
+type launchInfo struct {
+	runtime     *task.RuntimeInfo
+	config      *task.TaskConfig
+	configAddOn *models.ConfigAddOn
+	spec        *pbpod.PodSpec
+}
+
+// getLaunchInfos reads the runtimes and configurations of the given
+// instances of a job in batches. Tasks whose runtime belongs to a run
+// other than the given mesos task id are returned without configuration,
+// and tasks which cannot be read are logged and left out of the result.
+func (l *launcher) getLaunchInfos(
+	ctx context.Context,
+	cachedJob cached.Job,
+	mtaskIDs map[uint32]*mesos.TaskID,
+) map[uint32]*launchInfo {
+	jobID := cachedJob.ID()
+	infos := make(map[uint32]*launchInfo)
+
+	instanceIDs := make([]uint32, 0, len(mtaskIDs))
+	for instanceID := range mtaskIDs {
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+
+	cachedTasks, errs := cachedJob.AddTasks(ctx, instanceIDs)
+	for instanceID, err := range errs {
+		log.WithError(err).
+			WithFields(log.Fields{
+				"job_id":      jobID.GetValue(),
+				"instance_id": instanceID,
+			}).Error("cannot add and recover task from DB")
+	}
+
+	versions := make(map[uint32]uint64)
+	for instanceID, cachedTask := range cachedTasks {
+		cachedRuntime, err := cachedTask.GetRuntime(ctx)
+		if err != nil {
+			log.WithError(err).
+				WithFields(log.Fields{
+					"job_id":      jobID.GetValue(),
+					"instance_id": instanceID,
+				}).Error("cannot fetch task runtime")
+			continue
+		}
+
+		infos[instanceID] = &launchInfo{runtime: cachedRuntime}
+		if cachedRuntime.GetMesosTaskId().GetValue() ==
+			mtaskIDs[instanceID].GetValue() {
+			versions[instanceID] = cachedRuntime.GetConfigVersion()
+		}
+	}
+
+	if len(versions) == 0 {
+		return infos
+	}
+
+	configs, errs := l.taskConfigV2Ops.GetTaskConfigs(ctx, jobID, versions)
+	for instanceID, err := range errs {
+		log.WithError(err).
+			WithField("task_id", util.CreatePelotonTaskID(
+				jobID.GetValue(), instanceID)).
+			Error("not able to get task configuration")
+		delete(infos, instanceID)
+	}
+	for instanceID, result := range configs {
+		infos[instanceID].config = result.TaskConfig
+		infos[instanceID].configAddOn = result.ConfigAddOn
+	}
+
+	if !l.hmVersion.IsV1() {
+		return infos
+	}
+
+	specs, errs := l.taskConfigV2Ops.GetPodSpecs(ctx, jobID, versions)
+	for instanceID, err := range errs {
+		log.WithError(err).
+			WithField("task_id", util.CreatePelotonTaskID(
+				jobID.GetValue(), instanceID)).
+			Error("not able to get pod spec")
+		delete(infos, instanceID)
+	}
+	for instanceID, spec := range specs {
+		if info, ok := infos[instanceID]; ok {
+			info.spec = spec
+		}
+	}
+	return infos
+}
+
 // updateTaskRuntime updates task runtime with goalstate, reason and message
 // for the given task id.
 func (l *launcher) updateTaskRuntime(
@@ -651,9 +740,9 @@ func (l *launcher) TryReturnOffers(ctx context.Context, err error, placement *re
 
 // populateSecrets checks task config for secret volumes.
 // If the config has volumes of type secret, it means that the Value field
-// of that secret contains the secret ID. This function queries
-// the DB to fetch the secret by secret ID and then replaces
-// the secret Value by the fetched secret data.
+// of that secret contains the secret reference, which is the secret ID for
+// secrets stored by Peloton. This function fetches the secret from its
+// secret store and then replaces the secret Value by the fetched secret data.
 // We do this to prevent secrets from being leaked as a part
 // of job or task config and populate the task config with
 // actual secrets just before task launch.
@@ -666,32 +755,24 @@ func (l *launcher) populateSecrets(
 	for _, volume := range taskConfig.GetContainer().GetVolumes() {
 		if volume.GetSource().GetType() == mesos.Volume_Source_SECRET &&
 			volume.GetSource().GetSecret().GetValue().GetData() != nil {
-			// Replace secret ID with actual secret here.
-			// This is done to make sure secrets are read from the DB
-			// when it is absolutely necessary and that they are not
-			// persisted in any place other than the secret_info table
+			// Replace secret reference with actual secret here.
+			// This is done to make sure secrets are read from the secret
+			// store when it is absolutely necessary and that they are not
+			// persisted in any place other than the secret store
 			// (for example as part of job/task config)
 			ctx, cancel := context.WithTimeout(
-				context.Background(), _defaultSecretInfoOpsTimeout)
+				context.Background(), _defaultSecretProviderTimeout)
 			defer cancel()
 
-			secretID := string(volume.GetSource().GetSecret().GetValue().GetData())
-			secretInfoObj, err := l.secretInfoOps.GetSecret(
+			s, err := l.secretProvider.GetSecret(
 				ctx,
-				secretID,
+				secret.VolumeReference(volume),
 			)
-
-			if err != nil {
-				l.metrics.TaskPopulateSecretFail.Inc(1)
-				return err
-			}
-			secretStr, err := base64.StdEncoding.DecodeString(secretInfoObj.Data)
 			if err != nil {
 				l.metrics.TaskPopulateSecretFail.Inc(1)
 				return err
 			}
-			volume.GetSource().GetSecret().GetValue().Data =
-				[]byte(secretStr)
+			volume.GetSource().GetSecret().GetValue().Data = s.Data
 		}
 	}
 	return nil
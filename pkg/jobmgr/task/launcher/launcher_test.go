@@ -49,8 +49,10 @@ import (
 	"github.com/uber/peloton/pkg/common/api"
 	"github.com/uber/peloton/pkg/common/backoff"
 	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
 	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
 	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+	"github.com/uber/peloton/pkg/jobmgr/secret"
 	jobmgrtask "github.com/uber/peloton/pkg/jobmgr/task"
 	store_mocks "github.com/uber/peloton/pkg/storage/mocks"
 )
@@ -111,6 +113,10 @@ func (suite *LauncherTestSuite) SetupTest() {
 	suite.secretInfoOps = objectmocks.NewMockSecretInfoOps(suite.ctrl)
 	suite.taskConfigV2Ops = objectmocks.NewMockTaskConfigV2Ops(suite.ctrl)
 
+	secretProvider, err := secret.NewProvider(
+		secret.Config{}, suite.secretInfoOps)
+	suite.NoError(err)
+
 	suite.testScope = tally.NewTestScope("", map[string]string{})
 	suite.metrics = NewMetrics(suite.testScope)
 	suite.taskLauncher = launcher{
@@ -118,7 +124,7 @@ func (suite *LauncherTestSuite) SetupTest() {
 		hostMgrV1AlphaClient: suite.mockV1HostMgr,
 		jobFactory:           suite.jobFactory,
 		taskConfigV2Ops:      suite.taskConfigV2Ops,
-		secretInfoOps:        suite.secretInfoOps,
+		secretProvider:       secretProvider,
 		metrics:              suite.metrics,
 		retryPolicy:          backoff.NewRetryPolicy(5, 15*time.Millisecond),
 	}
@@ -235,22 +241,34 @@ func (suite *LauncherTestSuite) TestGetLaunchableTasks() {
 	rs := createResources(1)
 	hostOffer := createHostOffer(0, rs)
 
+	cachedTasks := make(map[uint32]cached.Task)
+	taskConfigs := make(map[uint32]*objects.TaskConfigResult)
 	for i := 0; i < numTasks; i++ {
 		mtaskID := tasks[i].GetValue()
 		jobID, instanceID, err := util.ParseJobAndInstanceID(mtaskID)
 		suite.NoError(err)
 		ptaskID := util.CreatePelotonTaskID(jobID, instanceID)
-		suite.jobFactory.EXPECT().
-			GetJob(&peloton.JobID{Value: jobID}).Return(suite.cachedJob)
-		suite.cachedJob.EXPECT().
-			AddTask(gomock.Any(), uint32(instanceID)).
-			Return(suite.cachedTask, nil)
-		suite.taskConfigV2Ops.EXPECT().
-			GetTaskConfig(gomock.Any(), &peloton.JobID{Value: jobID}, uint32(instanceID), gomock.Any()).
-			Return(taskInfos[ptaskID].GetConfig(), &models.ConfigAddOn{}, nil)
-		suite.cachedTask.EXPECT().
+		cachedTask := cachedmocks.NewMockTask(suite.ctrl)
+		cachedTask.EXPECT().
 			GetRuntime(gomock.Any()).Return(taskInfos[ptaskID].GetRuntime(), nil)
+		cachedTasks[instanceID] = cachedTask
+		taskConfigs[instanceID] = &objects.TaskConfigResult{
+			TaskConfig:  taskInfos[ptaskID].GetConfig(),
+			ConfigAddOn: &models.ConfigAddOn{},
+		}
 	}
+	suite.jobFactory.EXPECT().
+		GetJob(&peloton.JobID{Value: _testJobID}).Return(suite.cachedJob)
+	suite.cachedJob.EXPECT().ID().Return(&peloton.JobID{Value: _testJobID})
+	suite.cachedJob.EXPECT().
+		AddTasks(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, ids []uint32) {
+			suite.Len(ids, numTasks)
+		}).
+		Return(cachedTasks, map[uint32]error{})
+	suite.taskConfigV2Ops.EXPECT().
+		GetTaskConfigs(gomock.Any(), &peloton.JobID{Value: _testJobID}, gomock.Any()).
+		Return(taskConfigs, map[uint32]error{})
 
 	for _, taskID := range unknownTasks {
 		jobID, _, err := util.ParseJobAndInstanceID(taskID.GetValue())
@@ -291,9 +309,12 @@ func (suite *LauncherTestSuite) TestGetLaunchableTasksSkipOldRun() {
 
 	suite.jobFactory.EXPECT().
 		GetJob(&peloton.JobID{Value: jobID}).Return(suite.cachedJob)
+	suite.cachedJob.EXPECT().ID().Return(&peloton.JobID{Value: jobID})
 	suite.cachedJob.EXPECT().
-		AddTask(gomock.Any(), uint32(instanceID)).
-		Return(suite.cachedTask, nil)
+		AddTasks(gomock.Any(), []uint32{uint32(instanceID)}).
+		Return(
+			map[uint32]cached.Task{uint32(instanceID): suite.cachedTask},
+			map[uint32]error{})
 	suite.cachedTask.EXPECT().
 		GetRuntime(gomock.Any()).Return(testLaunchableTask.GetRuntime(), nil)
 
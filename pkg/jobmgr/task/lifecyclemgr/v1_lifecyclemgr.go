@@ -120,7 +120,7 @@ func (l *v1LifecycleMgr) Launch(
 }
 
 // Kill tries to kill the pod using podID.
-// Functionality to reserve a host is not implemented in v1.
+// If a host is provided, it kills the pod and holds the host for the pod.
 func (l *v1LifecycleMgr) Kill(
 	ctx context.Context,
 	podID string,
@@ -140,12 +140,27 @@ func (l *v1LifecycleMgr) Kill(
 			"rate limit reached for kill")
 	}
 
-	_, err := l.hostManagerV1.KillPods(
-		ctx,
-		&v1_hostsvc.KillPodsRequest{
-			PodIds: []*peloton.PodID{{Value: podID}},
-		},
-	)
+	var err error
+	if len(hostToReserve) != 0 {
+		_, err = l.hostManagerV1.KillAndHoldPods(
+			ctx,
+			&v1_hostsvc.KillAndHoldPodsRequest{
+				Entries: []*v1_hostsvc.KillAndHoldPodsRequest_Entry{
+					{
+						PodId:      &peloton.PodID{Value: podID},
+						HostToHold: hostToReserve,
+					},
+				},
+			},
+		)
+	} else {
+		_, err = l.hostManagerV1.KillPods(
+			ctx,
+			&v1_hostsvc.KillPodsRequest{
+				PodIds: []*peloton.PodID{{Value: podID}},
+			},
+		)
+	}
 	if err != nil {
 		l.metrics.KillFail.Inc(1)
 		return err
@@ -110,6 +110,27 @@ func (suite *v1LifecycleTestSuite) TestKill() {
 	suite.Nil(err)
 }
 
+// TestKillAndHold tests Kill pods holds the host when a host to reserve
+// is provided.
+func (suite *v1LifecycleTestSuite) TestKillAndHold() {
+	suite.mockHostMgr.EXPECT().
+		KillAndHoldPods(gomock.Any(), &v1_hostsvc.KillAndHoldPodsRequest{
+			Entries: []*v1_hostsvc.KillAndHoldPodsRequest_Entry{
+				{
+					PodId:      &peloton.PodID{Value: suite.podID},
+					HostToHold: "hostname",
+				},
+			},
+		})
+	err := suite.lm.Kill(
+		suite.ctx,
+		suite.podID,
+		"hostname",
+		nil,
+	)
+	suite.Nil(err)
+}
+
 // TestKillLock tests Kill pods is blocked when kill is locked
 func (suite *v1LifecycleTestSuite) TestKillLock() {
 	suite.lm.LockKill()
@@ -429,6 +429,8 @@ func ConvertJobConfigToJobSpec(config *job.JobConfig) *stateless.JobSpec {
 		InstanceSpec:  instanceSpec,
 		RespoolId: &v1alphapeloton.ResourcePoolID{
 			Value: config.GetRespoolID().GetValue()},
+		Autoscaling: ConvertAutoscalingConfigToAutoscalingSpec(
+			config.GetAutoscaling()),
 	}
 }
 
@@ -466,6 +468,8 @@ func ConvertUpdateModelToWorkflowStatus(
 		CreationTime:          updateInfo.GetCreationTime(),
 		UpdateTime:            updateInfo.GetUpdateTime(),
 		CompletionTime:        updateInfo.GetCompletionTime(),
+		CanaryCompletionTime:  updateInfo.GetCanaryCompletionTime(),
+		CanaryPromoted:        updateInfo.GetCanaryPromoted(),
 	}
 }
 
@@ -543,6 +547,44 @@ func ConvertSLASpecToSLAConfig(slaSpec *stateless.SlaSpec) *job.SlaConfig {
 	}
 }
 
+// ConvertAutoscalingConfigToAutoscalingSpec converts job's autoscaling
+// config to autoscaling spec
+func ConvertAutoscalingConfigToAutoscalingSpec(
+	config *job.AutoscalingConfig,
+) *stateless.AutoscalingSpec {
+	if config == nil {
+		return nil
+	}
+
+	return &stateless.AutoscalingSpec{
+		MinInstances:      config.GetMinInstances(),
+		MaxInstances:      config.GetMaxInstances(),
+		Metric:            stateless.AutoscalingMetric(config.GetMetric()),
+		TargetUtilization: config.GetTargetUtilization(),
+		CooldownSeconds:   config.GetCooldownSeconds(),
+		BatchSize:         config.GetBatchSize(),
+	}
+}
+
+// ConvertAutoscalingSpecToAutoscalingConfig converts job's autoscaling
+// spec to autoscaling config
+func ConvertAutoscalingSpecToAutoscalingConfig(
+	spec *stateless.AutoscalingSpec,
+) *job.AutoscalingConfig {
+	if spec == nil {
+		return nil
+	}
+
+	return &job.AutoscalingConfig{
+		MinInstances:      spec.GetMinInstances(),
+		MaxInstances:      spec.GetMaxInstances(),
+		Metric:            job.AutoscalingConfig_Metric(spec.GetMetric()),
+		TargetUtilization: spec.GetTargetUtilization(),
+		CooldownSeconds:   spec.GetCooldownSeconds(),
+		BatchSize:         spec.GetBatchSize(),
+	}
+}
+
 // ConvertUpdateModelToWorkflowInfo converts private UpdateModel
 // to v1alpha stateless.WorkflowInfo
 func ConvertUpdateModelToWorkflowInfo(
@@ -566,6 +608,8 @@ func ConvertUpdateModelToWorkflowInfo(
 			MaxTolerableInstanceFailures: updateInfo.GetUpdateConfig().GetMaxFailureInstances(),
 			StartPaused:                  updateInfo.GetUpdateConfig().GetStartPaused(),
 			InPlace:                      updateInfo.GetUpdateConfig().GetInPlace(),
+			Canary:                       ConvertCanaryConfigToCanarySpec(updateInfo.GetUpdateConfig().GetCanary()),
+			HealthGates:                  ConvertHealthGateConfigsToHealthGateSpecs(updateInfo.GetUpdateConfig().GetHealthGates()),
 		}
 	} else if updateInfo.GetType() == models.WorkflowType_RESTART {
 		result.RestartSpec = &stateless.RestartSpec{
@@ -711,6 +755,9 @@ func ConvertJobSpecToJobConfig(spec *stateless.JobSpec) (*job.JobConfig, error)
 		}
 	}
 
+	result.Autoscaling = ConvertAutoscalingSpecToAutoscalingConfig(
+		spec.GetAutoscaling())
+
 	return result, nil
 }
 
@@ -1315,7 +1362,127 @@ func ConvertUpdateSpecToUpdateConfig(spec *stateless.UpdateSpec) *update.UpdateC
 		StartPaused:         spec.GetStartPaused(),
 		InPlace:             spec.GetInPlace(),
 		StartTasks:          spec.GetStartPods(),
+		Canary:              ConvertCanarySpecToCanaryConfig(spec.GetCanary()),
+		HealthGates:         ConvertHealthGateSpecsToHealthGateConfigs(spec.GetHealthGates()),
+	}
+}
+
+// ConvertCanarySpecToCanaryConfig converts canary spec to canary config
+func ConvertCanarySpecToCanaryConfig(spec *stateless.CanarySpec) *update.CanaryConfig {
+	if spec == nil {
+		return nil
+	}
+
+	return &update.CanaryConfig{
+		InstanceCount:       spec.GetInstanceCount(),
+		InstancePercentage:  spec.GetInstancePercentage(),
+		BakePeriodSeconds:   spec.GetBakePeriodSeconds(),
+		AutoPromote:         spec.GetAutoPromote(),
+		MaxFailureInstances: spec.GetMaxTolerableInstanceFailures(),
+	}
+}
+
+// ConvertCanaryConfigToCanarySpec converts canary config to canary spec
+func ConvertCanaryConfigToCanarySpec(config *update.CanaryConfig) *stateless.CanarySpec {
+	if config == nil {
+		return nil
+	}
+
+	return &stateless.CanarySpec{
+		InstanceCount:                config.GetInstanceCount(),
+		InstancePercentage:           config.GetInstancePercentage(),
+		BakePeriodSeconds:            config.GetBakePeriodSeconds(),
+		AutoPromote:                  config.GetAutoPromote(),
+		MaxTolerableInstanceFailures: config.GetMaxFailureInstances(),
+	}
+}
+
+// ConvertHealthGateSpecsToHealthGateConfigs converts
+// health gate specs to health gate configs
+func ConvertHealthGateSpecsToHealthGateConfigs(
+	specs []*stateless.HealthGateSpec,
+) []*update.HealthGateConfig {
+	var result []*update.HealthGateConfig
+	for _, spec := range specs {
+		config := &update.HealthGateConfig{
+			Name:           spec.GetName(),
+			TimeoutSeconds: spec.GetTimeoutSeconds(),
+		}
+
+		if spec.GetOnFailure() == stateless.HealthGateFailureAction_HEALTH_GATE_FAILURE_ACTION_ROLLBACK {
+			config.OnFailure = update.HealthGateConfig_ROLLBACK
+		} else {
+			config.OnFailure = update.HealthGateConfig_PAUSE
+		}
+
+		if spec.GetWebhook() != nil {
+			config.Webhook = &update.WebhookGateConfig{
+				Url:     spec.GetWebhook().GetUrl(),
+				Headers: spec.GetWebhook().GetHeaders(),
+			}
+		}
+
+		if spec.GetPrometheus() != nil {
+			config.Prometheus = &update.PrometheusGateConfig{
+				Address:           spec.GetPrometheus().GetAddress(),
+				Query:             spec.GetPrometheus().GetQuery(),
+				Threshold:         spec.GetPrometheus().GetThreshold(),
+				PassOnEmptyResult: spec.GetPrometheus().GetPassOnEmptyResult(),
+			}
+			if spec.GetPrometheus().GetComparison() == stateless.PrometheusComparison_PROMETHEUS_COMPARISON_GREATER_THAN {
+				config.Prometheus.Comparison = update.PrometheusGateConfig_GREATER_THAN
+			} else {
+				config.Prometheus.Comparison = update.PrometheusGateConfig_LESS_THAN
+			}
+		}
+
+		result = append(result, config)
 	}
+	return result
+}
+
+// ConvertHealthGateConfigsToHealthGateSpecs converts
+// health gate configs to health gate specs
+func ConvertHealthGateConfigsToHealthGateSpecs(
+	configs []*update.HealthGateConfig,
+) []*stateless.HealthGateSpec {
+	var result []*stateless.HealthGateSpec
+	for _, config := range configs {
+		spec := &stateless.HealthGateSpec{
+			Name:           config.GetName(),
+			TimeoutSeconds: config.GetTimeoutSeconds(),
+		}
+
+		if config.GetOnFailure() == update.HealthGateConfig_ROLLBACK {
+			spec.OnFailure = stateless.HealthGateFailureAction_HEALTH_GATE_FAILURE_ACTION_ROLLBACK
+		} else {
+			spec.OnFailure = stateless.HealthGateFailureAction_HEALTH_GATE_FAILURE_ACTION_PAUSE
+		}
+
+		if config.GetWebhook() != nil {
+			spec.Webhook = &stateless.WebhookGateSpec{
+				Url:     config.GetWebhook().GetUrl(),
+				Headers: config.GetWebhook().GetHeaders(),
+			}
+		}
+
+		if config.GetPrometheus() != nil {
+			spec.Prometheus = &stateless.PrometheusGateSpec{
+				Address:           config.GetPrometheus().GetAddress(),
+				Query:             config.GetPrometheus().GetQuery(),
+				Threshold:         config.GetPrometheus().GetThreshold(),
+				PassOnEmptyResult: config.GetPrometheus().GetPassOnEmptyResult(),
+			}
+			if config.GetPrometheus().GetComparison() == update.PrometheusGateConfig_GREATER_THAN {
+				spec.Prometheus.Comparison = stateless.PrometheusComparison_PROMETHEUS_COMPARISON_GREATER_THAN
+			} else {
+				spec.Prometheus.Comparison = stateless.PrometheusComparison_PROMETHEUS_COMPARISON_LESS_THAN
+			}
+		}
+
+		result = append(result, spec)
+	}
+	return result
 }
 
 // ConvertCreateSpecToUpdateConfig converts create spec to update config
@@ -1533,6 +1533,114 @@ func (suite *apiConverterTestSuite) TestConvertUpdateSpecToUpdateConfig() {
 	suite.Equal(spec.GetMaxInstanceRetries(), config.GetMaxInstanceAttempts())
 	suite.Equal(spec.GetMaxTolerableInstanceFailures(), config.GetMaxFailureInstances())
 	suite.Equal(spec.GetStartPaused(), config.GetStartPaused())
+	suite.Nil(config.GetCanary())
+}
+
+// TestConvertCanarySpecToCanaryConfig tests the round trip conversion
+// between canary spec and canary config
+func (suite *apiConverterTestSuite) TestConvertCanarySpecToCanaryConfig() {
+	spec := &stateless.CanarySpec{
+		InstanceCount:                2,
+		InstancePercentage:           10,
+		BakePeriodSeconds:            300,
+		AutoPromote:                  true,
+		MaxTolerableInstanceFailures: 1,
+	}
+
+	config := ConvertUpdateSpecToUpdateConfig(&stateless.UpdateSpec{
+		BatchSize: 10,
+		Canary:    spec,
+	}).GetCanary()
+
+	suite.Equal(spec.GetInstanceCount(), config.GetInstanceCount())
+	suite.Equal(spec.GetInstancePercentage(), config.GetInstancePercentage())
+	suite.Equal(spec.GetBakePeriodSeconds(), config.GetBakePeriodSeconds())
+	suite.Equal(spec.GetAutoPromote(), config.GetAutoPromote())
+	suite.Equal(spec.GetMaxTolerableInstanceFailures(), config.GetMaxFailureInstances())
+	suite.Equal(spec, ConvertCanaryConfigToCanarySpec(config))
+}
+
+// TestConvertHealthGateSpecsToHealthGateConfigs tests the round trip
+// conversion between health gate specs and health gate configs
+func (suite *apiConverterTestSuite) TestConvertHealthGateSpecsToHealthGateConfigs() {
+	specs := []*stateless.HealthGateSpec{
+		{
+			Name:           "webhook",
+			OnFailure:      stateless.HealthGateFailureAction_HEALTH_GATE_FAILURE_ACTION_PAUSE,
+			TimeoutSeconds: 10,
+			Webhook: &stateless.WebhookGateSpec{
+				Url:     "http://localhost/check",
+				Headers: map[string]string{"X-Token": "secret"},
+			},
+		},
+		{
+			Name:      "error-rate",
+			OnFailure: stateless.HealthGateFailureAction_HEALTH_GATE_FAILURE_ACTION_ROLLBACK,
+			Prometheus: &stateless.PrometheusGateSpec{
+				Address:           "http://localhost:9090",
+				Query:             "job:errors:rate5m",
+				Threshold:         0.05,
+				Comparison:        stateless.PrometheusComparison_PROMETHEUS_COMPARISON_GREATER_THAN,
+				PassOnEmptyResult: true,
+			},
+		},
+	}
+
+	configs := ConvertUpdateSpecToUpdateConfig(&stateless.UpdateSpec{
+		BatchSize:   10,
+		HealthGates: specs,
+	}).GetHealthGates()
+
+	suite.Len(configs, 2)
+	suite.Equal(update.HealthGateConfig_PAUSE, configs[0].GetOnFailure())
+	suite.Equal("http://localhost/check", configs[0].GetWebhook().GetUrl())
+	suite.Equal(uint32(10), configs[0].GetTimeoutSeconds())
+	suite.Nil(configs[0].GetPrometheus())
+	suite.Equal(update.HealthGateConfig_ROLLBACK, configs[1].GetOnFailure())
+	suite.Equal(update.PrometheusGateConfig_GREATER_THAN,
+		configs[1].GetPrometheus().GetComparison())
+	suite.True(configs[1].GetPrometheus().GetPassOnEmptyResult())
+	suite.Nil(configs[1].GetWebhook())
+	suite.Equal(specs, ConvertHealthGateConfigsToHealthGateSpecs(configs))
+
+	// unset enums default to pause and less than
+	configs = ConvertHealthGateSpecsToHealthGateConfigs([]*stateless.HealthGateSpec{
+		{Prometheus: &stateless.PrometheusGateSpec{}},
+	})
+	suite.Equal(update.HealthGateConfig_PAUSE, configs[0].GetOnFailure())
+	suite.Equal(update.PrometheusGateConfig_LESS_THAN,
+		configs[0].GetPrometheus().GetComparison())
+	suite.Nil(ConvertHealthGateSpecsToHealthGateConfigs(nil))
+}
+
+// TestConvertAutoscalingSpecToAutoscalingConfig tests the conversion of
+// autoscaling policy between job spec and job config
+func (suite *apiConverterTestSuite) TestConvertAutoscalingSpecToAutoscalingConfig() {
+	spec := &stateless.AutoscalingSpec{
+		MinInstances:      2,
+		MaxInstances:      10,
+		Metric:            stateless.AutoscalingMetric_AUTOSCALING_METRIC_CPU,
+		TargetUtilization: 0.6,
+		CooldownSeconds:   300,
+		BatchSize:         2,
+	}
+
+	config, err := ConvertJobSpecToJobConfig(&stateless.JobSpec{
+		InstanceCount: 2,
+		Autoscaling:   spec,
+	})
+	suite.NoError(err)
+	suite.Equal(uint32(2), config.GetAutoscaling().GetMinInstances())
+	suite.Equal(uint32(10), config.GetAutoscaling().GetMaxInstances())
+	suite.Equal(job.AutoscalingConfig_CPU, config.GetAutoscaling().GetMetric())
+	suite.Equal(0.6, config.GetAutoscaling().GetTargetUtilization())
+	suite.Equal(uint32(300), config.GetAutoscaling().GetCooldownSeconds())
+	suite.Equal(uint32(2), config.GetAutoscaling().GetBatchSize())
+	suite.Equal(spec, ConvertJobConfigToJobSpec(config).GetAutoscaling())
+
+	// jobs without autoscaling policy are not converted to an empty policy
+	suite.Nil(ConvertAutoscalingSpecToAutoscalingConfig(nil))
+	suite.Nil(ConvertAutoscalingConfigToAutoscalingSpec(nil))
 }
 
 // TestConvertInstanceIDListToInstanceRange tests conversion from
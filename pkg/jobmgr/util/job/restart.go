@@ -0,0 +1,167 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+
+	"github.com/uber/peloton/pkg/common/util"
+	versionutil "github.com/uber/peloton/pkg/common/util/entityversion"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
+	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+	"github.com/uber/peloton/pkg/jobmgr/goalstate"
+	taskutil "github.com/uber/peloton/pkg/jobmgr/util/task"
+	"github.com/uber/peloton/pkg/storage"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/pkg/errors"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const _restartFailedMessage = "Restarted failed instance"
+
+// RestartFailedInstances restarts the FAILED and LOST instances of a
+// terminal batch job without touching its other instances, and moves
+// the job back to an active state. The instances are restarted with a new
+// run id and the last configuration version of the job. If entityVersion
+// is not nil, it has to match the current version of the job.
+// It returns the new job runtime and the instances restarted.
+func RestartFailedInstances(
+	ctx context.Context,
+	cachedJob cached.Job,
+	entityVersion *v1alphapeloton.EntityVersion,
+	taskStore storage.TaskStore,
+	activeJobsOps ormobjects.ActiveJobsOps,
+	goalStateDriver goalstate.Driver,
+) (*pbjob.RuntimeInfo, []uint32, error) {
+	jobID := cachedJob.ID()
+
+	jobConfig, err := cachedJob.GetConfig(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "fail to get job config")
+	}
+
+	if jobConfig.GetType() != pbjob.JobType_BATCH {
+		return nil, nil, yarpcerrors.InvalidArgumentErrorf(
+			"only failed instances of batch jobs can be restarted")
+	}
+
+	// load all the tasks of the job into cache, the job may have
+	// been untracked after it reached a terminal state
+	taskInfos, err := taskStore.GetTasksForJob(ctx, jobID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "fail to get tasks of the job")
+	}
+	if err := cachedJob.ReplaceTasks(taskInfos, false); err != nil {
+		return nil, nil, errors.Wrap(err, "fail to add tasks to cache")
+	}
+
+	configVersion := jobConfig.GetChangeLog().GetVersion()
+	runtimeDiffs := make(map[uint32]jobmgrcommon.RuntimeDiff)
+	for instanceID, taskInfo := range taskInfos {
+		state := taskInfo.GetRuntime().GetState()
+		if state != task.TaskState_FAILED && state != task.TaskState_LOST {
+			continue
+		}
+
+		runtimeDiff := taskutil.RegenerateMesosTaskIDDiff(
+			jobID,
+			instanceID,
+			taskInfo.GetRuntime(),
+			taskutil.GetInitialHealthState(taskInfo.GetConfig()))
+		runtimeDiff[jobmgrcommon.ConfigVersionField] = configVersion
+		runtimeDiff[jobmgrcommon.DesiredConfigVersionField] = configVersion
+		runtimeDiff[jobmgrcommon.GoalStateField] = task.TaskState_SUCCEEDED
+		runtimeDiff[jobmgrcommon.FailureCountField] = uint32(0)
+		runtimeDiff[jobmgrcommon.MessageField] = _restartFailedMessage
+		runtimeDiffs[instanceID] = runtimeDiff
+	}
+
+	if len(runtimeDiffs) == 0 {
+		return nil, nil, yarpcerrors.FailedPreconditionErrorf(
+			"job has no failed instances")
+	}
+
+	var jobRuntime *pbjob.RuntimeInfo
+	count := 0
+	for {
+		jobRuntime, err = cachedJob.GetRuntime(ctx)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "fail to get job runtime")
+		}
+
+		if entityVersion != nil &&
+			versionutil.GetJobEntityVersion(
+				jobRuntime.GetConfigurationVersion(),
+				jobRuntime.GetDesiredStateVersion(),
+				jobRuntime.GetWorkflowVersion(),
+			).GetValue() != entityVersion.GetValue() {
+			return nil, nil, jobmgrcommon.InvalidEntityVersionError
+		}
+
+		if !util.IsPelotonJobStateTerminal(jobRuntime.GetState()) {
+			return nil, nil, yarpcerrors.FailedPreconditionErrorf(
+				"job is not in a terminal state: %s", jobRuntime.GetState())
+		}
+
+		// add the job back to active jobs BEFORE moving it to an active
+		// state, the same as goal state engine does for terminal to
+		// active transitions
+		if err := activeJobsOps.Create(ctx, jobID); err != nil {
+			return nil, nil, errors.Wrap(err, "fail to add job to active jobs")
+		}
+
+		jobRuntime.State = pbjob.JobState_PENDING
+		jobRuntime.GoalState = pbjob.JobState_SUCCEEDED
+		jobRuntime.CompletionTime = ""
+		jobRuntime.DesiredStateVersion++
+
+		if jobRuntime, err = cachedJob.CompareAndSetRuntime(
+			ctx, jobRuntime); err != nil {
+			if err == jobmgrcommon.UnexpectedVersionError {
+				// concurrency error; retry MaxConcurrencyErrorRetry times
+				count = count + 1
+				if count < jobmgrcommon.MaxConcurrencyErrorRetry {
+					continue
+				}
+			}
+			// it is uncertain whether job runtime is updated successfully,
+			// let goal state engine figure it out.
+			goalStateDriver.EnqueueJob(jobID, time.Now())
+			return nil, nil, errors.Wrap(err, "fail to update job runtime")
+		}
+		break
+	}
+
+	instancesSucceeded, _, err := cachedJob.PatchTasks(ctx, runtimeDiffs, false)
+	for _, instanceID := range instancesSucceeded {
+		goalStateDriver.EnqueueTask(jobID, instanceID, time.Now())
+	}
+	goalStateDriver.EnqueueJob(jobID, time.Now())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "fail to patch failed tasks")
+	}
+
+	sort.Slice(instancesSucceeded, func(i, j int) bool {
+		return instancesSucceeded[i] < instancesSucceeded[j]
+	})
+	return jobRuntime, instancesSucceeded, nil
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+
+	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+
+	cachedmocks "github.com/uber/peloton/pkg/jobmgr/cached/mocks"
+	goalstatemocks "github.com/uber/peloton/pkg/jobmgr/goalstate/mocks"
+	storemocks "github.com/uber/peloton/pkg/storage/mocks"
+	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const _testJobID = "481d565e-28da-457d-8434-f6bb7faa0e95"
+
+func newTaskInfo(instanceID uint32, state task.TaskState) *task.TaskInfo {
+	mesosTaskID := fmt.Sprintf("%s-%d-1", _testJobID, instanceID)
+	return &task.TaskInfo{
+		InstanceId: instanceID,
+		Runtime: &task.RuntimeInfo{
+			State:       state,
+			MesosTaskId: &mesos.TaskID{Value: &mesosTaskID},
+		},
+	}
+}
+
+// TestRestartFailedInstances tests restarting the failed and lost
+// instances of a terminal batch job
+func TestRestartFailedInstances(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	jobID := &peloton.JobID{Value: _testJobID}
+	cachedJob := cachedmocks.NewMockJob(ctrl)
+	cachedConfig := cachedmocks.NewMockJobConfigCache(ctrl)
+	taskStore := storemocks.NewMockTaskStore(ctrl)
+	activeJobsOps := objectmocks.NewMockActiveJobsOps(ctrl)
+	goalStateDriver := goalstatemocks.NewMockDriver(ctrl)
+
+	taskInfos := map[uint32]*task.TaskInfo{
+		0: newTaskInfo(0, task.TaskState_SUCCEEDED),
+		1: newTaskInfo(1, task.TaskState_FAILED),
+		2: newTaskInfo(2, task.TaskState_LOST),
+	}
+
+	cachedJob.EXPECT().ID().Return(jobID).AnyTimes()
+	cachedJob.EXPECT().GetConfig(gomock.Any()).Return(cachedConfig, nil)
+	cachedConfig.EXPECT().GetType().Return(pbjob.JobType_BATCH)
+	cachedConfig.EXPECT().
+		GetChangeLog().
+		Return(&peloton.ChangeLog{Version: 3}).
+		AnyTimes()
+	taskStore.EXPECT().GetTasksForJob(gomock.Any(), jobID).Return(taskInfos, nil)
+	cachedJob.EXPECT().ReplaceTasks(taskInfos, false).Return(nil)
+	cachedJob.EXPECT().GetRuntime(gomock.Any()).Return(&pbjob.RuntimeInfo{
+		State:                pbjob.JobState_FAILED,
+		GoalState:            pbjob.JobState_SUCCEEDED,
+		ConfigurationVersion: 1,
+		DesiredStateVersion:  2,
+		WorkflowVersion:      1,
+	}, nil)
+	activeJobsOps.EXPECT().Create(gomock.Any(), jobID).Return(nil)
+	cachedJob.EXPECT().
+		CompareAndSetRuntime(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			runtime *pbjob.RuntimeInfo,
+		) (*pbjob.RuntimeInfo, error) {
+			assert.Equal(t, pbjob.JobState_PENDING, runtime.GetState())
+			assert.Equal(t, pbjob.JobState_SUCCEEDED, runtime.GetGoalState())
+			assert.Equal(t, uint64(3), runtime.GetDesiredStateVersion())
+			return runtime, nil
+		})
+	cachedJob.EXPECT().
+		PatchTasks(gomock.Any(), gomock.Any(), false).
+		DoAndReturn(func(
+			_ context.Context,
+			runtimeDiffs map[uint32]jobmgrcommon.RuntimeDiff,
+			_ bool,
+		) ([]uint32, []uint32, error) {
+			assert.Len(t, runtimeDiffs, 2)
+			assert.Equal(t, task.TaskState_INITIALIZED,
+				runtimeDiffs[1][jobmgrcommon.StateField])
+			assert.Equal(t, uint32(0),
+				runtimeDiffs[2][jobmgrcommon.FailureCountField])
+			assert.Equal(t, uint64(3),
+				runtimeDiffs[1][jobmgrcommon.ConfigVersionField])
+			assert.Equal(t, uint64(3),
+				runtimeDiffs[2][jobmgrcommon.DesiredConfigVersionField])
+			return []uint32{2, 1}, nil, nil
+		})
+	goalStateDriver.EXPECT().EnqueueTask(jobID, gomock.Any(), gomock.Any()).Times(2)
+	goalStateDriver.EXPECT().EnqueueJob(jobID, gomock.Any())
+
+	runtime, instanceIDs, err := RestartFailedInstances(
+		context.Background(),
+		cachedJob,
+		&v1alphapeloton.EntityVersion{Value: "1-2-1"},
+		taskStore,
+		activeJobsOps,
+		goalStateDriver,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1, 2}, instanceIDs)
+	assert.Equal(t, pbjob.JobState_PENDING, runtime.GetState())
+}
+
+// TestRestartFailedInstancesNoFailedInstance tests restarting a
+// batch job without failed instances
+func TestRestartFailedInstancesNoFailedInstance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	jobID := &peloton.JobID{Value: _testJobID}
+	cachedJob := cachedmocks.NewMockJob(ctrl)
+	cachedConfig := cachedmocks.NewMockJobConfigCache(ctrl)
+	taskStore := storemocks.NewMockTaskStore(ctrl)
+
+	taskInfos := map[uint32]*task.TaskInfo{
+		0: newTaskInfo(0, task.TaskState_SUCCEEDED),
+	}
+
+	cachedJob.EXPECT().ID().Return(jobID).AnyTimes()
+	cachedJob.EXPECT().GetConfig(gomock.Any()).Return(cachedConfig, nil)
+	cachedConfig.EXPECT().GetType().Return(pbjob.JobType_BATCH)
+	cachedConfig.EXPECT().
+		GetChangeLog().
+		Return(&peloton.ChangeLog{Version: 3}).
+		AnyTimes()
+	taskStore.EXPECT().GetTasksForJob(gomock.Any(), jobID).Return(taskInfos, nil)
+	cachedJob.EXPECT().ReplaceTasks(taskInfos, false).Return(nil)
+
+	_, _, err := RestartFailedInstances(
+		context.Background(),
+		cachedJob,
+		nil,
+		taskStore,
+		nil,
+		nil,
+	)
+	assert.True(t, yarpcerrors.IsFailedPrecondition(err))
+}
+
+// TestRestartFailedInstancesNonTerminalJob tests restarting the failed
+// instances of a batch job which is still running
+func TestRestartFailedInstancesNonTerminalJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	jobID := &peloton.JobID{Value: _testJobID}
+	cachedJob := cachedmocks.NewMockJob(ctrl)
+	cachedConfig := cachedmocks.NewMockJobConfigCache(ctrl)
+	taskStore := storemocks.NewMockTaskStore(ctrl)
+
+	taskInfos := map[uint32]*task.TaskInfo{
+		0: newTaskInfo(0, task.TaskState_FAILED),
+	}
+
+	cachedJob.EXPECT().ID().Return(jobID).AnyTimes()
+	cachedJob.EXPECT().GetConfig(gomock.Any()).Return(cachedConfig, nil)
+	cachedConfig.EXPECT().GetType().Return(pbjob.JobType_BATCH)
+	cachedConfig.EXPECT().
+		GetChangeLog().
+		Return(&peloton.ChangeLog{Version: 3}).
+		AnyTimes()
+	taskStore.EXPECT().GetTasksForJob(gomock.Any(), jobID).Return(taskInfos, nil)
+	cachedJob.EXPECT().ReplaceTasks(taskInfos, false).Return(nil)
+	cachedJob.EXPECT().GetRuntime(gomock.Any()).Return(&pbjob.RuntimeInfo{
+		State: pbjob.JobState_RUNNING,
+	}, nil)
+
+	_, _, err := RestartFailedInstances(
+		context.Background(),
+		cachedJob,
+		nil,
+		taskStore,
+		nil,
+		nil,
+	)
+	assert.True(t, yarpcerrors.IsFailedPrecondition(err))
+}
+
+// TestRestartFailedInstancesNotBatchJob tests restarting the failed
+// instances of a stateless job
+func TestRestartFailedInstancesNotBatchJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cachedJob := cachedmocks.NewMockJob(ctrl)
+	cachedConfig := cachedmocks.NewMockJobConfigCache(ctrl)
+
+	cachedJob.EXPECT().ID().Return(&peloton.JobID{Value: _testJobID}).AnyTimes()
+	cachedJob.EXPECT().GetConfig(gomock.Any()).Return(cachedConfig, nil)
+	cachedConfig.EXPECT().GetType().Return(pbjob.JobType_SERVICE)
+
+	_, _, err := RestartFailedInstances(
+		context.Background(),
+		cachedJob,
+		nil,
+		nil,
+		nil,
+		nil,
+	)
+	assert.True(t, yarpcerrors.IsInvalidArgument(err))
+}
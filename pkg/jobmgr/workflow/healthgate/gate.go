@@ -0,0 +1,91 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthgate
+
+import (
+	"context"
+	"net/http"
+
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// UpdateHealthGate is consulted by the update goal state between
+// batches of an update, to decide if the update may continue
+// to the next batch of instances.
+type UpdateHealthGate interface {
+	// Name returns the name of the health gate
+	Name() string
+
+	// Check evaluates the health gate for the given update progress.
+	// It returns an error describing why the gate failed if the update
+	// must not continue, including when the gate cannot be evaluated.
+	Check(ctx context.Context, req *CheckRequest) error
+}
+
+// Factory creates health gates from their configuration
+type Factory interface {
+	// Create returns the health gate described by the configuration
+	Create(config *pbupdate.HealthGateConfig) (UpdateHealthGate, error)
+}
+
+// CheckRequest describes the progress of the update
+// for which a health gate is evaluated.
+type CheckRequest struct {
+	// JobID is the identifier of the job being updated
+	JobID string `json:"job_id"`
+	// UpdateID is the identifier of the update
+	UpdateID string `json:"update_id"`
+	// JobVersion is the job configuration version the update moves to
+	JobVersion uint64 `json:"job_version"`
+	// InstancesTotal is the number of instances in the update
+	InstancesTotal uint32 `json:"instances_total"`
+	// InstancesDone are the instances updated successfully so far
+	InstancesDone []uint32 `json:"instances_done"`
+	// InstancesFailed are the instances which failed to update so far
+	InstancesFailed []uint32 `json:"instances_failed"`
+}
+
+// factory is the default Factory, which creates the
+// built-in webhook and Prometheus health gates.
+type factory struct {
+	client *http.Client
+}
+
+// NewFactory returns a Factory which creates the built-in health gates.
+// All gates send their requests using the given http client.
+func NewFactory(client *http.Client) Factory {
+	return &factory{client: client}
+}
+
+// Create returns the health gate described by the configuration
+func (f *factory) Create(
+	config *pbupdate.HealthGateConfig,
+) (UpdateHealthGate, error) {
+	switch {
+	case config.GetWebhook() != nil && config.GetPrometheus() != nil:
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"health gate %s has both webhook and prometheus configured",
+			config.GetName())
+	case config.GetWebhook() != nil:
+		return newWebhookGate(config.GetName(), config.GetWebhook(), f.client)
+	case config.GetPrometheus() != nil:
+		return newPrometheusGate(config.GetName(), config.GetPrometheus(), f.client)
+	}
+	return nil, yarpcerrors.InvalidArgumentErrorf(
+		"health gate %s has neither webhook nor prometheus configured",
+		config.GetName())
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthgate
+
+import (
+	"net/http"
+	"testing"
+
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FactoryTestSuite struct {
+	suite.Suite
+
+	factory Factory
+}
+
+func (suite *FactoryTestSuite) SetupTest() {
+	suite.factory = NewFactory(&http.Client{})
+}
+
+func TestFactory(t *testing.T) {
+	suite.Run(t, new(FactoryTestSuite))
+}
+
+// TestCreateWebhookGate tests creating a webhook health gate
+func (suite *FactoryTestSuite) TestCreateWebhookGate() {
+	gate, err := suite.factory.Create(&pbupdate.HealthGateConfig{
+		Name: "webhook",
+		Webhook: &pbupdate.WebhookGateConfig{
+			Url: "http://localhost/check",
+		},
+	})
+	suite.NoError(err)
+	suite.Equal("webhook", gate.Name())
+	suite.IsType(&webhookGate{}, gate)
+}
+
+// TestCreatePrometheusGate tests creating a Prometheus health gate
+func (suite *FactoryTestSuite) TestCreatePrometheusGate() {
+	gate, err := suite.factory.Create(&pbupdate.HealthGateConfig{
+		Name: "error-rate",
+		Prometheus: &pbupdate.PrometheusGateConfig{
+			Address: "http://localhost:9090",
+			Query:   `job:errors:rate5m{job_id="{{.JobID}}"}`,
+		},
+	})
+	suite.NoError(err)
+	suite.Equal("error-rate", gate.Name())
+	suite.IsType(&prometheusGate{}, gate)
+}
+
+// TestCreateInvalidGates tests creating health gates
+// with invalid configuration
+func (suite *FactoryTestSuite) TestCreateInvalidGates() {
+	configs := []*pbupdate.HealthGateConfig{
+		{Name: "empty"},
+		{
+			Name:       "both",
+			Webhook:    &pbupdate.WebhookGateConfig{Url: "http://localhost"},
+			Prometheus: &pbupdate.PrometheusGateConfig{Address: "http://localhost"},
+		},
+		{
+			Name:    "no-url",
+			Webhook: &pbupdate.WebhookGateConfig{},
+		},
+		{
+			Name:       "no-address",
+			Prometheus: &pbupdate.PrometheusGateConfig{Query: "up"},
+		},
+		{
+			Name: "bad-query",
+			Prometheus: &pbupdate.PrometheusGateConfig{
+				Address: "http://localhost",
+				Query:   "{{.JobID",
+			},
+		},
+	}
+
+	for _, config := range configs {
+		_, err := suite.factory.Create(config)
+		suite.Error(err, config.GetName())
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthgate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// path of the Prometheus instant query API
+const _prometheusQueryPath = "/api/v1/query"
+
+// prometheusGate is a health gate evaluated by running an instant
+// query against a Prometheus server. The gate passes if every sample
+// returned by the query satisfies the comparison against the threshold.
+// A query which returns no samples fails the gate, unless the gate is
+// configured to pass on an empty result, since a misspelled metric or
+// label would otherwise pass every check.
+type prometheusGate struct {
+	name              string
+	address           string
+	query             *template.Template
+	threshold         float64
+	comparison        pbupdate.PrometheusGateConfig_Comparison
+	passOnEmptyResult bool
+	client            *http.Client
+}
+
+// prometheusResponse is the response of the Prometheus query API
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// prometheusSample is a single sample of a vector result
+type prometheusSample struct {
+	Value []interface{} `json:"value"`
+}
+
+func newPrometheusGate(
+	name string,
+	config *pbupdate.PrometheusGateConfig,
+	client *http.Client,
+) (UpdateHealthGate, error) {
+	if len(config.GetAddress()) == 0 {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"health gate %s has no prometheus address", name)
+	}
+
+	query, err := template.New(name).
+		Option("missingkey=error").
+		Parse(config.GetQuery())
+	if err != nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"health gate %s has invalid prometheus query: %v", name, err)
+	}
+
+	return &prometheusGate{
+		name:              name,
+		address:           strings.TrimSuffix(config.GetAddress(), "/"),
+		query:             query,
+		threshold:         config.GetThreshold(),
+		comparison:        config.GetComparison(),
+		passOnEmptyResult: config.GetPassOnEmptyResult(),
+		client:            client,
+	}, nil
+}
+
+// Name returns the name of the health gate
+func (g *prometheusGate) Name() string {
+	return g.name
+}
+
+// Check runs the query and compares the samples against the threshold
+func (g *prometheusGate) Check(ctx context.Context, req *CheckRequest) error {
+	var query bytes.Buffer
+	if err := g.query.Execute(&query, req); err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(
+		http.MethodGet,
+		g.address+_prometheusQueryPath+"?"+
+			url.Values{"query": {query.String()}}.Encode(),
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	resp, err := g.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode prometheus response with status %d: %v",
+			resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return fmt.Errorf("prometheus query failed: %s", result.Error)
+	}
+
+	values, err := parsePrometheusResult(
+		result.Data.ResultType,
+		result.Data.Result,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 0 && !g.passOnEmptyResult {
+		return fmt.Errorf("query %s returned no samples", query.String())
+	}
+
+	for _, value := range values {
+		if !g.compare(value) {
+			return fmt.Errorf("query %s returned %v, want %s %v",
+				query.String(), value, g.comparisonString(), g.threshold)
+		}
+	}
+	return nil
+}
+
+// compare returns true if the value satisfies
+// the comparison against the threshold
+func (g *prometheusGate) compare(value float64) bool {
+	if g.comparison == pbupdate.PrometheusGateConfig_GREATER_THAN {
+		return value > g.threshold
+	}
+	return value < g.threshold
+}
+
+func (g *prometheusGate) comparisonString() string {
+	if g.comparison == pbupdate.PrometheusGateConfig_GREATER_THAN {
+		return ">"
+	}
+	return "<"
+}
+
+// parsePrometheusResult returns the sample values of a
+// vector or scalar query result
+func parsePrometheusResult(
+	resultType string,
+	result json.RawMessage,
+) ([]float64, error) {
+	switch resultType {
+	case "vector":
+		var samples []prometheusSample
+		if err := json.Unmarshal(result, &samples); err != nil {
+			return nil, err
+		}
+		values := make([]float64, 0, len(samples))
+		for _, sample := range samples {
+			value, err := parsePrometheusValue(sample.Value)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case "scalar":
+		var sample []interface{}
+		if err := json.Unmarshal(result, &sample); err != nil {
+			return nil, err
+		}
+		value, err := parsePrometheusValue(sample)
+		if err != nil {
+			return nil, err
+		}
+		return []float64{value}, nil
+	}
+	return nil, fmt.Errorf("unsupported prometheus result type %q", resultType)
+}
+
+// parsePrometheusValue parses a [timestamp, "value"] sample pair
+func parsePrometheusValue(sample []interface{}) (float64, error) {
+	if len(sample) != 2 {
+		return 0, fmt.Errorf("malformed prometheus sample %v", sample)
+	}
+	value, ok := sample[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("malformed prometheus sample value %v", sample[1])
+	}
+	return strconv.ParseFloat(value, 64)
+}
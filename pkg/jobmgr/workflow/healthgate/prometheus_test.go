@@ -0,0 +1,166 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthgate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	_errorRateQuery    = `job:errors:rate5m{job_id="{{.JobID}}",update_id="{{.UpdateID}}"}`
+	_errorRateExpanded = `job:errors:rate5m{job_id="job",update_id="update"}`
+)
+
+type PrometheusGateTestSuite struct {
+	suite.Suite
+
+	req *CheckRequest
+
+	// query received by the stub server, and the response it returns
+	query    string
+	response string
+	server   *httptest.Server
+}
+
+func (suite *PrometheusGateTestSuite) SetupTest() {
+	suite.req = &CheckRequest{
+		JobID:    "job",
+		UpdateID: "update",
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			suite.Equal(_prometheusQueryPath, r.URL.Path)
+			suite.query = r.URL.Query().Get("query")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(suite.response))
+		}))
+}
+
+func (suite *PrometheusGateTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func TestPrometheusGate(t *testing.T) {
+	suite.Run(t, new(PrometheusGateTestSuite))
+}
+
+func (suite *PrometheusGateTestSuite) newGate(
+	comparison pbupdate.PrometheusGateConfig_Comparison,
+) UpdateHealthGate {
+	gate, err := newPrometheusGate(
+		"error-rate",
+		&pbupdate.PrometheusGateConfig{
+			Address:    suite.server.URL + "/",
+			Query:      _errorRateQuery,
+			Threshold:  0.05,
+			Comparison: comparison,
+		},
+		&http.Client{},
+	)
+	suite.NoError(err)
+	return gate
+}
+
+// TestCheckVectorPass tests that the gate passes when every
+// sample of a vector result satisfies the threshold
+func (suite *PrometheusGateTestSuite) TestCheckVectorPass() {
+	suite.response = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"instance":"0"},"value":[1571400000.1,"0.01"]},
+		{"metric":{"instance":"1"},"value":[1571400000.1,"0.02"]}]}}`
+
+	gate := suite.newGate(pbupdate.PrometheusGateConfig_LESS_THAN)
+	suite.NoError(gate.Check(context.Background(), suite.req))
+	suite.Equal(_errorRateExpanded, suite.query)
+}
+
+// TestCheckVectorFail tests that the gate fails when a
+// sample of a vector result breaches the threshold
+func (suite *PrometheusGateTestSuite) TestCheckVectorFail() {
+	suite.response = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"instance":"0"},"value":[1571400000.1,"0.01"]},
+		{"metric":{"instance":"1"},"value":[1571400000.1,"0.2"]}]}}`
+
+	gate := suite.newGate(pbupdate.PrometheusGateConfig_LESS_THAN)
+	suite.EqualError(
+		gate.Check(context.Background(), suite.req),
+		"query "+_errorRateExpanded+" returned 0.2, want < 0.05",
+	)
+}
+
+// TestCheckScalarGreaterThan tests a scalar result
+// compared with greater than
+func (suite *PrometheusGateTestSuite) TestCheckScalarGreaterThan() {
+	gate := suite.newGate(pbupdate.PrometheusGateConfig_GREATER_THAN)
+
+	suite.response = `{"status":"success","data":{"resultType":"scalar","result":[1571400000.1,"0.5"]}}`
+	suite.NoError(gate.Check(context.Background(), suite.req))
+
+	suite.response = `{"status":"success","data":{"resultType":"scalar","result":[1571400000.1,"0.01"]}}`
+	suite.Error(gate.Check(context.Background(), suite.req))
+}
+
+// TestCheckEmptyResult tests that a query without samples fails
+// the gate, unless the gate is set to pass on an empty result
+func (suite *PrometheusGateTestSuite) TestCheckEmptyResult() {
+	suite.response = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+
+	gate := suite.newGate(pbupdate.PrometheusGateConfig_LESS_THAN)
+	suite.EqualError(
+		gate.Check(context.Background(), suite.req),
+		"query "+_errorRateExpanded+" returned no samples",
+	)
+
+	gate, err := newPrometheusGate(
+		"error-rate",
+		&pbupdate.PrometheusGateConfig{
+			Address:           suite.server.URL,
+			Query:             _errorRateQuery,
+			Threshold:         0.05,
+			PassOnEmptyResult: true,
+		},
+		&http.Client{},
+	)
+	suite.NoError(err)
+	suite.NoError(gate.Check(context.Background(), suite.req))
+}
+
+// TestCheckQueryError tests that the gate fails
+// when the query cannot be evaluated
+func (suite *PrometheusGateTestSuite) TestCheckQueryError() {
+	gate := suite.newGate(pbupdate.PrometheusGateConfig_LESS_THAN)
+
+	suite.response = `{"status":"error","errorType":"bad_data","error":"parse error"}`
+	suite.EqualError(
+		gate.Check(context.Background(), suite.req),
+		"prometheus query failed: parse error",
+	)
+
+	suite.response = `{"status":"success","data":{"resultType":"matrix","result":[]}}`
+	suite.Error(gate.Check(context.Background(), suite.req))
+
+	suite.response = `not json`
+	suite.Error(gate.Check(context.Background(), suite.req))
+
+	suite.response = `{"status":"success","data":{"resultType":"scalar","result":[1571400000.1,"NaN?"]}}`
+	suite.Error(gate.Check(context.Background(), suite.req))
+}
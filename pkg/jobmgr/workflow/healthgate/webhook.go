@@ -0,0 +1,97 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthgate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// maximum number of bytes of the webhook response included in the error
+const _maxWebhookResponseBytes = 512
+
+// webhookGate is a health gate evaluated by POSTing the update
+// progress to a webhook. The gate passes if the webhook
+// responds with a 2xx status code.
+type webhookGate struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newWebhookGate(
+	name string,
+	config *pbupdate.WebhookGateConfig,
+	client *http.Client,
+) (UpdateHealthGate, error) {
+	if len(config.GetUrl()) == 0 {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"health gate %s has no webhook url", name)
+	}
+
+	return &webhookGate{
+		name:    name,
+		url:     config.GetUrl(),
+		headers: config.GetHeaders(),
+		client:  client,
+	}, nil
+}
+
+// Name returns the name of the health gate
+func (g *webhookGate) Name() string {
+	return g.name
+}
+
+// Check calls the webhook with the update progress
+func (g *webhookGate) Check(ctx context.Context, req *CheckRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range g.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := g.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusOK &&
+		resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, _maxWebhookResponseBytes))
+	return fmt.Errorf("webhook responded with status %d: %s",
+		resp.StatusCode, bytes.TrimSpace(msg))
+}
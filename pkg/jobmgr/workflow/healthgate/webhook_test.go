@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthgate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pbupdate "github.com/uber/peloton/.gen/peloton/api/v0/update"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhookGateTestSuite struct {
+	suite.Suite
+
+	req *CheckRequest
+}
+
+func (suite *WebhookGateTestSuite) SetupTest() {
+	suite.req = &CheckRequest{
+		JobID:           "job",
+		UpdateID:        "update",
+		JobVersion:      3,
+		InstancesTotal:  5,
+		InstancesDone:   []uint32{0, 1},
+		InstancesFailed: []uint32{2},
+	}
+}
+
+func TestWebhookGate(t *testing.T) {
+	suite.Run(t, new(WebhookGateTestSuite))
+}
+
+func (suite *WebhookGateTestSuite) newGate(url string) UpdateHealthGate {
+	gate, err := newWebhookGate(
+		"webhook",
+		&pbupdate.WebhookGateConfig{
+			Url:     url,
+			Headers: map[string]string{"X-Token": "secret"},
+		},
+		&http.Client{},
+	)
+	suite.NoError(err)
+	return gate
+}
+
+// TestCheckPass tests that the gate passes when
+// the webhook responds with a 2xx status code
+func (suite *WebhookGateTestSuite) TestCheckPass() {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			suite.Equal(http.MethodPost, r.Method)
+			suite.Equal("secret", r.Header.Get("X-Token"))
+			suite.Equal("application/json", r.Header.Get("Content-Type"))
+
+			var req CheckRequest
+			suite.NoError(json.NewDecoder(r.Body).Decode(&req))
+			suite.Equal(*suite.req, req)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	defer server.Close()
+
+	suite.NoError(suite.newGate(server.URL).Check(context.Background(), suite.req))
+}
+
+// TestCheckFail tests that the gate fails with the webhook response
+// when the webhook responds with a non 2xx status code
+func (suite *WebhookGateTestSuite) TestCheckFail() {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("error budget exhausted\n"))
+		}))
+	defer server.Close()
+
+	err := suite.newGate(server.URL).Check(context.Background(), suite.req)
+	suite.EqualError(err,
+		"webhook responded with status 503: error budget exhausted")
+}
+
+// TestCheckUnreachable tests that the gate fails
+// when the webhook cannot be reached
+func (suite *WebhookGateTestSuite) TestCheckUnreachable() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	suite.Error(suite.newGate(url).Check(context.Background(), suite.req))
+}
@@ -105,6 +105,7 @@ func (u *WorkflowProgressCheck) Check() {
 
 		lastUpdateTime := cachedWorkflow.GetLastUpdateTime()
 		if time.Now().Sub(lastUpdateTime) > u.Config.StaleWorkflowThreshold &&
+			!isWorkflowWaitingForPromotion(cachedWorkflow) &&
 			!isWorkflowStaleDueToTaskThrottling(ctx, cachedJob, cachedWorkflow) {
 			log.WithFields(log.Fields{
 				"job_id":           cachedJob.ID().GetValue(),
@@ -124,6 +125,15 @@ func (u *WorkflowProgressCheck) Check() {
 	u.Metrics.GetJobRuntimeFailure.Update(float64(getJobRuntimeFailure))
 }
 
+// a workflow whose canary phase has completed but has not been
+// promoted does not make progress until it is promoted explicitly,
+// so it should not be considered as stale.
+func isWorkflowWaitingForPromotion(cachedWorkflow cached.Update) bool {
+	return cached.HasCanary(cachedWorkflow.GetUpdateConfig()) &&
+		!cachedWorkflow.GetCanaryCompletionTime().IsZero() &&
+		!cachedWorkflow.IsCanaryPromoted()
+}
+
 // when a task fails, it would be restarted but is subject
 // to throttling. As a result, the workflow may not
 // see progress updated within the StaleWorkflowThreshold.
@@ -95,6 +95,7 @@ func (s *ProgressCheckerTestSuite) TestCheckProgressSuccess() {
 		Return(workflow2)
 	workflow2.EXPECT().GetState().Return(&cached.UpdateStateVector{State: update.State_ROLLING_FORWARD})
 	workflow2.EXPECT().GetLastUpdateTime().Return(time.Now().Add(-100 * time.Hour))
+	workflow2.EXPECT().GetUpdateConfig().Return(&update.UpdateConfig{})
 	workflow2.EXPECT().GetWorkflowType().Return(models.WorkflowType_UPDATE)
 	workflow2.EXPECT().GetInstancesCurrent().Return([]uint32{0})
 	job2.EXPECT().ID().Return(&peloton.JobID{Value: "job2"})
@@ -110,6 +111,7 @@ func (s *ProgressCheckerTestSuite) TestCheckProgressSuccess() {
 		Return(workflow3)
 	workflow3.EXPECT().GetState().Return(&cached.UpdateStateVector{State: update.State_ROLLING_FORWARD})
 	workflow3.EXPECT().GetLastUpdateTime().Return(time.Now().Add(-100 * time.Hour))
+	workflow3.EXPECT().GetUpdateConfig().Return(&update.UpdateConfig{})
 	workflow3.EXPECT().GetInstancesCurrent().Return([]uint32{0})
 	job3.EXPECT().GetTask(uint32(0)).Return(task1)
 	task1.EXPECT().
@@ -124,6 +126,42 @@ func (s *ProgressCheckerTestSuite) TestCheckProgressSuccess() {
 	s.Equal(int(gauges["workflow_progress.workflow.total_workflow+workflow_type=stale"].Value()), 1)
 }
 
+// TestCheckWorkflowWaitingForPromotion tests that a workflow whose
+// canary phase is waiting for promotion is not considered as stale
+func (s *ProgressCheckerTestSuite) TestCheckWorkflowWaitingForPromotion() {
+	job1 := cachemock.NewMockJob(s.mockCtrl)
+	workflow1 := cachemock.NewMockUpdate(s.mockCtrl)
+
+	s.jobFactory.EXPECT().
+		GetAllJobs().
+		Return(map[string]cached.Job{
+			"job1": job1,
+		})
+
+	job1.EXPECT().GetJobType().Return(pbjob.JobType_SERVICE)
+	job1.EXPECT().
+		GetRuntime(gomock.Any()).
+		Return(&pbjob.RuntimeInfo{
+			UpdateID: &peloton.UpdateID{Value: "update1"},
+		}, nil)
+	job1.EXPECT().
+		GetWorkflow(gomock.Any()).
+		Return(workflow1)
+	workflow1.EXPECT().GetState().Return(&cached.UpdateStateVector{State: update.State_ROLLING_FORWARD})
+	workflow1.EXPECT().GetLastUpdateTime().Return(time.Now().Add(-100 * time.Hour))
+	workflow1.EXPECT().GetUpdateConfig().Return(&update.UpdateConfig{
+		Canary: &update.CanaryConfig{InstanceCount: 1},
+	})
+	workflow1.EXPECT().GetCanaryCompletionTime().Return(time.Now().Add(-100 * time.Hour))
+	workflow1.EXPECT().IsCanaryPromoted().Return(false)
+
+	s.checker.Check()
+
+	gauges := s.testScope.Snapshot().Gauges()
+	s.Equal(int(gauges["workflow_progress.workflow.total_workflow+workflow_type=active"].Value()), 1)
+	s.Equal(int(gauges["workflow_progress.workflow.total_workflow+workflow_type=stale"].Value()), 0)
+}
+
 // mockCtrl tests progress check would skip job that fail to get runtime
 // and continue to check progress on the remaining workflows
 func (s *ProgressCheckerTestSuite) TestGetJobRuntimeFailure() {
@@ -158,6 +196,7 @@ func (s *ProgressCheckerTestSuite) TestGetJobRuntimeFailure() {
 		Return(workflow2)
 	workflow2.EXPECT().GetState().Return(&cached.UpdateStateVector{State: update.State_ROLLING_FORWARD})
 	workflow2.EXPECT().GetLastUpdateTime().Return(time.Now().Add(-100 * time.Hour))
+	workflow2.EXPECT().GetUpdateConfig().Return(&update.UpdateConfig{})
 	workflow2.EXPECT().GetWorkflowType().Return(models.WorkflowType_UPDATE)
 	workflow2.EXPECT().GetInstancesCurrent().Return([]uint32{0})
 	job2.EXPECT().GetTask(uint32(0)).Return(task0)
@@ -0,0 +1,89 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inbound
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/audit"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// AuditInboundMiddleware is the inbound middleware which records every
+// call to a peloton service in the audit trail, with the user making
+// the call and its result.
+type AuditInboundMiddleware struct {
+	trail audit.Trail
+}
+
+// Handle invokes the underlying handler and audits the call
+func (m *AuditInboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	start := time.Now()
+	err := h.Handle(ctx, req, resw)
+	m.record(req.Headers, req.Service, req.Procedure, req.Caller, start, err)
+	return err
+}
+
+// HandleOneway invokes the underlying handler and audits the call
+func (m *AuditInboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	start := time.Now()
+	err := h.HandleOneway(ctx, req)
+	m.record(req.Headers, req.Service, req.Procedure, req.Caller, start, err)
+	return err
+}
+
+// HandleStream invokes the underlying handler and audits the stream
+// once it is done
+func (m *AuditInboundMiddleware) HandleStream(s *transport.ServerStream, h transport.StreamHandler) error {
+	start := time.Now()
+	err := h.HandleStream(s)
+	meta := s.Request().Meta
+	m.record(meta.Headers, meta.Service, meta.Procedure, meta.Caller, start, err)
+	return err
+}
+
+func (m *AuditInboundMiddleware) record(
+	headers transport.Headers,
+	service string,
+	procedure string,
+	caller string,
+	start time.Time,
+	err error) {
+	// only calls to peloton services are audited, see
+	// AuthInboundMiddleware
+	if !strings.HasPrefix(service, _pelotonServicePrefix) {
+		return
+	}
+
+	m.trail.Record(&audit.Event{
+		User:      audit.UserFromHeaders(headers.Items()),
+		Procedure: procedure,
+		Details: map[string]interface{}{
+			"service":  service,
+			"caller":   caller,
+			"duration": time.Since(start),
+		},
+		Error: err,
+	})
+}
+
+// NewAuditInboundMiddleware returns AuditInboundMiddleware recording
+// the calls in the given audit trail
+func NewAuditInboundMiddleware(trail audit.Trail) *AuditInboundMiddleware {
+	return &AuditInboundMiddleware{trail: trail}
+}
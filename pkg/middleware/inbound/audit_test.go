@@ -0,0 +1,122 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inbound
+
+import (
+	"context"
+	"testing"
+
+	"github.com/uber/peloton/pkg/common/audit"
+	auditmocks "github.com/uber/peloton/pkg/common/audit/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type AuditInboundMiddlewareSuite struct {
+	suite.Suite
+
+	ctrl  *gomock.Controller
+	trail *auditmocks.MockTrail
+	m     *AuditInboundMiddleware
+	r     *transport.Request
+}
+
+func (suite *AuditInboundMiddlewareSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.trail = auditmocks.NewMockTrail(suite.ctrl)
+	suite.m = NewAuditInboundMiddleware(suite.trail)
+	suite.r = &transport.Request{
+		Service:   _testService,
+		Procedure: _testProcedure,
+		Caller:    "peloton-cli",
+		Headers: transport.HeadersFromMap(map[string]string{
+			audit.AuthenticatedUserHeader: "peloton",
+		}),
+	}
+}
+
+// expectRecord expects a call to be recorded in the audit trail
+// with the given error
+func (suite *AuditInboundMiddlewareSuite) expectRecord(err error) {
+	suite.trail.EXPECT().
+		Record(gomock.Any()).
+		Do(func(event *audit.Event) {
+			suite.Equal("peloton", event.User)
+			suite.Equal(_testProcedure, event.Procedure)
+			suite.Equal(_testService, event.Details["service"])
+			suite.Equal(err, event.Error)
+		})
+}
+
+func (suite *AuditInboundMiddlewareSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func (suite *AuditInboundMiddlewareSuite) TestHandle() {
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+
+	h.EXPECT().Handle(gomock.Any(), suite.r, gomock.Any()).Return(nil)
+	suite.expectRecord(nil)
+	suite.NoError(suite.m.Handle(context.Background(), suite.r, nil, h))
+
+	internalError := yarpcerrors.InternalErrorf("test error")
+	h.EXPECT().Handle(gomock.Any(), suite.r, gomock.Any()).Return(internalError)
+	suite.expectRecord(internalError)
+	suite.Equal(internalError, suite.m.Handle(context.Background(), suite.r, nil, h))
+}
+
+func (suite *AuditInboundMiddlewareSuite) TestHandleNonPelotonService() {
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	suite.r.Service = "Scheduler"
+
+	h.EXPECT().Handle(gomock.Any(), suite.r, gomock.Any()).Return(nil)
+	suite.NoError(suite.m.Handle(context.Background(), suite.r, nil, h))
+}
+
+func (suite *AuditInboundMiddlewareSuite) TestHandleOneway() {
+	h := transporttest.NewMockOnewayHandler(suite.ctrl)
+
+	internalError := yarpcerrors.InternalErrorf("test error")
+	h.EXPECT().HandleOneway(gomock.Any(), suite.r).Return(internalError)
+	suite.expectRecord(internalError)
+	suite.Equal(internalError, suite.m.HandleOneway(context.Background(), suite.r, h))
+}
+
+func (suite *AuditInboundMiddlewareSuite) TestHandleStream() {
+	h := transporttest.NewMockStreamHandler(suite.ctrl)
+	s := transporttest.NewMockStream(suite.ctrl)
+	ss, err := transport.NewServerStream(s)
+	suite.NoError(err)
+
+	s.EXPECT().Request().Return(
+		&transport.StreamRequest{
+			Meta: &transport.RequestMeta{
+				Procedure: _testProcedure,
+				Service:   _testService,
+				Headers:   suite.r.Headers,
+			}},
+	)
+	h.EXPECT().HandleStream(ss).Return(nil)
+	suite.expectRecord(nil)
+	suite.NoError(suite.m.HandleStream(ss, h))
+}
+
+func TestAuditInboundMiddlewareSuite(t *testing.T) {
+	suite.Run(t, &AuditInboundMiddlewareSuite{})
+}
@@ -19,6 +19,8 @@ import (
 	"strings"
 
 	"github.com/uber/peloton/pkg/auth"
+	"github.com/uber/peloton/pkg/common/audit"
+
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/yarpcerrors"
 
@@ -40,7 +42,7 @@ type AuthInboundMiddleware struct {
 
 // Handle authenticates user and invokes the underlying handler
 func (m *AuthInboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
-	permitted, err := m.isPermitted(req.Headers, req.Service, req.Procedure, req.Caller)
+	user, permitted, err := m.isPermitted(req.Headers, req.Service, req.Procedure, req.Caller)
 	if err != nil {
 		return err
 	}
@@ -49,12 +51,14 @@ func (m *AuthInboundMiddleware) Handle(ctx context.Context, req *transport.Reque
 		return yarpcerrors.PermissionDeniedErrorf(permissionDeniedErrorStr, req.Procedure, req.Service)
 	}
 
+	req.Headers = withAuthenticatedUser(req.Headers, user)
+
 	return h.Handle(ctx, req, resw)
 }
 
 // HandleOneway authenticates user and invokes the underlying handler
 func (m *AuthInboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
-	permitted, err := m.isPermitted(req.Headers, req.Service, req.Procedure, req.Caller)
+	user, permitted, err := m.isPermitted(req.Headers, req.Service, req.Procedure, req.Caller)
 	if err != nil {
 		return err
 	}
@@ -63,6 +67,8 @@ func (m *AuthInboundMiddleware) HandleOneway(ctx context.Context, req *transport
 		return yarpcerrors.PermissionDeniedErrorf(permissionDeniedErrorStr, req.Procedure, req.Service)
 	}
 
+	req.Headers = withAuthenticatedUser(req.Headers, user)
+
 	return h.HandleOneway(ctx, req)
 }
 
@@ -71,7 +77,7 @@ func (m *AuthInboundMiddleware) HandleStream(s *transport.ServerStream, h transp
 	service := s.Request().Meta.Service
 	procedure := s.Request().Meta.Procedure
 
-	permitted, err := m.isPermitted(s.Request().Meta.Headers, service, procedure, s.Request().Meta.Caller)
+	user, permitted, err := m.isPermitted(s.Request().Meta.Headers, service, procedure, s.Request().Meta.Caller)
 	if err != nil {
 		return err
 	}
@@ -80,6 +86,8 @@ func (m *AuthInboundMiddleware) HandleStream(s *transport.ServerStream, h transp
 		return yarpcerrors.PermissionDeniedErrorf(permissionDeniedErrorStr, service, procedure)
 	}
 
+	s.Request().Meta.Headers = withAuthenticatedUser(s.Request().Meta.Headers, user)
+
 	return h.HandleStream(s)
 }
 
@@ -87,17 +95,17 @@ func (m *AuthInboundMiddleware) isPermitted(
 	headers transport.Headers,
 	service string,
 	procedure string,
-	caller string) (permitted bool, err error) {
+	caller string) (user auth.User, permitted bool, err error) {
 	// check the service name and authenticate only peloton services.
 	// Other services such as Mesos callback (service name: Scheduler)
 	// cannot be authenticated by peloton auth mechanism for now.
 	if !strings.HasPrefix(service, _pelotonServicePrefix) {
-		return true, nil
+		return nil, true, nil
 	}
 
-	user, err := m.Authenticate(headers)
+	user, err = m.Authenticate(headers)
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 
 	m.RedactToken(headers)
@@ -111,7 +119,21 @@ func (m *AuthInboundMiddleware) isPermitted(
 		}).Info("procedure called not permitted for user")
 	}
 
-	return permitted, err
+	return user, permitted, err
+}
+
+// withAuthenticatedUser returns the headers carrying the name of the
+// authenticated user for the middleware and handlers down the chain.
+// A value sent by the caller is always dropped.
+func withAuthenticatedUser(headers transport.Headers, user auth.User) transport.Headers {
+	headers.Del(audit.AuthenticatedUserHeader)
+	if user == nil {
+		return headers
+	}
+	if name := user.Name(); len(name) > 0 {
+		return headers.With(audit.AuthenticatedUserHeader, name)
+	}
+	return headers
 }
 
 // NewAuthInboundMiddleware returns AuthInboundMiddleware with auth check
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	auth_mocks "github.com/uber/peloton/pkg/auth/mocks"
+	"github.com/uber/peloton/pkg/common/audit"
 
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
@@ -62,10 +63,43 @@ func (suite *AuthInboundMiddlewareSuite) TestHandleSuccess() {
 	suite.s.EXPECT().Authenticate(gomock.Any()).Return(suite.u, nil)
 	suite.s.EXPECT().RedactToken(gomock.Any()).Return()
 	suite.u.EXPECT().IsPermitted(gomock.Any()).Return(true)
+	suite.u.EXPECT().Name().Return("user1")
 	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 	suite.NoError(suite.m.Handle(context.Background(), suite.r, nil, h))
 }
 
+// TestHandleAuthenticatedUser tests that the handler gets the name of the
+// authenticated user, and not the one sent by the caller
+func (suite *AuthInboundMiddlewareSuite) TestHandleAuthenticatedUser() {
+	h := transporttest.NewMockUnaryHandler(suite.ctrl)
+	suite.r.Headers = suite.r.Headers.With(audit.AuthenticatedUserHeader, "forged")
+	suite.s.EXPECT().Authenticate(gomock.Any()).Return(suite.u, nil)
+	suite.s.EXPECT().RedactToken(gomock.Any()).Return()
+	suite.u.EXPECT().IsPermitted(gomock.Any()).Return(true)
+	suite.u.EXPECT().Name().Return("user1")
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *transport.Request, _ transport.ResponseWriter) {
+			user, _ := req.Headers.Get(audit.AuthenticatedUserHeader)
+			suite.Equal("user1", user)
+		}).
+		Return(nil)
+	suite.NoError(suite.m.Handle(context.Background(), suite.r, nil, h))
+
+	// the header is dropped if the user is not identified
+	suite.r.Headers = suite.r.Headers.With(audit.AuthenticatedUserHeader, "forged")
+	suite.s.EXPECT().Authenticate(gomock.Any()).Return(suite.u, nil)
+	suite.s.EXPECT().RedactToken(gomock.Any()).Return()
+	suite.u.EXPECT().IsPermitted(gomock.Any()).Return(true)
+	suite.u.EXPECT().Name().Return("")
+	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *transport.Request, _ transport.ResponseWriter) {
+			_, ok := req.Headers.Get(audit.AuthenticatedUserHeader)
+			suite.False(ok)
+		}).
+		Return(nil)
+	suite.NoError(suite.m.Handle(context.Background(), suite.r, nil, h))
+}
+
 func (suite *AuthInboundMiddlewareSuite) TestHandleAuthenticateFail() {
 	h := transporttest.NewMockUnaryHandler(suite.ctrl)
 	suite.s.EXPECT().Authenticate(gomock.Any()).Return(nil, errors.New("test error"))
@@ -85,6 +119,7 @@ func (suite *AuthInboundMiddlewareSuite) TestHandleHandlerFail() {
 	suite.s.EXPECT().Authenticate(gomock.Any()).Return(suite.u, nil)
 	suite.s.EXPECT().RedactToken(gomock.Any()).Return()
 	suite.u.EXPECT().IsPermitted(gomock.Any()).Return(true)
+	suite.u.EXPECT().Name().Return("user1")
 	h.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("test error"))
 	suite.Error(suite.m.Handle(context.Background(), suite.r, nil, h))
 }
@@ -94,6 +129,7 @@ func (suite *AuthInboundMiddlewareSuite) TestHandleOnewaySuccess() {
 	suite.s.EXPECT().Authenticate(gomock.Any()).Return(suite.u, nil)
 	suite.s.EXPECT().RedactToken(gomock.Any()).Return()
 	suite.u.EXPECT().IsPermitted(gomock.Any()).Return(true)
+	suite.u.EXPECT().Name().Return("user1")
 	h.EXPECT().HandleOneway(gomock.Any(), gomock.Any()).Return(nil)
 	suite.NoError(suite.m.HandleOneway(context.Background(), suite.r, h))
 }
@@ -117,6 +153,7 @@ func (suite *AuthInboundMiddlewareSuite) TestHandleOnewayHandlerFail() {
 	suite.s.EXPECT().Authenticate(gomock.Any()).Return(suite.u, nil)
 	suite.s.EXPECT().RedactToken(gomock.Any()).Return()
 	suite.u.EXPECT().IsPermitted(gomock.Any()).Return(true)
+	suite.u.EXPECT().Name().Return("user1")
 	h.EXPECT().HandleOneway(gomock.Any(), gomock.Any()).Return(errors.New("test error"))
 	suite.Error(suite.m.HandleOneway(context.Background(), suite.r, h))
 }
@@ -133,6 +170,7 @@ func (suite *AuthInboundMiddlewareSuite) TestHandleStreamSuccess() {
 	suite.s.EXPECT().Authenticate(gomock.Any()).Return(suite.u, nil)
 	suite.s.EXPECT().RedactToken(gomock.Any()).Return()
 	suite.u.EXPECT().IsPermitted(gomock.Any()).Return(true)
+	suite.u.EXPECT().Name().Return("user1")
 	h.EXPECT().HandleStream(gomock.Any()).Return(nil)
 	suite.NoError(suite.m.HandleStream(ss, h))
 }
@@ -177,6 +215,7 @@ func (suite *AuthInboundMiddlewareSuite) TestHandleStreamHandlerFail() {
 	suite.s.EXPECT().Authenticate(gomock.Any()).Return(suite.u, nil)
 	suite.s.EXPECT().RedactToken(gomock.Any()).Return()
 	suite.u.EXPECT().IsPermitted(gomock.Any()).Return(true)
+	suite.u.EXPECT().Name().Return("user1")
 	h.EXPECT().HandleStream(gomock.Any()).Return(errors.New("test error"))
 	suite.Error(suite.m.HandleStream(ss, h))
 }
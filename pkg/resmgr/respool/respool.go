@@ -566,6 +566,7 @@ func (n *resPool) ToResourcePoolInfo() *respool.ResourcePoolInfo {
 		Usage: n.createRespoolUsage(
 			n.allocation.GetByType(scalar.TotalAllocation),
 			n.allocation.GetByType(scalar.SlackAllocation)),
+		Entitlement: n.createRespoolEntitlement(n.entitlement),
 	}
 }
 
@@ -694,6 +695,28 @@ func (n *resPool) createRespoolUsage(
 	return resUsage
 }
 
+// creates the current resource pool's entitlement of non-revocable
+// resources, as last set by the entitlement calculator.
+func (n *resPool) createRespoolEntitlement(
+	entitlement *scalar.Resources) []*respool.ResourceEntitlement {
+	if entitlement == nil {
+		entitlement = &scalar.Resources{}
+	}
+	resEntitlement := make([]*respool.ResourceEntitlement, 0, 4)
+	for _, kind := range []string{
+		common.CPU,
+		common.GPU,
+		common.MEMORY,
+		common.DISK,
+	} {
+		resEntitlement = append(resEntitlement, &respool.ResourceEntitlement{
+			Kind:        kind,
+			Entitlement: entitlement.Get(kind),
+		})
+	}
+	return resEntitlement
+}
+
 // isLeaf checks if the current resource pool has child resource or not.
 func (n *resPool) isLeaf() bool {
 	return n.children.Len() == 0
@@ -1069,11 +1069,32 @@ func (s *ResPoolSuite) TestToResourcePoolInfo() {
 	for _, resUsage := range info.GetUsage() {
 		s.Equal(float64(0), resUsage.GetAllocation())
 	}
+	for _, resEntitlement := range info.GetEntitlement() {
+		s.Equal(float64(0), resEntitlement.GetEntitlement())
+	}
 	s.Equal(0, len(info.GetChildren()))
 	s.Equal(&_rootResPoolID, info.GetParent())
 	s.Equal("/"+_testResPoolName, info.GetPath().GetValue())
 }
 
+func (s *ResPoolSuite) TestToResourcePoolInfoEntitlement() {
+	respoolNode := s.createTestResourcePool()
+	respoolNode.SetEntitlement(&scalar.Resources{
+		CPU:    10,
+		GPU:    1,
+		MEMORY: 100,
+		DISK:   1000,
+	})
+
+	info := respoolNode.ToResourcePoolInfo()
+	s.Equal([]*pb_respool.ResourceEntitlement{
+		{Kind: common.CPU, Entitlement: 10},
+		{Kind: common.GPU, Entitlement: 1},
+		{Kind: common.MEMORY, Entitlement: 100},
+		{Kind: common.DISK, Entitlement: 1000},
+	}, info.GetEntitlement())
+}
+
 func (s *ResPoolSuite) TestAggregatedChildrenReservations() {
 	respool1ID := peloton.ResourcePoolID{Value: "respool1"}
 	respool2ID := peloton.ResourcePoolID{Value: "respool2"}
@@ -26,20 +26,9 @@ import (
 
 // CreateStore is to create clusters and connections
 func CreateStore(storeConfig *CassandraConn, keySpace string, scope tally.Scope) (*Store, error) {
-	cluster := newCluster(storeConfig)
-	cluster.Keyspace = keySpace
-
-	if len(storeConfig.Username) != 0 {
-		cluster.Authenticator = gocql.PasswordAuthenticator{
-			Username: storeConfig.Username,
-			Password: storeConfig.Password,
-		}
-	}
-
-	cSession, err := cluster.CreateSession()
+	cSession, err := CreateSession(storeConfig, keySpace)
 	if err != nil {
-		log.Error("Fail to create session: ", err.Error())
-		return nil, api.ErrConnection
+		return nil, err
 	}
 	storeScope := scope.Tagged(map[string]string{"store": keySpace})
 	cb := Store{
@@ -59,6 +48,26 @@ func CreateStore(storeConfig *CassandraConn, keySpace string, scope tally.Scope)
 	return &cb, nil
 }
 
+// CreateSession creates a session on the keyspace
+func CreateSession(storeConfig *CassandraConn, keySpace string) (*gocql.Session, error) {
+	cluster := newCluster(storeConfig)
+	cluster.Keyspace = keySpace
+
+	if len(storeConfig.Username) != 0 {
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: storeConfig.Username,
+			Password: storeConfig.Password,
+		}
+	}
+
+	cSession, err := cluster.CreateSession()
+	if err != nil {
+		log.Error("Fail to create session: ", err.Error())
+		return nil, api.ErrConnection
+	}
+	return cSession, nil
+}
+
 const (
 	defaultConnectionsPerHost = 3
 	// defaultTimeout is overwritten by timeout provided
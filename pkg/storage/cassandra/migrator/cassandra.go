@@ -0,0 +1,205 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	_historyTable = "schema_migration_history"
+	_lockTable    = "schema_migration_lock"
+	_lockName     = "schema"
+
+	// _legacyTable and _legacyVersionRow are where gemnasium/migrate
+	// records the schema version. It is a counter which is set to 1 when
+	// the table is created, and incremented by each migration applied.
+	_legacyTable      = "schema_migrations"
+	_legacyVersionRow = 1
+)
+
+// cassandraBackend is a Backend for a Cassandra keyspace. The lock is a
+// row written with a lightweight transaction, which expires with its TTL.
+type cassandraBackend struct {
+	session      *gocql.Session
+	keyspace     string
+	historyTable string
+	lockTable    string
+	legacyTable  string
+}
+
+// NewCassandraBackend returns a Backend for the keyspace of the session.
+// The backend takes ownership of the session.
+func NewCassandraBackend(session *gocql.Session, keyspace string) Backend {
+	return &cassandraBackend{
+		session:      session,
+		keyspace:     keyspace,
+		historyTable: _historyTable,
+		lockTable:    _lockTable,
+		legacyTable:  _legacyTable,
+	}
+}
+
+func (b *cassandraBackend) Init() error {
+	if err := b.session.Query(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (
+			version bigint PRIMARY KEY,
+			name text,
+			checksum text,
+			state text,
+			updated_at timestamp,
+			updated_by text)`, b.historyTable)).Exec(); err != nil {
+		return fmt.Errorf("unable to create migration history: %v", err)
+	}
+	if err := b.session.Query(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (
+			name text PRIMARY KEY,
+			owner text,
+			acquired_at timestamp)`, b.lockTable)).Exec(); err != nil {
+		return fmt.Errorf("unable to create migration lock: %v", err)
+	}
+	return nil
+}
+
+func (b *cassandraBackend) AcquireLock(
+	owner string,
+	ttl time.Duration,
+) (bool, string, error) {
+	current := make(map[string]interface{})
+	applied, err := b.session.Query(fmt.Sprintf(
+		`INSERT INTO %s (name, owner, acquired_at) VALUES (?, ?, ?)
+			IF NOT EXISTS USING TTL ?`, b.lockTable),
+		_lockName, owner, time.Now().UTC(), int(ttl.Seconds())).
+		SerialConsistency(gocql.LocalSerial).
+		MapScanCAS(current)
+	if err != nil {
+		return false, "", fmt.Errorf("unable to acquire migration lock: %v",
+			err)
+	}
+	if applied {
+		return true, owner, nil
+	}
+	currentOwner, _ := current["owner"].(string)
+	if currentOwner == owner {
+		return true, owner, b.RefreshLock(owner, ttl)
+	}
+	return false, currentOwner, nil
+}
+
+func (b *cassandraBackend) RefreshLock(owner string, ttl time.Duration) error {
+	applied, err := b.session.Query(fmt.Sprintf(
+		`UPDATE %s USING TTL ? SET owner = ?, acquired_at = ?
+			WHERE name = ? IF owner = ?`, b.lockTable),
+		int(ttl.Seconds()), owner, time.Now().UTC(), _lockName, owner).
+		SerialConsistency(gocql.LocalSerial).
+		MapScanCAS(make(map[string]interface{}))
+	if err != nil {
+		return fmt.Errorf("unable to refresh migration lock: %v", err)
+	}
+	if !applied {
+		return fmt.Errorf("migration lock is no longer held by %s", owner)
+	}
+	return nil
+}
+
+func (b *cassandraBackend) ReleaseLock(owner string) error {
+	_, err := b.session.Query(fmt.Sprintf(
+		`DELETE FROM %s WHERE name = ? IF owner = ?`, b.lockTable),
+		_lockName, owner).
+		SerialConsistency(gocql.LocalSerial).
+		MapScanCAS(make(map[string]interface{}))
+	return err
+}
+
+func (b *cassandraBackend) History() ([]*HistoryEntry, error) {
+	iter := b.session.Query(fmt.Sprintf(
+		`SELECT version, name, checksum, state, updated_at, updated_by
+			FROM %s`, b.historyTable)).Iter()
+
+	var entries []*HistoryEntry
+	var version int64
+	var name, checksum, state, updatedBy string
+	var updatedAt time.Time
+	for iter.Scan(&version, &name, &checksum, &state, &updatedAt, &updatedBy) {
+		entries = append(entries, &HistoryEntry{
+			Version:   uint64(version),
+			Name:      name,
+			Checksum:  checksum,
+			State:     State(state),
+			UpdatedAt: updatedAt,
+			UpdatedBy: updatedBy,
+		})
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("unable to read migration history: %v", err)
+	}
+	return entries, nil
+}
+
+func (b *cassandraBackend) SaveHistory(entry *HistoryEntry) error {
+	return b.session.Query(fmt.Sprintf(
+		`INSERT INTO %s
+			(version, name, checksum, state, updated_at, updated_by)
+			VALUES (?, ?, ?, ?, ?, ?)`, b.historyTable),
+		int64(entry.Version),
+		entry.Name,
+		entry.Checksum,
+		string(entry.State),
+		entry.UpdatedAt,
+		entry.UpdatedBy).Exec()
+}
+
+func (b *cassandraBackend) DeleteHistory(version uint64) error {
+	return b.session.Query(fmt.Sprintf(
+		`DELETE FROM %s WHERE version = ?`, b.historyTable),
+		int64(version)).Exec()
+}
+
+func (b *cassandraBackend) LegacyMigrationCount() (uint64, error) {
+	meta, err := b.session.KeyspaceMetadata(b.keyspace)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := meta.Tables[b.legacyTable]; !ok {
+		return 0, nil
+	}
+
+	var counter int64
+	err = b.session.Query(fmt.Sprintf(
+		`SELECT version FROM %s WHERE versionRow = ?`, b.legacyTable),
+		_legacyVersionRow).Scan(&counter)
+	if err == gocql.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to read legacy schema version: %v", err)
+	}
+	// the counter starts at 1 before any migration is applied
+	if counter <= 1 {
+		return 0, nil
+	}
+	return uint64(counter - 1), nil
+}
+
+func (b *cassandraBackend) Exec(stmt string) error {
+	return b.session.Query(stmt).Exec()
+}
+
+func (b *cassandraBackend) Close() {
+	b.session.Close()
+}
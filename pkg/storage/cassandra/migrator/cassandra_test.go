@@ -0,0 +1,160 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/suite"
+)
+
+type CassandraBackendTestSuite struct {
+	suite.Suite
+	backend *cassandraBackend
+}
+
+func TestCassandraBackend(t *testing.T) {
+	suite.Run(t, new(CassandraBackendTestSuite))
+}
+
+func (suite *CassandraBackendTestSuite) SetupSuite() {
+	// same keyspace as the other storage tests
+	cluster := gocql.NewCluster("127.0.0.1")
+	cluster.Port = 9043
+	cluster.Keyspace = "peloton_test"
+	cluster.ProtoVersion = 3
+	cluster.Timeout = 20 * time.Second
+	session, err := cluster.CreateSession()
+	suite.NoError(err)
+
+	// use dedicated tables so that the test does not interfere with
+	// the schema migrations of the test keyspace
+	suffix := time.Now().UnixNano()
+	suite.backend = &cassandraBackend{
+		session:      session,
+		keyspace:     "peloton_test",
+		historyTable: fmt.Sprintf("test_migration_history_%d", suffix),
+		lockTable:    fmt.Sprintf("test_migration_lock_%d", suffix),
+		legacyTable:  fmt.Sprintf("test_schema_migrations_%d", suffix),
+	}
+	suite.NoError(suite.backend.Init())
+}
+
+func (suite *CassandraBackendTestSuite) TearDownSuite() {
+	suite.NoError(suite.backend.Exec("DROP TABLE " + suite.backend.historyTable))
+	suite.NoError(suite.backend.Exec("DROP TABLE " + suite.backend.lockTable))
+	suite.NoError(suite.backend.Exec(
+		"DROP TABLE IF EXISTS " + suite.backend.legacyTable))
+	suite.backend.Close()
+}
+
+// TestLock tests acquiring, refreshing and releasing the lock
+func (suite *CassandraBackendTestSuite) TestLock() {
+	acquired, owner, err := suite.backend.AcquireLock("a", time.Minute)
+	suite.NoError(err)
+	suite.True(acquired)
+	suite.Equal("a", owner)
+
+	// the lock is re-entrant for its owner
+	acquired, _, err = suite.backend.AcquireLock("a", time.Minute)
+	suite.NoError(err)
+	suite.True(acquired)
+
+	acquired, owner, err = suite.backend.AcquireLock("b", time.Minute)
+	suite.NoError(err)
+	suite.False(acquired)
+	suite.Equal("a", owner)
+	suite.Error(suite.backend.RefreshLock("b", time.Minute))
+
+	// only the owner can release the lock
+	suite.NoError(suite.backend.ReleaseLock("b"))
+	suite.NoError(suite.backend.RefreshLock("a", time.Minute))
+	suite.NoError(suite.backend.ReleaseLock("a"))
+
+	acquired, _, err = suite.backend.AcquireLock("b", time.Minute)
+	suite.NoError(err)
+	suite.True(acquired)
+	suite.NoError(suite.backend.ReleaseLock("b"))
+}
+
+// TestHistory tests saving and deleting history entries
+func (suite *CassandraBackendTestSuite) TestHistory() {
+	entry := &HistoryEntry{
+		Version:   1,
+		Name:      "first",
+		Checksum:  "abc",
+		State:     StateApplying,
+		UpdatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		UpdatedBy: "a",
+	}
+	suite.NoError(suite.backend.SaveHistory(entry))
+
+	entry.State = StateApplied
+	suite.NoError(suite.backend.SaveHistory(entry))
+
+	entries, err := suite.backend.History()
+	suite.NoError(err)
+	suite.Len(entries, 1)
+	suite.Equal(entry.Version, entries[0].Version)
+	suite.Equal(StateApplied, entries[0].State)
+	suite.True(entry.UpdatedAt.Equal(entries[0].UpdatedAt))
+
+	suite.NoError(suite.backend.DeleteHistory(1))
+	entries, err = suite.backend.History()
+	suite.NoError(err)
+	suite.Empty(entries)
+}
+
+// TestLegacyMigrationCount tests reading the migrations applied in a
+// keyspace migrated by gemnasium/migrate
+func (suite *CassandraBackendTestSuite) TestLegacyMigrationCount() {
+	// same table and statements as the cassandra driver of gemnasium/migrate
+	suite.NoError(suite.backend.Exec(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s
+			(version counter, versionRow bigint primary key)`,
+		suite.backend.legacyTable)))
+	increment := fmt.Sprintf(
+		`UPDATE %s SET version = version + 1 where versionRow = 1`,
+		suite.backend.legacyTable)
+	decrement := fmt.Sprintf(
+		`UPDATE %s SET version = version - 1 where versionRow = 1`,
+		suite.backend.legacyTable)
+
+	// the counter row is created along with the table
+	count, err := suite.backend.LegacyMigrationCount()
+	suite.NoError(err)
+	suite.Equal(uint64(0), count)
+	suite.NoError(suite.backend.Exec(increment))
+	count, err = suite.backend.LegacyMigrationCount()
+	suite.NoError(err)
+	suite.Equal(uint64(0), count)
+
+	// migrations 0000 to 0034 are applied
+	for i := 0; i < 35; i++ {
+		suite.NoError(suite.backend.Exec(increment))
+	}
+	count, err = suite.backend.LegacyMigrationCount()
+	suite.NoError(err)
+	suite.Equal(uint64(35), count)
+
+	// 0034 is rolled back
+	suite.NoError(suite.backend.Exec(decrement))
+	count, err = suite.backend.LegacyMigrationCount()
+	suite.NoError(err)
+	suite.Equal(uint64(34), count)
+}
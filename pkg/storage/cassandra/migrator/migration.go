@@ -0,0 +1,161 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFileRegex matches migration files such as 0001_add_table.up.cql
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_(.*)\.(up|down)\.cql$`)
+
+// Migration is a versioned schema change read from the migrations
+// directory.
+type Migration struct {
+	// Version of the migration, from the file name prefix
+	Version uint64
+	// Name of the migration, from the file name
+	Name string
+	// Statements to apply the migration
+	Up []string
+	// Statements to roll back the migration, nil if there is no down file
+	Down []string
+	// Checksum of the up file, used to detect edited migrations
+	Checksum string
+}
+
+// ReadMigrations reads the migrations in a directory ordered by version.
+func ReadMigrations(dir string) ([]*Migration, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migrations %s: %v", dir, err)
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, f := range files {
+		match := migrationFileRegex.FindStringSubmatch(f.Name())
+		if f.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %v",
+				f.Name(), err)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read migration %s: %v",
+				f.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("conflicting migrations %s and %s "+
+				"for version %d", m.Name, match[2], version)
+		}
+
+		statements := splitStatements(string(content))
+		if match[3] == "up" {
+			sum := sha256.Sum256(content)
+			m.Up = statements
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			// keep an empty down migration distinct from a missing one
+			m.Down = append([]string{}, statements...)
+		}
+	}
+
+	var migrations []*Migration
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up file",
+				m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// splitStatements splits a CQL file into statements, dropping comments.
+func splitStatements(cql string) []string {
+	var statements []string
+	var stmt strings.Builder
+
+	flush := func() {
+		if s := strings.TrimSpace(stmt.String()); s != "" {
+			statements = append(statements, s)
+		}
+		stmt.Reset()
+	}
+
+	for i := 0; i < len(cql); i++ {
+		switch {
+		case cql[i] == '\'':
+			// copy string literals verbatim, '' is an escaped quote
+			end := i + 1
+			for end < len(cql) {
+				if cql[end] == '\'' {
+					if end+1 < len(cql) && cql[end+1] == '\'' {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(cql) {
+				end = len(cql) - 1
+			}
+			stmt.WriteString(cql[i : end+1])
+			i = end
+		case strings.HasPrefix(cql[i:], "/*"):
+			end := strings.Index(cql[i+2:], "*/")
+			if end < 0 {
+				i = len(cql)
+			} else {
+				i += end + 3
+			}
+		case strings.HasPrefix(cql[i:], "--"),
+			strings.HasPrefix(cql[i:], "//"):
+			end := strings.IndexByte(cql[i:], '\n')
+			if end < 0 {
+				i = len(cql)
+			} else {
+				i += end
+				stmt.WriteByte('\n')
+			}
+		case cql[i] == ';':
+			flush()
+		default:
+			stmt.WriteByte(cql[i])
+		}
+	}
+	flush()
+	return statements
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestMigration(t *testing.T) {
+	suite.Run(t, new(MigrationTestSuite))
+}
+
+func (suite *MigrationTestSuite) SetupTest() {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "migrations")
+	suite.NoError(err)
+}
+
+func (suite *MigrationTestSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+}
+
+func (suite *MigrationTestSuite) writeFile(name, content string) {
+	suite.NoError(ioutil.WriteFile(
+		filepath.Join(suite.dir, name), []byte(content), 0644))
+}
+
+// TestSplitStatements tests splitting CQL files into statements
+func (suite *MigrationTestSuite) TestSplitStatements() {
+	cql := `/*
+  A table; with a comment.
+*/
+CREATE TABLE t (k text PRIMARY KEY) WITH comment = 'a;b''c';
+-- drop it; later
+DROP INDEX IF EXISTS i;
+`
+	suite.Equal([]string{
+		"CREATE TABLE t (k text PRIMARY KEY) WITH comment = 'a;b''c'",
+		"DROP INDEX IF EXISTS i",
+	}, splitStatements(cql))
+
+	suite.Empty(splitStatements("/* nothing */\n"))
+}
+
+// TestReadMigrations tests reading migrations from a directory
+func (suite *MigrationTestSuite) TestReadMigrations() {
+	suite.writeFile("0002_second.up.cql", "CREATE TABLE b (k int PRIMARY KEY);")
+	suite.writeFile("0001_first.up.cql", "CREATE TABLE a (k int PRIMARY KEY);")
+	suite.writeFile("0001_first.down.cql", "DROP TABLE a;")
+	suite.writeFile("README.md", "not a migration")
+
+	migrations, err := ReadMigrations(suite.dir)
+	suite.NoError(err)
+	suite.Len(migrations, 2)
+
+	suite.Equal(uint64(1), migrations[0].Version)
+	suite.Equal("first", migrations[0].Name)
+	suite.Equal([]string{"CREATE TABLE a (k int PRIMARY KEY)"}, migrations[0].Up)
+	suite.Equal([]string{"DROP TABLE a"}, migrations[0].Down)
+	suite.NotEmpty(migrations[0].Checksum)
+
+	suite.Equal(uint64(2), migrations[1].Version)
+	suite.Nil(migrations[1].Down)
+	suite.NotEqual(migrations[0].Checksum, migrations[1].Checksum)
+}
+
+// TestReadMigrationsErrors tests invalid migration directories
+func (suite *MigrationTestSuite) TestReadMigrationsErrors() {
+	_, err := ReadMigrations(filepath.Join(suite.dir, "missing"))
+	suite.Error(err)
+
+	suite.writeFile("0001_first.down.cql", "DROP TABLE a;")
+	_, err = ReadMigrations(suite.dir)
+	suite.Error(err)
+
+	suite.writeFile("0001_first.up.cql", "CREATE TABLE a (k int PRIMARY KEY);")
+	suite.writeFile("0001_other.up.cql", "CREATE TABLE b (k int PRIMARY KEY);")
+	_, err = ReadMigrations(suite.dir)
+	suite.Error(err)
+}
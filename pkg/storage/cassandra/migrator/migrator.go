@@ -0,0 +1,500 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrator
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// _lockTTL is how long the migration lock is held without a refresh,
+	// so that a crashed migrator does not block migrations forever.
+	_lockTTL = 10 * time.Minute
+	// _lockRetryInterval is how often a busy lock is retried
+	_lockRetryInterval = 5 * time.Second
+	// _lockRefreshInterval is how often the lock is refreshed while
+	// migrations run, well within _lockTTL
+	_lockRefreshInterval = _lockTTL / 5
+
+	// _adoptedBy is recorded as the migrator of migrations that were
+	// applied before the migration history existed
+	_adoptedBy = "adopted"
+)
+
+// State is the state of a migration.
+type State string
+
+const (
+	// StatePending is a migration that has not been applied.
+	StatePending State = "pending"
+	// StateApplying is a migration that was started but did not finish,
+	// leaving the schema partially migrated.
+	StateApplying State = "applying"
+	// StateRollingBack is a migration whose rollback did not finish.
+	StateRollingBack State = "rolling_back"
+	// StateApplied is a migration that has been applied.
+	StateApplied State = "applied"
+	// StateModified is an applied migration whose file has been edited
+	// since it was applied.
+	StateModified State = "modified"
+	// StateMissing is an applied migration with no migration file, e.g.
+	// when the schema was migrated by a newer version.
+	StateMissing State = "missing"
+)
+
+// HistoryEntry is a row of the migration history.
+type HistoryEntry struct {
+	Version   uint64
+	Name      string
+	Checksum  string
+	State     State
+	UpdatedAt time.Time
+	UpdatedBy string
+}
+
+// MigrationStatus is the status of a migration.
+type MigrationStatus struct {
+	Version   uint64
+	Name      string
+	State     State
+	UpdatedAt time.Time
+	UpdatedBy string
+}
+
+// Backend stores the migration history and lock, and executes schema
+// changes.
+type Backend interface {
+	// Init creates the migration history and lock tables if needed.
+	Init() error
+	// AcquireLock takes the migration lock for the given time. It
+	// returns false and the current owner if the lock is held by
+	// someone else.
+	AcquireLock(owner string, ttl time.Duration) (bool, string, error)
+	// RefreshLock extends a lock held by owner.
+	RefreshLock(owner string, ttl time.Duration) error
+	// ReleaseLock releases a lock held by owner.
+	ReleaseLock(owner string) error
+	// History returns the migration history.
+	History() ([]*HistoryEntry, error)
+	// SaveHistory creates or replaces a migration history entry.
+	SaveHistory(entry *HistoryEntry) error
+	// DeleteHistory deletes the history entry of a migration.
+	DeleteHistory(version uint64) error
+	// LegacyMigrationCount returns the number of migrations applied by
+	// the previous migration tool, or 0 if there is none.
+	LegacyMigrationCount() (uint64, error)
+	// Exec executes a schema change statement.
+	Exec(stmt string) error
+	// Close releases the resources of the backend.
+	Close()
+}
+
+// Migrator applies and rolls back versioned schema migrations. Each
+// migration is recorded in the migration history before and after it
+// is applied, so that a partially applied migration is detected instead
+// of being retried on top of a half-migrated schema.
+type Migrator struct {
+	backend    Backend
+	migrations []*Migration
+	owner      string
+	lockWait   time.Duration
+
+	// the lock is refreshed in the background while it is held, and the
+	// first refresh failure is sent to lockLost
+	lockRefreshInterval time.Duration
+	stopRefresh         chan struct{}
+	refreshDone         chan struct{}
+	lockLost            chan error
+}
+
+// New creates a Migrator for the migrations in dir. Up and Down wait up
+// to lockWait for the migration lock held by another migrator.
+func New(backend Backend, dir string, lockWait time.Duration) (*Migrator, error) {
+	migrations, err := ReadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := backend.Init(); err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	return &Migrator{
+		backend:    backend,
+		migrations: migrations,
+		owner:      fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		lockWait:   lockWait,
+
+		lockRefreshInterval: _lockRefreshInterval,
+	}, nil
+}
+
+// Close closes the backend of the migrator.
+func (m *Migrator) Close() {
+	m.backend.Close()
+}
+
+// Status returns the status of every known migration ordered by version.
+func (m *Migrator) Status() ([]*MigrationStatus, error) {
+	history, err := m.history()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*MigrationStatus
+	for _, mig := range m.migrations {
+		status := &MigrationStatus{
+			Version: mig.Version,
+			Name:    mig.Name,
+			State:   StatePending,
+		}
+		if entry, ok := history[mig.Version]; ok {
+			status.State = entry.State
+			status.UpdatedAt = entry.UpdatedAt
+			status.UpdatedBy = entry.UpdatedBy
+			if entry.State == StateApplied && entry.Checksum != mig.Checksum {
+				status.State = StateModified
+			}
+			delete(history, mig.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, entry := range history {
+		statuses = append(statuses, &MigrationStatus{
+			Version:   entry.Version,
+			Name:      entry.Name,
+			State:     StateMissing,
+			UpdatedAt: entry.UpdatedAt,
+			UpdatedBy: entry.UpdatedBy,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Up applies the pending migrations up to and including version to, or
+// all of them if to is 0. It refuses to run if a migration is partially
+// applied or an applied migration has been edited. With dryRun, it only
+// returns the migrations which would be applied.
+func (m *Migrator) Up(to uint64, dryRun bool) ([]*Migration, error) {
+	if !dryRun {
+		if err := m.lock(); err != nil {
+			return nil, err
+		}
+		defer m.unlock()
+	}
+
+	history, err := m.history()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*Migration
+	for _, mig := range m.migrations {
+		entry, ok := history[mig.Version]
+		switch {
+		case !ok:
+			if to == 0 || mig.Version <= to {
+				pending = append(pending, mig)
+			}
+		case entry.State != StateApplied:
+			return nil, fmt.Errorf("migration %d (%s) is %s, roll it back "+
+				"with down --to %d first",
+				mig.Version, mig.Name, entry.State, mig.Version-1)
+		case entry.Checksum != mig.Checksum:
+			return nil, fmt.Errorf("migration %d (%s) was edited after "+
+				"it was applied", mig.Version, mig.Name)
+		}
+	}
+
+	if dryRun {
+		return pending, nil
+	}
+	for _, mig := range pending {
+		if err := m.apply(mig); err != nil {
+			return nil, err
+		}
+	}
+	return pending, nil
+}
+
+// Down rolls back the applied migrations newer than version to, newest
+// first. Partially applied migrations are rolled back as well, which is
+// why down migrations should tolerate missing schema elements. With
+// dryRun, it only returns the migrations which would be rolled back.
+func (m *Migrator) Down(to uint64, dryRun bool) ([]*Migration, error) {
+	if !dryRun {
+		if err := m.lock(); err != nil {
+			return nil, err
+		}
+		defer m.unlock()
+	}
+
+	history, err := m.history()
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var rollback []*Migration
+	for version := range history {
+		if version <= to {
+			continue
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration file for version %d",
+				version)
+		}
+		if mig.Down == nil {
+			return nil, fmt.Errorf("migration %d (%s) has no down file",
+				mig.Version, mig.Name)
+		}
+		rollback = append(rollback, mig)
+	}
+	sort.Slice(rollback, func(i, j int) bool {
+		return rollback[i].Version > rollback[j].Version
+	})
+
+	if dryRun {
+		return rollback, nil
+	}
+	for _, mig := range rollback {
+		if err := m.rollback(mig); err != nil {
+			return nil, err
+		}
+	}
+	return rollback, nil
+}
+
+func (m *Migrator) apply(mig *Migration) error {
+	log.WithFields(log.Fields{
+		"version": mig.Version,
+		"name":    mig.Name,
+	}).Info("Applying schema migration")
+
+	if err := m.checkLock(); err != nil {
+		return err
+	}
+	if err := m.saveHistory(mig, StateApplying); err != nil {
+		return err
+	}
+	for _, stmt := range mig.Up {
+		if err := m.checkLock(); err != nil {
+			return fmt.Errorf("migration %d (%s) aborted, the schema is "+
+				"partially migrated: %v", mig.Version, mig.Name, err)
+		}
+		if err := m.backend.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %d (%s) failed, the schema is "+
+				"partially migrated: %v", mig.Version, mig.Name, err)
+		}
+	}
+	return m.saveHistory(mig, StateApplied)
+}
+
+func (m *Migrator) rollback(mig *Migration) error {
+	log.WithFields(log.Fields{
+		"version": mig.Version,
+		"name":    mig.Name,
+	}).Info("Rolling back schema migration")
+
+	if err := m.checkLock(); err != nil {
+		return err
+	}
+	if err := m.saveHistory(mig, StateRollingBack); err != nil {
+		return err
+	}
+	for _, stmt := range mig.Down {
+		if err := m.checkLock(); err != nil {
+			return fmt.Errorf("rollback of migration %d (%s) aborted: %v",
+				mig.Version, mig.Name, err)
+		}
+		if err := m.backend.Exec(stmt); err != nil {
+			return fmt.Errorf("rollback of migration %d (%s) failed: %v",
+				mig.Version, mig.Name, err)
+		}
+	}
+	return m.backend.DeleteHistory(mig.Version)
+}
+
+func (m *Migrator) saveHistory(mig *Migration, state State) error {
+	return m.backend.SaveHistory(&HistoryEntry{
+		Version:   mig.Version,
+		Name:      mig.Name,
+		Checksum:  mig.Checksum,
+		State:     state,
+		UpdatedAt: time.Now().UTC(),
+		UpdatedBy: m.owner,
+	})
+}
+
+// history returns the migration history by version. If there is no
+// history yet, the migrations applied by the previous migration tool
+// are treated as applied. That tool only counts the migrations it
+// applied, in version order, so the first ones are adopted rather than
+// the ones up to a version.
+func (m *Migrator) history() (map[uint64]*HistoryEntry, error) {
+	entries, err := m.backend.History()
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[uint64]*HistoryEntry)
+	for _, entry := range entries {
+		history[entry.Version] = entry
+	}
+	if len(history) > 0 {
+		return history, nil
+	}
+
+	legacyCount, err := m.backend.LegacyMigrationCount()
+	if err != nil {
+		return nil, err
+	}
+	if legacyCount > uint64(len(m.migrations)) {
+		return nil, fmt.Errorf("%d migrations were applied by the previous "+
+			"migration tool, but there are only %d migration files",
+			legacyCount, len(m.migrations))
+	}
+	for _, mig := range m.migrations[:legacyCount] {
+		history[mig.Version] = &HistoryEntry{
+			Version:   mig.Version,
+			Name:      mig.Name,
+			Checksum:  mig.Checksum,
+			State:     StateApplied,
+			UpdatedBy: _adoptedBy,
+		}
+	}
+	return history, nil
+}
+
+// lock takes the migration lock, waiting up to lockWait for it, and
+// persists the adopted history once the lock is held.
+func (m *Migrator) lock() error {
+	deadline := time.Now().Add(m.lockWait)
+	for {
+		acquired, owner, err := m.backend.AcquireLock(m.owner, _lockTTL)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("migration lock is held by %s", owner)
+		}
+		log.WithField("owner", owner).
+			Info("Waiting for the schema migration lock")
+		time.Sleep(_lockRetryInterval)
+	}
+
+	m.startRefresh()
+	if err := m.adoptLegacyHistory(); err != nil {
+		m.unlock()
+		return err
+	}
+	return nil
+}
+
+func (m *Migrator) unlock() {
+	m.stopRefreshing()
+	if err := m.backend.ReleaseLock(m.owner); err != nil {
+		log.WithError(err).Warn("Failed to release the schema migration lock")
+	}
+}
+
+// startRefresh refreshes the lock in the background until unlock, since
+// a single migration may run for longer than the lock TTL.
+func (m *Migrator) startRefresh() {
+	m.stopRefresh = make(chan struct{})
+	m.refreshDone = make(chan struct{})
+	m.lockLost = make(chan error, 1)
+
+	go func(stop <-chan struct{}, done chan<- struct{}, lost chan<- error) {
+		defer close(done)
+		ticker := time.NewTicker(m.lockRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := m.backend.RefreshLock(m.owner, _lockTTL); err != nil {
+					log.WithError(err).
+						Error("Failed to refresh the schema migration lock")
+					lost <- err
+					return
+				}
+			}
+		}
+	}(m.stopRefresh, m.refreshDone, m.lockLost)
+}
+
+func (m *Migrator) stopRefreshing() {
+	if m.stopRefresh == nil {
+		return
+	}
+	close(m.stopRefresh)
+	<-m.refreshDone
+	m.stopRefresh = nil
+}
+
+// checkLock returns an error once a refresh of the lock has failed, after
+// which another migrator may hold the lock and no statement may be run.
+func (m *Migrator) checkLock() error {
+	select {
+	case err := <-m.lockLost:
+		// keep failing the checks which follow
+		m.lockLost <- err
+		return fmt.Errorf("the schema migration lock was lost: %v", err)
+	default:
+		return nil
+	}
+}
+
+// adoptLegacyHistory records the migrations applied by the previous
+// migration tool in the migration history.
+func (m *Migrator) adoptLegacyHistory() error {
+	entries, err := m.backend.History()
+	if err != nil || len(entries) > 0 {
+		return err
+	}
+	history, err := m.history()
+	if err != nil {
+		return err
+	}
+	for _, entry := range history {
+		entry.UpdatedAt = time.Now().UTC()
+		if err := m.backend.SaveHistory(entry); err != nil {
+			return err
+		}
+	}
+	if len(history) > 0 {
+		log.WithField("migrations", len(history)).
+			Info("Adopted schema migrations applied before the migration history")
+	}
+	return nil
+}
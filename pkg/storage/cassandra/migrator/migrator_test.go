@@ -0,0 +1,403 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeBackend is an in-memory Backend
+type fakeBackend struct {
+	history     map[uint64]*HistoryEntry
+	legacyCount uint64
+	executed    []string
+	failOn      string
+	execDelay   time.Duration
+
+	// the lock is refreshed in the background by the migrator
+	sync.Mutex
+	lockOwner  string
+	refreshes  int
+	refreshErr error
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{history: make(map[uint64]*HistoryEntry)}
+}
+
+func (b *fakeBackend) Init() error { return nil }
+
+func (b *fakeBackend) AcquireLock(
+	owner string,
+	ttl time.Duration,
+) (bool, string, error) {
+	b.Lock()
+	defer b.Unlock()
+	if b.lockOwner != "" && b.lockOwner != owner {
+		return false, b.lockOwner, nil
+	}
+	b.lockOwner = owner
+	return true, owner, nil
+}
+
+func (b *fakeBackend) RefreshLock(owner string, ttl time.Duration) error {
+	b.Lock()
+	defer b.Unlock()
+	if b.refreshErr != nil {
+		return b.refreshErr
+	}
+	if b.lockOwner != owner {
+		return errors.New("lock lost")
+	}
+	b.refreshes++
+	return nil
+}
+
+func (b *fakeBackend) ReleaseLock(owner string) error {
+	b.Lock()
+	defer b.Unlock()
+	if b.lockOwner == owner {
+		b.lockOwner = ""
+	}
+	return nil
+}
+
+func (b *fakeBackend) History() ([]*HistoryEntry, error) {
+	var entries []*HistoryEntry
+	for _, e := range b.history {
+		entry := *e
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+func (b *fakeBackend) SaveHistory(entry *HistoryEntry) error {
+	e := *entry
+	b.history[entry.Version] = &e
+	return nil
+}
+
+func (b *fakeBackend) DeleteHistory(version uint64) error {
+	delete(b.history, version)
+	return nil
+}
+
+func (b *fakeBackend) LegacyMigrationCount() (uint64, error) {
+	return b.legacyCount, nil
+}
+
+func (b *fakeBackend) Exec(stmt string) error {
+	time.Sleep(b.execDelay)
+	if stmt == b.failOn {
+		return errors.New("exec failed")
+	}
+	b.executed = append(b.executed, stmt)
+	return nil
+}
+
+func (b *fakeBackend) Close() {}
+
+type MigratorTestSuite struct {
+	suite.Suite
+	dir     string
+	backend *fakeBackend
+}
+
+func TestMigrator(t *testing.T) {
+	suite.Run(t, new(MigratorTestSuite))
+}
+
+func (suite *MigratorTestSuite) SetupTest() {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "migrations")
+	suite.NoError(err)
+	suite.backend = newFakeBackend()
+
+	suite.writeFile("0001_a.up.cql", "CREATE TABLE a (k int PRIMARY KEY);")
+	suite.writeFile("0001_a.down.cql", "DROP TABLE a;")
+	suite.writeFile("0002_b.up.cql", "CREATE TABLE b (k int PRIMARY KEY);")
+	suite.writeFile("0002_b.down.cql", "DROP TABLE b;")
+	suite.writeFile("0003_c.up.cql",
+		"CREATE TABLE c (k int PRIMARY KEY);\nCREATE INDEX ON c (k);")
+	suite.writeFile("0003_c.down.cql", "DROP TABLE c;")
+}
+
+func (suite *MigratorTestSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+}
+
+func (suite *MigratorTestSuite) writeFile(name, content string) {
+	suite.NoError(ioutil.WriteFile(
+		filepath.Join(suite.dir, name), []byte(content), 0644))
+}
+
+func (suite *MigratorTestSuite) newMigrator() *Migrator {
+	m, err := New(suite.backend, suite.dir, 0)
+	suite.NoError(err)
+	return m
+}
+
+func (suite *MigratorTestSuite) states() map[uint64]State {
+	statuses, err := suite.newMigrator().Status()
+	suite.NoError(err)
+	states := make(map[uint64]State)
+	for _, s := range statuses {
+		states[s.Version] = s.State
+	}
+	return states
+}
+
+// TestUpDown tests applying and rolling back migrations
+func (suite *MigratorTestSuite) TestUpDown() {
+	m := suite.newMigrator()
+
+	applied, err := m.Up(2, false)
+	suite.NoError(err)
+	suite.Len(applied, 2)
+	suite.Equal(map[uint64]State{
+		1: StateApplied,
+		2: StateApplied,
+		3: StatePending,
+	}, suite.states())
+
+	applied, err = m.Up(0, false)
+	suite.NoError(err)
+	suite.Len(applied, 1)
+	suite.Equal(uint64(3), applied[0].Version)
+	suite.Equal([]string{
+		"CREATE TABLE a (k int PRIMARY KEY)",
+		"CREATE TABLE b (k int PRIMARY KEY)",
+		"CREATE TABLE c (k int PRIMARY KEY)",
+		"CREATE INDEX ON c (k)",
+	}, suite.backend.executed)
+
+	// nothing left to apply
+	applied, err = m.Up(0, false)
+	suite.NoError(err)
+	suite.Empty(applied)
+
+	rolledBack, err := m.Down(1, false)
+	suite.NoError(err)
+	suite.Len(rolledBack, 2)
+	suite.Equal(uint64(3), rolledBack[0].Version)
+	suite.Equal(uint64(2), rolledBack[1].Version)
+	suite.Equal(map[uint64]State{
+		1: StateApplied,
+		2: StatePending,
+		3: StatePending,
+	}, suite.states())
+
+	// the lock is released after each run
+	suite.Empty(suite.backend.lockOwner)
+}
+
+// TestDryRun tests that dry runs do not change the schema
+func (suite *MigratorTestSuite) TestDryRun() {
+	m := suite.newMigrator()
+
+	pending, err := m.Up(0, true)
+	suite.NoError(err)
+	suite.Len(pending, 3)
+	suite.Empty(suite.backend.executed)
+	suite.Empty(suite.backend.history)
+
+	_, err = m.Up(0, false)
+	suite.NoError(err)
+	rollback, err := m.Down(0, true)
+	suite.NoError(err)
+	suite.Len(rollback, 3)
+	suite.Len(suite.backend.history, 3)
+}
+
+// TestPartiallyApplied tests that a failed migration blocks further
+// migrations until it is rolled back
+func (suite *MigratorTestSuite) TestPartiallyApplied() {
+	suite.backend.failOn = "CREATE INDEX ON c (k)"
+	m := suite.newMigrator()
+
+	_, err := m.Up(0, false)
+	suite.Error(err)
+	suite.Equal(StateApplying, suite.states()[3])
+
+	suite.backend.failOn = ""
+	_, err = m.Up(0, false)
+	suite.Error(err)
+
+	rolledBack, err := m.Down(2, false)
+	suite.NoError(err)
+	suite.Len(rolledBack, 1)
+	suite.Equal(StatePending, suite.states()[3])
+
+	_, err = m.Up(0, false)
+	suite.NoError(err)
+	suite.Equal(StateApplied, suite.states()[3])
+}
+
+// TestModified tests that edited migrations are detected
+func (suite *MigratorTestSuite) TestModified() {
+	_, err := suite.newMigrator().Up(0, false)
+	suite.NoError(err)
+
+	suite.writeFile("0002_b.up.cql", "CREATE TABLE bb (k int PRIMARY KEY);")
+	suite.Equal(StateModified, suite.states()[2])
+
+	_, err = suite.newMigrator().Up(0, false)
+	suite.Error(err)
+}
+
+// TestMissing tests migrations applied by a newer version
+func (suite *MigratorTestSuite) TestMissing() {
+	suite.backend.history[4] = &HistoryEntry{
+		Version: 4,
+		Name:    "d",
+		State:   StateApplied,
+	}
+	suite.Equal(StateMissing, suite.states()[4])
+
+	// migrating up ignores newer migrations
+	applied, err := suite.newMigrator().Up(0, false)
+	suite.NoError(err)
+	suite.Len(applied, 3)
+
+	// but they cannot be rolled back without their files
+	_, err = suite.newMigrator().Down(0, false)
+	suite.Error(err)
+}
+
+// TestNoDownFile tests rolling back a migration without a down file
+func (suite *MigratorTestSuite) TestNoDownFile() {
+	suite.NoError(os.Remove(filepath.Join(suite.dir, "0003_c.down.cql")))
+	m := suite.newMigrator()
+	_, err := m.Up(0, false)
+	suite.NoError(err)
+
+	_, err = m.Down(2, false)
+	suite.Error(err)
+	suite.Equal(StateApplied, suite.states()[3])
+}
+
+// TestLegacyMigrationCount tests adopting the migrations applied by the
+// previous migration tool
+func (suite *MigratorTestSuite) TestLegacyMigrationCount() {
+	suite.backend.legacyCount = 2
+	m := suite.newMigrator()
+
+	suite.Equal(map[uint64]State{
+		1: StateApplied,
+		2: StateApplied,
+		3: StatePending,
+	}, suite.states())
+	suite.Empty(suite.backend.history)
+
+	applied, err := m.Up(0, false)
+	suite.NoError(err)
+	suite.Len(applied, 1)
+	suite.Equal(uint64(3), applied[0].Version)
+	suite.Len(suite.backend.history, 3)
+	suite.Equal(_adoptedBy, suite.backend.history[1].UpdatedBy)
+}
+
+// TestLegacyMigrationCountFromZero tests adopting the migrations applied
+// by the previous migration tool when migrations are numbered from 0, as
+// the migrations of Peloton are
+func (suite *MigratorTestSuite) TestLegacyMigrationCountFromZero() {
+	suite.writeFile("0000_z.up.cql", "CREATE TABLE z (k int PRIMARY KEY);")
+	suite.writeFile("0000_z.down.cql", "DROP TABLE z;")
+
+	// nothing was applied, including the first migration
+	suite.Equal(map[uint64]State{
+		0: StatePending,
+		1: StatePending,
+		2: StatePending,
+		3: StatePending,
+	}, suite.states())
+
+	// 0000 to 0002 were applied, 0003 must not be skipped
+	suite.backend.legacyCount = 3
+	suite.Equal(map[uint64]State{
+		0: StateApplied,
+		1: StateApplied,
+		2: StateApplied,
+		3: StatePending,
+	}, suite.states())
+
+	applied, err := suite.newMigrator().Up(0, false)
+	suite.NoError(err)
+	suite.Len(applied, 1)
+	suite.Equal(uint64(3), applied[0].Version)
+
+	// more migrations applied than there are files
+	suite.backend.history = make(map[uint64]*HistoryEntry)
+	suite.backend.legacyCount = 5
+	_, err = suite.newMigrator().Status()
+	suite.Error(err)
+}
+
+// TestLockRefresh tests that the lock is refreshed while migrations run
+func (suite *MigratorTestSuite) TestLockRefresh() {
+	suite.backend.execDelay = 20 * time.Millisecond
+	m := suite.newMigrator()
+	m.lockRefreshInterval = time.Millisecond
+
+	applied, err := m.Up(0, false)
+	suite.NoError(err)
+	suite.Len(applied, 3)
+
+	suite.backend.Lock()
+	defer suite.backend.Unlock()
+	suite.NotZero(suite.backend.refreshes)
+	suite.Empty(suite.backend.lockOwner)
+}
+
+// TestLockLost tests that migrations are aborted once the lock cannot be
+// refreshed
+func (suite *MigratorTestSuite) TestLockLost() {
+	suite.backend.execDelay = 20 * time.Millisecond
+	suite.backend.refreshErr = errors.New("timeout")
+	m := suite.newMigrator()
+	m.lockRefreshInterval = time.Millisecond
+
+	_, err := m.Up(0, false)
+	suite.Error(err)
+	suite.True(len(suite.backend.executed) < 4)
+	suite.NotEqual(StateApplied, suite.states()[3])
+	suite.Empty(suite.backend.lockOwner)
+}
+
+// TestLockHeld tests that migrations fail while another migrator holds
+// the lock
+func (suite *MigratorTestSuite) TestLockHeld() {
+	suite.backend.lockOwner = "other"
+	m := suite.newMigrator()
+
+	_, err := m.Up(0, false)
+	suite.Error(err)
+	_, err = m.Down(0, false)
+	suite.Error(err)
+	suite.Empty(suite.backend.executed)
+
+	// dry runs do not need the lock
+	pending, err := m.Up(0, true)
+	suite.NoError(err)
+	suite.Len(pending, 3)
+}
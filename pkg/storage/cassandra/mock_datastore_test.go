@@ -32,7 +32,6 @@ import (
 	datastoremocks "github.com/uber/peloton/pkg/storage/cassandra/api/mocks"
 	datastoreimpl "github.com/uber/peloton/pkg/storage/cassandra/impl"
 	objectmocks "github.com/uber/peloton/pkg/storage/objects/mocks"
-	qb "github.com/uber/peloton/pkg/storage/querybuilder"
 
 	"github.com/golang/mock/gomock"
 	"github.com/pborman/uuid"
@@ -53,6 +52,7 @@ type MockDatastoreTestSuite struct {
 	mockedDataStore *datastoremocks.MockDataStore
 	store           *Store
 	jobConfigOps    *objectmocks.MockJobConfigOps
+	jobIndexOps     *objectmocks.MockJobIndexOps
 	jobRuntimeOps   *objectmocks.MockJobRuntimeOps
 }
 
@@ -64,11 +64,13 @@ func (suite *MockDatastoreTestSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 	suite.mockedDataStore = datastoremocks.NewMockDataStore(suite.ctrl)
 	suite.jobConfigOps = objectmocks.NewMockJobConfigOps(suite.ctrl)
+	suite.jobIndexOps = objectmocks.NewMockJobIndexOps(suite.ctrl)
 	suite.jobRuntimeOps = objectmocks.NewMockJobRuntimeOps(suite.ctrl)
 
 	suite.store = &Store{
 		DataStore:     suite.mockedDataStore,
 		jobConfigOps:  suite.jobConfigOps,
+		jobIndexOps:   suite.jobIndexOps,
 		jobRuntimeOps: suite.jobRuntimeOps,
 		metrics:       storage.NewMetrics(testScope.SubScope("storage")),
 		Conf:          &Config{},
@@ -114,29 +116,6 @@ func (suite *MockDatastoreTestSuite) TestGetStreamID() {
 	suite.Error(err)
 }
 
-// TestDataStoreFailureGetJobSummary tests datastore failures in getting
-// job summary
-func (suite *MockDatastoreTestSuite) TestDataStoreFailureGetJobSummary() {
-	_, err := suite.store.getJobSummaryFromIndex(
-		context.Background(), suite.testJobID)
-	suite.Error(err)
-
-	result := make(map[string]interface{})
-	jobID, err := qb.ParseUUID(suite.testJobID.GetValue())
-	suite.NoError(err)
-	result["job_id"] = jobID
-
-	// if name is set to "", we will try to get the summary by querying the
-	// job_config table. In case there is a Data Store error in this query, it
-	// should not prevent the rest of the jobs to be returned. So it should only
-	// log the error and move on to the next entry.
-	result["name"] = ""
-	results := []map[string]interface{}{result}
-	_, err = suite.store.getJobSummaryFromResultMap(
-		context.Background(), results)
-	suite.NoError(err)
-}
-
 // TestDataStoreFailureGetJob tests datastore failures in getting job
 func (suite *MockDatastoreTestSuite) TestDataStoreFailureGetJob() {
 	_, err := suite.store.GetMaxJobConfigVersion(
@@ -194,6 +173,8 @@ func (suite *MockDatastoreTestSuite) TestDataStoreFailureGetTaskRuntime() {
 
 // TestDataStoreFailureJobQuery tests datastore failures in job query
 func (suite *MockDatastoreTestSuite) TestDataStoreFailureJobQuery() {
+	suite.jobIndexOps.EXPECT().Query(gomock.Any(), nil, gomock.Any()).
+		Return(nil, uint32(0), errors.New("my-error"))
 	_, _, _, err := suite.store.QueryJobs(
 		context.Background(), nil, &job.QuerySpec{}, false)
 	suite.Error(err)
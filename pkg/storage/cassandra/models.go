@@ -164,6 +164,8 @@ type UpdateRecord struct {
 	UpdateTime           time.Time         `cql:"update_time"`
 	OpaqueData           string            `cql:"opaque_data"`
 	CompletionTime       string            `cql:"completion_time"`
+	CanaryCompletionTime string            `cql:"canary_completion_time"`
+	CanaryPromoted       bool              `cql:"canary_promoted"`
 }
 
 // GetUpdateConfig unmarshals and returns the configuration of the job update.
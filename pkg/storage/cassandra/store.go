@@ -18,14 +18,12 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"reflect"
 	"sort"
-	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	mesos_v1 "github.com/uber/peloton/.gen/mesos/v1"
@@ -44,15 +42,13 @@ import (
 	"github.com/uber/peloton/pkg/storage"
 	"github.com/uber/peloton/pkg/storage/cassandra/api"
 	"github.com/uber/peloton/pkg/storage/cassandra/impl"
+	"github.com/uber/peloton/pkg/storage/cassandra/migrator"
 	ormcassandra "github.com/uber/peloton/pkg/storage/connectors/cassandra"
 	ormobjects "github.com/uber/peloton/pkg/storage/objects"
 	qb "github.com/uber/peloton/pkg/storage/querybuilder"
 
-	_ "github.com/gemnasium/migrate/driver/cassandra" // Pull in C* driver for migrate
-	"github.com/gemnasium/migrate/migrate"
 	"github.com/gocql/gocql"
 	"github.com/gogo/protobuf/proto"
-	"github.com/golang/protobuf/ptypes"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/uber-go/tally"
@@ -77,9 +73,8 @@ const (
 	volumeTable            = "persistent_volumes"
 
 	// DB field names
-	creationTimeField   = "creation_time"
-	completionTimeField = "completion_time"
-	stateField          = "state"
+	creationTimeField = "creation_time"
+	stateField        = "state"
 
 	// Task query sort by field
 	hostField       = "host"
@@ -93,9 +88,9 @@ const (
 
 	_defaultWorkflowEventsDedupeWarnLimit = 1000
 
-	jobIndexTimeFormat        = "20060102150405"
-	jobQueryDefaultSpanInDays = 7
-	jobQueryJitter            = time.Second * 30
+	// _defaultMaxParallelReads is the maximum number of reads in flight,
+	// or of instances read with one query, for the batch read APIs
+	_defaultMaxParallelReads = 100
 
 	// _defaultPodEventsLimit is default number of pod events
 	// to read if not provided for jobID + instanceID
@@ -104,6 +99,10 @@ const (
 	// Default context timeout for the method to cleanup old
 	// job updates from the storage
 	_jobUpdatesCleanupTimeout = 120 * time.Second
+
+	// How long AutoMigrate waits for another instance to finish
+	// migrating the schema
+	autoMigrateLockWait = 10 * time.Minute
 )
 
 // Config is the config for cassandra Store
@@ -166,41 +165,39 @@ func ToOrmConfig(c *Config) *ormcassandra.Config {
 	}
 }
 
-type luceneClauses []string
-
 // AutoMigrate migrates the db schemas for cassandra
 func (c *Config) AutoMigrate() []error {
-	connString := c.MigrateString()
-	errs, ok := migrate.UpSync(connString, c.Migrations)
-	log.Infof("UpSync complete")
-	if !ok {
-		log.Errorf("UpSync failed with errors: %v", errs)
-		return errs
+	m, err := c.NewMigrator(autoMigrateLockWait)
+	if err != nil {
+		return []error{err}
 	}
+	defer m.Close()
+
+	applied, err := m.Up(0, false)
+	if err != nil {
+		log.WithError(err).Error("Schema migration failed")
+		return []error{err}
+	}
+	log.WithField("applied", len(applied)).Info("Schema migration complete")
 	return nil
 }
 
-// MigrateString returns the db string required for database migration
-// The code assumes that the keyspace (indicated by StoreName) is already created
-func (c *Config) MigrateString() string {
-	// see https://github.com/gemnasium/migrate/pull/17 on why disable_init_host_lookup is needed
-	// This is for making local testing faster with docker running on mac
-	connStr := fmt.Sprintf("cassandra://%v:%v/%v?protocol=4&disable_init_host_lookup",
-		c.CassandraConn.ContactPoints[0],
-		c.CassandraConn.Port,
-		c.StoreName)
-
-	if len(c.CassandraConn.Username) != 0 {
-		connStr = fmt.Sprintf("cassandra://%v:%v@%v:%v/%v",
-			c.CassandraConn.Username,
-			c.CassandraConn.Password,
-			c.CassandraConn.ContactPoints[0],
-			c.CassandraConn.Port,
-			c.StoreName)
+// NewMigrator returns a schema migrator for the keyspace, which waits up
+// to lockWait for other migrators to finish
+func (c *Config) NewMigrator(lockWait time.Duration) (*migrator.Migrator, error) {
+	session, err := impl.CreateSession(c.CassandraConn, c.StoreName)
+	if err != nil {
+		return nil, err
 	}
-	connStr = strings.Replace(connStr, " ", "", -1)
-	log.Infof("Cassandra migration string %v", connStr)
-	return connStr
+	m, err := migrator.New(
+		migrator.NewCassandraBackend(session, c.StoreName),
+		c.Migrations,
+		lockWait)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return m, nil
 }
 
 // Store implements JobStore, TaskStore, UpdateStore, FrameworkInfoStore,
@@ -210,6 +207,7 @@ func (c *Config) MigrateString() string {
 type Store struct {
 	DataStore          api.DataStore
 	jobConfigOps       ormobjects.JobConfigOps
+	jobIndexOps        ormobjects.JobIndexOps
 	jobRuntimeOps      ormobjects.JobRuntimeOps
 	jobUpdateEventsOps ormobjects.JobUpdateEventsOps
 	taskConfigV2Ops    ormobjects.TaskConfigV2Ops
@@ -238,6 +236,7 @@ func NewStore(config *Config, scope tally.Scope) (*Store, error) {
 		// DO NOT ADD MORE ORM Objects here. These are added here for
 		// supporting Job.Query() which cannot be fully moved to ORM
 		jobConfigOps:       ormobjects.NewJobConfigOps(ormStore),
+		jobIndexOps:        ormobjects.NewJobIndexOps(ormStore),
 		jobRuntimeOps:      ormobjects.NewJobRuntimeOps(ormStore),
 		jobUpdateEventsOps: ormobjects.NewJobUpdateEventsOps(ormStore),
 		taskConfigV2Ops:    ormobjects.NewTaskConfigV2Ops(ormStore),
@@ -417,287 +416,34 @@ func (s *Store) GetMaxJobConfigVersion(
 	return 0, nil
 }
 
-// WithTimeRangeFilter will take timerange and time_field (creation_time|completion_time) as
-// input and create a range filter on those fields and append to the clauses list
-func (c *luceneClauses) WithTimeRangeFilter(timeRange *peloton.TimeRange, timeField string) error {
-	if timeRange == nil || c == nil {
-		return nil
-	}
-	if timeField != creationTimeField && timeField != completionTimeField {
-		return fmt.Errorf("Invalid time field %s", timeField)
-	}
-	// Create filter if time range is not nil
-	min, err := ptypes.Timestamp(timeRange.GetMin())
-	if err != nil {
-		log.WithField("timeRange", timeRange).
-			WithField("timeField", timeField).
-			WithError(err).
-			Error("fail to get min time range")
-		return err
-	}
-	max, err := ptypes.Timestamp(timeRange.GetMax())
-	if err != nil {
-		log.WithField("timeRange", timeRange).
-			WithField("timeField", timeField).
-			WithError(err).
-			Error("fail to get max time range")
-		return err
-	}
-	// validate min and max limits are legit (i.e. max > min)
-	if max.Before(min) {
-		return fmt.Errorf("Incorrect timerange")
-	}
-	timeRangeMinStr := fmt.Sprintf(min.Format(jobIndexTimeFormat))
-	timeRangeMaxStr := fmt.Sprintf(max.Format(jobIndexTimeFormat))
-	*c = append(*c, fmt.Sprintf(`{type: "range", field:"%s", lower: "%s", upper: "%s", include_lower: true}`, timeField, timeRangeMinStr, timeRangeMaxStr))
-	return nil
-}
-
 // QueryJobs returns all jobs in the resource pool that matches the spec.
 func (s *Store) QueryJobs(ctx context.Context, respoolID *peloton.ResourcePoolID, spec *job.QuerySpec, summaryOnly bool) ([]*job.JobInfo, []*job.JobSummary, uint32, error) {
-	// Query is based on stratio lucene index on jobs.
-	// See https://github.com/Stratio/cassandra-lucene-index
-	// We are using "must" for the labels and only return the jobs that contains all
-	// label values
-	// TODO: investigate if there are any golang library that can build lucene query
-	var clauses luceneClauses
-
 	if spec == nil {
 		return nil, nil, 0, nil
 	}
 
-	// Labels field must contain value of the specified labels
-	for _, label := range spec.GetLabels() {
-		clauses = append(clauses, fmt.Sprintf(`{type: "contains", field:"labels", values:%s}`, strconv.Quote(label.Value)))
-	}
-
-	// jobconfig field must contain all specified keywords
-	for _, word := range spec.GetKeywords() {
-		// Lucene for some reason does wildcard search as case insensitive
-		// However, to match individual words we still need to match
-		// by exact keyword. Using boolean filter to do this.
-		// using the "should" syntax will enable us to match on either
-		// wildcard search or exact match
-		wildcardWord := fmt.Sprintf("*%s*", strings.ToLower(word))
-		clauses = append(clauses, fmt.Sprintf(
-			`{type: "boolean",`+
-				`should: [`+
-				`{type: "wildcard", field:"config", value:%s},`+
-				`{type: "match", field:"config", value:%s}`+
-				`]`+
-				`}`, strconv.Quote(wildcardWord), strconv.Quote(word)))
-	}
-
-	// Add support on query by job state
-	// queryTerminalStates will be set if the spec contains any
-	// terminal job state. In this case we will restrict the
-	// job query to query for jobs over the last 7 days.
-	// This is a temporary fix so that lucene index query doesn't
-	// time out when searching for ALL jobs with terminal states
-	//  which is a huge number.
-	// TODO (adityacb): change this once we have query spec support
-	// a custom time range
-	queryTerminalStates := false
-	if len(spec.GetJobStates()) > 0 {
-		values := ""
-		for i, s := range spec.GetJobStates() {
-			if util.IsPelotonJobStateTerminal(s) {
-				queryTerminalStates = true
-			}
-			values = values + strconv.Quote(s.String())
-			if i < len(spec.JobStates)-1 {
-				values = values + ","
-			}
-		}
-		clauses = append(clauses, fmt.Sprintf(`{type: "contains", field:"state", values:[%s]}`, values))
-	}
-
-	if respoolID != nil {
-		clauses = append(clauses, fmt.Sprintf(`{type: "contains", field:"respool_id", values:%s}`, strconv.Quote(respoolID.GetValue())))
-	}
-
-	owner := spec.GetOwner()
-	if owner != "" {
-		clauses = append(clauses, fmt.Sprintf(`{type: "match", field:"owner", value:%s}`, strconv.Quote(owner)))
-	}
-
-	name := spec.GetName()
-	if name != "" {
-		wildcardName := fmt.Sprintf("*%s*", name)
-		clauses = append(clauses, fmt.Sprintf(`{type: "wildcard", field:"name", value:%s}`, strconv.Quote(wildcardName)))
-	}
-
-	creationTimeRange := spec.GetCreationTimeRange()
-	completionTimeRange := spec.GetCompletionTimeRange()
-	err := clauses.WithTimeRangeFilter(creationTimeRange, creationTimeField)
-	if err != nil {
-		s.metrics.JobMetrics.JobQueryFail.Inc(1)
-		return nil, nil, 0, err
-	}
-
-	err = clauses.WithTimeRangeFilter(completionTimeRange, completionTimeField)
+	summaryResults, total, err := s.jobIndexOps.Query(ctx, respoolID, spec)
 	if err != nil {
-		s.metrics.JobMetrics.JobQueryFail.Inc(1)
-		return nil, nil, 0, err
-	}
-
-	// If no time range is specified in query spec, but the query is for terminal state,
-	// use default time range
-	if creationTimeRange == nil && completionTimeRange == nil && queryTerminalStates {
-		// Add jobQueryJitter to max bound to account for jobs
-		// that have just been created.
-		// if time range is not specified and the job is in terminal state,
-		// apply a default range of last 7 days
-		// TODO (adityacb): remove artificially enforcing default time range for
-		// completed jobs once UI supports query by time range.
-		now := time.Now().Add(jobQueryJitter).UTC()
-		max, err := ptypes.TimestampProto(now)
-		if err != nil {
-			s.metrics.JobMetrics.JobQueryFail.Inc(1)
-			return nil, nil, 0, err
-		}
-		min, err := ptypes.TimestampProto(now.AddDate(0, 0, -jobQueryDefaultSpanInDays))
-		if err != nil {
-			s.metrics.JobMetrics.JobQueryFail.Inc(1)
-			return nil, nil, 0, err
-		}
-		defaultCreationTimeRange := &peloton.TimeRange{Min: min, Max: max}
-		err = clauses.WithTimeRangeFilter(defaultCreationTimeRange, "creation_time")
-		if err != nil {
-			s.metrics.JobMetrics.JobQueryFail.Inc(1)
-			return nil, nil, 0, err
-		}
-	}
-
-	where := `expr(job_index_lucene_v2, '{filter: [`
-	for i, c := range clauses {
-		if i > 0 {
-			where += ", "
-		}
-		where += c
-	}
-	where += "]"
-
-	// add default sorting by creation time in descending order in case orderby
-	// is not specificed in the query spec
-	var orderBy = spec.GetPagination().GetOrderBy()
-	if orderBy == nil || len(orderBy) == 0 {
-		orderBy = []*query.OrderBy{
-			{
-				Order: query.OrderBy_DESC,
-				Property: &query.PropertyPath{
-					Value: "creation_time",
-				},
-			},
-		}
-	}
-
-	// add sorter into the query
-	where += ", sort:["
-	count := 0
-	for _, order := range orderBy {
-		where += fmt.Sprintf("{field: \"%s\"", order.Property.GetValue())
-		if order.Order == query.OrderBy_DESC {
-			where += ", reverse: true"
-		}
-		where += "}"
-		if count < len(orderBy)-1 {
-			where += ","
-		}
-		count++
-	}
-	where += "]"
-
-	where += "}')"
-
-	maxLimit := _defaultQueryMaxLimit
-	if spec.GetPagination().GetMaxLimit() != 0 {
-		maxLimit = spec.GetPagination().GetMaxLimit()
-	}
-	where += fmt.Sprintf(" Limit %d", maxLimit)
-
-	log.WithField("where", where).Debug("query string")
-
-	queryBuilder := s.DataStore.NewQuery()
-	stmt := queryBuilder.Select("job_id",
-		"name",
-		"owner",
-		"job_type",
-		"respool_id",
-		"instance_count",
-		"labels",
-		"runtime_info").
-		From(jobIndexTable)
-	stmt = stmt.Where(where)
-
-	allResults, err := s.executeRead(ctx, stmt)
-	if err != nil {
-		uql, args, _, _ := stmt.ToUql()
 		log.WithField("labels", spec.GetLabels()).
-			WithField("uql", uql).
-			WithField("args", args).
 			WithError(err).
 			Error("fail to query jobs")
 		s.metrics.JobMetrics.JobQueryFail.Inc(1)
 		return nil, nil, 0, err
 	}
 
-	total := uint32(len(allResults))
-
-	// Apply offset and limit.
-	begin := spec.GetPagination().GetOffset()
-	if begin > total {
-		begin = total
-	}
-	allResults = allResults[begin:]
-
-	end := _defaultQueryLimit
-	if spec.GetPagination() != nil {
-		limit := spec.GetPagination().GetLimit()
-		if limit > 0 {
-			// end should not be 0, it will yield in empty result
-			end = limit
-		}
-	}
-	if end > uint32(len(allResults)) {
-		end = uint32(len(allResults))
-	}
-	allResults = allResults[:end]
-
-	summaryResults, err := s.getJobSummaryFromResultMap(ctx, allResults)
 	if summaryOnly {
-		if err != nil {
-			s.metrics.JobMetrics.JobQueryFail.Inc(1)
-			return nil, nil, 0, err
-		}
-		// Lucene index entry for some batch jobs may be out of sync with the
-		// base job_index table. Scrub such jobs from the summary list.
-		summaryResults, err := s.reconcileStaleBatchJobsFromJobSummaryList(
-			ctx, summaryResults, queryTerminalStates)
-		if err != nil {
-			s.metrics.JobMetrics.JobQueryFail.Inc(1)
-			return nil, nil, 0, err
-		}
 		s.metrics.JobMetrics.JobQuery.Inc(1)
 		return nil, summaryResults, total, nil
 	}
 
 	var results []*job.JobInfo
-	for _, value := range allResults {
-		id, ok := value["job_id"].(qb.UUID)
-		if !ok {
-			s.metrics.JobMetrics.JobQueryFail.Inc(1)
-			return nil, nil, 0, fmt.Errorf("got invalid response from cassandra")
-		}
-
-		jobID := &peloton.JobID{
-			Value: id.String(),
-		}
+	for _, summary := range summaryResults {
+		jobID := summary.GetId()
 
 		jobRuntime, err := s.jobRuntimeOps.Get(ctx, jobID)
 		if err != nil {
 			log.WithError(err).
-				WithField("job_id", id.String()).
+				WithField("job_id", jobID.GetValue()).
 				Warn("no job runtime found when executing jobs query")
 			continue
 		}
@@ -705,7 +451,7 @@ func (s *Store) QueryJobs(ctx context.Context, respoolID *peloton.ResourcePoolID
 		jobConfig, _, err := s.jobConfigOps.GetCurrentVersion(ctx, jobID)
 		if err != nil {
 			log.WithField("labels", spec.GetLabels()).
-				WithField("job_id", id.String()).
+				WithField("job_id", jobID.GetValue()).
 				WithError(err).
 				Error("fail to query jobs as not able to get job config")
 			continue
@@ -759,12 +505,13 @@ func (s *Store) CreateTaskRuntime(
 			runtime.GetRevision().GetVersion(),
 			time.Now().UTC(),
 			runtime.GetState().String(),
-			runtimeBuffer)
-
-	// IfNotExist() will cause Writing task runtimes to Cassandra concurrently
-	// failed with Operation timed out issue when batch size is small, e.g. 1.
-	// For now, we have to drop the IfNotExist()
+			runtimeBuffer).
+		IfNotExist()
 
+	// The runtime is created with a lightweight transaction, same as the
+	// conditional updates of the runtime, since mixing plain writes with
+	// lightweight transactions on the same row is unsafe. Creating the
+	// runtime of an existing task fails with AlreadyExists.
 	taskID := fmt.Sprintf(taskIDFmt, jobID, instanceID)
 	if err := s.applyStatement(ctx, stmt, taskID); err != nil {
 		s.metrics.TaskMetrics.TaskCreateFail.Inc(1)
@@ -1455,19 +1202,42 @@ func (s *Store) GetTasksForJobByRange(ctx context.Context,
 
 	// map of instanceID -> task config
 	configMap := make(map[uint32]*task.TaskConfig)
+	var mu sync.Mutex
 
-	for configVersion, instances := range configVersions {
+	// read the configs of the different config versions in parallel
+	var versions []uint64
+	var versionIndexes []uint32
+	for configVersion := range configVersions {
+		versionIndexes = append(versionIndexes, uint32(len(versions)))
+		versions = append(versions, configVersion)
+	}
+	getConfigs := func(index uint32) error {
 		// Get the configs for a particular config version
-		configs, _, err := s.GetTaskConfigs(ctx, id, instances,
+		configVersion := versions[index]
+		configs, _, err := s.GetTaskConfigs(
+			ctx,
+			id,
+			configVersions[configVersion],
 			configVersion)
 		if err != nil {
-			return result, err
+			return err
 		}
 
 		// appends the configs
+		mu.Lock()
+		defer mu.Unlock()
 		for instanceID, config := range configs {
 			configMap[instanceID] = config
 		}
+		return nil
+	}
+	errs := util.RunInParallelWithLimit(
+		versionIndexes,
+		_defaultMaxParallelReads,
+		getConfigs)
+	// the task infos are incomplete if any config version failed
+	for _, err := range errs {
+		return result, err
 	}
 
 	// We have the task configs and the task runtimes, so we can
@@ -1512,6 +1282,96 @@ func (s *Store) GetTaskRuntime(ctx context.Context, jobID *peloton.JobID, instan
 	return runtime, err
 }
 
+// GetTaskRuntimes for a job and a list of instance ids. The runtimes
+// are read with IN queries on the partition of the job, each of at most
+// _defaultMaxParallelReads instances, and the instances which could not
+// be read are returned in the error map.
+func (s *Store) GetTaskRuntimes(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceIDs []uint32,
+) (map[uint32]*task.RuntimeInfo, map[uint32]error) {
+	runtimes := make(map[uint32]*task.RuntimeInfo)
+	errs := make(map[uint32]error)
+
+	for start := 0; start < len(instanceIDs); start += _defaultMaxParallelReads {
+		end := start + _defaultMaxParallelReads
+		if end > len(instanceIDs) {
+			end = len(instanceIDs)
+		}
+		s.getTaskRuntimesIn(ctx, jobID, instanceIDs[start:end], runtimes, errs)
+	}
+
+	for _, instanceID := range instanceIDs {
+		if _, ok := runtimes[instanceID]; ok {
+			continue
+		}
+		if _, ok := errs[instanceID]; ok {
+			continue
+		}
+		errs[instanceID] = yarpcerrors.NotFoundErrorf("task:%s not found",
+			fmt.Sprintf(taskIDFmt, jobID.GetValue(), int(instanceID)))
+		s.metrics.TaskMetrics.TaskNotFound.Inc(1)
+	}
+	return runtimes, errs
+}
+
+// getTaskRuntimesIn reads the runtimes of a list of instances of a job
+// with one query, and adds them to runtimes. The instances which could
+// not be read are added to errs, the ones not found are left out of both.
+func (s *Store) getTaskRuntimesIn(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceIDs []uint32,
+	runtimes map[uint32]*task.RuntimeInfo,
+	errs map[uint32]error,
+) {
+	// none of the runtimes can be read if the query fails
+	failAll := func(err error) {
+		for _, instanceID := range instanceIDs {
+			delete(runtimes, instanceID)
+			errs[instanceID] = err
+		}
+		s.metrics.TaskMetrics.TaskGetFail.Inc(int64(len(instanceIDs)))
+	}
+
+	stmt := s.DataStore.NewQuery().Select("*").From(taskRuntimeTable).
+		Where(qb.Eq{"job_id": jobID.GetValue(), "instance_id": instanceIDs})
+	allResults, err := s.executeRead(ctx, stmt)
+	if err != nil {
+		log.WithError(err).
+			WithField("job_id", jobID.GetValue()).
+			WithField("instance_ids", instanceIDs).
+			Error("failed to get task runtimes")
+		failAll(err)
+		return
+	}
+
+	for _, value := range allResults {
+		var record TaskRuntimeRecord
+		if err := FillObject(value, &record, reflect.TypeOf(record)); err != nil {
+			log.WithError(err).
+				WithField("job_id", jobID.GetValue()).
+				Error("failed to fill into task runtime record")
+			failAll(err)
+			return
+		}
+
+		instanceID := uint32(record.InstanceID)
+		runtime, err := record.GetTaskRuntime()
+		if err != nil {
+			log.WithError(err).
+				WithField("record", record).
+				Error("failed to parse task runtime from record")
+			errs[instanceID] = err
+			s.metrics.TaskMetrics.TaskGetFail.Inc(1)
+			continue
+		}
+		runtimes[instanceID] = runtime
+		s.metrics.TaskMetrics.TaskGet.Inc(1)
+	}
+}
+
 // UpdateTaskRuntime updates a task for a peloton job
 func (s *Store) UpdateTaskRuntime(
 	ctx context.Context,
@@ -1895,40 +1755,6 @@ func (s *Store) applyStatement(ctx context.Context, stmt api.Statement, itemName
 	return nil
 }
 
-// getJobSummaryFromIndex gets the job summary from job index table.
-// This is a helper function used by QueryJobs(). Do not use it for
-// anything other than QueryJobs; consider using ORM directly.
-// TODO Remove this when QueryJobs() uses ORM.
-func (s *Store) getJobSummaryFromIndex(
-	ctx context.Context, id *peloton.JobID) (*job.JobSummary, error) {
-	queryBuilder := s.DataStore.NewQuery()
-	stmt := queryBuilder.Select(
-		"job_id",
-		"name",
-		"owner",
-		"job_type",
-		"respool_id",
-		"instance_count",
-		"labels",
-		"runtime_info").
-		From(jobIndexTable).
-		Where(qb.Eq{"job_id": id.GetValue()})
-
-	allResults, err := s.executeRead(ctx, stmt)
-	if err != nil {
-		return nil, err
-	}
-	summary, err := s.getJobSummaryFromResultMap(ctx, allResults)
-	if err != nil {
-		return nil, err
-	}
-	if len(summary) != 1 {
-		return nil, yarpcerrors.FailedPreconditionErrorf(
-			"found %d jobs %v for job id %v", len(allResults), allResults, id)
-	}
-	return summary[0], nil
-}
-
 // Less function holds the task sorting logic
 func Less(orderByList []*query.OrderBy, t1 *task.TaskInfo, t2 *task.TaskInfo) bool {
 	// Keep comparing the two tasks by the field related with Order from the OrderbyList
@@ -2398,6 +2224,27 @@ func (s *Store) deleteWorkflowEvents(
 	return nil
 }
 
+// DeleteWorkflowEventsBefore deletes the workflow events for an update
+// and instance which were created before the given time
+func (s *Store) DeleteWorkflowEventsBefore(
+	ctx context.Context,
+	id *peloton.UpdateID,
+	instanceID uint32,
+	before time.Time) error {
+	queryBuilder := s.DataStore.NewQuery()
+	stmt := queryBuilder.Delete(podWorkflowEventsTable).
+		Where(qb.Eq{"update_id": id.GetValue()}).
+		Where(qb.Eq{"instance_id": int(instanceID)}).
+		Where("create_time < ?", qb.UUID{UUID: gocql.UUIDFromTime(before)})
+	if err := s.applyStatement(ctx, stmt, id.GetValue()); err != nil {
+		s.metrics.WorkflowMetrics.WorkflowEventsDeleteFail.Inc(1)
+		return err
+	}
+
+	s.metrics.WorkflowMetrics.WorkflowEventsDelete.Inc(1)
+	return nil
+}
+
 // TODO determine if this function should be part of storage or api handler.
 // cleanupPreviousUpdatesForJob cleans up the old job configurations
 // and updates. This is called when a new update is created, and ensures
@@ -2541,6 +2388,8 @@ func (s *Store) GetUpdate(ctx context.Context, id *peloton.UpdateID) (
 			UpdateTime:           record.UpdateTime.Format(time.RFC3339Nano),
 			OpaqueData:           &peloton.OpaqueData{Data: record.OpaqueData},
 			CompletionTime:       record.CompletionTime,
+			CanaryCompletionTime: record.CanaryCompletionTime,
+			CanaryPromoted:       record.CanaryPromoted,
 		}
 
 		s.metrics.UpdateMetrics.UpdateGet.Inc(1)
@@ -2658,6 +2507,15 @@ func (s *Store) WriteUpdateProgress(
 		stmt = stmt.Set("completion_time", updateInfo.GetCompletionTime())
 	}
 
+	if len(updateInfo.GetCanaryCompletionTime()) != 0 {
+		stmt = stmt.Set("canary_completion_time", updateInfo.GetCanaryCompletionTime())
+	}
+
+	// canary promotion cannot be undone, so only set it when true
+	if updateInfo.GetCanaryPromoted() {
+		stmt = stmt.Set("canary_promoted", true)
+	}
+
 	stmt = stmt.Where(qb.Eq{"update_id": updateInfo.GetUpdateID().GetValue()})
 
 	if err := s.applyStatement(
@@ -2756,15 +2614,17 @@ func (s *Store) GetUpdateProgress(ctx context.Context, id *peloton.UpdateID) (
 		}
 
 		updateInfo := &models.UpdateModel{
-			UpdateID:         id,
-			State:            update.State(update.State_value[record.State]),
-			PrevState:        update.State(update.State_value[record.PrevState]),
-			InstancesTotal:   uint32(record.InstancesTotal),
-			InstancesDone:    uint32(record.InstancesDone),
-			InstancesFailed:  uint32(record.InstancesFailed),
-			InstancesCurrent: record.GetProcessingInstances(),
-			UpdateTime:       record.UpdateTime.Format(time.RFC3339Nano),
-			CompletionTime:   record.CompletionTime,
+			UpdateID:             id,
+			State:                update.State(update.State_value[record.State]),
+			PrevState:            update.State(update.State_value[record.PrevState]),
+			InstancesTotal:       uint32(record.InstancesTotal),
+			InstancesDone:        uint32(record.InstancesDone),
+			InstancesFailed:      uint32(record.InstancesFailed),
+			InstancesCurrent:     record.GetProcessingInstances(),
+			UpdateTime:           record.UpdateTime.Format(time.RFC3339Nano),
+			CompletionTime:       record.CompletionTime,
+			CanaryCompletionTime: record.CanaryCompletionTime,
+			CanaryPromoted:       record.CanaryPromoted,
 		}
 
 		s.metrics.UpdateMetrics.UpdateGetProgess.Inc(1)
@@ -2833,92 +2693,6 @@ func parseTime(v string) time.Time {
 	return r
 }
 
-// If a BATCH job is in active state for more than a threshold of time, it is
-// possible that the lucene index is out of sync with the job_index table so we
-// can read job summary from job_index table for such jobs. This function goes
-// through a list of job summary and looks for such stale jobs. If the query is
-// for only active jobs, the stale jobs are skipped from the job summary list
-// and a new list is returned.
-func (s *Store) reconcileStaleBatchJobsFromJobSummaryList(
-	ctx context.Context, summaryList []*job.JobSummary,
-	queryTerminalStates bool) ([]*job.JobSummary, error) {
-	newSummaryList := []*job.JobSummary{}
-	var err error
-	for _, summary := range summaryList {
-		if summary.GetType() == job.JobType_BATCH &&
-			!util.IsPelotonJobStateTerminal(summary.GetRuntime().GetState()) &&
-			time.Since(
-				parseTime(summary.GetRuntime().GetCreationTime()),
-			) > common.StaleJobStateDurationThreshold {
-			// get job summary from DB table instead of index
-			summary, err = s.getJobSummaryFromIndex(ctx, summary.Id)
-			if err != nil {
-				return nil, err
-			}
-			if util.IsPelotonJobStateTerminal(
-				summary.GetRuntime().GetState()) &&
-				!queryTerminalStates {
-				// Since now the job shows up as terminal, we can conclude
-				// that lucene index entry for this job is stale. Because
-				// the query is for getting active jobs only, we can skip
-				// this job entry.
-				continue
-			}
-		}
-		newSummaryList = append(newSummaryList, summary)
-	}
-	return newSummaryList, nil
-}
-
-func (s *Store) getJobSummaryFromResultMap(
-	ctx context.Context, allResults []map[string]interface{},
-) ([]*job.JobSummary, error) {
-	var summaryResults []*job.JobSummary
-	for _, value := range allResults {
-		summary := &job.JobSummary{}
-		id, ok := value["job_id"].(qb.UUID)
-		if !ok {
-			return nil, yarpcerrors.InternalErrorf(
-				"invalid job_id %v", value["job_id"])
-		}
-		summary.Id = &peloton.JobID{Value: id.String()}
-		if name, ok := value["name"].(string); ok {
-			summary.Name = name
-		}
-		if runtimeInfo, ok := value["runtime_info"].(string); ok {
-			err := json.Unmarshal([]byte(runtimeInfo), &summary.Runtime)
-			if err != nil {
-				log.WithError(err).
-					WithField("runtime_info", runtimeInfo).
-					Info("failed to unmarshal runtime info")
-			}
-		}
-		if owningTeam, ok := value["owner"].(string); ok {
-			summary.Owner = owningTeam
-			summary.OwningTeam = owningTeam
-		}
-		if instcnt, ok := value["instance_count"].(int); ok {
-			summary.InstanceCount = uint32(instcnt)
-		}
-		if jobType, ok := value["job_type"].(int); ok {
-			summary.Type = job.JobType(jobType)
-		}
-		if respoolIDStr, ok := value["respool_id"].(string); ok {
-			summary.RespoolID = &peloton.ResourcePoolID{Value: respoolIDStr}
-		}
-		if labelBuffer, ok := value["labels"].(string); ok {
-			err := json.Unmarshal([]byte(labelBuffer), &summary.Labels)
-			if err != nil {
-				log.WithError(err).
-					WithField("labels", labelBuffer).
-					Info("failed to unmarshal labels")
-			}
-		}
-		summaryResults = append(summaryResults, summary)
-	}
-	return summaryResults, nil
-}
-
 // SortedTaskInfoList makes TaskInfo implement sortable interface
 type SortedTaskInfoList []*task.TaskInfo
 
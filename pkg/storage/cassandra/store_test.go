@@ -19,9 +19,7 @@ package cassandra
 import (
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"fmt"
-	"strings"
 	"testing"
 	"time"
 
@@ -66,6 +64,7 @@ type CassandraStoreTestSuite struct {
 var store *Store
 var testScope = tally.NewTestScope("", map[string]string{})
 var jobConfigOps ormobjects.JobConfigOps
+var jobIndexOps ormobjects.JobIndexOps
 var jobRuntimeOps ormobjects.JobRuntimeOps
 
 // This test vector borrowed from gzip test suite to simulate a checksum
@@ -101,6 +100,7 @@ func init() {
 	}
 
 	jobConfigOps = ormobjects.NewJobConfigOps(ormStore)
+	jobIndexOps = ormobjects.NewJobIndexOps(ormStore)
 	jobRuntimeOps = ormobjects.NewJobRuntimeOps(ormStore)
 }
 
@@ -160,7 +160,7 @@ func (suite *CassandraStoreTestSuite) createJob(
 	if err != nil {
 		return err
 	}
-	err = updateJobIndex(ctx, id, jobConfig, &initialJobRuntime)
+	err = jobIndexOps.Create(ctx, id, jobConfig, &initialJobRuntime, nil)
 	if err != nil {
 		return err
 	}
@@ -203,95 +203,6 @@ func createTaskConfigs(ctx context.Context, id *peloton.JobID, jobConfig *job.Jo
 	return nil
 }
 
-// updateJobIndex creates/updates job_index row for a job. This method
-// is provided for tests related to QueryJobs(), and should not be used
-// for anything else.
-// TODO Remove when QueryJobs() is moved to ORM.
-func updateJobIndex(
-	ctx context.Context,
-	id *peloton.JobID,
-	config *job.JobConfig,
-	runtime *job.RuntimeInfo,
-) error {
-	if runtime == nil && config == nil {
-		return nil
-	}
-
-	queryBuilder := store.DataStore.NewQuery()
-	stmt := queryBuilder.Update(jobIndexTable).
-		Where(qb.Eq{"job_id": id.GetValue()})
-
-	if runtime != nil {
-		runtimeBuffer, err := json.Marshal(runtime)
-		if err != nil {
-			return err
-		}
-
-		completeTime := time.Time{}
-		if runtime.GetCompletionTime() != "" {
-			completeTime, _ = time.Parse(
-				time.RFC3339Nano,
-				runtime.GetCompletionTime())
-		}
-
-		stmt = stmt.Set("runtime_info", runtimeBuffer).
-			Set("state", runtime.GetState().String()).
-			Set("creation_time", parseTime(runtime.GetCreationTime())).
-			Set("completion_time", completeTime).
-			Set("update_time", time.Now())
-	}
-
-	if config != nil {
-		// Do not save the instance config with the job
-		// configuration in the job_index table.
-		instanceConfig := config.GetInstanceConfig()
-		config.InstanceConfig = nil
-		configBuffer, err := json.Marshal(config)
-		config.InstanceConfig = instanceConfig
-		if err != nil {
-			return err
-		}
-
-		labelBuffer, err := json.Marshal(config.Labels)
-		if err != nil {
-			return err
-		}
-
-		stmt = stmt.Set("config", configBuffer).
-			Set("respool_id", config.GetRespoolID().GetValue()).
-			Set("owner", config.GetOwningTeam()).
-			Set("name", config.GetName()).
-			Set("job_type", uint32(config.GetType())).
-			Set("instance_count", uint32(config.GetInstanceCount())).
-			Set("labels", labelBuffer)
-	}
-
-	err := store.applyStatement(ctx, stmt, id.GetValue())
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// updateJobIndex deletes job_index row for a job. This method
-// is provided for tests related to QueryJobs(), and should not be used
-// for anything else.
-// TODO Remove when QueryJobs() is moved to ORM.
-func deleteJobIndex(ctx context.Context, id *peloton.JobID) error {
-	queryBuilder := store.DataStore.NewQuery()
-	stmt := queryBuilder.Delete(jobIndexTable).
-		Where(qb.Eq{"job_id": id.GetValue()})
-	return store.applyStatement(ctx, stmt, id.GetValue())
-}
-
-// Run the following query to trigger lucene index refresh
-func (suite *CassandraStoreTestSuite) refreshLuceneIndex() {
-	queryBuilder := store.DataStore.NewQuery()
-	stmt := queryBuilder.Select("*").From(jobIndexTable).Where("expr(job_index_lucene_v2, '{refresh:true}')")
-	_, err := store.DataStore.Execute(context.Background(), stmt)
-	suite.NoError(err)
-}
-
 // Test compress and uncompress functionality
 func (suite *CassandraStoreTestSuite) TestCompression() {
 	buf := []byte("Test data for compression")
@@ -314,22 +225,6 @@ func (suite *CassandraStoreTestSuite) TestCompression() {
 	suite.Equal(buf, uncompressedBuf)
 }
 
-// TestMigrateString tests MigrateString functionality
-func (suite *CassandraStoreTestSuite) TestMigrateString() {
-	conf := GenerateTestCassandraConfig()
-	conf.CassandraConn.Username = "user"
-	conf.CassandraConn.Password = "password"
-	expectedStr := fmt.Sprintf("cassandra://%v:%v@%v:%v/%v",
-		conf.CassandraConn.Username,
-		conf.CassandraConn.Password,
-		conf.CassandraConn.ContactPoints[0],
-		conf.CassandraConn.Port,
-		conf.StoreName)
-	expectedStr = strings.Replace(expectedStr, " ", "", -1)
-	connStr := conf.MigrateString()
-	suite.Equal(connStr, expectedStr)
-}
-
 // TestGetMaxJobConfigVersion tests get latest job version from job_config
 func (suite *CassandraStoreTestSuite) TestGetMaxJobConfigVersion() {
 	var jobStore storage.JobStore
@@ -415,8 +310,6 @@ func (suite *CassandraStoreTestSuite) TestQueryJobPaging() {
 		suite.NoError(err)
 	}
 
-	suite.refreshLuceneIndex()
-
 	spec := &job.QuerySpec{
 		Keywords: []string{"TestQueryJobPaging", "test", "awesome"},
 		Pagination: &query.PaginationSpec{
@@ -496,7 +389,7 @@ func (suite *CassandraStoreTestSuite) TestQueryJobPaging() {
 
 	for _, jobID := range jobIDs {
 		suite.NoError(jobStore.DeleteJob(context.Background(), jobID.GetValue()))
-		suite.NoError(deleteJobIndex(context.Background(), jobID))
+		suite.NoError(jobIndexOps.Delete(context.Background(), jobID))
 	}
 }
 
@@ -513,63 +406,6 @@ func (suite *CassandraStoreTestSuite) queryJobs(
 	return result, summary
 }
 
-func (suite *CassandraStoreTestSuite) TestJobQueryStaleLuceneIndex() {
-	jobID := peloton.JobID{Value: uuid.New()}
-	configAddOn := &models.ConfigAddOn{}
-	var jobConfig = job.JobConfig{
-		Name:        "StaleLuceneIndex",
-		OwningTeam:  "owner",
-		Type:        job.JobType_BATCH,
-		Description: fmt.Sprintf("get jobs summary"),
-	}
-	err := suite.createJob(context.Background(), &jobID, &jobConfig, configAddOn, "uber")
-	suite.NoError(err)
-
-	runtime, err := jobRuntimeOps.Get(context.Background(), &jobID)
-	suite.NoError(err)
-
-	// set job creation time to two days ago
-	creationTime := time.Now().AddDate(0, 0, -5).UTC().Format(time.RFC3339Nano)
-	runtime.CreationTime = creationTime
-	err = jobRuntimeOps.Upsert(context.Background(), &jobID, runtime)
-	suite.NoError(err)
-	err = updateJobIndex(context.Background(), &jobID, nil, runtime)
-	suite.NoError(err)
-	suite.refreshLuceneIndex()
-
-	jobStates := []job.JobState{
-		job.JobState_PENDING, job.JobState_RUNNING, job.JobState_INITIALIZED}
-	spec := &job.QuerySpec{
-		Name:      "StaleLuceneIndex",
-		JobStates: jobStates,
-	}
-	_, summary := suite.queryJobs(spec, 1, 1)
-	suite.Equal(creationTime, summary[0].GetRuntime().GetCreationTime())
-
-	// Set runtime state to succeeded and update the job_index
-	runtime.State = job.JobState_SUCCEEDED
-	err = jobRuntimeOps.Upsert(context.Background(), &jobID, runtime)
-	suite.NoError(err)
-	err = updateJobIndex(context.Background(), &jobID, nil, runtime)
-	suite.NoError(err)
-
-	// Now we have query from lucnene index showing the job as PENDING for 5days
-	// but the job_index table has been updated to SUCCEEDED. This should be
-	// reconciled and the final summary should not contain this job.
-	newSummary, err := store.reconcileStaleBatchJobsFromJobSummaryList(
-		context.Background(), summary, false)
-	suite.NoError(err)
-	suite.Equal(0, len(newSummary))
-
-	// Run reconciliation but for terminal job query. So the new summary list
-	// should contain the job but with its new terminal state.
-	newSummary, err = store.reconcileStaleBatchJobsFromJobSummaryList(
-		context.Background(), summary, true)
-	suite.NoError(err)
-	suite.Equal(1, len(newSummary))
-	suite.Equal(job.JobState_SUCCEEDED, newSummary[0].GetRuntime().GetState())
-}
-
 func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	var jobStore storage.JobStore
 	jobStore = store
@@ -591,24 +427,7 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	err := suite.createJob(context.Background(), &jobID, &jobConfig, configAddOn, "uber")
 	suite.NoError(err)
 
-	where := fmt.Sprintf(`expr(job_index_lucene_v2,` +
-		`'{refresh:true, filter: [` +
-		`{type: "match", field:"name", value:"GetJobSummary"}` +
-		`]}')`)
 	queryBuilder := store.DataStore.NewQuery()
-	stmt := queryBuilder.Select("job_id",
-		"name",
-		"owner",
-		"job_type",
-		"respool_id",
-		"instance_count",
-		"labels",
-		"runtime_info").
-		From(jobIndexTable)
-	stmt = stmt.Where(where)
-	_, err = store.executeRead(context.Background(), stmt)
-	suite.NoError(err)
-
 	spec := &job.QuerySpec{
 		Name: "GetJobSummaryByTimeRange",
 	}
@@ -619,8 +438,6 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	_, err = store.executeWrite(context.Background(), updateStmt)
 	suite.NoError(err)
 
-	suite.refreshLuceneIndex()
-
 	_, _ = suite.queryJobs(spec, 1, 1)
 
 	// Modify state to SUCCEEDED. Now this job should not show up.
@@ -630,8 +447,6 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	_, err = store.executeWrite(context.Background(), updateStmt)
 	suite.NoError(err)
 
-	suite.refreshLuceneIndex()
-
 	// Any query for terminal states should not display jobs that are older than 7 days
 	jobStates := []job.JobState{job.JobState_KILLED, job.JobState_FAILED, job.JobState_SUCCEEDED}
 	spec = &job.QuerySpec{
@@ -646,8 +461,6 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	_, err = store.executeWrite(context.Background(), updateStmt)
 	suite.NoError(err)
 
-	suite.refreshLuceneIndex()
-
 	_, _ = suite.queryJobs(spec, 0, 0)
 
 	updateStmt = queryBuilder.Update(jobIndexTable).
@@ -656,8 +469,6 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	_, err = store.executeWrite(context.Background(), updateStmt)
 	suite.NoError(err)
 
-	suite.refreshLuceneIndex()
-
 	_, _ = suite.queryJobs(spec, 0, 0)
 
 	updateStmt = queryBuilder.Update(jobIndexTable).
@@ -666,8 +477,6 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	_, err = store.executeWrite(context.Background(), updateStmt)
 	suite.NoError(err)
 
-	suite.refreshLuceneIndex()
-
 	// Any query for active states should display jobs that are older than 7 days
 	jobStates = []job.JobState{job.JobState_PENDING, job.JobState_RUNNING, job.JobState_INITIALIZED}
 	spec = &job.QuerySpec{
@@ -693,8 +502,6 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	_, err = store.executeWrite(context.Background(), updateStmt)
 	suite.NoError(err)
 
-	suite.refreshLuceneIndex()
-
 	_, _ = suite.queryJobs(spec, 1, 1)
 
 	// query by creation time ranges with last 5 days
@@ -727,8 +534,6 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	_, err = store.executeWrite(context.Background(), updateStmt)
 	suite.NoError(err)
 
-	suite.refreshLuceneIndex()
-
 	// query by creation time ranges with last 2 hours
 	now = time.Now().UTC()
 	max, _ = ptypes.TimestampProto(now)
@@ -779,8 +584,6 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	_, err = store.executeWrite(context.Background(), updateStmt)
 	suite.NoError(err)
 
-	suite.refreshLuceneIndex()
-
 	// again query by both creation and completion time ranges with last 2 hours
 	// The 1 hour old job here will show up in this query
 	_, _ = suite.queryJobs(spec, 1, 1)
@@ -794,7 +597,7 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummaryByTimeRange() {
 	_, _ = suite.queryJobs(spec, 1, 1)
 
 	suite.NoError(jobStore.DeleteJob(context.Background(), jobID.GetValue()))
-	suite.NoError(deleteJobIndex(context.Background(), &jobID))
+	suite.NoError(jobIndexOps.Delete(context.Background(), &jobID))
 }
 
 func (suite *CassandraStoreTestSuite) TestGetJobSummary() {
@@ -819,40 +622,6 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummary() {
 	err := suite.createJob(context.Background(), &jobID, &jobConfig, configAddOn, "uber")
 	suite.NoError(err)
 
-	where := fmt.Sprintf(`expr(job_index_lucene_v2,` +
-		`'{refresh:true, filter: [` +
-		`{type: "match", field:"name", value:"GetJobSummary"}` +
-		`]}')`)
-	queryBuilder := store.DataStore.NewQuery()
-	stmt := queryBuilder.Select("job_id",
-		"name",
-		"owner",
-		"job_type",
-		"respool_id",
-		"instance_count",
-		"labels",
-		"runtime_info").
-		From(jobIndexTable)
-	stmt = stmt.Where(where)
-	allResults, err := store.executeRead(context.Background(), stmt)
-	suite.NoError(err)
-	suite.Equal(1, len(allResults))
-
-	summaryResultFromLucene, err := store.getJobSummaryFromResultMap(
-		context.Background(), allResults)
-	suite.NoError(err)
-
-	suite.Equal(1, len(summaryResultFromLucene))
-	suite.Equal("GetJobSummary", summaryResultFromLucene[0].GetName())
-	suite.Equal("owner", summaryResultFromLucene[0].GetOwningTeam())
-	suite.Equal("owner", summaryResultFromLucene[0].GetOwner())
-
-	// tamper allResults to make job_id not UUID and look for error.
-	allResults[0]["job_id"] = "junk"
-	_, err = store.getJobSummaryFromResultMap(
-		context.Background(), allResults)
-	suite.Error(err)
-
 	// QueryJobs with summaryOnly = true. result1 should be nil
 	spec := &job.QuerySpec{
 		Name: "GetJobSummary",
@@ -863,6 +632,8 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummary() {
 	suite.Equal(1, len(summary))
 	suite.Equal(1, int(total))
 	suite.Equal("GetJobSummary", summary[0].GetName())
+	suite.Equal("owner", summary[0].GetOwningTeam())
+	suite.Equal("owner", summary[0].GetOwner())
 
 	// query with spec = nil should not result in error, it should result in 0 entries.
 	_, summary, total, err = jobStore.QueryJobs(context.Background(), nil, nil, true)
@@ -871,7 +642,7 @@ func (suite *CassandraStoreTestSuite) TestGetJobSummary() {
 	suite.Equal(0, int(total))
 
 	suite.NoError(jobStore.DeleteJob(context.Background(), jobID.GetValue()))
-	suite.NoError(deleteJobIndex(context.Background(), &jobID))
+	suite.NoError(jobIndexOps.Delete(context.Background(), &jobID))
 }
 
 func (suite *CassandraStoreTestSuite) TestQueryJob() {
@@ -938,12 +709,10 @@ func (suite *CassandraStoreTestSuite) TestQueryJob() {
 		err = jobRuntimeOps.Upsert(context.Background(), &jobID, runtime)
 		suite.NoError(err)
 
-		err = updateJobIndex(context.Background(), &jobID, nil, runtime)
+		err = jobIndexOps.Update(context.Background(), &jobID, nil, runtime)
 		suite.NoError(err)
 	}
 
-	suite.refreshLuceneIndex()
-
 	// query by common label should return all jobs
 	spec := &job.QuerySpec{
 		Labels: []*peloton.Label{
@@ -1197,8 +966,6 @@ func (suite *CassandraStoreTestSuite) TestQueryJob() {
 		jobRuntimeOps.Upsert(context.Background(), jobIDs[i], runtime)
 	}
 
-	suite.refreshLuceneIndex()
-
 	jobStates := []job.JobState{job.JobState_PENDING, job.JobState_RUNNING, job.JobState_SUCCEEDED}
 	spec = &job.QuerySpec{
 		Labels: []*peloton.Label{
@@ -1218,7 +985,7 @@ func (suite *CassandraStoreTestSuite) TestQueryJob() {
 	_, _ = suite.queryJobs(spec, len(jobStates), len(jobStates))
 	for _, jobID := range jobIDs {
 		suite.NoError(jobStore.DeleteJob(context.Background(), jobID.GetValue()))
-		suite.NoError(deleteJobIndex(context.Background(), jobID))
+		suite.NoError(jobIndexOps.Delete(context.Background(), jobID))
 	}
 }
 
@@ -2198,7 +1965,7 @@ func (suite *CassandraStoreTestSuite) TestUpdate() {
 
 	// delete the job
 	store.DeleteJob(context.Background(), jobID.GetValue())
-	suite.NoError(deleteJobIndex(context.Background(), jobID))
+	suite.NoError(jobIndexOps.Delete(context.Background(), jobID))
 
 	// make sure update is not found
 	_, err = store.GetUpdate(
@@ -2440,7 +2207,7 @@ func (suite *CassandraStoreTestSuite) TestModifyUpdate() {
 
 	// delete the job
 	suite.NoError(store.DeleteJob(context.Background(), jobID.GetValue()))
-	suite.NoError(deleteJobIndex(context.Background(), jobID))
+	suite.NoError(jobIndexOps.Delete(context.Background(), jobID))
 
 	updateResult, err = store.GetUpdate(context.Background(), updateID)
 	suite.Error(err)
@@ -2533,6 +2300,47 @@ func (suite *CassandraStoreTestSuite) TestGetTaskRuntime() {
 	suite.Equal(info.Runtime, runtime)
 }
 
+// TestGetTaskRuntimes tests reading the runtimes of several tasks
+func (suite *CassandraStoreTestSuite) TestGetTaskRuntimes() {
+	jobID := &peloton.JobID{Value: uuid.NewRandom().String()}
+	// more instances than read with one query
+	n := uint32(_defaultMaxParallelReads + 2)
+	var instanceIDs []uint32
+	for i := uint32(0); i < n; i++ {
+		tID := fmt.Sprintf("%s-%d-%d", jobID.GetValue(), i, 1)
+		suite.NoError(store.CreateTaskRuntime(
+			context.Background(),
+			jobID,
+			i,
+			&task.RuntimeInfo{
+				MesosTaskId: &mesos.TaskID{Value: &tID},
+				State:       task.TaskState_PENDING,
+			},
+			"",
+			job.JobType_BATCH))
+		instanceIDs = append(instanceIDs, i)
+	}
+
+	runtimes, errs := store.GetTaskRuntimes(
+		context.Background(),
+		jobID,
+		append(instanceIDs, n+3))
+	suite.Len(runtimes, int(n))
+	for i := uint32(0); i < n; i++ {
+		suite.Equal(
+			fmt.Sprintf("%s-%d-%d", jobID.GetValue(), i, 1),
+			runtimes[i].GetMesosTaskId().GetValue())
+	}
+
+	// the missing instance is reported as not found
+	suite.Len(errs, 1)
+	suite.True(yarpcerrors.IsNotFound(errs[n+3]))
+
+	runtimes, errs = store.GetTaskRuntimes(context.Background(), jobID, nil)
+	suite.Empty(runtimes)
+	suite.Empty(errs)
+}
+
 func (suite *CassandraStoreTestSuite) TestTaskQueryFilter() {
 	var taskStore storage.TaskStore
 	taskStore = store
@@ -3288,6 +3096,15 @@ func (suite *CassandraStoreTestSuite) TestCreateTaskRuntimeForServiceJob() {
 		jobConfig.GetType())
 	suite.NoError(err)
 
+	// the runtime of an existing task is not overwritten
+	err = taskStore.CreateTaskRuntime(
+		context.Background(),
+		&jobID,
+		0,
+		taskInfo.Runtime,
+		"test",
+		jobConfig.GetType())
+	suite.True(yarpcerrors.IsAlreadyExists(err))
 }
 
 func (suite *CassandraStoreTestSuite) TestGetTasksForJobError() {
@@ -3386,3 +3203,44 @@ func (suite *CassandraStoreTestSuite) TestDeleteTaskConfig() {
 		suite.Error(err)
 	}
 }
+
+// TestDeleteWorkflowEventsBefore tests deleting the workflow events of an
+// update and instance older than a given time
+func (suite *CassandraStoreTestSuite) TestDeleteWorkflowEventsBefore() {
+	updateID := &peloton.UpdateID{Value: uuid.New()}
+	suite.NoError(store.AddWorkflowEvent(
+		context.Background(),
+		updateID,
+		0,
+		models.WorkflowType_UPDATE,
+		update.State_ROLLING_FORWARD))
+
+	// events created after the given time are kept
+	suite.NoError(store.DeleteWorkflowEventsBefore(
+		context.Background(),
+		updateID,
+		0,
+		time.Now().Add(-time.Hour)))
+	workflowEvents, err := store.GetWorkflowEvents(
+		context.Background(),
+		updateID,
+		0,
+		0,
+	)
+	suite.NoError(err)
+	suite.Len(workflowEvents, 1)
+
+	suite.NoError(store.DeleteWorkflowEventsBefore(
+		context.Background(),
+		updateID,
+		0,
+		time.Now().Add(time.Minute)))
+	workflowEvents, err = store.GetWorkflowEvents(
+		context.Background(),
+		updateID,
+		0,
+		0,
+	)
+	suite.NoError(err)
+	suite.Empty(workflowEvents)
+}
@@ -37,12 +37,14 @@ const (
 
 const (
 	// operation tags for metrics
-	create  = "create"
-	cas     = "cas"
-	get     = "get"
-	getIter = "get_iter"
-	update  = "update"
-	del     = "delete"
+	create   = "create"
+	cas      = "cas"
+	get      = "get"
+	getIter  = "get_iter"
+	getIn    = "get_in"
+	update   = "update"
+	updateIf = "update_if"
+	del      = "delete"
 )
 
 type cassandraConnector struct {
@@ -426,6 +428,58 @@ func (c *cassandraConnector) GetAllIter(
 	), nil
 }
 
+// GetIn fetches the rows matching the keys whose inColumn takes one of
+// the inValues. The values are bound as a single list, so inColumn must
+// be the last column of either the partition or the clustering key.
+func (c *cassandraConnector) GetIn(
+	ctx context.Context,
+	e *base.Definition,
+	keyCols []base.Column,
+	inColumn string,
+	inValues []interface{},
+) (rows [][]base.Column, err error) {
+	if len(inValues) == 0 {
+		return nil, nil
+	}
+
+	colNamesToRead := e.GetColumnsToRead()
+	keyColNames, keyColValues := splitColumnNameValue(keyCols)
+
+	stmt, err := SelectInStmt(
+		Table(e.Name),
+		Columns(colNamesToRead),
+		Conditions(keyColNames),
+		In(inColumn),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	q := c.Session.Query(stmt, append(keyColValues, inValues)...).
+		WithContext(ctx)
+	cqlIter := q.Iter()
+	sendLatency(c.scope, e.Name, getIn, time.Duration(q.Latency()))
+
+	iter := newIterator(
+		e,
+		colNamesToRead,
+		c.executeSuccessScope,
+		c.executeFailScope,
+		cqlIter,
+	)
+	defer iter.Close()
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			return rows, nil
+		}
+		rows = append(rows, row)
+	}
+}
+
 // Delete deletes a record from DB using primary keys
 func (c *cassandraConnector) Delete(
 	ctx context.Context,
@@ -504,6 +558,55 @@ func (c *cassandraConnector) Update(
 	return nil
 }
 
+// UpdateIf updates an existing row in DB only if the given conditions
+// hold for the row. Uses CAS write.
+func (c *cassandraConnector) UpdateIf(
+	ctx context.Context,
+	e *base.Definition,
+	row []base.Column,
+	keyCols []base.Column,
+	conditions []base.Column,
+) error {
+
+	// split keyCols, row and conditions into lists of names and values to
+	// compose query stmt using names and use values in the session query
+	// call, so the order needs to be maintained.
+	keyColNames, keyColValues := splitColumnNameValue(keyCols)
+	colNames, colValues := splitColumnNameValue(row)
+	condColNames, condColValues := splitColumnNameValue(conditions)
+
+	// Prepare conditional update statement
+	stmt, err := UpdateStmt(
+		Table(e.Name),
+		Updates(colNames),
+		Conditions(keyColNames),
+		IfConditions(condColNames),
+	)
+	if err != nil {
+		return err
+	}
+
+	// list of values to be supplied in the query
+	updateVals := append(colValues, keyColValues...)
+	updateVals = append(updateVals, condColValues...)
+
+	q := c.Session.Query(
+		stmt, updateVals...).WithContext(ctx)
+
+	applied, err := q.MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		sendCounters(c.executeFailScope, e.Name, updateIf, err)
+		return err
+	}
+	if !applied {
+		return yarpcerrors.AbortedErrorf("update condition not met")
+	}
+
+	sendLatency(c.scope, e.Name, updateIf, time.Duration(q.Latency()))
+	sendCounters(c.executeSuccessScope, e.Name, updateIf, nil)
+	return nil
+}
+
 // cassandraIterator implements interface Iterator for Cassandra
 type cassandraIterator struct {
 	cqlIter        *gocql.Iter
@@ -322,6 +322,57 @@ func (suite *CassandraConnSuite) TestCreateGetAll() {
 	}
 }
 
+// TestCreateGetIn tests the GetIn operation
+func (suite *CassandraConnSuite) TestCreateGetIn() {
+	// Definition stores schema information about an Object
+	obj := &base.Definition{
+		Name: testTableName2,
+		Key: &base.PrimaryKey{
+			PartitionKeys: []string{"id"},
+			ClusteringKeys: []*base.ClusteringKey{
+				{
+					Name:       "ck",
+					Descending: true,
+				},
+			},
+		},
+		// Column name to data type mapping of the object
+		ColumnToType: map[string]reflect.Type{
+			"id":   reflect.TypeOf(1),
+			"ck":   reflect.TypeOf(1),
+			"data": reflect.TypeOf("data"),
+			"name": reflect.TypeOf("name"),
+		},
+	}
+
+	// create the test rows in C*
+	for _, row := range testRowsWithCK {
+		err := connector.Create(context.Background(), obj, row)
+		suite.NoError(err)
+	}
+
+	// read one of the rows and a missing row of the partition
+	rows, err := connector.GetIn(
+		context.Background(),
+		obj,
+		keyRow,
+		"ck",
+		[]interface{}{uint64(20), uint64(30)},
+	)
+	suite.NoError(err)
+	suite.Len(rows, 1)
+	for _, col := range rows[0] {
+		if col.Name == "ck" {
+			suite.Equal(20, *col.Value.(*int))
+		}
+	}
+
+	// no values to read
+	rows, err = connector.GetIn(context.Background(), obj, keyRow, "ck", nil)
+	suite.NoError(err)
+	suite.Empty(rows)
+}
+
 // TestCreateGetAllIter tests the GetAllIter operation
 func (suite *CassandraConnSuite) TestCreateGetAllIter() {
 	// Definition stores schema information about an Object
@@ -466,6 +517,59 @@ func (suite *CassandraConnSuite) TestCreateIfNotExists() {
 	suite.True(yarpcerrors.IsAlreadyExists(err))
 }
 
+// TestCreateUpdateIf tests the conditional update operation
+func (suite *CassandraConnSuite) TestCreateUpdateIf() {
+	// Definition stores schema information about an Object
+	obj := &base.Definition{
+		Name: testTableName1,
+		Key: &base.PrimaryKey{
+			PartitionKeys: []string{"id"},
+		},
+		// Column name to data type mapping of the object
+		ColumnToType: map[string]reflect.Type{
+			"id":   reflect.TypeOf(1),
+			"data": reflect.TypeOf("data"),
+			"name": reflect.TypeOf("name"),
+		},
+	}
+	err := connector.Create(context.Background(), obj, testRow)
+	suite.NoError(err)
+
+	testUpdateRow := []base.Column{
+		{
+			Name:  "name",
+			Value: "test-update-if",
+		},
+	}
+
+	// condition does not match the stored row, update should be aborted
+	err = connector.UpdateIf(
+		context.Background(), obj, testUpdateRow, keyRow,
+		[]base.Column{{Name: "name", Value: "not-test"}})
+	suite.True(yarpcerrors.IsAborted(err))
+
+	// condition matches the stored row, update should succeed
+	err = connector.UpdateIf(
+		context.Background(), obj, testUpdateRow, keyRow,
+		[]base.Column{{Name: "name", Value: "test"}})
+	suite.NoError(err)
+
+	row, err := connector.Get(context.Background(), obj, keyRow)
+	suite.NoError(err)
+	for _, col := range row {
+		if col.Name == "name" {
+			name := col.Value.(*string)
+			suite.Equal("test-update-if", *name)
+		}
+	}
+
+	// row no longer matches the old condition
+	err = connector.UpdateIf(
+		context.Background(), obj, testUpdateRow, keyRow,
+		[]base.Column{{Name: "name", Value: "test"}})
+	suite.True(yarpcerrors.IsAborted(err))
+}
+
 // TestCreateDBFailures tests failures executing DB query
 func (suite *CassandraConnSuite) TestDBFailures() {
 	// Definition stores schema information about an Object
@@ -496,6 +600,14 @@ func (suite *CassandraConnSuite) TestDBFailures() {
 	err = connector.Update(ctx, obj, testRow, keyRow)
 	suite.Error(err)
 
+	// conditional update using wrong table name
+	err = connector.UpdateIf(ctx, obj, testRow, keyRow, testRow)
+	suite.Error(err)
+
+	// get in using wrong table name
+	_, err = connector.GetIn(ctx, obj, nil, "id", []interface{}{1})
+	suite.Error(err)
+
 	// delete using wrong table name
 	err = connector.Delete(ctx, obj, keyRow)
 	suite.Error(err)
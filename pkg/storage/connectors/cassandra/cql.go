@@ -35,6 +35,10 @@ const (
 	updates = "Updates"
 	// ifNotExist is used to indicate CAS write in the insert query
 	ifNotExist = "IfNotExist"
+	// ifConditions is used to indicate CAS conditions in the update query
+	ifConditions = "IfConditions"
+	// in is used to indicate the column of an IN condition in the query
+	in = "In"
 
 	// insertTemplate is used to construct an insert query
 	insertTemplate = `INSERT INTO {{.Table}} ({{ColumnFunc .Columns ", "}})` +
@@ -44,13 +48,19 @@ const (
 	selectTemplate = `SELECT {{ColumnFunc .Columns ", "}} FROM {{.Table}}` +
 		`{{WhereFunc .Conditions}}{{ConditionsFunc .Conditions " AND "}};`
 
+	// selectInTemplate is used to construct a select query with an IN
+	// condition, whose values are bound as a single list
+	selectInTemplate = `SELECT {{ColumnFunc .Columns ", "}} FROM {{.Table}}` +
+		` WHERE {{ConditionsFunc .Conditions " AND "}}{{InFunc .Conditions .In}};`
+
 	// deleteTemplate is used to construct a delete query
 	deleteTemplate = `DELETE FROM {{.Table}} WHERE ` +
 		`{{ConditionsFunc .Conditions " AND "}};`
 
 	// updateTemplate is used to construct update query
 	updateTemplate = `UPDATE {{.Table}} SET {{ConditionsFunc .Updates ", "}}` +
-		`{{WhereFunc .Conditions}}{{ConditionsFunc .Conditions " AND "}};`
+		`{{WhereFunc .Conditions}}{{ConditionsFunc .Conditions " AND "}}` +
+		`{{IfFunc .IfConditions}}{{ConditionsFunc .IfConditions " AND "}};`
 )
 
 var (
@@ -61,6 +71,8 @@ var (
 		"ConditionsFunc": conditionsFunc,
 		"WhereFunc":      whereFunc,
 		"ExistsFunc":     existsFunc,
+		"IfFunc":         ifFunc,
+		"InFunc":         inFunc,
 	}
 
 	// insert CQL query template implementation
@@ -69,6 +81,9 @@ var (
 	// select CQL query template implementation
 	selectTmpl = template.Must(
 		template.New("select").Funcs(funcMap).Parse(selectTemplate))
+	// select with IN condition CQL query template implementation
+	selectInTmpl = template.Must(
+		template.New("selectIn").Funcs(funcMap).Parse(selectInTemplate))
 	// delete CQL query template implementation
 	deleteTmpl = template.Must(
 		template.New("delete").Funcs(funcMap).Parse(deleteTemplate))
@@ -111,6 +126,23 @@ func existsFunc(exists bool) string {
 	return ""
 }
 
+// ifFunc adds an if clause to the update query
+func ifFunc(conds []string) string {
+	if len(conds) > 0 {
+		return " IF "
+	}
+	return ""
+}
+
+// inFunc adds an IN condition on the column to the select query
+func inFunc(conds []string, column string) string {
+	cond := fmt.Sprintf("%s IN ?", column)
+	if len(conds) > 0 {
+		return " AND " + cond
+	}
+	return cond
+}
+
 // Option to compose a cql statement
 type Option map[string]interface{}
 
@@ -163,6 +195,20 @@ func IfNotExist(v interface{}) OptFunc {
 	}
 }
 
+// IfConditions sets the `if` clause to the cql statement
+func IfConditions(v interface{}) OptFunc {
+	return func(opt Option) {
+		opt[ifConditions] = v
+	}
+}
+
+// In sets the column of the `IN` condition of the cql statement
+func In(v string) OptFunc {
+	return func(opt Option) {
+		opt[in] = v
+	}
+}
+
 // InsertStmt creates insert statement
 func InsertStmt(opts ...OptFunc) (string, error) {
 	var bb bytes.Buffer
@@ -185,6 +231,17 @@ func SelectStmt(opts ...OptFunc) (string, error) {
 	return bb.String(), err
 }
 
+// SelectInStmt creates select statement with an IN condition
+func SelectInStmt(opts ...OptFunc) (string, error) {
+	var bb bytes.Buffer
+	option := Option{}
+	for _, opt := range opts {
+		opt(option)
+	}
+	err := selectInTmpl.Execute(&bb, option)
+	return bb.String(), err
+}
+
 // DeleteStmt creates delete statement
 func DeleteStmt(opts ...OptFunc) (string, error) {
 	var bb bytes.Buffer
@@ -108,6 +108,44 @@ func (suite *CassandraConnSuite) TestSelectStmt() {
 	}
 }
 
+// TestSelectInStmt tests constructing select CQL query with an IN
+// condition
+func (suite *CassandraConnSuite) TestSelectInStmt() {
+	data := []struct {
+		table   string
+		cols    []string
+		keyCols []string
+		in      string
+		stmt    string
+	}{
+		{
+			table:   "table1",
+			cols:    []string{"c1", "c2"},
+			keyCols: []string{"c3", "c4"},
+			in:      "c5",
+			stmt: "SELECT \"c1\", \"c2\" FROM \"table1\"" +
+				" WHERE c3=? AND c4=? AND c5 IN ?;",
+		},
+		{
+			table:   "table2",
+			cols:    []string{"c1"},
+			keyCols: []string{},
+			in:      "c2",
+			stmt:    "SELECT \"c1\" FROM \"table2\" WHERE c2 IN ?;",
+		},
+	}
+	for _, d := range data {
+		stmt, err := SelectInStmt(
+			Table(d.table),
+			Columns(d.cols),
+			Conditions(d.keyCols),
+			In(d.in),
+		)
+		suite.NoError(err)
+		suite.Equal(stmt, d.stmt)
+	}
+}
+
 // TestDeleteStmt tests constructing delete CQL query
 func (suite *CassandraConnSuite) TestDeleteStmt() {
 
@@ -197,3 +235,49 @@ func (suite *CassandraConnSuite) TestUpdateStmt() {
 		suite.Equal(stmt, d.stmt)
 	}
 }
+
+// TestUpdateStmtWithIfConditions tests constructing the conditional update
+// statement
+func (suite *CassandraConnSuite) TestUpdateStmtWithIfConditions() {
+	data := []struct {
+		table   string
+		cols    []string
+		keyCols []string
+		ifCols  []string
+		stmt    string
+	}{
+		{
+			table:   "table1",
+			cols:    []string{"c1", "c2"},
+			keyCols: []string{"c3", "c4"},
+			ifCols:  []string{"c5"},
+			stmt: "UPDATE \"table1\" SET c1=?, c2=? WHERE c3=? AND c4=?" +
+				" IF c5=?;",
+		},
+		{
+			table:   "table2",
+			cols:    []string{"c1"},
+			keyCols: []string{"c2"},
+			ifCols:  []string{"c3", "c4"},
+			stmt: "UPDATE \"table2\" SET c1=? WHERE c2=?" +
+				" IF c3=? AND c4=?;",
+		},
+		{
+			table:   "table3",
+			cols:    []string{"c1"},
+			keyCols: []string{"c2"},
+			ifCols:  []string{},
+			stmt:    "UPDATE \"table3\" SET c1=? WHERE c2=?;",
+		},
+	}
+	for _, d := range data {
+		stmt, err := UpdateStmt(
+			Table(d.table),
+			Updates(d.cols),
+			Conditions(d.keyCols),
+			IfConditions(d.ifCols),
+		)
+		suite.NoError(err)
+		suite.Equal(stmt, d.stmt)
+	}
+}
@@ -0,0 +1,490 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	"github.com/uber/peloton/pkg/storage/orm"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// row is a row of a table, from column name to value
+type row map[string]interface{}
+
+// table holds the rows of a table by primary key
+type table struct {
+	rows map[string]row
+}
+
+type memoryConnector struct {
+	sync.RWMutex
+
+	// tables by name
+	tables map[string]*table
+}
+
+// NewMemoryConnector creates a Connector which keeps the rows in memory,
+// with the same partition and clustering key semantics as the Cassandra
+// connector. It is safe for concurrent use and meant for tests.
+func NewMemoryConnector() orm.Connector {
+	return &memoryConnector{
+		tables: make(map[string]*table),
+	}
+}
+
+// ensure that implementation (memoryConnector) satisfies the interface
+var _ orm.Connector = (*memoryConnector)(nil)
+
+// normalize converts a column value to the type the Cassandra connector
+// would read it back as, so that objects are read the same way
+func normalize(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.Slice:
+		if b, ok := value.([]byte); ok {
+			// copy so that the caller cannot modify the stored value
+			return append([]byte{}, b...)
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return normalize(v.Elem().Interface())
+	}
+	return value
+}
+
+// compare compares two normalized values of the same column
+func compare(a, b interface{}) int {
+	switch av := a.(type) {
+	case int64:
+		bv, _ := b.(int64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	case string:
+		bv, _ := b.(string)
+		return strings.Compare(av, bv)
+	case []byte:
+		bv, _ := b.([]byte)
+		return bytes.Compare(av, bv)
+	case time.Time:
+		bv, _ := b.(time.Time)
+		switch {
+		case av.Before(bv):
+			return -1
+		case av.After(bv):
+			return 1
+		}
+		return 0
+	case bool:
+		bv, _ := b.(bool)
+		switch {
+		case av == bv:
+			return 0
+		case !av:
+			return -1
+		}
+		return 1
+	}
+	if b == nil {
+		return 0
+	}
+	return -1
+}
+
+// primaryKeyColumns returns the names of the partition and
+// clustering keys of the object
+func primaryKeyColumns(e *base.Definition) []string {
+	keys := append([]string{}, e.Key.PartitionKeys...)
+	for _, ck := range e.Key.ClusteringKeys {
+		keys = append(keys, ck.Name)
+	}
+	return keys
+}
+
+// toRow converts a list of columns to a row of normalized values
+func toRow(columns []base.Column) row {
+	r := make(row, len(columns))
+	for _, column := range columns {
+		r[column.Name] = normalize(column.Value)
+	}
+	return r
+}
+
+// primaryKey encodes the primary key of a row, which must have
+// all the primary key columns
+func primaryKey(e *base.Definition, r row) (string, error) {
+	var parts []string
+	for _, k := range primaryKeyColumns(e) {
+		value, ok := r[k]
+		if !ok || value == nil {
+			return "", yarpcerrors.InvalidArgumentErrorf(
+				"missing primary key column %s of table %s", k, e.Name)
+		}
+		parts = append(parts, fmt.Sprintf("%T:%v", value, value))
+	}
+	return strings.Join(parts, "\x00"), nil
+}
+
+// checkPartitionKeys checks that the key columns restrict the rows to
+// a partition, as required by Cassandra
+func checkPartitionKeys(e *base.Definition, keys row) error {
+	for _, k := range e.Key.PartitionKeys {
+		if _, ok := keys[k]; !ok {
+			return yarpcerrors.InvalidArgumentErrorf(
+				"missing partition key column %s of table %s", k, e.Name)
+		}
+	}
+	return nil
+}
+
+// getTable returns the table of an object, creating it if needed.
+// Must be called with the lock held.
+func (c *memoryConnector) getTable(e *base.Definition) *table {
+	t, ok := c.tables[e.Name]
+	if !ok {
+		t = &table{rows: make(map[string]row)}
+		c.tables[e.Name] = t
+	}
+	return t
+}
+
+// matches returns true if the row has the values of all the key columns
+func matches(r row, keys row) bool {
+	for k, value := range keys {
+		if compare(r[k], value) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// toColumns converts the named columns of a row to a list of columns
+func toColumns(r row, names []string) []base.Column {
+	columns := make([]base.Column, 0, len(names))
+	for _, name := range names {
+		value := r[name]
+		if b, ok := value.([]byte); ok {
+			value = append([]byte{}, b...)
+		}
+		columns = append(columns, base.Column{Name: name, Value: value})
+	}
+	return columns
+}
+
+// CreateIfNotExists creates a new row in DB if it already doesn't exist.
+func (c *memoryConnector) CreateIfNotExists(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+) error {
+	r := toRow(values)
+	key, err := primaryKey(e, r)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	t := c.getTable(e)
+	if _, ok := t.rows[key]; ok {
+		return yarpcerrors.AlreadyExistsErrorf("item already exists")
+	}
+	t.rows[key] = r
+	return nil
+}
+
+// Create creates a new row in DB, overwriting the columns of any existing
+// row with the same primary key.
+func (c *memoryConnector) Create(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+) error {
+	return c.upsert(e, toRow(values))
+}
+
+// upsert inserts a row or updates the columns of the existing row with
+// the same primary key
+func (c *memoryConnector) upsert(e *base.Definition, r row) error {
+	key, err := primaryKey(e, r)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	t := c.getTable(e)
+	existing, ok := t.rows[key]
+	if !ok {
+		t.rows[key] = r
+		return nil
+	}
+	for name, value := range r {
+		existing[name] = value
+	}
+	return nil
+}
+
+// Get fetches a record from DB using primary keys
+func (c *memoryConnector) Get(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+	colNamesToRead ...string,
+) ([]base.Column, error) {
+	if len(colNamesToRead) == 0 {
+		colNamesToRead = e.GetColumnsToRead()
+	}
+
+	key, err := primaryKey(e, toRow(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	r, ok := c.getTableLocked(e).rows[key]
+	if !ok {
+		return nil, yarpcerrors.NotFoundErrorf("not found")
+	}
+	return toColumns(r, colNamesToRead), nil
+}
+
+// getTableLocked returns the table of an object, or an empty table if
+// it does not exist. Must be called with the read lock held.
+func (c *memoryConnector) getTableLocked(e *base.Definition) *table {
+	if t, ok := c.tables[e.Name]; ok {
+		return t
+	}
+	return &table{}
+}
+
+// GetAll fetches all rows from DB using partition keys
+func (c *memoryConnector) GetAll(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+) ([][]base.Column, error) {
+	keyRow := toRow(keys)
+	if err := checkPartitionKeys(e, keyRow); err != nil {
+		return nil, err
+	}
+
+	return c.selectRows(e, func(r row) bool {
+		return matches(r, keyRow)
+	}), nil
+}
+
+// GetIn fetches the rows matching the keys whose inColumn takes one of
+// the inValues
+func (c *memoryConnector) GetIn(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+	inColumn string,
+	inValues []interface{},
+) ([][]base.Column, error) {
+	keyRow := toRow(keys)
+	partitionRow := toRow(keys)
+	partitionRow[inColumn] = nil
+	if err := checkPartitionKeys(e, partitionRow); err != nil {
+		return nil, err
+	}
+
+	in := make([]interface{}, len(inValues))
+	for i, value := range inValues {
+		in[i] = normalize(value)
+	}
+
+	return c.selectRows(e, func(r row) bool {
+		if !matches(r, keyRow) {
+			return false
+		}
+		for _, value := range in {
+			if compare(r[inColumn], value) == 0 {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// selectRows returns the rows of a table for which match returns true,
+// in clustering order
+func (c *memoryConnector) selectRows(
+	e *base.Definition,
+	match func(r row) bool,
+) [][]base.Column {
+	c.RLock()
+	defer c.RUnlock()
+
+	var matched []row
+	for _, r := range c.getTableLocked(e).rows {
+		if match(r) {
+			matched = append(matched, r)
+		}
+	}
+
+	// return the rows in clustering order
+	sort.Slice(matched, func(i, j int) bool {
+		for _, ck := range e.Key.ClusteringKeys {
+			cmp := compare(matched[i][ck.Name], matched[j][ck.Name])
+			if cmp == 0 {
+				continue
+			}
+			if ck.Descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+
+	colNamesToRead := e.GetColumnsToRead()
+	var rows [][]base.Column
+	for _, r := range matched {
+		rows = append(rows, toColumns(r, colNamesToRead))
+	}
+	return rows
+}
+
+// GetAllIter gives an iterator to fetch all rows from DB. The iterator
+// reads a snapshot of the rows at the time of the call.
+func (c *memoryConnector) GetAllIter(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+) (orm.Iterator, error) {
+	rows, err := c.GetAll(ctx, e, keys)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryIterator{rows: rows}, nil
+}
+
+// Update updates an existing row in DB. Same as Cassandra, the row is
+// created if it does not exist.
+func (c *memoryConnector) Update(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+	keys []base.Column,
+) error {
+	r := toRow(values)
+	for name, value := range toRow(keys) {
+		r[name] = value
+	}
+	return c.upsert(e, r)
+}
+
+// UpdateIf updates an existing row in DB only if the given conditions
+// hold for the row. Unlike Update, the row is not created if it does
+// not exist.
+func (c *memoryConnector) UpdateIf(
+	ctx context.Context,
+	e *base.Definition,
+	values []base.Column,
+	keys []base.Column,
+	conditions []base.Column,
+) error {
+	key, err := primaryKey(e, toRow(keys))
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	existing, ok := c.getTable(e).rows[key]
+	if !ok || !matches(existing, toRow(conditions)) {
+		return yarpcerrors.AbortedErrorf("update condition not met")
+	}
+	for name, value := range toRow(values) {
+		existing[name] = value
+	}
+	return nil
+}
+
+// Delete deletes the rows matching the keys from DB. The keys must have
+// at least the partition keys.
+func (c *memoryConnector) Delete(
+	ctx context.Context,
+	e *base.Definition,
+	keys []base.Column,
+) error {
+	keyRow := toRow(keys)
+	if err := checkPartitionKeys(e, keyRow); err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	t := c.getTable(e)
+	for key, r := range t.rows {
+		if matches(r, keyRow) {
+			delete(t.rows, key)
+		}
+	}
+	return nil
+}
+
+// memoryIterator implements interface Iterator for the rows read
+// from memory
+type memoryIterator struct {
+	rows [][]base.Column
+}
+
+// ensure that implementation (memoryIterator) satisfies the interface
+var _ orm.Iterator = (*memoryIterator)(nil)
+
+func (iter *memoryIterator) Close() {
+	iter.rows = nil
+}
+
+func (iter *memoryIterator) Next() ([]base.Column, error) {
+	if len(iter.rows) == 0 {
+		return nil, nil
+	}
+	r := iter.rows[0]
+	iter.rows = iter.rows[1:]
+	return r, nil
+}
@@ -0,0 +1,337 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// testDefinition is the definition of the test table, with
+// primary key ((id), ck)
+var testDefinition = &base.Definition{
+	Name: "test_table",
+	Key: &base.PrimaryKey{
+		PartitionKeys: []string{"id"},
+		ClusteringKeys: []*base.ClusteringKey{
+			{Name: "ck", Descending: true},
+		},
+	},
+	ColumnToType: map[string]reflect.Type{
+		"id":      reflect.TypeOf(""),
+		"ck":      reflect.TypeOf(uint64(0)),
+		"data":    reflect.TypeOf([]byte{}),
+		"created": reflect.TypeOf(time.Time{}),
+		"name":    reflect.TypeOf(&base.OptionalString{}),
+	},
+}
+
+type MemoryConnectorTestSuite struct {
+	suite.Suite
+
+	connector *memoryConnector
+}
+
+func (suite *MemoryConnectorTestSuite) SetupTest() {
+	suite.connector = NewMemoryConnector().(*memoryConnector)
+}
+
+func TestMemoryConnectorTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryConnectorTestSuite))
+}
+
+// testRow returns a row of the test table
+func testRow(id string, ck uint64, data string) []base.Column {
+	return []base.Column{
+		{Name: "id", Value: id},
+		{Name: "ck", Value: ck},
+		{Name: "data", Value: []byte(data)},
+		{Name: "created", Value: time.Unix(1546300800, 0)},
+	}
+}
+
+// testKeyRow returns the primary key of a row of the test table
+func testKeyRow(id string, ck uint64) []base.Column {
+	return []base.Column{
+		{Name: "id", Value: id},
+		{Name: "ck", Value: ck},
+	}
+}
+
+// getColumn returns the value of a column of a row
+func getColumn(row []base.Column, name string) interface{} {
+	for _, column := range row {
+		if column.Name == name {
+			return column.Value
+		}
+	}
+	return nil
+}
+
+// TestCreateAndGet tests creating and reading rows
+func (suite *MemoryConnectorTestSuite) TestCreateAndGet() {
+	ctx := context.Background()
+
+	suite.NoError(suite.connector.CreateIfNotExists(
+		ctx, testDefinition, testRow("id1", 1, "data1")))
+	err := suite.connector.CreateIfNotExists(
+		ctx, testDefinition, testRow("id1", 1, "data2"))
+	suite.True(yarpcerrors.IsAlreadyExists(err))
+
+	row, err := suite.connector.Get(
+		ctx, testDefinition, testKeyRow("id1", 1))
+	suite.NoError(err)
+	suite.Len(row, len(testDefinition.ColumnToType))
+	suite.Equal([]byte("data1"), getColumn(row, "data"))
+	// integers are read back as int64, same as Cassandra
+	suite.Equal(int64(1), getColumn(row, "ck"))
+	suite.Equal(time.Unix(1546300800, 0), getColumn(row, "created"))
+	suite.Nil(getColumn(row, "name"))
+
+	// Create overwrites the columns of the existing row
+	suite.NoError(suite.connector.Create(
+		ctx, testDefinition, testRow("id1", 1, "data2")))
+	row, err = suite.connector.Get(
+		ctx, testDefinition, testKeyRow("id1", 1), "data")
+	suite.NoError(err)
+	suite.Equal([]base.Column{{Name: "data", Value: []byte("data2")}}, row)
+
+	_, err = suite.connector.Get(ctx, testDefinition, testKeyRow("id1", 2))
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	_, err = suite.connector.Get(ctx, testDefinition,
+		[]base.Column{{Name: "id", Value: "id1"}})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestGetReturnsCopy tests that the values read cannot be used to
+// modify the stored row
+func (suite *MemoryConnectorTestSuite) TestGetReturnsCopy() {
+	ctx := context.Background()
+	data := []byte("data")
+	suite.NoError(suite.connector.Create(ctx, testDefinition, []base.Column{
+		{Name: "id", Value: "id1"},
+		{Name: "ck", Value: uint64(1)},
+		{Name: "data", Value: data},
+	}))
+	data[0] = 'x'
+
+	row, err := suite.connector.Get(
+		ctx, testDefinition, testKeyRow("id1", 1), "data")
+	suite.NoError(err)
+	row[0].Value.([]byte)[1] = 'x'
+
+	row, err = suite.connector.Get(
+		ctx, testDefinition, testKeyRow("id1", 1), "data")
+	suite.NoError(err)
+	suite.Equal([]byte("data"), row[0].Value)
+}
+
+// TestUpdate tests updating some columns of a row
+func (suite *MemoryConnectorTestSuite) TestUpdate() {
+	ctx := context.Background()
+
+	suite.NoError(suite.connector.Create(
+		ctx, testDefinition, testRow("id1", 1, "data1")))
+	suite.NoError(suite.connector.Update(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "name", Value: "name1"}},
+		testKeyRow("id1", 1),
+	))
+
+	row, err := suite.connector.Get(ctx, testDefinition, testKeyRow("id1", 1))
+	suite.NoError(err)
+	suite.Equal("name1", getColumn(row, "name"))
+	suite.Equal([]byte("data1"), getColumn(row, "data"))
+
+	// same as Cassandra, updating a row which does not exist creates it
+	suite.NoError(suite.connector.Update(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "name", Value: "name2"}},
+		testKeyRow("id1", 2),
+	))
+	row, err = suite.connector.Get(ctx, testDefinition, testKeyRow("id1", 2))
+	suite.NoError(err)
+	suite.Equal("name2", getColumn(row, "name"))
+	suite.Nil(getColumn(row, "data"))
+}
+
+// TestUpdateIf tests updating a row only if the conditions hold
+func (suite *MemoryConnectorTestSuite) TestUpdateIf() {
+	ctx := context.Background()
+
+	suite.NoError(suite.connector.Create(
+		ctx, testDefinition, testRow("id1", 1, "data1")))
+
+	// condition does not match the stored row
+	err := suite.connector.UpdateIf(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "data", Value: []byte("data2")}},
+		testKeyRow("id1", 1),
+		[]base.Column{{Name: "data", Value: []byte("data0")}},
+	)
+	suite.True(yarpcerrors.IsAborted(err))
+
+	// condition matches the stored row
+	suite.NoError(suite.connector.UpdateIf(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "data", Value: []byte("data2")}},
+		testKeyRow("id1", 1),
+		[]base.Column{{Name: "data", Value: []byte("data1")}},
+	))
+	row, err := suite.connector.Get(
+		ctx, testDefinition, testKeyRow("id1", 1), "data")
+	suite.NoError(err)
+	suite.Equal([]base.Column{{Name: "data", Value: []byte("data2")}}, row)
+
+	// the row is not created if it does not exist
+	err = suite.connector.UpdateIf(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "data", Value: []byte("data2")}},
+		testKeyRow("id1", 2),
+		[]base.Column{{Name: "data", Value: []byte("data1")}},
+	)
+	suite.True(yarpcerrors.IsAborted(err))
+	_, err = suite.connector.Get(ctx, testDefinition, testKeyRow("id1", 2))
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestGetAllAndDelete tests reading all the rows of a partition in
+// clustering order and deleting them
+func (suite *MemoryConnectorTestSuite) TestGetAllAndDelete() {
+	ctx := context.Background()
+
+	for ck := uint64(1); ck <= 3; ck++ {
+		suite.NoError(suite.connector.Create(
+			ctx, testDefinition, testRow("id1", ck, "data")))
+	}
+	suite.NoError(suite.connector.Create(
+		ctx, testDefinition, testRow("id2", 1, "data")))
+
+	rows, err := suite.connector.GetAll(
+		ctx, testDefinition, []base.Column{{Name: "id", Value: "id1"}})
+	suite.NoError(err)
+	suite.Len(rows, 3)
+	for i, row := range rows {
+		suite.Equal(int64(3-i), getColumn(row, "ck"))
+	}
+
+	_, err = suite.connector.GetAll(ctx, testDefinition, nil)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	suite.NoError(suite.connector.Delete(
+		ctx, testDefinition, testKeyRow("id1", 2)))
+	iter, err := suite.connector.GetAllIter(
+		ctx, testDefinition, []base.Column{{Name: "id", Value: "id1"}})
+	suite.NoError(err)
+	defer iter.Close()
+
+	var cks []int64
+	for {
+		row, err := iter.Next()
+		suite.NoError(err)
+		if row == nil {
+			break
+		}
+		cks = append(cks, getColumn(row, "ck").(int64))
+	}
+	suite.Equal([]int64{3, 1}, cks)
+
+	// delete the whole partition
+	suite.NoError(suite.connector.Delete(
+		ctx, testDefinition, []base.Column{{Name: "id", Value: "id1"}}))
+	rows, err = suite.connector.GetAll(
+		ctx, testDefinition, []base.Column{{Name: "id", Value: "id1"}})
+	suite.NoError(err)
+	suite.Empty(rows)
+	rows, err = suite.connector.GetAll(
+		ctx, testDefinition, []base.Column{{Name: "id", Value: "id2"}})
+	suite.NoError(err)
+	suite.Len(rows, 1)
+}
+
+// TestGetIn tests reading the rows of a partition whose clustering key
+// takes one of a list of values
+func (suite *MemoryConnectorTestSuite) TestGetIn() {
+	ctx := context.Background()
+
+	for ck := uint64(1); ck <= 3; ck++ {
+		suite.NoError(suite.connector.Create(
+			ctx, testDefinition, testRow("id1", ck, "data")))
+	}
+
+	rows, err := suite.connector.GetIn(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "id", Value: "id1"}},
+		"ck",
+		[]interface{}{uint64(1), uint64(3), uint64(4)},
+	)
+	suite.NoError(err)
+	suite.Len(rows, 2)
+	suite.Equal(int64(3), getColumn(rows[0], "ck"))
+	suite.Equal(int64(1), getColumn(rows[1], "ck"))
+
+	// the in column may be a partition key
+	rows, err = suite.connector.GetIn(
+		ctx, testDefinition, nil, "id", []interface{}{"id1", "id2"})
+	suite.NoError(err)
+	suite.Len(rows, 3)
+
+	_, err = suite.connector.GetIn(
+		ctx, testDefinition, nil, "ck", []interface{}{uint64(1)})
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestConcurrentAccess tests reading and writing rows concurrently
+func (suite *MemoryConnectorTestSuite) TestConcurrentAccess() {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("id%d", i%2)
+			suite.NoError(suite.connector.Create(
+				ctx, testDefinition, testRow(id, uint64(i), "data")))
+			_, err := suite.connector.GetAll(
+				ctx, testDefinition, []base.Column{{Name: "id", Value: id}})
+			suite.NoError(err)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, id := range []string{"id0", "id1"} {
+		rows, err := suite.connector.GetAll(
+			ctx, testDefinition, []base.Column{{Name: "id", Value: id}})
+		suite.NoError(err)
+		suite.Len(rows, 5)
+	}
+}
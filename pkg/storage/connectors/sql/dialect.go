@@ -0,0 +1,259 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// Postgres is the driver name of PostgreSQL
+	Postgres = "postgres"
+	// MySQL is the driver name of MySQL
+	MySQL = "mysql"
+	// SQLite is the driver name of SQLite
+	SQLite = "sqlite3"
+)
+
+// dialect captures the differences in the SQL understood by the
+// supported databases
+type dialect interface {
+	// quote quotes an identifier
+	quote(name string) string
+	// placeholder returns the placeholder of the i-th (0 based) argument
+	placeholder(i int) string
+	// columnType returns the column type to store values of type typ,
+	// isKey is set for primary key columns
+	columnType(typ reflect.Type, isKey bool) (string, error)
+	// onConflict returns the clause of an insert statement to either
+	// ignore the insert or update the updateCols of the existing row if a
+	// row with the same primary key exists
+	onConflict(keyCols []string, updateCols []string) string
+	// checkDSN checks that the data source name sets the options the
+	// connector relies on
+	checkDSN(dsn string) error
+}
+
+// getDialect returns the dialect of a database driver
+func getDialect(driver string) (dialect, error) {
+	switch driver {
+	case Postgres:
+		return postgresDialect{}, nil
+	case MySQL:
+		return mysqlDialect{}, nil
+	case SQLite:
+		return sqliteDialect{}, nil
+	default:
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"unsupported SQL driver %q", driver)
+	}
+}
+
+var (
+	timeType           = reflect.TypeOf(time.Time{})
+	optionalStringType = reflect.TypeOf(&base.OptionalString{})
+	optionalUInt64Type = reflect.TypeOf(&base.OptionalUInt64{})
+)
+
+// columnKind maps the type of an object field to the kind of
+// values stored in the database, one of reflect.String, reflect.Int64,
+// reflect.Bool, reflect.Slice (bytes) and reflect.Struct (time).
+func columnKind(typ reflect.Type) (reflect.Kind, error) {
+	switch {
+	case typ == timeType:
+		return reflect.Struct, nil
+	case typ == optionalStringType:
+		return reflect.String, nil
+	case typ == optionalUInt64Type:
+		return reflect.Int64, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return reflect.String, nil
+	case reflect.Int, reflect.Int32, reflect.Int64,
+		reflect.Uint32, reflect.Uint64:
+		return reflect.Int64, nil
+	case reflect.Bool:
+		return reflect.Bool, nil
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return reflect.Slice, nil
+		}
+	}
+	return reflect.Invalid, yarpcerrors.InternalErrorf(
+		"unsupported column type %v", typ)
+}
+
+// upsertClause builds "<prefix> c1=<value(c1)>, c2=<value(c2)>"
+func upsertClause(
+	prefix string,
+	cols []string,
+	quote func(string) string,
+	value func(string) string,
+) string {
+	sets := make([]string, len(cols))
+	for i, c := range cols {
+		sets[i] = fmt.Sprintf("%s=%s", quote(c), value(c))
+	}
+	return prefix + strings.Join(sets, ", ")
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) quote(name string) string {
+	return `"` + name + `"`
+}
+
+func (postgresDialect) placeholder(i int) string {
+	return fmt.Sprintf("$%d", i+1)
+}
+
+func (postgresDialect) columnType(typ reflect.Type, isKey bool) (string, error) {
+	kind, err := columnKind(typ)
+	if err != nil {
+		return "", err
+	}
+	switch kind {
+	case reflect.String:
+		return "TEXT", nil
+	case reflect.Int64:
+		return "BIGINT", nil
+	case reflect.Bool:
+		return "BOOLEAN", nil
+	case reflect.Slice:
+		return "BYTEA", nil
+	default:
+		return "TIMESTAMPTZ", nil
+	}
+}
+
+func (d postgresDialect) onConflict(keyCols []string, updateCols []string) string {
+	quoted := make([]string, len(keyCols))
+	for i, c := range keyCols {
+		quoted[i] = d.quote(c)
+	}
+	clause := fmt.Sprintf(" ON CONFLICT (%s) DO ", strings.Join(quoted, ", "))
+	if len(updateCols) == 0 {
+		return clause + "NOTHING"
+	}
+	return upsertClause(clause+"UPDATE SET ", updateCols, d.quote,
+		func(c string) string { return "EXCLUDED." + d.quote(c) })
+}
+
+func (postgresDialect) checkDSN(dsn string) error {
+	return nil
+}
+
+// sqliteDialect supports SQLite 3.24 and later, which supports
+// the same upsert syntax as PostgreSQL
+type sqliteDialect struct {
+	postgresDialect
+}
+
+func (sqliteDialect) placeholder(i int) string {
+	return "?"
+}
+
+func (sqliteDialect) columnType(typ reflect.Type, isKey bool) (string, error) {
+	kind, err := columnKind(typ)
+	if err != nil {
+		return "", err
+	}
+	switch kind {
+	case reflect.String:
+		return "TEXT", nil
+	case reflect.Int64:
+		return "INTEGER", nil
+	case reflect.Bool:
+		return "BOOLEAN", nil
+	case reflect.Slice:
+		return "BLOB", nil
+	default:
+		// the driver converts columns declared as TIMESTAMP to time.Time
+		return "TIMESTAMP", nil
+	}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) quote(name string) string {
+	return "`" + name + "`"
+}
+
+func (mysqlDialect) placeholder(i int) string {
+	return "?"
+}
+
+func (mysqlDialect) columnType(typ reflect.Type, isKey bool) (string, error) {
+	kind, err := columnKind(typ)
+	if err != nil {
+		return "", err
+	}
+	switch kind {
+	case reflect.String:
+		// MySQL cannot index TEXT columns without a prefix length
+		if isKey {
+			return "VARCHAR(255)", nil
+		}
+		return "MEDIUMTEXT", nil
+	case reflect.Int64:
+		return "BIGINT", nil
+	case reflect.Bool:
+		return "BOOLEAN", nil
+	case reflect.Slice:
+		return "LONGBLOB", nil
+	default:
+		return "DATETIME(6)", nil
+	}
+}
+
+func (d mysqlDialect) onConflict(keyCols []string, updateCols []string) string {
+	if len(updateCols) == 0 {
+		// a no-op update so that the insert affects no rows
+		updateCols = keyCols[:1]
+		return upsertClause(" ON DUPLICATE KEY UPDATE ", updateCols, d.quote, d.quote)
+	}
+	return upsertClause(" ON DUPLICATE KEY UPDATE ", updateCols, d.quote,
+		func(c string) string { return "VALUES(" + d.quote(c) + ")" })
+}
+
+// checkDSN requires clientFoundRows, without it MySQL reports the changed
+// rows instead of the matched rows and conditional updates which set the
+// current values would be reported as failed
+func (mysqlDialect) checkDSN(dsn string) error {
+	var params string
+	if i := strings.LastIndex(dsn, "?"); i >= 0 {
+		params = dsn[i+1:]
+	}
+	values, err := url.ParseQuery(params)
+	if err != nil {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"invalid MySQL DSN parameters: %v", err)
+	}
+	if values.Get("clientFoundRows") != "true" {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"MySQL DSN must set clientFoundRows=true")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"strings"
+
+	_ "github.com/gemnasium/migrate/driver/mysql"    // Pull in MySQL driver for migrate
+	_ "github.com/gemnasium/migrate/driver/postgres" // Pull in PostgreSQL driver for migrate
+	_ "github.com/gemnasium/migrate/driver/sqlite3"  // Pull in SQLite driver for migrate
+	"github.com/gemnasium/migrate/migrate"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// migrateURL returns the URL of the database used by migrate
+func migrateURL(config *Config) string {
+	if strings.HasPrefix(config.DSN, config.Driver+"://") {
+		return config.DSN
+	}
+	return config.Driver + "://" + config.DSN
+}
+
+// migrateSchema applies the schema migrations which have not been
+// applied yet to the database
+func migrateSchema(config *Config) error {
+	errs, ok := migrate.UpSync(migrateURL(config), config.Migrations)
+	if !ok {
+		return yarpcerrors.InternalErrorf(
+			"failed to migrate schema: %v", errs)
+	}
+	log.WithField("migrations", config.Migrations).
+		Info("SQL schema migrated")
+	return nil
+}
@@ -0,0 +1,566 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"reflect"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+	"github.com/uber/peloton/pkg/storage/orm"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+
+	// Pull in the database drivers
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// operation tags for metrics
+	create   = "create"
+	cas      = "cas"
+	get      = "get"
+	getIter  = "get_iter"
+	getIn    = "get_in"
+	update   = "update"
+	updateIf = "update_if"
+	del      = "delete"
+)
+
+type sqlConnector struct {
+	// DB is the database handle of this connector
+	DB *dbsql.DB
+	// dialect is the SQL dialect of the database
+	dialect dialect
+	// scope is the storage scope for metrics
+	scope tally.Scope
+	// scope is the storage scope for success metrics
+	executeSuccessScope tally.Scope
+	// scope is the storage scope for failure metrics
+	executeFailScope tally.Scope
+
+	// Conf is the SQL connector config
+	Conf *Config
+}
+
+// NewSQLConnector initializes a SQL Connector. If enabled in the config,
+// the tables of the object definitions are created if they do not exist
+// and the schema migrations are applied.
+func NewSQLConnector(
+	config *Config,
+	scope tally.Scope,
+	definitions ...*base.Definition,
+) (orm.Connector, error) {
+	d, err := getDialect(config.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkDSN(config.DSN); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsql.Open(config.Driver, config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+
+	storeScope := scope.SubScope("sql").Tagged(
+		map[string]string{"driver": config.Driver})
+
+	c := &sqlConnector{
+		DB:      db,
+		dialect: d,
+		scope:   storeScope,
+		executeSuccessScope: storeScope.Tagged(
+			map[string]string{"result": "success"}),
+		executeFailScope: storeScope.Tagged(
+			map[string]string{"result": "fail"}),
+		Conf: config,
+	}
+
+	if config.CreateTables {
+		if err := c.createTables(context.Background(), definitions); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if len(config.Migrations) > 0 {
+		if err := migrateSchema(config); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	log.WithField("driver", config.Driver).Info("ORM SQL connector created")
+	return c, nil
+}
+
+// ensure that implementation (sqlConnector) satisfies the interface
+var _ orm.Connector = (*sqlConnector)(nil)
+
+// createTables creates the tables of the object definitions
+func (c *sqlConnector) createTables(
+	ctx context.Context,
+	definitions []*base.Definition,
+) error {
+	for _, e := range definitions {
+		stmt, err := createTableStmt(c.dialect, e)
+		if err != nil {
+			return err
+		}
+		if _, err := c.DB.ExecContext(ctx, stmt); err != nil {
+			return yarpcerrors.InternalErrorf(
+				"failed to create table %s: %v", e.Name, err)
+		}
+	}
+	return nil
+}
+
+// getErrorTag gets a error tag for metrics
+func getErrorTag(err error) string {
+	if yarpcerrors.IsAlreadyExists(err) {
+		return "already_exists"
+	}
+	if yarpcerrors.IsNotFound(err) {
+		return "not_found"
+	}
+	switch err {
+	case context.DeadlineExceeded:
+		return "timeout"
+	case context.Canceled:
+		return "canceled"
+	case dbsql.ErrConnDone:
+		return "conn_done"
+	default:
+		return "unknown"
+	}
+}
+
+// buildResultRow is used to allocate memory for the row to be populated by
+// a select statement based on what object fields are being read
+func buildResultRow(e *base.Definition, columns []string) ([]interface{}, error) {
+	results := make([]interface{}, len(columns))
+
+	for i, column := range columns {
+		kind, err := columnKind(e.ColumnToType[column])
+		if err != nil {
+			return nil, err
+		}
+
+		// scan into pointers so that NULL columns are read as nil,
+		// same as the values read by the Cassandra connector
+		switch kind {
+		case reflect.String:
+			var value *string
+			results[i] = &value
+		case reflect.Int64:
+			var value *int64
+			results[i] = &value
+		case reflect.Bool:
+			var value *bool
+			results[i] = &value
+		case reflect.Slice:
+			var value *[]byte
+			results[i] = &value
+		default:
+			var value *time.Time
+			results[i] = &value
+		}
+	}
+
+	return results, nil
+}
+
+// getRowFromResult translates a row read from the database into a list of
+// base.Column to be interpreted by base store client
+func getRowFromResult(
+	columnNames []string, columnVals []interface{},
+) []base.Column {
+	row := make([]base.Column, 0, len(columnNames))
+
+	for i, columnName := range columnNames {
+		column := base.Column{
+			Name: columnName,
+		}
+
+		switch rv := columnVals[i].(type) {
+		case **int64:
+			column.Value = *rv
+		case **string:
+			column.Value = *rv
+		case **time.Time:
+			column.Value = *rv
+		case **bool:
+			column.Value = *rv
+		case **[]byte:
+			column.Value = *rv
+		}
+		row = append(row, column)
+	}
+	return row
+}
+
+// splitColumnNameValue is used to return list of column names and list of
+// their corresponding value.
+func splitColumnNameValue(row []base.Column) (
+	colNames []string, colValues []interface{}) {
+	for _, column := range row {
+		colNames = append(colNames, column.Name)
+		colValues = append(colValues, column.Value)
+	}
+	return colNames, colValues
+}
+
+// CreateIfNotExists creates a new row in DB if it already doesn't exist.
+func (c *sqlConnector) CreateIfNotExists(
+	ctx context.Context,
+	e *base.Definition,
+	row []base.Column,
+) error {
+	colNames, colValues := splitColumnNameValue(row)
+	stmt := insertStmt(c.dialect, e, colNames, false)
+
+	start := time.Now()
+	result, err := c.DB.ExecContext(ctx, stmt, colValues...)
+	if err != nil {
+		sendCounters(c.executeFailScope, e.Name, cas, err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		sendCounters(c.executeFailScope, e.Name, cas, err)
+		return err
+	}
+	if affected == 0 {
+		return yarpcerrors.AlreadyExistsErrorf("item already exists")
+	}
+
+	sendLatency(c.scope, e.Name, cas, time.Since(start))
+	sendCounters(c.executeSuccessScope, e.Name, cas, nil)
+	return nil
+}
+
+// Create creates a new row in DB, overwriting any existing row with the
+// same primary key as Cassandra does.
+func (c *sqlConnector) Create(
+	ctx context.Context,
+	e *base.Definition,
+	row []base.Column,
+) error {
+	return c.upsert(ctx, e, row, create)
+}
+
+// upsert inserts a row or updates the existing row with the same
+// primary key
+func (c *sqlConnector) upsert(
+	ctx context.Context,
+	e *base.Definition,
+	row []base.Column,
+	operation string,
+) error {
+	colNames, colValues := splitColumnNameValue(row)
+	stmt := insertStmt(c.dialect, e, colNames, true)
+
+	start := time.Now()
+	if _, err := c.DB.ExecContext(ctx, stmt, colValues...); err != nil {
+		sendCounters(c.executeFailScope, e.Name, operation, err)
+		return err
+	}
+
+	sendLatency(c.scope, e.Name, operation, time.Since(start))
+	sendCounters(c.executeSuccessScope, e.Name, operation, nil)
+	return nil
+}
+
+// Get fetches a record from DB using primary keys
+func (c *sqlConnector) Get(
+	ctx context.Context,
+	e *base.Definition,
+	keyCols []base.Column,
+	colNamesToRead ...string,
+) ([]base.Column, error) {
+	if len(colNamesToRead) == 0 {
+		colNamesToRead = e.GetColumnsToRead()
+	}
+
+	result, err := buildResultRow(e, colNamesToRead)
+	if err != nil {
+		return nil, err
+	}
+
+	keyColNames, keyColValues := splitColumnNameValue(keyCols)
+	stmt := selectStmt(c.dialect, e, colNamesToRead, keyColNames)
+
+	start := time.Now()
+	if err := c.DB.QueryRowContext(ctx, stmt, keyColValues...).
+		Scan(result...); err != nil {
+		if err == dbsql.ErrNoRows {
+			err = yarpcerrors.NotFoundErrorf("not found")
+		}
+		sendCounters(c.executeFailScope, e.Name, get, err)
+		return nil, err
+	}
+
+	sendLatency(c.scope, e.Name, get, time.Since(start))
+	sendCounters(c.executeSuccessScope, e.Name, get, nil)
+	return getRowFromResult(colNamesToRead, result), nil
+}
+
+// GetAll fetches all rows from DB using partition keys
+func (c *sqlConnector) GetAll(
+	ctx context.Context,
+	e *base.Definition,
+	keyCols []base.Column,
+) ([][]base.Column, error) {
+	iter, err := c.GetAllIter(ctx, e, keyCols)
+	if err != nil {
+		return nil, err
+	}
+	return readAll(iter)
+}
+
+// GetAllIter gives an iterator to fetch all rows from DB
+func (c *sqlConnector) GetAllIter(
+	ctx context.Context,
+	e *base.Definition,
+	keyCols []base.Column,
+) (orm.Iterator, error) {
+	colNamesToRead := e.GetColumnsToRead()
+	keyColNames, keyColValues := splitColumnNameValue(keyCols)
+	stmt := selectStmt(c.dialect, e, colNamesToRead, keyColNames)
+	return c.query(ctx, e, getIter, stmt, colNamesToRead, keyColValues)
+}
+
+// GetIn fetches the rows matching the keys whose inColumn takes one of
+// the inValues
+func (c *sqlConnector) GetIn(
+	ctx context.Context,
+	e *base.Definition,
+	keyCols []base.Column,
+	inColumn string,
+	inValues []interface{},
+) ([][]base.Column, error) {
+	if len(inValues) == 0 {
+		return nil, nil
+	}
+
+	colNamesToRead := e.GetColumnsToRead()
+	keyColNames, keyColValues := splitColumnNameValue(keyCols)
+	stmt := selectInStmt(c.dialect, e, colNamesToRead, keyColNames,
+		inColumn, len(inValues))
+	iter, err := c.query(ctx, e, getIn, stmt, colNamesToRead,
+		append(keyColValues, inValues...))
+	if err != nil {
+		return nil, err
+	}
+	return readAll(iter)
+}
+
+// query runs a select statement and returns an iterator over its rows
+func (c *sqlConnector) query(
+	ctx context.Context,
+	e *base.Definition,
+	op string,
+	stmt string,
+	colNamesToRead []string,
+	args []interface{},
+) (orm.Iterator, error) {
+	start := time.Now()
+	rows, err := c.DB.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		sendCounters(c.executeFailScope, e.Name, op, err)
+		return nil, err
+	}
+	sendLatency(c.scope, e.Name, op, time.Since(start))
+
+	return &sqlIterator{
+		rows:           rows,
+		tableDef:       e,
+		colNamesToRead: colNamesToRead,
+		successScope:   c.executeSuccessScope,
+		failScope:      c.executeFailScope,
+	}, nil
+}
+
+// readAll reads all the rows of an iterator and closes it
+func readAll(iter orm.Iterator) (rows [][]base.Column, err error) {
+	defer iter.Close()
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			return rows, nil
+		}
+		rows = append(rows, row)
+	}
+}
+
+// Delete deletes a record from DB using primary keys
+func (c *sqlConnector) Delete(
+	ctx context.Context,
+	e *base.Definition,
+	keyCols []base.Column,
+) error {
+	keyColNames, keyColValues := splitColumnNameValue(keyCols)
+	stmt := deleteStmt(c.dialect, e, keyColNames)
+
+	start := time.Now()
+	if _, err := c.DB.ExecContext(ctx, stmt, keyColValues...); err != nil {
+		sendCounters(c.executeFailScope, e.Name, del, err)
+		return err
+	}
+
+	sendLatency(c.scope, e.Name, del, time.Since(start))
+	sendCounters(c.executeSuccessScope, e.Name, del, nil)
+	return nil
+}
+
+// Update updates an existing row in DB. Same as Cassandra, the row is
+// created if it does not exist.
+func (c *sqlConnector) Update(
+	ctx context.Context,
+	e *base.Definition,
+	row []base.Column,
+	keyCols []base.Column,
+) error {
+	// the insert of the upsert needs the key columns as well
+	merged := append([]base.Column{}, keyCols...)
+	isKey := make(map[string]struct{})
+	for _, k := range keyCols {
+		isKey[k.Name] = struct{}{}
+	}
+	for _, column := range row {
+		if _, ok := isKey[column.Name]; !ok {
+			merged = append(merged, column)
+		}
+	}
+	return c.upsert(ctx, e, merged, update)
+}
+
+// UpdateIf updates an existing row in DB only if the given conditions
+// hold for the row. Unlike Update, the row is not created if it does
+// not exist.
+func (c *sqlConnector) UpdateIf(
+	ctx context.Context,
+	e *base.Definition,
+	row []base.Column,
+	keyCols []base.Column,
+	conditions []base.Column,
+) error {
+	colNames, colValues := splitColumnNameValue(row)
+	condColNames, condColValues := splitColumnNameValue(
+		append(append([]base.Column{}, keyCols...), conditions...))
+	stmt := updateStmt(c.dialect, e, colNames, condColNames)
+
+	start := time.Now()
+	result, err := c.DB.ExecContext(
+		ctx, stmt, append(colValues, condColValues...)...)
+	if err != nil {
+		sendCounters(c.executeFailScope, e.Name, updateIf, err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		sendCounters(c.executeFailScope, e.Name, updateIf, err)
+		return err
+	}
+	if affected == 0 {
+		return yarpcerrors.AbortedErrorf("update condition not met")
+	}
+
+	sendLatency(c.scope, e.Name, updateIf, time.Since(start))
+	sendCounters(c.executeSuccessScope, e.Name, updateIf, nil)
+	return nil
+}
+
+// sqlIterator implements interface Iterator for SQL
+type sqlIterator struct {
+	rows           *dbsql.Rows
+	tableDef       *base.Definition
+	colNamesToRead []string
+	successScope   tally.Scope
+	failScope      tally.Scope
+}
+
+// ensure that implementation (sqlIterator) satisfies the interface
+var _ orm.Iterator = (*sqlIterator)(nil)
+
+func (iter *sqlIterator) Close() {
+	iter.rows.Close()
+}
+
+func (iter *sqlIterator) Next() ([]base.Column, error) {
+	if iter.rows.Next() {
+		result, err := buildResultRow(iter.tableDef, iter.colNamesToRead)
+		if err != nil {
+			return nil, err
+		}
+		if err := iter.rows.Scan(result...); err != nil {
+			sendCounters(iter.failScope, iter.tableDef.Name, getIter, err)
+			return nil, err
+		}
+		return getRowFromResult(iter.colNamesToRead, result), nil
+	}
+	// Either end-of-results or error
+	if err := iter.rows.Err(); err != nil {
+		sendCounters(iter.failScope, iter.tableDef.Name, getIter, err)
+		return nil, err
+	}
+	sendCounters(iter.successScope, iter.tableDef.Name, getIter, nil)
+	return nil, nil
+}
+
+// helper function to record call latency metric
+func sendLatency(
+	scope tally.Scope,
+	table, operation string,
+	d time.Duration,
+) {
+	s := scope.Tagged(map[string]string{
+		"table":     table,
+		"operation": operation,
+	})
+	s.Timer("execute_latency").Record(d)
+}
+
+// helper function to record query success/failure metrics
+func sendCounters(
+	scope tally.Scope,
+	table, operation string,
+	err error,
+) {
+	errMsg := "none"
+	if err != nil {
+		errMsg = getErrorTag(err)
+	}
+	s := scope.Tagged(map[string]string{
+		"table":     table,
+		"operation": operation,
+		"error":     errMsg,
+	})
+	s.Counter("execute").Inc(1)
+}
@@ -0,0 +1,311 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type SQLConnectorTestSuite struct {
+	suite.Suite
+
+	connector *sqlConnector
+}
+
+func (suite *SQLConnectorTestSuite) SetupTest() {
+	conn, err := NewSQLConnector(
+		&Config{
+			Driver:       SQLite,
+			DSN:          "file:" + uuid.New() + "?mode=memory&cache=shared",
+			CreateTables: true,
+		},
+		tally.NewTestScope("", map[string]string{}),
+		testDefinition,
+	)
+	suite.NoError(err)
+	suite.connector = conn.(*sqlConnector)
+}
+
+func (suite *SQLConnectorTestSuite) TearDownTest() {
+	suite.connector.DB.Close()
+}
+
+func TestSQLConnectorTestSuite(t *testing.T) {
+	suite.Run(t, new(SQLConnectorTestSuite))
+}
+
+// testRow returns a row of the test table
+func testRow(ck uint64, data string) []base.Column {
+	return []base.Column{
+		{Name: "id", Value: "id1"},
+		{Name: "ck", Value: ck},
+		{Name: "data", Value: []byte(data)},
+		{Name: "valid", Value: true},
+		{Name: "created", Value: time.Unix(1546300800, 0).UTC()},
+	}
+}
+
+// testKeyRow returns the primary key of a row of the test table
+func testKeyRow(ck uint64) []base.Column {
+	return []base.Column{
+		{Name: "id", Value: "id1"},
+		{Name: "ck", Value: ck},
+	}
+}
+
+// getColumn returns the value of a column of a row
+func getColumn(row []base.Column, name string) interface{} {
+	for _, column := range row {
+		if column.Name == name {
+			return column.Value
+		}
+	}
+	return nil
+}
+
+// TestCreateAndGet tests creating and reading rows
+func (suite *SQLConnectorTestSuite) TestCreateAndGet() {
+	ctx := context.Background()
+
+	suite.NoError(suite.connector.CreateIfNotExists(
+		ctx, testDefinition, testRow(1, "data1")))
+	err := suite.connector.CreateIfNotExists(
+		ctx, testDefinition, testRow(1, "data2"))
+	suite.True(yarpcerrors.IsAlreadyExists(err))
+
+	row, err := suite.connector.Get(ctx, testDefinition, testKeyRow(1))
+	suite.NoError(err)
+	suite.Equal([]byte("data1"), *getColumn(row, "data").(*[]byte))
+	suite.Equal(int64(1), *getColumn(row, "ck").(*int64))
+	suite.True(*getColumn(row, "valid").(*bool))
+	suite.True(time.Unix(1546300800, 0).Equal(
+		*getColumn(row, "created").(*time.Time)))
+	// the optional column which was not set is read as nil
+	suite.Nil(getColumn(row, "name").(*string))
+
+	// Create overwrites the existing row
+	suite.NoError(suite.connector.Create(
+		ctx, testDefinition, testRow(1, "data2")))
+	row, err = suite.connector.Get(ctx, testDefinition, testKeyRow(1), "data")
+	suite.NoError(err)
+	suite.Len(row, 1)
+	suite.Equal([]byte("data2"), *getColumn(row, "data").(*[]byte))
+
+	_, err = suite.connector.Get(ctx, testDefinition, testKeyRow(2))
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestUpdate tests updating some columns of a row
+func (suite *SQLConnectorTestSuite) TestUpdate() {
+	ctx := context.Background()
+
+	suite.NoError(suite.connector.Create(
+		ctx, testDefinition, testRow(1, "data1")))
+	suite.NoError(suite.connector.Update(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "name", Value: "name1"}},
+		testKeyRow(1),
+	))
+
+	row, err := suite.connector.Get(ctx, testDefinition, testKeyRow(1))
+	suite.NoError(err)
+	suite.Equal("name1", *getColumn(row, "name").(*string))
+	suite.Equal([]byte("data1"), *getColumn(row, "data").(*[]byte))
+
+	// same as Cassandra, updating a row which does not exist creates it
+	suite.NoError(suite.connector.Update(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "name", Value: "name2"}},
+		testKeyRow(2),
+	))
+	row, err = suite.connector.Get(ctx, testDefinition, testKeyRow(2))
+	suite.NoError(err)
+	suite.Equal("name2", *getColumn(row, "name").(*string))
+	suite.Nil(getColumn(row, "data").(*[]byte))
+}
+
+// TestUpdateIf tests updating a row only if the conditions hold
+func (suite *SQLConnectorTestSuite) TestUpdateIf() {
+	ctx := context.Background()
+
+	suite.NoError(suite.connector.Create(
+		ctx, testDefinition, testRow(1, "data1")))
+
+	// condition does not match the stored row
+	err := suite.connector.UpdateIf(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "data", Value: []byte("data2")}},
+		testKeyRow(1),
+		[]base.Column{{Name: "data", Value: []byte("data0")}},
+	)
+	suite.True(yarpcerrors.IsAborted(err))
+
+	// condition matches the stored row
+	suite.NoError(suite.connector.UpdateIf(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "data", Value: []byte("data2")}},
+		testKeyRow(1),
+		[]base.Column{{Name: "data", Value: []byte("data1")}},
+	))
+	row, err := suite.connector.Get(ctx, testDefinition, testKeyRow(1))
+	suite.NoError(err)
+	suite.Equal([]byte("data2"), *getColumn(row, "data").(*[]byte))
+
+	// the row is not created if it does not exist
+	err = suite.connector.UpdateIf(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "data", Value: []byte("data2")}},
+		testKeyRow(2),
+		[]base.Column{{Name: "data", Value: []byte("data1")}},
+	)
+	suite.True(yarpcerrors.IsAborted(err))
+	_, err = suite.connector.Get(ctx, testDefinition, testKeyRow(2))
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestGetAllAndDelete tests reading all the rows of a partition in
+// clustering order and deleting them
+func (suite *SQLConnectorTestSuite) TestGetAllAndDelete() {
+	ctx := context.Background()
+
+	for ck := uint64(1); ck <= 3; ck++ {
+		suite.NoError(suite.connector.Create(
+			ctx, testDefinition, testRow(ck, "data")))
+	}
+
+	rows, err := suite.connector.GetAll(
+		ctx, testDefinition, []base.Column{{Name: "id", Value: "id1"}})
+	suite.NoError(err)
+	suite.Len(rows, 3)
+	for i, row := range rows {
+		suite.Equal(int64(3-i), *getColumn(row, "ck").(*int64))
+	}
+
+	rows, err = suite.connector.GetAll(
+		ctx, testDefinition, []base.Column{{Name: "id", Value: "id2"}})
+	suite.NoError(err)
+	suite.Empty(rows)
+
+	suite.NoError(suite.connector.Delete(ctx, testDefinition, testKeyRow(2)))
+	iter, err := suite.connector.GetAllIter(
+		ctx, testDefinition, []base.Column{{Name: "id", Value: "id1"}})
+	suite.NoError(err)
+	defer iter.Close()
+
+	var cks []int64
+	for {
+		row, err := iter.Next()
+		suite.NoError(err)
+		if row == nil {
+			break
+		}
+		cks = append(cks, *getColumn(row, "ck").(*int64))
+	}
+	suite.Equal([]int64{3, 1}, cks)
+}
+
+// TestGetIn tests reading the rows of a partition whose clustering key
+// takes one of a list of values
+func (suite *SQLConnectorTestSuite) TestGetIn() {
+	ctx := context.Background()
+
+	for ck := uint64(1); ck <= 3; ck++ {
+		suite.NoError(suite.connector.Create(
+			ctx, testDefinition, testRow(ck, "data")))
+	}
+
+	rows, err := suite.connector.GetIn(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "id", Value: "id1"}},
+		"ck",
+		[]interface{}{uint64(1), uint64(3), uint64(4)},
+	)
+	suite.NoError(err)
+	var cks []int64
+	for _, row := range rows {
+		cks = append(cks, *getColumn(row, "ck").(*int64))
+	}
+	suite.Equal([]int64{3, 1}, cks)
+
+	rows, err = suite.connector.GetIn(
+		ctx,
+		testDefinition,
+		[]base.Column{{Name: "id", Value: "id1"}},
+		"ck",
+		nil,
+	)
+	suite.NoError(err)
+	suite.Empty(rows)
+}
+
+// TestNewSQLConnectorInvalidDriver tests creating a connector for an
+// unsupported database
+func (suite *SQLConnectorTestSuite) TestNewSQLConnectorInvalidDriver() {
+	_, err := NewSQLConnector(
+		&Config{Driver: "oracle"},
+		tally.NoopScope,
+	)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestNewSQLConnectorMySQLFoundRows tests that a MySQL connector needs
+// the matched rows to be reported for conditional updates
+func (suite *SQLConnectorTestSuite) TestNewSQLConnectorMySQLFoundRows() {
+	_, err := NewSQLConnector(
+		&Config{Driver: MySQL, DSN: "user@tcp(host:3306)/peloton?parseTime=true"},
+		tally.NoopScope,
+	)
+	suite.True(yarpcerrors.IsInvalidArgument(err))
+
+	d := mysqlDialect{}
+	suite.Error(d.checkDSN("user@tcp(host:3306)/peloton"))
+	suite.Error(d.checkDSN("user@tcp(host:3306)/peloton?clientFoundRows=false"))
+	suite.NoError(d.checkDSN(
+		"user@tcp(host:3306)/peloton?parseTime=true&clientFoundRows=true"))
+}
+
+// TestMigrateURL tests building the URL of the database for migrate
+func (suite *SQLConnectorTestSuite) TestMigrateURL() {
+	suite.Equal("postgres://user@host:5432/peloton",
+		migrateURL(&Config{
+			Driver: Postgres,
+			DSN:    "postgres://user@host:5432/peloton",
+		}))
+	suite.Equal("mysql://user@tcp(host:3306)/peloton",
+		migrateURL(&Config{
+			Driver: MySQL,
+			DSN:    "user@tcp(host:3306)/peloton",
+		}))
+	suite.Equal("sqlite3:///var/lib/peloton.db",
+		migrateURL(&Config{
+			Driver: SQLite,
+			DSN:    "/var/lib/peloton.db",
+		}))
+}
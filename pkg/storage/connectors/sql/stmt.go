@@ -0,0 +1,220 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+)
+
+// quoteAll quotes a list of identifiers
+func quoteAll(d dialect, names []string) []string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = d.quote(n)
+	}
+	return quoted
+}
+
+// whereClause builds a where clause with an equality condition on each
+// column, whose placeholders start at the offset-th argument
+func whereClause(d dialect, conds []string, offset int) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	cstrs := make([]string, len(conds))
+	for i, c := range conds {
+		cstrs[i] = fmt.Sprintf("%s=%s", d.quote(c), d.placeholder(offset+i))
+	}
+	return " WHERE " + strings.Join(cstrs, " AND ")
+}
+
+// primaryKeyColumns returns the names of the partition and
+// clustering keys of the object
+func primaryKeyColumns(e *base.Definition) []string {
+	keys := append([]string{}, e.Key.PartitionKeys...)
+	for _, ck := range e.Key.ClusteringKeys {
+		keys = append(keys, ck.Name)
+	}
+	return keys
+}
+
+// insertStmt creates an insert statement. If a row with the same primary
+// key exists, the statement updates the columns of the row if upsert is
+// set and does nothing otherwise.
+func insertStmt(
+	d dialect,
+	e *base.Definition,
+	cols []string,
+	upsert bool,
+) string {
+	placeholders := make([]string, len(cols))
+	for i := range cols {
+		placeholders[i] = d.placeholder(i)
+	}
+
+	var updateCols []string
+	if upsert {
+		keys := make(map[string]struct{})
+		for _, k := range primaryKeyColumns(e) {
+			keys[k] = struct{}{}
+		}
+		for _, c := range cols {
+			if _, ok := keys[c]; !ok {
+				updateCols = append(updateCols, c)
+			}
+		}
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)%s;",
+		d.quote(e.Name),
+		strings.Join(quoteAll(d, cols), ", "),
+		strings.Join(placeholders, ", "),
+		d.onConflict(primaryKeyColumns(e), updateCols),
+	)
+}
+
+// selectStmt creates a select statement of the cols of the rows matching
+// the conds, in the clustering order of the object
+func selectStmt(
+	d dialect,
+	e *base.Definition,
+	cols []string,
+	conds []string,
+) string {
+	return selectWhereStmt(d, e, cols, whereClause(d, conds, 0))
+}
+
+// selectInStmt creates a select statement of the cols of the rows matching
+// the conds whose inCol takes one of n values, in the clustering order of
+// the object
+func selectInStmt(
+	d dialect,
+	e *base.Definition,
+	cols []string,
+	conds []string,
+	inCol string,
+	n int,
+) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = d.placeholder(len(conds) + i)
+	}
+	in := fmt.Sprintf("%s IN (%s)",
+		d.quote(inCol), strings.Join(placeholders, ", "))
+
+	where := whereClause(d, conds, 0)
+	if where == "" {
+		where = " WHERE " + in
+	} else {
+		where += " AND " + in
+	}
+	return selectWhereStmt(d, e, cols, where)
+}
+
+// selectWhereStmt creates a select statement of the cols of the rows
+// matching the where clause, in the clustering order of the object
+func selectWhereStmt(
+	d dialect,
+	e *base.Definition,
+	cols []string,
+	where string,
+) string {
+	var orderBy []string
+	for _, ck := range e.Key.ClusteringKeys {
+		order := " ASC"
+		if ck.Descending {
+			order = " DESC"
+		}
+		orderBy = append(orderBy, d.quote(ck.Name)+order)
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM %s%s",
+		strings.Join(quoteAll(d, cols), ", "),
+		d.quote(e.Name),
+		where,
+	)
+	if len(orderBy) > 0 {
+		stmt += " ORDER BY " + strings.Join(orderBy, ", ")
+	}
+	return stmt + ";"
+}
+
+// deleteStmt creates a delete statement of the rows matching the conds
+func deleteStmt(d dialect, e *base.Definition, conds []string) string {
+	return fmt.Sprintf("DELETE FROM %s%s;",
+		d.quote(e.Name),
+		whereClause(d, conds, 0),
+	)
+}
+
+// updateStmt creates an update statement which sets the cols of the
+// rows matching the conds
+func updateStmt(
+	d dialect,
+	e *base.Definition,
+	cols []string,
+	conds []string,
+) string {
+	updates := make([]string, len(cols))
+	for i, c := range cols {
+		updates[i] = fmt.Sprintf("%s=%s", d.quote(c), d.placeholder(i))
+	}
+	return fmt.Sprintf("UPDATE %s SET %s%s;",
+		d.quote(e.Name),
+		strings.Join(updates, ", "),
+		whereClause(d, conds, len(cols)),
+	)
+}
+
+// createTableStmt creates a statement to create the table of an object,
+// if it does not exist, from its definition
+func createTableStmt(d dialect, e *base.Definition) (string, error) {
+	keys := primaryKeyColumns(e)
+	isKey := make(map[string]bool)
+	for _, k := range keys {
+		isKey[k] = true
+	}
+
+	// sort the columns so that the statement is deterministic
+	var names []string
+	for name := range e.ColumnToType {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var defs []string
+	for _, name := range names {
+		typ, err := d.columnType(e.ColumnToType[name], isKey[name])
+		if err != nil {
+			return "", err
+		}
+		def := d.quote(name) + " " + typ
+		if isKey[name] {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+	}
+	defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)",
+		strings.Join(quoteAll(d, keys), ", ")))
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);",
+		d.quote(e.Name),
+		strings.Join(defs, ", "),
+	), nil
+}
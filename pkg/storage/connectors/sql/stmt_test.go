@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// testDefinition is the definition of the test table, with
+// primary key ((id), ck)
+var testDefinition = &base.Definition{
+	Name: "test_table",
+	Key: &base.PrimaryKey{
+		PartitionKeys: []string{"id"},
+		ClusteringKeys: []*base.ClusteringKey{
+			{Name: "ck", Descending: true},
+		},
+	},
+	ColumnToType: map[string]reflect.Type{
+		"id":      reflect.TypeOf(""),
+		"ck":      reflect.TypeOf(uint64(0)),
+		"data":    reflect.TypeOf([]byte{}),
+		"valid":   reflect.TypeOf(true),
+		"created": reflect.TypeOf(time.Time{}),
+		"name":    reflect.TypeOf(&base.OptionalString{}),
+	},
+}
+
+type StmtTestSuite struct {
+	suite.Suite
+}
+
+func TestStmtTestSuite(t *testing.T) {
+	suite.Run(t, new(StmtTestSuite))
+}
+
+// TestInsertStmt tests constructing insert statements
+func (suite *StmtTestSuite) TestInsertStmt() {
+	data := []struct {
+		dialect dialect
+		cols    []string
+		upsert  bool
+		stmt    string
+	}{
+		{
+			dialect: postgresDialect{},
+			cols:    []string{"id", "ck", "data"},
+			upsert:  false,
+			stmt: `INSERT INTO "test_table" ("id", "ck", "data") ` +
+				`VALUES ($1, $2, $3) ON CONFLICT ("id", "ck") DO NOTHING;`,
+		},
+		{
+			dialect: postgresDialect{},
+			cols:    []string{"id", "ck", "data", "valid"},
+			upsert:  true,
+			stmt: `INSERT INTO "test_table" ("id", "ck", "data", "valid") ` +
+				`VALUES ($1, $2, $3, $4) ON CONFLICT ("id", "ck") DO UPDATE ` +
+				`SET "data"=EXCLUDED."data", "valid"=EXCLUDED."valid";`,
+		},
+		{
+			dialect: sqliteDialect{},
+			cols:    []string{"id", "ck", "data"},
+			upsert:  true,
+			stmt: `INSERT INTO "test_table" ("id", "ck", "data") ` +
+				`VALUES (?, ?, ?) ON CONFLICT ("id", "ck") DO UPDATE ` +
+				`SET "data"=EXCLUDED."data";`,
+		},
+		{
+			dialect: mysqlDialect{},
+			cols:    []string{"id", "ck", "data"},
+			upsert:  false,
+			stmt: "INSERT INTO `test_table` (`id`, `ck`, `data`) " +
+				"VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `id`=`id`;",
+		},
+		{
+			dialect: mysqlDialect{},
+			cols:    []string{"id", "ck", "data"},
+			upsert:  true,
+			stmt: "INSERT INTO `test_table` (`id`, `ck`, `data`) " +
+				"VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE " +
+				"`data`=VALUES(`data`);",
+		},
+	}
+	for _, d := range data {
+		suite.Equal(d.stmt,
+			insertStmt(d.dialect, testDefinition, d.cols, d.upsert))
+	}
+}
+
+// TestSelectStmt tests constructing select statements
+func (suite *StmtTestSuite) TestSelectStmt() {
+	suite.Equal(
+		`SELECT "data", "valid" FROM "test_table" `+
+			`WHERE "id"=$1 AND "ck"=$2 ORDER BY "ck" DESC;`,
+		selectStmt(postgresDialect{}, testDefinition,
+			[]string{"data", "valid"}, []string{"id", "ck"}))
+	suite.Equal(
+		"SELECT `data` FROM `test_table` ORDER BY `ck` DESC;",
+		selectStmt(mysqlDialect{}, testDefinition,
+			[]string{"data"}, nil))
+}
+
+// TestSelectInStmt tests constructing select statements with an IN
+// condition
+func (suite *StmtTestSuite) TestSelectInStmt() {
+	suite.Equal(
+		`SELECT "data" FROM "test_table" `+
+			`WHERE "id"=$1 AND "ck" IN ($2, $3) ORDER BY "ck" DESC;`,
+		selectInStmt(postgresDialect{}, testDefinition,
+			[]string{"data"}, []string{"id"}, "ck", 2))
+	suite.Equal(
+		"SELECT `data` FROM `test_table` WHERE `id` IN (?) "+
+			"ORDER BY `ck` DESC;",
+		selectInStmt(mysqlDialect{}, testDefinition,
+			[]string{"data"}, nil, "id", 1))
+}
+
+// TestDeleteStmt tests constructing delete statements
+func (suite *StmtTestSuite) TestDeleteStmt() {
+	suite.Equal(
+		`DELETE FROM "test_table" WHERE "id"=$1 AND "ck"=$2;`,
+		deleteStmt(postgresDialect{}, testDefinition, []string{"id", "ck"}))
+	suite.Equal(
+		`DELETE FROM "test_table" WHERE "id"=?;`,
+		deleteStmt(sqliteDialect{}, testDefinition, []string{"id"}))
+}
+
+// TestUpdateStmt tests constructing update statements
+func (suite *StmtTestSuite) TestUpdateStmt() {
+	suite.Equal(
+		`UPDATE "test_table" SET "data"=$1, "valid"=$2 `+
+			`WHERE "id"=$3 AND "ck"=$4 AND "name"=$5;`,
+		updateStmt(postgresDialect{}, testDefinition,
+			[]string{"data", "valid"}, []string{"id", "ck", "name"}))
+	suite.Equal(
+		"UPDATE `test_table` SET `data`=? WHERE `id`=?;",
+		updateStmt(mysqlDialect{}, testDefinition,
+			[]string{"data"}, []string{"id"}))
+}
+
+// TestCreateTableStmt tests generating the table schema from the
+// object definition
+func (suite *StmtTestSuite) TestCreateTableStmt() {
+	stmt, err := createTableStmt(postgresDialect{}, testDefinition)
+	suite.NoError(err)
+	suite.Equal(`CREATE TABLE IF NOT EXISTS "test_table" (`+
+		`"ck" BIGINT NOT NULL, "created" TIMESTAMPTZ, "data" BYTEA, `+
+		`"id" TEXT NOT NULL, "name" TEXT, "valid" BOOLEAN, `+
+		`PRIMARY KEY ("id", "ck"));`, stmt)
+
+	stmt, err = createTableStmt(mysqlDialect{}, testDefinition)
+	suite.NoError(err)
+	suite.Equal("CREATE TABLE IF NOT EXISTS `test_table` ("+
+		"`ck` BIGINT NOT NULL, `created` DATETIME(6), `data` LONGBLOB, "+
+		"`id` VARCHAR(255) NOT NULL, `name` MEDIUMTEXT, `valid` BOOLEAN, "+
+		"PRIMARY KEY (`id`, `ck`));", stmt)
+
+	stmt, err = createTableStmt(sqliteDialect{}, testDefinition)
+	suite.NoError(err)
+	suite.Equal(`CREATE TABLE IF NOT EXISTS "test_table" (`+
+		`"ck" INTEGER NOT NULL, "created" TIMESTAMP, "data" BLOB, `+
+		`"id" TEXT NOT NULL, "name" TEXT, "valid" BOOLEAN, `+
+		`PRIMARY KEY ("id", "ck"));`, stmt)
+}
+
+// TestCreateTableStmtUnsupportedType tests generating the table schema
+// of an object with a field type that cannot be stored
+func (suite *StmtTestSuite) TestCreateTableStmtUnsupportedType() {
+	_, err := createTableStmt(postgresDialect{}, &base.Definition{
+		Name: "bad_table",
+		Key:  &base.PrimaryKey{PartitionKeys: []string{"id"}},
+		ColumnToType: map[string]reflect.Type{
+			"id":  reflect.TypeOf(""),
+			"map": reflect.TypeOf(map[string]string{}),
+		},
+	})
+	suite.Error(err)
+}
+
+// TestGetDialect tests getting the dialect of the supported drivers
+func (suite *StmtTestSuite) TestGetDialect() {
+	for _, driver := range []string{Postgres, MySQL, SQLite} {
+		_, err := getDialect(driver)
+		suite.NoError(err)
+	}
+	_, err := getDialect("oracle")
+	suite.Error(err)
+}
@@ -17,6 +17,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
@@ -70,6 +71,10 @@ type TaskStore interface {
 		jobType job.JobType) error
 	// GetTaskRuntime gets the runtime of a given task
 	GetTaskRuntime(ctx context.Context, jobID *peloton.JobID, instanceID uint32) (*task.RuntimeInfo, error)
+	// GetTaskRuntimes gets the runtimes of the given tasks of a job. The
+	// tasks whose runtime could not be read, including the ones which do
+	// not exist, are returned in the error map by instance ID.
+	GetTaskRuntimes(ctx context.Context, jobID *peloton.JobID, instanceIDs []uint32) (map[uint32]*task.RuntimeInfo, map[uint32]error)
 	// UpdateTaskRuntime updates the runtime of a given task
 	UpdateTaskRuntime(
 		ctx context.Context,
@@ -172,6 +177,15 @@ type UpdateStore interface {
 		instanceID uint32,
 		limit uint32,
 	) ([]*stateless.WorkflowEvent, error)
+
+	// DeleteWorkflowEventsBefore deletes the workflow events for an
+	// update and instance which were created before the given time
+	DeleteWorkflowEventsBefore(
+		ctx context.Context,
+		updateID *peloton.UpdateID,
+		instanceID uint32,
+		before time.Time,
+	) error
 }
 
 // FrameworkInfoStore is the interface to store mesosStreamID for peloton frameworks
@@ -0,0 +1,993 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/api/v0/update"
+	pb_volume "github.com/uber/peloton/.gen/peloton/api/v0/volume"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/storage"
+	"github.com/uber/peloton/pkg/storage/cassandra"
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	taskIDFmt = "%s-%d"
+
+	_defaultQueryLimit uint32 = 10
+)
+
+// workflowEventKey identifies the workflow events of an instance
+// in an update
+type workflowEventKey struct {
+	updateID   string
+	instanceID uint32
+}
+
+// workflowEvent is a workflow state change recorded for an instance
+type workflowEvent struct {
+	workflowType  models.WorkflowType
+	workflowState update.State
+	createTime    time.Time
+}
+
+// frameworkInfo is the mesos framework information of a framework
+type frameworkInfo struct {
+	frameworkID   string
+	mesosStreamID string
+}
+
+// Store implements the legacy storage.Store interfaces in memory, for
+// tests which run the peloton components in-process without a live
+// database. The job configs, job runtimes, job index, update events,
+// task configs, task runtimes and pod events are kept in the ORM store,
+// so that the ORM ops of the components see the same rows as this
+// store. The ORM store should be created by ormobjects.NewMemoryStore.
+type Store struct {
+	sync.RWMutex
+
+	jobConfigOps       ormobjects.JobConfigOps
+	jobIndexOps        ormobjects.JobIndexOps
+	jobRuntimeOps      ormobjects.JobRuntimeOps
+	jobUpdateEventsOps ormobjects.JobUpdateEventsOps
+	podEventsOps       ormobjects.PodEventsOps
+	taskConfigV2Ops    ormobjects.TaskConfigV2Ops
+	taskRuntimeOps     ormobjects.TaskRuntimeOps
+
+	// updates by update ID
+	updates map[string]*models.UpdateModel
+	// workflow events by update and instance, most recent first
+	workflowEvents map[workflowEventKey][]*workflowEvent
+	// framework info by framework name
+	frameworks map[string]*frameworkInfo
+	// persistent volumes by volume ID
+	volumes map[string]*pb_volume.PersistentVolumeInfo
+}
+
+// ensure that Store satisfies the legacy store interface
+var _ storage.Store = (*Store)(nil)
+
+// NewStore creates a Store which keeps the ORM objects in ormStore
+func NewStore(ormStore *ormobjects.Store) *Store {
+	return &Store{
+		jobConfigOps:       ormobjects.NewJobConfigOps(ormStore),
+		jobIndexOps:        ormobjects.NewJobIndexOps(ormStore),
+		jobRuntimeOps:      ormobjects.NewJobRuntimeOps(ormStore),
+		jobUpdateEventsOps: ormobjects.NewJobUpdateEventsOps(ormStore),
+		podEventsOps:       ormobjects.NewPodEventsOps(ormStore),
+		taskConfigV2Ops:    ormobjects.NewTaskConfigV2Ops(ormStore),
+		taskRuntimeOps:     ormobjects.NewTaskRuntimeOps(ormStore),
+
+		updates:        make(map[string]*models.UpdateModel),
+		workflowEvents: make(map[workflowEventKey][]*workflowEvent),
+		frameworks:     make(map[string]*frameworkInfo),
+		volumes:        make(map[string]*pb_volume.PersistentVolumeInfo),
+	}
+}
+
+// GetMaxJobConfigVersion returns the maximum version of configs of a given job
+func (s *Store) GetMaxJobConfigVersion(
+	ctx context.Context,
+	jobID string) (uint64, error) {
+	id := &peloton.JobID{Value: jobID}
+
+	// the config versions of a job are consecutive, so probe the
+	// versions after the current one until one is missing
+	var version uint64
+	if runtime, err := s.jobRuntimeOps.Get(ctx, id); err == nil {
+		version = runtime.GetConfigurationVersion()
+	}
+	for {
+		_, _, err := s.jobConfigOps.Get(ctx, id, version+1)
+		if yarpcerrors.IsNotFound(errors.Cause(err)) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version++
+	}
+}
+
+// QueryJobs returns all jobs in the resource pool that matches the spec.
+func (s *Store) QueryJobs(
+	ctx context.Context,
+	respoolID *peloton.ResourcePoolID,
+	spec *job.QuerySpec,
+	summaryOnly bool,
+) ([]*job.JobInfo, []*job.JobSummary, uint32, error) {
+	if spec == nil {
+		return nil, nil, 0, nil
+	}
+
+	summaryResults, total, err := s.jobIndexOps.Query(ctx, respoolID, spec)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if summaryOnly {
+		return nil, summaryResults, total, nil
+	}
+
+	var results []*job.JobInfo
+	for _, summary := range summaryResults {
+		jobID := summary.GetId()
+
+		jobRuntime, err := s.jobRuntimeOps.Get(ctx, jobID)
+		if err != nil {
+			log.WithError(err).
+				WithField("job_id", jobID.GetValue()).
+				Warn("no job runtime found when executing jobs query")
+			continue
+		}
+		jobConfig, _, err := s.jobConfigOps.GetCurrentVersion(ctx, jobID)
+		if err != nil {
+			log.WithError(err).
+				WithField("job_id", jobID.GetValue()).
+				Warn("no job config found when executing jobs query")
+			continue
+		}
+		jobConfig.InstanceConfig = nil
+
+		results = append(results, &job.JobInfo{
+			Id:      jobID,
+			Config:  jobConfig,
+			Runtime: jobRuntime,
+		})
+	}
+	return results, summaryResults, total, nil
+}
+
+// DeleteJob deletes a job and associated tasks, by job id.
+func (s *Store) DeleteJob(
+	ctx context.Context,
+	jobID string) error {
+	id := &peloton.JobID{Value: jobID}
+
+	maxVersion, err := s.GetMaxJobConfigVersion(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	// delete the task configs of every config version of the job
+	for version := uint64(1); version <= maxVersion; version++ {
+		jobConfig, _, err := s.jobConfigOps.Get(ctx, id, version)
+		if err != nil {
+			return err
+		}
+		if err := s.taskConfigV2Ops.Delete(
+			ctx, id, common.DefaultTaskConfigID, version); err != nil {
+			return err
+		}
+		for i := uint32(0); i < jobConfig.GetInstanceCount(); i++ {
+			if err := s.taskConfigV2Ops.Delete(
+				ctx, id, int64(i), version); err != nil {
+				return err
+			}
+		}
+	}
+
+	runtimes, err := s.taskRuntimeOps.GetAll(ctx, id)
+	if err != nil {
+		return err
+	}
+	for instanceID := range runtimes {
+		if err := s.taskRuntimeOps.Delete(ctx, id, instanceID); err != nil {
+			return err
+		}
+		if err := s.podEventsOps.Delete(
+			ctx, jobID, instanceID, 0, math.MaxUint64); err != nil {
+			return err
+		}
+	}
+
+	// delete all updates of the job
+	updateIDs, err := s.GetUpdatesForJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	for _, updateID := range updateIDs {
+		if err := s.deleteSingleUpdate(ctx, updateID); err != nil {
+			return err
+		}
+	}
+
+	for version := uint64(1); version <= maxVersion; version++ {
+		if err := s.jobConfigOps.Delete(ctx, id, version); err != nil {
+			return err
+		}
+	}
+	return s.jobRuntimeOps.Delete(ctx, id)
+}
+
+// CreateTaskRuntime creates a task runtime for a peloton job
+func (s *Store) CreateTaskRuntime(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceID uint32,
+	runtime *task.RuntimeInfo,
+	owner string,
+	jobType job.JobType) error {
+	return s.taskRuntimeOps.Create(ctx, jobID, instanceID, runtime)
+}
+
+// GetPodEvents returns pod events for a Job + Instance + PodID (optional).
+// Without a PodID, the events of the latest run are returned.
+func (s *Store) GetPodEvents(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32,
+	podID ...string) ([]*task.PodEvent, error) {
+	return s.podEventsOps.GetAll(ctx, jobID, instanceID, podID...)
+}
+
+// DeletePodEvents deletes the pod events for provided JobID,
+// InstanceID and RunID in the range [fromRunID-toRunID)
+func (s *Store) DeletePodEvents(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32,
+	fromRunID uint64,
+	toRunID uint64,
+) error {
+	return s.podEventsOps.Delete(ctx, jobID, instanceID, fromRunID, toRunID)
+}
+
+// GetTasksForJob returns all the task runtimes (no configuration) in a
+// map of tasks.TaskInfo for a peloton job
+func (s *Store) GetTasksForJob(
+	ctx context.Context,
+	id *peloton.JobID) (map[uint32]*task.TaskInfo, error) {
+	runtimes, err := s.GetTaskRuntimesForJobByRange(ctx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint32]*task.TaskInfo)
+	for instanceID, runtime := range runtimes {
+		result[instanceID] = &task.TaskInfo{
+			Runtime:    runtime,
+			InstanceId: instanceID,
+			JobId:      id,
+		}
+	}
+	return result, nil
+}
+
+// GetTaskConfigs returns the task configs for a list of instance IDs,
+// job ID and config version.
+func (s *Store) GetTaskConfigs(
+	ctx context.Context,
+	id *peloton.JobID,
+	instanceIDs []uint32,
+	version uint64,
+) (map[uint32]*task.TaskConfig, *models.ConfigAddOn, error) {
+	versions := make(map[uint32]uint64)
+	for _, instanceID := range instanceIDs {
+		versions[instanceID] = version
+	}
+
+	results, errs := s.taskConfigV2Ops.GetTaskConfigs(ctx, id, versions)
+	for _, err := range errs {
+		return nil, nil, err
+	}
+
+	var configAddOn *models.ConfigAddOn
+	taskConfigMap := make(map[uint32]*task.TaskConfig)
+	for instanceID, result := range results {
+		taskConfigMap[instanceID] = result.TaskConfig
+		// config add-on is the same for all tasks of a job
+		if configAddOn == nil {
+			configAddOn = result.ConfigAddOn
+		}
+	}
+	return taskConfigMap, configAddOn, nil
+}
+
+// GetTasksForJobAndStates returns the tasks for a peloton job which are
+// in one of the specified states.
+func (s *Store) GetTasksForJobAndStates(
+	ctx context.Context,
+	id *peloton.JobID,
+	states []task.TaskState) (map[uint32]*task.TaskInfo, error) {
+	tasks, err := s.GetTasksForJobByRange(ctx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for instanceID, taskInfo := range tasks {
+		if !containsState(states, taskInfo.GetRuntime().GetState()) {
+			delete(tasks, instanceID)
+		}
+	}
+	return tasks, nil
+}
+
+// containsState returns true if state is in states
+func containsState(states []task.TaskState, state task.TaskState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// specContains returns true if the specifier is empty or contains item
+func specContains(specifier []string, item string) bool {
+	if len(specifier) == 0 {
+		return true
+	}
+	return util.Contains(specifier, item)
+}
+
+// GetTaskRuntimesForJobByRange returns the Task RuntimeInfo for batch
+// jobs by instance ID range.
+func (s *Store) GetTaskRuntimesForJobByRange(
+	ctx context.Context,
+	id *peloton.JobID,
+	instanceRange *task.InstanceRange,
+) (map[uint32]*task.RuntimeInfo, error) {
+	runtimes, err := s.taskRuntimeOps.GetAll(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if instanceRange != nil {
+		for instanceID := range runtimes {
+			if instanceID < instanceRange.GetFrom() ||
+				instanceID >= instanceRange.GetTo() {
+				delete(runtimes, instanceID)
+			}
+		}
+	}
+	return runtimes, nil
+}
+
+// GetTasksForJobByRange returns the TaskInfo for batch jobs by
+// instance ID range.
+func (s *Store) GetTasksForJobByRange(
+	ctx context.Context,
+	id *peloton.JobID,
+	instanceRange *task.InstanceRange,
+) (map[uint32]*task.TaskInfo, error) {
+	runtimes, err := s.GetTaskRuntimesForJobByRange(ctx, id, instanceRange)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[uint32]uint64)
+	for instanceID, runtime := range runtimes {
+		versions[instanceID] = runtime.GetConfigVersion()
+	}
+	configs, errs := s.taskConfigV2Ops.GetTaskConfigs(ctx, id, versions)
+	for _, err := range errs {
+		return nil, err
+	}
+
+	result := make(map[uint32]*task.TaskInfo)
+	for instanceID, runtime := range runtimes {
+		result[instanceID] = &task.TaskInfo{
+			InstanceId: instanceID,
+			JobId:      id,
+			Config:     configs[instanceID].TaskConfig,
+			Runtime:    runtime,
+		}
+	}
+	return result, nil
+}
+
+// GetTaskRuntime for a job and instance id.
+func (s *Store) GetTaskRuntime(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceID uint32) (*task.RuntimeInfo, error) {
+	runtime, err := s.taskRuntimeOps.Get(ctx, jobID, instanceID)
+	if yarpcerrors.IsNotFound(errors.Cause(err)) {
+		return nil, yarpcerrors.NotFoundErrorf(
+			"task:%s not found",
+			fmt.Sprintf(taskIDFmt, jobID.GetValue(), instanceID))
+	}
+	return runtime, err
+}
+
+// GetTaskRuntimes for a job and a list of instance ids. The instances
+// which could not be read are returned in the error map.
+func (s *Store) GetTaskRuntimes(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceIDs []uint32,
+) (map[uint32]*task.RuntimeInfo, map[uint32]error) {
+	runtimes := make(map[uint32]*task.RuntimeInfo)
+	errs := make(map[uint32]error)
+	for _, instanceID := range instanceIDs {
+		runtime, err := s.GetTaskRuntime(ctx, jobID, instanceID)
+		if err != nil {
+			errs[instanceID] = err
+			continue
+		}
+		runtimes[instanceID] = runtime
+	}
+	return runtimes, errs
+}
+
+// UpdateTaskRuntime updates a task for a peloton job
+func (s *Store) UpdateTaskRuntime(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceID uint32,
+	runtime *task.RuntimeInfo,
+	jobType job.JobType) error {
+	// the runtime is written unconditionally, as for the Cassandra store
+	return s.taskRuntimeOps.Create(ctx, jobID, instanceID, runtime)
+}
+
+// GetTaskForJob returns a task by jobID and instanceID
+func (s *Store) GetTaskForJob(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32) (map[uint32]*task.TaskInfo, error) {
+	taskInfo, err := s.getTask(ctx, jobID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return map[uint32]*task.TaskInfo{instanceID: taskInfo}, nil
+}
+
+// GetTaskByID returns the tasks (tasks.TaskInfo) for a peloton job
+func (s *Store) GetTaskByID(
+	ctx context.Context,
+	taskID string) (*task.TaskInfo, error) {
+	jobID, instanceID, err := util.ParseTaskID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return s.getTask(ctx, jobID, instanceID)
+}
+
+// getTask returns the runtime and config of a task
+func (s *Store) getTask(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32) (*task.TaskInfo, error) {
+	id := &peloton.JobID{Value: jobID}
+	runtime, err := s.GetTaskRuntime(ctx, id, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := s.taskConfigV2Ops.GetTaskConfig(
+		ctx, id, instanceID, runtime.GetConfigVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	return &task.TaskInfo{
+		Runtime:    runtime,
+		Config:     config,
+		InstanceId: instanceID,
+		JobId:      id,
+	}, nil
+}
+
+// QueryTasks returns the tasks filtered on states(spec.TaskStates) in
+// the given offset..offset+limit range.
+func (s *Store) QueryTasks(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	spec *task.QuerySpec) ([]*task.TaskInfo, uint32, error) {
+	var tasks map[uint32]*task.TaskInfo
+	var err error
+	if len(spec.GetTaskStates()) == 0 {
+		tasks, err = s.GetTasksForJobByRange(ctx, jobID, nil)
+	} else {
+		tasks, err = s.GetTasksForJobAndStates(ctx, jobID, spec.GetTaskStates())
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var sortedTasks []*task.TaskInfo
+	for _, taskInfo := range tasks {
+		if specContains(spec.GetNames(), taskInfo.GetConfig().GetName()) &&
+			specContains(spec.GetHosts(), taskInfo.GetRuntime().GetHost()) {
+			sortedTasks = append(sortedTasks, taskInfo)
+		}
+	}
+
+	orderByList := spec.GetPagination().GetOrderBy()
+	sort.Slice(sortedTasks, func(i, j int) bool {
+		return cassandra.Less(orderByList, sortedTasks[i], sortedTasks[j])
+	})
+
+	offset := spec.GetPagination().GetOffset()
+	limit := _defaultQueryLimit
+	if spec.GetPagination().GetLimit() != 0 {
+		limit = spec.GetPagination().GetLimit()
+	}
+
+	end := offset + limit
+	if end > uint32(len(sortedTasks)) {
+		end = uint32(len(sortedTasks))
+	}
+
+	var result []*task.TaskInfo
+	if offset < end {
+		result = sortedTasks[offset:end]
+	}
+	return result, uint32(len(sortedTasks)), nil
+}
+
+// DeleteTaskRuntime deletes runtime of a particular task. The pod
+// events and task configs are retained as for the Cassandra store.
+func (s *Store) DeleteTaskRuntime(
+	ctx context.Context,
+	id *peloton.JobID,
+	instanceID uint32) error {
+	return s.taskRuntimeOps.Delete(ctx, id, instanceID)
+}
+
+// CreateUpdate creates a new update entry.
+// If it already exists, the create will return an error.
+func (s *Store) CreateUpdate(
+	ctx context.Context,
+	updateInfo *models.UpdateModel,
+) error {
+	s.Lock()
+	defer s.Unlock()
+
+	updateID := updateInfo.GetUpdateID().GetValue()
+	if _, ok := s.updates[updateID]; ok {
+		return yarpcerrors.AlreadyExistsErrorf(
+			"%v is not applied, item could exist already", updateID)
+	}
+
+	updateModel := proto.Clone(updateInfo).(*models.UpdateModel)
+	updateModel.InstancesDone = 0
+	updateModel.InstancesFailed = 0
+	updateModel.InstancesCurrent = nil
+	s.updates[updateID] = updateModel
+	return nil
+}
+
+// DeleteUpdate deletes the update and the job configuration created
+// for the update.
+func (s *Store) DeleteUpdate(
+	ctx context.Context,
+	updateID *peloton.UpdateID,
+	jobID *peloton.JobID,
+	jobConfigVersion uint64,
+) error {
+	if err := s.jobConfigOps.Delete(ctx, jobID, jobConfigVersion); err != nil {
+		return err
+	}
+	return s.deleteSingleUpdate(ctx, updateID)
+}
+
+// deleteSingleUpdate deletes an update along with its workflow
+// events and job update events.
+func (s *Store) deleteSingleUpdate(
+	ctx context.Context,
+	updateID *peloton.UpdateID,
+) error {
+	if err := s.jobUpdateEventsOps.Delete(ctx, updateID); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for key := range s.workflowEvents {
+		if key.updateID == updateID.GetValue() {
+			delete(s.workflowEvents, key)
+		}
+	}
+	delete(s.updates, updateID.GetValue())
+	return nil
+}
+
+// GetUpdate fetches the job update.
+func (s *Store) GetUpdate(
+	ctx context.Context,
+	id *peloton.UpdateID,
+) (*models.UpdateModel, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	updateModel, ok := s.updates[id.GetValue()]
+	if !ok {
+		return nil, yarpcerrors.NotFoundErrorf("update not found")
+	}
+	return proto.Clone(updateModel).(*models.UpdateModel), nil
+}
+
+// WriteUpdateProgress writes the progress of the job update.
+// The inputs to this function are the only mutable fields in update.
+func (s *Store) WriteUpdateProgress(
+	ctx context.Context,
+	updateInfo *models.UpdateModel,
+) error {
+	s.Lock()
+	defer s.Unlock()
+
+	updateModel, ok := s.updates[updateInfo.GetUpdateID().GetValue()]
+	if !ok {
+		return yarpcerrors.NotFoundErrorf("update not found")
+	}
+
+	updateModel.UpdateTime = updateInfo.GetUpdateTime()
+	if updateInfo.GetState() != update.State_INVALID {
+		updateModel.State = updateInfo.GetState()
+		updateModel.PrevState = updateInfo.GetPrevState()
+		updateModel.InstancesDone = updateInfo.GetInstancesDone()
+		updateModel.InstancesFailed = updateInfo.GetInstancesFailed()
+		updateModel.InstancesCurrent = updateInfo.GetInstancesCurrent()
+	}
+	if updateInfo.GetOpaqueData() != nil {
+		updateModel.OpaqueData = updateInfo.GetOpaqueData()
+	}
+	if len(updateInfo.GetCompletionTime()) != 0 {
+		updateModel.CompletionTime = updateInfo.GetCompletionTime()
+	}
+	if len(updateInfo.GetCanaryCompletionTime()) != 0 {
+		updateModel.CanaryCompletionTime = updateInfo.GetCanaryCompletionTime()
+	}
+	// canary promotion cannot be undone, so only set it when true
+	if updateInfo.GetCanaryPromoted() {
+		updateModel.CanaryPromoted = true
+	}
+	return nil
+}
+
+// ModifyUpdate modify the progress of an update,
+// instances to update/remove/add and the job config version
+func (s *Store) ModifyUpdate(
+	ctx context.Context,
+	updateInfo *models.UpdateModel,
+) error {
+	s.Lock()
+	defer s.Unlock()
+
+	updateModel, ok := s.updates[updateInfo.GetUpdateID().GetValue()]
+	if !ok {
+		return yarpcerrors.NotFoundErrorf("update not found")
+	}
+
+	updateModel.State = updateInfo.GetState()
+	updateModel.PrevState = updateInfo.GetPrevState()
+	updateModel.InstancesDone = updateInfo.GetInstancesDone()
+	updateModel.InstancesFailed = updateInfo.GetInstancesFailed()
+	updateModel.InstancesCurrent = updateInfo.GetInstancesCurrent()
+	updateModel.InstancesAdded = updateInfo.GetInstancesAdded()
+	updateModel.InstancesUpdated = updateInfo.GetInstancesUpdated()
+	updateModel.InstancesRemoved = updateInfo.GetInstancesRemoved()
+	updateModel.InstancesTotal = updateInfo.GetInstancesTotal()
+	updateModel.JobConfigVersion = updateInfo.GetJobConfigVersion()
+	updateModel.PrevJobConfigVersion = updateInfo.GetPrevJobConfigVersion()
+	updateModel.UpdateTime = updateInfo.GetUpdateTime()
+	if updateInfo.GetOpaqueData() != nil {
+		updateModel.OpaqueData = updateInfo.GetOpaqueData()
+	}
+	return nil
+}
+
+// GetUpdateProgress fetches the job update progress, which includes the
+// instances already updated, instances being updated and the current
+// state of the update.
+func (s *Store) GetUpdateProgress(
+	ctx context.Context,
+	id *peloton.UpdateID,
+) (*models.UpdateModel, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	updateModel, ok := s.updates[id.GetValue()]
+	if !ok {
+		return nil, yarpcerrors.NotFoundErrorf("update not found")
+	}
+	return &models.UpdateModel{
+		UpdateID:             id,
+		State:                updateModel.GetState(),
+		PrevState:            updateModel.GetPrevState(),
+		InstancesTotal:       updateModel.GetInstancesTotal(),
+		InstancesDone:        updateModel.GetInstancesDone(),
+		InstancesFailed:      updateModel.GetInstancesFailed(),
+		InstancesCurrent:     updateModel.GetInstancesCurrent(),
+		UpdateTime:           updateModel.GetUpdateTime(),
+		CompletionTime:       updateModel.GetCompletionTime(),
+		CanaryCompletionTime: updateModel.GetCanaryCompletionTime(),
+		CanaryPromoted:       updateModel.GetCanaryPromoted(),
+	}, nil
+}
+
+// GetUpdatesForJob returns the list of job updates created for a given
+// job, most recently created first.
+func (s *Store) GetUpdatesForJob(
+	ctx context.Context,
+	jobID string,
+) ([]*peloton.UpdateID, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	var updates []*models.UpdateModel
+	for _, updateModel := range s.updates {
+		if updateModel.GetJobID().GetValue() == jobID {
+			updates = append(updates, updateModel)
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return parseTime(updates[i].GetCreationTime()).After(
+			parseTime(updates[j].GetCreationTime()))
+	})
+
+	var updateIDs []*peloton.UpdateID
+	for _, updateModel := range updates {
+		updateIDs = append(updateIDs, updateModel.GetUpdateID())
+	}
+	return updateIDs, nil
+}
+
+// parseTime parses a RFC3339Nano time, returning zero time on failure
+func parseTime(v string) time.Time {
+	r, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}
+	}
+	return r
+}
+
+// AddWorkflowEvent adds workflow events for an update and instance
+// to track the progress
+func (s *Store) AddWorkflowEvent(
+	ctx context.Context,
+	updateID *peloton.UpdateID,
+	instanceID uint32,
+	workflowType models.WorkflowType,
+	workflowState update.State,
+) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := workflowEventKey{
+		updateID:   updateID.GetValue(),
+		instanceID: instanceID,
+	}
+	s.workflowEvents[key] = append([]*workflowEvent{{
+		workflowType:  workflowType,
+		workflowState: workflowState,
+		createTime:    time.Now(),
+	}}, s.workflowEvents[key]...)
+	return nil
+}
+
+// GetWorkflowEvents gets workflow events for an update and instance,
+// events are sorted in descending create timestamp. Consecutive events
+// in the same state are returned once.
+func (s *Store) GetWorkflowEvents(
+	ctx context.Context,
+	updateID *peloton.UpdateID,
+	instanceID uint32,
+	limit uint32,
+) ([]*stateless.WorkflowEvent, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	events := s.workflowEvents[workflowEventKey{
+		updateID:   updateID.GetValue(),
+		instanceID: instanceID,
+	}]
+	if limit > 0 && uint32(len(events)) > limit {
+		events = events[:limit]
+	}
+
+	var workflowEvents []*stateless.WorkflowEvent
+	prevState := stateless.WorkflowState_WORKFLOW_STATE_INVALID
+	for _, event := range events {
+		state := stateless.WorkflowState(event.workflowState)
+		if state == prevState {
+			continue
+		}
+		workflowEvents = append(workflowEvents, &stateless.WorkflowEvent{
+			Type:      stateless.WorkflowType(event.workflowType),
+			State:     state,
+			Timestamp: event.createTime.Format(time.RFC3339),
+		})
+		prevState = state
+	}
+	return workflowEvents, nil
+}
+
+// DeleteWorkflowEventsBefore deletes the workflow events for an update
+// and instance which were created before the given time
+func (s *Store) DeleteWorkflowEventsBefore(
+	ctx context.Context,
+	updateID *peloton.UpdateID,
+	instanceID uint32,
+	before time.Time,
+) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := workflowEventKey{
+		updateID:   updateID.GetValue(),
+		instanceID: instanceID,
+	}
+	var events []*workflowEvent
+	for _, event := range s.workflowEvents[key] {
+		if event.createTime.Before(before) {
+			continue
+		}
+		events = append(events, event)
+	}
+	s.workflowEvents[key] = events
+	return nil
+}
+
+// SetMesosStreamID stores the mesos stream id for a framework name
+func (s *Store) SetMesosStreamID(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.getOrCreateFrameworkInfo(frameworkName).mesosStreamID = mesosStreamID
+	return nil
+}
+
+// SetMesosFrameworkID stores the mesos framework id for a framework name
+func (s *Store) SetMesosFrameworkID(
+	ctx context.Context,
+	frameworkName string,
+	frameworkID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.getOrCreateFrameworkInfo(frameworkName).frameworkID = frameworkID
+	return nil
+}
+
+// getOrCreateFrameworkInfo returns the framework info of a framework,
+// creating it if it does not exist. Must be called with the lock held.
+func (s *Store) getOrCreateFrameworkInfo(frameworkName string) *frameworkInfo {
+	info, ok := s.frameworks[frameworkName]
+	if !ok {
+		info = &frameworkInfo{}
+		s.frameworks[frameworkName] = info
+	}
+	return info
+}
+
+// GetMesosStreamID reads the mesos stream id for a framework name
+func (s *Store) GetMesosStreamID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	info, ok := s.frameworks[frameworkName]
+	if !ok {
+		return "", fmt.Errorf(
+			"FrameworkInfo not found for framework %v", frameworkName)
+	}
+	return info.mesosStreamID, nil
+}
+
+// GetFrameworkID reads the framework id for a framework name
+func (s *Store) GetFrameworkID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	info, ok := s.frameworks[frameworkName]
+	if !ok {
+		return "", fmt.Errorf(
+			"FrameworkInfo not found for framework %v", frameworkName)
+	}
+	return info.frameworkID, nil
+}
+
+// CreatePersistentVolume creates a persistent volume entry.
+func (s *Store) CreatePersistentVolume(
+	ctx context.Context,
+	volume *pb_volume.PersistentVolumeInfo) error {
+	s.Lock()
+	defer s.Unlock()
+
+	volumeID := volume.GetId().GetValue()
+	if _, ok := s.volumes[volumeID]; ok {
+		return yarpcerrors.AlreadyExistsErrorf(
+			"%v is not applied, item could exist already", volumeID)
+	}
+
+	now := time.Now().UTC().String()
+	volume = proto.Clone(volume).(*pb_volume.PersistentVolumeInfo)
+	volume.CreateTime = now
+	volume.UpdateTime = now
+	s.volumes[volumeID] = volume
+	return nil
+}
+
+// UpdatePersistentVolume updates persistent volume info.
+func (s *Store) UpdatePersistentVolume(
+	ctx context.Context,
+	volumeInfo *pb_volume.PersistentVolumeInfo) error {
+	s.Lock()
+	defer s.Unlock()
+
+	volume, ok := s.volumes[volumeInfo.GetId().GetValue()]
+	if !ok {
+		volume = &pb_volume.PersistentVolumeInfo{Id: volumeInfo.GetId()}
+		s.volumes[volumeInfo.GetId().GetValue()] = volume
+	}
+	volume.State = volumeInfo.GetState()
+	volume.GoalState = volumeInfo.GetGoalState()
+	volume.UpdateTime = time.Now().UTC().String()
+	return nil
+}
+
+// GetPersistentVolume gets the persistent volume object.
+func (s *Store) GetPersistentVolume(
+	ctx context.Context,
+	volumeID *peloton.VolumeID) (*pb_volume.PersistentVolumeInfo, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	volume, ok := s.volumes[volumeID.GetValue()]
+	if !ok {
+		return nil, &storage.VolumeNotFoundError{VolumeID: volumeID}
+	}
+	return proto.Clone(volume).(*pb_volume.PersistentVolumeInfo), nil
+}
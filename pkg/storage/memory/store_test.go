@@ -0,0 +1,179 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/api/v0/update"
+	"github.com/uber/peloton/.gen/peloton/private/models"
+
+	ormobjects "github.com/uber/peloton/pkg/storage/objects"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type MemoryStoreTestSuite struct {
+	suite.Suite
+
+	ormStore *ormobjects.Store
+	store    *Store
+	jobID    *peloton.JobID
+}
+
+func (s *MemoryStoreTestSuite) SetupTest() {
+	var err error
+	s.ormStore, err = ormobjects.NewMemoryStore(tally.NoopScope)
+	s.Require().NoError(err)
+	s.store = NewStore(s.ormStore)
+	s.jobID = &peloton.JobID{Value: uuid.New()}
+}
+
+func TestMemoryStore(t *testing.T) {
+	suite.Run(t, new(MemoryStoreTestSuite))
+}
+
+// newRuntime returns a runtime of an instance of the test job in its
+// run runID
+func (s *MemoryStoreTestSuite) newRuntime(
+	instanceID uint32,
+	runID uint64,
+	state task.TaskState,
+) *task.RuntimeInfo {
+	mesosTaskID := fmt.Sprintf("%s-%d-%d", s.jobID.GetValue(), instanceID, runID)
+	return &task.RuntimeInfo{
+		State:       state,
+		MesosTaskId: &mesos.TaskID{Value: &mesosTaskID},
+	}
+}
+
+// TestTaskRuntime tests creating, updating, reading and deleting task
+// runtimes
+func (s *MemoryStoreTestSuite) TestTaskRuntime() {
+	ctx := context.Background()
+
+	for i := uint32(0); i < 3; i++ {
+		s.NoError(s.store.CreateTaskRuntime(
+			ctx,
+			s.jobID,
+			i,
+			s.newRuntime(i, 1, task.TaskState_INITIALIZED),
+			"owner",
+			job.JobType_BATCH))
+	}
+
+	runtime := s.newRuntime(1, 1, task.TaskState_RUNNING)
+	s.NoError(s.store.UpdateTaskRuntime(
+		ctx, s.jobID, 1, runtime, job.JobType_BATCH))
+
+	actual, err := s.store.GetTaskRuntime(ctx, s.jobID, 1)
+	s.NoError(err)
+	s.True(proto.Equal(runtime, actual))
+
+	runtimes, errs := s.store.GetTaskRuntimes(ctx, s.jobID, []uint32{0, 1, 5})
+	s.Len(runtimes, 2)
+	s.Equal(task.TaskState_RUNNING, runtimes[1].GetState())
+	s.Len(errs, 1)
+	s.True(yarpcerrors.IsNotFound(errs[5]))
+
+	events, err := s.store.GetPodEvents(ctx, s.jobID.GetValue(), 1)
+	s.NoError(err)
+	s.NotEmpty(events)
+
+	s.NoError(s.store.DeleteTaskRuntime(ctx, s.jobID, 1))
+	_, err = s.store.GetTaskRuntime(ctx, s.jobID, 1)
+	s.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestTaskRuntimeORM tests that the task runtimes of the store are the
+// rows of the ORM task_runtime table, which the job cache updates
+// conditionally
+func (s *MemoryStoreTestSuite) TestTaskRuntimeORM() {
+	ctx := context.Background()
+
+	runtime := s.newRuntime(0, 1, task.TaskState_INITIALIZED)
+	runtime.Revision = &peloton.ChangeLog{Version: 1}
+	s.NoError(s.store.CreateTaskRuntime(
+		ctx, s.jobID, 0, runtime, "owner", job.JobType_BATCH))
+
+	newRuntime := s.newRuntime(0, 1, task.TaskState_RUNNING)
+	newRuntime.Revision = &peloton.ChangeLog{Version: 2}
+	s.NoError(ormobjects.NewTaskRuntimeOps(s.ormStore).CompareAndSet(
+		ctx, s.jobID, 0, newRuntime, 1))
+
+	actual, err := s.store.GetTaskRuntime(ctx, s.jobID, 0)
+	s.NoError(err)
+	s.True(proto.Equal(newRuntime, actual))
+
+	events, err := s.store.GetPodEvents(ctx, s.jobID.GetValue(), 0)
+	s.NoError(err)
+	s.Len(events, 2)
+
+	// a new run of the task, then the events of the first run are deleted
+	s.NoError(s.store.UpdateTaskRuntime(
+		ctx,
+		s.jobID,
+		0,
+		s.newRuntime(0, 2, task.TaskState_INITIALIZED),
+		job.JobType_BATCH))
+	s.NoError(s.store.DeletePodEvents(ctx, s.jobID.GetValue(), 0, 1, 2))
+	events, err = s.store.GetPodEvents(
+		ctx, s.jobID.GetValue(), 0, fmt.Sprintf("%s-0-1", s.jobID.GetValue()))
+	s.NoError(err)
+	s.Empty(events)
+	events, err = s.store.GetPodEvents(ctx, s.jobID.GetValue(), 0)
+	s.NoError(err)
+	s.Len(events, 1)
+}
+
+// TestUpdate tests creating, modifying and reading a job update
+func (s *MemoryStoreTestSuite) TestUpdate() {
+	ctx := context.Background()
+	updateID := &peloton.UpdateID{Value: uuid.New()}
+
+	_, err := s.store.GetUpdate(ctx, updateID)
+	s.True(yarpcerrors.IsNotFound(err))
+
+	update := &models.UpdateModel{
+		UpdateID:       updateID,
+		JobID:          s.jobID,
+		State:          update.State_INITIALIZED,
+		InstancesTotal: 3,
+	}
+	s.NoError(s.store.CreateUpdate(ctx, update))
+	s.True(yarpcerrors.IsAlreadyExists(s.store.CreateUpdate(ctx, update)))
+
+	s.NoError(s.store.WriteUpdateProgress(ctx, &models.UpdateModel{
+		UpdateID:      updateID,
+		State:         update.State_ROLLING_FORWARD,
+		InstancesDone: 2,
+	}))
+
+	actual, err := s.store.GetUpdate(ctx, updateID)
+	s.NoError(err)
+	s.Equal(update.State_ROLLING_FORWARD, actual.GetState())
+	s.Equal(uint32(2), actual.GetInstancesDone())
+	s.Equal(uint32(3), actual.GetInstancesTotal())
+}
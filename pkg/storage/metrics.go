@@ -70,16 +70,20 @@ type JobMetrics struct {
 // OrmJobMetrics tracks counters for job related tables accessed through ORM layer
 type OrmJobMetrics struct {
 	// job_index
-	JobIndexCreate     tally.Counter
-	JobIndexCreateFail tally.Counter
-	JobIndexGet        tally.Counter
-	JobIndexGetFail    tally.Counter
-	JobIndexGetAll     tally.Counter
-	JobIndexGetAllFail tally.Counter
-	JobIndexUpdate     tally.Counter
-	JobIndexUpdateFail tally.Counter
-	JobIndexDelete     tally.Counter
-	JobIndexDeleteFail tally.Counter
+	JobIndexCreate      tally.Counter
+	JobIndexCreateFail  tally.Counter
+	JobIndexGet         tally.Counter
+	JobIndexGetFail     tally.Counter
+	JobIndexGetAll      tally.Counter
+	JobIndexGetAllFail  tally.Counter
+	JobIndexUpdate      tally.Counter
+	JobIndexUpdateFail  tally.Counter
+	JobIndexDelete      tally.Counter
+	JobIndexDeleteFail  tally.Counter
+	JobIndexQuery       tally.Counter
+	JobIndexQueryFail   tally.Counter
+	JobIndexRebuild     tally.Counter
+	JobIndexRebuildFail tally.Counter
 
 	// job_name_to_id
 	JobNameToIDCreate     tally.Counter
@@ -304,17 +308,39 @@ type OrmTaskMetrics struct {
 	PodEventsGet     tally.Counter
 	PodEventsGetFail tally.Counter
 
+	PodEventsDelete     tally.Counter
+	PodEventsDeleteFail tally.Counter
+
 	TaskConfigV2Create     tally.Counter
 	TaskConfigV2CreateFail tally.Counter
 
 	TaskConfigV2Get     tally.Counter
 	TaskConfigV2GetFail tally.Counter
 
+	TaskConfigV2Delete     tally.Counter
+	TaskConfigV2DeleteFail tally.Counter
+
 	TaskConfigLegacyGet     tally.Counter
 	TaskConfigLegacyGetFail tally.Counter
 
 	PodSpecGet     tally.Counter
 	PodSpecGetFail tally.Counter
+
+	TaskRuntimeCreate     tally.Counter
+	TaskRuntimeCreateFail tally.Counter
+
+	TaskRuntimeGet     tally.Counter
+	TaskRuntimeGetFail tally.Counter
+
+	TaskRuntimeGetAll     tally.Counter
+	TaskRuntimeGetAllFail tally.Counter
+
+	TaskRuntimeDelete     tally.Counter
+	TaskRuntimeDeleteFail tally.Counter
+
+	TaskRuntimeCAS         tally.Counter
+	TaskRuntimeCASFail     tally.Counter
+	TaskRuntimeCASConflict tally.Counter
 }
 
 // OrmHostInfoMetrics tracks counters for host info related table
@@ -632,6 +658,12 @@ func NewMetrics(scope tally.Scope) *Metrics {
 	podSpecFailScope := podSpecScope.Tagged(
 		map[string]string{"result": "fail"})
 
+	taskRuntimeScope := ormScope.SubScope("task_runtime")
+	taskRuntimeSuccessScope := taskRuntimeScope.Tagged(
+		map[string]string{"result": "success"})
+	taskRuntimeFailScope := taskRuntimeScope.Tagged(
+		map[string]string{"result": "fail"})
+
 	respoolScope := ormScope.SubScope("respool")
 	respoolSuccessScope := respoolScope.Tagged(
 		map[string]string{"result": "success"})
@@ -651,16 +683,20 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		map[string]string{"result": "fail"})
 
 	ormJobMetrics := &OrmJobMetrics{
-		JobIndexCreate:     jobIndexSuccessScope.Counter("create"),
-		JobIndexCreateFail: jobIndexFailScope.Counter("create"),
-		JobIndexGet:        jobIndexSuccessScope.Counter("get"),
-		JobIndexGetFail:    jobIndexFailScope.Counter("get"),
-		JobIndexGetAll:     jobIndexSuccessScope.Counter("geAll"),
-		JobIndexGetAllFail: jobIndexFailScope.Counter("getAll"),
-		JobIndexUpdate:     jobIndexSuccessScope.Counter("update"),
-		JobIndexUpdateFail: jobIndexFailScope.Counter("update"),
-		JobIndexDelete:     jobIndexSuccessScope.Counter("delete"),
-		JobIndexDeleteFail: jobIndexFailScope.Counter("delete"),
+		JobIndexCreate:      jobIndexSuccessScope.Counter("create"),
+		JobIndexCreateFail:  jobIndexFailScope.Counter("create"),
+		JobIndexGet:         jobIndexSuccessScope.Counter("get"),
+		JobIndexGetFail:     jobIndexFailScope.Counter("get"),
+		JobIndexGetAll:      jobIndexSuccessScope.Counter("geAll"),
+		JobIndexGetAllFail:  jobIndexFailScope.Counter("getAll"),
+		JobIndexUpdate:      jobIndexSuccessScope.Counter("update"),
+		JobIndexUpdateFail:  jobIndexFailScope.Counter("update"),
+		JobIndexDelete:      jobIndexSuccessScope.Counter("delete"),
+		JobIndexDeleteFail:  jobIndexFailScope.Counter("delete"),
+		JobIndexQuery:       jobIndexSuccessScope.Counter("query"),
+		JobIndexQueryFail:   jobIndexFailScope.Counter("query"),
+		JobIndexRebuild:     jobIndexSuccessScope.Counter("rebuild_query_index"),
+		JobIndexRebuildFail: jobIndexFailScope.Counter("rebuild_query_index"),
 
 		JobNameToIDCreate:     jobNameToIDSuccessScope.Counter("create"),
 		JobNameToIDCreateFail: jobNameToIDFailScope.Counter("create"),
@@ -711,17 +747,39 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		PodEventsGet:     podEventsSuccessScope.Counter("get"),
 		PodEventsGetFail: podEventsFailScope.Counter("get"),
 
+		PodEventsDelete:     podEventsSuccessScope.Counter("delete"),
+		PodEventsDeleteFail: podEventsFailScope.Counter("delete"),
+
 		TaskConfigV2Create:     taskConfigV2SuccessScope.Counter("create"),
 		TaskConfigV2CreateFail: taskConfigV2FailScope.Counter("create"),
 
 		TaskConfigV2Get:     taskConfigV2SuccessScope.Counter("get"),
 		TaskConfigV2GetFail: taskConfigV2FailScope.Counter("get"),
 
+		TaskConfigV2Delete:     taskConfigV2SuccessScope.Counter("delete"),
+		TaskConfigV2DeleteFail: taskConfigV2FailScope.Counter("delete"),
+
 		TaskConfigLegacyGet:     taskConfigV2SuccessScope.Counter("get_legacy"),
 		TaskConfigLegacyGetFail: taskConfigV2FailScope.Counter("get_legacy"),
 
 		PodSpecGet:     podSpecSuccessScope.Counter("get"),
 		PodSpecGetFail: podSpecFailScope.Counter("get"),
+
+		TaskRuntimeCreate:     taskRuntimeSuccessScope.Counter("create"),
+		TaskRuntimeCreateFail: taskRuntimeFailScope.Counter("create"),
+
+		TaskRuntimeGet:     taskRuntimeSuccessScope.Counter("get"),
+		TaskRuntimeGetFail: taskRuntimeFailScope.Counter("get"),
+
+		TaskRuntimeGetAll:     taskRuntimeSuccessScope.Counter("get_all"),
+		TaskRuntimeGetAllFail: taskRuntimeFailScope.Counter("get_all"),
+
+		TaskRuntimeDelete:     taskRuntimeSuccessScope.Counter("delete"),
+		TaskRuntimeDeleteFail: taskRuntimeFailScope.Counter("delete"),
+
+		TaskRuntimeCAS:         taskRuntimeSuccessScope.Counter("cas"),
+		TaskRuntimeCASFail:     taskRuntimeFailScope.Counter("cas"),
+		TaskRuntimeCASConflict: taskRuntimeFailScope.Counter("cas_conflict"),
 	}
 
 	ormHostInfoMetrics := &OrmHostInfoMetrics{
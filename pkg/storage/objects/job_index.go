@@ -18,11 +18,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
 	"github.com/uber/peloton/pkg/storage/objects/base"
+	"github.com/uber/peloton/pkg/storage/orm"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -125,6 +128,18 @@ type JobIndexOps interface {
 
 	// Delete removes an object from the table.
 	Delete(ctx context.Context, id *peloton.JobID) error
+
+	// Query returns the summaries of the jobs in the resource pool which
+	// match the spec, along with the total number of matching jobs capped
+	// by the max limit of the spec.
+	Query(
+		ctx context.Context,
+		respoolID *peloton.ResourcePoolID,
+		spec *job.QuerySpec,
+	) ([]*job.JobSummary, uint32, error)
+
+	// RebuildQueryIndex adds the rows of all the jobs to the query index.
+	RebuildQueryIndex(ctx context.Context) error
 }
 
 // ensure that default implementation (jobIndexOps) satisfies the interface
@@ -301,6 +316,11 @@ func (d *jobIndexOps) Create(
 		return err
 	}
 
+	if err = d.addQueryTerms(ctx, id, jobQueryTerms(obj)); err != nil {
+		d.store.metrics.OrmJobMetrics.JobIndexCreateFail.Inc(1)
+		return err
+	}
+
 	d.store.metrics.OrmJobMetrics.JobIndexCreate.Inc(1)
 	return nil
 }
@@ -379,6 +399,24 @@ func (d *jobIndexOps) Update(
 		return errors.Wrap(err, "Failed to construct JobIndexObject")
 	}
 
+	// The current terms of the updated columns are read so that the ones
+	// no longer valid after the update, such as the previous state, can
+	// be removed. Failing to read them only leaves stale terms behind,
+	// which Query filters out.
+	var currentTerms []string
+	if current, err := d.Get(ctx, id); err == nil {
+		if config != nil {
+			currentTerms = append(currentTerms, jobQueryConfigTerms(current)...)
+		}
+		if runtime != nil {
+			currentTerms = append(currentTerms, jobQueryRuntimeTerms(current)...)
+		}
+	} else if !yarpcerrors.IsNotFound(err) {
+		log.WithField("job_id", id.GetValue()).
+			WithError(err).
+			Warn("Failed to read job_index terms to remove")
+	}
+
 	fields := []string{}
 	if config != nil {
 		fields = append(fields, _configFields...)
@@ -398,6 +436,24 @@ func (d *jobIndexOps) Update(
 		return err
 	}
 
+	// Only the terms of the indexed columns which changed are written,
+	// so that most runtime updates, which keep the state of the job,
+	// do not touch job_query_index. All the terms are added if the
+	// current ones could not be read, adding a term is idempotent.
+	var terms []string
+	if config != nil {
+		terms = jobQueryConfigTerms(obj)
+	}
+	if runtime != nil {
+		terms = append(terms, jobQueryRuntimeTerms(obj)...)
+	}
+	if err = d.addQueryTerms(
+		ctx, id, subtractTerms(terms, currentTerms)); err != nil {
+		d.store.metrics.OrmJobMetrics.JobIndexUpdateFail.Inc(1)
+		return err
+	}
+	d.deleteQueryTerms(ctx, id, subtractTerms(currentTerms, terms))
+
 	d.store.metrics.OrmJobMetrics.JobIndexUpdate.Inc(1)
 	return nil
 }
@@ -407,6 +463,16 @@ func (d *jobIndexOps) Delete(
 	ctx context.Context,
 	id *peloton.JobID,
 ) error {
+	// Failing to read the terms of the job only leaves them behind,
+	// Query skips the terms of deleted jobs.
+	if current, err := d.Get(ctx, id); err == nil {
+		d.deleteQueryTerms(ctx, id, jobQueryTerms(current))
+	} else if !yarpcerrors.IsNotFound(err) {
+		log.WithField("job_id", id.GetValue()).
+			WithError(err).
+			Warn("Failed to read job_index terms to remove")
+	}
+
 	jobIndexObject := &JobIndexObject{
 		JobID: base.NewOptionalString(id.GetValue()),
 	}
@@ -417,3 +483,317 @@ func (d *jobIndexOps) Delete(
 	d.store.metrics.OrmJobMetrics.JobIndexDelete.Inc(1)
 	return nil
 }
+
+// Query returns the summaries of the jobs in the resource pool which
+// match the spec. The candidate jobs are looked up in job_query_index
+// and then matched against their job_index row, so that the result
+// never depends on stale index terms.
+func (d *jobIndexOps) Query(
+	ctx context.Context,
+	respoolID *peloton.ResourcePoolID,
+	spec *job.QuerySpec,
+) ([]*job.JobSummary, uint32, error) {
+	if spec == nil {
+		return nil, 0, nil
+	}
+
+	filter, err := newJobQueryFilter(respoolID, spec)
+	if err != nil {
+		d.store.metrics.OrmJobMetrics.JobIndexQueryFail.Inc(1)
+		return nil, 0, err
+	}
+
+	less, err := newJobQueryLess(spec.GetPagination().GetOrderBy())
+	if err != nil {
+		d.store.metrics.OrmJobMetrics.JobIndexQueryFail.Inc(1)
+		return nil, 0, err
+	}
+
+	begin, err := JobQueryOffset(spec.GetPagination())
+	if err != nil {
+		d.store.metrics.OrmJobMetrics.JobIndexQueryFail.Inc(1)
+		return nil, 0, err
+	}
+
+	maxLimit := _jobQueryDefaultMaxLimit
+	if spec.GetPagination().GetMaxLimit() != 0 {
+		maxLimit = spec.GetPagination().GetMaxLimit()
+	}
+
+	objs, err := d.queryMatches(
+		ctx, filter, spec.GetPagination().GetOrderBy(), less, maxLimit)
+	if err != nil {
+		log.WithField("spec", spec).
+			WithError(err).
+			Error("Failed to query jobs")
+		d.store.metrics.OrmJobMetrics.JobIndexQueryFail.Inc(1)
+		return nil, 0, err
+	}
+	total := uint32(len(objs))
+
+	// Apply offset and limit.
+	if begin > total {
+		begin = total
+	}
+	objs = objs[begin:]
+
+	end := _jobQueryDefaultLimit
+	if limit := spec.GetPagination().GetLimit(); limit > 0 {
+		end = limit
+	}
+	if end > uint32(len(objs)) {
+		end = uint32(len(objs))
+	}
+	objs = objs[:end]
+
+	summaries := make([]*job.JobSummary, 0, len(objs))
+	for _, obj := range objs {
+		summary, err := obj.ToJobSummary()
+		if err != nil {
+			d.store.metrics.OrmJobMetrics.JobIndexQueryFail.Inc(1)
+			return nil, 0, err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	d.store.metrics.OrmJobMetrics.JobIndexQuery.Inc(1)
+	return summaries, total, nil
+}
+
+// RebuildQueryIndex adds the job_query_index rows of all the jobs in
+// job_index. It backfills the index for jobs which were created before
+// it was maintained. Stale rows are left in place, Query filters them out.
+func (d *jobIndexOps) RebuildQueryIndex(ctx context.Context) error {
+	objs, err := d.store.oClient.GetAll(ctx, &JobIndexObject{})
+	if err != nil {
+		d.store.metrics.OrmJobMetrics.JobIndexRebuildFail.Inc(1)
+		return err
+	}
+
+	for _, obj := range objs {
+		jobIndexObject := obj.(*JobIndexObject)
+		id := &peloton.JobID{Value: jobIndexObject.JobID.String()}
+		if err := d.addQueryTerms(
+			ctx, id, jobQueryTerms(jobIndexObject)); err != nil {
+			d.store.metrics.OrmJobMetrics.JobIndexRebuildFail.Inc(1)
+			return err
+		}
+	}
+
+	log.WithField("jobs", len(objs)).Info("Rebuilt job query index")
+	d.store.metrics.OrmJobMetrics.JobIndexRebuild.Inc(1)
+	return nil
+}
+
+// queryMatches returns the first limit job_index rows matching the filter
+// in the order of the query. The candidate jobs are looked up through the
+// job_query_index terms of the filter, one creation day at a time if the
+// query is restricted to a creation time range. If the query is ordered
+// by creation time, the days are looked up in that order and the lookup
+// stops once limit rows match, the jobs created on the following days
+// coming after them. Queries without any term scan job_index.
+func (d *jobIndexOps) queryMatches(
+	ctx context.Context,
+	filter *jobQueryFilter,
+	orderBy []*query.OrderBy,
+	less func(a, b *JobIndexObject) bool,
+	limit uint32,
+) ([]*JobIndexObject, error) {
+	byCreation, descending := jobQueryCreationOrder(orderBy)
+	days := filter.creationDays(descending)
+	if days == nil {
+		days = []string{""}
+	}
+
+	var matches []*JobIndexObject
+	seen := make(map[string]bool)
+	termJobIDs := make(map[string][]string)
+	for _, day := range days {
+		groups := filter.indexTerms(day)
+		if len(groups) == 0 {
+			return d.scanMatches(ctx, filter, less, limit)
+		}
+
+		jobIDs, err := d.lookupQueryTerms(ctx, groups, termJobIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, jobID := range jobIDs {
+			if seen[jobID] {
+				continue
+			}
+			seen[jobID] = true
+
+			obj, err := d.Get(ctx, &peloton.JobID{Value: jobID})
+			if yarpcerrors.IsNotFound(err) {
+				// the job was deleted, but not its terms
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if filter.matches(obj) {
+				matches = append(matches, obj)
+			}
+		}
+
+		if byCreation && uint32(len(matches)) >= limit {
+			break
+		}
+	}
+	return sortJobIndexObjects(matches, less, limit), nil
+}
+
+// lookupQueryTerms returns the IDs of the jobs which have at least one
+// term of every group. The jobs of the terms already read are cached in
+// termJobIDs.
+func (d *jobIndexOps) lookupQueryTerms(
+	ctx context.Context,
+	groups [][]string,
+	termJobIDs map[string][]string,
+) ([]string, error) {
+	var jobIDs map[string]bool
+	for _, terms := range groups {
+		matched := make(map[string]bool)
+		for _, term := range terms {
+			ids, ok := termJobIDs[term]
+			if !ok {
+				objs, err := d.store.oClient.GetAll(
+					ctx, &JobQueryIndexObject{Term: term})
+				if err != nil {
+					return nil, err
+				}
+				ids = make([]string, 0, len(objs))
+				for _, obj := range objs {
+					ids = append(ids, obj.(*JobQueryIndexObject).JobID)
+				}
+				termJobIDs[term] = ids
+			}
+			for _, jobID := range ids {
+				if jobIDs == nil || jobIDs[jobID] {
+					matched[jobID] = true
+				}
+			}
+		}
+		if len(matched) == 0 {
+			return nil, nil
+		}
+		jobIDs = matched
+	}
+
+	result := make([]string, 0, len(jobIDs))
+	for jobID := range jobIDs {
+		result = append(result, jobID)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// scanMatches returns the first limit job_index rows matching the filter
+// in the order of the query, reading all the rows of job_index.
+func (d *jobIndexOps) scanMatches(
+	ctx context.Context,
+	filter *jobQueryFilter,
+	less func(a, b *JobIndexObject) bool,
+	limit uint32,
+) ([]*JobIndexObject, error) {
+	table, err := orm.TableFromObject(&JobIndexObject{})
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := d.store.oClient.GetAllIter(ctx, &JobIndexObject{})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var matches []*JobIndexObject
+	for {
+		row, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			break
+		}
+
+		obj := &JobIndexObject{}
+		table.SetObjectFromRow(obj, row)
+		if filter.matches(obj) {
+			matches = append(matches, obj)
+		}
+	}
+	return sortJobIndexObjects(matches, less, limit), nil
+}
+
+// sortJobIndexObjects sorts the job_index rows in the order of the query
+// and returns the first limit ones.
+func sortJobIndexObjects(
+	objs []*JobIndexObject,
+	less func(a, b *JobIndexObject) bool,
+	limit uint32,
+) []*JobIndexObject {
+	sort.Slice(objs, func(i, j int) bool {
+		return less(objs[i], objs[j])
+	})
+	if uint32(len(objs)) > limit {
+		objs = objs[:limit]
+	}
+	return objs
+}
+
+// addQueryTerms adds the job_query_index rows mapping the terms to the job.
+func (d *jobIndexOps) addQueryTerms(
+	ctx context.Context,
+	id *peloton.JobID,
+	terms []string,
+) error {
+	for _, term := range terms {
+		if err := d.store.oClient.Create(ctx, &JobQueryIndexObject{
+			Term:  term,
+			JobID: id.GetValue(),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteQueryTerms deletes the job_query_index rows mapping the terms to
+// the job. Failures are only logged since Query filters out stale terms.
+func (d *jobIndexOps) deleteQueryTerms(
+	ctx context.Context,
+	id *peloton.JobID,
+	terms []string,
+) {
+	for _, term := range terms {
+		if err := d.store.oClient.Delete(ctx, &JobQueryIndexObject{
+			Term:  term,
+			JobID: id.GetValue(),
+		}); err != nil {
+			log.WithField("job_id", id.GetValue()).
+				WithField("term", term).
+				WithError(err).
+				Warn("Failed to delete job_query_index term")
+		}
+	}
+}
+
+// subtractTerms returns the terms of a which are not in b.
+func subtractTerms(a, b []string) []string {
+	var terms []string
+	for _, term := range a {
+		found := false
+		for _, other := range b {
+			if term == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
@@ -17,6 +17,7 @@ package objects
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -25,6 +26,7 @@ import (
 	mesos_v1 "github.com/uber/peloton/.gen/mesos/v1"
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/pkg/storage/objects/base"
 	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
@@ -356,8 +358,9 @@ func (s *JobIndexObjectTestSuite) TestJobIndexOpsClientFail() {
 
 	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
 		Return(errors.New("create failed"))
+	// Update and Delete also read the row to find the terms to remove
 	mockClient.EXPECT().Get(gomock.Any(), gomock.Any()).
-		Return(errors.New("get failed")).Times(2)
+		Return(errors.New("get failed")).Times(4)
 	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
 		Return(nil, errors.New("getAll failed"))
 	mockClient.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).
@@ -393,6 +396,408 @@ func (s *JobIndexObjectTestSuite) TestJobIndexOpsClientFail() {
 	s.Equal("delete failed", err.Error())
 }
 
+// TestQueryJobIndex tests querying jobs through the job query index
+func (s *JobIndexObjectTestSuite) TestQueryJobIndex() {
+	db := NewJobIndexOps(testStore)
+	ctx := context.Background()
+
+	// a unique owner and name keep the jobs of other tests out of the
+	// query results
+	owner := uuid.New()
+	name := uuid.New()
+	now := time.Now().UTC()
+
+	var jobIDs []*peloton.JobID
+	for i, state := range []job.JobState{
+		job.JobState_RUNNING,
+		job.JobState_SUCCEEDED,
+		job.JobState_PENDING,
+	} {
+		jobID := &peloton.JobID{Value: uuid.New()}
+		jobIDs = append(jobIDs, jobID)
+
+		config := proto.Clone(s.config).(*job.JobConfig)
+		config.OwningTeam = owner
+		config.Name = fmt.Sprintf("%s-%d", name, i)
+		config.Labels = []*peloton.Label{
+			{Key: "index", Value: fmt.Sprintf("%s-%d", name, i)},
+			{Key: "org", Value: "peloton"},
+		}
+
+		runtime := proto.Clone(s.runtime).(*job.RuntimeInfo)
+		runtime.State = state
+		runtime.CreationTime = now.Add(-time.Duration(i) * time.Hour).
+			Format(time.RFC3339Nano)
+		runtime.CompletionTime = ""
+
+		s.NoError(db.Create(ctx, jobID, config, runtime, nil))
+	}
+
+	testcases := []struct {
+		description string
+		respoolID   *peloton.ResourcePoolID
+		spec        *job.QuerySpec
+		expected    []*peloton.JobID
+		total       uint32
+	}{
+		{
+			description: "by owner, newest first",
+			spec:        &job.QuerySpec{Owner: owner},
+			expected:    jobIDs,
+			total:       3,
+		},
+		{
+			description: "by owner and resource pool",
+			respoolID:   s.config.GetRespoolID(),
+			spec:        &job.QuerySpec{Owner: owner},
+			expected:    jobIDs,
+			total:       3,
+		},
+		{
+			description: "by owner in another resource pool",
+			respoolID:   &peloton.ResourcePoolID{Value: uuid.New()},
+			spec:        &job.QuerySpec{Owner: owner},
+			total:       0,
+		},
+		{
+			description: "by label",
+			spec: &job.QuerySpec{
+				Labels: []*peloton.Label{
+					{Key: "index", Value: name + "-1"},
+					{Key: "org", Value: "peloton"},
+				},
+			},
+			expected: jobIDs[1:2],
+			total:    1,
+		},
+		{
+			description: "by name without index terms",
+			spec:        &job.QuerySpec{Name: name},
+			expected:    jobIDs,
+			total:       3,
+		},
+		{
+			description: "by keyword and state",
+			spec: &job.QuerySpec{
+				Owner:     owner,
+				Keywords:  []string{"SIMPLE JOB"},
+				JobStates: []job.JobState{job.JobState_RUNNING, job.JobState_PENDING},
+			},
+			expected: []*peloton.JobID{jobIDs[0], jobIDs[2]},
+			total:    2,
+		},
+		{
+			description: "terminal state within the default time range",
+			spec: &job.QuerySpec{
+				Name:      name,
+				JobStates: []job.JobState{job.JobState_SUCCEEDED},
+			},
+			expected: jobIDs[1:2],
+			total:    1,
+		},
+		{
+			description: "by creation time range",
+			spec: &job.QuerySpec{
+				Name: name,
+				CreationTimeRange: &peloton.TimeRange{
+					Min: toTimestamp(now.Add(-90 * time.Minute)),
+					Max: toTimestamp(now.Add(time.Minute)),
+				},
+			},
+			expected: jobIDs[:2],
+			total:    2,
+		},
+		{
+			description: "ordered by name with offset and limit",
+			spec: &job.QuerySpec{
+				Owner: owner,
+				Pagination: &query.PaginationSpec{
+					Offset: 1,
+					Limit:  1,
+					OrderBy: []*query.OrderBy{
+						{
+							Order:    query.OrderBy_ASC,
+							Property: &query.PropertyPath{Value: "name"},
+						},
+					},
+				},
+			},
+			expected: jobIDs[1:2],
+			total:    3,
+		},
+		{
+			description: "page token",
+			spec: &job.QuerySpec{
+				Owner: owner,
+				Pagination: &query.PaginationSpec{
+					PageToken: EncodeJobQueryPageToken(2),
+				},
+			},
+			expected: jobIDs[2:],
+			total:    3,
+		},
+	}
+
+	for _, tc := range testcases {
+		summaries, total, err := db.Query(ctx, tc.respoolID, tc.spec)
+		s.NoError(err, tc.description)
+		s.Equal(tc.total, total, tc.description)
+
+		var ids []*peloton.JobID
+		for _, summary := range summaries {
+			ids = append(ids, summary.GetId())
+		}
+		s.Equal(tc.expected, ids, tc.description)
+	}
+
+	// max limit keeps the first jobs in the order of the query, with
+	// and without a creation time range
+	for _, spec := range []*job.QuerySpec{
+		{
+			Owner:      owner,
+			Pagination: &query.PaginationSpec{MaxLimit: 2},
+		},
+		{
+			Name: name,
+			CreationTimeRange: &peloton.TimeRange{
+				Min: toTimestamp(now.AddDate(0, 0, -2)),
+				Max: toTimestamp(now.Add(time.Minute)),
+			},
+			Pagination: &query.PaginationSpec{MaxLimit: 2},
+		},
+		{
+			Name: name,
+			CreationTimeRange: &peloton.TimeRange{
+				Min: toTimestamp(now.AddDate(0, 0, -2)),
+				Max: toTimestamp(now.Add(time.Minute)),
+			},
+			Pagination: &query.PaginationSpec{
+				MaxLimit: 2,
+				OrderBy: []*query.OrderBy{
+					{
+						Order:    query.OrderBy_ASC,
+						Property: &query.PropertyPath{Value: "creation_time"},
+					},
+				},
+			},
+		},
+	} {
+		summaries, total, err := db.Query(ctx, nil, spec)
+		s.NoError(err)
+		s.Equal(uint32(2), total)
+
+		var ids []*peloton.JobID
+		for _, summary := range summaries {
+			ids = append(ids, summary.GetId())
+		}
+		if len(spec.GetPagination().GetOrderBy()) == 0 {
+			s.Equal(jobIDs[:2], ids)
+		} else {
+			s.Equal([]*peloton.JobID{jobIDs[2], jobIDs[1]}, ids)
+		}
+	}
+
+	// time ranges ending before they start and unknown order by
+	// properties are rejected
+	_, _, err = db.Query(ctx, nil, &job.QuerySpec{
+		Owner: owner,
+		CreationTimeRange: &peloton.TimeRange{
+			Min: toTimestamp(now),
+			Max: toTimestamp(now.Add(-time.Hour)),
+		},
+	})
+	s.True(yarpcerrors.IsInvalidArgument(err))
+
+	_, _, err = db.Query(ctx, nil, &job.QuerySpec{
+		Owner: owner,
+		Pagination: &query.PaginationSpec{
+			OrderBy: []*query.OrderBy{
+				{Property: &query.PropertyPath{Value: "config"}},
+			},
+		},
+	})
+	s.True(yarpcerrors.IsInvalidArgument(err))
+
+	for _, jobID := range jobIDs {
+		s.NoError(db.Delete(ctx, jobID))
+	}
+}
+
+// TestQueryJobIndexTerms tests that the job query index follows the
+// updates and the deletion of a job
+func (s *JobIndexObjectTestSuite) TestQueryJobIndexTerms() {
+	db := NewJobIndexOps(testStore)
+	ctx := context.Background()
+	jobID := &peloton.JobID{Value: uuid.New()}
+
+	config := proto.Clone(s.config).(*job.JobConfig)
+	config.OwningTeam = uuid.New()
+	s.NoError(db.Create(ctx, jobID, config, s.runtime, nil))
+
+	oldOwnerTerm := &JobQueryIndexObject{
+		Term: _jobQueryTermOwner + config.GetOwningTeam(),
+	}
+	objs, err := testStore.oClient.GetAll(ctx, oldOwnerTerm)
+	s.NoError(err)
+	s.Len(objs, 1)
+
+	// changing the owner moves the job to the term of the new owner
+	newConfig := proto.Clone(config).(*job.JobConfig)
+	newConfig.OwningTeam = uuid.New()
+	s.NoError(db.Update(ctx, jobID, newConfig, nil))
+
+	objs, err = testStore.oClient.GetAll(ctx, oldOwnerTerm)
+	s.NoError(err)
+	s.Len(objs, 0)
+
+	// the terms are not written again if the indexed columns are
+	// unchanged: a removed state term stays removed
+	oldStateTerm := &JobQueryIndexObject{
+		Term: _jobQueryTermState + s.runtime.GetState().String(),
+	}
+	s.NoError(testStore.oClient.Delete(ctx, &JobQueryIndexObject{
+		Term:  oldStateTerm.Term,
+		JobID: jobID.GetValue(),
+	}))
+	s.NoError(db.Update(ctx, jobID, nil, s.runtime))
+	objs, err = testStore.oClient.GetAll(ctx, oldStateTerm)
+	s.NoError(err)
+	for _, obj := range objs {
+		s.NotEqual(jobID.GetValue(), obj.(*JobQueryIndexObject).JobID)
+	}
+
+	// changing the state moves the job to the term of the new state
+	newRuntime := proto.Clone(s.runtime).(*job.RuntimeInfo)
+	newRuntime.State = job.JobState_KILLED
+	s.NoError(db.Update(ctx, jobID, nil, newRuntime))
+
+	objs, err = testStore.oClient.GetAll(ctx, oldStateTerm)
+	s.NoError(err)
+	for _, obj := range objs {
+		s.NotEqual(jobID.GetValue(), obj.(*JobQueryIndexObject).JobID)
+	}
+	objs, err = testStore.oClient.GetAll(ctx, &JobQueryIndexObject{
+		Term: jobQueryStateTerm(
+			job.JobState_KILLED.String(),
+			jobQueryCreationDay(s.createTime)),
+	})
+	s.NoError(err)
+	found := false
+	for _, obj := range objs {
+		if obj.(*JobQueryIndexObject).JobID == jobID.GetValue() {
+			found = true
+		}
+	}
+	s.True(found)
+
+	summaries, total, err := db.Query(
+		ctx, nil, &job.QuerySpec{Owner: newConfig.GetOwningTeam()})
+	s.NoError(err)
+	s.Equal(uint32(1), total)
+	s.Equal(jobID, summaries[0].GetId())
+
+	// the terms are removed along with the job
+	s.NoError(db.Delete(ctx, jobID))
+	objs, err = testStore.oClient.GetAll(ctx, &JobQueryIndexObject{
+		Term: _jobQueryTermOwner + newConfig.GetOwningTeam(),
+	})
+	s.NoError(err)
+	s.Len(objs, 0)
+}
+
+// TestRebuildQueryIndex tests adding the terms of existing jobs to the
+// job query index
+func (s *JobIndexObjectTestSuite) TestRebuildQueryIndex() {
+	db := NewJobIndexOps(testStore)
+	ctx := context.Background()
+	jobID := &peloton.JobID{Value: uuid.New()}
+
+	config := proto.Clone(s.config).(*job.JobConfig)
+	config.OwningTeam = uuid.New()
+	s.NoError(db.Create(ctx, jobID, config, s.runtime, nil))
+
+	// remove the owner term as if the job had been created before the
+	// index was maintained
+	s.NoError(testStore.oClient.Delete(ctx, &JobQueryIndexObject{
+		Term:  _jobQueryTermOwner + config.GetOwningTeam(),
+		JobID: jobID.GetValue(),
+	}))
+	spec := &job.QuerySpec{Owner: config.GetOwningTeam()}
+	_, total, err := db.Query(ctx, nil, spec)
+	s.NoError(err)
+	s.Equal(uint32(0), total)
+
+	s.NoError(db.RebuildQueryIndex(ctx))
+	summaries, total, err := db.Query(ctx, nil, spec)
+	s.NoError(err)
+	s.Equal(uint32(1), total)
+	s.Equal(jobID, summaries[0].GetId())
+
+	s.NoError(db.Delete(ctx, jobID))
+}
+
+// TestJobIndexQueryClientFail tests query failures due to ORM Client errors
+func (s *JobIndexObjectTestSuite) TestJobIndexQueryClientFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	indexOps := NewJobIndexOps(mockStore)
+	ctx := context.Background()
+
+	// scan of job_index for queries without terms
+	mockClient.EXPECT().GetAllIter(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getAllIter failed"))
+	_, _, err := indexOps.Query(ctx, nil, &job.QuerySpec{Name: "job"})
+	s.Error(err)
+	s.Equal("getAllIter failed", err.Error())
+
+	// the rows of job_index fail to be read
+	iter := ormmocks.NewMockIterator(ctrl)
+	mockClient.EXPECT().GetAllIter(gomock.Any(), gomock.Any()).
+		Return(iter, nil)
+	iter.EXPECT().Next().Return(nil, errors.New("next failed"))
+	iter.EXPECT().Close()
+	_, _, err = indexOps.Query(ctx, nil, &job.QuerySpec{
+		JobStates: []job.JobState{job.JobState_SUCCEEDED},
+		CreationTimeRange: &peloton.TimeRange{
+			Min: toTimestamp(time.Now().AddDate(0, 0, -60)),
+			Max: toTimestamp(time.Now()),
+		},
+	})
+	s.Error(err)
+	s.Equal("next failed", err.Error())
+
+	// lookup of job_query_index
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getAll failed")).Times(2)
+	_, _, err = indexOps.Query(ctx, nil, &job.QuerySpec{Owner: "owner"})
+	s.Error(err)
+	s.Equal("getAll failed", err.Error())
+
+	_, _, err = indexOps.Query(ctx, nil, &job.QuerySpec{
+		JobStates: []job.JobState{job.JobState_RUNNING},
+	})
+	s.Error(err)
+	s.Equal("getAll failed", err.Error())
+
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getAll failed"))
+	err = indexOps.RebuildQueryIndex(ctx)
+	s.Error(err)
+	s.Equal("getAll failed", err.Error())
+
+	// the job query index rows of a job fail to be added
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	err = indexOps.Create(
+		ctx, &peloton.JobID{Value: uuid.New()}, s.config, s.runtime, nil)
+	s.Error(err)
+	s.Equal("create failed", err.Error())
+}
+
 // TestToJobSummary tests converting JobIndexObject to JobSummary
 func (s *JobIndexObjectTestSuite) TestToJobSummary() {
 	jobID := &peloton.JobID{Value: uuid.New()}
@@ -0,0 +1,554 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/golang/protobuf/ptypes"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// Prefixes of the job_query_index terms, naming the job_index column
+	// the term was derived from.
+	_jobQueryTermOwner   = "owner:"
+	_jobQueryTermRespool = "respool:"
+	_jobQueryTermLabel   = "label:"
+	_jobQueryTermCreated = "created:"
+	_jobQueryTermState   = "state:"
+
+	// _jobQueryCreatedDayFormat is the format of the creation day of a job
+	// in its creation term.
+	_jobQueryCreatedDayFormat = "2006-01-02"
+
+	// _jobQueryMaxIndexedDays is the longest creation time range, in days,
+	// which is looked up through the creation day terms. Queries over
+	// longer ranges are looked up through their other terms, or scan
+	// job_index if they have none.
+	_jobQueryMaxIndexedDays = 31
+
+	_jobQueryDefaultLimit    uint32 = 10
+	_jobQueryDefaultMaxLimit uint32 = 100
+
+	// _jobQueryDefaultSpanInDays is the creation time range applied to
+	// queries for terminal jobs which do not specify any time range.
+	_jobQueryDefaultSpanInDays = 7
+	// _jobQueryJitter is added to the upper bound of the default creation
+	// time range to account for jobs that have just been created.
+	_jobQueryJitter = 30 * time.Second
+
+	_jobQueryDefaultOrderBy = "creation_time"
+
+	_jobQueryPageTokenPrefix = "offset:"
+)
+
+// _jobQueryOrderByFields maps the properties a job query can be ordered
+// by to the comparison of the corresponding job_index column of two rows.
+var _jobQueryOrderByFields = map[string]func(a, b *JobIndexObject) int{
+	"name": func(a, b *JobIndexObject) int {
+		return strings.Compare(a.Name, b.Name)
+	},
+	"owner": func(a, b *JobIndexObject) int {
+		return strings.Compare(a.Owner, b.Owner)
+	},
+	"respool_id": func(a, b *JobIndexObject) int {
+		return strings.Compare(a.RespoolID, b.RespoolID)
+	},
+	"state": func(a, b *JobIndexObject) int {
+		return strings.Compare(a.State, b.State)
+	},
+	"job_type": func(a, b *JobIndexObject) int {
+		return compareUint32(a.JobType, b.JobType)
+	},
+	"instance_count": func(a, b *JobIndexObject) int {
+		return compareUint32(a.InstanceCount, b.InstanceCount)
+	},
+	"creation_time": func(a, b *JobIndexObject) int {
+		return compareTime(a.CreationTime, b.CreationTime)
+	},
+	"start_time": func(a, b *JobIndexObject) int {
+		return compareTime(a.StartTime, b.StartTime)
+	},
+	"completion_time": func(a, b *JobIndexObject) int {
+		return compareTime(a.CompletionTime, b.CompletionTime)
+	},
+	"update_time": func(a, b *JobIndexObject) int {
+		return compareTime(a.UpdateTime, b.UpdateTime)
+	},
+}
+
+// init adds a JobQueryIndexObject instance to the global list of storage objects
+func init() {
+	Objs = append(Objs, &JobQueryIndexObject{})
+}
+
+// JobQueryIndexObject corresponds to a row in job_query_index table.
+// Each row maps a search term of a job, such as its owner or one of its
+// label values, to the job. The rows are maintained by JobIndexOps along
+// with the job_index row of the job.
+type JobQueryIndexObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=job_query_index, primaryKey=((term), job_id)"`
+
+	// Term of the job, prefixed by the job_index column it comes from
+	Term string `column:"name=term"`
+	// JobID of the job
+	JobID string `column:"name=job_id"`
+}
+
+// jobQueryConfigTerms returns the terms derived from the job configuration
+// columns of a job_index row.
+func jobQueryConfigTerms(obj *JobIndexObject) []string {
+	var terms []string
+	if obj.Owner != "" {
+		terms = append(terms, _jobQueryTermOwner+obj.Owner)
+	}
+	if obj.RespoolID != "" {
+		terms = append(terms, _jobQueryTermRespool+obj.RespoolID)
+	}
+
+	seen := make(map[string]bool)
+	for _, value := range jobIndexLabelValues(obj.Labels) {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		terms = append(terms, _jobQueryTermLabel+value)
+	}
+	return terms
+}
+
+// jobQueryCreationDay returns the creation day of a job, or an empty
+// string if the creation time is not known.
+func jobQueryCreationDay(creationTime time.Time) string {
+	if creationTime.IsZero() {
+		return ""
+	}
+	return creationTime.UTC().Format(_jobQueryCreatedDayFormat)
+}
+
+// jobQueryBucketedState returns true if the state terms of the state are
+// bucketed by creation day. Jobs stay in terminal states once they are
+// done, so the partitions of these terms would otherwise grow with the
+// history of the cluster.
+func jobQueryBucketedState(state string) bool {
+	value, ok := job.JobState_value[state]
+	return ok && util.IsPelotonJobStateTerminal(job.JobState(value))
+}
+
+// jobQueryStateTerm returns the term of a state, bucketed by the creation
+// day for terminal states.
+func jobQueryStateTerm(state string, day string) string {
+	if day == "" || !jobQueryBucketedState(state) {
+		return _jobQueryTermState + state
+	}
+	return _jobQueryTermState + state + ":" + day
+}
+
+// jobQueryRuntimeTerms returns the terms derived from the job runtime
+// columns of a job_index row: its state and creation day.
+func jobQueryRuntimeTerms(obj *JobIndexObject) []string {
+	var terms []string
+	day := jobQueryCreationDay(obj.CreationTime)
+	if obj.State != "" {
+		terms = append(terms, jobQueryStateTerm(obj.State, day))
+	}
+	if day != "" {
+		terms = append(terms, _jobQueryTermCreated+day)
+	}
+	return terms
+}
+
+// jobQueryTerms returns all the terms of a job_index row.
+func jobQueryTerms(obj *JobIndexObject) []string {
+	return append(jobQueryConfigTerms(obj), jobQueryRuntimeTerms(obj)...)
+}
+
+// jobIndexLabelValues returns the label values stored in the labels
+// column of a job_index row.
+func jobIndexLabelValues(labels string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	var pelotonLabels []*peloton.Label
+	if err := json.Unmarshal([]byte(labels), &pelotonLabels); err != nil {
+		return nil
+	}
+
+	values := make([]string, 0, len(pelotonLabels))
+	for _, label := range pelotonLabels {
+		values = append(values, label.GetValue())
+	}
+	return values
+}
+
+// jobQueryTimeRange is a [min, max) time range of a job query.
+type jobQueryTimeRange struct {
+	min time.Time
+	max time.Time
+}
+
+// newJobQueryTimeRange converts a time range of a query spec.
+func newJobQueryTimeRange(
+	timeRange *peloton.TimeRange,
+) (*jobQueryTimeRange, error) {
+	if timeRange == nil {
+		return nil, nil
+	}
+
+	min, err := ptypes.Timestamp(timeRange.GetMin())
+	if err != nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"invalid time range min: %v", err)
+	}
+	max, err := ptypes.Timestamp(timeRange.GetMax())
+	if err != nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"invalid time range max: %v", err)
+	}
+	if max.Before(min) {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"incorrect time range: max %v is before min %v", max, min)
+	}
+	return &jobQueryTimeRange{min: min, max: max}, nil
+}
+
+// contains returns true if the time is within the range.
+func (r *jobQueryTimeRange) contains(t time.Time) bool {
+	return !t.Before(r.min) && t.Before(r.max)
+}
+
+// creationDays returns the creation days covering the range, the newest
+// first if descending, or nil if the range is too long to be looked up
+// through the index.
+func (r *jobQueryTimeRange) creationDays(descending bool) []string {
+	if r.max.Sub(r.min) > _jobQueryMaxIndexedDays*24*time.Hour {
+		return nil
+	}
+
+	days := []string{}
+	if !r.min.Before(r.max) {
+		return days
+	}
+	day := r.min.UTC().Truncate(24 * time.Hour)
+	for ; day.Before(r.max); day = day.AddDate(0, 0, 1) {
+		days = append(days, jobQueryCreationDay(day))
+	}
+	if descending {
+		for i, j := 0, len(days)-1; i < j; i, j = i+1, j-1 {
+			days[i], days[j] = days[j], days[i]
+		}
+	}
+	return days
+}
+
+// jobQueryFilter matches job_index rows against a job query spec.
+type jobQueryFilter struct {
+	respoolID string
+	owner     string
+	name      string
+	labels    []string
+	// keywords are lower-cased, they are matched case-insensitively
+	keywords            []string
+	states              map[string]bool
+	types               map[uint32]bool
+	creationTimeRange   *jobQueryTimeRange
+	completionTimeRange *jobQueryTimeRange
+}
+
+// newJobQueryFilter creates the filter of a job query spec.
+func newJobQueryFilter(
+	respoolID *peloton.ResourcePoolID,
+	spec *job.QuerySpec,
+) (*jobQueryFilter, error) {
+	f := &jobQueryFilter{
+		respoolID: respoolID.GetValue(),
+		owner:     spec.GetOwner(),
+		name:      spec.GetName(),
+		states:    make(map[string]bool),
+		types:     make(map[uint32]bool),
+	}
+
+	for _, label := range spec.GetLabels() {
+		f.labels = append(f.labels, label.GetValue())
+	}
+
+	for _, word := range spec.GetKeywords() {
+		f.keywords = append(f.keywords, strings.ToLower(word))
+	}
+
+	for _, jobType := range spec.GetJobTypes() {
+		f.types[uint32(jobType)] = true
+	}
+
+	// queryTerminalStates will be set if the spec contains any terminal
+	// job state. In this case the query is restricted to the jobs created
+	// over the last days, unless the spec has its own time range.
+	queryTerminalStates := false
+	for _, state := range spec.GetJobStates() {
+		if util.IsPelotonJobStateTerminal(state) {
+			queryTerminalStates = true
+		}
+		f.states[state.String()] = true
+	}
+
+	var err error
+	if f.creationTimeRange, err = newJobQueryTimeRange(
+		spec.GetCreationTimeRange()); err != nil {
+		return nil, err
+	}
+	if f.completionTimeRange, err = newJobQueryTimeRange(
+		spec.GetCompletionTimeRange()); err != nil {
+		return nil, err
+	}
+
+	if f.creationTimeRange == nil &&
+		f.completionTimeRange == nil &&
+		queryTerminalStates {
+		now := time.Now().Add(_jobQueryJitter).UTC()
+		f.creationTimeRange = &jobQueryTimeRange{
+			min: now.AddDate(0, 0, -_jobQueryDefaultSpanInDays),
+			max: now,
+		}
+	}
+	return f, nil
+}
+
+// creationDays returns the creation days of the jobs the query looks up
+// through the index, in the order they are looked up, or nil if the query
+// is not restricted to a short enough creation time range.
+func (f *jobQueryFilter) creationDays(descending bool) []string {
+	if f.creationTimeRange == nil {
+		return nil
+	}
+	return f.creationTimeRange.creationDays(descending)
+}
+
+// indexTerms returns the job_query_index terms to look up the candidate
+// jobs of the query created on the day, or on any day if day is empty.
+// A job is a candidate if it has at least one term of every returned
+// group. It returns no group if the candidates cannot be looked up
+// through the index: the jobs in terminal states are only looked up by
+// creation day.
+func (f *jobQueryFilter) indexTerms(day string) [][]string {
+	var terms [][]string
+	if f.owner != "" {
+		terms = append(terms, []string{_jobQueryTermOwner + f.owner})
+	}
+	if f.respoolID != "" {
+		terms = append(terms, []string{_jobQueryTermRespool + f.respoolID})
+	}
+	for _, label := range f.labels {
+		terms = append(terms, []string{_jobQueryTermLabel + label})
+	}
+	if day != "" {
+		terms = append(terms, []string{_jobQueryTermCreated + day})
+	}
+
+	var stateTerms []string
+	for state := range f.states {
+		if day == "" && jobQueryBucketedState(state) {
+			stateTerms = nil
+			break
+		}
+		stateTerms = append(stateTerms, jobQueryStateTerm(state, day))
+	}
+	if len(stateTerms) > 0 {
+		sort.Strings(stateTerms)
+		terms = append(terms, stateTerms)
+	}
+	return terms
+}
+
+// matches returns true if the job_index row matches the query spec.
+func (f *jobQueryFilter) matches(obj *JobIndexObject) bool {
+	if f.respoolID != "" && obj.RespoolID != f.respoolID {
+		return false
+	}
+	if f.owner != "" && obj.Owner != f.owner {
+		return false
+	}
+	if f.name != "" && !strings.Contains(obj.Name, f.name) {
+		return false
+	}
+	if len(f.states) > 0 && !f.states[obj.State] {
+		return false
+	}
+	if len(f.types) > 0 && !f.types[obj.JobType] {
+		return false
+	}
+	if f.creationTimeRange != nil &&
+		!f.creationTimeRange.contains(obj.CreationTime) {
+		return false
+	}
+	if f.completionTimeRange != nil &&
+		!f.completionTimeRange.contains(obj.CompletionTime) {
+		return false
+	}
+
+	if len(f.labels) > 0 {
+		values := make(map[string]bool)
+		for _, value := range jobIndexLabelValues(obj.Labels) {
+			values[value] = true
+		}
+		for _, label := range f.labels {
+			if !values[label] {
+				return false
+			}
+		}
+	}
+
+	if len(f.keywords) > 0 {
+		config := strings.ToLower(obj.Config)
+		for _, word := range f.keywords {
+			if !strings.Contains(config, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// newJobQueryLess returns the ordering of job_index rows for a job query,
+// which defaults to the most recently created jobs first.
+func newJobQueryLess(
+	orderBy []*query.OrderBy,
+) (func(a, b *JobIndexObject) bool, error) {
+	if len(orderBy) == 0 {
+		orderBy = []*query.OrderBy{
+			{
+				Order: query.OrderBy_DESC,
+				Property: &query.PropertyPath{
+					Value: _jobQueryDefaultOrderBy,
+				},
+			},
+		}
+	}
+
+	var compares []func(a, b *JobIndexObject) int
+	var descending []bool
+	for _, order := range orderBy {
+		compare, ok := _jobQueryOrderByFields[order.GetProperty().GetValue()]
+		if !ok {
+			return nil, yarpcerrors.InvalidArgumentErrorf(
+				"unsupported order by property %s",
+				order.GetProperty().GetValue())
+		}
+		compares = append(compares, compare)
+		descending = append(descending, order.GetOrder() == query.OrderBy_DESC)
+	}
+
+	return func(a, b *JobIndexObject) bool {
+		for i, compare := range compares {
+			c := compare(a, b)
+			if c == 0 {
+				continue
+			}
+			if descending[i] {
+				return c > 0
+			}
+			return c < 0
+		}
+		// break ties by job id so that the pages of a query do not
+		// overlap or skip jobs
+		return a.JobID.String() < b.JobID.String()
+	}, nil
+}
+
+// jobQueryCreationOrder returns whether the results of a job query are
+// ordered by creation time first, and if so whether the most recently
+// created jobs come first.
+func jobQueryCreationOrder(orderBy []*query.OrderBy) (bool, bool) {
+	if len(orderBy) == 0 {
+		return true, true
+	}
+	if orderBy[0].GetProperty().GetValue() != _jobQueryDefaultOrderBy {
+		return false, false
+	}
+	return true, orderBy[0].GetOrder() == query.OrderBy_DESC
+}
+
+// EncodeJobQueryPageToken returns the page token of the job query result
+// page starting at the offset.
+func EncodeJobQueryPageToken(offset uint32) string {
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(fmt.Sprintf("%s%d", _jobQueryPageTokenPrefix, offset)))
+}
+
+// NextJobQueryPageToken returns the page token of the job query result
+// page following the page of count results starting at offset, or an
+// empty string if there are no more results.
+func NextJobQueryPageToken(offset, count, total uint32) string {
+	if offset+count >= total {
+		return ""
+	}
+	return EncodeJobQueryPageToken(offset + count)
+}
+
+// JobQueryOffset returns the offset of the first result of a job query
+// page, decoded from the page token of the pagination spec if it is set.
+func JobQueryOffset(pagination *query.PaginationSpec) (uint32, error) {
+	token := pagination.GetPageToken()
+	if token == "" {
+		return pagination.GetOffset(), nil
+	}
+
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(buf), _jobQueryPageTokenPrefix) {
+		return 0, yarpcerrors.InvalidArgumentErrorf(
+			"invalid page token %q", token)
+	}
+	offset, err := strconv.ParseUint(
+		strings.TrimPrefix(string(buf), _jobQueryPageTokenPrefix), 10, 32)
+	if err != nil {
+		return 0, yarpcerrors.InvalidArgumentErrorf(
+			"invalid page token %q", token)
+	}
+	return uint32(offset), nil
+}
+
+func compareUint32(a, b uint32) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
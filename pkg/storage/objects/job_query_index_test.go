@@ -0,0 +1,352 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type JobQueryIndexTestSuite struct {
+	suite.Suite
+}
+
+func TestJobQueryIndexSuite(t *testing.T) {
+	suite.Run(t, new(JobQueryIndexTestSuite))
+}
+
+// toTimestamp converts a time to its protobuf timestamp
+func toTimestamp(t time.Time) *timestamp.Timestamp {
+	ts, _ := ptypes.TimestampProto(t)
+	return ts
+}
+
+// TestJobQueryTerms tests the terms derived from a job_index row
+func (s *JobQueryIndexTestSuite) TestJobQueryTerms() {
+	obj := &JobIndexObject{
+		Owner:        "peloton",
+		RespoolID:    "respool",
+		Labels:       `[{"key":"a","value":"x"},{"key":"b","value":"x"}]`,
+		State:        job.JobState_RUNNING.String(),
+		CreationTime: time.Date(2019, 1, 25, 23, 30, 0, 0, time.UTC),
+	}
+	s.Equal([]string{
+		"owner:peloton",
+		"respool:respool",
+		"label:x",
+		"state:RUNNING",
+		"created:2019-01-25",
+	}, jobQueryTerms(obj))
+
+	// the terms of terminal states are bucketed by creation day
+	obj.State = job.JobState_SUCCEEDED.String()
+	s.Contains(jobQueryTerms(obj), "state:SUCCEEDED:2019-01-25")
+	obj.CreationTime = time.Time{}
+	s.Contains(jobQueryTerms(obj), "state:SUCCEEDED")
+
+	// unknown values do not have terms
+	s.Empty(jobQueryTerms(&JobIndexObject{Labels: "invalid"}))
+}
+
+// TestCreationDays tests the creation days of a time range
+func (s *JobQueryIndexTestSuite) TestCreationDays() {
+	min := time.Date(2019, 1, 25, 18, 0, 0, 0, time.UTC)
+
+	r := &jobQueryTimeRange{min: min, max: min.Add(30 * time.Hour)}
+	s.Equal([]string{"2019-01-25", "2019-01-26"}, r.creationDays(false))
+	s.Equal([]string{"2019-01-26", "2019-01-25"}, r.creationDays(true))
+
+	r = &jobQueryTimeRange{min: min, max: min}
+	s.Empty(r.creationDays(true))
+	s.NotNil(r.creationDays(true))
+
+	r = &jobQueryTimeRange{min: min, max: min.AddDate(0, 0, 32)}
+	s.Nil(r.creationDays(true))
+}
+
+// TestJobQueryFilter tests matching job_index rows against a query spec
+func (s *JobQueryIndexTestSuite) TestJobQueryFilter() {
+	now := time.Now().UTC()
+	obj := &JobIndexObject{
+		Name:           "my-test-job",
+		Owner:          "peloton",
+		RespoolID:      "respool",
+		Config:         `{"description":"A Simple Job"}`,
+		Labels:         `[{"key":"org","value":"peloton"}]`,
+		State:          "SUCCEEDED",
+		CreationTime:   now.Add(-time.Hour),
+		CompletionTime: now,
+	}
+
+	testcases := []struct {
+		description string
+		respoolID   *peloton.ResourcePoolID
+		spec        *job.QuerySpec
+		matches     bool
+	}{
+		{
+			description: "empty spec",
+			spec:        &job.QuerySpec{},
+			matches:     true,
+		},
+		{
+			description: "all fields",
+			respoolID:   &peloton.ResourcePoolID{Value: "respool"},
+			spec: &job.QuerySpec{
+				Owner:     "peloton",
+				Name:      "test",
+				Keywords:  []string{"simple", "JOB"},
+				Labels:    []*peloton.Label{{Key: "org", Value: "peloton"}},
+				JobStates: []job.JobState{job.JobState_SUCCEEDED},
+				JobTypes:  []job.JobType{job.JobType_BATCH},
+				CompletionTimeRange: &peloton.TimeRange{
+					Min: toTimestamp(now.Add(-time.Minute)),
+					Max: toTimestamp(now.Add(time.Minute)),
+				},
+			},
+			matches: true,
+		},
+		{
+			description: "other resource pool",
+			respoolID:   &peloton.ResourcePoolID{Value: "other"},
+			spec:        &job.QuerySpec{},
+		},
+		{
+			description: "owner is matched exactly",
+			spec:        &job.QuerySpec{Owner: "pelo"},
+		},
+		{
+			description: "name is case sensitive",
+			spec:        &job.QuerySpec{Name: "Test"},
+		},
+		{
+			description: "missing label value",
+			spec: &job.QuerySpec{
+				Labels: []*peloton.Label{{Key: "org", Value: "other"}},
+			},
+		},
+		{
+			description: "missing keyword",
+			spec:        &job.QuerySpec{Keywords: []string{"simple", "other"}},
+		},
+		{
+			description: "other state",
+			spec: &job.QuerySpec{
+				JobStates: []job.JobState{job.JobState_RUNNING},
+			},
+		},
+		{
+			description: "other job type",
+			spec: &job.QuerySpec{
+				JobTypes: []job.JobType{job.JobType_SERVICE},
+			},
+		},
+		{
+			description: "creation time range excludes its max",
+			spec: &job.QuerySpec{
+				CreationTimeRange: &peloton.TimeRange{
+					Min: toTimestamp(now.Add(-2 * time.Hour)),
+					Max: toTimestamp(now.Add(-time.Hour)),
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		f, err := newJobQueryFilter(tc.respoolID, tc.spec)
+		s.NoError(err, tc.description)
+		s.Equal(tc.matches, f.matches(obj), tc.description)
+	}
+
+	// terminal jobs are only queried over the last days by default
+	f, err := newJobQueryFilter(nil, &job.QuerySpec{
+		JobStates: []job.JobState{job.JobState_SUCCEEDED},
+	})
+	s.NoError(err)
+	s.True(f.matches(obj))
+	obj.CreationTime = now.AddDate(0, 0, -_jobQueryDefaultSpanInDays-1)
+	s.False(f.matches(obj))
+
+	// but active jobs are not
+	f, err = newJobQueryFilter(nil, &job.QuerySpec{
+		JobStates: []job.JobState{job.JobState_RUNNING},
+	})
+	s.NoError(err)
+	s.Nil(f.creationTimeRange)
+
+	_, err = newJobQueryFilter(nil, &job.QuerySpec{
+		CompletionTimeRange: &peloton.TimeRange{
+			Min: toTimestamp(now),
+			Max: toTimestamp(now.Add(-time.Hour)),
+		},
+	})
+	s.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestJobQueryIndexTerms tests the terms used to look up the candidate
+// jobs of a query
+func (s *JobQueryIndexTestSuite) TestJobQueryIndexTerms() {
+	f, err := newJobQueryFilter(nil, &job.QuerySpec{
+		Name:     "my-test-job",
+		Keywords: []string{"simple"},
+	})
+	s.NoError(err)
+	// queries without any term scan job_index
+	s.Empty(f.indexTerms(""))
+	s.Equal([][]string{{"created:2019-01-25"}}, f.indexTerms("2019-01-25"))
+
+	f, err = newJobQueryFilter(nil, &job.QuerySpec{
+		JobStates: []job.JobState{
+			job.JobState_RUNNING,
+			job.JobState_PENDING,
+		},
+	})
+	s.NoError(err)
+	s.Equal([][]string{
+		{"state:PENDING", "state:RUNNING"},
+	}, f.indexTerms(""))
+
+	// the jobs in terminal states are only looked up by creation day
+	f, err = newJobQueryFilter(nil, &job.QuerySpec{
+		JobStates: []job.JobState{
+			job.JobState_RUNNING,
+			job.JobState_SUCCEEDED,
+		},
+	})
+	s.NoError(err)
+	s.Empty(f.indexTerms(""))
+	s.Equal([][]string{
+		{"created:2019-01-25"},
+		{"state:RUNNING", "state:SUCCEEDED:2019-01-25"},
+	}, f.indexTerms("2019-01-25"))
+
+	f, err = newJobQueryFilter(
+		&peloton.ResourcePoolID{Value: "respool"},
+		&job.QuerySpec{
+			Owner:  "peloton",
+			Labels: []*peloton.Label{{Key: "org", Value: "peloton"}},
+		})
+	s.NoError(err)
+	s.Equal([][]string{
+		{"owner:peloton"},
+		{"respool:respool"},
+		{"label:peloton"},
+	}, f.indexTerms(""))
+}
+
+// TestJobQueryCreationOrder tests whether queries are ordered by creation
+// time first
+func (s *JobQueryIndexTestSuite) TestJobQueryCreationOrder() {
+	byCreation, descending := jobQueryCreationOrder(nil)
+	s.True(byCreation)
+	s.True(descending)
+
+	byCreation, descending = jobQueryCreationOrder([]*query.OrderBy{
+		{
+			Order:    query.OrderBy_ASC,
+			Property: &query.PropertyPath{Value: "creation_time"},
+		},
+	})
+	s.True(byCreation)
+	s.False(descending)
+
+	byCreation, _ = jobQueryCreationOrder([]*query.OrderBy{
+		{Property: &query.PropertyPath{Value: "name"}},
+	})
+	s.False(byCreation)
+}
+
+// TestJobQueryLess tests the ordering of the query results
+func (s *JobQueryIndexTestSuite) TestJobQueryLess() {
+	now := time.Now()
+	a := &JobIndexObject{
+		JobID:        base.NewOptionalString("a"),
+		Owner:        "peloton",
+		CreationTime: now,
+	}
+	b := &JobIndexObject{
+		JobID:        base.NewOptionalString("b"),
+		Owner:        "peloton",
+		CreationTime: now.Add(-time.Hour),
+	}
+
+	// newest first by default
+	less, err := newJobQueryLess(nil)
+	s.NoError(err)
+	s.True(less(a, b))
+	s.False(less(b, a))
+
+	// ties are broken by job id
+	less, err = newJobQueryLess([]*query.OrderBy{
+		{
+			Order:    query.OrderBy_DESC,
+			Property: &query.PropertyPath{Value: "owner"},
+		},
+	})
+	s.NoError(err)
+	s.True(less(a, b))
+	s.False(less(b, a))
+
+	less, err = newJobQueryLess([]*query.OrderBy{
+		{
+			Order:    query.OrderBy_ASC,
+			Property: &query.PropertyPath{Value: "creation_time"},
+		},
+	})
+	s.NoError(err)
+	s.True(less(b, a))
+
+	_, err = newJobQueryLess([]*query.OrderBy{
+		{Property: &query.PropertyPath{Value: "labels"}},
+	})
+	s.True(yarpcerrors.IsInvalidArgument(err))
+}
+
+// TestJobQueryPageToken tests encoding and decoding page tokens
+func (s *JobQueryIndexTestSuite) TestJobQueryPageToken() {
+	offset, err := JobQueryOffset(&query.PaginationSpec{Offset: 5})
+	s.NoError(err)
+	s.Equal(uint32(5), offset)
+
+	offset, err = JobQueryOffset(nil)
+	s.NoError(err)
+	s.Equal(uint32(0), offset)
+
+	// the token takes precedence over the offset
+	offset, err = JobQueryOffset(&query.PaginationSpec{
+		Offset:    5,
+		PageToken: EncodeJobQueryPageToken(20),
+	})
+	s.NoError(err)
+	s.Equal(uint32(20), offset)
+
+	for _, token := range []string{"!", EncodeJobQueryPageToken(1)[1:]} {
+		_, err = JobQueryOffset(&query.PaginationSpec{PageToken: token})
+		s.True(yarpcerrors.IsInvalidArgument(err), token)
+	}
+
+	s.Equal(EncodeJobQueryPageToken(10), NextJobQueryPageToken(0, 10, 25))
+	s.Equal("", NextJobQueryPageToken(20, 5, 25))
+}
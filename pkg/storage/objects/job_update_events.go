@@ -45,6 +45,8 @@ type JobUpdateEventsObject struct {
 	State string `column:"name=state"`
 	// CreateTime of the job update events
 	CreateTime *base.OptionalString `column:"name=create_time"`
+	// Message describing why the event was recorded
+	Message string `column:"name=message"`
 }
 
 // JobUpdateEventsOps provides methods for manipulating job_update_events table.
@@ -57,6 +59,16 @@ type JobUpdateEventsOps interface {
 		updateState update.State,
 	) error
 
+	// CreateWithMessage upserts single job state change for a job along
+	// with a message describing why the state change happened.
+	CreateWithMessage(
+		ctx context.Context,
+		updateID *peloton.UpdateID,
+		updateType models.WorkflowType,
+		updateState update.State,
+		message string,
+	) error
+
 	// GetAll returns job update events for an update.
 	// Update state events are sorted by
 	// reverse order of time of event.
@@ -91,12 +103,25 @@ func (d *jobUpdateEventsOps) Create(
 	updateID *peloton.UpdateID,
 	updateType models.WorkflowType,
 	updateState update.State,
+) error {
+	return d.CreateWithMessage(ctx, updateID, updateType, updateState, "")
+}
+
+// CreateWithMessage upserts single job state change for a job along
+// with a message describing why the state change happened.
+func (d *jobUpdateEventsOps) CreateWithMessage(
+	ctx context.Context,
+	updateID *peloton.UpdateID,
+	updateType models.WorkflowType,
+	updateState update.State,
+	message string,
 ) error {
 	obj := &JobUpdateEventsObject{
 		UpdateID:   updateID.GetValue(),
 		Type:       updateType.String(),
 		State:      updateState.String(),
 		CreateTime: base.NewOptionalString(gocql.TimeUUID().String()),
+		Message:    message,
 	}
 
 	if err := d.store.oClient.Create(ctx, obj); err != nil {
@@ -141,6 +166,7 @@ func (d *jobUpdateEventsOps) GetAll(
 			Timestamp: timeUUID.Time().Format(time.RFC3339),
 			State: stateless.WorkflowState(
 				update.State_value[jobUpdateEventsObjectValue.State]),
+			Message: jobUpdateEventsObjectValue.Message,
 		})
 	}
 
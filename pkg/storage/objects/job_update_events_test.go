@@ -68,6 +68,30 @@ func (s *JobUpdateEventsObjectTestSuite) TestAddJobUpdateEvents() {
 	s.Equal(workflowEvents[2].GetState(), stateless.WorkflowState_WORKFLOW_STATE_INITIALIZED)
 }
 
+func (s *JobUpdateEventsObjectTestSuite) TestAddJobUpdateEventsWithMessage() {
+	db := NewJobUpdateEventsOps(testStore)
+	ctx := context.Background()
+
+	s.NoError(db.Create(ctx, s.updateID, models.WorkflowType_UPDATE, update.State_ROLLING_FORWARD))
+	time.Sleep(10 * time.Microsecond)
+	s.NoError(db.CreateWithMessage(
+		ctx,
+		s.updateID,
+		models.WorkflowType_UPDATE,
+		update.State_PAUSED,
+		"health gate error-rate failed",
+	))
+
+	workflowEvents, err := db.GetAll(ctx, s.updateID)
+	s.NoError(err)
+	s.Len(workflowEvents, 2)
+
+	s.Equal(workflowEvents[0].GetState(), stateless.WorkflowState_WORKFLOW_STATE_PAUSED)
+	s.Equal(workflowEvents[0].GetMessage(), "health gate error-rate failed")
+	s.Equal(workflowEvents[1].GetState(), stateless.WorkflowState_WORKFLOW_STATE_ROLLING_FORWARD)
+	s.Empty(workflowEvents[1].GetMessage())
+}
+
 func (s *JobUpdateEventsObjectTestSuite) TestDeleteJobUpdateEvents() {
 	db := NewJobUpdateEventsOps(testStore)
 	ctx := context.Background()
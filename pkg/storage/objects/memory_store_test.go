@@ -0,0 +1,112 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// MemoryStoreTestSuite runs object operations against the in-memory store
+type MemoryStoreTestSuite struct {
+	suite.Suite
+
+	store *Store
+}
+
+func (suite *MemoryStoreTestSuite) SetupTest() {
+	store, err := NewMemoryStore(tally.NewTestScope("", map[string]string{}))
+	suite.NoError(err)
+	suite.store = store
+}
+
+func TestMemoryStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryStoreTestSuite))
+}
+
+// TestJobRuntimeOps tests job runtime upsert, get and delete
+func (suite *MemoryStoreTestSuite) TestJobRuntimeOps() {
+	db := NewJobRuntimeOps(suite.store)
+	ctx := context.Background()
+	jobID := &peloton.JobID{Value: uuid.New()}
+
+	_, err := db.Get(ctx, jobID)
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	suite.NoError(db.Upsert(ctx, jobID, &job.RuntimeInfo{
+		State:                job.JobState_RUNNING,
+		ConfigurationVersion: 3,
+	}))
+	runtime, err := db.Get(ctx, jobID)
+	suite.NoError(err)
+	suite.Equal(job.JobState_RUNNING, runtime.GetState())
+	suite.Equal(uint64(3), runtime.GetConfigurationVersion())
+
+	suite.NoError(db.Delete(ctx, jobID))
+	_, err = db.Get(ctx, jobID)
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestJobNameToIDOps tests getting all the rows of a partition
+func (suite *MemoryStoreTestSuite) TestJobNameToIDOps() {
+	db := NewJobNameToIDOps(suite.store)
+	ctx := context.Background()
+	jobID := &peloton.JobID{Value: uuid.New()}
+
+	suite.NoError(db.Create(ctx, "test-job", jobID))
+	suite.NoError(db.Create(ctx, "other-job", &peloton.JobID{Value: uuid.New()}))
+
+	objs, err := db.GetAll(ctx, "test-job")
+	suite.NoError(err)
+	suite.Len(objs, 1)
+	suite.Equal(jobID.GetValue(), objs[0].JobID)
+}
+
+// TestJobIndexQuery tests querying jobs through the job query index
+func (suite *MemoryStoreTestSuite) TestJobIndexQuery() {
+	db := NewJobIndexOps(suite.store)
+	ctx := context.Background()
+	jobID := &peloton.JobID{Value: uuid.New()}
+
+	suite.NoError(db.Create(ctx, jobID, &job.JobConfig{
+		Name:       "test-job",
+		OwningTeam: "peloton",
+		Labels:     []*peloton.Label{{Key: "org", Value: "peloton"}},
+	}, &job.RuntimeInfo{State: job.JobState_RUNNING}, nil))
+	suite.NoError(db.Create(ctx, &peloton.JobID{Value: uuid.New()},
+		&job.JobConfig{Name: "other-job", OwningTeam: "other"}, nil, nil))
+
+	summaries, total, err := db.Query(ctx, nil, &job.QuerySpec{
+		Owner:     "peloton",
+		Labels:    []*peloton.Label{{Key: "org", Value: "peloton"}},
+		JobStates: []job.JobState{job.JobState_RUNNING},
+	})
+	suite.NoError(err)
+	suite.Equal(uint32(1), total)
+	suite.Equal(jobID, summaries[0].GetId())
+
+	suite.NoError(db.Delete(ctx, jobID))
+	_, total, err = db.Query(ctx, nil, &job.QuerySpec{Owner: "peloton"})
+	suite.NoError(err)
+	suite.Equal(uint32(0), total)
+}
@@ -97,6 +97,16 @@ type PodEventsOps interface {
 		instanceID uint32,
 		podID ...string,
 	) ([]*task.PodEvent, error)
+
+	// Delete removes the pod events of a Job + Instance whose run ID is
+	// in the range [fromRunID, toRunID)
+	Delete(
+		ctx context.Context,
+		jobID string,
+		instanceID uint32,
+		fromRunID uint64,
+		toRunID uint64,
+	) error
 }
 
 // ensure that default implementation (podEventsOps) satisfies the interface
@@ -299,3 +309,38 @@ func (d *podEventsOps) GetAll(
 
 	return podEvents, nil
 }
+
+// Delete removes the pod events of a Job + Instance whose run ID is in
+// the range [fromRunID, toRunID). The ORM has no range deletes, so the
+// events of the instance are read to find the rows to delete.
+func (d *podEventsOps) Delete(
+	ctx context.Context,
+	jobID string,
+	instanceID uint32,
+	fromRunID uint64,
+	toRunID uint64,
+) error {
+	objs, err := d.store.oClient.GetAll(ctx, &PodEventsObject{
+		JobID:      jobID,
+		InstanceID: instanceID,
+	})
+	if err != nil {
+		d.store.metrics.OrmTaskMetrics.PodEventsDeleteFail.Inc(1)
+		return err
+	}
+
+	for _, obj := range objs {
+		podEventsObject := obj.(*PodEventsObject)
+		runID := base.ConvertFromOptionalToRawType(reflect.ValueOf(
+			podEventsObject.RunID)).(uint64)
+		if runID < fromRunID || runID >= toRunID {
+			continue
+		}
+		if err := d.store.oClient.Delete(ctx, podEventsObject); err != nil {
+			d.store.metrics.OrmTaskMetrics.PodEventsDeleteFail.Inc(1)
+			return err
+		}
+	}
+	d.store.metrics.OrmTaskMetrics.PodEventsDelete.Inc(1)
+	return nil
+}
@@ -16,6 +16,7 @@ package objects
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -230,3 +231,34 @@ func (s *PodEventsObjectTestSuite) TestGetPodEvents() {
 	s.Equal(len(podEvents), 1)
 	s.NoError(err)
 }
+
+// TestDeletePodEvents tests deleting the pod events of a range of runs
+func (s *PodEventsObjectTestSuite) TestDeletePodEvents() {
+	db := NewPodEventsOps(testStore)
+	ctx := context.Background()
+	jobID := &peloton.JobID{Value: uuid.New()}
+
+	for runID := 1; runID <= 3; runID++ {
+		mesosTaskID := fmt.Sprintf("%s-0-%d", jobID.GetValue(), runID)
+		s.NoError(db.Create(ctx, jobID, 0, &task.RuntimeInfo{
+			State:       task.TaskState_RUNNING,
+			MesosTaskId: &mesos.TaskID{Value: &mesosTaskID},
+		}))
+	}
+
+	s.NoError(db.Delete(ctx, jobID.GetValue(), 0, 1, 3))
+
+	for runID := 1; runID <= 3; runID++ {
+		podEvents, err := db.GetAll(
+			ctx,
+			jobID.GetValue(),
+			0,
+			fmt.Sprintf("%s-0-%d", jobID.GetValue(), runID))
+		s.NoError(err)
+		if runID < 3 {
+			s.Empty(podEvents)
+		} else {
+			s.Len(podEvents, 1)
+		}
+	}
+}
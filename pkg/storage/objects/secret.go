@@ -80,6 +80,14 @@ type SecretInfoOps interface {
 		secretID, secretString string,
 	) error
 
+	// UpdateSecretDataAndVersion modifies the data and the version of the
+	// SecretInfoObject in the table.
+	UpdateSecretDataAndVersion(
+		ctx context.Context,
+		secretID, secretString string,
+		version int64,
+	) error
+
 	// Delete removes the SecretInfoObject from the table.
 	DeleteSecret(
 		ctx context.Context,
@@ -181,6 +189,27 @@ func (s *secretInfoOps) UpdateSecretData(
 	return nil
 }
 
+// UpdateSecretDataAndVersion updates a secret data and version in db
+func (s *secretInfoOps) UpdateSecretDataAndVersion(
+	ctx context.Context,
+	secretID, secretString string,
+	version int64,
+) error {
+	secretInfoObject := &SecretInfoObject{
+		SecretID: secretID,
+		Valid:    true,
+		Data:     secretString,
+		Version:  version,
+	}
+	fieldsToUpdate := []string{"Data", "Version"}
+	if err := s.store.oClient.Update(ctx, secretInfoObject, fieldsToUpdate...); err != nil {
+		s.store.metrics.OrmJobMetrics.SecretInfoUpdateFail.Inc(1)
+		return err
+	}
+	s.store.metrics.OrmJobMetrics.SecretInfoUpdate.Inc(1)
+	return nil
+}
+
 // DeleteSecret deletes a secret object in db
 func (s *secretInfoOps) DeleteSecret(
 	ctx context.Context,
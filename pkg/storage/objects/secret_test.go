@@ -76,6 +76,16 @@ func (suite *SecretInfoObjectTestSuite) TestSecretInfoOps() {
 	suite.Equal(secretInfoObj.Data, testUpdatedSecretByteStr)
 	suite.Equal(secretInfoObj.Path, testSecretPath)
 
+	// UPDATE data and version, and GET ops.
+	err = db.UpdateSecretDataAndVersion(ctx, secretID, testSecretByteStr, 1)
+	suite.NoError(err)
+
+	secretInfoObj, err = db.GetSecret(ctx, secretID)
+	suite.NoError(err)
+	suite.Equal(secretInfoObj.Data, testSecretByteStr)
+	suite.Equal(secretInfoObj.Version, int64(1))
+	suite.Equal(secretInfoObj.Path, testSecretPath)
+
 	// DELETE op.
 	err = db.DeleteSecret(ctx, secretID)
 	suite.NoError(err)
@@ -0,0 +1,140 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/uber/peloton/pkg/storage/connectors/sql"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// SQLStoreTestSuite runs object operations against an in-memory
+// SQLite database
+type SQLStoreTestSuite struct {
+	suite.Suite
+
+	store *Store
+}
+
+func (suite *SQLStoreTestSuite) SetupTest() {
+	store, err := NewSQLStore(
+		&sql.Config{
+			Driver: sql.SQLite,
+			// name the database after the test so that tests do not
+			// share their data
+			DSN: "file:" + uuid.New() +
+				"?mode=memory&cache=shared",
+			CreateTables: true,
+		},
+		tally.NewTestScope("", map[string]string{}),
+	)
+	suite.NoError(err)
+	suite.store = store
+}
+
+func TestSQLStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(SQLStoreTestSuite))
+}
+
+// TestSecretInfoOps tests secret CRUD operations on SQLite
+func (suite *SQLStoreTestSuite) TestSecretInfoOps() {
+	db := NewSecretInfoOps(suite.store)
+	ctx := context.Background()
+	jobID := uuid.New()
+	secretID := uuid.New()
+
+	suite.NoError(db.CreateSecret(
+		ctx, jobID, time.Now().UTC(), secretID, "data", "/tmp/secret"))
+
+	obj, err := db.GetSecret(ctx, secretID)
+	suite.NoError(err)
+	suite.Equal(jobID, obj.JobID)
+	suite.Equal("data", obj.Data)
+	suite.Equal("/tmp/secret", obj.Path)
+	suite.True(obj.Valid)
+
+	suite.NoError(db.UpdateSecretData(ctx, secretID, "new data"))
+	obj, err = db.GetSecret(ctx, secretID)
+	suite.NoError(err)
+	suite.Equal("new data", obj.Data)
+	suite.Equal(jobID, obj.JobID)
+
+	suite.NoError(db.DeleteSecret(ctx, secretID))
+	_, err = db.GetSecret(ctx, secretID)
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestJobRuntimeOps tests job runtime upsert and get on SQLite
+func (suite *SQLStoreTestSuite) TestJobRuntimeOps() {
+	db := NewJobRuntimeOps(suite.store)
+	ctx := context.Background()
+	jobID := &peloton.JobID{Value: uuid.New()}
+
+	_, err := db.Get(ctx, jobID)
+	suite.True(yarpcerrors.IsNotFound(err))
+
+	for _, state := range []job.JobState{
+		job.JobState_PENDING,
+		job.JobState_RUNNING,
+	} {
+		suite.NoError(db.Upsert(ctx, jobID, &job.RuntimeInfo{
+			State:                state,
+			ConfigurationVersion: 2,
+		}))
+		runtime, err := db.Get(ctx, jobID)
+		suite.NoError(err)
+		suite.Equal(state, runtime.GetState())
+		suite.Equal(uint64(2), runtime.GetConfigurationVersion())
+	}
+
+	suite.NoError(db.Delete(ctx, jobID))
+	_, err = db.Get(ctx, jobID)
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestJobNameToIDOps tests getting all the rows of a partition on SQLite
+func (suite *SQLStoreTestSuite) TestJobNameToIDOps() {
+	db := NewJobNameToIDOps(suite.store)
+	ctx := context.Background()
+	jobIDs := []*peloton.JobID{
+		{Value: uuid.New()},
+		{Value: uuid.New()},
+	}
+
+	for _, jobID := range jobIDs {
+		suite.NoError(db.Create(ctx, "test-job", jobID))
+	}
+	suite.NoError(db.Create(ctx, "other-job", &peloton.JobID{Value: uuid.New()}))
+
+	objs, err := db.GetAll(ctx, "test-job")
+	suite.NoError(err)
+	suite.Len(objs, len(jobIDs))
+	for _, obj := range objs {
+		suite.Equal("test-job", obj.JobName)
+		suite.Contains(
+			[]string{jobIDs[0].GetValue(), jobIDs[1].GetValue()},
+			obj.JobID)
+	}
+}
@@ -15,22 +15,27 @@
 package objects
 
 import (
-	"fmt"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	pelotonstore "github.com/uber/peloton/pkg/storage"
+	"github.com/uber/peloton/pkg/storage/cassandra/migrator"
 	"github.com/uber/peloton/pkg/storage/connectors/cassandra"
+	"github.com/uber/peloton/pkg/storage/connectors/memory"
+	"github.com/uber/peloton/pkg/storage/connectors/sql"
 	"github.com/uber/peloton/pkg/storage/objects/base"
 	"github.com/uber/peloton/pkg/storage/orm"
 
-	_ "github.com/gemnasium/migrate/driver/cassandra" // Pull in C* driver for migrate
-	"github.com/gemnasium/migrate/migrate"
 	log "github.com/sirupsen/logrus"
 	"github.com/uber-go/tally"
 )
 
+// _migrateLockWait is how long MigrateSchema waits for the migrations
+// of other test packages sharing the keyspace
+const _migrateLockWait = 5 * time.Minute
+
 // Objs is a global list of storage objects. Every storage object will be added
 // using an init method to this list. This list will be used when creating the
 // ORM client.
@@ -63,6 +68,47 @@ func NewCassandraStore(
 	}, nil
 }
 
+// NewSQLStore creates a new storage client backed by a SQL database
+func NewSQLStore(
+	config *sql.Config,
+	scope tally.Scope,
+) (*Store, error) {
+	tables, err := orm.BuildObjectIndex(Objs)
+	if err != nil {
+		return nil, err
+	}
+	var definitions []*base.Definition
+	for _, table := range tables {
+		definitions = append(definitions, &table.Definition)
+	}
+
+	connector, err := sql.NewSQLConnector(config, scope, definitions...)
+	if err != nil {
+		return nil, err
+	}
+	oclient, err := orm.NewClient(connector, Objs...)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		oClient: oclient,
+		metrics: pelotonstore.NewMetrics(scope),
+	}, nil
+}
+
+// NewMemoryStore creates a new storage client which keeps the objects in
+// memory, for tests which cannot depend on a live database
+func NewMemoryStore(scope tally.Scope) (*Store, error) {
+	oclient, err := orm.NewClient(memory.NewMemoryConnector(), Objs...)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		oClient: oclient,
+		metrics: pelotonstore.NewMetrics(scope),
+	}, nil
+}
+
 // GenerateTestCassandraConfig generates a test config for local C* client
 // This is meant for sharing testing code only, not for production
 func GenerateTestCassandraConfig() *cassandra.Config {
@@ -93,23 +139,29 @@ func MigrateSchema(conf *cassandra.Config) {
 		dir, "pkg", "storage", "cassandra", conf.Migrations)
 	log.Infof("pwd=%v migration path=%v", dir, conf.Migrations)
 
-	connStr := fmt.Sprintf(
-		"cassandra://%v:%v/%v?protocol=4&disable_init_host_lookup",
-		conf.CassandraConn.ContactPoints[0],
-		conf.CassandraConn.Port,
-		conf.StoreName)
-	connStr = strings.Replace(connStr, " ", "", -1)
+	session, err := cassandra.CreateStoreSession(
+		conf.CassandraConn, conf.StoreName)
+	if err != nil {
+		log.Fatalf("failed to create C* session, err=%v", err)
+	}
 
-	errs, ok := migrate.DownSync(connStr, conf.Migrations)
-	if !ok {
-		log.Fatalf("DownSync failed with errors: %v", errs)
+	m, err := migrator.New(
+		migrator.NewCassandraBackend(session, conf.StoreName),
+		conf.Migrations,
+		_migrateLockWait)
+	if err != nil {
+		session.Close()
+		log.Fatalf("failed to create migrator, err=%v", err)
 	}
-	log.Infof("DownSync complete")
+	defer m.Close()
 
-	errs, ok = migrate.UpSync(connStr, conf.Migrations)
-	if !ok {
-		log.Fatalf("UpSync failed with errors: %v", errs)
+	if _, err := m.Down(0, false); err != nil {
+		log.Fatalf("Down failed with error: %v", err)
 	}
+	log.Infof("Down complete")
 
-	log.Infof("UpSync complete")
+	if _, err := m.Up(0, false); err != nil {
+		log.Fatalf("Up failed with error: %v", err)
+	}
+	log.Infof("Up complete")
 }
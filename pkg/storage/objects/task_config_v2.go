@@ -82,6 +82,9 @@ const (
 	specColumn        = "spec"
 	configColumn      = "config"
 	configAddOnColumn = "config_addon"
+
+	// _maxInValues is the maximum number of instances read with one query
+	_maxInValues = 100
 )
 
 // TaskConfigV2Ops provides methods for manipulating task_config_v2 table.
@@ -112,6 +115,41 @@ type TaskConfigV2Ops interface {
 		instanceID uint32,
 		version uint64,
 	) (*pbtask.TaskConfig, *models.ConfigAddOn, error)
+
+	// GetTaskConfigs returns the task specific configs of the given
+	// instances of a job, each at the config version in versions which
+	// is keyed by instance ID. The instances whose config could not be
+	// read are returned in the error map.
+	GetTaskConfigs(
+		ctx context.Context,
+		id *peloton.JobID,
+		versions map[uint32]uint64,
+	) (map[uint32]*TaskConfigResult, map[uint32]error)
+
+	// GetPodSpecs returns the pod specs of the given instances of a job,
+	// each at the config version in versions which is keyed by instance
+	// ID. The instances whose pod spec could not be read are returned in
+	// the error map.
+	GetPodSpecs(
+		ctx context.Context,
+		id *peloton.JobID,
+		versions map[uint32]uint64,
+	) (map[uint32]*pbpod.PodSpec, map[uint32]error)
+
+	// Delete deletes the task config of a task at the given version
+	Delete(
+		ctx context.Context,
+		id *peloton.JobID,
+		instanceID int64,
+		version uint64,
+	) error
+}
+
+// TaskConfigResult is the task config of an instance returned by
+// GetTaskConfigs.
+type TaskConfigResult struct {
+	TaskConfig  *pbtask.TaskConfig
+	ConfigAddOn *models.ConfigAddOn
 }
 
 // ensure that default implementation (taskConfigV2Object) satisfies the interface
@@ -182,6 +220,27 @@ func (d *taskConfigV2Object) Create(
 	return d.store.oClient.Create(ctx, obj)
 }
 
+// Delete deletes the task config of a task at the given version
+func (d *taskConfigV2Object) Delete(
+	ctx context.Context,
+	id *peloton.JobID,
+	instanceID int64,
+	version uint64,
+) error {
+	obj := &TaskConfigV2Object{
+		JobID:      id.GetValue(),
+		Version:    version,
+		InstanceID: instanceID,
+	}
+
+	if err := d.store.oClient.Delete(ctx, obj); err != nil {
+		d.store.metrics.OrmTaskMetrics.TaskConfigV2DeleteFail.Inc(1)
+		return err
+	}
+	d.store.metrics.OrmTaskMetrics.TaskConfigV2Delete.Inc(1)
+	return nil
+}
+
 // GetPodSpec returns the pod spec of a task config
 func (d *taskConfigV2Object) GetPodSpec(
 	ctx context.Context,
@@ -215,18 +274,7 @@ func (d *taskConfigV2Object) GetPodSpec(
 		}
 	}
 
-	// no spec set, return nil
-	if len(obj.Spec) == 0 {
-		return nil, nil
-	}
-
-	podSpec := &pbpod.PodSpec{}
-	if err := proto.Unmarshal(obj.Spec, podSpec); err != nil {
-		return nil, errors.Wrap(yarpcerrors.InternalErrorf(err.Error()),
-			"Failed to unmarshal pod spec")
-	}
-
-	return podSpec, nil
+	return decodePodSpec(obj)
 }
 
 // GetTaskConfig returns the task specific config
@@ -255,30 +303,192 @@ func (d *taskConfigV2Object) GetTaskConfig(
 	return taskConfig, configAddOn, err
 }
 
-// getTaskConfig returns config of specific version,
-// different from GetTaskConfig it does not which version
-// number is default config version and which is instance
-// specific config version
-func (d *taskConfigV2Object) getTaskConfig(
+// GetTaskConfigs returns the task specific configs of a list of instances.
+// The configs of the instances at the same version are read with one query.
+func (d *taskConfigV2Object) GetTaskConfigs(
 	ctx context.Context,
 	id *peloton.JobID,
-	instanceID int64,
-	version uint64,
-) (*pbtask.TaskConfig, *models.ConfigAddOn, error) {
-	obj := &TaskConfigV2Object{
-		JobID:      id.GetValue(),
-		InstanceID: int64(instanceID),
-		Version:    version,
+	versions map[uint32]uint64,
+) (map[uint32]*TaskConfigResult, map[uint32]error) {
+	results := make(map[uint32]*TaskConfigResult)
+	errs := make(map[uint32]error)
+
+	for version, instanceIDs := range instancesByVersion(versions) {
+		objs, err := d.getConfigObjects(ctx, id, version, instanceIDs)
+		if err != nil {
+			for _, instanceID := range instanceIDs {
+				errs[instanceID] = err
+			}
+			d.store.metrics.OrmTaskMetrics.TaskConfigV2GetFail.Inc(
+				int64(len(instanceIDs)))
+			continue
+		}
+
+		// the default config is decoded once and copied for each
+		// instance without an instance config, as callers may modify it
+		var defaultConfig *TaskConfigResult
+		if obj, ok := objs[common.DefaultTaskConfigID]; ok {
+			taskConfig, configAddOn, err := decodeTaskConfig(obj)
+			if err != nil {
+				for _, instanceID := range instanceIDs {
+					errs[instanceID] = err
+				}
+				d.store.metrics.OrmTaskMetrics.TaskConfigV2GetFail.Inc(
+					int64(len(instanceIDs)))
+				continue
+			}
+			defaultConfig = &TaskConfigResult{
+				TaskConfig:  taskConfig,
+				ConfigAddOn: configAddOn,
+			}
+		}
+
+		for _, instanceID := range instanceIDs {
+			var result *TaskConfigResult
+			var err error
+			if obj, ok := objs[int64(instanceID)]; ok {
+				result = &TaskConfigResult{}
+				result.TaskConfig, result.ConfigAddOn, err = decodeTaskConfig(obj)
+			} else if defaultConfig != nil {
+				result = defaultConfig.clone()
+			} else {
+				// neither config is in task_config_v2, which is the case
+				// of the jobs created before it, so read the legacy table
+				result = &TaskConfigResult{}
+				result.TaskConfig, result.ConfigAddOn, err = d.getTaskConfig(
+					ctx, id, int64(instanceID), version)
+				if yarpcerrors.IsNotFound(errors.Cause(err)) {
+					result.TaskConfig, result.ConfigAddOn, err = d.getTaskConfig(
+						ctx, id, common.DefaultTaskConfigID, version)
+				}
+			}
+
+			if err != nil {
+				errs[instanceID] = err
+				d.store.metrics.OrmTaskMetrics.TaskConfigV2GetFail.Inc(1)
+				continue
+			}
+			results[instanceID] = result
+			d.store.metrics.OrmTaskMetrics.TaskConfigV2Get.Inc(1)
+		}
 	}
+	return results, errs
+}
 
-	if err := d.store.oClient.Get(
-		ctx, obj, configColumn, configAddOnColumn); err != nil {
-		if yarpcerrors.IsNotFound(errors.Cause(err)) {
-			return d.handleLegacyConfig(ctx, id, instanceID, version)
+// GetPodSpecs returns the pod specs of a list of instances. The pod specs
+// of the instances at the same version are read with one query.
+func (d *taskConfigV2Object) GetPodSpecs(
+	ctx context.Context,
+	id *peloton.JobID,
+	versions map[uint32]uint64,
+) (map[uint32]*pbpod.PodSpec, map[uint32]error) {
+	results := make(map[uint32]*pbpod.PodSpec)
+	errs := make(map[uint32]error)
+
+	for version, instanceIDs := range instancesByVersion(versions) {
+		objs, err := d.getConfigObjects(ctx, id, version, instanceIDs)
+		if err != nil {
+			for _, instanceID := range instanceIDs {
+				errs[instanceID] = err
+			}
+			d.store.metrics.OrmTaskMetrics.PodSpecGetFail.Inc(
+				int64(len(instanceIDs)))
+			continue
 		}
-		return nil, nil, err
+
+		for _, instanceID := range instanceIDs {
+			obj, ok := objs[int64(instanceID)]
+			if !ok {
+				// per-instance spec not found, return default spec
+				obj, ok = objs[common.DefaultTaskConfigID]
+			}
+			if !ok {
+				errs[instanceID] = yarpcerrors.NotFoundErrorf(
+					"pod spec of instance %d at version %d not found",
+					instanceID, version)
+				d.store.metrics.OrmTaskMetrics.PodSpecGetFail.Inc(1)
+				continue
+			}
+
+			podSpec, err := decodePodSpec(obj)
+			if err != nil {
+				errs[instanceID] = err
+				d.store.metrics.OrmTaskMetrics.PodSpecGetFail.Inc(1)
+				continue
+			}
+			results[instanceID] = podSpec
+			d.store.metrics.OrmTaskMetrics.PodSpecGet.Inc(1)
+		}
+	}
+	return results, errs
+}
+
+// getConfigObjects reads the task_config_v2 rows of a list of instances at
+// a version, together with the default row of the version, with IN queries
+// of at most _maxInValues instances each. The rows found are returned by
+// instance ID.
+func (d *taskConfigV2Object) getConfigObjects(
+	ctx context.Context,
+	id *peloton.JobID,
+	version uint64,
+	instanceIDs []uint32,
+) (map[int64]*TaskConfigV2Object, error) {
+	// the default row is read with the first batch of instances
+	inValues := []interface{}{int64(common.DefaultTaskConfigID)}
+	result := make(map[int64]*TaskConfigV2Object)
+	for i, instanceID := range instanceIDs {
+		inValues = append(inValues, int64(instanceID))
+		if len(inValues) < _maxInValues && i < len(instanceIDs)-1 {
+			continue
+		}
+
+		objs, err := d.store.oClient.GetIn(
+			ctx,
+			&TaskConfigV2Object{
+				JobID:   id.GetValue(),
+				Version: version,
+			},
+			"InstanceID",
+			inValues,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			configObj := obj.(*TaskConfigV2Object)
+			result[configObj.InstanceID] = configObj
+		}
+		inValues = nil
+	}
+	return result, nil
+}
+
+// clone returns a copy of the task config result
+func (r *TaskConfigResult) clone() *TaskConfigResult {
+	if r.TaskConfig == nil {
+		return &TaskConfigResult{}
+	}
+	return &TaskConfigResult{
+		TaskConfig:  proto.Clone(r.TaskConfig).(*pbtask.TaskConfig),
+		ConfigAddOn: proto.Clone(r.ConfigAddOn).(*models.ConfigAddOn),
+	}
+}
+
+// instancesByVersion groups the instance IDs which are the keys of versions
+// by their version
+func instancesByVersion(versions map[uint32]uint64) map[uint64][]uint32 {
+	instances := make(map[uint64][]uint32)
+	for instanceID, version := range versions {
+		instances[version] = append(instances[version], instanceID)
 	}
+	return instances
+}
 
+// decodeTaskConfig unmarshals the task config and the config addon of a
+// task_config_v2 row, returning nil if no config is set
+func decodeTaskConfig(
+	obj *TaskConfigV2Object,
+) (*pbtask.TaskConfig, *models.ConfigAddOn, error) {
 	// no config set, return nil
 	if len(obj.Config) == 0 {
 		return nil, nil, nil
@@ -299,6 +509,50 @@ func (d *taskConfigV2Object) getTaskConfig(
 	return taskConfig, configAddOn, nil
 }
 
+// decodePodSpec unmarshals the pod spec of a task_config_v2 row,
+// returning nil if no spec is set
+func decodePodSpec(obj *TaskConfigV2Object) (*pbpod.PodSpec, error) {
+	// no spec set, return nil
+	if len(obj.Spec) == 0 {
+		return nil, nil
+	}
+
+	podSpec := &pbpod.PodSpec{}
+	if err := proto.Unmarshal(obj.Spec, podSpec); err != nil {
+		return nil, errors.Wrap(yarpcerrors.InternalErrorf(err.Error()),
+			"Failed to unmarshal pod spec")
+	}
+
+	return podSpec, nil
+}
+
+// getTaskConfig returns config of specific version,
+// different from GetTaskConfig it does not which version
+// number is default config version and which is instance
+// specific config version
+func (d *taskConfigV2Object) getTaskConfig(
+	ctx context.Context,
+	id *peloton.JobID,
+	instanceID int64,
+	version uint64,
+) (*pbtask.TaskConfig, *models.ConfigAddOn, error) {
+	obj := &TaskConfigV2Object{
+		JobID:      id.GetValue(),
+		InstanceID: int64(instanceID),
+		Version:    version,
+	}
+
+	if err := d.store.oClient.Get(
+		ctx, obj, configColumn, configAddOnColumn); err != nil {
+		if yarpcerrors.IsNotFound(errors.Cause(err)) {
+			return d.handleLegacyConfig(ctx, id, instanceID, version)
+		}
+		return nil, nil, err
+	}
+
+	return decodeTaskConfig(obj)
+}
+
 // Read config from legacy task_config table and back fill to task_config_v2.
 func (d *taskConfigV2Object) handleLegacyConfig(
 	ctx context.Context,
@@ -209,6 +209,39 @@ func (s *TaskConfigV2ObjectTestSuite) TestCreateGetTaskConfig() {
 	s.Equal(addOn, configAddOn)
 }
 
+// TestDeleteTaskConfig tests deleting the default and the task specific
+// config of a version
+func (s *TaskConfigV2ObjectTestSuite) TestDeleteTaskConfig() {
+	var configVersion uint64 = 1
+	var instance0 int64 = 0
+
+	db := NewTaskConfigV2Ops(testStore)
+	ctx := context.Background()
+
+	taskConfig := &pbtask.TaskConfig{Name: "instance0"}
+	for _, instanceID := range []int64{common.DefaultTaskConfigID, instance0} {
+		s.NoError(db.Create(
+			ctx,
+			s.jobID,
+			instanceID,
+			taskConfig,
+			&models.ConfigAddOn{},
+			nil,
+			configVersion,
+		))
+	}
+
+	config, _, err := db.GetTaskConfig(ctx, s.jobID, uint32(instance0), configVersion)
+	s.NoError(err)
+	s.Equal(taskConfig, config)
+
+	s.NoError(db.Delete(ctx, s.jobID, instance0, configVersion))
+	s.NoError(db.Delete(ctx, s.jobID, common.DefaultTaskConfigID, configVersion))
+
+	_, _, err = db.GetTaskConfig(ctx, s.jobID, uint32(instance0), configVersion)
+	s.Error(err)
+}
+
 // TestGetTaskConfigLegacy tests a case where config is present in task_config
 // and not in task_config_v2.
 func (s *TaskConfigV2ObjectTestSuite) TestGetTaskConfigLegacy() {
@@ -246,6 +279,16 @@ func (s *TaskConfigV2ObjectTestSuite) TestGetTaskConfigLegacy() {
 	}
 	s.NoError(testStore.oClient.Create(ctx, obj))
 
+	// batch reads fall back to the task_config table as well
+	configs, errs := db.GetTaskConfigs(
+		ctx,
+		s.jobID,
+		map[uint32]uint64{0: configVersion},
+	)
+	s.Empty(errs)
+	s.Equal(taskConfig, configs[0].TaskConfig)
+	s.Equal(configAddOn, configs[0].ConfigAddOn)
+
 	// read should go through and fetch data internally from task_config table.
 	config, addOn, err := db.GetTaskConfig(
 		ctx,
@@ -257,3 +300,66 @@ func (s *TaskConfigV2ObjectTestSuite) TestGetTaskConfigLegacy() {
 	s.Equal(config, taskConfig)
 	s.Equal(addOn, configAddOn)
 }
+
+// TestGetTaskConfigsAndPodSpecs tests reading the configs and pod specs
+// of several instances at once
+func (s *TaskConfigV2ObjectTestSuite) TestGetTaskConfigsAndPodSpecs() {
+	db := NewTaskConfigV2Ops(testStore)
+	ctx := context.Background()
+
+	defaultConfig := &pbtask.TaskConfig{Name: "default"}
+	instanceConfig := &pbtask.TaskConfig{Name: "instance-0"}
+	defaultSpec := &pbpod.PodSpec{
+		PodName: &v1alphapeloton.PodName{Value: "default"},
+	}
+
+	// version 1 has a default config and an instance config for 0,
+	// version 2 only has a default config
+	s.NoError(db.Create(ctx, s.jobID, common.DefaultTaskConfigID,
+		defaultConfig, &models.ConfigAddOn{}, defaultSpec, 1))
+	s.NoError(db.Create(ctx, s.jobID, 0,
+		instanceConfig, &models.ConfigAddOn{}, nil, 1))
+	s.NoError(db.Create(ctx, s.jobID, common.DefaultTaskConfigID,
+		defaultConfig, &models.ConfigAddOn{}, defaultSpec, 2))
+
+	versions := map[uint32]uint64{0: 1, 1: 1, 2: 2, 3: 3}
+	configs, errs := db.GetTaskConfigs(ctx, s.jobID, versions)
+	s.Len(configs, 3)
+	s.Equal(instanceConfig, configs[0].TaskConfig)
+	s.Equal(defaultConfig, configs[1].TaskConfig)
+	s.Equal(defaultConfig, configs[2].TaskConfig)
+	s.NotNil(configs[1].ConfigAddOn)
+
+	// the instances share the default config, but not the same object
+	configs[1].TaskConfig.Name = "changed"
+	s.Equal(defaultConfig, configs[2].TaskConfig)
+
+	// version 3 does not exist
+	s.Len(errs, 1)
+	s.Error(errs[3])
+
+	specs, errs := db.GetPodSpecs(ctx, s.jobID, versions)
+	s.Len(specs, 3)
+	s.Nil(specs[0])
+	s.Equal(defaultSpec, specs[1])
+	s.Equal(defaultSpec, specs[2])
+	s.Len(errs, 1)
+	s.Error(errs[3])
+
+	// more instances than read with one query, with an instance
+	// config beyond the first query
+	lastInstance := uint32(_maxInValues + 1)
+	lastConfig := &pbtask.TaskConfig{Name: "instance-last"}
+	s.NoError(db.Create(ctx, s.jobID, int64(lastInstance),
+		lastConfig, &models.ConfigAddOn{}, nil, 1))
+	versions = make(map[uint32]uint64)
+	for i := uint32(0); i <= lastInstance; i++ {
+		versions[i] = 1
+	}
+	configs, errs = db.GetTaskConfigs(ctx, s.jobID, versions)
+	s.Empty(errs)
+	s.Len(configs, int(lastInstance)+1)
+	s.Equal(instanceConfig, configs[0].TaskConfig)
+	s.Equal(defaultConfig, configs[lastInstance-1].TaskConfig)
+	s.Equal(lastConfig, configs[lastInstance].TaskConfig)
+}
@@ -13,3 +13,263 @@
 // limitations under the License.
 
 package objects
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/storage/objects/base"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// init adds a TaskRuntimeObject instance to the global list of storage objects
+func init() {
+	Objs = append(Objs, &TaskRuntimeObject{})
+}
+
+// TaskRuntimeObject corresponds to a row in task_runtime table.
+type TaskRuntimeObject struct {
+	// DB specific annotations
+	base.Object `cassandra:"name=task_runtime, primaryKey=((job_id), instance_id)"`
+
+	// JobID of the job which the task belongs to
+	JobID string `column:"name=job_id"`
+	// InstanceID of the task
+	InstanceID uint32 `column:"name=instance_id"`
+	// Version of the runtime, same as RuntimeInfo.Revision.Version
+	Version uint64 `column:"name=version"`
+	// Update time of the task
+	UpdateTime time.Time `column:"name=update_time"`
+	// Current state of the task
+	State string `column:"name=state"`
+	// RuntimeInfo of the task
+	RuntimeInfo []byte `column:"name=runtime_info"`
+}
+
+// TaskRuntimeOps provides methods for manipulating task_runtime table.
+type TaskRuntimeOps interface {
+	// Create writes a row in the table, replacing the row of the task
+	// if there is one, and adds a pod event for the runtime.
+	Create(
+		ctx context.Context,
+		jobID *peloton.JobID,
+		instanceID uint32,
+		runtime *task.RuntimeInfo,
+	) error
+
+	// Get retrieves a row from the table.
+	Get(
+		ctx context.Context,
+		jobID *peloton.JobID,
+		instanceID uint32,
+	) (*task.RuntimeInfo, error)
+
+	// GetAll retrieves the runtimes of all the tasks of a job by
+	// instance ID.
+	GetAll(
+		ctx context.Context,
+		jobID *peloton.JobID,
+	) (map[uint32]*task.RuntimeInfo, error)
+
+	// Delete removes a row from the table.
+	Delete(
+		ctx context.Context,
+		jobID *peloton.JobID,
+		instanceID uint32,
+	) error
+
+	// CompareAndSet updates the runtime of a task only if the version
+	// stored in the table is expectedVersion, and adds a pod event
+	// for the new runtime. Returns an Aborted error if the stored version
+	// does not match or the task does not exist.
+	CompareAndSet(
+		ctx context.Context,
+		jobID *peloton.JobID,
+		instanceID uint32,
+		runtime *task.RuntimeInfo,
+		expectedVersion uint64,
+	) error
+}
+
+// ensure that default implementation (taskRuntimeOps) satisfies the interface
+var _ TaskRuntimeOps = (*taskRuntimeOps)(nil)
+
+// taskRuntimeOps implements TaskRuntimeOps using a particular Store
+type taskRuntimeOps struct {
+	store *Store
+}
+
+// NewTaskRuntimeOps constructs a taskRuntimeOps object for provided Store.
+func NewTaskRuntimeOps(s *Store) TaskRuntimeOps {
+	return &taskRuntimeOps{store: s}
+}
+
+// Create creates or replaces the task runtime in db
+func (d *taskRuntimeOps) Create(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceID uint32,
+	runtime *task.RuntimeInfo,
+) error {
+	obj, err := newTaskRuntimeObject(jobID, instanceID, runtime)
+	if err != nil {
+		d.store.metrics.OrmTaskMetrics.TaskRuntimeCreateFail.Inc(1)
+		return err
+	}
+
+	if err := d.store.oClient.Create(ctx, obj); err != nil {
+		d.store.metrics.OrmTaskMetrics.TaskRuntimeCreateFail.Inc(1)
+		return err
+	}
+	d.store.metrics.OrmTaskMetrics.TaskRuntimeCreate.Inc(1)
+
+	return NewPodEventsOps(d.store).Create(ctx, jobID, instanceID, runtime)
+}
+
+// Get gets the task runtime from db
+func (d *taskRuntimeOps) Get(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceID uint32,
+) (*task.RuntimeInfo, error) {
+	obj := &TaskRuntimeObject{
+		JobID:      jobID.GetValue(),
+		InstanceID: instanceID,
+	}
+
+	if err := d.store.oClient.Get(ctx, obj); err != nil {
+		d.store.metrics.OrmTaskMetrics.TaskRuntimeGetFail.Inc(1)
+		return nil, err
+	}
+
+	runtime := &task.RuntimeInfo{}
+	if err := proto.Unmarshal(obj.RuntimeInfo, runtime); err != nil {
+		d.store.metrics.OrmTaskMetrics.TaskRuntimeGetFail.Inc(1)
+		return nil, errors.Wrap(err, "Failed to unmarshal task runtime")
+	}
+
+	d.store.metrics.OrmTaskMetrics.TaskRuntimeGet.Inc(1)
+	return runtime, nil
+}
+
+// GetAll gets the runtimes of all the tasks of a job from db
+func (d *taskRuntimeOps) GetAll(
+	ctx context.Context,
+	jobID *peloton.JobID,
+) (map[uint32]*task.RuntimeInfo, error) {
+	objs, err := d.store.oClient.GetAll(ctx, &TaskRuntimeObject{
+		JobID: jobID.GetValue(),
+	})
+	if err != nil {
+		d.store.metrics.OrmTaskMetrics.TaskRuntimeGetAllFail.Inc(1)
+		return nil, err
+	}
+
+	runtimes := make(map[uint32]*task.RuntimeInfo)
+	for _, obj := range objs {
+		taskRuntimeObject := obj.(*TaskRuntimeObject)
+		runtime := &task.RuntimeInfo{}
+		if err := proto.Unmarshal(
+			taskRuntimeObject.RuntimeInfo, runtime); err != nil {
+			d.store.metrics.OrmTaskMetrics.TaskRuntimeGetAllFail.Inc(1)
+			return nil, errors.Wrap(err, "Failed to unmarshal task runtime")
+		}
+		runtimes[taskRuntimeObject.InstanceID] = runtime
+	}
+
+	d.store.metrics.OrmTaskMetrics.TaskRuntimeGetAll.Inc(1)
+	return runtimes, nil
+}
+
+// Delete deletes the task runtime from db
+func (d *taskRuntimeOps) Delete(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceID uint32,
+) error {
+	obj := &TaskRuntimeObject{
+		JobID:      jobID.GetValue(),
+		InstanceID: instanceID,
+	}
+
+	if err := d.store.oClient.Delete(ctx, obj); err != nil {
+		d.store.metrics.OrmTaskMetrics.TaskRuntimeDeleteFail.Inc(1)
+		return err
+	}
+	d.store.metrics.OrmTaskMetrics.TaskRuntimeDelete.Inc(1)
+	return nil
+}
+
+// CompareAndSet updates the task runtime in db if the stored version
+// matches expectedVersion
+func (d *taskRuntimeOps) CompareAndSet(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instanceID uint32,
+	runtime *task.RuntimeInfo,
+	expectedVersion uint64,
+) error {
+	obj, err := newTaskRuntimeObject(jobID, instanceID, runtime)
+	if err != nil {
+		d.store.metrics.OrmTaskMetrics.TaskRuntimeCASFail.Inc(1)
+		return err
+	}
+
+	if err := d.store.oClient.UpdateIf(
+		ctx,
+		obj,
+		map[string]interface{}{"Version": expectedVersion},
+		"Version",
+		"UpdateTime",
+		"State",
+		"RuntimeInfo",
+	); err != nil {
+		if yarpcerrors.IsAborted(err) {
+			d.store.metrics.OrmTaskMetrics.TaskRuntimeCASConflict.Inc(1)
+		} else {
+			d.store.metrics.OrmTaskMetrics.TaskRuntimeCASFail.Inc(1)
+		}
+		return err
+	}
+	d.store.metrics.OrmTaskMetrics.TaskRuntimeCAS.Inc(1)
+
+	// same as the legacy store, failing to add the pod event does not
+	// fail the runtime update
+	if err := NewPodEventsOps(d.store).Create(
+		ctx, jobID, instanceID, runtime); err != nil {
+		log.WithError(err).
+			WithField("job_id", jobID.GetValue()).
+			WithField("instance_id", instanceID).
+			Warn("failed to add pod event")
+	}
+
+	return nil
+}
+
+// newTaskRuntimeObject returns the task_runtime row of a task runtime
+func newTaskRuntimeObject(
+	jobID *peloton.JobID,
+	instanceID uint32,
+	runtime *task.RuntimeInfo,
+) (*TaskRuntimeObject, error) {
+	runtimeBuffer, err := proto.Marshal(runtime)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal task runtime")
+	}
+
+	return &TaskRuntimeObject{
+		JobID:       jobID.GetValue(),
+		InstanceID:  instanceID,
+		Version:     runtime.GetRevision().GetVersion(),
+		UpdateTime:  time.Now().UTC(),
+		State:       runtime.GetState().String(),
+		RuntimeInfo: runtimeBuffer,
+	}, nil
+}
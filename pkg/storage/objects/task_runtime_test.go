@@ -0,0 +1,191 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objects
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	ormmocks "github.com/uber/peloton/pkg/storage/orm/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type TaskRuntimeObjectTestSuite struct {
+	suite.Suite
+	jobID   *peloton.JobID
+	runtime *task.RuntimeInfo
+}
+
+func (s *TaskRuntimeObjectTestSuite) SetupTest() {
+	setupTestStore()
+	s.buildRuntime()
+}
+
+func TestTaskRuntimeObjectSuite(t *testing.T) {
+	suite.Run(t, new(TaskRuntimeObjectTestSuite))
+}
+
+// TestCompareAndSetTaskRuntime tests conditionally updating the task runtime
+func (s *TaskRuntimeObjectTestSuite) TestCompareAndSetTaskRuntime() {
+	taskRuntimeOps := NewTaskRuntimeOps(testStore)
+	ctx := context.Background()
+
+	// the task does not exist yet
+	err := taskRuntimeOps.CompareAndSet(ctx, s.jobID, 0, s.runtime, 1)
+	s.True(yarpcerrors.IsAborted(err))
+
+	runtimeBuffer, err := proto.Marshal(s.runtime)
+	s.NoError(err)
+	s.NoError(testStore.oClient.Create(ctx, &TaskRuntimeObject{
+		JobID:       s.jobID.GetValue(),
+		InstanceID:  0,
+		Version:     s.runtime.GetRevision().GetVersion(),
+		UpdateTime:  time.Now().UTC(),
+		State:       s.runtime.GetState().String(),
+		RuntimeInfo: runtimeBuffer,
+	}))
+
+	newRuntime := proto.Clone(s.runtime).(*task.RuntimeInfo)
+	newRuntime.State = task.TaskState_RUNNING
+	newRuntime.Revision.Version = 2
+
+	// stale version is rejected
+	err = taskRuntimeOps.CompareAndSet(ctx, s.jobID, 0, newRuntime, 0)
+	s.True(yarpcerrors.IsAborted(err))
+
+	s.NoError(taskRuntimeOps.CompareAndSet(ctx, s.jobID, 0, newRuntime, 1))
+	runtime, err := taskRuntimeOps.Get(ctx, s.jobID, 0)
+	s.NoError(err)
+	s.True(proto.Equal(newRuntime, runtime))
+
+	// the version has moved on, the same write is rejected
+	err = taskRuntimeOps.CompareAndSet(ctx, s.jobID, 0, newRuntime, 1)
+	s.True(yarpcerrors.IsAborted(err))
+
+	// the pod events are added for the successful write only
+	events, err := NewPodEventsOps(testStore).GetAll(
+		ctx, s.jobID.GetValue(), 0)
+	s.NoError(err)
+	s.Len(events, 1)
+	s.Equal(task.TaskState_RUNNING.String(), events[0].GetActualState())
+}
+
+// TestCreateGetAllDeleteTaskRuntime tests creating, reading all and
+// deleting the task runtimes of a job
+func (s *TaskRuntimeObjectTestSuite) TestCreateGetAllDeleteTaskRuntime() {
+	taskRuntimeOps := NewTaskRuntimeOps(testStore)
+	ctx := context.Background()
+
+	s.NoError(taskRuntimeOps.Create(ctx, s.jobID, 0, s.runtime))
+	s.NoError(taskRuntimeOps.Create(ctx, s.jobID, 1, s.runtime))
+
+	// the created runtime can be conditionally updated
+	newRuntime := proto.Clone(s.runtime).(*task.RuntimeInfo)
+	newRuntime.State = task.TaskState_PENDING
+	newRuntime.Revision.Version = 2
+	s.NoError(taskRuntimeOps.CompareAndSet(ctx, s.jobID, 1, newRuntime, 1))
+
+	runtimes, err := taskRuntimeOps.GetAll(ctx, s.jobID)
+	s.NoError(err)
+	s.Len(runtimes, 2)
+	s.True(proto.Equal(s.runtime, runtimes[0]))
+	s.True(proto.Equal(newRuntime, runtimes[1]))
+
+	// a pod event is added on create
+	events, err := NewPodEventsOps(testStore).GetAll(
+		ctx, s.jobID.GetValue(), 0)
+	s.NoError(err)
+	s.Len(events, 1)
+	s.Equal(task.TaskState_INITIALIZED.String(), events[0].GetActualState())
+
+	s.NoError(taskRuntimeOps.Delete(ctx, s.jobID, 0))
+	_, err = taskRuntimeOps.Get(ctx, s.jobID, 0)
+	s.True(yarpcerrors.IsNotFound(err))
+	runtimes, err = taskRuntimeOps.GetAll(ctx, s.jobID)
+	s.NoError(err)
+	s.Len(runtimes, 1)
+}
+
+// TestCompareAndSetTaskRuntimeFail tests failure cases due to ORM Client
+// errors
+func (s *TaskRuntimeObjectTestSuite) TestCompareAndSetTaskRuntimeFail() {
+	ctrl := gomock.NewController(s.T())
+	defer ctrl.Finish()
+
+	mockClient := ormmocks.NewMockClient(ctrl)
+	mockStore := &Store{oClient: mockClient, metrics: testStore.metrics}
+	taskRuntimeOps := NewTaskRuntimeOps(mockStore)
+
+	mockClient.EXPECT().
+		UpdateIf(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("update failed"))
+	mockClient.EXPECT().Get(gomock.Any(), gomock.Any()).
+		Return(errors.New("get failed"))
+	mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+		Return(errors.New("create failed"))
+	mockClient.EXPECT().GetAll(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("getAll failed"))
+	mockClient.EXPECT().Delete(gomock.Any(), gomock.Any()).
+		Return(errors.New("delete failed"))
+
+	ctx := context.Background()
+
+	err := taskRuntimeOps.CompareAndSet(ctx, s.jobID, 0, s.runtime, 1)
+	s.Error(err)
+	s.Equal("update failed", err.Error())
+
+	_, err = taskRuntimeOps.Get(ctx, s.jobID, 0)
+	s.Error(err)
+	s.Equal("get failed", err.Error())
+
+	err = taskRuntimeOps.Create(ctx, s.jobID, 0, s.runtime)
+	s.Error(err)
+	s.Equal("create failed", err.Error())
+
+	_, err = taskRuntimeOps.GetAll(ctx, s.jobID)
+	s.Error(err)
+	s.Equal("getAll failed", err.Error())
+
+	err = taskRuntimeOps.Delete(ctx, s.jobID, 0)
+	s.Error(err)
+	s.Equal("delete failed", err.Error())
+}
+
+func (s *TaskRuntimeObjectTestSuite) buildRuntime() {
+	s.jobID = &peloton.JobID{Value: uuid.New()}
+	mesosTaskID := s.jobID.GetValue() + "-0-1"
+
+	s.runtime = &task.RuntimeInfo{
+		State:       task.TaskState_INITIALIZED,
+		GoalState:   task.TaskState_SUCCEEDED,
+		MesosTaskId: &mesos.TaskID{Value: &mesosTaskID},
+		Revision: &peloton.ChangeLog{
+			CreatedAt: uint64(time.Now().UnixNano()),
+			UpdatedAt: uint64(time.Now().UnixNano()),
+			Version:   1,
+		},
+		ConfigVersion: 1,
+	}
+}
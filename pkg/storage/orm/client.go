@@ -17,6 +17,7 @@ package orm
 import (
 	"context"
 	"reflect"
+	"sort"
 
 	"github.com/uber/peloton/pkg/storage/objects/base"
 
@@ -38,12 +39,32 @@ type Client interface {
 	// GetAllIter provides an iterative way to fetch all storage objects
 	// for the partition key
 	GetAllIter(ctx context.Context, e base.Object) (Iterator, error)
+	// GetIn gets the storage objects whose inField, which must be a
+	// primary key field, takes one of the inValues. The primary key fields
+	// before inField are read from e. Unlike GetAll, inField may be the
+	// last partition key field, to read several partitions at once.
+	GetIn(
+		ctx context.Context,
+		e base.Object,
+		inField string,
+		inValues []interface{},
+	) ([]base.Object, error)
 	// Update updates the storage object in the database
 	// The fields to be updated can be specified as fieldsToUpdate which is
 	// a variable list of field names and is to be optionally specified by
 	// the caller. If not specified, all fields in the object will be updated
 	// to the DB
 	Update(ctx context.Context, e base.Object, fieldsToUpdate ...string) error
+	// UpdateIf updates the storage object in the database only if the
+	// stored object exists and has the values in conditions, which maps
+	// field names to their expected values. Returns an Aborted error if
+	// the conditions do not hold. fieldsToUpdate is same as for Update.
+	UpdateIf(
+		ctx context.Context,
+		e base.Object,
+		conditions map[string]interface{},
+		fieldsToUpdate ...string,
+	) error
 	// Delete deletes the storage object from the database
 	Delete(ctx context.Context, e base.Object) error
 }
@@ -178,6 +199,52 @@ func (c *client) GetAllIter(
 	return c.connector.GetAllIter(ctx, &table.Definition, keyRow)
 }
 
+// GetIn fetches the list of base objects whose inField takes one of the
+// inValues, for the values of the primary key fields before inField in
+// the base object provided
+func (c *client) GetIn(
+	ctx context.Context,
+	e base.Object,
+	inField string,
+	inValues []interface{},
+) ([]base.Object, error) {
+
+	// lookup if a table exists for this object, return error if not found
+	table, err := c.getTable(e)
+	if err != nil {
+		return nil, err
+	}
+
+	inColumn, ok := table.FieldToCol[inField]
+	if !ok {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"unknown field %q", inField)
+	}
+
+	// build a row of the key columns preceding the IN column
+	var keyRow []base.Column
+	found := false
+	for _, col := range table.GetKeyRowFromObject(e) {
+		if col.Name == inColumn {
+			found = true
+			break
+		}
+		keyRow = append(keyRow, col)
+	}
+	if !found {
+		return nil, yarpcerrors.InvalidArgumentErrorf(
+			"field %q is not a primary key field", inField)
+	}
+
+	rows, err := c.connector.GetIn(
+		ctx, &table.Definition, keyRow, inColumn, inValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return table.BuildObjectsFromRows(e, rows), nil
+}
+
 // Update updates the storage object in the database
 func (c *client) Update(
 	ctx context.Context,
@@ -200,6 +267,52 @@ func (c *client) Update(
 	return c.connector.Update(ctx, &table.Definition, row, keyRow)
 }
 
+// UpdateIf updates the storage object in the database if the conditions
+// hold for the stored object
+func (c *client) UpdateIf(
+	ctx context.Context,
+	e base.Object,
+	conditions map[string]interface{},
+	fieldsToUpdate ...string,
+) error {
+	// lookup if a table exists for this object, return error if not found
+	table, err := c.getTable(e)
+	if err != nil {
+		return err
+	}
+
+	// translate the conditions into a list of columns, in a stable order
+	// so that the same query is built for the same conditions
+	var fieldNames []string
+	for fieldName := range conditions {
+		fieldNames = append(fieldNames, fieldName)
+	}
+	sort.Strings(fieldNames)
+
+	var condRow []base.Column
+	for _, fieldName := range fieldNames {
+		columnName, ok := table.FieldToCol[fieldName]
+		if !ok {
+			return yarpcerrors.InvalidArgumentErrorf(
+				"unknown condition field %q", fieldName)
+		}
+		condRow = append(condRow, base.Column{
+			Name:  columnName,
+			Value: conditions[fieldName],
+		})
+	}
+
+	// translate the storage object into a row (list of column)
+	row := table.GetRowFromObject(e, fieldsToUpdate...)
+
+	// build a primary key row from storage object
+	keyRow := table.GetKeyRowFromObject(e)
+
+	// Tell the connector to update a row in the DB using this row if
+	// the conditions hold
+	return c.connector.UpdateIf(ctx, &table.Definition, row, keyRow, condRow)
+}
+
 // Delete deletes the storage object in the database
 func (c *client) Delete(ctx context.Context, e base.Object) error {
 	// lookup if a table exists for this object, return error if not found
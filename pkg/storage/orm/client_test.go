@@ -273,6 +273,48 @@ func (suite *ORMTestSuite) TestClientGetAllIter() {
 	suite.Error(err)
 }
 
+// TestClientGetIn tests client GetIn operation on valid and invalid
+// entities
+func (suite *ORMTestSuite) TestClientGetIn() {
+	defer suite.ctrl.Finish()
+	conn := ormmocks.NewMockConnector(suite.ctrl)
+
+	// ValidObject instance with only partition key set
+	e := &ValidObject{
+		ID: uint64(1),
+	}
+	names := []interface{}{"test1", "test2"}
+
+	conn.EXPECT().GetIn(suite.ctx, gomock.Any(), gomock.Any(), "name", names).
+		Do(func(_ context.Context, _ *base.Definition,
+			row []base.Column, _ string, _ []interface{}) {
+			suite.Len(row, 1)
+			suite.Equal("id", row[0].Name)
+			suite.Equal(e.ID, row[0].Value)
+		}).Return(testRows, nil)
+
+	client, err := orm.NewClient(conn, &ValidObject{})
+	suite.NoError(err)
+
+	objs, err := client.GetIn(suite.ctx, e, "Name", names)
+	suite.NoError(err)
+	suite.Len(objs, 2)
+	for i, obj := range objs {
+		validObj := obj.(*ValidObject)
+		suite.Equal(testRows[i][1].Value, validObj.Name)
+		suite.Equal(testRows[i][2].Value, validObj.Data)
+	}
+
+	// the field must be a primary key field
+	_, err = client.GetIn(suite.ctx, e, "Data", names)
+	suite.Error(err)
+	_, err = client.GetIn(suite.ctx, e, "Unknown", names)
+	suite.Error(err)
+
+	_, err = client.GetIn(suite.ctx, &InvalidObject1{}, "Name", names)
+	suite.Error(err)
+}
+
 // TestClientUpdate tests client update operation on valid and invalid entities
 func (suite *ORMTestSuite) TestClientUpdate() {
 	defer suite.ctrl.Finish()
@@ -315,6 +357,51 @@ func (suite *ORMTestSuite) TestClientUpdate() {
 	suite.Error(err)
 }
 
+// TestClientUpdateIf tests client conditional update operation on valid
+// and invalid entities
+func (suite *ORMTestSuite) TestClientUpdateIf() {
+	defer suite.ctrl.Finish()
+	conn := ormmocks.NewMockConnector(suite.ctrl)
+
+	conn.EXPECT().UpdateIf(
+		suite.ctx, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, _ *base.Definition,
+			row []base.Column, keyRow []base.Column, conds []base.Column) {
+			suite.Equal("data", row[0].Name)
+			suite.Equal("testdata", row[0].Value)
+			suite.Equal("id", keyRow[0].Name)
+			suite.Equal(uint64(1), keyRow[0].Value)
+			suite.Equal([]base.Column{
+				{Name: "data", Value: "olddata"},
+				{Name: "name", Value: "test"},
+			}, conds)
+		}).Return(nil)
+
+	client, err := orm.NewClient(conn, &ValidObject{})
+	suite.NoError(err)
+
+	// Update Data field in testValidObject if Data and Name match
+	err = client.UpdateIf(
+		suite.ctx,
+		testValidObject,
+		map[string]interface{}{"Name": "test", "Data": "olddata"},
+		"Data",
+	)
+	suite.NoError(err)
+
+	// condition on a field which does not exist
+	err = client.UpdateIf(
+		suite.ctx,
+		testValidObject,
+		map[string]interface{}{"Unknown": "test"},
+		"Data",
+	)
+	suite.Error(err)
+
+	err = client.UpdateIf(suite.ctx, &InvalidObject1{}, nil)
+	suite.Error(err)
+}
+
 // TestClientDelete tests client delete operation on valid and invalid entities
 func (suite *ORMTestSuite) TestClientDelete() {
 	defer suite.ctrl.Finish()
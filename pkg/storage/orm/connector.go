@@ -55,6 +55,17 @@ type Connector interface {
 		keys []base.Column,
 	) (Iterator, error)
 
+	// GetIn fetches the rows matching the keys whose inColumn takes one
+	// of the inValues. inColumn is the primary key column following the
+	// keys, and may be the last partition key column.
+	GetIn(
+		ctx context.Context,
+		e *base.Definition,
+		keys []base.Column,
+		inColumn string,
+		inValues []interface{},
+	) ([][]base.Column, error)
+
 	// Update updates a row in the DB for the base object
 	Update(
 		ctx context.Context,
@@ -63,6 +74,17 @@ type Connector interface {
 		keys []base.Column,
 	) error
 
+	// UpdateIf updates a row in the DB for the base object only if the
+	// row exists and its columns match the conditions. Returns an Aborted
+	// error otherwise.
+	UpdateIf(
+		ctx context.Context,
+		e *base.Definition,
+		values []base.Column,
+		keys []base.Column,
+		conditions []base.Column,
+	) error
+
 	// Delete deletes a row from the DB for the base object
 	Delete(ctx context.Context, e *base.Definition, keys []base.Column) error
 }